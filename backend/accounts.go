@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math/big"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -25,6 +26,7 @@ import (
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/observable"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/observable/action"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/socksproxy"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/ethereum/go-ethereum/params"
 )
@@ -494,7 +496,7 @@ func (backend *Backend) LookupInsuredAccounts(accountCode accountsTypes.Code) ([
 	}
 
 	// check the insurance status of the selected accounts.
-	bitsuranceAccounts, err := bitsurance.LookupBitsuranceAccounts(backend.DevServers(), accountList, backend.httpClient)
+	bitsuranceAccounts, err := bitsurance.LookupBitsuranceAccounts(backend.DevServers(), accountList, backend.MarketHTTPClient())
 	if err != nil {
 		return nil, err
 	}
@@ -805,6 +807,7 @@ func (backend *Backend) addAccount(account accounts.Interface) {
 			Object:  event.Object,
 		})
 		if event.Subject == string(accountsTypes.EventSyncDone) {
+			backend.resetTransactionsQueryCache(account)
 			backend.notifyNewTxs(account)
 			go backend.checkAccountUsed(account)
 		}
@@ -952,8 +955,10 @@ func (backend *Backend) createAndAddAccount(
 	accountConfig := &accounts.AccountConfig{
 		Config:          persistedConfig,
 		DBFolder:        backend.arguments.CacheDirectoryPath(),
+		InMemoryDB:      backend.keyring.Enabled(),
 		SkipInitialSync: options.skipETHInitialSync,
 		NotesFolder:     backend.arguments.NotesDirectoryPath(),
+		NotesKeyring:    backend.keyring,
 		ConnectKeystore: func() (keystore.Keystore, error) {
 			accountRootFingerprint, err := persistedConfig.SigningConfigurations.RootFingerprint()
 			if err != nil {
@@ -968,8 +973,12 @@ func (backend *Backend) createAndAddAccount(
 		GetNotifier: func(configurations signing.Configurations) accounts.Notifier {
 			return backend.notifier.ForAccount(persistedConfig.Code)
 		},
-		GetSaveFilename:  backend.environment.GetSaveFilename,
-		UnsafeSystemOpen: backend.environment.SystemOpen,
+		GetSaveFilename:    backend.environment.GetSaveFilename,
+		UnsafeSystemOpen:   backend.environment.SystemOpen,
+		LookupContact:      backend.lookupContactFunc(coin.Code()),
+		ResemblingContacts: backend.resemblingContactsFunc(coin.Code()),
+		FetchNFTMetadata:   backend.fetchNFTMetadata,
+		ERC20Transactions:  backend.erc20TransactionsFunc(persistedConfig.Code),
 	}
 
 	// This function is passed as a callback to the BTC account constructor. It is called when the
@@ -1011,7 +1020,11 @@ func (backend *Backend) createAndAddAccount(
 		)
 		backend.addAccount(account)
 	case *eth.Coin:
-		account = backend.makeEthAccount(accountConfig, specificCoin, backend.httpClient, backend.log)
+		accountConfig.SameAddressAccounts = backend.sameAddressAccountsFunc(persistedConfig.Code)
+		account = backend.makeEthAccount(
+			accountConfig, specificCoin,
+			backend.accountHTTPClient(socksproxy.ServiceOther, persistedConfig.Code),
+			backend.log)
 		backend.addAccount(account)
 
 		// Load ERC20 tokens enabled with this Ethereum account.
@@ -1046,6 +1059,62 @@ func (backend *Backend) createAndAddAccount(
 	}
 }
 
+// erc20TransactionsFunc returns the `ERC20Transactions` callback of the Ethereum account with the
+// given code.
+func (backend *Backend) erc20TransactionsFunc(
+	ethAccountCode accountsTypes.Code) func() []*accounts.TransactionData {
+	return func() []*accounts.TransactionData {
+		persistedConfig := backend.config.AccountsConfig().Lookup(ethAccountCode)
+		if persistedConfig == nil {
+			return nil
+		}
+		accountsList := backend.Accounts()
+		result := []*accounts.TransactionData{}
+		for _, tokenCode := range persistedConfig.ActiveTokens {
+			account := accountsList.lookup(Erc20AccountCode(ethAccountCode, tokenCode))
+			if account == nil {
+				continue
+			}
+			transactions, err := account.Transactions()
+			if err != nil {
+				// The token account might not be synced yet.
+				continue
+			}
+			result = append(result, transactions...)
+		}
+		return result
+	}
+}
+
+// sameAddressAccountsFunc returns the `SameAddressAccounts` callback of the Ethereum or ERC20 token
+// account with the given code.
+func (backend *Backend) sameAddressAccountsFunc(
+	code accountsTypes.Code) func() []accounts.Interface {
+	return func() []accounts.Interface {
+		accountsList := backend.Accounts()
+		for _, ethConfig := range backend.config.AccountsConfig().Accounts {
+			codes := []accountsTypes.Code{ethConfig.Code}
+			for _, tokenCode := range ethConfig.ActiveTokens {
+				codes = append(codes, Erc20AccountCode(ethConfig.Code, tokenCode))
+			}
+			if !slices.Contains(codes, code) {
+				continue
+			}
+			result := []accounts.Interface{}
+			for _, otherCode := range codes {
+				if otherCode == code {
+					continue
+				}
+				if account := accountsList.lookup(otherCode); account != nil {
+					result = append(result, account)
+				}
+			}
+			return result
+		}
+		return nil
+	}
+}
+
 func (backend *Backend) emitAccountsStatusChanged() {
 	backend.Notify(observable.Event{
 		Subject: "accounts",
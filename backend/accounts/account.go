@@ -44,6 +44,9 @@ type TxProposalArgs struct {
 	SelectedUTXOs  map[wire.OutPoint]struct{}
 	Note           string
 	PaymentRequest *paymentrequest.Request
+	// Data is the calldata of a contract call. Only supported by Ethereum accounts, not by ERC20
+	// token accounts.
+	Data []byte
 }
 
 // Interface is the API of a Account.
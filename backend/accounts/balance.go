@@ -8,13 +8,21 @@ import "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
 type Balance struct {
 	available coin.Amount
 	incoming  coin.Amount
+	frozen    coin.Amount
 }
 
 // NewBalance creates a new balance with the given amounts.
 func NewBalance(available coin.Amount, incoming coin.Amount) *Balance {
+	return NewBalanceWithFrozen(available, incoming, coin.NewAmountFromInt64(0))
+}
+
+// NewBalanceWithFrozen creates a new balance with the given amounts, of which `frozen` are coins
+// that are excluded from spending.
+func NewBalanceWithFrozen(available coin.Amount, incoming coin.Amount, frozen coin.Amount) *Balance {
 	return &Balance{
 		available: available,
 		incoming:  incoming,
+		frozen:    frozen,
 	}
 }
 
@@ -28,3 +36,9 @@ func (balance *Balance) Available() coin.Amount {
 func (balance *Balance) Incoming() coin.Amount {
 	return balance.incoming
 }
+
+// Frozen returns the sum of all available coins which are frozen by the user and can't be spent.
+// They are included in `Available()`.
+func (balance *Balance) Frozen() coin.Amount {
+	return balance.frozen
+}
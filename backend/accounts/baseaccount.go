@@ -19,6 +19,7 @@ import (
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/keystore"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/rates"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/signing"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/atrest"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/observable"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/observable/action"
@@ -36,11 +37,16 @@ type AccountConfig struct {
 	// `backend.config.ModifyAccountsConfig()` instead.
 	Config   *config.Account
 	DBFolder string
+	// InMemoryDB keeps the transactions database only in memory instead of in DBFolder, e.g. while
+	// the local data is encrypted at rest. The transactions are synced again after a restart.
+	InMemoryDB bool
 	// SkipInitialSync suppresses the ETH init-time per-account update when a batch sync will
 	// refresh the account right after loading.
 	SkipInitialSync bool
 	// NotesFolder is the folder where the transaction notes are stored. Full path.
-	NotesFolder     string
+	NotesFolder string
+	// NotesKeyring encrypts the notes at rest. nil if the notes are stored in plaintext.
+	NotesKeyring    *atrest.Keyring
 	ConnectKeystore func() (keystore.Keystore, error)
 	RateUpdater     *rates.RateUpdater
 	// Returns the currency selected by the user in app settings.
@@ -49,6 +55,23 @@ type AccountConfig struct {
 	GetSaveFilename func(suggestedFilename string) string
 	// Opens a file in a default application. The filename is not checked.
 	UnsafeSystemOpen func(filename string) error
+	// LookupContact returns the address book contact of an address of this account's coin, or nil
+	// if there is none. Can be nil.
+	LookupContact func(address string) *AddressBookContact
+	// ResemblingContacts returns the address book contacts of this account's coin whose address
+	// resembles the given address without being equal to it. Can be nil.
+	ResemblingContacts func(address string) []*AddressBookContact
+	// FetchNFTMetadata returns true if the metadata of NFTs may be fetched from the hosts of their
+	// metadata URIs, which learn the IP address of the user unless the connections are proxied. Can
+	// be nil, which means false.
+	FetchNFTMetadata func() bool
+	// ERC20Transactions returns the transactions of the ERC20 token accounts of an Ethereum account.
+	// Can be nil.
+	ERC20Transactions func() []*TransactionData
+	// SameAddressAccounts returns the other loaded accounts using the address of this account, i.e.
+	// the Ethereum account and its ERC20 token accounts, which share the nonces of the address. Can
+	// be nil.
+	SameAddressAccounts func() []Interface
 }
 
 // BaseAccount is an account struct with common functionality to all coin accounts.
@@ -142,10 +165,10 @@ func (account *BaseAccount) SetOffline(offline error) {
 // Initialize initializes the account. `accountIdentifier` is used as part of the filename of
 // account databases.
 func (account *BaseAccount) Initialize(accountIdentifier string) error {
-	txNotes, err := notes.LoadNotes(path.Join(
+	txNotes, err := notes.LoadEncryptedNotes(path.Join(
 		account.config.NotesFolder,
 		fmt.Sprintf("%s.json", accountIdentifier),
-	))
+	), account.config.NotesKeyring)
 	if err != nil {
 		return err
 	}
@@ -213,10 +236,10 @@ func (account *BaseAccount) migrateLegacyNotes() error {
 			continue // skip nonexistent legacy notes file
 		}
 
-		legacyNotes, err := notes.LoadNotes(path.Join(
+		legacyNotes, err := notes.LoadEncryptedNotes(path.Join(
 			account.config.NotesFolder,
 			fmt.Sprintf("%s.json", identifier),
-		))
+		), account.config.NotesKeyring)
 		if err != nil {
 			return err
 		}
@@ -249,6 +272,39 @@ func (account *BaseAccount) TxNote(txID string) string {
 	return account.notes.TxNote(txID)
 }
 
+// LabelContacts sets the address book contact of the recipient addresses of outgoing transactions.
+// The list and the transactions which are modified are copied, so the passed transactions can be
+// shared.
+func (account *BaseAccount) LabelContacts(transactions OrderedTransactions) OrderedTransactions {
+	if account.config.LookupContact == nil {
+		return transactions
+	}
+	transactions = append(OrderedTransactions{}, transactions...)
+	for i, transaction := range transactions {
+		if transaction.Type != TxTypeSend {
+			continue
+		}
+		var addresses []AddressAndAmount
+		for j, address := range transaction.Addresses {
+			contact := account.config.LookupContact(address.Address)
+			if contact == nil {
+				continue
+			}
+			if addresses == nil {
+				addresses = append([]AddressAndAmount{}, transaction.Addresses...)
+			}
+			addresses[j].Contact = contact
+		}
+		if addresses == nil {
+			continue
+		}
+		labelled := *transaction
+		labelled.Addresses = addresses
+		transactions[i] = &labelled
+	}
+	return transactions
+}
+
 // ExportCSV implements accounts.Account.
 func (account *BaseAccount) ExportCSV(w io.Writer, transactions []*TransactionData) error {
 	writer := csv.NewWriter(w)
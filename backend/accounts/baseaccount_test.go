@@ -386,3 +386,44 @@ func TestBaseAccount(t *testing.T) {
 		)
 	})
 }
+
+func TestLabelContacts(t *testing.T) {
+	alice := &AddressBookContact{ID: "alice", Name: "Alice", Verified: true}
+	account := NewBaseAccount(&AccountConfig{
+		LookupContact: func(address string) *AddressBookContact {
+			if address == "alice-address" {
+				return alice
+			}
+			return nil
+		},
+	}, &mocks.CoinMock{}, logging.Get().WithGroup("baseaccount_test"))
+
+	send := &TransactionData{
+		Type: TxTypeSend,
+		Addresses: []AddressAndAmount{
+			{Address: "alice-address"},
+			{Address: "other-address"},
+		},
+	}
+	receive := &TransactionData{
+		Type:      TxTypeReceive,
+		Addresses: []AddressAndAmount{{Address: "alice-address"}},
+	}
+	other := &TransactionData{
+		Type:      TxTypeSend,
+		Addresses: []AddressAndAmount{{Address: "other-address"}},
+	}
+	transactions := OrderedTransactions{send, receive, other}
+	labelled := account.LabelContacts(transactions)
+	require.Len(t, labelled, 3)
+	require.Equal(t, alice, labelled[0].Addresses[0].Contact)
+	require.Nil(t, labelled[0].Addresses[1].Contact)
+	require.Same(t, receive, labelled[1])
+	require.Same(t, other, labelled[2])
+	// The passed transactions are not modified.
+	require.Same(t, send, transactions[0])
+	require.Nil(t, send.Addresses[0].Contact)
+
+	account = NewBaseAccount(&AccountConfig{}, &mocks.CoinMock{}, logging.Get().WithGroup("baseaccount_test"))
+	require.Equal(t, transactions, account.LabelContacts(transactions))
+}
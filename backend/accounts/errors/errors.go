@@ -19,6 +19,12 @@ var (
 	// ErrInvalidAddress is used when the recipient address is invalid or does not match the correct
 	// network.
 	ErrInvalidAddress = TxValidationError("invalidAddress")
+	// ErrENSNameNotFound is used when the recipient is an ENS name which does not resolve to an
+	// address.
+	ErrENSNameNotFound = TxValidationError("ensNameNotFound")
+	// ErrInvalidData is used when the contract calldata is malformatted or cannot be encoded with
+	// the provided ABI.
+	ErrInvalidData = TxValidationError("invalidData")
 	// ErrInvalidAmount is used when the user entered amount is malformatted or not positive.
 	ErrInvalidAmount = TxValidationError("invalidAmount")
 	// ErrInsufficientFunds is returned when there are not enough funds to cover the target amount
@@ -29,6 +35,9 @@ var (
 	ErrFeeTooLow = TxValidationError("feeTooLow")
 	// ErrAccountNotsynced is used when the account sync has not successfully finished.
 	ErrAccountNotsynced = TxValidationError("accountNotSynced")
+	// ErrResemblingContact is returned when the recipient address resembles the address of an
+	// address book contact without being equal to it, and the user did not confirm sending to it.
+	ErrResemblingContact = TxValidationError("resemblingContact")
 
 	// ErrNotAvailable is returned if data required is not available yet. Example: the headers are
 	// not synced yet, which is a prerequisite to making a timeseries of the portfolio.
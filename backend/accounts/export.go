@@ -0,0 +1,433 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package accounts
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/logging"
+)
+
+// historyLoadTimeout is the maximum time to fetch the missing exchange rates history of an export.
+const historyLoadTimeout = 2 * time.Minute
+
+// ExportFormat is the CSV layout of a transactions export.
+type ExportFormat string
+
+const (
+	// ExportFormatDefault is the layout of `BaseAccount.ExportCSV()`, with amounts in the smallest
+	// unit and one row per address. It is only available for the export of a single account.
+	ExportFormatDefault ExportFormat = "default"
+	// ExportFormatKoinly is the Koinly universal import format.
+	ExportFormatKoinly ExportFormat = "koinly"
+	// ExportFormatCoinTracking is the CoinTracking CSV import format.
+	ExportFormatCoinTracking ExportFormat = "cointracking"
+	// ExportFormatUniversal is a generic format for accounting tools, with one row per movement of
+	// coins, including separate rows for fees.
+	ExportFormatUniversal ExportFormat = "universal"
+)
+
+// ExportOptions are the options of a transactions export.
+type ExportOptions struct {
+	// Format defaults to ExportFormatDefault if empty.
+	Format ExportFormat `json:"format"`
+	// From and To restrict the export to the transactions in this time range (inclusive), as unix
+	// timestamps in seconds. Zero means no limit. Transactions without a timestamp, e.g. unconfirmed
+	// ones, are excluded if a limit is set.
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+	// Fiat is the currency of the fiat values. Defaults to the main currency if empty. The fiat values
+	// are left empty where no exchange rate of the currency is available.
+	Fiat string `json:"fiat"`
+}
+
+// Validate returns an error if the format is not supported or the time range is invalid.
+func (options ExportOptions) Validate() error {
+	if options.Format != "" && options.Format != ExportFormatDefault {
+		if _, ok := exporters[options.Format]; !ok {
+			return errp.Newf("unsupported export format %q", options.Format)
+		}
+	}
+	if options.From != 0 && options.To != 0 && options.From > options.To {
+		return errp.New("the start of the time range is after its end")
+	}
+	return nil
+}
+
+// Filter returns the transactions in the time range of the options, keeping their order.
+func (options ExportOptions) Filter(transactions []*TransactionData) []*TransactionData {
+	if options.From == 0 && options.To == 0 {
+		return transactions
+	}
+	result := []*TransactionData{}
+	for _, transaction := range transactions {
+		if transaction.Timestamp == nil {
+			continue
+		}
+		if options.From != 0 && transaction.Timestamp.Before(time.Unix(options.From, 0)) {
+			continue
+		}
+		if options.To != 0 && transaction.Timestamp.After(time.Unix(options.To, 0)) {
+			continue
+		}
+		result = append(result, transaction)
+	}
+	return result
+}
+
+// ExportTransaction is a transaction of an account prepared for the export formats of tax and
+// accounting tools, with decimal amounts and fiat values at the time of the transaction.
+type ExportTransaction struct {
+	AccountCode string
+	AccountName string
+	Transaction *TransactionData
+	// Amount is the decimal amount in Unit.
+	Amount string
+	Unit   string
+	// Fee is the decimal fee in FeeUnit, empty if there is no fee.
+	Fee     string
+	FeeUnit string
+	// FiatValue is the value of Amount in Fiat at the time of the transaction, empty if unknown.
+	FiatValue string
+	// FeeFiatValue is the value of Fee in Fiat at the time of the transaction, empty if unknown.
+	FeeFiatValue string
+	Fiat         string
+	Note         string
+}
+
+// formatDecimal formats an amount in the smallest unit as a decimal amount without trailing zeros.
+func formatDecimal(amount coin.Amount, accountCoin coin.Coin, isFee bool) string {
+	formatted := coin.ToUnitRat(amount, accountCoin, isFee).FloatString(int(accountCoin.Decimals(isFee)))
+	if strings.Contains(formatted, ".") {
+		formatted = strings.TrimRight(strings.TrimRight(formatted, "0"), ".")
+	}
+	return formatted
+}
+
+// fiatValueAt returns the fiat value of the amount at the given time, or the empty string if no
+// rate is available.
+func fiatValueAt(
+	account Interface, amount coin.Amount, isFee bool, fiat string, timestamp *time.Time,
+) string {
+	ratesUpdater := account.Config().RateUpdater
+	if ratesUpdater == nil || fiat == "" || timestamp == nil {
+		return ""
+	}
+	accountCoin := account.Coin()
+	price := ratesUpdater.HistoricalPriceAt(string(accountCoin.Code()), fiat, *timestamp)
+	// The historical rates can lag behind, see `coin.ConversionsAtTime()`.
+	if price == 0 && time.Since(*timestamp) < 2*time.Hour {
+		price = ratesUpdater.LatestPrice()[accountCoin.Unit(isFee)][fiat]
+	}
+	if price == 0 {
+		return ""
+	}
+	value := new(big.Rat).Mul(coin.ToUnitRat(amount, accountCoin, isFee), new(big.Rat).SetFloat64(price))
+	return coin.FormatAsPlainCurrency(value, fiat)
+}
+
+// loadRatesHistory loads the exchange rates history needed for the fiat values of the
+// transactions, as the fiat currency might not be the active one. Failing to fetch the history,
+// e.g. when offline or if the fiat currency is not supported, is only logged, as the cached or
+// imported rates may already cover the transactions.
+func loadRatesHistory(account Interface, transactions []*TransactionData, fiat string) {
+	ratesUpdater := account.Config().RateUpdater
+	if ratesUpdater == nil || fiat == "" {
+		return
+	}
+	var since, until time.Time
+	for _, transaction := range transactions {
+		if transaction.Timestamp == nil {
+			continue
+		}
+		if since.IsZero() || transaction.Timestamp.Before(since) {
+			since = *transaction.Timestamp
+		}
+		if transaction.Timestamp.After(until) {
+			until = *transaction.Timestamp
+		}
+	}
+	if since.IsZero() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), historyLoadTimeout)
+	defer cancel()
+	coinCode := account.Coin().Code()
+	if err := ratesUpdater.LoadHistory(ctx, string(coinCode), fiat, since, until); err != nil {
+		logging.Get().WithGroup("export").WithError(err).Warningf(
+			"Could not load the %s/%s exchange rates history", coinCode, fiat)
+	}
+}
+
+// NewExportTransactions prepares the transactions of the account for an export. If fiat is empty,
+// the main currency is used. The exchange rates history of the fiat currency is loaded first if
+// possible. The fiat values of the transactions without an exchange rate are left empty.
+func NewExportTransactions(
+	account Interface, transactions []*TransactionData, fiat string,
+) []*ExportTransaction {
+	config := account.Config()
+	if fiat == "" && config.GetMainCurrency != nil {
+		fiat = config.GetMainCurrency()
+	}
+	loadRatesHistory(account, transactions, fiat)
+	accountCoin := account.Coin()
+	result := make([]*ExportTransaction, len(transactions))
+	for i, transaction := range transactions {
+		exportTransaction := &ExportTransaction{
+			AccountCode: string(config.Config.Code),
+			AccountName: config.Config.Name,
+			Transaction: transaction,
+			Amount:      formatDecimal(transaction.Amount, accountCoin, false),
+			Unit:        accountCoin.Unit(false),
+			FiatValue:   fiatValueAt(account, transaction.Amount, false, fiat, transaction.Timestamp),
+			Fiat:        fiat,
+			Note:        account.TxNote(transaction.InternalID),
+		}
+		if transaction.Fee != nil && transaction.Fee.BigInt().Sign() > 0 {
+			exportTransaction.Fee = formatDecimal(*transaction.Fee, accountCoin, true)
+			exportTransaction.FeeUnit = accountCoin.Unit(true)
+			// The rate of a fee paid in another coin, e.g. ETH for ERC20 tokens, is not known here.
+			if !transaction.FeeIsDifferentUnit {
+				exportTransaction.FeeFiatValue = fiatValueAt(
+					account, *transaction.Fee, true, fiat, transaction.Timestamp)
+			}
+		}
+		result[i] = exportTransaction
+	}
+	return result
+}
+
+// exportKind classifies a transaction for the tax tool formats.
+type exportKind int
+
+const (
+	exportKindSkip exportKind = iota
+	exportKindReceive
+	exportKindSend
+	// exportKindFeeOnly is a transaction in which only the fee left the wallet, i.e. a send to self
+	// or a failed send which was mined.
+	exportKindFeeOnly
+)
+
+func (transaction *ExportTransaction) kind() exportKind {
+	tx := transaction.Transaction
+	// Tax tools need the time of the transaction.
+	if tx.Timestamp == nil {
+		return exportKindSkip
+	}
+	switch {
+	case tx.Type == TxTypeReceive && tx.Status != TxStatusFailed:
+		return exportKindReceive
+	case tx.Type == TxTypeSend && tx.Status != TxStatusFailed:
+		return exportKindSend
+	case tx.Type == TxTypeSend || tx.Type == TxTypeSendSelf:
+		if transaction.Fee == "" {
+			return exportKindSkip
+		}
+		return exportKindFeeOnly
+	default:
+		return exportKindSkip
+	}
+}
+
+// exporter is the template of an export format.
+type exporter interface {
+	header() []string
+	// rows returns the rows of one transaction, which can be none.
+	rows(transaction *ExportTransaction) [][]string
+}
+
+var exporters = map[ExportFormat]exporter{
+	ExportFormatKoinly:       koinlyExporter{},
+	ExportFormatCoinTracking: coinTrackingExporter{},
+	ExportFormatUniversal:    universalExporter{},
+}
+
+// WriteExportCSV writes the transactions, which can belong to multiple accounts, in the given
+// format, in the given order. ExportFormatDefault is not supported, use `Interface.ExportCSV()`.
+func WriteExportCSV(w io.Writer, format ExportFormat, transactions []*ExportTransaction) error {
+	exporter, ok := exporters[format]
+	if !ok {
+		return errp.Newf("unsupported export format %q", format)
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(exporter.header()); err != nil {
+		return errp.WithStack(err)
+	}
+	for _, transaction := range transactions {
+		for _, row := range exporter.rows(transaction) {
+			if err := writer.Write(row); err != nil {
+				return errp.WithStack(err)
+			}
+		}
+	}
+	writer.Flush()
+	return errp.WithStack(writer.Error())
+}
+
+// koinlyExporter implements the Koinly universal CSV format. Fees of sends to self and of failed
+// transactions are labeled as costs.
+type koinlyExporter struct{}
+
+func (koinlyExporter) header() []string {
+	return []string{
+		"Date",
+		"Sent Amount",
+		"Sent Currency",
+		"Received Amount",
+		"Received Currency",
+		"Fee Amount",
+		"Fee Currency",
+		"Net Worth Amount",
+		"Net Worth Currency",
+		"Label",
+		"Description",
+		"TxHash",
+	}
+}
+
+func (koinlyExporter) rows(transaction *ExportTransaction) [][]string {
+	kind := transaction.kind()
+	if kind == exportKindSkip {
+		return nil
+	}
+	date := transaction.Transaction.Timestamp.UTC().Format("2006-01-02 15:04:05 UTC")
+	txID := transaction.Transaction.TxID
+	switch kind {
+	case exportKindReceive:
+		return [][]string{{
+			date, "", "", transaction.Amount, transaction.Unit, "", "",
+			transaction.FiatValue, transaction.Fiat, "", transaction.Note, txID,
+		}}
+	case exportKindSend:
+		return [][]string{{
+			date, transaction.Amount, transaction.Unit, "", "", transaction.Fee, transaction.FeeUnit,
+			transaction.FiatValue, transaction.Fiat, "", transaction.Note, txID,
+		}}
+	case exportKindFeeOnly:
+		return [][]string{{
+			date, transaction.Fee, transaction.FeeUnit, "", "", "", "",
+			transaction.FeeFiatValue, transaction.Fiat, "cost", transaction.Note, txID,
+		}}
+	default:
+		return nil
+	}
+}
+
+// coinTrackingExporter implements the CoinTracking CSV import format. Fees of sends to self and of
+// failed transactions are "Other Fee" rows.
+type coinTrackingExporter struct{}
+
+func (coinTrackingExporter) header() []string {
+	return []string{
+		"Type",
+		"Buy Amount",
+		"Buy Currency",
+		"Sell Amount",
+		"Sell Currency",
+		"Fee",
+		"Fee Currency",
+		"Exchange",
+		"Trade-Group",
+		"Comment",
+		"Date",
+		"Tx-ID",
+		"Buy Value in Account Currency",
+		"Sell Value in Account Currency",
+	}
+}
+
+func (coinTrackingExporter) rows(transaction *ExportTransaction) [][]string {
+	kind := transaction.kind()
+	if kind == exportKindSkip {
+		return nil
+	}
+	date := transaction.Transaction.Timestamp.UTC().Format("2006-01-02 15:04:05")
+	txID := transaction.Transaction.TxID
+	switch kind {
+	case exportKindReceive:
+		return [][]string{{
+			"Deposit", transaction.Amount, transaction.Unit, "", "", "", "",
+			"BitBoxApp", transaction.AccountName, transaction.Note, date, txID, transaction.FiatValue, "",
+		}}
+	case exportKindSend:
+		return [][]string{{
+			"Withdrawal", "", "", transaction.Amount, transaction.Unit, transaction.Fee, transaction.FeeUnit,
+			"BitBoxApp", transaction.AccountName, transaction.Note, date, txID, "", transaction.FiatValue,
+		}}
+	case exportKindFeeOnly:
+		return [][]string{{
+			"Other Fee", "", "", transaction.Fee, transaction.FeeUnit, "", "",
+			"BitBoxApp", transaction.AccountName, transaction.Note, date, txID, "", transaction.FeeFiatValue,
+		}}
+	default:
+		return nil
+	}
+}
+
+// universalExporter implements ExportFormatUniversal.
+type universalExporter struct{}
+
+func (universalExporter) header() []string {
+	return []string{
+		"Time",
+		"Account",
+		"Type",
+		"Amount",
+		"Unit",
+		"Fiat Value",
+		"Fiat",
+		"Addresses",
+		"Transaction ID",
+		"Note",
+	}
+}
+
+func (universalExporter) rows(transaction *ExportTransaction) [][]string {
+	tx := transaction.Transaction
+	timeString := ""
+	if tx.Timestamp != nil {
+		timeString = tx.Timestamp.Format(time.RFC3339)
+	}
+	addresses := make([]string, len(tx.Addresses))
+	for i, address := range tx.Addresses {
+		addresses[i] = address.Address
+	}
+	row := func(rowType, amount, unit, fiatValue string) []string {
+		return []string{
+			timeString,
+			transaction.AccountName,
+			rowType,
+			amount,
+			unit,
+			fiatValue,
+			transaction.Fiat,
+			strings.Join(addresses, " "),
+			tx.TxID,
+			transaction.Note,
+		}
+	}
+	rows := [][]string{}
+	if tx.Status != TxStatusFailed {
+		switch tx.Type {
+		case TxTypeReceive:
+			rows = append(rows, row("receive", transaction.Amount, transaction.Unit, transaction.FiatValue))
+		case TxTypeSend:
+			rows = append(rows, row("send", transaction.Amount, transaction.Unit, transaction.FiatValue))
+		case TxTypeSendSelf:
+			rows = append(rows, row("send_to_self", transaction.Amount, transaction.Unit, ""))
+		}
+	}
+	// Like in NewOrderedTransactions, the fee is paid even if the tx failed.
+	if tx.Type != TxTypeReceive && transaction.Fee != "" {
+		rows = append(rows, row("fee", transaction.Fee, transaction.FeeUnit, transaction.FeeFiatValue))
+	}
+	return rows
+}
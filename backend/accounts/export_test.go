@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package accounts
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin/mocks"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/rates"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/logging"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/test"
+	"github.com/stretchr/testify/require"
+)
+
+// exportTestAccount implements the parts of Interface used by NewExportTransactions.
+type exportTestAccount struct {
+	Interface
+	base *BaseAccount
+}
+
+func (account exportTestAccount) Config() *AccountConfig { return account.base.Config() }
+func (account exportTestAccount) Coin() coin.Coin        { return account.base.Coin() }
+func (account exportTestAccount) TxNote(txID string) string {
+	return account.base.TxNote(txID)
+}
+
+func TestExportOptions(t *testing.T) {
+	require.NoError(t, ExportOptions{}.Validate())
+	require.NoError(t, ExportOptions{Format: ExportFormatDefault}.Validate())
+	require.NoError(t, ExportOptions{Format: ExportFormatKoinly, From: 1, To: 1}.Validate())
+	require.Error(t, ExportOptions{Format: "unknown"}.Validate())
+	require.Error(t, ExportOptions{From: 2, To: 1}.Validate())
+
+	time1 := time.Unix(1000, 0)
+	time2 := time.Unix(2000, 0)
+	tx1 := &TransactionData{TxID: "tx1", Timestamp: &time1}
+	tx2 := &TransactionData{TxID: "tx2", Timestamp: &time2}
+	pending := &TransactionData{TxID: "pending"}
+	transactions := []*TransactionData{pending, tx2, tx1}
+
+	require.Equal(t, transactions, ExportOptions{}.Filter(transactions))
+	require.Equal(t, []*TransactionData{tx2, tx1}, ExportOptions{From: 1000}.Filter(transactions))
+	require.Equal(t, []*TransactionData{tx2}, ExportOptions{From: 1001}.Filter(transactions))
+	require.Equal(t, []*TransactionData{tx1}, ExportOptions{To: 1999}.Filter(transactions))
+	require.Equal(t, []*TransactionData{tx2, tx1}, ExportOptions{From: 1000, To: 2000}.Filter(transactions))
+}
+
+func TestWriteExportCSV(t *testing.T) {
+	rateUpdater := rates.MockRateUpdater()
+	defer rateUpdater.Stop()
+
+	mockCoin := &mocks.CoinMock{
+		CodeFunc:     func() coin.Code { return coin.CodeBTC },
+		DecimalsFunc: func(isFee bool) uint { return 8 },
+		UnitFunc:     func(isFee bool) string { return "BTC" },
+	}
+	base := NewBaseAccount(&AccountConfig{
+		Config: &config.Account{
+			Code: "v0-55555555-btc-0",
+			Name: "Bitcoin",
+		},
+		DBFolder:        test.TstTempDir("export_test_dbfolder"),
+		NotesFolder:     test.TstTempDir("export_test_notesfolder"),
+		RateUpdater:     rateUpdater,
+		GetMainCurrency: func() string { return "USD" },
+	}, mockCoin, logging.Get().WithGroup("export_test"))
+	require.NoError(t, base.Initialize("export-test-account"))
+	require.NoError(t, base.SetTxNote("internal-receive", "salary, March"))
+
+	timestamp := time.Unix(1598832062, 0)
+	fee := coin.NewAmountFromInt64(1000)
+	failedFee := coin.NewAmountFromInt64(500)
+	transactions := []*TransactionData{
+		{
+			Type:      TxTypeSend,
+			Status:    TxStatusFailed,
+			TxID:      "failed",
+			Timestamp: &timestamp,
+			Amount:    coin.NewAmountFromInt64(100000),
+			Fee:       &failedFee,
+		},
+		{
+			Type:      TxTypeSendSelf,
+			Status:    TxStatusComplete,
+			TxID:      "self",
+			Timestamp: &timestamp,
+			Amount:    coin.NewAmountFromInt64(2000000),
+			Fee:       &fee,
+		},
+		{
+			Type:      TxTypeSend,
+			Status:    TxStatusComplete,
+			TxID:      "send",
+			Timestamp: &timestamp,
+			Amount:    coin.NewAmountFromInt64(1000000),
+			Fee:       &fee,
+			Addresses: []AddressAndAmount{{Address: "address-1", Amount: coin.NewAmountFromInt64(1000000)}},
+		},
+		{
+			Type:       TxTypeReceive,
+			Status:     TxStatusComplete,
+			TxID:       "receive",
+			InternalID: "internal-receive",
+			Timestamp:  &timestamp,
+			Amount:     coin.NewAmountFromInt64(10000000),
+			Addresses:  []AddressAndAmount{{Address: "address-2", Amount: coin.NewAmountFromInt64(10000000)}},
+		},
+		{
+			Type:   TxTypeReceive,
+			Status: TxStatusPending,
+			TxID:   "pending",
+			Amount: coin.NewAmountFromInt64(1),
+		},
+	}
+	// The fiat values can't be computed in an unsupported currency, but the export still works.
+	unsupportedFiat := NewExportTransactions(exportTestAccount{base: base}, transactions, "XYZ")
+	require.Len(t, unsupportedFiat, len(transactions))
+	for _, exportTransaction := range unsupportedFiat {
+		require.Equal(t, "XYZ", exportTransaction.Fiat)
+		require.Empty(t, exportTransaction.FiatValue)
+		require.Empty(t, exportTransaction.FeeFiatValue)
+	}
+	require.Equal(t, "0.1", unsupportedFiat[3].Amount)
+
+	exportTransactions := NewExportTransactions(exportTestAccount{base: base}, transactions, "")
+	require.Len(t, exportTransactions, len(transactions))
+	receive := exportTransactions[3]
+	require.Equal(t, "0.1", receive.Amount)
+	require.Equal(t, "BTC", receive.Unit)
+	require.Equal(t, "USD", receive.Fiat)
+	require.Equal(t, "0.10", receive.FiatValue)
+	require.Equal(t, "salary, March", receive.Note)
+	require.Equal(t, "Bitcoin", receive.AccountName)
+	require.Empty(t, receive.Fee)
+	send := exportTransactions[2]
+	require.Equal(t, "0.00001", send.Fee)
+	require.Equal(t, "BTC", send.FeeUnit)
+	require.Empty(t, exportTransactions[4].FiatValue)
+
+	// Set the fiat values independently of the mocked rates.
+	for _, transaction := range exportTransactions {
+		if transaction.Transaction.Timestamp != nil {
+			transaction.FiatValue = "100.00"
+			if transaction.Fee != "" {
+				transaction.FeeFiatValue = "0.10"
+			}
+		}
+	}
+
+	export := func(format ExportFormat) string {
+		var result bytes.Buffer
+		require.NoError(t, WriteExportCSV(&result, format, exportTransactions))
+		return result.String()
+	}
+
+	require.Equal(t,
+		`Date,Sent Amount,Sent Currency,Received Amount,Received Currency,Fee Amount,Fee Currency,Net Worth Amount,Net Worth Currency,Label,Description,TxHash
+2020-08-31 00:01:02 UTC,0.000005,BTC,,,,,0.10,USD,cost,,failed
+2020-08-31 00:01:02 UTC,0.00001,BTC,,,,,0.10,USD,cost,,self
+2020-08-31 00:01:02 UTC,0.01,BTC,,,0.00001,BTC,100.00,USD,,,send
+2020-08-31 00:01:02 UTC,,,0.1,BTC,,,100.00,USD,,"salary, March",receive
+`,
+		export(ExportFormatKoinly))
+
+	require.Equal(t,
+		`Type,Buy Amount,Buy Currency,Sell Amount,Sell Currency,Fee,Fee Currency,Exchange,Trade-Group,Comment,Date,Tx-ID,Buy Value in Account Currency,Sell Value in Account Currency
+Other Fee,,,0.000005,BTC,,,BitBoxApp,Bitcoin,,2020-08-31 00:01:02,failed,,0.10
+Other Fee,,,0.00001,BTC,,,BitBoxApp,Bitcoin,,2020-08-31 00:01:02,self,,0.10
+Withdrawal,,,0.01,BTC,0.00001,BTC,BitBoxApp,Bitcoin,,2020-08-31 00:01:02,send,,100.00
+Deposit,0.1,BTC,,,,,BitBoxApp,Bitcoin,"salary, March",2020-08-31 00:01:02,receive,100.00,
+`,
+		export(ExportFormatCoinTracking))
+
+	timeString := timestamp.Format(time.RFC3339)
+	require.Equal(t,
+		`Time,Account,Type,Amount,Unit,Fiat Value,Fiat,Addresses,Transaction ID,Note
+`+timeString+`,Bitcoin,fee,0.000005,BTC,0.10,USD,,failed,
+`+timeString+`,Bitcoin,send_to_self,0.02,BTC,,USD,,self,
+`+timeString+`,Bitcoin,fee,0.00001,BTC,0.10,USD,,self,
+`+timeString+`,Bitcoin,send,0.01,BTC,100.00,USD,address-1,send,
+`+timeString+`,Bitcoin,fee,0.00001,BTC,0.10,USD,address-1,send,
+`+timeString+`,Bitcoin,receive,0.1,BTC,100.00,USD,address-2,receive,"salary, March"
+,Bitcoin,receive,0.00000001,BTC,,USD,,pending,
+`,
+		export(ExportFormatUniversal))
+
+	require.Error(t, WriteExportCSV(&bytes.Buffer{}, ExportFormatDefault, exportTransactions))
+}
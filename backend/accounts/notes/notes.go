@@ -1,6 +1,7 @@
 // SPDX-License-Identifier: Apache-2.0
 
-// Package notes provides functionality to retrieve and store account transaction notes.
+// Package notes provides functionality to retrieve and store account notes, i.e. labels of
+// transactions, addresses, inputs, outputs and public keys, following the label types of BIP-329.
 package notes
 
 import (
@@ -8,6 +9,7 @@ import (
 	"os"
 	"sync"
 
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/atrest"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
 )
 
@@ -16,87 +18,106 @@ const MaxNoteLen = 1024
 
 // Data is the notes JSON data serialized to disk.
 type Data struct {
-	// More fields to be added when we can label more stuff, e.g. receive addresses, utxos, etc.
-
 	// a map of transaction ID to transaction note.
 	TransactionNotes map[string]string `json:"transactions"`
+	// a map of address (as encoded for humans) to address note.
+	AddressNotes map[string]string `json:"addresses,omitempty"`
+	// a map of outpoint (`<txid>:<vout>`) to output note.
+	OutputNotes map[string]string `json:"outputs,omitempty"`
+	// a map of spent outpoint (`<txid>:<vin>`) to input note. Like in BIP-329, the ref of an input
+	// is the txid and input index of the spending transaction.
+	InputNotes map[string]string `json:"inputs,omitempty"`
+	// a map of hex-encoded public key to public key note.
+	PubkeyNotes map[string]string `json:"pubkeys,omitempty"`
+	// the set of outpoints (`<txid>:<vout>`) which are marked as not spendable. Outputs are
+	// spendable by default, so only the exceptions are stored.
+	UnspendableOutputs map[string]bool `json:"unspendableOutputs,omitempty"`
 }
 
 // read deserializes the json files into notes. If the file does not exist yet, no error is
 // returned, and the struct is retruned with default values.
-func read(filename string) (*Data, error) {
-	file, err := os.Open(filename)
+func read(filename string, keyring *atrest.Keyring) (*Data, error) {
+	contents, err := keyring.ReadFile(filename)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return &Data{}, nil
 		}
 		return nil, errp.WithStack(err)
 	}
-	defer file.Close() //nolint:errcheck
 	var notes Data
-	if err := json.NewDecoder(file).Decode(&notes); err != nil {
+	if err := json.Unmarshal(contents, &notes); err != nil {
 		return nil, errp.WithStack(err)
 	}
 	return &notes, nil
 }
 
-func write(data *Data, filename string) error {
-	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+func write(data *Data, filename string, keyring *atrest.Keyring) error {
+	contents, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
-		return err
-	}
-	defer func() { _ = file.Close() }()
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(data); err != nil {
 		return errp.WithStack(err)
 	}
-	return nil
+	return keyring.WriteFile(filename, append(contents, '\n'), 0600)
 }
 
 // Notes is a high level helper for notes, allowing you to read and set notes for transactions.
 type Notes struct {
 	filename string
-	data     *Data
-	dataMu   sync.RWMutex
+	// keyring encrypts the notes file at rest. nil if the notes are stored in plaintext.
+	keyring *atrest.Keyring
+	data    *Data
+	dataMu  sync.RWMutex
 }
 
 // LoadNotes makes a new Notes instance, already pre-loading all notes into RAM. If the file does
 // not exist, no error is returned.  Returns an error for other kinds of file read errors.
 func LoadNotes(filename string) (*Notes, error) {
-	data, err := read(filename)
+	return LoadEncryptedNotes(filename, nil)
+}
+
+// LoadEncryptedNotes is like `LoadNotes()`, but the notes file is read and written with the given
+// keyring, which encrypts it if encryption at rest is enabled.
+func LoadEncryptedNotes(filename string, keyring *atrest.Keyring) (*Notes, error) {
+	data, err := read(filename, keyring)
 	if err != nil {
 		return nil, err
 	}
 	return &Notes{
 		filename: filename,
+		keyring:  keyring,
 		data:     data,
 	}, nil
 }
 
-// SetTxNote stores a note for a transaction. An empty note will result in the entry being deleted
-// (or not written if it didn't exist), since `TxNote()` returns an empty string anyway if there is
-// no note. Returns whether the note was modified.
-func (notes *Notes) SetTxNote(txID string, note string) (bool, error) {
-	notes.dataMu.Lock()
-	defer notes.dataMu.Unlock()
-
+// setNote stores a note in the given map, which is created if needed. An empty note will result
+// in the entry being deleted (or not written if it didn't exist), since not existing entries are
+// returned as `""` anyway. Returns whether the note was modified. The caller must hold the write
+// lock.
+func (notes *Notes) setNote(noteMap *map[string]string, key string, note string) (bool, error) {
 	if len(note) > MaxNoteLen {
 		return false, errp.Newf("Length of note must be smaller than %d. Got %d", MaxNoteLen, len(note))
 	}
 
-	if notes.data.TransactionNotes == nil {
-		notes.data.TransactionNotes = map[string]string{}
+	if *noteMap == nil {
+		*noteMap = map[string]string{}
 	}
-	changed := notes.data.TransactionNotes[txID] != note
+	changed := (*noteMap)[key] != note
 	if note == "" {
 		// Since not existing entries are returned as `""` anyway, there no need to actually store
 		// them in the JSON file.
-		delete(notes.data.TransactionNotes, txID)
+		delete(*noteMap, key)
 	} else {
-		notes.data.TransactionNotes[txID] = note
+		(*noteMap)[key] = note
 	}
-	return changed, write(notes.data, notes.filename)
+	return changed, write(notes.data, notes.filename, notes.keyring)
+}
+
+// SetTxNote stores a note for a transaction. An empty note will result in the entry being deleted
+// (or not written if it didn't exist), since `TxNote()` returns an empty string anyway if there is
+// no note. Returns whether the note was modified.
+func (notes *Notes) SetTxNote(txID string, note string) (bool, error) {
+	notes.dataMu.Lock()
+	defer notes.dataMu.Unlock()
+	return notes.setNote(&notes.data.TransactionNotes, txID, note)
 }
 
 // TxNote fetches a note for a transaction. Returns the empty string if no note was found.
@@ -107,6 +128,87 @@ func (notes *Notes) TxNote(txID string) string {
 	return notes.data.TransactionNotes[txID]
 }
 
+// SetAddressNote stores a note for an address, see `SetTxNote()`.
+func (notes *Notes) SetAddressNote(address string, note string) (bool, error) {
+	notes.dataMu.Lock()
+	defer notes.dataMu.Unlock()
+	return notes.setNote(&notes.data.AddressNotes, address, note)
+}
+
+// AddressNote fetches a note for an address. Returns the empty string if no note was found.
+func (notes *Notes) AddressNote(address string) string {
+	notes.dataMu.RLock()
+	defer notes.dataMu.RUnlock()
+	return notes.data.AddressNotes[address]
+}
+
+// SetOutputNote stores a note for an output identified by `<txid>:<vout>`, see `SetTxNote()`.
+func (notes *Notes) SetOutputNote(outPoint string, note string) (bool, error) {
+	notes.dataMu.Lock()
+	defer notes.dataMu.Unlock()
+	return notes.setNote(&notes.data.OutputNotes, outPoint, note)
+}
+
+// OutputNote fetches a note for an output. Returns the empty string if no note was found.
+func (notes *Notes) OutputNote(outPoint string) string {
+	notes.dataMu.RLock()
+	defer notes.dataMu.RUnlock()
+	return notes.data.OutputNotes[outPoint]
+}
+
+// SetInputNote stores a note for an input identified by `<txid>:<vin>`, see `SetTxNote()`.
+func (notes *Notes) SetInputNote(input string, note string) (bool, error) {
+	notes.dataMu.Lock()
+	defer notes.dataMu.Unlock()
+	return notes.setNote(&notes.data.InputNotes, input, note)
+}
+
+// InputNote fetches a note for an input. Returns the empty string if no note was found.
+func (notes *Notes) InputNote(input string) string {
+	notes.dataMu.RLock()
+	defer notes.dataMu.RUnlock()
+	return notes.data.InputNotes[input]
+}
+
+// SetPubkeyNote stores a note for a hex-encoded public key, see `SetTxNote()`.
+func (notes *Notes) SetPubkeyNote(pubkey string, note string) (bool, error) {
+	notes.dataMu.Lock()
+	defer notes.dataMu.Unlock()
+	return notes.setNote(&notes.data.PubkeyNotes, pubkey, note)
+}
+
+// PubkeyNote fetches a note for a public key. Returns the empty string if no note was found.
+func (notes *Notes) PubkeyNote(pubkey string) string {
+	notes.dataMu.RLock()
+	defer notes.dataMu.RUnlock()
+	return notes.data.PubkeyNotes[pubkey]
+}
+
+// SetOutputSpendable marks an output identified by `<txid>:<vout>` as spendable or not. Returns
+// whether the flag was modified.
+func (notes *Notes) SetOutputSpendable(outPoint string, spendable bool) (bool, error) {
+	notes.dataMu.Lock()
+	defer notes.dataMu.Unlock()
+
+	if notes.data.UnspendableOutputs == nil {
+		notes.data.UnspendableOutputs = map[string]bool{}
+	}
+	changed := notes.data.UnspendableOutputs[outPoint] == spendable
+	if spendable {
+		delete(notes.data.UnspendableOutputs, outPoint)
+	} else {
+		notes.data.UnspendableOutputs[outPoint] = true
+	}
+	return changed, write(notes.data, notes.filename, notes.keyring)
+}
+
+// OutputSpendable returns false if the output was marked as not spendable.
+func (notes *Notes) OutputSpendable(outPoint string) bool {
+	notes.dataMu.RLock()
+	defer notes.dataMu.RUnlock()
+	return !notes.data.UnspendableOutputs[outPoint]
+}
+
 // Data retrieves all stored notes. You must not modify the returned object.
 func (notes *Notes) Data() *Data {
 	notes.dataMu.RLock()
@@ -130,5 +232,36 @@ func (notes *Notes) MergeLegacy(legacy *Notes) error {
 			notes.data.TransactionNotes[txID] = note
 		}
 	}
-	return write(notes.data, notes.filename)
+	return write(notes.data, notes.filename, notes.keyring)
+}
+
+// mergeNotes adds the notes of `other` to the given map, which is created if needed. Existing
+// notes take priority in case of conflict.
+func mergeNotes[T any](noteMap *map[string]T, other map[string]T) {
+	if len(other) == 0 {
+		return
+	}
+	if *noteMap == nil {
+		*noteMap = map[string]T{}
+	}
+	for key, note := range other {
+		if _, ok := (*noteMap)[key]; !ok {
+			(*noteMap)[key] = note
+		}
+	}
+}
+
+// Merge merges the notes of all label types from another notes file, e.g. from a backup.
+// Current notes take priority in case of conflict.
+func (notes *Notes) Merge(other *Data) error {
+	notes.dataMu.Lock()
+	defer notes.dataMu.Unlock()
+
+	mergeNotes(&notes.data.TransactionNotes, other.TransactionNotes)
+	mergeNotes(&notes.data.AddressNotes, other.AddressNotes)
+	mergeNotes(&notes.data.OutputNotes, other.OutputNotes)
+	mergeNotes(&notes.data.InputNotes, other.InputNotes)
+	mergeNotes(&notes.data.PubkeyNotes, other.PubkeyNotes)
+	mergeNotes(&notes.data.UnspendableOutputs, other.UnspendableOutputs)
+	return write(notes.data, notes.filename, notes.keyring)
 }
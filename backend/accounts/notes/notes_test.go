@@ -45,6 +45,59 @@ func TestNotes(t *testing.T) {
 		notes.Data())
 }
 
+func TestLabelTypes(t *testing.T) {
+	filename := test.TstTempFile("account-notes")
+	notes, err := LoadNotes(filename)
+	require.NoError(t, err)
+
+	setters := []func(string, string) (bool, error){
+		notes.SetAddressNote, notes.SetOutputNote, notes.SetInputNote, notes.SetPubkeyNote,
+	}
+	for _, set := range setters {
+		changed, err := set("ref", "note")
+		require.NoError(t, err)
+		require.True(t, changed)
+		changed, err = set("ref", "note")
+		require.NoError(t, err)
+		require.False(t, changed)
+		_, err = set("ref", strings.Repeat("x", 1025))
+		require.Error(t, err)
+	}
+	require.True(t, notes.OutputSpendable("ref"))
+	changed, err := notes.SetOutputSpendable("ref", false)
+	require.NoError(t, err)
+	require.True(t, changed)
+	changed, err = notes.SetOutputSpendable("ref", false)
+	require.NoError(t, err)
+	require.False(t, changed)
+	require.False(t, notes.OutputSpendable("ref"))
+
+	// Reload notes.
+	notes, err = LoadNotes(filename)
+	require.NoError(t, err)
+	require.Equal(t, "note", notes.AddressNote("ref"))
+	require.Equal(t, "note", notes.OutputNote("ref"))
+	require.Equal(t, "note", notes.InputNote("ref"))
+	require.Equal(t, "note", notes.PubkeyNote("ref"))
+	require.Equal(t, "", notes.TxNote("ref"))
+	require.False(t, notes.OutputSpendable("ref"))
+
+	_, err = notes.SetAddressNote("ref", "")
+	require.NoError(t, err)
+	changed, err = notes.SetOutputSpendable("ref", true)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(t,
+		&Data{
+			AddressNotes:       map[string]string{},
+			OutputNotes:        map[string]string{"ref": "note"},
+			InputNotes:         map[string]string{"ref": "note"},
+			PubkeyNotes:        map[string]string{"ref": "note"},
+			UnspendableOutputs: map[string]bool{},
+		},
+		notes.Data())
+}
+
 // TestNotesPersisted checks that notes are persisted.
 func TestNotesPersisted(t *testing.T) {
 	filename := test.TstTempFile("account-notes")
@@ -116,3 +169,31 @@ func TestMergeLegacy(t *testing.T) {
 		},
 		notes.Data())
 }
+
+func TestMerge(t *testing.T) {
+	filename := test.TstTempFile("account-notes")
+	notes, err := LoadNotes(filename)
+	require.NoError(t, err)
+	_, err = notes.SetTxNote("tx-id-1", "note for tx-id-1")
+	require.NoError(t, err)
+	_, err = notes.SetAddressNote("address-1", "note for address-1")
+	require.NoError(t, err)
+
+	require.NoError(t, notes.Merge(&Data{
+		TransactionNotes:   map[string]string{"tx-id-1": "other note", "tx-id-2": "note for tx-id-2"},
+		OutputNotes:        map[string]string{"txid:0": "note for txid:0"},
+		UnspendableOutputs: map[string]bool{"txid:1": true},
+	}))
+	expected := &Data{
+		TransactionNotes:   map[string]string{"tx-id-1": "note for tx-id-1", "tx-id-2": "note for tx-id-2"},
+		AddressNotes:       map[string]string{"address-1": "note for address-1"},
+		OutputNotes:        map[string]string{"txid:0": "note for txid:0"},
+		UnspendableOutputs: map[string]bool{"txid:1": true},
+	}
+	require.Equal(t, expected, notes.Data())
+
+	// Check that the merged notes were persisted.
+	notes, err = LoadNotes(filename)
+	require.NoError(t, err)
+	require.Equal(t, expected, notes.Data())
+}
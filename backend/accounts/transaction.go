@@ -47,6 +47,17 @@ type AddressAndAmount struct {
 	Amount coin.Amount
 	// Ours is true if the address is one of our receive addresses.
 	Ours bool
+	// Contact is the address book contact of the address, or nil if the address is not in the
+	// address book.
+	Contact *AddressBookContact
+}
+
+// AddressBookContact identifies the address book contact of an address.
+type AddressBookContact struct {
+	ID   string
+	Name string
+	// Verified is true if the contact address was verified by a test send or a signed message.
+	Verified bool
 }
 
 // TransactionData holds transaction data to be shown to the user. It is as coin-agnostic as
@@ -266,3 +277,30 @@ func (txs OrderedTransactions) Timeseries(
 	}
 	return result, nil
 }
+
+// BalanceAt returns the balance of the account at the given time, i.e. after all confirmed
+// transactions up to and including this time. Returns `errors.ErrNotAvailable` if timestamp data is
+// missing.
+func (txs OrderedTransactions) BalanceAt(t time.Time) (coin.Amount, error) {
+	timeseries, err := txs.Timeseries(t, t, time.Hour)
+	if err != nil {
+		return coin.Amount{}, err
+	}
+	if len(timeseries) == 0 {
+		return coin.NewAmountFromInt64(0), nil
+	}
+	return timeseries[0].Value, nil
+}
+
+// BalanceAtHeight returns the balance of the account after all confirmed transactions up to and
+// including the given block height, and the timestamp of the latest of these transactions (nil if
+// there is none or if it is not available).
+func (txs OrderedTransactions) BalanceAtHeight(height int) (coin.Amount, *time.Time) {
+	// The transactions are sorted by height, newest first, after the unconfirmed ones.
+	for _, tx := range txs {
+		if tx.isConfirmed() && tx.Height <= height {
+			return tx.Balance, tx.Timestamp
+		}
+	}
+	return coin.NewAmountFromInt64(0), nil
+}
@@ -154,6 +154,25 @@ func TestOrderedTransactions(t *testing.T) {
 			Value: coin.NewAmountFromInt64(589),
 		},
 	}, timeseries)
+
+	balance, err := ordered.BalanceAt(time.Date(2020, 9, 9, 13, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, coin.NewAmountFromInt64(0), balance)
+	balance, err = ordered.BalanceAt(time.Date(2020, 9, 15, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, coin.NewAmountFromInt64(290), balance)
+	balance, err = ordered.BalanceAt(time.Date(2020, 12, 31, 23, 59, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, coin.NewAmountFromInt64(584), balance)
+
+	balance, timestamp := ordered.BalanceAtHeight(9)
+	require.Equal(t, coin.NewAmountFromInt64(0), balance)
+	require.Nil(t, timestamp)
+	balance, timestamp = ordered.BalanceAtHeight(14)
+	require.Equal(t, coin.NewAmountFromInt64(190), balance)
+	require.Equal(t, time.Date(2020, 9, 11, 12, 0, 0, 0, time.UTC), *timestamp)
+	balance, _ = ordered.BalanceAtHeight(1000)
+	require.Equal(t, coin.NewAmountFromInt64(584), balance)
 }
 
 // TestOrderedTransactionsWithFailedTransactions tests that the cumulative balance takes into
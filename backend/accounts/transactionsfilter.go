@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package accounts
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+// TransactionsFilter selects the transactions returned by a transactions query. Zero values do
+// not filter.
+type TransactionsFilter struct {
+	// From and To restrict the transactions to this time range (inclusive), as unix timestamps in
+	// seconds. Transactions without a timestamp, e.g. unconfirmed ones, are excluded if a limit is
+	// set.
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+	// Types and Statuses are the allowed transaction types and statuses.
+	Types    []TxType   `json:"types"`
+	Statuses []TxStatus `json:"statuses"`
+	// MinAmount and MaxAmount restrict the amount (inclusive), as decimal amounts in the unit of the
+	// coin of each account, e.g. "0.001".
+	MinAmount string `json:"minAmount"`
+	MaxAmount string `json:"maxAmount"`
+	// Address matches transactions with an address containing this text, case-insensitive.
+	Address string `json:"address"`
+	// Note matches transactions with a note containing this text, case-insensitive.
+	Note string `json:"note"`
+	// MinConfirmations is the minimum number of confirmations.
+	MinConfirmations int `json:"minConfirmations"`
+}
+
+// Validate returns an error if the time range or the number of confirmations is invalid.
+func (filter TransactionsFilter) Validate() error {
+	if filter.From != 0 && filter.To != 0 && filter.From > filter.To {
+		return errp.New("the start of the time range is after its end")
+	}
+	if filter.MinConfirmations < 0 {
+		return errp.New("the minimum number of confirmations must not be negative")
+	}
+	return nil
+}
+
+// parseAmount parses an amount limit of the filter. Returns nil if the limit is not set.
+func parseAmount(accountCoin coin.Coin, amount string) (*coin.Amount, error) {
+	if amount == "" {
+		return nil, nil
+	}
+	parsed, err := accountCoin.ParseAmount(amount)
+	if err != nil {
+		return nil, errp.WithMessage(err, "invalid amount")
+	}
+	return &parsed, nil
+}
+
+// Matcher returns a function which returns true if a transaction of the account matches the
+// filter. Zero amount ERC20 transactions never match, like in the transactions list, as they are
+// used in address poisoning attacks.
+func (filter TransactionsFilter) Matcher(account Interface) (func(transaction *TransactionData) bool, error) {
+	minAmount, err := parseAmount(account.Coin(), filter.MinAmount)
+	if err != nil {
+		return nil, err
+	}
+	maxAmount, err := parseAmount(account.Coin(), filter.MaxAmount)
+	if err != nil {
+		return nil, err
+	}
+	address := strings.ToLower(filter.Address)
+	note := strings.ToLower(filter.Note)
+
+	matches := func(transaction *TransactionData) bool {
+		if transaction.IsErc20 && transaction.Amount.BigInt().Sign() == 0 {
+			return false
+		}
+		if filter.From != 0 || filter.To != 0 {
+			if transaction.Timestamp == nil {
+				return false
+			}
+			if filter.From != 0 && transaction.Timestamp.Before(time.Unix(filter.From, 0)) {
+				return false
+			}
+			if filter.To != 0 && transaction.Timestamp.After(time.Unix(filter.To, 0)) {
+				return false
+			}
+		}
+		if len(filter.Types) != 0 && !slices.Contains(filter.Types, transaction.Type) {
+			return false
+		}
+		if len(filter.Statuses) != 0 && !slices.Contains(filter.Statuses, transaction.Status) {
+			return false
+		}
+		if minAmount != nil && transaction.Amount.BigInt().Cmp(minAmount.BigInt()) < 0 {
+			return false
+		}
+		if maxAmount != nil && transaction.Amount.BigInt().Cmp(maxAmount.BigInt()) > 0 {
+			return false
+		}
+		if transaction.NumConfirmations < filter.MinConfirmations {
+			return false
+		}
+		if address != "" {
+			found := false
+			for _, addressAndAmount := range transaction.Addresses {
+				if strings.Contains(strings.ToLower(addressAndAmount.Address), address) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		if note != "" && !strings.Contains(strings.ToLower(account.TxNote(transaction.InternalID)), note) {
+			return false
+		}
+		return true
+	}
+	return matches, nil
+}
+
+// Filter returns the transactions of the account which match the filter, keeping their order. See
+// `Matcher()`.
+func (filter TransactionsFilter) Filter(account Interface, transactions []*TransactionData) ([]*TransactionData, error) {
+	matches, err := filter.Matcher(account)
+	if err != nil {
+		return nil, err
+	}
+	result := []*TransactionData{}
+	for _, transaction := range transactions {
+		if matches(transaction) {
+			result = append(result, transaction)
+		}
+	}
+	return result, nil
+}
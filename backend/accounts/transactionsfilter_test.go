@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package accounts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin/mocks"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/logging"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionsFilter(t *testing.T) {
+	require.NoError(t, TransactionsFilter{}.Validate())
+	require.NoError(t, TransactionsFilter{From: 1, To: 1}.Validate())
+	require.Error(t, TransactionsFilter{From: 2, To: 1}.Validate())
+	require.Error(t, TransactionsFilter{MinConfirmations: -1}.Validate())
+
+	mockCoin := &mocks.CoinMock{
+		ParseAmountFunc: func(amount string) (coin.Amount, error) {
+			if amount == "1" {
+				return coin.NewAmountFromInt64(100), nil
+			}
+			return coin.Amount{}, errp.New("invalid")
+		},
+	}
+	base := NewBaseAccount(&AccountConfig{
+		Config:      &config.Account{Code: "test"},
+		DBFolder:    test.TstTempDir("transactionsfilter_test_dbfolder"),
+		NotesFolder: test.TstTempDir("transactionsfilter_test_notesfolder"),
+	}, mockCoin, logging.Get().WithGroup("transactionsfilter_test"))
+	require.NoError(t, base.Initialize("transactionsfilter-test-account"))
+	account := exportTestAccount{base: base}
+	require.NoError(t, base.SetTxNote("large", "Rent"))
+
+	timestamp := time.Unix(1000, 0)
+	large := &TransactionData{InternalID: "large", Amount: coin.NewAmountFromInt64(100), Timestamp: &timestamp}
+	small := &TransactionData{InternalID: "small", Amount: coin.NewAmountFromInt64(99)}
+	poisoned := &TransactionData{InternalID: "poisoned", Amount: coin.NewAmountFromInt64(0), IsErc20: true}
+	transactions := []*TransactionData{large, small, poisoned}
+
+	filtered, err := TransactionsFilter{}.Filter(account, transactions)
+	require.NoError(t, err)
+	require.Equal(t, []*TransactionData{large, small}, filtered)
+
+	filtered, err = TransactionsFilter{MinAmount: "1"}.Filter(account, transactions)
+	require.NoError(t, err)
+	require.Equal(t, []*TransactionData{large}, filtered)
+
+	filtered, err = TransactionsFilter{Note: "RENT"}.Filter(account, transactions)
+	require.NoError(t, err)
+	require.Equal(t, []*TransactionData{large}, filtered)
+
+	filtered, err = TransactionsFilter{To: 1000}.Filter(account, transactions)
+	require.NoError(t, err)
+	require.Equal(t, []*TransactionData{large}, filtered)
+
+	_, err = TransactionsFilter{MaxAmount: "invalid"}.Filter(account, transactions)
+	require.Error(t, err)
+}
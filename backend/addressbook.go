@@ -0,0 +1,374 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	accountsTypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/addressbook"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc"
+	coinpkg "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// contactXpubAddresses is the number of receive addresses of an xpub contact which are matched
+// against transaction addresses.
+const contactXpubAddresses = 100
+
+// addressBookCoin returns the coin under which the contacts of the given coin are stored. ERC20
+// tokens share the addresses of their chain.
+func addressBookCoin(code coinpkg.Code) coinpkg.Code {
+	if chain, _, ok := strings.Cut(string(code), "-erc20-"); ok {
+		return coinpkg.Code(chain)
+	}
+	return code
+}
+
+// deriveContactAddresses implements addressbook.DeriveFunc.
+func (backend *Backend) deriveContactAddresses(coinCode coinpkg.Code, xpub string) ([]string, error) {
+	coin, err := backend.Coin(coinCode)
+	if err != nil {
+		return nil, err
+	}
+	btcCoin, ok := coin.(*btc.Coin)
+	if !ok {
+		return nil, errp.Newf("xpub contacts are not supported for %s", coinCode)
+	}
+	return btcCoin.DeriveReceiveAddresses(xpub, contactXpubAddresses)
+}
+
+// contactFromConfig converts a stored contact.
+func contactFromConfig(contact config.Contact) addressbook.Contact {
+	return addressbook.Contact{
+		ID:                 contact.ID,
+		CoinCode:           contact.CoinCode,
+		Name:               contact.Name,
+		Address:            contact.Address,
+		Xpub:               contact.Xpub,
+		DerivedAddresses:   contact.DerivedAddresses,
+		Notes:              contact.Notes,
+		Verified:           contact.Verified,
+		VerificationMethod: addressbook.VerificationMethod(contact.VerificationMethod),
+		VerifiedAt:         contact.VerifiedAt,
+		Created:            contact.Created,
+	}
+}
+
+// configContact converts a contact for storing it.
+func configContact(contact addressbook.Contact) config.Contact {
+	return config.Contact{
+		ID:                 contact.ID,
+		CoinCode:           contact.CoinCode,
+		Name:               contact.Name,
+		Address:            contact.Address,
+		Xpub:               contact.Xpub,
+		DerivedAddresses:   contact.DerivedAddresses,
+		Notes:              contact.Notes,
+		Verified:           contact.Verified,
+		VerificationMethod: string(contact.VerificationMethod),
+		VerifiedAt:         contact.VerifiedAt,
+		Created:            contact.Created,
+	}
+}
+
+// storedContacts returns the contacts of the accounts config.
+func (backend *Backend) storedContacts() []addressbook.Contact {
+	contacts := []addressbook.Contact{}
+	for _, contact := range backend.config.AccountsConfig().AddressBook {
+		contacts = append(contacts, contactFromConfig(contact))
+	}
+	return contacts
+}
+
+// addressBook returns the address book of the configured contacts. It is cached until the
+// contacts are modified.
+func (backend *Backend) addressBook() *addressbook.AddressBook {
+	defer backend.addressBookLock.Lock()()
+	if backend.addressBookCache == nil {
+		backend.addressBookCache = addressbook.NewAddressBook(
+			backend.storedContacts(), backend.deriveContactAddresses)
+	}
+	return backend.addressBookCache
+}
+
+// modifyAddressBook modifies the stored contacts and resets the cached address book.
+func (backend *Backend) modifyAddressBook(f func(contacts []addressbook.Contact) ([]addressbook.Contact, error)) error {
+	defer backend.addressBookLock.Lock()()
+	backend.addressBookCache = nil
+	backend.resetTransactionsQueryCache(nil)
+	return backend.config.ModifyAccountsConfig(func(accountsConfig *config.AccountsConfig) error {
+		contacts := []addressbook.Contact{}
+		for _, contact := range accountsConfig.AddressBook {
+			contacts = append(contacts, contactFromConfig(contact))
+		}
+		contacts, err := f(contacts)
+		if err != nil {
+			return err
+		}
+		accountsConfig.AddressBook = nil
+		for _, contact := range contacts {
+			accountsConfig.AddressBook = append(accountsConfig.AddressBook, configContact(contact))
+		}
+		return nil
+	})
+}
+
+// accountContact converts a contact to the type used in accounts.
+func accountContact(contact *addressbook.Contact) *accounts.AddressBookContact {
+	return &accounts.AddressBookContact{
+		ID:       contact.ID,
+		Name:     contact.Name,
+		Verified: contact.Verified,
+	}
+}
+
+// lookupContactFunc returns the `LookupContact` callback of an account of the given coin.
+func (backend *Backend) lookupContactFunc(code coinpkg.Code) func(string) *accounts.AddressBookContact {
+	coinCode := addressBookCoin(code)
+	return func(address string) *accounts.AddressBookContact {
+		contact := backend.addressBook().Lookup(coinCode, address)
+		if contact == nil {
+			return nil
+		}
+		return accountContact(contact)
+	}
+}
+
+// resemblingContactsFunc returns the `ResemblingContacts` callback of an account of the given coin.
+func (backend *Backend) resemblingContactsFunc(code coinpkg.Code) func(string) []*accounts.AddressBookContact {
+	coinCode := addressBookCoin(code)
+	return func(address string) []*accounts.AddressBookContact {
+		result := []*accounts.AddressBookContact{}
+		for _, contact := range backend.addressBook().Resembling(coinCode, address) {
+			result = append(result, accountContact(&contact))
+		}
+		return result
+	}
+}
+
+// validateContact checks the contact fields and that its address or xpub is valid for its coin.
+// The receive addresses of an xpub contact are derived and stored in the contact.
+func (backend *Backend) validateContact(contact *addressbook.Contact) error {
+	contact.Name = strings.TrimSpace(contact.Name)
+	contact.Address = strings.TrimSpace(contact.Address)
+	contact.Xpub = strings.TrimSpace(contact.Xpub)
+	contact.DerivedAddresses = nil
+	if err := contact.Validate(); err != nil {
+		return err
+	}
+	if addressBookCoin(contact.CoinCode) != contact.CoinCode {
+		return errp.Newf("contacts of %s are stored as contacts of its chain", contact.CoinCode)
+	}
+	coin, err := backend.Coin(contact.CoinCode)
+	if err != nil {
+		return err
+	}
+	switch specificCoin := coin.(type) {
+	case *btc.Coin:
+		if contact.Xpub != "" {
+			derived, err := addressbook.DeriveAddresses(
+				backend.deriveContactAddresses, contact.CoinCode, contact.Xpub)
+			if err != nil {
+				return errp.WithMessage(err, "invalid xpub")
+			}
+			contact.DerivedAddresses = derived
+			return nil
+		}
+		if _, err := specificCoin.AddressToPkScript(contact.Address); err != nil {
+			return errp.WithMessage(err, "invalid address")
+		}
+	default:
+		if contact.Xpub != "" {
+			return errp.Newf("xpub contacts are not supported for %s", contact.CoinCode)
+		}
+		if !common.IsHexAddress(contact.Address) {
+			return errp.New("invalid address")
+		}
+	}
+	return nil
+}
+
+// AddressBook returns the contacts of the given coin, or all contacts if coinCode is empty.
+func (backend *Backend) AddressBook(coinCode coinpkg.Code) []addressbook.Contact {
+	if coinCode != "" {
+		coinCode = addressBookCoin(coinCode)
+	}
+	return backend.addressBook().Contacts(coinCode)
+}
+
+// AddContact validates and stores a new contact. A new ID is assigned to it, and it is not
+// verified. Returns the stored contact.
+func (backend *Backend) AddContact(contact addressbook.Contact) (*addressbook.Contact, error) {
+	if err := backend.validateContact(&contact); err != nil {
+		return nil, err
+	}
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	contact.ID = hex.EncodeToString(id[:])
+	contact.Verified = false
+	contact.VerificationMethod = ""
+	contact.VerifiedAt = nil
+	contact.Created = time.Now()
+	err := backend.modifyAddressBook(func(contacts []addressbook.Contact) ([]addressbook.Contact, error) {
+		return append(contacts, contact), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// UpdateContact updates the name, address, xpub and notes of the contact with the given ID.
+// Changing the address or xpub resets the verification. Returns the stored contact.
+func (backend *Backend) UpdateContact(contact addressbook.Contact) (*addressbook.Contact, error) {
+	var updated *addressbook.Contact
+	err := backend.modifyAddressBook(func(contacts []addressbook.Contact) ([]addressbook.Contact, error) {
+		for i := range contacts {
+			stored := &contacts[i]
+			if stored.ID != contact.ID {
+				continue
+			}
+			contact.CoinCode = stored.CoinCode
+			if err := backend.validateContact(&contact); err != nil {
+				return nil, err
+			}
+			if contact.Address != stored.Address || contact.Xpub != stored.Xpub {
+				stored.Verified = false
+				stored.VerificationMethod = ""
+				stored.VerifiedAt = nil
+			}
+			stored.Name = contact.Name
+			stored.Address = contact.Address
+			stored.Xpub = contact.Xpub
+			stored.DerivedAddresses = contact.DerivedAddresses
+			stored.Notes = contact.Notes
+			result := *stored
+			updated = &result
+			return contacts, nil
+		}
+		return nil, errp.Newf("contact %s not found", contact.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteContact deletes the contact with the given ID.
+func (backend *Backend) DeleteContact(id string) error {
+	return backend.modifyAddressBook(func(contacts []addressbook.Contact) ([]addressbook.Contact, error) {
+		for i, contact := range contacts {
+			if contact.ID == id {
+				return append(contacts[:i:i], contacts[i+1:]...), nil
+			}
+		}
+		return nil, errp.Newf("contact %s not found", id)
+	})
+}
+
+// contactByID returns a copy of the contact with the given ID.
+func (backend *Backend) contactByID(id string) (*addressbook.Contact, error) {
+	for _, contact := range backend.storedContacts() {
+		if contact.ID == id {
+			return &contact, nil
+		}
+	}
+	return nil, errp.Newf("contact %s not found", id)
+}
+
+// setContactVerified marks the contact as verified by the given method.
+func (backend *Backend) setContactVerified(id string, method addressbook.VerificationMethod) error {
+	return backend.modifyAddressBook(func(contacts []addressbook.Contact) ([]addressbook.Contact, error) {
+		for i := range contacts {
+			if contacts[i].ID == id {
+				now := time.Now()
+				contacts[i].Verified = true
+				contacts[i].VerificationMethod = method
+				contacts[i].VerifiedAt = &now
+				return contacts, nil
+			}
+		}
+		return nil, errp.Newf("contact %s not found", id)
+	})
+}
+
+// VerifyContactTestSend marks the contact as verified after the user confirmed with the contact
+// that a small test payment was received. The payment is the transaction with the given ID in the
+// given account, which must send to an address of the contact.
+func (backend *Backend) VerifyContactTestSend(id string, accountCode accountsTypes.Code, txID string) error {
+	contact, err := backend.contactByID(id)
+	if err != nil {
+		return err
+	}
+	account := backend.Accounts().lookup(accountCode)
+	if account == nil {
+		return errp.Newf("unknown account %s", accountCode)
+	}
+	if addressBookCoin(account.Coin().Code()) != contact.CoinCode {
+		return errp.New("the account does not match the coin of the contact")
+	}
+	if err := account.Initialize(); err != nil {
+		return err
+	}
+	transactions, err := account.Transactions()
+	if err != nil {
+		return err
+	}
+	book := addressbook.NewAddressBook([]addressbook.Contact{*contact}, backend.deriveContactAddresses)
+	for _, transaction := range transactions {
+		if transaction.TxID != txID && transaction.InternalID != txID {
+			continue
+		}
+		if transaction.Type != accounts.TxTypeSend || transaction.Status == accounts.TxStatusFailed {
+			continue
+		}
+		for _, address := range transaction.Addresses {
+			if book.Lookup(contact.CoinCode, address.Address) != nil {
+				return backend.setContactVerified(id, addressbook.VerificationTestSend)
+			}
+		}
+	}
+	return errp.New("the transaction does not pay to the contact")
+}
+
+// VerifyContactSignedMessage marks the contact as verified if the signature of the message was
+// made by the key of the contact address. Bitcoin and Litecoin signatures are base64-encoded as
+// defined in BIP-137, Ethereum signatures are hex-encoded personal_sign signatures.
+func (backend *Backend) VerifyContactSignedMessage(id string, message string, signature string) error {
+	contact, err := backend.contactByID(id)
+	if err != nil {
+		return err
+	}
+	if contact.Address == "" {
+		return errp.New("only contacts with an address can sign a message")
+	}
+	coin, err := backend.Coin(contact.CoinCode)
+	if err != nil {
+		return err
+	}
+	switch specificCoin := coin.(type) {
+	case *btc.Coin:
+		messagePrefix := "Bitcoin Signed Message:\n"
+		switch contact.CoinCode {
+		case coinpkg.CodeLTC, coinpkg.CodeTLTC:
+			messagePrefix = "Litecoin Signed Message:\n"
+		}
+		err = addressbook.VerifyBTCMessage(
+			specificCoin.Net(), messagePrefix, contact.Address, []byte(message), signature)
+	default:
+		err = addressbook.VerifyETHMessage(contact.Address, []byte(message), signature)
+	}
+	if err != nil {
+		return err
+	}
+	return backend.setContactVerified(id, addressbook.VerificationSignedMessage)
+}
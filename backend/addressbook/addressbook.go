@@ -0,0 +1,248 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package addressbook provides the address book of payment recipients, and the matching of
+// transaction addresses against it.
+package addressbook
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+// MaxNameLen is the maximum length of a contact name.
+const MaxNameLen = 256
+
+// MaxNotesLen is the maximum length of the notes of a contact.
+const MaxNotesLen = 1024
+
+// resemblanceChars is the number of characters at the start or the end of an address which, if
+// equal to those of a contact address, make the addresses look the same at a glance. Address
+// poisoning attacks generate addresses which match the victim's contacts this way.
+const resemblanceChars = 4
+
+// VerificationMethod is how a contact address was verified.
+type VerificationMethod string
+
+const (
+	// VerificationTestSend means a small amount was sent to the contact, who confirmed receiving it.
+	VerificationTestSend VerificationMethod = "testSend"
+	// VerificationSignedMessage means the contact signed a message with the key of the address.
+	VerificationSignedMessage VerificationMethod = "signedMessage"
+)
+
+// Contact is an entry of the address book.
+type Contact struct {
+	ID       string    `json:"id"`
+	CoinCode coin.Code `json:"coinCode"`
+	Name     string    `json:"name"`
+	// Address is the address of the contact. Either Address or Xpub is set.
+	Address string `json:"address,omitempty"`
+	// Xpub is an extended public key of the contact. All receive addresses derived from it belong
+	// to the contact. Only supported for Bitcoin-based coins.
+	Xpub string `json:"xpub,omitempty"`
+	// DerivedAddresses are the receive addresses derived from Xpub, stored when the contact is
+	// saved so they do not need to be derived again.
+	DerivedAddresses []string `json:"derivedAddresses,omitempty"`
+	Notes            string   `json:"notes"`
+	// Verified is true if the contact address was verified, see VerificationMethod. Modifying the
+	// address or xpub resets it.
+	Verified           bool               `json:"verified"`
+	VerificationMethod VerificationMethod `json:"verificationMethod,omitempty"`
+	VerifiedAt         *time.Time         `json:"verifiedAt,omitempty"`
+	Created            time.Time          `json:"created"`
+}
+
+// Validate returns an error if the contact is not valid. The address itself is validated by the
+// caller, as it depends on the coin.
+func (contact *Contact) Validate() error {
+	if contact.CoinCode == "" {
+		return errp.New("contact coin missing")
+	}
+	name := strings.TrimSpace(contact.Name)
+	if name == "" {
+		return errp.New("contact name missing")
+	}
+	if len(name) > MaxNameLen {
+		return errp.Newf("contact name must be shorter than %d characters", MaxNameLen)
+	}
+	if len(contact.Notes) > MaxNotesLen {
+		return errp.Newf("contact notes must be shorter than %d characters", MaxNotesLen)
+	}
+	if (contact.Address == "") == (contact.Xpub == "") {
+		return errp.New("a contact needs either an address or an xpub")
+	}
+	return nil
+}
+
+// bech32Prefixes are the human-readable parts of the bech32 addresses of the supported coins,
+// including the separator.
+var bech32Prefixes = []string{"bc1", "tb1", "bcrt1", "ltc1", "tltc1"}
+
+// NormalizeAddress returns the address in the form used to compare addresses. Ethereum addresses
+// and bech32 addresses are case-insensitive, base58 addresses are not.
+func NormalizeAddress(address string) string {
+	address = strings.TrimSpace(address)
+	lower := strings.ToLower(address)
+	if strings.HasPrefix(lower, "0x") {
+		return lower
+	}
+	for _, prefix := range bech32Prefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return lower
+		}
+	}
+	return address
+}
+
+// significantPart strips the prefix which is the same for all addresses of a type, e.g. "bc1q" or
+// "0x", so resembling addresses are detected by the characters that actually differ. The address
+// must be normalized.
+func significantPart(address string) string {
+	if strings.HasPrefix(address, "0x") {
+		return address[2:]
+	}
+	for _, prefix := range bech32Prefixes {
+		// Human-readable part, separator and witness version.
+		if strings.HasPrefix(address, prefix) && len(address) > len(prefix) {
+			return address[len(prefix)+1:]
+		}
+	}
+	// base58: version character.
+	if len(address) > 0 {
+		return address[1:]
+	}
+	return address
+}
+
+// Resembles returns true if the two addresses are not the same, but start or end with the same
+// characters, so that they are easily confused.
+func Resembles(address1, address2 string) bool {
+	address1 = NormalizeAddress(address1)
+	address2 = NormalizeAddress(address2)
+	if address1 == address2 {
+		return false
+	}
+	part1 := significantPart(address1)
+	part2 := significantPart(address2)
+	if len(part1) < resemblanceChars || len(part2) < resemblanceChars {
+		return false
+	}
+	return part1[:resemblanceChars] == part2[:resemblanceChars] ||
+		part1[len(part1)-resemblanceChars:] == part2[len(part2)-resemblanceChars:]
+}
+
+// DeriveFunc returns the receive addresses of an xpub contact of the given coin.
+type DeriveFunc func(coinCode coin.Code, xpub string) ([]string, error)
+
+// AddressBook matches addresses against a list of contacts.
+type AddressBook struct {
+	contacts []Contact
+	derive   DeriveFunc
+
+	// derivedAddresses caches the addresses of xpub contacts without stored DerivedAddresses by
+	// contact ID.
+	derivedAddresses   map[string][]string
+	derivedAddressesMu sync.Mutex
+}
+
+// NewAddressBook creates an address book of the given contacts. derive is used to derive the
+// addresses of xpub contacts which do not have their DerivedAddresses stored. If derive is nil,
+// such contacts never match.
+func NewAddressBook(contacts []Contact, derive DeriveFunc) *AddressBook {
+	return &AddressBook{
+		contacts:         contacts,
+		derive:           derive,
+		derivedAddresses: map[string][]string{},
+	}
+}
+
+// addresses returns the normalized addresses of a contact.
+func (book *AddressBook) addresses(contact *Contact) []string {
+	if contact.Address != "" {
+		return []string{NormalizeAddress(contact.Address)}
+	}
+	if len(contact.DerivedAddresses) > 0 {
+		return contact.DerivedAddresses
+	}
+	if book.derive == nil {
+		return nil
+	}
+	book.derivedAddressesMu.Lock()
+	defer book.derivedAddressesMu.Unlock()
+	if derived, ok := book.derivedAddresses[contact.ID]; ok {
+		return derived
+	}
+	derived, err := DeriveAddresses(book.derive, contact.CoinCode, contact.Xpub)
+	if err != nil {
+		// The xpub was validated when the contact was added, so this should not happen. Not
+		// matching the contact is the safe fallback.
+		derived = nil
+	}
+	book.derivedAddresses[contact.ID] = derived
+	return derived
+}
+
+// DeriveAddresses returns the normalized receive addresses of an xpub, to be stored in
+// Contact.DerivedAddresses.
+func DeriveAddresses(derive DeriveFunc, coinCode coin.Code, xpub string) ([]string, error) {
+	derived, err := derive(coinCode, xpub)
+	if err != nil {
+		return nil, err
+	}
+	for i, address := range derived {
+		derived[i] = NormalizeAddress(address)
+	}
+	return derived, nil
+}
+
+// Contacts returns all contacts of the given coin, or all contacts if coinCode is empty.
+func (book *AddressBook) Contacts(coinCode coin.Code) []Contact {
+	result := []Contact{}
+	for _, contact := range book.contacts {
+		if coinCode == "" || contact.CoinCode == coinCode {
+			result = append(result, contact)
+		}
+	}
+	return result
+}
+
+// Lookup returns the contact of the coin with the given address, or nil if there is none.
+func (book *AddressBook) Lookup(coinCode coin.Code, address string) *Contact {
+	address = NormalizeAddress(address)
+	for i := range book.contacts {
+		contact := &book.contacts[i]
+		if contact.CoinCode != coinCode {
+			continue
+		}
+		for _, contactAddress := range book.addresses(contact) {
+			if contactAddress == address {
+				return contact
+			}
+		}
+	}
+	return nil
+}
+
+// Resembling returns the contacts of the coin which have an address that resembles the given
+// address without being equal to it, see `Resembles()`. Contacts which own the address are not
+// included. Only the addresses of contacts with a fixed address are compared, as the derived
+// addresses of xpub contacts are not displayed to the user.
+func (book *AddressBook) Resembling(coinCode coin.Code, address string) []Contact {
+	result := []Contact{}
+	if book.Lookup(coinCode, address) != nil {
+		return result
+	}
+	for _, contact := range book.contacts {
+		if contact.CoinCode != coinCode || contact.Address == "" {
+			continue
+		}
+		if Resembles(contact.Address, address) {
+			result = append(result, contact)
+		}
+	}
+	return result
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package addressbook
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContactValidate(t *testing.T) {
+	valid := Contact{CoinCode: coin.CodeBTC, Name: "Alice", Address: "bc1q..."}
+	require.NoError(t, valid.Validate())
+
+	contact := valid
+	contact.Name = " "
+	require.Error(t, contact.Validate())
+
+	contact = valid
+	contact.CoinCode = ""
+	require.Error(t, contact.Validate())
+
+	contact = valid
+	contact.Xpub = "xpub..."
+	require.Error(t, contact.Validate())
+
+	contact = valid
+	contact.Address = ""
+	require.Error(t, contact.Validate())
+}
+
+func TestResembles(t *testing.T) {
+	const address = "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"
+	require.False(t, Resembles(address, address))
+	require.False(t, Resembles(address, "BC1QCR8TE4KR609GCAWUTMRZA0J4XV80JY8Z306FYU"))
+	// Same characters after the common "bc1q" prefix.
+	require.True(t, Resembles(address, "bc1qcr8tqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq"))
+	// Same suffix.
+	require.True(t, Resembles(address, "bc1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq6fyu"))
+	// Only the common prefix is the same.
+	require.False(t, Resembles(address, "bc1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq"))
+
+	require.True(t, Resembles(
+		"0x3f5CE5FBFe3E9af3971dD833D26bA9b5C936f0bE",
+		"0x3f5cE0000000000000000000000000000000000e",
+	))
+	require.False(t, Resembles(
+		"0x3f5CE5FBFe3E9af3971dD833D26bA9b5C936f0bE",
+		"0x3F5CE5FBFE3E9AF3971DD833D26BA9B5C936F0BE",
+	))
+	require.True(t, Resembles(
+		"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+		"1BvBMqqqqqqqqqqqqqqqqqqqqqqqqqqqqq",
+	))
+	require.False(t, Resembles("bc1qab", "bc1qab"))
+}
+
+func TestAddressBook(t *testing.T) {
+	alice := Contact{ID: "alice", CoinCode: coin.CodeBTC, Name: "Alice", Address: "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"}
+	bob := Contact{ID: "bob", CoinCode: coin.CodeBTC, Name: "Bob", Xpub: "bob-xpub"}
+	carol := Contact{ID: "carol", CoinCode: coin.CodeETH, Name: "Carol", Address: "0x3f5CE5FBFe3E9af3971dD833D26bA9b5C936f0bE"}
+	deriveCalls := 0
+	derive := func(coinCode coin.Code, xpub string) ([]string, error) {
+		deriveCalls++
+		require.Equal(t, coin.CodeBTC, coinCode)
+		require.Equal(t, "bob-xpub", xpub)
+		return []string{"BC1QNJG0JD8228AQ7EGYZACY8CYS3KNF9XVRERKF9G"}, nil
+	}
+	book := NewAddressBook([]Contact{alice, bob, carol}, derive)
+
+	require.Equal(t, []Contact{alice, bob}, book.Contacts(coin.CodeBTC))
+	require.Equal(t, []Contact{alice, bob, carol}, book.Contacts(""))
+	require.Equal(t, []Contact{}, book.Contacts(coin.CodeLTC))
+
+	require.Equal(t, &alice, book.Lookup(coin.CodeBTC, "BC1QCR8TE4KR609GCAWUTMRZA0J4XV80JY8Z306FYU"))
+	require.Equal(t, &bob, book.Lookup(coin.CodeBTC, "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g"))
+	require.Equal(t, &carol, book.Lookup(coin.CodeETH, "0x3f5ce5fbfe3e9af3971dd833d26ba9b5c936f0be"))
+	require.Nil(t, book.Lookup(coin.CodeLTC, alice.Address))
+	require.Nil(t, book.Lookup(coin.CodeBTC, "bc1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq"))
+	require.Equal(t, 1, deriveCalls)
+
+	poisoned := "bc1qcr8tqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq6fyu"
+	require.Equal(t, []Contact{alice}, book.Resembling(coin.CodeBTC, poisoned))
+	require.Equal(t, []Contact{}, book.Resembling(coin.CodeLTC, poisoned))
+	require.Equal(t, []Contact{}, book.Resembling(coin.CodeBTC, alice.Address))
+	require.Equal(t, []Contact{}, book.Resembling(coin.CodeBTC, "bc1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq"))
+
+	// Without a derive function, xpub contacts never match.
+	require.Nil(t, NewAddressBook([]Contact{bob}, nil).Lookup(
+		coin.CodeBTC, "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g"))
+
+	// Stored derived addresses are used without deriving.
+	bob.DerivedAddresses, _ = DeriveAddresses(derive, coin.CodeBTC, bob.Xpub)
+	require.Equal(t, []string{"bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g"}, bob.DerivedAddresses)
+	require.Equal(t, 2, deriveCalls)
+	require.Equal(t, &bob, NewAddressBook([]Contact{bob}, derive).Lookup(
+		coin.CodeBTC, "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g"))
+	require.Equal(t, 2, deriveCalls)
+}
+
+func TestVerifyBTCMessage(t *testing.T) {
+	const messagePrefix = "Bitcoin Signed Message:\n"
+	privateKey, _ := btcec.PrivKeyFromBytes([]byte("01234567890123456789012345678901"))
+	publicKeyHash := btcutil.Hash160(privateKey.PubKey().SerializeCompressed())
+	p2pkh, err := btcutil.NewAddressPubKeyHash(publicKeyHash, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	p2wpkh, err := btcutil.NewAddressWitnessPubKeyHash(publicKeyHash, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+
+	message := []byte("I am Alice")
+	hash, err := btcMessageHash(messagePrefix, message)
+	require.NoError(t, err)
+	signature := ecdsa.SignCompact(privateKey, hash, true)
+	signatureBase64 := base64.StdEncoding.EncodeToString(signature)
+
+	net := &chaincfg.MainNetParams
+	require.NoError(t, VerifyBTCMessage(net, messagePrefix, p2pkh.EncodeAddress(), message, signatureBase64))
+	require.NoError(t, VerifyBTCMessage(net, messagePrefix, p2wpkh.EncodeAddress(), message, signatureBase64))
+
+	// BIP-137 P2WPKH header.
+	segwitSignature := append([]byte{}, signature...)
+	segwitSignature[0] += 8
+	require.NoError(t, VerifyBTCMessage(net, messagePrefix, p2wpkh.EncodeAddress(), message,
+		base64.StdEncoding.EncodeToString(segwitSignature)))
+
+	require.Error(t, VerifyBTCMessage(net, messagePrefix, p2pkh.EncodeAddress(), []byte("I am Bob"), signatureBase64))
+	require.Error(t, VerifyBTCMessage(net, messagePrefix, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", message, signatureBase64))
+	require.Error(t, VerifyBTCMessage(net, messagePrefix, p2pkh.EncodeAddress(), message, "invalid"))
+}
+
+func TestVerifyETHMessage(t *testing.T) {
+	privateKey, err := crypto.ToECDSA([]byte("01234567890123456789012345678901"))
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+
+	message := []byte("I am Carol")
+	signature, err := crypto.Sign(accounts.TextHash(message), privateKey)
+	require.NoError(t, err)
+	signature[64] += 27
+	signatureHex := "0x" + hex.EncodeToString(signature)
+
+	require.NoError(t, VerifyETHMessage(address, message, signatureHex))
+	require.Error(t, VerifyETHMessage(address, []byte("I am Bob"), signatureHex))
+	require.Error(t, VerifyETHMessage("0x3f5CE5FBFe3E9af3971dD833D26bA9b5C936f0bE", message, signatureHex))
+	require.Error(t, VerifyETHMessage(address, message, "0x1234"))
+}
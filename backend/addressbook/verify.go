@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package addressbook
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// btcMessageHash returns the hash which is signed when signing a message, see BIP-137.
+func btcMessageHash(messagePrefix string, message []byte) ([]byte, error) {
+	var serialized bytes.Buffer
+	if err := wire.WriteVarString(&serialized, 0, messagePrefix); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if err := wire.WriteVarBytes(&serialized, 0, message); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return chainhash.DoubleHashB(serialized.Bytes()), nil
+}
+
+// VerifyBTCMessage checks that a base64-encoded BIP-137 message signature was made by the key of a
+// P2PKH, P2WPKH or P2WPKH-P2SH address. messagePrefix is e.g. "Bitcoin Signed Message:\n".
+func VerifyBTCMessage(
+	net *chaincfg.Params, messagePrefix string, address string, message []byte, signatureBase64 string,
+) error {
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signatureBase64))
+	if err != nil || len(signature) != 65 {
+		return errp.New("invalid signature encoding")
+	}
+	// BIP-137 header bytes: 27-30 P2PKH uncompressed, 31-34 P2PKH compressed, 35-38 P2WPKH-P2SH,
+	// 39-42 P2WPKH. The segwit headers use compressed keys. We check all address types of the key
+	// anyway, as not all wallets use the segwit headers.
+	header := signature[0]
+	switch {
+	case header >= 35 && header <= 38:
+		signature[0] = header - 4
+	case header >= 39 && header <= 42:
+		signature[0] = header - 8
+	}
+	hash, err := btcMessageHash(messagePrefix, message)
+	if err != nil {
+		return err
+	}
+	publicKey, compressed, err := ecdsa.RecoverCompact(signature, hash)
+	if err != nil {
+		return errp.New("invalid signature")
+	}
+	var serializedPublicKey []byte
+	if compressed {
+		serializedPublicKey = publicKey.SerializeCompressed()
+	} else {
+		serializedPublicKey = publicKey.SerializeUncompressed()
+	}
+	publicKeyHash := btcutil.Hash160(serializedPublicKey)
+
+	candidates := []btcutil.Address{}
+	p2pkh, err := btcutil.NewAddressPubKeyHash(publicKeyHash, net)
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	candidates = append(candidates, p2pkh)
+	if compressed {
+		p2wpkh, err := btcutil.NewAddressWitnessPubKeyHash(publicKeyHash, net)
+		if err != nil {
+			return errp.WithStack(err)
+		}
+		redeemScript, err := txscript.PayToAddrScript(p2wpkh)
+		if err != nil {
+			return errp.WithStack(err)
+		}
+		p2shP2wpkh, err := btcutil.NewAddressScriptHash(redeemScript, net)
+		if err != nil {
+			return errp.WithStack(err)
+		}
+		candidates = append(candidates, p2wpkh, p2shP2wpkh)
+	}
+	address = NormalizeAddress(address)
+	for _, candidate := range candidates {
+		if NormalizeAddress(candidate.EncodeAddress()) == address {
+			return nil
+		}
+	}
+	return errp.New("the signature does not match the address")
+}
+
+// VerifyETHMessage checks that a hex-encoded personal_sign (EIP-191) message signature was made by
+// the key of the address.
+func VerifyETHMessage(address string, message []byte, signatureHex string) error {
+	signature, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(signatureHex), "0x"))
+	if err != nil || len(signature) != 65 {
+		return errp.New("invalid signature encoding")
+	}
+	if signature[64] >= 27 {
+		signature[64] -= 27
+	}
+	publicKey, err := crypto.SigToPub(accounts.TextHash(message), signature)
+	if err != nil {
+		return errp.New("invalid signature")
+	}
+	if NormalizeAddress(crypto.PubkeyToAddress(*publicKey).Hex()) != NormalizeAddress(address) {
+		return errp.New("the signature does not match the address")
+	}
+	return nil
+}
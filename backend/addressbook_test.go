@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/addressbook"
+	coinpkg "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	ethaccounts "github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddressBook(t *testing.T) {
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+
+	require.Equal(t, []addressbook.Contact{}, b.AddressBook(""))
+
+	_, err := b.AddContact(addressbook.Contact{CoinCode: coinpkg.CodeBTC, Name: "Alice", Address: "invalid"})
+	require.Error(t, err)
+	_, err = b.AddContact(addressbook.Contact{
+		CoinCode: coinpkg.CodeETH, Name: "Alice", Address: "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu",
+	})
+	require.Error(t, err)
+	_, err = b.AddContact(addressbook.Contact{CoinCode: coinpkg.CodeETH, Name: "Alice", Xpub: "xpub..."})
+	require.Error(t, err)
+	_, err = b.AddContact(addressbook.Contact{CoinCode: coinpkg.CodeBTC, Name: "Alice", Xpub: "invalid"})
+	require.Error(t, err)
+
+	alice, err := b.AddContact(addressbook.Contact{
+		CoinCode: coinpkg.CodeBTC, Name: " Alice ", Address: "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+		Verified: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, alice.ID, 16)
+	require.Equal(t, "Alice", alice.Name)
+	require.False(t, alice.Verified)
+
+	bob, err := b.AddContact(addressbook.Contact{
+		CoinCode: coinpkg.CodeBTC, Name: "Bob",
+		// BIP-84 test vector.
+		Xpub: "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs",
+	})
+	require.NoError(t, err)
+	// The derived addresses are stored with the contact.
+	require.Len(t, bob.DerivedAddresses, contactXpubAddresses)
+	require.Equal(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", bob.DerivedAddresses[0])
+	require.Equal(t, bob.DerivedAddresses, b.config.AccountsConfig().AddressBook[1].DerivedAddresses)
+
+	privateKey, err := crypto.ToECDSA([]byte("01234567890123456789012345678901"))
+	require.NoError(t, err)
+	carol, err := b.AddContact(addressbook.Contact{
+		CoinCode: coinpkg.CodeETH, Name: "Carol", Address: crypto.PubkeyToAddress(privateKey.PublicKey).Hex(),
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []addressbook.Contact{*alice, *bob}, b.AddressBook(coinpkg.CodeBTC))
+	require.Equal(t, []addressbook.Contact{*carol}, b.AddressBook("eth-erc20-usdt"))
+
+	// Account callbacks.
+	lookupBTC := b.lookupContactFunc(coinpkg.CodeBTC)
+	require.Equal(t,
+		&accounts.AddressBookContact{ID: alice.ID, Name: "Alice"},
+		lookupBTC("1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"))
+	require.Equal(t,
+		&accounts.AddressBookContact{ID: bob.ID, Name: "Bob"},
+		lookupBTC("bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g"))
+	require.Nil(t, lookupBTC("bc1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq"))
+	require.Equal(t,
+		&accounts.AddressBookContact{ID: carol.ID, Name: "Carol"},
+		b.lookupContactFunc("eth-erc20-usdt")(carol.Address))
+	require.Equal(t,
+		[]*accounts.AddressBookContact{{ID: alice.ID, Name: "Alice"}},
+		b.resemblingContactsFunc(coinpkg.CodeBTC)("1BvBMqqqqqqqqqqqqqqqqqqqqqqqqqqqqq"))
+
+	// Signed message verification.
+	message := "I am Carol"
+	signature, err := crypto.Sign(ethaccounts.TextHash([]byte(message)), privateKey)
+	require.NoError(t, err)
+	require.Error(t, b.VerifyContactSignedMessage(carol.ID, "I am Bob", hex.EncodeToString(signature)))
+	require.Error(t, b.VerifyContactSignedMessage(bob.ID, message, hex.EncodeToString(signature)))
+	require.NoError(t, b.VerifyContactSignedMessage(carol.ID, message, hex.EncodeToString(signature)))
+	verified := b.AddressBook(coinpkg.CodeETH)[0]
+	require.True(t, verified.Verified)
+	require.Equal(t, addressbook.VerificationSignedMessage, verified.VerificationMethod)
+	require.NotNil(t, verified.VerifiedAt)
+	require.True(t, b.lookupContactFunc(coinpkg.CodeETH)(carol.Address).Verified)
+
+	require.Error(t, b.VerifyContactTestSend(carol.ID, "unknown-account", "txid"))
+
+	// Updating the notes keeps the verification, updating the address resets it.
+	verified.Notes = "met in person"
+	updated, err := b.UpdateContact(verified)
+	require.NoError(t, err)
+	require.True(t, updated.Verified)
+	require.Equal(t, "met in person", updated.Notes)
+	verified.Address = "0x3f5CE5FBFe3E9af3971dD833D26bA9b5C936f0bE"
+	updated, err = b.UpdateContact(verified)
+	require.NoError(t, err)
+	require.False(t, updated.Verified)
+	require.Nil(t, updated.VerifiedAt)
+	require.Nil(t, b.lookupContactFunc(coinpkg.CodeETH)(carol.Address))
+	verified.Address = "invalid"
+	_, err = b.UpdateContact(verified)
+	require.Error(t, err)
+	_, err = b.UpdateContact(addressbook.Contact{ID: "unknown", Name: "Dave", Address: carol.Address})
+	require.Error(t, err)
+
+	require.NoError(t, b.DeleteContact(alice.ID))
+	require.Error(t, b.DeleteContact(alice.ID))
+	require.Nil(t, lookupBTC("1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"))
+	require.Equal(t, []addressbook.Contact{*bob}, b.AddressBook(coinpkg.CodeBTC))
+}
@@ -0,0 +1,241 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/notes"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/appbackup"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/atrest"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+// AppBackupRestoreMode defines how a restored app backup is combined with the current app state.
+type AppBackupRestoreMode string
+
+const (
+	// AppBackupRestoreMerge adds the accounts, keystores, contacts, price alerts and notes of the
+	// backup which do not exist yet. Existing entries and the settings are kept.
+	AppBackupRestoreMerge AppBackupRestoreMode = "merge"
+	// AppBackupRestoreReplace replaces the accounts, keystores, contacts, price alerts and notes
+	// with the contents of the backup, and restores the portable settings, see
+	// `portableAppConfig()`.
+	AppBackupRestoreReplace AppBackupRestoreMode = "replace"
+)
+
+// appBackupArchive collects the app state contained in an app backup.
+func (backend *Backend) appBackupArchive() (*appbackup.Archive, error) {
+	if backend.keyring.Locked() {
+		return nil, errp.WithStack(atrest.ErrLocked)
+	}
+	archive := &appbackup.Archive{
+		Created:        time.Now(),
+		AppConfig:      backend.config.AppConfig(),
+		AccountsConfig: backend.config.AccountsConfig(),
+		Notes:          map[string]string{},
+	}
+	notesDir := backend.arguments.NotesDirectoryPath()
+	entries, err := os.ReadDir(notesDir)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !appbackup.IsNotesFile(entry.Name()) {
+			continue
+		}
+		contents, err := backend.keyring.ReadFile(filepath.Join(notesDir, entry.Name()))
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		archive.Notes[entry.Name()] = string(contents)
+	}
+	return archive, nil
+}
+
+// ExportAppBackup exports the app config, the accounts config, including the address book, and the
+// notes to a file encrypted with the given password.
+func (backend *Backend) ExportAppBackup(password string) error {
+	archive, err := backend.appBackupArchive()
+	if err != nil {
+		return err
+	}
+	contents, err := appbackup.Encrypt(archive, password)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-bitboxapp-backup.json", time.Now().Format("2006-01-02-at-15-04-05"))
+	return backend.exportFile(name, func(writer io.Writer) error {
+		_, err := writer.Write(contents)
+		return errp.WithStack(err)
+	})
+}
+
+// mergeAccountsConfig adds the accounts, keystores, contacts and price alerts of the backup which
+// do not exist yet.
+func mergeAccountsConfig(accountsConfig *config.AccountsConfig, restored *config.AccountsConfig) {
+	for _, account := range restored.Accounts {
+		if accountsConfig.Lookup(account.Code) == nil {
+			accountsConfig.Accounts = append(accountsConfig.Accounts, account)
+		}
+	}
+	for _, keystore := range restored.Keystores {
+		if _, err := accountsConfig.LookupKeystore(keystore.RootFingerprint); err != nil {
+			accountsConfig.Keystores = append(accountsConfig.Keystores, keystore)
+		}
+	}
+	for _, contact := range restored.AddressBook {
+		exists := slices.ContainsFunc(accountsConfig.AddressBook, func(existing config.Contact) bool {
+			return existing.ID == contact.ID
+		})
+		if !exists {
+			accountsConfig.AddressBook = append(accountsConfig.AddressBook, contact)
+		}
+	}
+	for _, alert := range restored.PriceAlerts {
+		exists := slices.ContainsFunc(accountsConfig.PriceAlerts, func(existing config.PriceAlert) bool {
+			return existing.ID == alert.ID
+		})
+		if !exists {
+			accountsConfig.PriceAlerts = append(accountsConfig.PriceAlerts, alert)
+		}
+	}
+}
+
+// restoreNotesFile writes a notes file of a backup to the notes directory, encrypted with the
+// keyring if encryption at rest is enabled. If mergeExisting is true and the file exists, the notes
+// of the backup are added to it.
+func restoreNotesFile(path string, contents string, mergeExisting bool, keyring *atrest.Keyring) error {
+	if _, err := os.Stat(path); err != nil || !mergeExisting {
+		return keyring.WriteFile(path, []byte(contents), 0600)
+	}
+	if filepath.Ext(path) == ".json" {
+		var data notes.Data
+		if err := json.Unmarshal([]byte(contents), &data); err != nil {
+			return errp.WithStack(err)
+		}
+		existing, err := notes.LoadEncryptedNotes(path, keyring)
+		if err != nil {
+			return err
+		}
+		return existing.Merge(&data)
+	}
+	// JSON lines: add the lines which do not exist yet.
+	existingContents, err := keyring.ReadFile(path)
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	lines := strings.Split(strings.TrimRight(string(existingContents), "\n"), "\n")
+	for _, line := range strings.Split(contents, "\n") {
+		if line != "" && !slices.Contains(lines, line) {
+			lines = append(lines, line)
+		}
+	}
+	return keyring.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+}
+
+// portableAppConfig returns the current app config with the settings of the backup which are not
+// specific to the machine: the fiat currencies, the language, the unit, the gap limits and the
+// frontend settings. The proxy and the routes of the services, the privacy settings, the
+// authentication, the blockchain servers and the rates sources are kept.
+func portableAppConfig(current *config.AppConfig, restored *config.AppConfig) config.AppConfig {
+	result := *current
+	result.Backend.FiatList = restored.Backend.FiatList
+	result.Backend.MainFiat = restored.Backend.MainFiat
+	result.Backend.UserLanguage = restored.Backend.UserLanguage
+	result.Backend.BtcUnit = restored.Backend.BtcUnit
+	result.Backend.GapLimitReceive = restored.Backend.GapLimitReceive
+	result.Backend.GapLimitChange = restored.Backend.GapLimitChange
+	result.Frontend = restored.Frontend
+	return result
+}
+
+// restoreAppConfig restores the portable settings of a backup, see `portableAppConfig()`. The
+// settings are only restored when replacing the app state.
+func (backend *Backend) restoreAppConfig(restored *config.AppConfig, mode AppBackupRestoreMode) error {
+	if mode != AppBackupRestoreReplace {
+		return nil
+	}
+	return backend.config.ModifyAppConfig(func(appConfig *config.AppConfig) error {
+		*appConfig = portableAppConfig(appConfig, restored)
+		return nil
+	})
+}
+
+// restoreAccountsConfig restores the accounts config of a backup, including the address book and
+// the price alerts, and resets the cached address book.
+func (backend *Backend) restoreAccountsConfig(restored *config.AccountsConfig, mode AppBackupRestoreMode) error {
+	defer backend.addressBookLock.Lock()()
+	backend.addressBookCache = nil
+	backend.resetTransactionsQueryCache(nil)
+	return backend.config.ModifyAccountsConfig(func(accountsConfig *config.AccountsConfig) error {
+		if mode == AppBackupRestoreReplace {
+			*accountsConfig = *restored
+			return nil
+		}
+		mergeAccountsConfig(accountsConfig, restored)
+		return nil
+	})
+}
+
+// RestoreAppBackup decrypts an app backup exported with `ExportAppBackup()` and restores its
+// contents, see AppBackupRestoreMode. The accounts are closed while restoring, so that they do not
+// overwrite the restored notes, and are initialized again afterwards.
+func (backend *Backend) RestoreAppBackup(contents []byte, password string, mode AppBackupRestoreMode) error {
+	if mode != AppBackupRestoreMerge && mode != AppBackupRestoreReplace {
+		return errp.Newf("unsupported restore mode %q", mode)
+	}
+	if backend.keyring.Locked() {
+		return errp.WithStack(atrest.ErrLocked)
+	}
+	archive, err := appbackup.Decrypt(contents, password)
+	if err != nil {
+		return err
+	}
+	backend.log.Infof("Restoring app backup created at %s (mode: %s)", archive.Created, mode)
+
+	defer backend.accountsAndKeystoreLock.Lock()()
+	backend.uninitAccounts(true)
+	defer backend.initAccounts(true)
+
+	err = backend.restoreAppConfig(&archive.AppConfig, mode)
+	if err != nil {
+		return err
+	}
+	if err := backend.restoreAccountsConfig(&archive.AccountsConfig, mode); err != nil {
+		return err
+	}
+
+	notesDir := backend.arguments.NotesDirectoryPath()
+	if mode == AppBackupRestoreReplace {
+		entries, err := os.ReadDir(notesDir)
+		if err != nil {
+			return errp.WithStack(err)
+		}
+		for _, entry := range entries {
+			if _, ok := archive.Notes[entry.Name()]; ok {
+				continue
+			}
+			if entry.Type().IsRegular() && appbackup.IsNotesFile(entry.Name()) {
+				if err := os.Remove(filepath.Join(notesDir, entry.Name())); err != nil {
+					return errp.WithStack(err)
+				}
+			}
+		}
+	}
+	for filename, notesContents := range archive.Notes {
+		path := filepath.Join(notesDir, filename)
+		if err := restoreNotesFile(path, notesContents, mode == AppBackupRestoreMerge, backend.keyring); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package appbackup provides the password-encrypted archive of the app state, used to move the app
+// to another machine. It contains the app config, including the address book, the accounts config
+// and the notes. Caches are not included, as they are rebuilt from the network.
+package appbackup
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/notes"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// format identifies app backup files.
+	format = "bitboxapp-backup"
+	// version is the version of the file format.
+	version = 1
+	// cipherName is the AEAD used to encrypt the archive.
+	cipherName = "xchacha20poly1305"
+	// kdfName is the function deriving the encryption key from the password.
+	kdfName = "scrypt"
+
+	// MinPasswordLen is the minimum length of a backup password.
+	MinPasswordLen = 8
+
+	// scrypt parameters of new backups, as recommended for file encryption in 2017.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+	// maxScryptN and maxScryptRP limit the work of decrypting a backup with manipulated
+	// parameters.
+	maxScryptN  = 1 << 20
+	maxScryptRP = 32
+)
+
+const (
+	// ErrWrongPassword is returned if a backup can't be decrypted with the given password, or if
+	// it was modified.
+	ErrWrongPassword errp.ErrorCode = "wrongPassword"
+	// ErrPasswordTooShort is returned if the password of a new backup is shorter than
+	// MinPasswordLen.
+	ErrPasswordTooShort errp.ErrorCode = "passwordTooShort"
+)
+
+// Archive is the app state contained in a backup.
+type Archive struct {
+	Created        time.Time             `json:"created"`
+	AppConfig      config.AppConfig      `json:"appConfig"`
+	AccountsConfig config.AccountsConfig `json:"accountsConfig"`
+	// Notes contains the files of the notes directory by filename.
+	Notes map[string]string `json:"notes"`
+}
+
+// IsNotesFile returns true if the file of the notes directory with the given name belongs into a
+// backup: the notes of the accounts (.json) and the preserved BIP-329 labels (.jsonl).
+func IsNotesFile(filename string) bool {
+	if filename != filepath.Base(filename) || strings.HasPrefix(filename, ".") {
+		return false
+	}
+	extension := filepath.Ext(filename)
+	return extension == ".json" || extension == ".jsonl"
+}
+
+// Validate returns an error if the archive contains invalid accounts or notes.
+func (archive *Archive) Validate() error {
+	codes := map[string]struct{}{}
+	for _, account := range archive.AccountsConfig.Accounts {
+		if account == nil || account.Code == "" || account.CoinCode == "" {
+			return errp.New("invalid account in backup")
+		}
+		if _, ok := codes[string(account.Code)]; ok {
+			return errp.Newf("duplicate account %s in backup", account.Code)
+		}
+		codes[string(account.Code)] = struct{}{}
+	}
+	for _, keystore := range archive.AccountsConfig.Keystores {
+		if keystore == nil || len(keystore.RootFingerprint) == 0 {
+			return errp.New("invalid keystore in backup")
+		}
+	}
+	for filename, contents := range archive.Notes {
+		if !IsNotesFile(filename) {
+			return errp.Newf("invalid notes file %q in backup", filename)
+		}
+		if filepath.Ext(filename) == ".json" {
+			var data notes.Data
+			if err := json.Unmarshal([]byte(contents), &data); err != nil {
+				return errp.Newf("invalid notes file %q in backup", filename)
+			}
+		}
+	}
+	return nil
+}
+
+// kdfParams are the parameters of the key derivation.
+type kdfParams struct {
+	Name string `json:"name"`
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt []byte `json:"salt"`
+}
+
+// header is the unencrypted part of a backup file. It is authenticated as associated data.
+type header struct {
+	Format  string    `json:"format"`
+	Version int       `json:"version"`
+	Cipher  string    `json:"cipher"`
+	KDF     kdfParams `json:"kdf"`
+}
+
+// file is the encoding of a backup file.
+type file struct {
+	header
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func deriveKey(password string, params kdfParams) ([]byte, error) {
+	key, err := scrypt.Key([]byte(password), params.Salt, params.N, params.R, params.P, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return key, nil
+}
+
+// Encrypt encrypts the archive with a key derived from the password, and returns the contents of
+// the backup file.
+func Encrypt(archive *Archive, password string) ([]byte, error) {
+	if len(password) < MinPasswordLen {
+		return nil, errp.WithStack(ErrPasswordTooShort)
+	}
+	plaintext, err := json.Marshal(archive)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	backupFile := file{
+		header: header{
+			Format:  format,
+			Version: version,
+			Cipher:  cipherName,
+			KDF:     kdfParams{Name: kdfName, N: scryptN, R: scryptR, P: scryptP, Salt: salt},
+		},
+		Nonce: make([]byte, chacha20poly1305.NonceSizeX),
+	}
+	if _, err := rand.Read(backupFile.Nonce); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	key, err := deriveKey(password, backupFile.KDF)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	associatedData, err := json.Marshal(backupFile.header)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	backupFile.Ciphertext = aead.Seal(nil, backupFile.Nonce, plaintext, associatedData)
+	result, err := json.MarshalIndent(backupFile, "", "  ")
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return result, nil
+}
+
+// Decrypt decrypts and validates the contents of a backup file.
+func Decrypt(contents []byte, password string) (*Archive, error) {
+	var backupFile file
+	if err := json.Unmarshal(contents, &backupFile); err != nil || backupFile.Format != format {
+		return nil, errp.New("not an app backup file")
+	}
+	if backupFile.Version != version {
+		return nil, errp.Newf("unsupported backup version %d", backupFile.Version)
+	}
+	params := backupFile.KDF
+	if backupFile.Cipher != cipherName || params.Name != kdfName {
+		return nil, errp.New("unsupported backup encryption")
+	}
+	if params.N > maxScryptN || params.R < 1 || params.P < 1 || params.R*params.P > maxScryptRP {
+		return nil, errp.New("unsupported backup key derivation parameters")
+	}
+	if len(backupFile.Nonce) != chacha20poly1305.NonceSizeX {
+		return nil, errp.New("invalid backup nonce")
+	}
+	key, err := deriveKey(password, params)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	associatedData, err := json.Marshal(backupFile.header)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	plaintext, err := aead.Open(nil, backupFile.Nonce, backupFile.Ciphertext, associatedData)
+	if err != nil {
+		return nil, errp.WithStack(ErrWrongPassword)
+	}
+	var archive Archive
+	if err := json.Unmarshal(plaintext, &archive); err != nil {
+		return nil, errp.WithMessage(err, "invalid backup contents")
+	}
+	if err := archive.Validate(); err != nil {
+		return nil, err
+	}
+	return &archive, nil
+}
@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package appbackup
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/stretchr/testify/require"
+)
+
+const password = "correct horse battery staple"
+
+func testArchive() *Archive {
+	appConfig := config.NewDefaultAppConfig()
+	appConfig.Backend.MainFiat = "CHF"
+	return &Archive{
+		Created:   time.Unix(1700000000, 0).UTC(),
+		AppConfig: appConfig,
+		AccountsConfig: config.AccountsConfig{
+			Accounts: []*config.Account{
+				{Code: "v0-55555555-btc-0", CoinCode: "btc", Name: "Bitcoin"},
+			},
+			Keystores: []*config.Keystore{
+				{RootFingerprint: []byte{0x55, 0x55, 0x55, 0x55}, Name: "My BitBox"},
+			},
+		},
+		Notes: map[string]string{
+			"v0-55555555-btc-0.json": `{"transactions":{"txid":"note"}}`,
+			"bip329-preserved.jsonl": `{"type":"xpub","ref":"xpub","label":"label"}` + "\n",
+		},
+	}
+}
+
+func TestIsNotesFile(t *testing.T) {
+	require.True(t, IsNotesFile("v0-55555555-btc-0.json"))
+	require.True(t, IsNotesFile("bip329-preserved.jsonl"))
+	require.False(t, IsNotesFile("../config.json"))
+	require.False(t, IsNotesFile("dir/notes.json"))
+	require.False(t, IsNotesFile(".hidden.json"))
+	require.False(t, IsNotesFile("notes.txt"))
+	require.False(t, IsNotesFile(""))
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	archive := testArchive()
+	contents, err := Encrypt(archive, password)
+	require.NoError(t, err)
+	require.NotContains(t, string(contents), "note")
+	require.NotContains(t, string(contents), "CHF")
+
+	decrypted, err := Decrypt(contents, password)
+	require.NoError(t, err)
+	require.Equal(t, archive, decrypted)
+
+	// New salt and nonce for each backup.
+	contents2, err := Encrypt(archive, password)
+	require.NoError(t, err)
+	require.NotEqual(t, contents, contents2)
+
+	_, err = Decrypt(contents, "wrong password")
+	require.Equal(t, ErrWrongPassword, errp.Cause(err))
+
+	_, err = Encrypt(archive, "short")
+	require.Equal(t, ErrPasswordTooShort, errp.Cause(err))
+
+	_, err = Decrypt([]byte("not json"), password)
+	require.Error(t, err)
+}
+
+func TestDecryptModified(t *testing.T) {
+	contents, err := Encrypt(testArchive(), password)
+	require.NoError(t, err)
+
+	modify := func(f func(backupFile *file)) []byte {
+		var backupFile file
+		require.NoError(t, json.Unmarshal(contents, &backupFile))
+		f(&backupFile)
+		modified, err := json.Marshal(backupFile)
+		require.NoError(t, err)
+		return modified
+	}
+
+	// The header is authenticated.
+	_, err = Decrypt(modify(func(backupFile *file) { backupFile.KDF.Salt[0] ^= 1 }), password)
+	require.Equal(t, ErrWrongPassword, errp.Cause(err))
+	_, err = Decrypt(modify(func(backupFile *file) { backupFile.Ciphertext[0] ^= 1 }), password)
+	require.Equal(t, ErrWrongPassword, errp.Cause(err))
+
+	for _, f := range []func(backupFile *file){
+		func(backupFile *file) { backupFile.Format = "other" },
+		func(backupFile *file) { backupFile.Version = 2 },
+		func(backupFile *file) { backupFile.Cipher = "aes" },
+		func(backupFile *file) { backupFile.KDF.N = 1 << 30 },
+		func(backupFile *file) { backupFile.KDF.P = 0 },
+		func(backupFile *file) { backupFile.KDF.R = 1024 },
+		func(backupFile *file) { backupFile.Nonce = backupFile.Nonce[:12] },
+	} {
+		_, err := Decrypt(modify(f), password)
+		require.Error(t, err)
+		require.NotEqual(t, ErrWrongPassword, errp.Cause(err))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, testArchive().Validate())
+
+	for _, f := range []func(archive *Archive){
+		func(archive *Archive) { archive.Notes["../config.json"] = "{}" },
+		func(archive *Archive) { archive.Notes["v0-55555555-ltc-0.json"] = "invalid" },
+		func(archive *Archive) {
+			archive.AccountsConfig.Accounts = append(archive.AccountsConfig.Accounts,
+				&config.Account{Code: "v0-55555555-btc-0", CoinCode: "btc"})
+		},
+		func(archive *Archive) {
+			archive.AccountsConfig.Accounts = append(archive.AccountsConfig.Accounts, &config.Account{})
+		},
+		func(archive *Archive) {
+			archive.AccountsConfig.Keystores = append(archive.AccountsConfig.Keystores, &config.Keystore{})
+		},
+	} {
+		archive := testArchive()
+		f(archive)
+		require.Error(t, archive.Validate())
+		// Invalid archives are rejected when decrypting.
+		contents, err := Encrypt(archive, password)
+		require.NoError(t, err)
+		_, err = Decrypt(contents, password)
+		require.Error(t, err)
+	}
+}
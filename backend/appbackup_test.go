@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/notes"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/addressbook"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/appbackup"
+	coinpkg "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreAppBackup(t *testing.T) {
+	const password = "correct horse battery staple"
+
+	// The app to back up.
+	source := newBackend(t, testnetDisabled, regtestDisabled)
+	defer source.Close()
+	require.NoError(t, source.config.ModifyAppConfig(func(appConfig *config.AppConfig) error {
+		appConfig.Backend.MainFiat = "CHF"
+		appConfig.Backend.StrictPrivacy = true
+		appConfig.Backend.Proxy.ProxyAddress = "127.0.0.1:9150"
+		return nil
+	}))
+	alice, err := source.AddContact(addressbook.Contact{
+		CoinCode: coinpkg.CodeBTC, Name: "Alice", Address: "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+	})
+	require.NoError(t, err)
+	source.registerKeystore(makeBitBox02Multi())
+	sourceAccounts := source.config.AccountsConfig().Accounts
+	require.NotEmpty(t, sourceAccounts)
+	notesFilename := string(sourceAccounts[0].Code) + ".json"
+	require.NoError(t, os.WriteFile(
+		filepath.Join(source.arguments.NotesDirectoryPath(), notesFilename),
+		[]byte(`{"transactions":{"txid-1":"source note 1","txid-2":"source note 2"}}`),
+		0600))
+	// Caches are not part of the backup.
+	require.NoError(t, os.WriteFile(
+		filepath.Join(source.arguments.NotesDirectoryPath(), "cache.db"), []byte("cache"), 0600))
+
+	archive, err := source.appBackupArchive()
+	require.NoError(t, err)
+	require.Equal(t, []string{notesFilename}, func() []string {
+		filenames := []string{}
+		for filename := range archive.Notes {
+			filenames = append(filenames, filename)
+		}
+		return filenames
+	}())
+	backup, err := appbackup.Encrypt(archive, password)
+	require.NoError(t, err)
+
+	// The app to restore to.
+	target := newBackend(t, testnetDisabled, regtestDisabled)
+	defer target.Close()
+	require.NoError(t, target.config.ModifyAppConfig(func(appConfig *config.AppConfig) error {
+		appConfig.Backend.MainFiat = "EUR"
+		appConfig.Backend.Proxy.UseProxy = true
+		appConfig.Backend.Proxy.ProxyAddress = "127.0.0.1:9050"
+		return nil
+	}))
+	bob, err := target.AddContact(addressbook.Contact{
+		CoinCode: coinpkg.CodeBTC, Name: "Bob", Address: "1BoatSLRHtKNngkdXEeobR76b53LETtpyT",
+	})
+	require.NoError(t, err)
+	targetNotesDir := target.arguments.NotesDirectoryPath()
+	require.NoError(t, os.WriteFile(
+		filepath.Join(targetNotesDir, notesFilename),
+		[]byte(`{"transactions":{"txid-1":"target note 1"}}`),
+		0600))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(targetNotesDir, "local.json"), []byte(`{"transactions":{}}`), 0600))
+
+	err = target.RestoreAppBackup(backup, "wrong password", AppBackupRestoreMerge)
+	require.Equal(t, appbackup.ErrWrongPassword, errp.Cause(err))
+	require.Error(t, target.RestoreAppBackup(backup, password, "unknown"))
+	contactIDs := func() []string {
+		ids := []string{}
+		for _, contact := range target.AddressBook("") {
+			ids = append(ids, contact.ID)
+		}
+		return ids
+	}
+	require.Equal(t, []string{bob.ID}, contactIDs())
+
+	// Merge keeps the settings and the existing notes, and adds what is missing.
+	require.NoError(t, target.RestoreAppBackup(backup, password, AppBackupRestoreMerge))
+	require.Equal(t, "EUR", target.config.AppConfig().Backend.MainFiat)
+	for _, account := range sourceAccounts {
+		require.NotNil(t, target.config.AccountsConfig().Lookup(account.Code))
+	}
+	require.Equal(t, []string{bob.ID, alice.ID}, contactIDs())
+	restoredNotes, err := notes.LoadNotes(filepath.Join(targetNotesDir, notesFilename))
+	require.NoError(t, err)
+	require.Equal(t, "target note 1", restoredNotes.TxNote("txid-1"))
+	require.Equal(t, "source note 2", restoredNotes.TxNote("txid-2"))
+	require.FileExists(t, filepath.Join(targetNotesDir, "local.json"))
+
+	// Replace restores the state of the backup, except for the network and privacy settings of
+	// this machine.
+	require.NoError(t, target.RestoreAppBackup(backup, password, AppBackupRestoreReplace))
+	require.Equal(t, "CHF", target.config.AppConfig().Backend.MainFiat)
+	require.False(t, target.config.AppConfig().Backend.StrictPrivacy)
+	require.True(t, target.config.AppConfig().Backend.Proxy.UseProxy)
+	require.Equal(t, "127.0.0.1:9050", target.config.AppConfig().Backend.Proxy.ProxyAddress)
+	require.Equal(t, []string{alice.ID}, contactIDs())
+	require.Len(t, target.config.AccountsConfig().Accounts, len(sourceAccounts))
+	for i, account := range target.config.AccountsConfig().Accounts {
+		require.Equal(t, sourceAccounts[i].Code, account.Code)
+	}
+	restoredNotes, err = notes.LoadNotes(filepath.Join(targetNotesDir, notesFilename))
+	require.NoError(t, err)
+	require.Equal(t, "source note 1", restoredNotes.TxNote("txid-1"))
+	require.NoFileExists(t, filepath.Join(targetNotesDir, "local.json"))
+}
@@ -0,0 +1,166 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/appbackup"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/atrest"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/observable"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/observable/action"
+)
+
+const (
+	// atRestKeyringFilename is the name of the keyring file in the main directory. Encryption at
+	// rest is enabled if it exists.
+	atRestKeyringFilename = "encryption.json"
+
+	// ErrAuthRequired is returned when unlocking the encryption at rest before the user
+	// authenticated, if authentication is enabled in the app config.
+	ErrAuthRequired errp.ErrorCode = "authRequired"
+)
+
+// AtRestEncryptionStatus is the state of the encryption at rest of the notes, labels and the
+// accounts config.
+type AtRestEncryptionStatus struct {
+	Enabled bool `json:"enabled"`
+	// Locked is true if the passphrase has to be entered to load the accounts.
+	Locked bool `json:"locked"`
+}
+
+// removeAccountsDBs removes the transaction databases of all accounts from the given directory.
+func removeAccountsDBs(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "account-") {
+			if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+				return errp.WithStack(err)
+			}
+		}
+	}
+	return nil
+}
+
+// AtRestEncryptionStatus returns the state of the encryption at rest.
+func (backend *Backend) AtRestEncryptionStatus() AtRestEncryptionStatus {
+	return AtRestEncryptionStatus{
+		Enabled: backend.keyring.Enabled(),
+		Locked:  backend.keyring.Locked(),
+	}
+}
+
+func (backend *Backend) notifyAtRestEncryptionStatus() {
+	backend.Notify(observable.Event{
+		Subject: "at-rest-encryption",
+		Action:  action.Replace,
+		Object:  backend.AtRestEncryptionStatus(),
+	})
+}
+
+// rewriteAtRestFiles writes the accounts config, the connection log and all notes files again, so
+// that they are encrypted with the current data key, or written in plaintext when disabling the
+// encryption.
+func (backend *Backend) rewriteAtRestFiles() error {
+	if err := backend.config.ModifyAccountsConfig(func(*config.AccountsConfig) error { return nil }); err != nil {
+		return err
+	}
+	if err := backend.connectionLog.Flush(); err != nil {
+		return err
+	}
+	notesDir := backend.arguments.NotesDirectoryPath()
+	entries, err := os.ReadDir(notesDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !appbackup.IsNotesFile(entry.Name()) {
+			continue
+		}
+		if err := backend.keyring.RewriteFile(filepath.Join(notesDir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnlockAtRestEncryption decrypts the keyring with the passphrase and loads the accounts. If
+// authentication is enabled in the app config, the user has to authenticate first.
+func (backend *Backend) UnlockAtRestEncryption(passphrase string) error {
+	if backend.config.AppConfig().Backend.Authentication && !backend.authenticated.Load() {
+		return errp.WithStack(ErrAuthRequired)
+	}
+	if !backend.keyring.Locked() {
+		return nil
+	}
+	if err := backend.keyring.Unlock(passphrase); err != nil {
+		return err
+	}
+	if err := backend.config.ReloadAccountsConfig(); err != nil {
+		return err
+	}
+	// The address book is stored in the accounts config, which was not loaded while locked.
+	unlock := backend.addressBookLock.Lock()
+	backend.addressBookCache = nil
+	backend.resetTransactionsQueryCache(nil)
+	unlock()
+	backend.ReinitializeAccounts()
+	backend.notifyAtRestEncryptionStatus()
+	return nil
+}
+
+// EnableAtRestEncryption encrypts the notes, labels and the accounts config with a key protected
+// by the passphrase. The transaction databases of the accounts are deleted and from now on kept
+// only in memory, see `accounts.AccountConfig.InMemoryDB`.
+func (backend *Backend) EnableAtRestEncryption(passphrase string) error {
+	defer backend.accountsAndKeystoreLock.Lock()()
+	backend.log.Info("Enabling encryption at rest")
+	backend.uninitAccounts(true)
+	defer backend.initAccounts(true)
+	defer backend.notifyAtRestEncryptionStatus()
+	if err := backend.keyring.Enable(passphrase, backend.rewriteAtRestFiles); err != nil {
+		return err
+	}
+	return removeAccountsDBs(backend.arguments.CacheDirectoryPath())
+}
+
+// ChangeAtRestPassphrase changes the passphrase and rekeys the encrypted files.
+func (backend *Backend) ChangeAtRestPassphrase(passphrase string, newPassphrase string) error {
+	if backend.keyring.Locked() {
+		// The accounts config is not loaded, rewriting it would lose it.
+		return errp.WithStack(atrest.ErrLocked)
+	}
+	defer backend.accountsAndKeystoreLock.Lock()()
+	backend.log.Info("Changing the passphrase of the encryption at rest")
+	backend.uninitAccounts(true)
+	defer backend.initAccounts(true)
+	return backend.keyring.ChangePassphrase(passphrase, newPassphrase, backend.rewriteAtRestFiles)
+}
+
+// DisableAtRestEncryption decrypts the notes, labels and the accounts config and stores them in
+// plaintext again.
+func (backend *Backend) DisableAtRestEncryption(passphrase string) error {
+	if backend.keyring.Locked() {
+		// The accounts config is not loaded, rewriting it would lose it.
+		return errp.WithStack(atrest.ErrLocked)
+	}
+	defer backend.accountsAndKeystoreLock.Lock()()
+	backend.log.Info("Disabling encryption at rest")
+	backend.uninitAccounts(true)
+	defer backend.initAccounts(true)
+	defer backend.notifyAtRestEncryptionStatus()
+	return backend.keyring.Disable(passphrase, backend.rewriteAtRestFiles)
+}
@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/atrest"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAtRestEncryption(t *testing.T) {
+	const passphrase = "correct horse battery staple"
+
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	b.registerKeystore(makeBitBox02Multi())
+	accountsConfig := b.config.AccountsConfig()
+	require.NotEmpty(t, accountsConfig.Accounts)
+	notesFilename := filepath.Join(b.arguments.NotesDirectoryPath(), "notes.json")
+	notesContents := []byte(`{"transactions":{"txid":"note"}}`)
+	require.NoError(t, os.WriteFile(notesFilename, notesContents, 0600))
+	accountDB := filepath.Join(b.arguments.CacheDirectoryPath(), "account-test.db")
+	require.NoError(t, os.WriteFile(accountDB, nil, 0600))
+
+	isEncrypted := func(filename string) bool {
+		contents, err := os.ReadFile(filename)
+		require.NoError(t, err)
+		return atrest.IsEncrypted(contents)
+	}
+
+	require.Equal(t, AtRestEncryptionStatus{}, b.AtRestEncryptionStatus())
+	require.Equal(t, atrest.ErrPassphraseTooShort, errp.Cause(b.EnableAtRestEncryption("short")))
+	require.False(t, isEncrypted(b.arguments.AccountsConfigFilename()))
+
+	require.NoError(t, b.EnableAtRestEncryption(passphrase))
+	require.Equal(t, AtRestEncryptionStatus{Enabled: true}, b.AtRestEncryptionStatus())
+	require.True(t, isEncrypted(b.arguments.AccountsConfigFilename()))
+	require.True(t, isEncrypted(notesFilename))
+	require.True(t, isEncrypted(filepath.Join(b.arguments.MainDirectoryPath(), connectionLogFilename)))
+	require.NoFileExists(t, accountDB)
+	require.NoError(t, b.Close())
+
+	// After a restart, the accounts config is locked.
+	b = newBackendWithArguments(t, b.arguments)
+	require.Equal(t, AtRestEncryptionStatus{Enabled: true, Locked: true}, b.AtRestEncryptionStatus())
+	require.Empty(t, b.config.AccountsConfig().Accounts)
+	require.Equal(t, atrest.ErrLocked, errp.Cause(b.config.ModifyAccountsConfig(
+		func(*config.AccountsConfig) error { return nil })))
+	require.Equal(t, atrest.ErrLocked, errp.Cause(b.DisableAtRestEncryption(passphrase)))
+	require.Equal(t, atrest.ErrWrongPassphrase, errp.Cause(b.UnlockAtRestEncryption("wrong passphrase")))
+
+	// Authentication gates the unlock.
+	require.NoError(t, b.config.ModifyAppConfig(func(appConfig *config.AppConfig) error {
+		appConfig.Backend.Authentication = true
+		return nil
+	}))
+	require.Equal(t, ErrAuthRequired, errp.Cause(b.UnlockAtRestEncryption(passphrase)))
+	b.AuthResult(AuthResultOk)
+	require.NoError(t, b.UnlockAtRestEncryption(passphrase))
+	require.Equal(t, AtRestEncryptionStatus{Enabled: true}, b.AtRestEncryptionStatus())
+	unlockedConfig := b.config.AccountsConfig()
+	for _, account := range accountsConfig.Accounts {
+		require.NotNil(t, unlockedConfig.Lookup(account.Code))
+	}
+	// The transaction databases are kept in memory.
+	cacheEntries, err := os.ReadDir(b.arguments.CacheDirectoryPath())
+	require.NoError(t, err)
+	for _, entry := range cacheEntries {
+		require.False(t, strings.HasPrefix(entry.Name(), "account-"), entry.Name())
+	}
+
+	// Rekeying.
+	require.Equal(t, atrest.ErrWrongPassphrase, errp.Cause(b.ChangeAtRestPassphrase("wrong passphrase", "new passphrase")))
+	require.NoError(t, b.ChangeAtRestPassphrase(passphrase, "new passphrase"))
+	contents, err := b.keyring.ReadFile(notesFilename)
+	require.NoError(t, err)
+	require.Equal(t, notesContents, contents)
+
+	require.Equal(t, atrest.ErrWrongPassphrase, errp.Cause(b.DisableAtRestEncryption(passphrase)))
+	require.NoError(t, b.DisableAtRestEncryption("new passphrase"))
+	require.Equal(t, AtRestEncryptionStatus{}, b.AtRestEncryptionStatus())
+	require.False(t, isEncrypted(b.arguments.AccountsConfigFilename()))
+	contents, err = os.ReadFile(notesFilename)
+	require.NoError(t, err)
+	require.Equal(t, notesContents, contents)
+	require.NoError(t, b.Close())
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
 	accountsTypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/addressbook"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/arguments"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/banners"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc"
@@ -30,6 +31,8 @@ import (
 	coinpkg "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/etherscan"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/rpcnode"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/ltc"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/devices/bitbox"
@@ -40,9 +43,12 @@ import (
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/devices/usb"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/keystore"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/keystore/software"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/profiles"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/rates"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/versioninfo"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/atrest"
 	utilConfig "github.com/BitBoxSwiss/bitbox-wallet-app/util/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/connlog"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/locker"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/logging"
@@ -264,11 +270,34 @@ type Backend struct {
 	coins     map[coinpkg.Code]coinpkg.Coin
 	coinsLock locker.Locker
 
+	// addressBookCache is the address book of the configured contacts, see `addressBook()`. nil
+	// if it needs to be recreated.
+	addressBookCache *addressbook.AddressBook
+	addressBookLock  locker.Locker
+	// transactionsQueryCache are the sorted transactions of the accounts by account code, see
+	// `QueryTransactions()`.
+	transactionsQueryCache map[accountsTypes.Code]*accountTransactionsCache
+	// transactionsQueryCacheGeneration is incremented when the cache is reset.
+	transactionsQueryCacheGeneration uint64
+	transactionsQueryCacheLock       locker.Locker
+
+	// profiles manages the wallet profiles, see `SetProfiles()`. nil if the app does not support
+	// profiles.
+	profiles *profiles.Manager
+	// switchProfile restarts the app with the backend of the profile with the given app directory.
+	switchProfile func(mainDirectoryPath string)
+
 	log *logrus.Entry
 
 	socksProxy socksproxy.SocksProxy
-	// can be a regular or, if Tor is enabled in the config, a SOCKS5 proxy client.
-	httpClient           *http.Client
+	// httpClient is the client of `socksproxy.ServiceOther`, e.g. for AOPP callbacks.
+	httpClient *http.Client
+	// serviceHTTPClients are the http clients of the services which are routed separately, see
+	// `socksproxy.Service`.
+	serviceHTTPClients map[socksproxy.Service]*http.Client
+	// connectionLog records the outbound connections of all services, including the ones blocked by
+	// the strict privacy mode.
+	connectionLog        *connlog.Log
 	etherScanRateLimiter *rate.Limiter
 	ratesUpdater         *rates.RateUpdater
 	banners              *banners.Banners
@@ -286,6 +315,12 @@ type Backend struct {
 	// isOnline indicates whether the backend is online, i.e. able to connect to the internet.
 	isOnline atomic.Bool
 
+	// authenticated is true once the user authenticated successfully, see `AuthResult()`.
+	authenticated atomic.Bool
+
+	// keyring encrypts the notes, labels and the accounts config at rest, if enabled.
+	keyring *atrest.Keyring
+
 	// ethupdater takes care of updating ETH accounts.
 	ethupdater *eth.Updater
 }
@@ -293,22 +328,55 @@ type Backend struct {
 // NewBackend creates a new backend with the given arguments.
 func NewBackend(arguments *arguments.Arguments, environment Environment) (*Backend, error) {
 	log := logging.Get().WithGroup("backend")
-	backendConfig, err := config.NewConfig(arguments.AppConfigFilename(), arguments.AccountsConfigFilename())
+	keyring, err := atrest.NewKeyring(filepath.Join(arguments.MainDirectoryPath(), atRestKeyringFilename))
+	if err != nil {
+		return nil, err
+	}
+	backendConfig, err := config.NewConfigWithKeyring(
+		arguments.AppConfigFilename(), arguments.AccountsConfigFilename(), keyring)
 	if err != nil {
 		return nil, errp.WithStack(err)
 	}
 	log.Infof("backend config: %+v", backendConfig.AppConfig().Backend)
 	log.Infof("frontend config: %+v", backendConfig.AppConfig().Frontend)
+	proxyConfig := backendConfig.AppConfig().Backend.Proxy
 	// Disable on iOS as it does not work there.
-	useProxy := backendConfig.AppConfig().Backend.Proxy.UseProxy && runtime.GOOS != "ios"
-	backendProxy := socksproxy.NewSocksProxy(
+	useProxy := proxyConfig.UseProxy && runtime.GOOS != "ios"
+	proxyRoutes := map[socksproxy.Service]socksproxy.Route{}
+	for service, route := range proxyConfig.Routes {
+		if route == socksproxy.RouteProxy && runtime.GOOS == "ios" {
+			route = socksproxy.RouteDirect
+		}
+		proxyRoutes[service] = route
+	}
+	backendProxy := socksproxy.NewSocksProxyWithRoutes(
 		useProxy,
-		backendConfig.AppConfig().Backend.Proxy.ProxyAddress,
+		proxyConfig.ProxyAddress,
+		proxyRoutes,
+		proxyConfig.IsolateStreams,
 	)
-	hclient, err := backendProxy.GetHTTPClient()
+	connectionLog, err := connlog.OpenLog(
+		filepath.Join(arguments.MainDirectoryPath(), connectionLogFilename), maxConnectionLogEntries, keyring)
+	if err != nil {
+		log.WithError(err).Error("Failed to open the connection log, it is not persisted")
+		connectionLog = connlog.NewLog(maxConnectionLogEntries)
+	}
+	connectionLog.SetRecordURLs(func() bool { return backendConfig.AppConfig().Backend.ConnectionLogURLs })
+	backendProxy.SetConnectionPolicy(
+		func() bool { return backendConfig.AppConfig().Backend.StrictPrivacy },
+		connectionLog,
+	)
+	hclient, err := backendProxy.ServiceHTTPClient(socksproxy.ServiceOther, "")
 	if err != nil {
 		return nil, err
 	}
+	serviceHTTPClients := map[socksproxy.Service]*http.Client{}
+	for _, service := range socksproxy.Services {
+		serviceHTTPClients[service], err = backendProxy.ServiceHTTPClient(service, "")
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	accountUpdate := make(chan *eth.Account)
 
@@ -316,6 +384,7 @@ func NewBackend(arguments *arguments.Arguments, environment Environment) (*Backe
 		arguments:   arguments,
 		environment: environment,
 		config:      backendConfig,
+		keyring:     keyring,
 		events:      make(chan interface{}),
 
 		devices:  map[string]device.Interface{},
@@ -343,10 +412,13 @@ func NewBackend(arguments *arguments.Arguments, environment Environment) (*Backe
 	}
 	backend.notifier = notifier
 	backend.socksProxy = backendProxy
+	backend.connectionLog = connectionLog
 	backend.updateChecker = newUpdateChecker(&backend.socksProxy, backend.userAgent())
 	backend.updateChecker.Observe(backend.Notify)
 	backend.httpClient = hclient
-	backend.ethupdater = eth.NewUpdater(accountUpdate, backend.httpClient, backend.etherScanRateLimiter, backend.updateETHAccounts)
+	backend.serviceHTTPClients = serviceHTTPClients
+	backend.ethupdater = eth.NewUpdater(
+		accountUpdate, backend.serviceHTTPClient(socksproxy.ServiceEthereum), backend.etherScanRateLimiter, backend.updateETHAccounts)
 	backend.enqueueETHUpdateForAllAccountsAsync = backend.ethupdater.EnqueueUpdateForAllAccountsAsync
 
 	backend.ratesUpdater = backend.newRatesUpdater()
@@ -365,7 +437,11 @@ func (backend *Backend) newRatesUpdater() *rates.RateUpdater {
 	if err := os.MkdirAll(ratesCache, 0700); err != nil {
 		backend.log.Errorf("RateUpdater DB cache dir: %v", err)
 	}
-	updater := rates.NewRateUpdater(backend.httpClient, ratesCache)
+	updater := rates.NewRateUpdater(backend.serviceHTTPClient(socksproxy.ServiceRates), ratesCache)
+	if backendConfig := backend.config.AppConfig().Backend; len(backendConfig.RatesProviders) > 0 {
+		updater.SetProviders(backendConfig.RatesProviders, backendConfig.RatesStaticFile)
+	}
+	updater.SetPriceAlertsHandler(priceAlertsHandler{backend: backend})
 	updater.Observe(func(event observable.Event) {
 		backend.Notify(event)
 		backend.notifyCoinFiatPrices()
@@ -491,6 +567,9 @@ func (backend *Backend) ForceAuth() {
 // depending on the input value.
 func (backend *Backend) AuthResult(result AuthResultType) {
 	backend.log.Infof("Auth result: %v", result)
+	if result == AuthResultOk {
+		backend.authenticated.Store(true)
+	}
 	backend.Notify(observable.Event{
 		Subject: "auth",
 		Action:  action.Replace,
@@ -602,20 +681,20 @@ func (backend *Backend) Coin(code coinpkg.Code) (coinpkg.Coin, error) {
 		coin = btc.NewCoin(coinpkg.CodeLTC, "Litecoin", "LTC", coinpkg.BtcUnitDefault, &ltc.MainNetParams, dbFolder, servers,
 			"https://blockchair.com/litecoin/transaction/", "https://blockchair.com/litecoin/address/", backend.socksProxy)
 	case code == coinpkg.CodeETH:
-		etherScan := etherscan.NewEtherScan("1", backend.httpClient, backend.etherScanRateLimiter)
-		coin = eth.NewCoin(etherScan, code, "Ethereum", "ETH", "ETH", params.MainnetChainConfig,
+		etherScan := etherscan.NewEtherScan("1", backend.serviceHTTPClient(socksproxy.ServiceEthereum), backend.etherScanRateLimiter)
+		coin = eth.NewCoin(backend.ethMainnetClient(etherScan), code, "Ethereum", "ETH", "ETH", params.MainnetChainConfig,
 			"https://etherscan.io/",
 			etherScan,
 			nil)
 	case code == coinpkg.CodeSEPETH:
-		etherScan := etherscan.NewEtherScan("11155111", backend.httpClient, backend.etherScanRateLimiter)
+		etherScan := etherscan.NewEtherScan("11155111", backend.serviceHTTPClient(socksproxy.ServiceEthereum), backend.etherScanRateLimiter)
 		coin = eth.NewCoin(etherScan, code, "Ethereum Sepolia", "SEPETH", "SEPETH", params.SepoliaChainConfig,
 			"https://sepolia.etherscan.io/",
 			etherScan,
 			nil)
 	case erc20Token != nil:
-		etherScan := etherscan.NewEtherScan("1", backend.httpClient, backend.etherScanRateLimiter)
-		coin = eth.NewCoin(etherScan, erc20Token.code, erc20Token.name, erc20Token.unit, "ETH", params.MainnetChainConfig,
+		etherScan := etherscan.NewEtherScan("1", backend.serviceHTTPClient(socksproxy.ServiceEthereum), backend.etherScanRateLimiter)
+		coin = eth.NewCoin(backend.ethMainnetClient(etherScan), erc20Token.code, erc20Token.name, erc20Token.unit, "ETH", params.MainnetChainConfig,
 			"https://etherscan.io/",
 			etherScan,
 			erc20Token.token,
@@ -628,6 +707,21 @@ func (backend *Backend) Coin(code coinpkg.Code) (coinpkg.Coin, error) {
 	return coin, nil
 }
 
+// ethMainnetClient returns a client for the self-hosted Ethereum node if one is configured, and
+// the Etherscan client otherwise.
+func (backend *Backend) ethMainnetClient(etherScan *etherscan.EtherScan) rpcclient.Interface {
+	nodeURL := backend.config.AppConfig().Backend.ETH.NodeURL
+	if nodeURL == "" {
+		return etherScan
+	}
+	client, err := rpcnode.NewClient(nodeURL, backend.serviceHTTPClient(socksproxy.ServiceEthereum))
+	if err != nil {
+		backend.log.WithError(err).Error("Invalid Ethereum node URL, falling back to Etherscan")
+		return etherScan
+	}
+	return client
+}
+
 func (backend *Backend) updateETHAccounts() error {
 	backend.log.Debug("Updating ETH accounts balances")
 
@@ -642,7 +736,8 @@ func (backend *Backend) updateETHAccounts() error {
 	}
 
 	for chainID, ethAccounts := range accountsChainID {
-		etherScanClient := etherscan.NewEtherScan(chainID, backend.httpClient, backend.etherScanRateLimiter)
+		etherScanClient := etherscan.NewEtherScan(
+			chainID, backend.serviceHTTPClient(socksproxy.ServiceEthereum), backend.etherScanRateLimiter)
 		backend.ethupdater.UpdateBalancesAndBlockNumber(ethAccounts, etherScanClient)
 	}
 
@@ -684,6 +779,11 @@ func (backend *Backend) ManualReconnect(reconnectETH bool) {
 		}
 		blockchain.ManualReconnect()
 	}
+	for _, account := range backend.Accounts() {
+		if btcAccount, ok := account.(*btc.Account); ok {
+			btcAccount.ManualReconnect()
+		}
+	}
 	if reconnectETH {
 		backend.log.Info("Reconnecting ETH accounts")
 		backend.ethupdater.EnqueueUpdateForAllAccounts()
@@ -731,12 +831,7 @@ func (backend *Backend) Start() <-chan interface{} {
 		backend.Deregister)
 	backend.usbManager.Start()
 
-	httpClient, err := backend.socksProxy.GetHTTPClient()
-	if err != nil {
-		backend.log.Error(err.Error())
-	} else {
-		go backend.banners.Init(httpClient)
-	}
+	go backend.banners.Init(backend.serviceHTTPClient(socksproxy.ServiceApp))
 	backend.updateChecker.start()
 
 	defer backend.accountsAndKeystoreLock.Lock()()
@@ -772,9 +867,35 @@ func (backend *Backend) DevicesRegistered() map[string]device.Interface {
 	return maps.Clone(backend.devices)
 }
 
-// HTTPClient is a getter method for the HTTPClient instance.
-func (backend *Backend) HTTPClient() *http.Client {
-	return backend.httpClient
+// fetchNFTMetadata returns true if the metadata of NFTs may be fetched from their hosts, i.e. if the
+// user opted in and the connections are proxied, see `accounts.AccountConfig.FetchNFTMetadata`.
+func (backend *Backend) fetchNFTMetadata() bool {
+	return backend.config.AppConfig().Backend.NFTMetadata &&
+		backend.socksProxy.Route(socksproxy.ServiceOther) == socksproxy.RouteProxy
+}
+
+// MarketHTTPClient returns the http client for the market vendors, see `socksproxy.ServiceMarket`.
+func (backend *Backend) MarketHTTPClient() *http.Client {
+	return backend.serviceHTTPClient(socksproxy.ServiceMarket)
+}
+
+// serviceHTTPClient returns the http client of the service, routed according to the proxy config.
+func (backend *Backend) serviceHTTPClient(service socksproxy.Service) *http.Client {
+	return backend.serviceHTTPClients[service]
+}
+
+// accountHTTPClient returns the http client of the service for an account. If stream isolation is
+// enabled, the requests of the account use a separate Tor circuit.
+func (backend *Backend) accountHTTPClient(service socksproxy.Service, code accountsTypes.Code) *http.Client {
+	if !backend.socksProxy.IsolatesStreams(service) {
+		return backend.serviceHTTPClient(service)
+	}
+	client, err := backend.socksProxy.ServiceHTTPClient(service, string(code))
+	if err != nil {
+		backend.log.WithError(err).Error("could not create the account http client")
+		return backend.serviceHTTPClient(service)
+	}
+	return client
 }
 
 // Keystore returns the keystore registered at this backend, or nil if no keystore is registered.
@@ -1016,14 +1137,14 @@ func (backend *Backend) notifyCoinFiatPrices() {
 
 // DownloadCert downloads the first element of the remote certificate chain.
 func (backend *Backend) DownloadCert(server string) (string, error) {
-	return electrum.DownloadCert(server, backend.socksProxy.GetTCPProxyDialer())
+	return electrum.DownloadCert(server, backend.socksProxy.ServiceTCPDialer(socksproxy.ServiceElectrum, ""))
 }
 
 // CheckElectrumServer checks if a connection can be established with the electrum server, and
 // whether the server is an electrum server.
 func (backend *Backend) CheckElectrumServer(serverInfo *config.ServerInfo) error {
 	return electrum.CheckElectrumServer(
-		serverInfo, backend.log, backend.socksProxy.GetTCPProxyDialer())
+		serverInfo, backend.log, backend.socksProxy.ServiceTCPDialer(socksproxy.ServiceElectrum, ""))
 }
 
 // RegisterTestKeystore adds a keystore derived deterministically from a PIN, for convenience in
@@ -1182,6 +1303,9 @@ func (backend *Backend) Close() error {
 	if err := backend.notifier.Close(); err != nil {
 		errors = append(errors, err.Error())
 	}
+	if err := backend.connectionLog.Close(); err != nil {
+		errors = append(errors, err.Error())
+	}
 	if len(errors) > 0 {
 		return errp.New(strings.Join(errors, "; "))
 	}
@@ -23,11 +23,13 @@ import (
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/erc20"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/rpcclient/mocks"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/devices/usb"
 	keystoremock "github.com/BitBoxSwiss/bitbox-wallet-app/backend/keystore/mocks"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/keystore/software"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/signing"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/observable"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/socksproxy"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/test"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
@@ -360,14 +362,18 @@ func (m *mockTransactionsSource) Transactions(
 
 func newBackend(t *testing.T, testing, regtest bool) *Backend {
 	t.Helper()
-	b, err := NewBackend(
-		arguments.NewArguments(
-			test.TstTempDir("appfolder"),
-			testing, regtest,
-			true,
-			&types.GapLimits{Receive: 20, Change: 6}),
-		environment{},
-	)
+	return newBackendWithArguments(t, arguments.NewArguments(
+		test.TstTempDir("appfolder"),
+		testing, regtest,
+		true,
+		&types.GapLimits{Receive: 20, Change: 6}))
+}
+
+// newBackendWithArguments is like `newBackend()`, e.g. to start a backend again with the same app
+// folder.
+func newBackendWithArguments(t *testing.T, arguments *arguments.Arguments) *Backend {
+	t.Helper()
+	b, err := NewBackend(arguments, environment{})
 	b.tstCheckAccountUsed = func(accounts.Interface) bool {
 		return false
 	}
@@ -436,6 +442,27 @@ func TestDevicesRegisteredReturnsSnapshot(t *testing.T) {
 	require.Contains(t, b.DevicesRegistered(), "device-id")
 }
 
+func TestFetchNFTMetadata(t *testing.T) {
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+	setOptIn := func(optIn bool) {
+		require.NoError(t, b.config.ModifyAppConfig(func(appConfig *config.AppConfig) error {
+			appConfig.Backend.NFTMetadata = optIn
+			return nil
+		}))
+	}
+
+	require.False(t, b.fetchNFTMetadata())
+	setOptIn(true)
+	// The metadata hosts would learn the IP address of the user.
+	require.False(t, b.fetchNFTMetadata())
+
+	b.socksProxy = socksproxy.NewSocksProxy(true, "127.0.0.1:9050")
+	require.True(t, b.fetchNFTMetadata())
+	setOptIn(false)
+	require.False(t, b.fetchNFTMetadata())
+}
+
 func TestClearCachePreservesUserData(t *testing.T) {
 	b := newBackend(t, false, false)
 	defer b.Close()
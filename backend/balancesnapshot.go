@@ -0,0 +1,248 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+// BalanceSnapshotFormat is the file format of an exported balance snapshot.
+type BalanceSnapshotFormat string
+
+const (
+	// BalanceSnapshotFormatCSV has one row per account.
+	BalanceSnapshotFormatCSV BalanceSnapshotFormat = "csv"
+	// BalanceSnapshotFormatJSON is the JSON encoding of BalanceSnapshot.
+	BalanceSnapshotFormatJSON BalanceSnapshotFormat = "json"
+)
+
+// BalanceSnapshotArgs are the options of a balance snapshot.
+type BalanceSnapshotArgs struct {
+	// At is the time of the snapshot as a unix timestamp in seconds. Defaults to now if zero and no
+	// height is given. If a height is given, At is only the time of the valuation.
+	At int64 `json:"at"`
+	// Height is the block height of the snapshot, if not zero. Block heights are specific to one
+	// chain, so Coin must be set, and only the accounts of this coin and its tokens are included.
+	Height int `json:"height"`
+	// Coin is the coin code of the chain of Height.
+	Coin coin.Code `json:"coin"`
+	// Fiat defaults to the main fiat currency if empty.
+	Fiat string `json:"fiat"`
+}
+
+// BalanceSnapshotAccount is the balance of one account in a balance snapshot.
+type BalanceSnapshotAccount struct {
+	Code     string    `json:"code"`
+	Name     string    `json:"name"`
+	CoinCode coin.Code `json:"coinCode"`
+	Unit     string    `json:"unit"`
+	// Balance in the unit of the coin. Empty if the balance could not be computed, see DataMissing.
+	Balance string `json:"balance"`
+	// FiatValue is the value of the balance at the valuation time. Empty if no price was available.
+	FiatValue string `json:"fiatValue"`
+	// DataMissing is true if timestamps of transactions are missing, e.g. because block headers are
+	// still being downloaded.
+	DataMissing bool `json:"dataMissing"`
+}
+
+// BalanceSnapshot contains the balances of all active accounts at a point in time, and their fiat
+// values at this time.
+type BalanceSnapshot struct {
+	// At is the time of the snapshot and of the valuation.
+	At     time.Time `json:"at"`
+	Height int       `json:"height,omitempty"`
+	Fiat   string    `json:"fiat"`
+	// Accounts are sorted like `Backend.Accounts()`.
+	Accounts []*BalanceSnapshotAccount `json:"accounts"`
+	// Total is the total fiat value of all accounts with a fiat value.
+	Total string `json:"total"`
+	// Incomplete is true if any balance or fiat value is missing.
+	Incomplete bool `json:"incomplete"`
+}
+
+// onChain returns true if the coin is the given chain coin or one of its tokens.
+func onChain(coinCode coin.Code, chain coin.Code) bool {
+	return coinCode == chain || strings.HasPrefix(string(coinCode), string(chain)+"-erc20-")
+}
+
+// BalanceSnapshot computes the balances of all active accounts at a given time or block height
+// from their transaction history, valued with the historical exchange rates.
+func (backend *Backend) BalanceSnapshot(args BalanceSnapshotArgs) (*BalanceSnapshot, error) {
+	if args.Height < 0 {
+		return nil, errp.New("invalid block height")
+	}
+	if args.Height != 0 && args.Coin == "" {
+		return nil, errp.New("a snapshot at a block height needs a coin")
+	}
+	fiat := args.Fiat
+	if fiat == "" {
+		fiat = backend.config.AppConfig().Backend.MainFiat
+	}
+	type balance struct {
+		account accounts.Interface
+		amount  *coin.Amount
+	}
+	balances := []balance{}
+	// Latest transaction time included in a snapshot at a block height.
+	var heightTime time.Time
+	at := time.Now()
+	if args.At != 0 {
+		at = time.Unix(args.At, 0)
+	}
+	for _, account := range backend.Accounts() {
+		if account.Config().Config.Inactive {
+			continue
+		}
+		if account.FatalError() {
+			continue
+		}
+		if args.Height != 0 && !onChain(account.Coin().Code(), args.Coin) {
+			continue
+		}
+		if err := account.Initialize(); err != nil {
+			return nil, err
+		}
+		txs, err := account.Transactions()
+		if err != nil {
+			return nil, err
+		}
+		if args.Height != 0 {
+			amount, timestamp := txs.BalanceAtHeight(args.Height)
+			if timestamp != nil && timestamp.After(heightTime) {
+				heightTime = *timestamp
+			}
+			balances = append(balances, balance{account: account, amount: &amount})
+			continue
+		}
+		amount, err := txs.BalanceAt(at)
+		if errp.Cause(err) == errors.ErrNotAvailable {
+			balances = append(balances, balance{account: account})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		balances = append(balances, balance{account: account, amount: &amount})
+	}
+	// Without an explicit valuation time, a snapshot at a block height is valued at the time of the
+	// latest transaction included.
+	if args.Height != 0 && args.At == 0 && !heightTime.IsZero() {
+		at = heightTime
+	}
+
+	snapshot := &BalanceSnapshot{
+		At:       at,
+		Height:   args.Height,
+		Fiat:     fiat,
+		Accounts: []*BalanceSnapshotAccount{},
+	}
+	ratesUpdater := backend.RatesUpdater()
+	total := new(big.Rat)
+	for _, b := range balances {
+		accountCoin := b.account.Coin()
+		entry := &BalanceSnapshotAccount{
+			Code:     string(b.account.Config().Config.Code),
+			Name:     b.account.Config().Config.Name,
+			CoinCode: accountCoin.Code(),
+			Unit:     accountCoin.Unit(false),
+		}
+		snapshot.Accounts = append(snapshot.Accounts, entry)
+		if b.amount == nil {
+			entry.DataMissing = true
+			snapshot.Incomplete = true
+			continue
+		}
+		coinDecimals := coin.DecimalsExp(accountCoin, false)
+		amount := new(big.Rat).SetFrac(b.amount.BigInt(), coinDecimals)
+		entry.Balance = amount.FloatString(len(coinDecimals.String()) - 1)
+		if b.amount.BigInt().Sign() == 0 {
+			entry.FiatValue = coin.FormatAsPlainCurrency(new(big.Rat), fiat)
+			continue
+		}
+		price := ratesUpdater.HistoricalPriceAt(string(accountCoin.Code()), fiat, at)
+		// The historical rates can lag behind, see `coin.ConversionsAtTime()`.
+		if price == 0 && time.Since(at) < 2*time.Hour {
+			price = ratesUpdater.LatestPrice()[accountCoin.Unit(false)][fiat]
+		}
+		if price == 0 {
+			snapshot.Incomplete = true
+			continue
+		}
+		fiatValue := new(big.Rat).Mul(amount, new(big.Rat).SetFloat64(price))
+		entry.FiatValue = coin.FormatAsPlainCurrency(fiatValue, fiat)
+		total.Add(total, fiatValue)
+	}
+	snapshot.Total = coin.FormatAsPlainCurrency(total, fiat)
+	return snapshot, nil
+}
+
+// WriteCSV writes the snapshot as CSV, with one row per account.
+func (snapshot *BalanceSnapshot) WriteCSV(writer io.Writer) error {
+	csvWriter := csv.NewWriter(writer)
+	height := ""
+	if snapshot.Height != 0 {
+		height = strconv.Itoa(snapshot.Height)
+	}
+	err := csvWriter.Write([]string{
+		"Time",
+		"Block height",
+		"Account",
+		"Account name",
+		"Balance",
+		"Unit",
+		"Fiat value",
+		"Fiat",
+	})
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	for _, account := range snapshot.Accounts {
+		err := csvWriter.Write([]string{
+			snapshot.At.UTC().Format(time.RFC3339),
+			height,
+			account.Code,
+			account.Name,
+			account.Balance,
+			account.Unit,
+			account.FiatValue,
+			snapshot.Fiat,
+		})
+		if err != nil {
+			return errp.WithStack(err)
+		}
+	}
+	csvWriter.Flush()
+	return errp.WithStack(csvWriter.Error())
+}
+
+// ExportBalanceSnapshot exports the balance snapshot of all active accounts to a CSV or JSON file.
+func (backend *Backend) ExportBalanceSnapshot(args BalanceSnapshotArgs, format BalanceSnapshotFormat) error {
+	if format != BalanceSnapshotFormatCSV && format != BalanceSnapshotFormatJSON {
+		return errp.Newf("unsupported format %q", format)
+	}
+	snapshot, err := backend.BalanceSnapshot(args)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-balances.%s", snapshot.At.UTC().Format("2006-01-02-at-15-04-05"), format)
+	return backend.exportFile(name, func(writer io.Writer) error {
+		if format == BalanceSnapshotFormatCSV {
+			return snapshot.WriteCSV(writer)
+		}
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return errp.WithStack(encoder.Encode(snapshot))
+	})
+}
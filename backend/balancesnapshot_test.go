@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnChain(t *testing.T) {
+	require.True(t, onChain(coin.CodeETH, coin.CodeETH))
+	require.True(t, onChain("eth-erc20-usdt", coin.CodeETH))
+	require.False(t, onChain(coin.CodeBTC, coin.CodeETH))
+	require.False(t, onChain("sepeth", coin.CodeETH))
+}
+
+func TestBalanceSnapshot(t *testing.T) {
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+
+	_, err := b.BalanceSnapshot(BalanceSnapshotArgs{Height: 100})
+	require.Error(t, err)
+	_, err = b.BalanceSnapshot(BalanceSnapshotArgs{Height: -1, Coin: coin.CodeBTC})
+	require.Error(t, err)
+	require.Error(t, b.ExportBalanceSnapshot(BalanceSnapshotArgs{}, "xml"))
+
+	snapshot, err := b.BalanceSnapshot(BalanceSnapshotArgs{At: 1704067140, Fiat: "EUR"})
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1704067140, 0), snapshot.At)
+	require.Equal(t, "EUR", snapshot.Fiat)
+	require.Empty(t, snapshot.Accounts)
+	require.Equal(t, "0.00", snapshot.Total)
+	require.False(t, snapshot.Incomplete)
+}
+
+func TestBalanceSnapshotWriteCSV(t *testing.T) {
+	snapshot := &BalanceSnapshot{
+		At:     time.Date(2023, 12, 31, 23, 59, 0, 0, time.UTC),
+		Height: 824000,
+		Fiat:   "USD",
+		Accounts: []*BalanceSnapshotAccount{
+			{
+				Code:      "v0-55555555-btc-0",
+				Name:      "Bitcoin, savings",
+				CoinCode:  coin.CodeBTC,
+				Unit:      "BTC",
+				Balance:   "0.50000000",
+				FiatValue: "21000.00",
+			},
+			{
+				Code:        "v0-55555555-btc-1",
+				Name:        "Bitcoin 2",
+				CoinCode:    coin.CodeBTC,
+				Unit:        "BTC",
+				DataMissing: true,
+			},
+		},
+	}
+	var result bytes.Buffer
+	require.NoError(t, snapshot.WriteCSV(&result))
+	require.Equal(t,
+		`Time,Block height,Account,Account name,Balance,Unit,Fiat value,Fiat
+2023-12-31T23:59:00Z,824000,v0-55555555-btc-0,"Bitcoin, savings",0.50000000,BTC,21000.00,USD
+2023-12-31T23:59:00Z,824000,v0-55555555-btc-1,Bitcoin 2,,BTC,,USD
+`,
+		result.String())
+}
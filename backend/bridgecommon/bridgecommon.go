@@ -20,6 +20,7 @@ import (
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/devices/bluetooth"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/devices/usb"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/handlers"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/profiles"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/versioninfo"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/config"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
@@ -47,6 +48,9 @@ var (
 	globalToken string
 
 	globalShutdown func()
+	// globalServe serves the backend of the profile with the given app directory, using the
+	// arguments of the last `Serve()` call.
+	globalServe func(mainDirectoryPath string)
 )
 
 type response struct {
@@ -317,19 +321,42 @@ func Serve(
 		testnet = true
 	}
 
+	profilesManager, err := profiles.NewManager(config.AppDir())
+	if err != nil {
+		log.WithError(err).Fatal("Failed to load profiles")
+	}
+	mainDirectoryPath, err := profilesManager.Startup()
+	if err != nil {
+		log.WithError(err).Fatal("Failed to start profile")
+	}
+	globalServe = func(mainDirectoryPath string) {
+		serve(
+			arguments.NewArguments(
+				mainDirectoryPath,
+				testnet,
+				false,
+				false,
+				gapLimits,
+			),
+			backendEnvironment,
+			profilesManager,
+		)
+	}
+	globalServe(mainDirectoryPath)
+}
+
+// serve creates the backend and its handlers. mu must be held when calling this function.
+func serve(
+	backendArguments *arguments.Arguments,
+	backendEnvironment backend.Environment,
+	profilesManager *profiles.Manager) {
+	log := logging.Get().WithGroup("server")
 	var err error
-	globalBackend, err = backend.NewBackend(
-		arguments.NewArguments(
-			config.AppDir(),
-			testnet,
-			false,
-			false,
-			gapLimits,
-		),
-		backendEnvironment)
+	globalBackend, err = backend.NewBackend(backendArguments, backendEnvironment)
 	if err != nil {
 		log.WithError(err).Fatal("Failed to create backend")
 	}
+	globalBackend.SetProfiles(profilesManager, switchProfile)
 
 	quitChan := make(chan struct{})
 	globalShutdown = func() {
@@ -368,7 +395,26 @@ func Serve(
 			}
 		}
 	}()
+}
+
+// switchProfile closes the backend and serves the backend of the profile with the given app
+// directory. The frontend is notified to reload, as all its data belonged to the previous backend.
+func switchProfile(mainDirectoryPath string) {
+	mu.Lock()
+	defer mu.Unlock()
 
+	log := logging.Get().WithGroup("server")
+	if globalShutdown == nil {
+		log.Info("Profile switch called, but backend not running")
+		return
+	}
+	log.Info("Switching profile")
+	globalShutdown()
+	globalServe(mainDirectoryPath)
+	globalCommunication.PushNotify(string(jsonp.MustMarshal(observable.Event{
+		Subject: "profiles",
+		Action:  action.Reload,
+	})))
 }
 
 // Shutdown is cleaning up after Serve. It is called when the application is closed or goes to
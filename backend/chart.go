@@ -3,28 +3,94 @@
 package backend
 
 import (
+	"bytes"
+	"encoding/hex"
 	"math/big"
+	"slices"
 	"sort"
 	"time"
 
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/errors"
+	accountsTypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/types"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
 )
 
-func (backend *Backend) allCoinCodes() []string {
-	allCoinCodes := []string{}
-	for _, account := range backend.Accounts() {
+// ChartResolution is the interval between the points of a chart.
+type ChartResolution string
+
+const (
+	// ChartResolutionHourly has one point per hour of the last week.
+	ChartResolutionHourly ChartResolution = "hourly"
+	// ChartResolutionDaily has one point per day since the first transaction.
+	ChartResolutionDaily ChartResolution = "daily"
+	// ChartResolutionWeekly has one point per week since the first transaction, at the start of
+	// each week (Monday, UTC).
+	ChartResolutionWeekly ChartResolution = "weekly"
+)
+
+// ChartArgs selects the accounts, unit and resolution of a chart. The zero value is the chart of all
+// active accounts in the main fiat currency, with daily and hourly points.
+type ChartArgs struct {
+	// AccountCodes restricts the chart to these accounts.
+	AccountCodes []accountsTypes.Code
+	// RootFingerprint restricts the chart to the accounts of the keystore with this root
+	// fingerprint, hex encoded.
+	RootFingerprint string
+	// CoinUnit computes the chart in the unit of the coin instead of the main fiat currency. All
+	// selected accounts must hold the same coin.
+	CoinUnit bool
+	// Resolution restricts the chart to points of this resolution. If empty, the chart contains
+	// daily and hourly points.
+	Resolution ChartResolution
+}
+
+// chartAccounts returns the active accounts selected by the args.
+func (backend *Backend) chartAccounts(args ChartArgs) (AccountsList, error) {
+	var rootFingerprint []byte
+	if args.RootFingerprint != "" {
+		var err error
+		rootFingerprint, err = hex.DecodeString(args.RootFingerprint)
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+	}
+	allAccounts := backend.Accounts()
+	for _, code := range args.AccountCodes {
+		if allAccounts.lookup(code) == nil {
+			return nil, errp.Newf("account %s not found", code)
+		}
+	}
+	var result AccountsList
+	for _, account := range allAccounts {
 		if account.Config().Config.Inactive {
 			continue
 		}
 		if account.FatalError() {
 			continue
 		}
-		allCoinCodes = append(allCoinCodes, string(account.Coin().Code()))
+		if len(args.AccountCodes) > 0 && !slices.Contains(args.AccountCodes, account.Config().Config.Code) {
+			continue
+		}
+		if rootFingerprint != nil {
+			accountRootFingerprint, err := account.Config().Config.SigningConfigurations.RootFingerprint()
+			if err != nil || !bytes.Equal(accountRootFingerprint, rootFingerprint) {
+				continue
+			}
+		}
+		if args.CoinUnit && len(result) > 0 && result[0].Coin().Code() != account.Coin().Code() {
+			return nil, errp.New("a chart in coin units needs accounts of the same coin")
+		}
+		result = append(result, account)
 	}
-	return allCoinCodes
+	return result, nil
+}
+
+// weekStart returns the start of the week (Monday, UTC) of the given time.
+func weekStart(t time.Time) time.Time {
+	day := t.Truncate(24 * time.Hour)
+	return day.AddDate(0, 0, -(int(day.UTC().Weekday())+6)%7)
 }
 
 // ChartEntry is one data point in the chart timeseries.
@@ -49,9 +115,14 @@ type Chart struct {
 	DataDaily []ChartEntry `json:"chartDataDaily"`
 	// Only valid if DaataMissing is false. Contains the hourly points for the chart.
 	DataHourly []ChartEntry `json:"chartDataHourly"`
+	// Only valid if DataMissing is false. Contains the weekly points for the chart, if requested.
+	DataWeekly []ChartEntry `json:"chartDataWeekly"`
 	// Fiat currency of the value in the chart and in the total.
 	Fiat string `json:"chartFiat"`
-	// Current total value of all assets in the fiat currency. Nil if missing (this is independent
+	// Unit of the values in the chart and in the total. Equal to Fiat, or the unit of the coin if
+	// the chart is in coin units.
+	Unit string `json:"chartUnit"`
+	// Current total value of all assets in the chart unit. Nil if missing (this is independent
 	// of `DataMissing`).
 	Total *float64 `json:"chartTotal"`
 	// ChartTotal formatted for frontend visualization
@@ -70,10 +141,14 @@ func (backend *Backend) addChartData(
 	chartEntries map[int64]RatChartEntry,
 ) {
 	for _, e := range timeseries {
-		price := backend.RatesUpdater().HistoricalPriceAt(
-			string(coinCode),
-			fiat,
-			e.Time)
+		// An empty fiat means the chart is in coin units.
+		price := 1.0
+		if fiat != "" {
+			price = backend.RatesUpdater().HistoricalPriceAt(
+				string(coinCode),
+				fiat,
+				e.Time)
+		}
 		timestamp := e.Time.Unix()
 		chartEntry := chartEntries[timestamp]
 
@@ -95,8 +170,24 @@ func (backend *Backend) addChartData(
 	}
 }
 
-// ChartData assembles chart data for all active accounts.
-func (backend *Backend) ChartData() (*Chart, error) {
+// ChartData assembles chart data for the accounts selected by args, by default all active accounts.
+func (backend *Backend) ChartData(args ChartArgs) (*Chart, error) {
+	switch args.Resolution {
+	case "", ChartResolutionHourly, ChartResolutionDaily, ChartResolutionWeekly:
+	default:
+		return nil, errp.Newf("unsupported chart resolution %q", args.Resolution)
+	}
+	chartAccounts, err := backend.chartAccounts(args)
+	if err != nil {
+		return nil, err
+	}
+	withResolution := func(resolution ChartResolution) bool {
+		if args.Resolution == "" {
+			return resolution != ChartResolutionWeekly
+		}
+		return args.Resolution == resolution
+	}
+
 	// If true, we are missing headers or historical conversion rates necessary to compute the chart
 	// data,
 	chartDataMissing := false
@@ -104,29 +195,51 @@ func (backend *Backend) ChartData() (*Chart, error) {
 	// key: unix timestamp.
 	chartEntriesDaily := map[int64]RatChartEntry{}
 	chartEntriesHourly := map[int64]RatChartEntry{}
+	chartEntriesWeekly := map[int64]RatChartEntry{}
 
 	fiat := backend.Config().AppConfig().Backend.MainFiat
-
+	unit := fiat
+	// Formats the values of the chart in its unit.
+	format := func(value *big.Rat) string {
+		return coin.FormatAsCurrency(value, fiat)
+	}
 	// Chart data until this point in time.
-	until := backend.RatesUpdater().HistoryLatestTimestampFiat(backend.allCoinCodes(), fiat)
-	if until.IsZero() {
-		chartDataMissing = true
-		backend.log.Info("ChartDataMissing, until is zero")
+	var until time.Time
+	if args.CoinUnit {
+		until = time.Now()
+		if len(chartAccounts) > 0 {
+			accountCoin := chartAccounts[0].Coin()
+			unit = accountCoin.GetFormatUnit(false)
+			coinDecimals := coin.DecimalsExp(accountCoin, false)
+			format = func(value *big.Rat) string {
+				amount := new(big.Rat).Mul(value, new(big.Rat).SetInt(coinDecimals))
+				return accountCoin.FormatAmount(coin.NewAmount(new(big.Int).Quo(amount.Num(), amount.Denom())), false)
+			}
+		}
+	} else {
+		coinCodes := make([]string, len(chartAccounts))
+		for i, account := range chartAccounts {
+			coinCodes[i] = string(account.Coin().Code())
+		}
+		until = backend.RatesUpdater().HistoryLatestTimestampFiat(coinCodes, fiat)
+		if until.IsZero() {
+			chartDataMissing = true
+			backend.log.Info("ChartDataMissing, until is zero")
+		}
 	}
 	isUpToDate := time.Since(until) < 2*time.Hour
 	lastTimestamp := until.UnixMilli()
+	// The fiat currency used to value the points, empty for coin units.
+	priceFiat := fiat
+	if args.CoinUnit {
+		priceFiat = ""
+	}
 
 	currentTotal := new(big.Rat)
 	currentTotalMissing := false
-	// Total number of transactions across all active accounts.
+	// Total number of transactions across all selected accounts.
 	totalNumberOfTransactions := 0
-	for _, account := range backend.Accounts() {
-		if account.Config().Config.Inactive {
-			continue
-		}
-		if account.FatalError() {
-			continue
-		}
+	for _, account := range chartAccounts {
 		err := account.Initialize()
 		if err != nil {
 			return nil, err
@@ -139,17 +252,25 @@ func (backend *Backend) ChartData() (*Chart, error) {
 
 		coinDecimals := coin.DecimalsExp(account.Coin(), false)
 
-		// HACK: The latest prices might deviate from the latest historical prices (which can lag
-		// behind by many minutes), which results in different total balances in the chart and the
-		// summary table.
-		//
-		// As a workaround, we calls accountFiatBalance, which computes the total based on the latest rates.
-		fiatValue, err := backend.accountFiatBalance(account, fiat)
-		if err != nil {
-			currentTotalMissing = true
-			return nil, err
+		if args.CoinUnit {
+			balance, err := account.Balance()
+			if err != nil {
+				return nil, err
+			}
+			currentTotal.Add(currentTotal, new(big.Rat).SetFrac(balance.Available().BigInt(), coinDecimals))
+		} else {
+			// HACK: The latest prices might deviate from the latest historical prices (which can lag
+			// behind by many minutes), which results in different total balances in the chart and the
+			// summary table.
+			//
+			// As a workaround, we calls accountFiatBalance, which computes the total based on the latest rates.
+			fiatValue, err := backend.accountFiatBalance(account, fiat)
+			if err != nil {
+				currentTotalMissing = true
+				return nil, err
+			}
+			currentTotal.Add(currentTotal, fiatValue)
 		}
-		currentTotal.Add(currentTotal, fiatValue)
 
 		// Below here, only chart data is being computed.
 		if chartDataMissing {
@@ -159,10 +280,6 @@ func (backend *Backend) ChartData() (*Chart, error) {
 		// Time from which the chart turns from daily points to hourly points.
 		hourlyFrom := time.Now().AddDate(0, 0, -7).Truncate(24 * time.Hour)
 
-		earliestPriceAvailable := backend.RatesUpdater().HistoryEarliestTimestamp(
-			string(account.Coin().Code()),
-			fiat)
-
 		earliestTxTime, err := txs.EarliestTime()
 		if errp.Cause(err) == errors.ErrNotAvailable {
 			backend.log.WithField("coin", account.Coin().Code()).Info("ChartDataMissing/earliestTxtime")
@@ -177,49 +294,52 @@ func (backend *Backend) ChartData() (*Chart, error) {
 			// Ignore the chart for this account, there is no timed transaction.
 			continue
 		}
-		if earliestPriceAvailable.IsZero() || earliestTxTime.Before(earliestPriceAvailable) {
-			chartDataMissing = true
-			backend.log.
-				WithField("coin", account.Coin().Code()).
-				WithField("earliestTxTime", earliestTxTime).
-				WithField("earliestPriceAvailable", earliestPriceAvailable).
-				Info("ChartDataMissing")
-			continue
+		if !args.CoinUnit {
+			earliestPriceAvailable := backend.RatesUpdater().HistoryEarliestTimestamp(
+				string(account.Coin().Code()),
+				fiat)
+			if earliestPriceAvailable.IsZero() || earliestTxTime.Before(earliestPriceAvailable) {
+				chartDataMissing = true
+				backend.log.
+					WithField("coin", account.Coin().Code()).
+					WithField("earliestTxTime", earliestTxTime).
+					WithField("earliestPriceAvailable", earliestPriceAvailable).
+					Info("ChartDataMissing")
+				continue
+			}
 		}
 
-		timeseriesDaily, err := txs.Timeseries(
-			earliestTxTime.Truncate(24*time.Hour),
-			until,
-			24*time.Hour,
-		)
-		if errp.Cause(err) == errors.ErrNotAvailable {
-			backend.log.WithField("coin", account.Coin().Code()).Info("ChartDataMissing")
-			chartDataMissing = true
-			continue
+		series := []struct {
+			resolution   ChartResolution
+			start        time.Time
+			interval     time.Duration
+			chartEntries map[int64]RatChartEntry
+		}{
+			{ChartResolutionDaily, earliestTxTime.Truncate(24 * time.Hour), 24 * time.Hour, chartEntriesDaily},
+			{ChartResolutionHourly, hourlyFrom, time.Hour, chartEntriesHourly},
+			{ChartResolutionWeekly, weekStart(earliestTxTime), 7 * 24 * time.Hour, chartEntriesWeekly},
 		}
-		if err != nil {
-			return nil, err
-		}
-		timeseriesHourly, err := txs.Timeseries(
-			hourlyFrom,
-			until,
-			time.Hour,
-		)
-		if errp.Cause(err) == errors.ErrNotAvailable {
-			backend.log.WithField("coin", account.Coin().Code()).Info("ChartDataMissing")
-			chartDataMissing = true
-			continue
-		}
-		if err != nil {
-			return nil, err
+		for _, ts := range series {
+			if !withResolution(ts.resolution) || chartDataMissing {
+				continue
+			}
+			timeseries, err := txs.Timeseries(ts.start, until, ts.interval)
+			if errp.Cause(err) == errors.ErrNotAvailable {
+				backend.log.WithField("coin", account.Coin().Code()).Info("ChartDataMissing")
+				chartDataMissing = true
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			backend.addChartData(account.Coin().Code(), priceFiat, coinDecimals, timeseries, ts.chartEntries)
 		}
-
-		backend.addChartData(account.Coin().Code(), fiat, coinDecimals, timeseriesDaily, chartEntriesDaily)
-		backend.addChartData(account.Coin().Code(), fiat, coinDecimals, timeseriesHourly, chartEntriesHourly)
-
 	}
 
-	toSortedSlice := func(s map[int64]RatChartEntry, fiat string) []ChartEntry {
+	toSortedSlice := func(resolution ChartResolution, s map[int64]RatChartEntry) []ChartEntry {
+		if !withResolution(resolution) {
+			return []ChartEntry{}
+		}
 		result := make([]ChartEntry, len(s))
 		i := 0
 		// Discard the RatValue, which is not used anymore
@@ -228,7 +348,7 @@ func (backend *Backend) ChartData() (*Chart, error) {
 			result[i] = ChartEntry{
 				Time:           entry.Time,
 				Value:          floatValue,
-				FormattedValue: coin.FormatAsCurrency(entry.RatValue, fiat),
+				FormattedValue: format(entry.RatValue),
 			}
 			i++
 		}
@@ -244,7 +364,7 @@ func (backend *Backend) ChartData() (*Chart, error) {
 			result = append(result, ChartEntry{
 				Time:           time.Now().Unix(),
 				Value:          total,
-				FormattedValue: coin.FormatAsCurrency(currentTotal, fiat),
+				FormattedValue: format(currentTotal),
 			})
 		}
 		// Truncate leading zeroes, if there are any keep the first one to start the chart with 0
@@ -278,13 +398,15 @@ func (backend *Backend) ChartData() (*Chart, error) {
 	if !currentTotalMissing {
 		tot, _ := currentTotal.Float64()
 		chartTotal = &tot
-		formattedChartTotal = coin.FormatAsCurrency(currentTotal, fiat)
+		formattedChartTotal = format(currentTotal)
 	}
 	return &Chart{
 		DataMissing:    chartDataMissing,
-		DataDaily:      toSortedSlice(chartEntriesDaily, fiat),
-		DataHourly:     toSortedSlice(chartEntriesHourly, fiat),
+		DataDaily:      toSortedSlice(ChartResolutionDaily, chartEntriesDaily),
+		DataHourly:     toSortedSlice(ChartResolutionHourly, chartEntriesHourly),
+		DataWeekly:     toSortedSlice(ChartResolutionWeekly, chartEntriesWeekly),
 		Fiat:           fiat,
+		Unit:           unit,
 		Total:          chartTotal,
 		FormattedTotal: formattedChartTotal,
 		IsUpToDate:     isUpToDate,
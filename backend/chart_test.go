@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"testing"
+	"time"
+
+	accountsTypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/types"
+	coinpkg "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeekStart(t *testing.T) {
+	monday := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, monday, weekStart(monday).UTC())
+	require.Equal(t, monday, weekStart(monday.Add(time.Hour)).UTC())
+	require.Equal(t, monday, weekStart(time.Date(2024, 6, 9, 23, 59, 0, 0, time.UTC)).UTC())
+	require.Equal(t, monday.AddDate(0, 0, 7), weekStart(time.Date(2024, 6, 10, 1, 0, 0, 0, time.UTC)).UTC())
+}
+
+func TestChartAccounts(t *testing.T) {
+	ks := makeBitBox02Multi()
+	ks.RootFingerprintFunc = func() ([]byte, error) {
+		return rootFingerprint1, nil
+	}
+
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+	b.registerKeystore(ks)
+	_, err := b.CreateAndPersistAccountConfig(coinpkg.CodeBTC, "A second Bitcoin account", ks)
+	require.NoError(t, err)
+
+	codes := func(args ChartArgs) []accountsTypes.Code {
+		chartAccounts, err := b.chartAccounts(args)
+		require.NoError(t, err)
+		result := []accountsTypes.Code{}
+		for _, account := range chartAccounts {
+			result = append(result, account.Config().Config.Code)
+		}
+		return result
+	}
+
+	// Hidden unused accounts can be added in the background, so we only check a subset.
+	defaultAccounts := []accountsTypes.Code{
+		"v0-55555555-btc-0", "v0-55555555-btc-1", "v0-55555555-ltc-0", "v0-55555555-eth-0",
+	}
+	require.Subset(t, codes(ChartArgs{}), defaultAccounts)
+	require.Subset(t, codes(ChartArgs{RootFingerprint: "55555555"}), defaultAccounts)
+	require.Empty(t, codes(ChartArgs{RootFingerprint: "66666666"}))
+	require.Equal(t,
+		[]accountsTypes.Code{"v0-55555555-btc-0", "v0-55555555-btc-1"},
+		codes(ChartArgs{
+			AccountCodes: []accountsTypes.Code{"v0-55555555-btc-0", "v0-55555555-btc-1"},
+			CoinUnit:     true,
+		}))
+
+	_, err = b.chartAccounts(ChartArgs{RootFingerprint: "xyz"})
+	require.Error(t, err)
+	_, err = b.chartAccounts(ChartArgs{AccountCodes: []accountsTypes.Code{"unknown"}})
+	require.Error(t, err)
+	_, err = b.chartAccounts(ChartArgs{CoinUnit: true})
+	require.Error(t, err)
+
+	_, err = b.ChartData(ChartArgs{Resolution: "monthly"})
+	require.Error(t, err)
+}
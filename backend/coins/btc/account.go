@@ -101,6 +101,10 @@ type Account struct {
 
 	transactions transactions.Interface
 
+	// isolatedBlockchain is the separate connection of this account if stream isolation is
+	// enabled, see `Coin.NewAccountBlockchain()`. nil if the connection of the coin is used.
+	isolatedBlockchain blockchain.Interface
+
 	// if not nil, SendTx() will sign and send this transaction. Set by TxProposal().
 	activeTxProposal     *maketx.TxProposal
 	activeTxProposalLock locker.Locker
@@ -154,6 +158,23 @@ func NewAccount(
 	return account
 }
 
+// blockchain returns the connection to the blockchain backend used by this account.
+func (account *Account) blockchain() blockchain.Interface {
+	if account.isolatedBlockchain != nil {
+		return account.isolatedBlockchain
+	}
+	return account.coin.Blockchain()
+}
+
+// ManualReconnect reconnects the separate connection of this account if it is down. The connection
+// of the coin is reconnected by `Coin.Blockchain().ManualReconnect()`.
+func (account *Account) ManualReconnect() {
+	defer account.initializedLock.RLock()()
+	if account.isolatedBlockchain != nil {
+		account.isolatedBlockchain.ManualReconnect()
+	}
+}
+
 // String returns a representation of the account for logging.
 func (account *Account) String() string {
 	return fmt.Sprintf("%s-%s", account.Coin().Code(), account.Config().Config.Code)
@@ -251,7 +272,7 @@ func (account *Account) getMinRelayFeeRate() (btcutil.Amount, error) {
 		return *cached, nil
 	}
 
-	feeRate, err := account.coin.Blockchain().RelayFee()
+	feeRate, err := account.blockchain().RelayFee()
 	if err != nil {
 		return 0, err
 	}
@@ -290,6 +311,10 @@ func (account *Account) Initialize() error {
 			account.transactions.Close()
 			account.transactions = nil
 		}
+		if account.isolatedBlockchain != nil {
+			account.isolatedBlockchain.Close()
+			account.isolatedBlockchain = nil
+		}
 		if account.db != nil {
 			if closeErr := account.db.Close(); closeErr != nil {
 				account.log.WithError(closeErr).Error("couldn't close db")
@@ -306,19 +331,24 @@ func (account *Account) Initialize() error {
 	account.notifier = account.Config().GetNotifier(signingConfigurations)
 
 	accountIdentifier := fmt.Sprintf("account-%s", account.Config().Config.Code)
-	account.dbSubfolder = path.Join(account.Config().DBFolder, accountIdentifier)
-	if err := os.MkdirAll(account.dbSubfolder, 0700); err != nil {
-		return errp.WithStack(err)
-	}
+	if account.Config().InMemoryDB {
+		account.log.Debug("Keeping the transactions in memory.")
+		account.db = transactionsdb.NewMemoryDB()
+	} else {
+		account.dbSubfolder = path.Join(account.Config().DBFolder, accountIdentifier)
+		if err := os.MkdirAll(account.dbSubfolder, 0700); err != nil {
+			return errp.WithStack(err)
+		}
 
-	dbName := fmt.Sprintf("%s.db", accountIdentifier)
-	account.log.Debugf("Opening the database '%s' to persist the transactions.", dbName)
-	db, err := transactionsdb.NewDB(path.Join(account.Config().DBFolder, dbName))
-	if err != nil {
-		return err
+		dbName := fmt.Sprintf("%s.db", accountIdentifier)
+		account.log.Debugf("Opening the database '%s' to persist the transactions.", dbName)
+		db, err := transactionsdb.NewDB(path.Join(account.Config().DBFolder, dbName))
+		if err != nil {
+			return err
+		}
+		account.db = db
+		account.log.Debugf("Opened the database '%s' to persist the transactions.", dbName)
 	}
-	account.db = db
-	account.log.Debugf("Opened the database '%s' to persist the transactions.", dbName)
 
 	onConnectionStatusChanged := func(err error) {
 		if err != nil {
@@ -336,11 +366,12 @@ func (account *Account) Initialize() error {
 	if err := account.coin.Initialize(); err != nil {
 		return err
 	}
-	account.SetOffline(account.coin.Blockchain().ConnectionError())
+	account.isolatedBlockchain = account.coin.NewAccountBlockchain(string(account.Config().Config.Code))
+	account.SetOffline(account.blockchain().ConnectionError())
 	theHeaders := account.coin.Headers()
 	account.transactions = transactions.NewTransactions(
 		account.coin.Net(), account.db, theHeaders, account.Synchronizer,
-		account.coin.Blockchain(), account.notifier, account.log)
+		account.blockchain(), account.notifier, account.log)
 
 	for _, signingConfiguration := range signingConfigurations {
 
@@ -363,7 +394,7 @@ func (account *Account) Initialize() error {
 	if err := account.BaseAccount.Initialize(accountIdentifier); err != nil {
 		return err
 	}
-	account.coin.Blockchain().RegisterOnConnectionErrorChangedEvent(onConnectionStatusChanged)
+	account.blockchain().RegisterOnConnectionErrorChangedEvent(onConnectionStatusChanged)
 	account.initialized = true
 	go account.ensureAddresses()
 	return nil
@@ -462,6 +493,9 @@ func (account *Account) Close() {
 	if account.transactions != nil {
 		account.transactions.Close()
 	}
+	if account.isolatedBlockchain != nil {
+		account.isolatedBlockchain.Close()
+	}
 
 	if account.db != nil {
 		if err := account.db.Close(); err != nil {
@@ -537,7 +571,7 @@ func (account *Account) feeTargets() FeeTargets {
 			} else {
 				// If mempool.space fees are not available, we fallback on Bitcoin Core estimation.
 				// If even that one is not available, we just offer the min relay fee.
-				estimatedFeeRatePerKb, err := account.coin.Blockchain().EstimateFee(feeTarget.blocks)
+				estimatedFeeRatePerKb, err := account.blockchain().EstimateFee(feeTarget.blocks)
 				if err != nil {
 					if account.coin.Code() != coin.CodeTLTC {
 						account.log.WithField("fee-target", feeTarget.blocks).
@@ -610,7 +644,18 @@ func (account *Account) Balance() (*accounts.Balance, error) {
 	if err != nil {
 		return nil, err
 	}
-	return balance, nil
+	utxos, err := account.transactions.SpendableOutputs()
+	if err != nil {
+		return nil, err
+	}
+	var frozen int64
+	for outPoint, txOut := range utxos {
+		if account.IsOutPointFrozen(outPoint) {
+			frozen += txOut.TxOut.Value
+		}
+	}
+	return accounts.NewBalanceWithFrozen(
+		balance.Available(), balance.Incoming(), coin.NewAmountFromInt64(frozen)), nil
 }
 
 func (account *Account) incAndEmitSyncCounter() {
@@ -679,9 +724,9 @@ func (account *Account) onAddressStatus(address *addresses.AccountAddress, statu
 	account.log.Debug("Address status changed, fetching history.")
 
 	defer account.Synchronizer.IncRequestsCounter()()
-	history, err := account.coin.Blockchain().ScriptHashGetHistory(address.PubkeyScriptHashHex())
+	history, err := account.blockchain().ScriptHashGetHistory(address.PubkeyScriptHashHex())
 	if err != nil {
-		// We are not closing client.blockchain here, as it is reused per coin with
+		// We are not closing the blockchain here, as it can be reused per coin with
 		// different accounts.
 		account.reportFatalSyncError(err, "ScriptHashGetHistory failed")
 		return
@@ -729,7 +774,7 @@ func (account *Account) ensureAddresses() {
 }
 
 func (account *Account) subscribeAddress(address *addresses.AccountAddress) {
-	account.coin.Blockchain().ScriptHashSubscribe(
+	account.blockchain().ScriptHashSubscribe(
 		account.Synchronizer.IncRequestsCounter,
 		address.PubkeyScriptHashHex(),
 		func(status string) {
@@ -749,7 +794,11 @@ func (account *Account) Transactions() (accounts.OrderedTransactions, error) {
 	if !account.Synced() {
 		return nil, accounts.ErrSyncInProgress
 	}
-	return account.transactions.Transactions(account.IsChange)
+	transactions, err := account.transactions.Transactions(account.IsChange)
+	if err != nil {
+		return nil, err
+	}
+	return account.LabelContacts(transactions), nil
 }
 
 // GetUnusedReceiveAddresses returns a number of unused addresses. Returns nil if the account is not initialized.
@@ -1022,6 +1071,8 @@ type SpendableOutput struct {
 	OutPoint wire.OutPoint
 	Address  *addresses.AccountAddress
 	IsChange bool
+	// Frozen is true if the output is excluded from spending, see `SetOutPointFrozen()`.
+	Frozen bool
 }
 
 func (account *Account) makeSpendableOutputs(
@@ -1037,6 +1088,7 @@ func (account *Account) makeSpendableOutputs(
 				SpendableOutput: txOut,
 				Address:         account.AddressByID(addressID),
 				IsChange:        account.IsChange(addressID),
+				Frozen:          account.IsOutPointFrozen(outPoint),
 			})
 	}
 	return sortByAddresses(result)
@@ -1054,6 +1106,32 @@ func (account *Account) SpendableOutputs() ([]*SpendableOutput, error) {
 	return account.makeSpendableOutputs(utxos), nil
 }
 
+// IsOutPointFrozen returns true if the output was frozen by the user. Frozen outputs are never
+// spent. They are stored as outputs which are not spendable in the account notes, so they are
+// included in the BIP-329 labels export and import.
+func (account *Account) IsOutPointFrozen(outPoint wire.OutPoint) bool {
+	return !account.Notes().OutputSpendable(outPoint.String())
+}
+
+// SetOutPointFrozen freezes or unfreezes an output. Frozen outputs are not used in coin selection
+// and sending the maximum amount, even if selected manually. The frozen state is persisted and
+// can be set also for outputs which are spent or not known yet.
+func (account *Account) SetOutPointFrozen(outPoint wire.OutPoint, frozen bool) error {
+	changed, err := account.Notes().SetOutputSpendable(outPoint.String(), !frozen)
+	if err != nil {
+		return err
+	}
+	if changed {
+		// Reload balance and UTXOs in the frontend.
+		account.Notify(observable.Event{
+			Subject: string(accountsTypes.EventSyncDone),
+			Action:  action.Replace,
+			Object:  nil,
+		})
+	}
+	return nil
+}
+
 // ReusedAddressesForOutputs returns the subset of the provided outputs whose addresses are reused
 // across all indexed wallet outputs.
 func (account *Account) ReusedAddressesForOutputs(
@@ -5,6 +5,7 @@ package btc
 import (
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"math/big"
 	"os"
 	"testing"
@@ -82,6 +83,7 @@ func mockAccount(t *testing.T, accountConfig *config.Account) *Account {
 		&accounts.AccountConfig{
 			Config:          accountConfig,
 			DBFolder:        dbFolder,
+			NotesFolder:     test.TstTempDir("btc-notesfolder"),
 			RateUpdater:     nil,
 			GetNotifier:     func(signing.Configurations) accounts.Notifier { return nil },
 			GetSaveFilename: func(suggestedFilename string) string { return suggestedFilename },
@@ -300,6 +302,26 @@ func TestIsChange(t *testing.T) {
 	}
 }
 
+func TestAddressByStringAndPublicKey(t *testing.T) {
+	account := mockAccount(t, nil)
+	require.NoError(t, account.Initialize())
+	require.Eventually(t, account.Synced, time.Second, time.Millisecond*200)
+	account.ensureAddresses()
+	for _, subacc := range account.subaccounts {
+		unusedChangeAddresses, err := subacc.changeAddresses.GetUnused()
+		require.NoError(t, err)
+		for _, address := range unusedChangeAddresses {
+			require.Equal(t, address, account.AddressByString(address.EncodeForHumans()))
+			require.Equal(t, address, account.AddressByPublicKey(
+				hex.EncodeToString(address.PublicKey.SerializeCompressed())))
+		}
+	}
+	require.Nil(t, account.AddressByString("invalid"))
+	require.Nil(t, account.AddressByPublicKey("invalid"))
+	require.Nil(t, account.AddressByPublicKey(
+		"0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"))
+}
+
 func makeSigningConfiguration(
 	t *testing.T,
 	net *chaincfg.Params,
@@ -3,6 +3,8 @@
 package addresses
 
 import (
+	"bytes"
+
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc/types"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/signing"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
@@ -100,6 +102,18 @@ func (addresses *AddressChain) LookupByAddressID(addressID AddressID) *AccountAd
 	return addresses.addressesByID[addressID]
 }
 
+// LookupByPublicKey returns the address of the provided serialized public key. Returns nil if not
+// found.
+func (addresses *AddressChain) LookupByPublicKey(publicKey []byte) *AccountAddress {
+	defer addresses.addressesLock.RLock()()
+	for _, address := range addresses.addresses {
+		if address.PublicKey != nil && bytes.Equal(address.PublicKey.SerializeCompressed(), publicKey) {
+			return address
+		}
+	}
+	return nil
+}
+
 // EnsureAddresses appends addresses to the address chain until there are `gapLimit` unused
 // ones, and returns the new addresses.
 func (addresses *AddressChain) EnsureAddresses() ([]*AccountAddress, error) {
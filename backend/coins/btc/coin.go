@@ -7,16 +7,20 @@ import (
 	"math/big"
 	"os"
 	"path"
+	"strings"
 	"sync"
 
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc/addresses"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc/blockchain"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc/db/headersdb"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc/electrum"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc/headers"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc/types"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc/util"
 	coinpkg "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/signing"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/logging"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/observable"
@@ -24,6 +28,7 @@ import (
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/socksproxy"
 	"github.com/BitBoxSwiss/bitbox02-api-go/api/firmware"
 	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/sirupsen/logrus"
 )
@@ -37,10 +42,14 @@ type Coin struct {
 	// unit is the main unit of the coin, e.g. 'BTC'
 	unit string
 	// formatUnit keeps track of the unit used, e.g. 'BTC' or 'sat' depening on if sat mode is enabled
-	formatUnit                 coinpkg.BtcUnit
-	net                        *chaincfg.Params
-	dbFolder                   string
-	makeBlockchain             func() blockchain.Interface
+	formatUnit     coinpkg.BtcUnit
+	net            *chaincfg.Params
+	dbFolder       string
+	makeBlockchain func() blockchain.Interface
+	// makeAccountBlockchain makes a separate connection for an account, so that the queries of
+	// different accounts can't be linked by Tor exit nodes. nil if the accounts share the
+	// connection of the coin.
+	makeAccountBlockchain      func(isolationKey string) blockchain.Interface
 	blockExplorerTxPrefix      string
 	blockExplorerAddressPrefix string
 
@@ -79,11 +88,20 @@ func NewCoin(
 			return electrum.NewElectrumConnection(
 				servers,
 				log,
-				socksProxy.GetTCPProxyDialer(),
+				socksProxy.ServiceTCPDialer(socksproxy.ServiceElectrum, string(code)),
 			)
 		},
 		log: log,
 	}
+	if socksProxy.IsolatesStreams(socksproxy.ServiceElectrum) {
+		coin.makeAccountBlockchain = func(isolationKey string) blockchain.Interface {
+			return electrum.NewElectrumConnection(
+				servers,
+				log.WithField("isolated", true),
+				socksProxy.ServiceTCPDialer(socksproxy.ServiceElectrum, isolationKey),
+			)
+		}
+	}
 	return coin
 }
 
@@ -93,6 +111,15 @@ func (coin *Coin) TstSetMakeBlockchain(f func() blockchain.Interface) {
 	coin.makeBlockchain = f
 }
 
+// NewAccountBlockchain returns a separate connection for an account if stream isolation is enabled,
+// or nil if the account uses the connection of the coin. The caller must close it.
+func (coin *Coin) NewAccountBlockchain(isolationKey string) blockchain.Interface {
+	if coin.makeAccountBlockchain == nil {
+		return nil
+	}
+	return coin.makeAccountBlockchain(isolationKey)
+}
+
 // Initialize implements coinpkg.Coin.
 func (coin *Coin) Initialize() error {
 	coin.initLock.Lock()
@@ -302,6 +329,56 @@ func (coin *Coin) decodeAddress(address string) (btcutil.Address, error) {
 	return btcAddress, nil
 }
 
+// xpubVersionScriptTypes maps the version bytes of SLIP-132 extended public keys to the script type
+// they are used for. Other versions like xpub and tpub are used for any script type.
+var xpubVersionScriptTypes = map[[4]byte]signing.ScriptType{
+	{0x04, 0x9d, 0x7c, 0xb2}: signing.ScriptTypeP2WPKHP2SH, // ypub
+	{0x04, 0x4a, 0x52, 0x62}: signing.ScriptTypeP2WPKHP2SH, // upub
+	{0x04, 0xb2, 0x47, 0x46}: signing.ScriptTypeP2WPKH,     // zpub
+	{0x04, 0x5f, 0x1c, 0xf6}: signing.ScriptTypeP2WPKH,     // vpub
+}
+
+// DeriveReceiveAddresses returns the first `count` receive addresses (`<xpub>/0/i`) of an extended
+// public key of an account of this coin, e.g. of a third party. If the version bytes of the xpub do
+// not determine the script type (ypub, zpub, ...), the addresses of all single-sig script types are
+// returned.
+func (coin *Coin) DeriveReceiveAddresses(xpub string, count int) ([]string, error) {
+	extendedPublicKey, err := hdkeychain.NewKeyFromString(strings.TrimSpace(xpub))
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if extendedPublicKey.IsPrivate() {
+		return nil, errp.New("expected an extended public key")
+	}
+	var version [4]byte
+	copy(version[:], extendedPublicKey.Version())
+	scriptTypes := []signing.ScriptType{
+		signing.ScriptTypeP2PKH, signing.ScriptTypeP2WPKHP2SH, signing.ScriptTypeP2WPKH,
+	}
+	switch coin.code {
+	case coinpkg.CodeBTC, coinpkg.CodeTBTC, coinpkg.CodeRBTC:
+		scriptTypes = append(scriptTypes, signing.ScriptTypeP2TR)
+	}
+	if scriptType, ok := xpubVersionScriptTypes[version]; ok {
+		scriptTypes = []signing.ScriptType{scriptType}
+	}
+	result := []string{}
+	for _, scriptType := range scriptTypes {
+		configuration := signing.NewBitcoinConfiguration(
+			scriptType, nil, signing.NewEmptyAbsoluteKeypath(), extendedPublicKey)
+		for index := 0; index < count; index++ {
+			address := addresses.NewAccountAddress(
+				configuration,
+				types.Derivation{Change: false, AddressIndex: uint32(index)},
+				coin.Net(),
+				coin.log,
+			)
+			result = append(result, address.EncodeForHumans())
+		}
+	}
+	return result, nil
+}
+
 // AddressToPkScript decodes a btc/ltc address, checking that the format matches the account coin
 // type, returning the pubKeyScript the address represents.
 //
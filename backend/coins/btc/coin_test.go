@@ -19,6 +19,7 @@ import (
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/test"
 	"github.com/BitBoxSwiss/block-client-go/electrum/types"
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -253,3 +254,46 @@ func (s *testSuite) TestValidateSilentPaymentAddress() {
 		s.Require().Error(s.coin.ValidateSilentPaymentAddress(validTBTC))
 	}
 }
+
+func TestDeriveReceiveAddresses(t *testing.T) {
+	btcCoin := NewCoin(coin.CodeBTC, "Bitcoin", "BTC", coin.BtcUnitDefault, &chaincfg.MainNetParams,
+		test.TstTempDir("btc-dbfolder"), nil, explorer, addressExplorer, socksproxy.NewSocksProxy(false, ""))
+
+	// BIP-84 test vector.
+	addresses, err := btcCoin.DeriveReceiveAddresses(
+		"zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs",
+		2)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu",
+		"bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g",
+	}, addresses)
+
+	// A plain xpub yields the addresses of all script types.
+	addresses, err = btcCoin.DeriveReceiveAddresses(
+		"xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V",
+		1)
+	require.NoError(t, err)
+	require.Len(t, addresses, 4)
+
+	_, err = btcCoin.DeriveReceiveAddresses("invalid", 1)
+	require.Error(t, err)
+}
+
+func TestNewAccountBlockchain(t *testing.T) {
+	btcCoin := NewCoin(coin.CodeBTC, "Bitcoin", "BTC", coin.BtcUnitDefault, &chaincfg.MainNetParams,
+		test.TstTempDir("btc-dbfolder"), nil, explorer, addressExplorer, socksproxy.NewSocksProxy(true, ""))
+	require.Nil(t, btcCoin.NewAccountBlockchain("account"))
+
+	// Electrum is not routed through the proxy, so there is nothing to isolate.
+	btcCoin = NewCoin(coin.CodeBTC, "Bitcoin", "BTC", coin.BtcUnitDefault, &chaincfg.MainNetParams,
+		test.TstTempDir("btc-dbfolder"), nil, explorer, addressExplorer,
+		socksproxy.NewSocksProxyWithRoutes(true, "",
+			map[socksproxy.Service]socksproxy.Route{socksproxy.ServiceElectrum: socksproxy.RouteDirect}, true))
+	require.Nil(t, btcCoin.NewAccountBlockchain("account"))
+
+	btcCoin = NewCoin(coin.CodeBTC, "Bitcoin", "BTC", coin.BtcUnitDefault, &chaincfg.MainNetParams,
+		test.TstTempDir("btc-dbfolder"), nil, explorer, addressExplorer,
+		socksproxy.NewSocksProxyWithRoutes(true, "", nil, true))
+	require.NotNil(t, btcCoin.makeAccountBlockchain)
+}
@@ -13,9 +13,9 @@ import (
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc/types"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc/util"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/kvdb"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
-	"go.etcd.io/bbolt"
 )
 
 const (
@@ -27,20 +27,25 @@ const (
 	bucketConfigKey                 = "config"
 )
 
-// DB is a bbolt key/value database.
+// DB is a key/value database, persisted with bbolt or kept in memory.
 type DB struct {
-	db *bbolt.DB
+	db kvdb.DB
 }
 
 // NewDB creates/opens a new db.
 func NewDB(filename string) (*DB, error) {
-	db, err := bbolt.Open(filename, 0600, nil)
+	db, err := kvdb.OpenBolt(filename)
 	if err != nil {
-		return nil, errp.WithStack(err)
+		return nil, err
 	}
 	return &DB{db: db}, nil
 }
 
+// NewMemoryDB creates a db which is kept only in memory.
+func NewMemoryDB() *DB {
+	return &DB{db: kvdb.NewMemory()}
+}
+
 // Begin implements transactions.Begin.
 func (db *DB) Begin(writable bool) (transactions.DBTxInterface, error) {
 	tx, err := db.db.Begin(writable)
@@ -59,7 +64,7 @@ func (db *DB) Close() error {
 
 // Tx implements transactions.DBTxInterface.
 type Tx struct {
-	tx *bbolt.Tx
+	tx kvdb.Tx
 }
 
 // Rollback implements transactions.DBTxInterface.
@@ -81,7 +86,7 @@ func newWalletTransaction() *transactions.DBTxInfo {
 	}
 }
 
-func readJSON(bucket *bbolt.Bucket, key []byte, value interface{}) (bool, error) {
+func readJSON(bucket kvdb.Bucket, key []byte, value interface{}) (bool, error) {
 	if bucket == nil {
 		return false, nil
 	}
@@ -91,7 +96,7 @@ func readJSON(bucket *bbolt.Bucket, key []byte, value interface{}) (bool, error)
 	return false, nil
 }
 
-func writeJSON(bucket *bbolt.Bucket, key []byte, value interface{}) error {
+func writeJSON(bucket kvdb.Bucket, key []byte, value interface{}) error {
 	jsonBytes, err := json.Marshal(value)
 	if err != nil {
 		return err
@@ -184,7 +189,7 @@ func (tx *Tx) RemoveAddressFromTx(txHash chainhash.Hash, scriptHashHex blockchai
 	return empty, err
 }
 
-func getTransactions(bucket *bbolt.Bucket) ([]chainhash.Hash, error) {
+func getTransactions(bucket kvdb.Bucket) ([]chainhash.Hash, error) {
 	result := []chainhash.Hash{}
 	if bucket == nil {
 		return result, nil
@@ -29,7 +29,12 @@ func getDB() *DB {
 }
 
 func TestCommit(t *testing.T) {
-	db := getDB()
+	testCommit(t, getDB())
+	testCommit(t, NewMemoryDB())
+}
+
+func testCommit(t *testing.T, db *DB) {
+	t.Helper()
 	defer func() {
 		require.NoError(t, db.Close())
 	}()
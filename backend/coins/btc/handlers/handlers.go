@@ -3,8 +3,10 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math/big"
 	"net/http"
 	"os"
@@ -19,15 +21,20 @@ import (
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/etherscan"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/txpreview"
+	ethtypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/types"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/keystore"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/paymentrequest"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/signing"
 	backendutil "github.com/BitBoxSwiss/bitbox-wallet-app/backend/util"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/config"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/locker"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/wire"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
@@ -36,6 +43,11 @@ import (
 type Handlers struct {
 	account accounts.Interface
 	log     *logrus.Entry
+
+	// unconfirmedResemblance is true if the recipient of the active tx proposal resembles an
+	// address book contact and the user did not confirm sending to it. The tx can not be sent then.
+	unconfirmedResemblance     bool
+	unconfirmedResemblanceLock locker.Locker
 }
 
 func formatAddressForDisplay(account accounts.Interface, address string) string {
@@ -54,6 +66,7 @@ func NewHandlers(
 	handleFunc("/export", handlers.ensureAccountInitialized(handlers.postExportTransactions)).Methods("POST")
 	handleFunc("/info", handlers.ensureAccountInitialized(handlers.getAccountInfo)).Methods("GET")
 	handleFunc("/utxos", handlers.ensureAccountInitialized(handlers.getUTXOs)).Methods("GET")
+	handleFunc("/utxos/frozen", handlers.ensureAccountInitialized(handlers.postUTXOsFrozen)).Methods("POST")
 	handleFunc("/balance", handlers.ensureAccountInitialized(handlers.getAccountBalance)).Methods("GET")
 	handleFunc("/sendtx", handlers.ensureAccountInitialized(handlers.postAccountSendTx)).Methods("POST")
 	handleFunc("/fee-targets", handlers.ensureAccountInitialized(handlers.getAccountFeeTargets)).Methods("GET")
@@ -69,9 +82,22 @@ func NewHandlers(
 	handleFunc("/has-payment-request", handlers.ensureAccountInitialized(handlers.getHasPaymentRequest)).Methods("GET")
 	handleFunc("/has-swap-payment-request", handlers.ensureAccountInitialized(handlers.getHasSwapPaymentRequest)).Methods("GET")
 	handleFunc("/notes/tx", handlers.ensureAccountInitialized(handlers.postSetTxNote)).Methods("POST")
+	handleFunc("/notes/address", handlers.ensureAccountInitialized(handlers.postSetAddressNote)).Methods("POST")
+	handleFunc("/notes/output", handlers.ensureAccountInitialized(handlers.postSetOutputNote)).Methods("POST")
 	handleFunc("/eth-sign-msg", handlers.ensureAccountInitialized(handlers.postEthSignMsg)).Methods("POST")
 	handleFunc("/eth-sign-typed-msg", handlers.ensureAccountInitialized(handlers.postEthSignTypedMsg)).Methods("POST")
 	handleFunc("/eth-sign-wallet-connect-tx", handlers.ensureAccountInitialized(handlers.postEthSignWalletConnectTx)).Methods("POST")
+	handleFunc("/eth-wallet-connect-tx-preview", handlers.ensureAccountInitialized(handlers.postEthWalletConnectTxPreview)).Methods("POST")
+	handleFunc("/eth-typed-msg-preview", handlers.ensureAccountInitialized(handlers.postEthTypedMsgPreview)).Methods("POST")
+	handleFunc("/eth-signing-requests", handlers.ensureAccountInitialized(handlers.getEthSigningRequests)).Methods("GET")
+	handleFunc("/eth-allowances", handlers.ensureAccountInitialized(handlers.getEthAllowances)).Methods("GET")
+	handleFunc("/eth-revoke-allowance-proposal", handlers.ensureAccountInitialized(handlers.postEthRevokeAllowanceProposal)).Methods("POST")
+	handleFunc("/eth-nfts", handlers.ensureAccountInitialized(handlers.getEthNFTs)).Methods("GET")
+	handleFunc("/eth-nfts-refresh", handlers.ensureAccountInitialized(handlers.postEthNFTsRefresh)).Methods("POST")
+	handleFunc("/eth-nft-metadata", handlers.ensureAccountInitialized(handlers.getEthNFTMetadata)).Methods("GET")
+	handleFunc("/eth-nonce-state", handlers.ensureAccountInitialized(handlers.getEthNonceState)).Methods("GET")
+	handleFunc("/eth-rebroadcast-tx", handlers.ensureAccountInitialized(handlers.postEthRebroadcastTx)).Methods("POST")
+	handleFunc("/eth-replace-nonce-proposal", handlers.ensureAccountInitialized(handlers.postEthReplaceNonceProposal)).Methods("POST")
 	return handlers
 }
 
@@ -100,7 +126,9 @@ type Transaction struct {
 	Fee                      coin.FormattedAmountWithConversions `json:"fee"`
 	Time                     *string                             `json:"time"`
 	Addresses                []string                            `json:"addresses"`
-	Note                     string                              `json:"note"`
+	// Contacts are the address book contacts paid by the transaction.
+	Contacts []Contact `json:"contacts"`
+	Note     string    `json:"note"`
 
 	// BTC specific fields.
 	VSize       int64  `json:"vsize"`
@@ -113,6 +141,17 @@ type Transaction struct {
 	Nonce *uint64 `json:"nonce"`
 }
 
+// Contact is an address book contact of a transaction address.
+type Contact struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Verified bool   `json:"verified"`
+}
+
+func newContact(contact *accounts.AddressBookContact) Contact {
+	return Contact{ID: contact.ID, Name: contact.Name, Verified: contact.Verified}
+}
+
 func (handlers *Handlers) ensureAccountInitialized(h func(*http.Request) (interface{}, error)) func(*http.Request) (interface{}, error) {
 	return func(request *http.Request) (interface{}, error) {
 		if handlers.account == nil {
@@ -122,20 +161,20 @@ func (handlers *Handlers) ensureAccountInitialized(h func(*http.Request) (interf
 	}
 }
 
-// getTxInfoJSON encodes a given transaction in JSON.
+// NewTransaction encodes a given transaction of an account in JSON.
 // If `detail` is false, Coin related details, fees and historical fiat amount won't be included.
-func (handlers *Handlers) getTxInfoJSON(txInfo *accounts.TransactionData, detail bool) Transaction {
-	accountConfig := handlers.account.Config()
+func NewTransaction(account accounts.Interface, txInfo *accounts.TransactionData, detail bool) Transaction {
+	accountConfig := account.Config()
 	var feeString coin.FormattedAmountWithConversions
 	if txInfo.Fee != nil {
-		feeString = txInfo.Fee.FormatWithConversions(handlers.account.Coin(), true, accountConfig.RateUpdater)
+		feeString = txInfo.Fee.FormatWithConversions(account.Coin(), true, accountConfig.RateUpdater)
 	}
-	amount := txInfo.Amount.FormatWithConversions(handlers.account.Coin(), false, accountConfig.RateUpdater)
+	amount := txInfo.Amount.FormatWithConversions(account.Coin(), false, accountConfig.RateUpdater)
 	var formattedTime *string
 	timestamp := txInfo.Timestamp
 
-	deductedAmountAtTime := txInfo.DeductedAmount.FormatWithConversionsAtTime(handlers.account.Coin(), timestamp, accountConfig.RateUpdater)
-	amountAtTime := txInfo.Amount.FormatWithConversionsAtTime(handlers.account.Coin(), timestamp, accountConfig.RateUpdater)
+	deductedAmountAtTime := txInfo.DeductedAmount.FormatWithConversionsAtTime(account.Coin(), timestamp, accountConfig.RateUpdater)
+	amountAtTime := txInfo.Amount.FormatWithConversionsAtTime(account.Coin(), timestamp, accountConfig.RateUpdater)
 
 	if timestamp != nil {
 		t := timestamp.Format(time.RFC3339)
@@ -143,8 +182,16 @@ func (handlers *Handlers) getTxInfoJSON(txInfo *accounts.TransactionData, detail
 	}
 
 	addresses := []string{}
+	contacts := []Contact{}
+	contactIDs := map[string]struct{}{}
 	for _, addressAndAmount := range txInfo.Addresses {
 		addresses = append(addresses, addressAndAmount.Address)
+		if contact := addressAndAmount.Contact; contact != nil {
+			if _, ok := contactIDs[contact.ID]; !ok {
+				contactIDs[contact.ID] = struct{}{}
+				contacts = append(contacts, newContact(contact))
+			}
+		}
 	}
 	txInfoJSON := Transaction{
 		TxID:                     txInfo.TxID,
@@ -162,12 +209,13 @@ func (handlers *Handlers) getTxInfoJSON(txInfo *accounts.TransactionData, detail
 		DeductedAmountAtTime: deductedAmountAtTime,
 		Time:                 formattedTime,
 		Addresses:            addresses,
-		Note:                 handlers.account.TxNote(txInfo.InternalID),
+		Contacts:             contacts,
+		Note:                 account.TxNote(txInfo.InternalID),
 		Fee:                  feeString,
 	}
 
 	if detail {
-		switch handlers.account.Coin().(type) {
+		switch account.Coin().(type) {
 		case *btc.Coin:
 			txInfoJSON.VSize = txInfo.VSize
 			txInfoJSON.Size = txInfo.Size
@@ -181,6 +229,11 @@ func (handlers *Handlers) getTxInfoJSON(txInfo *accounts.TransactionData, detail
 	return txInfoJSON
 }
 
+// getTxInfoJSON encodes a given transaction of the account in JSON, see `NewTransaction()`.
+func (handlers *Handlers) getTxInfoJSON(txInfo *accounts.TransactionData, detail bool) Transaction {
+	return NewTransaction(handlers.account, txInfo, detail)
+}
+
 func (handlers *Handlers) getAccountTransactions(*http.Request) (interface{}, error) {
 	var result struct {
 		Success      bool          `json:"success"`
@@ -218,12 +271,34 @@ func (handlers *Handlers) getAccountTransaction(r *http.Request) (interface{}, e
 	return nil, nil
 }
 
-func (handlers *Handlers) postExportTransactions(*http.Request) (interface{}, error) {
+func (handlers *Handlers) postExportTransactions(r *http.Request) (interface{}, error) {
 	type result struct {
 		Success      bool   `json:"success"`
 		ErrorMessage string `json:"errorMessage"`
 	}
-	name := fmt.Sprintf("%s-%s-export.csv", time.Now().Format("2006-01-02-at-15-04-05"), handlers.account.Config().Config.Code)
+	// The options are optional, the default is the full export in the default format.
+	var options accounts.ExportOptions
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if len(bytes.TrimSpace(body)) != 0 {
+		if err := json.Unmarshal(body, &options); err != nil {
+			return result{Success: false, ErrorMessage: err.Error()}, nil
+		}
+	}
+	if err := options.Validate(); err != nil {
+		return result{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	if options.Format == "" {
+		options.Format = accounts.ExportFormatDefault
+	}
+
+	suffix := "export"
+	if options.Format != accounts.ExportFormatDefault {
+		suffix = "export-" + string(options.Format)
+	}
+	name := fmt.Sprintf("%s-%s-%s.csv", time.Now().Format("2006-01-02-at-15-04-05"), handlers.account.Config().Config.Code, suffix)
 	exportsDir, err := config.ExportsDir()
 	if err != nil {
 		handlers.log.WithError(err).Error("error exporting account")
@@ -241,13 +316,23 @@ func (handlers *Handlers) postExportTransactions(*http.Request) (interface{}, er
 		handlers.log.WithError(err).Error("error getting the transactions")
 		return result{Success: false, ErrorMessage: err.Error()}, nil
 	}
+	filtered := options.Filter(transactions)
+	var exportTransactions []*accounts.ExportTransaction
+	if options.Format != accounts.ExportFormatDefault {
+		exportTransactions = accounts.NewExportTransactions(handlers.account, filtered, options.Fiat)
+	}
 
 	file, err := os.Create(path)
 	if err != nil {
 		handlers.log.WithError(err).Error("error creating file")
 		return result{Success: false, ErrorMessage: err.Error()}, nil
 	}
-	if err := handlers.account.ExportCSV(file, transactions); err != nil {
+	if options.Format == accounts.ExportFormatDefault {
+		err = handlers.account.ExportCSV(file, filtered)
+	} else {
+		err = accounts.WriteExportCSV(file, options.Format, exportTransactions)
+	}
+	if err != nil {
 		_ = file.Close()
 		handlers.log.WithError(err).Error("error writing file")
 		return result{Success: false, ErrorMessage: err.Error()}, nil
@@ -324,8 +409,11 @@ func (handlers *Handlers) getUTXOs(*http.Request) (interface{}, error) {
 		Address         string                              `json:"address"`
 		ScriptType      signing.ScriptType                  `json:"scriptType"`
 		Note            string                              `json:"note"`
+		OutputNote      string                              `json:"outputNote"`
+		AddressNote     string                              `json:"addressNote"`
 		AddressReused   bool                                `json:"addressReused"`
 		IsChange        bool                                `json:"isChange"`
+		Frozen          bool                                `json:"frozen"`
 		HeaderTimestamp *string                             `json:"headerTimestamp"`
 	}
 	result := []utxoResponse{}
@@ -363,8 +451,11 @@ func (handlers *Handlers) getUTXOs(*http.Request) (interface{}, error) {
 				Address:         address,
 				ScriptType:      output.Address.AccountConfiguration.ScriptType(),
 				Note:            handlers.account.TxNote(output.OutPoint.Hash.String()),
+				OutputNote:      handlers.account.Notes().OutputNote(output.OutPoint.String()),
+				AddressNote:     handlers.account.Notes().AddressNote(address),
 				AddressReused:   addressReused,
 				IsChange:        output.IsChange,
+				Frozen:          output.Frozen,
 				HeaderTimestamp: formattedTime,
 			})
 	}
@@ -372,6 +463,30 @@ func (handlers *Handlers) getUTXOs(*http.Request) (interface{}, error) {
 	return result, nil
 }
 
+func (handlers *Handlers) postUTXOsFrozen(r *http.Request) (interface{}, error) {
+	var args struct {
+		OutPoints []string `json:"outPoints"`
+		Frozen    bool     `json:"frozen"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	btcAccount, ok := handlers.account.(*btc.Account)
+	if !ok {
+		return nil, errp.New("Interface must be of type btc.Account")
+	}
+	for _, outPointString := range args.OutPoints {
+		outPoint, err := wire.NewOutPointFromString(outPointString)
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		if err := btcAccount.SetOutPointFrozen(*outPoint, args.Frozen); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
 func (handlers *Handlers) getAccountBalance(*http.Request) (interface{}, error) {
 	accountConfig := handlers.account.Config()
 	type balance struct {
@@ -379,6 +494,9 @@ func (handlers *Handlers) getAccountBalance(*http.Request) (interface{}, error)
 		Available    coin.FormattedAmountWithConversions `json:"available"`
 		HasIncoming  bool                                `json:"hasIncoming"`
 		Incoming     coin.FormattedAmountWithConversions `json:"incoming"`
+		// Frozen is the part of Available which is frozen and can't be spent.
+		HasFrozen bool                                `json:"hasFrozen"`
+		Frozen    coin.FormattedAmountWithConversions `json:"frozen"`
 	}
 
 	type result struct {
@@ -396,12 +514,17 @@ func (handlers *Handlers) getAccountBalance(*http.Request) (interface{}, error)
 			Available:    accountBalance.Available().FormatWithConversions(handlers.account.Coin(), false, accountConfig.RateUpdater),
 			HasIncoming:  accountBalance.Incoming().BigInt().Sign() > 0,
 			Incoming:     accountBalance.Incoming().FormatWithConversions(handlers.account.Coin(), false, accountConfig.RateUpdater),
+			HasFrozen:    accountBalance.Frozen().BigInt().Sign() > 0,
+			Frozen:       accountBalance.Frozen().FormatWithConversions(handlers.account.Coin(), false, accountConfig.RateUpdater),
 		},
 	}, nil
 }
 
 type sendTxInput struct {
 	accounts.TxProposalArgs
+	// confirmResemblingContacts is true if the user confirmed sending to the recipient even though
+	// it resembles an address book contact.
+	confirmResemblingContacts bool
 }
 
 func (input *sendTxInput) UnmarshalJSON(jsonBytes []byte) error {
@@ -417,6 +540,15 @@ func (input *sendTxInput) UnmarshalJSON(jsonBytes []byte) error {
 		Counter        int                    `json:"counter"`
 		PaymentRequest *paymentrequest.Slip24 `json:"paymentRequest"`
 		UseHighestFee  bool                   `json:"useHighestFee"`
+		// Calldata of an ETH contract call, either hex encoded in `data`, or encoded from
+		// `contractAbi`, `contractFunction` and `contractArgs`.
+		Data             string            `json:"data"`
+		ContractABI      string            `json:"contractAbi"`
+		ContractFunction string            `json:"contractFunction"`
+		ContractArgs     []json.RawMessage `json:"contractArgs"`
+		// ConfirmResemblingContacts confirms sending to a recipient which resembles an address
+		// book contact.
+		ConfirmResemblingContacts bool `json:"confirmResemblingContacts"`
 	}{}
 	if err := json.Unmarshal(jsonBytes, &jsonBody); err != nil {
 		return errp.WithStack(err)
@@ -452,7 +584,17 @@ func (input *sendTxInput) UnmarshalJSON(jsonBytes []byte) error {
 		input.PaymentRequest = paymentRequest
 	}
 	input.UseHighestFee = jsonBody.UseHighestFee
-	return nil
+	input.confirmResemblingContacts = jsonBody.ConfirmResemblingContacts
+	switch {
+	case jsonBody.Data != "" && jsonBody.ContractABI != "":
+		return errp.WithStack(errors.ErrInvalidData)
+	case jsonBody.Data != "":
+		input.Data, err = eth.ParseCalldata(jsonBody.Data)
+	case jsonBody.ContractABI != "":
+		input.Data, err = eth.EncodeCalldata(
+			jsonBody.ContractABI, jsonBody.ContractFunction, jsonBody.ContractArgs)
+	}
+	return err
 }
 
 func (handlers *Handlers) postAccountSendTx(r *http.Request) (interface{}, error) {
@@ -471,7 +613,16 @@ func (handlers *Handlers) postAccountSendTx(r *http.Request) (interface{}, error
 		// not return but only log an error here.
 		handlers.log.WithError(err).Error("Failed to unmarshal transaction note")
 	}
-	txID, err := handlers.account.SendTx(txNote)
+	unlock := handlers.unconfirmedResemblanceLock.RLock()
+	unconfirmedResemblance := handlers.unconfirmedResemblance
+	unlock()
+	var txID string
+	var err error
+	if unconfirmedResemblance {
+		err = errp.WithStack(errors.ErrResemblingContact)
+	} else {
+		txID, err = handlers.account.SendTx(txNote)
+	}
 	if errp.Cause(err) == keystore.ErrSigningAborted || errp.Cause(err) == errp.ErrUserAbort {
 		return response{Success: false, Aborted: true}, nil
 	}
@@ -500,6 +651,25 @@ type txProposalResponse struct {
 	Fee                     *coin.FormattedAmountWithConversions `json:"fee,omitempty"`
 	Total                   *coin.FormattedAmountWithConversions `json:"total,omitempty"`
 	RecipientDisplayAddress string                               `json:"recipientDisplayAddress,omitempty"`
+	// RecipientENSName is set if the recipient was entered as an ENS name and resolved to
+	// RecipientDisplayAddress.
+	RecipientENSName string `json:"recipientEnsName,omitempty"`
+	// Data is the hex encoded calldata of ETH contract calls.
+	Data string `json:"data,omitempty"`
+	// RecipientContact is the address book contact of the recipient, if any.
+	RecipientContact *Contact `json:"recipientContact,omitempty"`
+	// ResemblingContacts are address book contacts whose address resembles the recipient address
+	// without being equal to it, as this is how address poisoning attacks trick users into paying
+	// the attacker. Unless the user confirmed sending to the recipient, the proposal fails with
+	// ErrResemblingContact and lists them.
+	ResemblingContacts []Contact `json:"resemblingContacts,omitempty"`
+}
+
+// setUnconfirmedResemblance records whether sending the active tx proposal requires the user to
+// confirm sending to a recipient which resembles an address book contact.
+func (handlers *Handlers) setUnconfirmedResemblance(unconfirmedResemblance bool) {
+	defer handlers.unconfirmedResemblanceLock.Lock()()
+	handlers.unconfirmedResemblance = unconfirmedResemblance
 }
 
 func txProposalError(err error) (interface{}, error) {
@@ -515,6 +685,10 @@ func (handlers *Handlers) postAccountTxProposal(r *http.Request) (interface{}, e
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		return txProposalError(errp.WithStack(err))
 	}
+	ethAccount, isETHAccount := handlers.account.(*eth.Account)
+	if len(input.Data) > 0 && !isETHAccount {
+		return txProposalError(errp.WithStack(errors.ErrInvalidData))
+	}
 	outputAmount, fee, total, err := handlers.account.TxProposal(&input.TxProposalArgs)
 	if err != nil {
 		return txProposalError(err)
@@ -522,19 +696,59 @@ func (handlers *Handlers) postAccountTxProposal(r *http.Request) (interface{}, e
 	amountResponse := outputAmount.FormatWithConversions(handlers.account.Coin(), false, accountConfig.RateUpdater)
 	feeResponse := fee.FormatWithConversions(handlers.account.Coin(), true, accountConfig.RateUpdater)
 	totalResponse := total.FormatWithConversions(handlers.account.Coin(), false, accountConfig.RateUpdater)
+	recipientAddress := input.RecipientAddress
+	var recipientENSName string
+	var data string
+	if isETHAccount {
+		if len(input.Data) > 0 {
+			data = hexutil.Encode(input.Data)
+		}
+		if address, ensName := ethAccount.ActiveTxProposalRecipient(); ensName != "" {
+			recipientAddress = address
+			recipientENSName = ensName
+		}
+	}
+	var recipientContact *Contact
+	if accountConfig.LookupContact != nil {
+		if contact := accountConfig.LookupContact(recipientAddress); contact != nil {
+			jsonContact := newContact(contact)
+			recipientContact = &jsonContact
+		}
+	}
+	var resemblingContacts []Contact
+	if accountConfig.ResemblingContacts != nil {
+		for _, contact := range accountConfig.ResemblingContacts(recipientAddress) {
+			resemblingContacts = append(resemblingContacts, newContact(contact))
+		}
+	}
+	unconfirmedResemblance := len(resemblingContacts) > 0 && !input.confirmResemblingContacts
+	handlers.setUnconfirmedResemblance(unconfirmedResemblance)
+	if unconfirmedResemblance {
+		return txProposalResponse{
+			Success:            false,
+			ErrorCode:          errors.ErrResemblingContact.Error(),
+			ResemblingContacts: resemblingContacts,
+		}, nil
+	}
 	return txProposalResponse{
 		Success:                 true,
 		Amount:                  &amountResponse,
 		Fee:                     &feeResponse,
 		Total:                   &totalResponse,
-		RecipientDisplayAddress: formatAddressForDisplay(handlers.account, input.RecipientAddress),
+		RecipientDisplayAddress: formatAddressForDisplay(handlers.account, recipientAddress),
+		RecipientENSName:        recipientENSName,
+		Data:                    data,
+		RecipientContact:        recipientContact,
+		ResemblingContacts:      resemblingContacts,
 	}, nil
 }
 
 func (handlers *Handlers) getAccountFeeTargets(*http.Request) (interface{}, error) {
 	type jsonFeeTarget struct {
-		Code        accounts.FeeTargetCode `json:"code"`
-		FeeRateInfo string                 `json:"feeRateInfo"`
+		Code                     accounts.FeeTargetCode `json:"code"`
+		FeeRateInfo              string                 `json:"feeRateInfo"`
+		ExpectedInclusionSeconds int                    `json:"expectedInclusionSeconds,omitempty"`
+		BaseFeeSpiking           bool                   `json:"baseFeeSpiking,omitempty"`
 	}
 	type response struct {
 		FeeTargets       []jsonFeeTarget        `json:"feeTargets"`
@@ -544,10 +758,15 @@ func (handlers *Handlers) getAccountFeeTargets(*http.Request) (interface{}, erro
 	feeTargets, defaultFeeTarget := handlers.account.FeeTargets()
 	result := []jsonFeeTarget{}
 	for _, feeTarget := range feeTargets {
-		result = append(result, jsonFeeTarget{
+		jsonTarget := jsonFeeTarget{
 			Code:        feeTarget.Code(),
 			FeeRateInfo: feeTarget.FormattedFeeRate(),
-		})
+		}
+		if ethFeeTarget, ok := feeTarget.(*ethtypes.FeeTarget); ok {
+			jsonTarget.ExpectedInclusionSeconds = ethFeeTarget.ExpectedInclusionBlocks * eth.BlockTimeSeconds
+			jsonTarget.BaseFeeSpiking = ethFeeTarget.BaseFeeSpiking
+		}
+		result = append(result, jsonTarget)
 	}
 	return response{
 		FeeTargets:       result,
@@ -637,6 +856,7 @@ func (handlers *Handlers) getUsedAddresses(*http.Request) (interface{}, error) {
 		AddressType    btc.UsedAddressType `json:"addressType"`
 		CanSignMsg     bool                `json:"canSignMsg"`
 		LastUsed       *string             `json:"lastUsed"`
+		Note           string              `json:"note"`
 	}
 	type response struct {
 		Success   bool              `json:"success"`
@@ -677,6 +897,7 @@ func (handlers *Handlers) getUsedAddresses(*http.Request) (interface{}, error) {
 			AddressType:    addr.AddressType,
 			CanSignMsg:     addr.CanSignMsg,
 			LastUsed:       lastUsed,
+			Note:           handlers.account.Notes().AddressNote(addr.Address),
 		}
 	}
 
@@ -754,23 +975,107 @@ func (handlers *Handlers) postSetTxNote(r *http.Request) (interface{}, error) {
 	return nil, handlers.account.SetTxNote(args.InternalTxID, args.Note)
 }
 
+func (handlers *Handlers) postSetAddressNote(r *http.Request) (interface{}, error) {
+	var args struct {
+		Address string `json:"address"`
+		Note    string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	_, err := handlers.account.Notes().SetAddressNote(args.Address, args.Note)
+	return nil, err
+}
+
+func (handlers *Handlers) postSetOutputNote(r *http.Request) (interface{}, error) {
+	var args struct {
+		OutPoint string `json:"outPoint"`
+		Note     string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	outPoint, err := wire.NewOutPointFromString(args.OutPoint)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	_, err = handlers.account.Notes().SetOutputNote(outPoint.String(), args.Note)
+	return nil, err
+}
+
 type signingResponse struct {
 	Success      bool   `json:"success"`
 	Signature    string `json:"signature"`
 	Aborted      bool   `json:"aborted"`
 	ErrorMessage string `json:"errorMessage"`
+	ErrorCode    string `json:"errorCode,omitempty"`
+	// Preview is the decoded request including its risk checks, if it could be decoded.
+	Preview *txpreview.Preview `json:"preview,omitempty"`
+}
+
+// checkEthPreviewConfirmed returns a response refusing to sign if the user did not confirm all
+// warnings of the preview, and nil otherwise. A request which could not be decoded is not
+// refused, as the device shows the full request.
+func checkEthPreviewConfirmed(
+	preview *txpreview.Preview, confirmedWarnings []txpreview.Warning) *signingResponse {
+	if preview == nil {
+		return nil
+	}
+	if err := preview.CheckConfirmed(confirmedWarnings); err != nil {
+		return &signingResponse{
+			Success:      false,
+			ErrorMessage: err.Error(),
+			ErrorCode:    string(txpreview.ErrUnconfirmedWarnings),
+			Preview:      preview,
+		}
+	}
+	return nil
+}
+
+// logEthSigningRequest persists the dApp signing request with the outcome derived from the signing
+// error in the audit log of the account. A txpreview.ErrUnconfirmedWarnings error means the request
+// was refused before signing.
+func (handlers *Handlers) logEthSigningRequest(
+	ethAccount *eth.Account, request *ethtypes.SigningRequest, signingErr error) {
+	request.Time = time.Now()
+	switch {
+	case errp.Cause(signingErr) == keystore.ErrSigningAborted || errp.Cause(signingErr) == errp.ErrUserAbort:
+		request.Outcome = ethtypes.SigningRequestAborted
+	case errp.Cause(signingErr) == txpreview.ErrUnconfirmedWarnings:
+		request.Outcome = ethtypes.SigningRequestRefused
+		request.Error = signingErr.Error()
+	case signingErr != nil:
+		request.Outcome = ethtypes.SigningRequestFailed
+		request.Error = signingErr.Error()
+	case request.Method == ethtypes.SigningRequestMethodSendTransaction:
+		request.Outcome = ethtypes.SigningRequestSent
+	default:
+		request.Outcome = ethtypes.SigningRequestSigned
+	}
+	if err := ethAccount.LogSigningRequest(request); err != nil {
+		handlers.log.WithError(err).Error("Failed to log signing request")
+	}
 }
 
 func (handlers *Handlers) postEthSignMsg(r *http.Request) (interface{}, error) {
-	var signInput string
-	if err := json.NewDecoder(r.Body).Decode(&signInput); err != nil {
+	var args struct {
+		Message string `json:"message"`
+		// Origin is the URL of the requesting dApp.
+		Origin string `json:"origin"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
 		return signingResponse{Success: false, ErrorMessage: err.Error()}, nil
 	}
 	ethAccount, ok := handlers.account.(*eth.Account)
 	if !ok {
 		return signingResponse{Success: false, ErrorMessage: "Must be an ETH based account"}, nil
 	}
-	signature, err := ethAccount.SignMsg(signInput)
+	signature, err := ethAccount.SignMsg(args.Message)
+	handlers.logEthSigningRequest(ethAccount, &ethtypes.SigningRequest{
+		Origin:  args.Origin,
+		Method:  ethtypes.SigningRequestMethodSignMessage,
+		ChainID: ethAccount.ETHCoin().ChainID(),
+	}, err)
 	if errp.Cause(err) == keystore.ErrSigningAborted || errp.Cause(err) == errp.ErrUserAbort {
 		return signingResponse{Success: false, Aborted: true}, nil
 	}
@@ -789,6 +1094,9 @@ func (handlers *Handlers) postEthSignTypedMsg(r *http.Request) (interface{}, err
 	var args struct {
 		ChainId uint64 `json:"chainId"`
 		Data    string `json:"data"`
+		Origin  string `json:"origin"`
+		// ConfirmedWarnings are the warnings of the preview the user has acknowledged.
+		ConfirmedWarnings []txpreview.Warning `json:"confirmedWarnings"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
 		return signingResponse{Success: false, ErrorMessage: err.Error()}, nil
@@ -797,18 +1105,36 @@ func (handlers *Handlers) postEthSignTypedMsg(r *http.Request) (interface{}, err
 	if !ok {
 		return signingResponse{Success: false, ErrorMessage: "Must be an ETH based account"}, nil
 	}
+	// The preview is recorded in the audit log. Failing to decode does not prevent signing, as the
+	// device shows the full typed data.
+	preview, err := ethAccount.TypedMsgPreview(args.ChainId, args.Data)
+	if err != nil {
+		handlers.log.WithError(err).Warning("Could not decode typed data")
+	}
+	request := &ethtypes.SigningRequest{
+		Origin:  args.Origin,
+		Method:  ethtypes.SigningRequestMethodSignTypedData,
+		ChainID: args.ChainId,
+		Preview: preview,
+	}
+	if refused := checkEthPreviewConfirmed(preview, args.ConfirmedWarnings); refused != nil {
+		handlers.logEthSigningRequest(ethAccount, request, errp.WithStack(txpreview.ErrUnconfirmedWarnings))
+		return *refused, nil
+	}
 	signature, err := ethAccount.SignTypedMsg(args.ChainId, args.Data)
+	handlers.logEthSigningRequest(ethAccount, request, err)
 	if errp.Cause(err) == keystore.ErrSigningAborted || errp.Cause(err) == errp.ErrUserAbort {
-		return signingResponse{Success: false, Aborted: true}, nil
+		return signingResponse{Success: false, Aborted: true, Preview: preview}, nil
 	}
 	if err != nil {
 		handlers.log.WithError(err).Error("Failed to sign typed data")
-		result := signingResponse{Success: false, ErrorMessage: err.Error()}
+		result := signingResponse{Success: false, ErrorMessage: err.Error(), Preview: preview}
 		return result, nil
 	}
 	return signingResponse{
 		Success:   true,
 		Signature: signature,
+		Preview:   preview,
 	}, nil
 }
 
@@ -820,11 +1146,15 @@ func (handlers *Handlers) postEthSignWalletConnectTx(r *http.Request) (interface
 		Send    bool                  `json:"send"`
 		ChainId uint64                `json:"chainId"`
 		Tx      eth.WalletConnectArgs `json:"tx"`
+		Origin  string                `json:"origin"`
+		// ConfirmedWarnings are the warnings of the preview the user has acknowledged.
+		ConfirmedWarnings []txpreview.Warning `json:"confirmedWarnings"`
 	}
 	type response struct {
-		Success bool   `json:"success"`
-		RawTx   string `json:"rawTx"`
-		TxHash  string `json:"txHash"`
+		Success bool               `json:"success"`
+		RawTx   string             `json:"rawTx"`
+		TxHash  string             `json:"txHash"`
+		Preview *txpreview.Preview `json:"preview,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
 		return signingResponse{Success: false, ErrorMessage: err.Error()}, nil
@@ -833,19 +1163,301 @@ func (handlers *Handlers) postEthSignWalletConnectTx(r *http.Request) (interface
 	if !ok {
 		return signingResponse{Success: false, ErrorMessage: "Must be an ETH based account"}, nil
 	}
+	preview, err := ethAccount.WalletConnectTxPreview(args.ChainId, args.Tx)
+	if err != nil {
+		handlers.log.WithError(err).Warning("Could not decode transaction")
+	}
+	method := ethtypes.SigningRequestMethodSignTransaction
+	if args.Send {
+		method = ethtypes.SigningRequestMethodSendTransaction
+	}
+	request := &ethtypes.SigningRequest{
+		Origin:  args.Origin,
+		Method:  method,
+		ChainID: args.ChainId,
+		Preview: preview,
+	}
+	if refused := checkEthPreviewConfirmed(preview, args.ConfirmedWarnings); refused != nil {
+		handlers.logEthSigningRequest(ethAccount, request, errp.WithStack(txpreview.ErrUnconfirmedWarnings))
+		return *refused, nil
+	}
 	txHash, rawTx, err := ethAccount.EthSignWalletConnectTx(args.Send, args.ChainId, args.Tx)
+	request.TxHash = txHash
+	handlers.logEthSigningRequest(ethAccount, request, err)
 	if errp.Cause(err) == keystore.ErrSigningAborted || errp.Cause(err) == errp.ErrUserAbort {
-		return signingResponse{Success: false, Aborted: true}, nil
+		return signingResponse{Success: false, Aborted: true, Preview: preview}, nil
 	}
 	if err != nil {
 		handlers.log.WithError(err).Error("Failed to send transaction")
-		result := signingResponse{Success: false, ErrorMessage: err.Error()}
+		result := signingResponse{Success: false, ErrorMessage: err.Error(), Preview: preview}
 		return result, nil
 	}
 	return response{
 		Success: true,
 		RawTx:   rawTx,
 		TxHash:  txHash,
+		Preview: preview,
+	}, nil
+}
+
+type txPreviewResponse struct {
+	Success      bool               `json:"success"`
+	Preview      *txpreview.Preview `json:"preview,omitempty"`
+	ErrorMessage string             `json:"errorMessage,omitempty"`
+}
+
+// postEthWalletConnectTxPreview decodes a WalletConnect transaction to be shown to the user before
+// signing.
+func (handlers *Handlers) postEthWalletConnectTxPreview(r *http.Request) (interface{}, error) {
+	var args struct {
+		ChainId uint64                `json:"chainId"`
+		Tx      eth.WalletConnectArgs `json:"tx"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		return txPreviewResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	ethAccount, ok := handlers.account.(*eth.Account)
+	if !ok {
+		return txPreviewResponse{Success: false, ErrorMessage: "Must be an ETH based account"}, nil
+	}
+	preview, err := ethAccount.WalletConnectTxPreview(args.ChainId, args.Tx)
+	if err != nil {
+		return txPreviewResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	return txPreviewResponse{Success: true, Preview: preview}, nil
+}
+
+// postEthTypedMsgPreview decodes EIP-712 typed data to be shown to the user before signing.
+func (handlers *Handlers) postEthTypedMsgPreview(r *http.Request) (interface{}, error) {
+	var args struct {
+		ChainId uint64 `json:"chainId"`
+		Data    string `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		return txPreviewResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	ethAccount, ok := handlers.account.(*eth.Account)
+	if !ok {
+		return txPreviewResponse{Success: false, ErrorMessage: "Must be an ETH based account"}, nil
+	}
+	preview, err := ethAccount.TypedMsgPreview(args.ChainId, args.Data)
+	if err != nil {
+		return txPreviewResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	return txPreviewResponse{Success: true, Preview: preview}, nil
+}
+
+func (handlers *Handlers) getEthSigningRequests(*http.Request) (interface{}, error) {
+	ethAccount, ok := handlers.account.(*eth.Account)
+	if !ok {
+		return nil, errp.New("Must be an ETH based account")
+	}
+	return ethAccount.SigningRequests()
+}
+
+func (handlers *Handlers) getEthAllowances(*http.Request) (interface{}, error) {
+	type jsonAllowance struct {
+		TokenAddress   string `json:"tokenAddress"`
+		Symbol         string `json:"symbol"`
+		SpenderAddress string `json:"spenderAddress"`
+		Amount         string `json:"amount"`
+		Unlimited      bool   `json:"unlimited"`
+	}
+	type response struct {
+		Success      bool            `json:"success"`
+		Allowances   []jsonAllowance `json:"allowances,omitempty"`
+		ErrorMessage string          `json:"errorMessage,omitempty"`
+	}
+	ethAccount, ok := handlers.account.(*eth.Account)
+	if !ok {
+		return response{Success: false, ErrorMessage: "Must be an ETH based account"}, nil
+	}
+	allowances, err := ethAccount.Allowances()
+	if err != nil {
+		handlers.log.WithError(err).Error("Failed to get allowances")
+		return response{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	result := []jsonAllowance{}
+	for _, allowance := range allowances {
+		result = append(result, jsonAllowance{
+			TokenAddress:   allowance.Token.Hex(),
+			Symbol:         allowance.Symbol,
+			SpenderAddress: allowance.Spender.Hex(),
+			Amount:         allowance.FormatAmount(),
+			Unlimited:      allowance.Unlimited(),
+		})
+	}
+	return response{Success: true, Allowances: result}, nil
+}
+
+// postEthRevokeAllowanceProposal creates a tx proposal setting the allowance of the spender to
+// zero. The proposal is sent using the regular `/sendtx` endpoint.
+func (handlers *Handlers) postEthRevokeAllowanceProposal(r *http.Request) (interface{}, error) {
+	var args struct {
+		TokenAddress   string                 `json:"tokenAddress"`
+		SpenderAddress string                 `json:"spenderAddress"`
+		FeeTarget      accounts.FeeTargetCode `json:"feeTarget"`
+		CustomFee      string                 `json:"customFee"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		return txProposalError(errp.WithStack(err))
+	}
+	ethAccount, ok := handlers.account.(*eth.Account)
+	if !ok {
+		return nil, errp.New("Must be an ETH based account")
+	}
+	if !ethcommon.IsHexAddress(args.TokenAddress) || !ethcommon.IsHexAddress(args.SpenderAddress) {
+		return txProposalResponse{Success: false, ErrorCode: errors.ErrInvalidAddress.Error()}, nil
+	}
+	fee, err := ethAccount.RevokeAllowanceTxProposal(
+		ethcommon.HexToAddress(args.TokenAddress),
+		ethcommon.HexToAddress(args.SpenderAddress),
+		args.FeeTarget,
+		args.CustomFee,
+	)
+	if err != nil {
+		return txProposalError(err)
+	}
+	// The allowance is revoked by calling the token contract, not paying a recipient.
+	handlers.setUnconfirmedResemblance(false)
+	accountConfig := handlers.account.Config()
+	zero := coin.NewAmountFromInt64(0).FormatWithConversions(handlers.account.Coin(), false, accountConfig.RateUpdater)
+	feeResponse := fee.FormatWithConversions(handlers.account.Coin(), true, accountConfig.RateUpdater)
+	return txProposalResponse{
+		Success:                 true,
+		Amount:                  &zero,
+		Fee:                     &feeResponse,
+		Total:                   &feeResponse,
+		RecipientDisplayAddress: args.TokenAddress,
+	}, nil
+}
+
+type ethNFTsResponse struct {
+	Success      bool            `json:"success"`
+	NFTs         []*ethtypes.NFT `json:"nfts,omitempty"`
+	ErrorMessage string          `json:"errorMessage,omitempty"`
+}
+
+// getEthNFTs returns the stored NFT inventory. It is rebuilt using `/eth-nfts-refresh`.
+func (handlers *Handlers) getEthNFTs(*http.Request) (interface{}, error) {
+	ethAccount, ok := handlers.account.(*eth.Account)
+	if !ok {
+		return ethNFTsResponse{Success: false, ErrorMessage: "Must be an ETH based account"}, nil
+	}
+	nfts, err := ethAccount.NFTs()
+	if err != nil {
+		return ethNFTsResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	return ethNFTsResponse{Success: true, NFTs: nfts}, nil
+}
+
+func (handlers *Handlers) postEthNFTsRefresh(*http.Request) (interface{}, error) {
+	ethAccount, ok := handlers.account.(*eth.Account)
+	if !ok {
+		return ethNFTsResponse{Success: false, ErrorMessage: "Must be an ETH based account"}, nil
+	}
+	nfts, err := ethAccount.UpdateNFTs()
+	if err != nil {
+		handlers.log.WithError(err).Error("Failed to update NFTs")
+		return ethNFTsResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	return ethNFTsResponse{Success: true, NFTs: nfts}, nil
+}
+
+// getEthNFTMetadata fetches the metadata of a token in the inventory. Fetching it is optional and
+// only done on request, as it contacts the server hosting the metadata.
+func (handlers *Handlers) getEthNFTMetadata(r *http.Request) (interface{}, error) {
+	type response struct {
+		Success      bool            `json:"success"`
+		Metadata     json.RawMessage `json:"metadata,omitempty"`
+		ErrorMessage string          `json:"errorMessage,omitempty"`
+		ErrorCode    string          `json:"errorCode,omitempty"`
+	}
+	ethAccount, ok := handlers.account.(*eth.Account)
+	if !ok {
+		return response{Success: false, ErrorMessage: "Must be an ETH based account"}, nil
+	}
+	metadata, err := ethAccount.NFTMetadata(r.URL.Query().Get("contract"), r.URL.Query().Get("tokenId"))
+	if err != nil {
+		handlers.log.WithError(err).Warn("Failed to fetch NFT metadata")
+		if errCode, ok := errp.Cause(err).(errp.ErrorCode); ok {
+			return response{Success: false, ErrorMessage: err.Error(), ErrorCode: string(errCode)}, nil
+		}
+		return response{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	return response{Success: true, Metadata: metadata}, nil
+}
+
+func (handlers *Handlers) getEthNonceState(*http.Request) (interface{}, error) {
+	type response struct {
+		Success      bool            `json:"success"`
+		State        *eth.NonceState `json:"state,omitempty"`
+		ErrorMessage string          `json:"errorMessage,omitempty"`
+	}
+	ethAccount, ok := handlers.account.(*eth.Account)
+	if !ok {
+		return response{Success: false, ErrorMessage: "Must be an ETH based account"}, nil
+	}
+	state, err := ethAccount.NonceState()
+	if err != nil {
+		handlers.log.WithError(err).Error("Failed to get the nonce state")
+		return response{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	return response{Success: true, State: state}, nil
+}
+
+func (handlers *Handlers) postEthRebroadcastTx(r *http.Request) (interface{}, error) {
+	type response struct {
+		Success      bool   `json:"success"`
+		ErrorMessage string `json:"errorMessage,omitempty"`
+	}
+	var txID string
+	if err := json.NewDecoder(r.Body).Decode(&txID); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	ethAccount, ok := handlers.account.(*eth.Account)
+	if !ok {
+		return response{Success: false, ErrorMessage: "Must be an ETH based account"}, nil
+	}
+	if err := ethAccount.RebroadcastTx(txID); err != nil {
+		handlers.log.WithError(err).Error("Failed to rebroadcast transaction")
+		return response{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	return response{Success: true}, nil
+}
+
+// postEthReplaceNonceProposal creates a tx proposal filling or replacing the given nonce with a
+// zero value transaction to the account's own address. The proposal is sent using the regular
+// `/sendtx` endpoint.
+func (handlers *Handlers) postEthReplaceNonceProposal(r *http.Request) (interface{}, error) {
+	var args struct {
+		Nonce     uint64                 `json:"nonce"`
+		FeeTarget accounts.FeeTargetCode `json:"feeTarget"`
+		CustomFee string                 `json:"customFee"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		return txProposalError(errp.WithStack(err))
+	}
+	ethAccount, ok := handlers.account.(*eth.Account)
+	if !ok {
+		return nil, errp.New("Must be an ETH based account")
+	}
+	fee, err := ethAccount.ReplaceNonceTxProposal(args.Nonce, args.FeeTarget, args.CustomFee)
+	if err != nil {
+		return txProposalError(err)
+	}
+	// The replacement pays the recipient of the user's own pending transaction.
+	handlers.setUnconfirmedResemblance(false)
+	recipient, _ := ethAccount.ActiveTxProposalRecipient()
+	accountConfig := handlers.account.Config()
+	zero := coin.NewAmountFromInt64(0).FormatWithConversions(handlers.account.Coin(), false, accountConfig.RateUpdater)
+	feeResponse := fee.FormatWithConversions(handlers.account.Coin(), true, accountConfig.RateUpdater)
+	return txProposalResponse{
+		Success:                 true,
+		Amount:                  &zero,
+		Fee:                     &feeResponse,
+		Total:                   &feeResponse,
+		RecipientDisplayAddress: recipient,
 	}, nil
 }
 
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/rpcclient/mocks"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/txpreview"
+	ethtypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/keystore"
+	keystoremock "github.com/BitBoxSwiss/bitbox-wallet-app/backend/keystore/mocks"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/signing"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/logging"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/test"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	test.TstSetupLogging()
+	os.Exit(m.Run())
+}
+
+// newEthAccount returns an initialized Sepolia account whose keystore must not be used.
+func newEthAccount(t *testing.T) *eth.Account {
+	t.Helper()
+	net := &chaincfg.TestNet3Params
+	keypath, err := signing.NewAbsoluteKeypath("m/60'/1'/0'/0")
+	require.NoError(t, err)
+	xpub, err := hdkeychain.NewMaster(make([]byte, 32), net)
+	require.NoError(t, err)
+	xpub, err = xpub.Neuter()
+	require.NoError(t, err)
+
+	ethCoin := eth.NewCoin(&mocks.InterfaceMock{}, coin.CodeSEPETH, "Sepolia", "SEPETH", "SEPETH",
+		params.SepoliaChainConfig, "", nil, nil)
+	account := eth.NewAccount(
+		&accounts.AccountConfig{
+			Config: &config.Account{
+				Code: "v0-55555555-sepeth-0",
+				Name: "Sepolia",
+				SigningConfigurations: signing.Configurations{
+					signing.NewEthereumConfiguration([]byte{1, 2, 3, 4}, keypath, xpub)},
+			},
+			DBFolder:        test.TstTempDir("handlers_test_dbfolder"),
+			NotesFolder:     test.TstTempDir("handlers_test_notesfolder"),
+			SkipInitialSync: true,
+			GetNotifier:     func(signing.Configurations) accounts.Notifier { return nil },
+			ConnectKeystore: func() (keystore.Keystore, error) {
+				return &keystoremock.KeystoreMock{}, nil
+			},
+		},
+		ethCoin,
+		&http.Client{},
+		logging.Get().WithGroup("handlers_test"),
+		make(chan *eth.Account),
+	)
+	require.NoError(t, account.Initialize())
+	return account
+}
+
+func TestEthSigningRequestRefused(t *testing.T) {
+	account := newEthAccount(t)
+	defer account.Close()
+	handlers := &Handlers{log: logging.Get().WithGroup("handlers_test")}
+	handlers.Init(account)
+
+	post := func(handler func(*http.Request) (interface{}, error), args interface{}) interface{} {
+		body, err := json.Marshal(args)
+		require.NoError(t, err)
+		response, err := handler(httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+		require.NoError(t, err)
+		return response
+	}
+
+	// The chain ID does not match the Sepolia account and the warning is not confirmed.
+	response := post(handlers.postEthSignWalletConnectTx, map[string]interface{}{
+		"send":              true,
+		"chainId":           1,
+		"tx":                eth.WalletConnectArgs{To: "0xa29163852021BF4C139D03Dff59ae763AC73e84e", Value: "0x64"},
+		"origin":            "https://dapp.example",
+		"confirmedWarnings": []txpreview.Warning{txpreview.WarningUnknownRecipient},
+	})
+	refused, ok := response.(signingResponse)
+	require.True(t, ok)
+	require.False(t, refused.Success)
+	require.Equal(t, string(txpreview.ErrUnconfirmedWarnings), refused.ErrorCode)
+
+	response = post(handlers.postEthSignTypedMsg, map[string]interface{}{
+		"chainId": 11155111,
+		"data": `{
+  "primaryType": "Permit",
+  "domain": {"chainId": 1, "verifyingContract": "0x00000000000000000000000000000000000000aa"},
+  "message": {"spender": "0x0000000000000000000000000000000000000001", "value": "1000"}
+}`,
+		"origin": "https://dapp.example",
+	})
+	refused, ok = response.(signingResponse)
+	require.True(t, ok)
+	require.Equal(t, string(txpreview.ErrUnconfirmedWarnings), refused.ErrorCode)
+
+	requests, err := account.SigningRequests()
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	// Newest first.
+	require.Equal(t, ethtypes.SigningRequestMethodSignTypedData, requests[0].Method)
+	require.Equal(t, ethtypes.SigningRequestMethodSendTransaction, requests[1].Method)
+	for _, request := range requests {
+		require.Equal(t, ethtypes.SigningRequestRefused, request.Outcome)
+		require.Equal(t, "https://dapp.example", request.Origin)
+		require.NotNil(t, request.Preview)
+		require.NotEmpty(t, request.Error)
+		require.Empty(t, request.TxHash)
+	}
+}
@@ -3,6 +3,7 @@
 package btc
 
 import (
+	"encoding/hex"
 	"math/big"
 	"strconv"
 
@@ -120,7 +121,7 @@ func (account *Account) pickChangeAddress(utxos map[wire.OutPoint]maketx.UTXO) (
 // newTx creates a new tx to the given recipient address. It also returns a set of used account
 // outputs, which contains all outputs that spent in the tx. Those are needed to be able to sign the
 // transaction. selectedUTXOs restricts the available coins; if empty, no restriction is applied and
-// all unspent coins can be used.
+// all unspent coins can be used. Frozen coins are never used.
 func (account *Account) newTx(args *accounts.TxProposalArgs) (
 	map[wire.OutPoint]*transactions.SpendableOutput, *maketx.TxProposal, error) {
 
@@ -146,6 +147,9 @@ func (account *Account) newTx(args *accounts.TxProposalArgs) (
 	}
 	wireUTXO := make(map[wire.OutPoint]maketx.UTXO, len(utxo))
 	for outPoint, txOut := range utxo {
+		if account.IsOutPointFrozen(outPoint) {
+			continue
+		}
 		// Apply coin control.
 		if len(args.SelectedUTXOs) != 0 {
 			if _, ok := args.SelectedUTXOs[outPoint]; !ok {
@@ -228,6 +232,34 @@ func (account *Account) AddressByID(addressID addresses.AddressID) *addresses.Ac
 	return address
 }
 
+// AddressByString returns the address in the account with the given encoding, as returned by
+// `EncodeForHumans()`. Returns nil if the address is invalid or does not exist in the account.
+func (account *Account) AddressByString(address string) *addresses.AccountAddress {
+	pkScript, err := account.coin.AddressToPkScript(address)
+	if err != nil || pkScript == nil {
+		return nil
+	}
+	return account.AddressByID(addresses.NewAddressID(pkScript))
+}
+
+// AddressByPublicKey returns the address in the account of the given hex-encoded compressed public
+// key. Returns nil if the public key does not belong to a single-sig address of the account.
+func (account *Account) AddressByPublicKey(publicKeyHex string) *addresses.AccountAddress {
+	publicKey, err := hex.DecodeString(publicKeyHex)
+	if err != nil {
+		return nil
+	}
+	for _, subacc := range account.subaccounts {
+		if addr := subacc.receiveAddresses.LookupByPublicKey(publicKey); addr != nil {
+			return addr
+		}
+		if addr := subacc.changeAddresses.LookupByPublicKey(publicKey); addr != nil {
+			return addr
+		}
+	}
+	return nil
+}
+
 // SendTx implements accounts.Interface.
 func (account *Account) SendTx(txNote string) (string, error) {
 	unlock := account.activeTxProposalLock.RLock()
@@ -238,13 +270,13 @@ func (account *Account) SendTx(txNote string) (string, error) {
 	}
 
 	account.log.Info("Signing and sending transaction")
-	signedTx, err := account.signTransaction(txProposal, account.coin.Blockchain().TransactionGet)
+	signedTx, err := account.signTransaction(txProposal, account.blockchain().TransactionGet)
 	if err != nil {
 		return "", errp.WithMessage(err, "Failed to sign transaction")
 	}
 
 	account.log.Info("Signed transaction is broadcasted")
-	if err := account.coin.Blockchain().TransactionBroadcast(signedTx); err != nil {
+	if err := account.blockchain().TransactionBroadcast(signedTx); err != nil {
 		return "", err
 	}
 
@@ -388,3 +388,68 @@ func TestTxProposal(t *testing.T) {
 		})
 	}
 }
+
+func TestTxProposalFrozenUTXOs(t *testing.T) {
+	account := testAccount(t, nil)
+	bigOutPoint := *wire.NewOutPoint(&chainhash.Hash{}, 0)
+	require.NoError(t, account.SetOutPointFrozen(bigOutPoint, true))
+	require.True(t, account.IsOutPointFrozen(bigOutPoint))
+
+	// Send-max only spends the coins which are not frozen.
+	amount, fee, total, err := account.TxProposal(&accounts.TxProposalArgs{
+		RecipientAddress: "myY3Bbvj5mjwqqvubtu5Hfy2nuCeBfvNXL",
+		Amount:           coin.NewSendAmountAll(),
+		FeeTargetCode:    accounts.FeeTargetCodeCustom,
+		CustomFee:        "10",
+	})
+	require.NoError(t, err)
+	require.Equal(t, coin.NewAmountFromInt64(998870), amount)
+	require.Equal(t, coin.NewAmountFromInt64(1130), fee)
+	require.Equal(t, coin.NewAmountFromInt64(1000000), total)
+
+	args := &accounts.TxProposalArgs{
+		RecipientAddress: "myY3Bbvj5mjwqqvubtu5Hfy2nuCeBfvNXL",
+		Amount:           coin.NewSendAmount("1"),
+		FeeTargetCode:    accounts.FeeTargetCodeCustom,
+		CustomFee:        "100",
+	}
+	_, _, _, err = account.TxProposal(args)
+	require.ErrorContains(t, err, errors.ErrInsufficientFunds.Error())
+
+	// Frozen coins are not spent even if selected manually.
+	args.SelectedUTXOs = map[wire.OutPoint]struct{}{bigOutPoint: {}}
+	_, _, _, err = account.TxProposal(args)
+	require.ErrorContains(t, err, errors.ErrInsufficientFunds.Error())
+
+	spendableOutputs, err := account.SpendableOutputs()
+	require.NoError(t, err)
+	for _, output := range spendableOutputs {
+		require.Equal(t, output.OutPoint == bigOutPoint, output.Frozen)
+	}
+
+	require.NoError(t, account.SetOutPointFrozen(bigOutPoint, false))
+	require.False(t, account.IsOutPointFrozen(bigOutPoint))
+	_, _, total, err = account.TxProposal(args)
+	require.NoError(t, err)
+	require.Equal(t, coin.NewAmountFromInt64(100014400), total)
+}
+
+func TestBalanceFrozen(t *testing.T) {
+	account := testAccount(t, nil)
+	transactionsMock := account.transactions.(*mocks.InterfaceMock)
+	transactionsMock.BalanceFunc = func() (*accounts.Balance, error) {
+		return accounts.NewBalance(coin.NewAmountFromInt64(1001000000), coin.NewAmountFromInt64(0)), nil
+	}
+
+	balance, err := account.Balance()
+	require.NoError(t, err)
+	require.Equal(t, coin.NewAmountFromInt64(0), balance.Frozen())
+
+	require.NoError(t, account.SetOutPointFrozen(*wire.NewOutPoint(&chainhash.Hash{}, 1), true))
+	// Frozen outputs which are not in the UTXO set are ignored.
+	require.NoError(t, account.SetOutPointFrozen(*wire.NewOutPoint(&chainhash.Hash{}, 2), true))
+	balance, err = account.Balance()
+	require.NoError(t, err)
+	require.Equal(t, coin.NewAmountFromInt64(1001000000), balance.Available())
+	require.Equal(t, coin.NewAmountFromInt64(1000000), balance.Frozen())
+}
@@ -18,6 +18,7 @@ import (
 	accountsTypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/types"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/db"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/ens"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/erc20"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/etherscan"
 	ethtypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/types"
@@ -166,19 +167,24 @@ func (account *Account) Initialize() error {
 	account.notifier = account.Config().GetNotifier(signingConfigurations)
 
 	accountIdentifier := fmt.Sprintf("account-%s", account.Config().Config.Code)
-	account.dbSubfolder = path.Join(account.Config().DBFolder, accountIdentifier)
-	if err := os.MkdirAll(account.dbSubfolder, 0700); err != nil {
-		return errp.WithStack(err)
-	}
+	if account.Config().InMemoryDB {
+		account.log.Debug("Keeping the transactions in memory.")
+		account.db = db.NewMemoryDB()
+	} else {
+		account.dbSubfolder = path.Join(account.Config().DBFolder, accountIdentifier)
+		if err := os.MkdirAll(account.dbSubfolder, 0700); err != nil {
+			return errp.WithStack(err)
+		}
 
-	dbName := fmt.Sprintf("%s.db", accountIdentifier)
-	account.log.Debugf("Opening the database '%s' to persist the transactions.", dbName)
-	db, err := db.NewDB(path.Join(account.Config().DBFolder, dbName))
-	if err != nil {
-		return err
+		dbName := fmt.Sprintf("%s.db", accountIdentifier)
+		account.log.Debugf("Opening the database '%s' to persist the transactions.", dbName)
+		db, err := db.NewDB(path.Join(account.Config().DBFolder, dbName))
+		if err != nil {
+			return err
+		}
+		account.db = db
+		account.log.Debugf("Opened the database '%s' to persist the transactions.", dbName)
 	}
-	account.db = db
-	account.log.Debugf("Opened the database '%s' to persist the transactions.", dbName)
 
 	account.address = Address{
 		Address:         crypto.PubkeyToAddress(*account.signingConfiguration.PublicKey().ToECDSA()),
@@ -347,16 +353,18 @@ func (account *Account) nextNonce() (uint64, error) {
 	nextNonce = nodeNonce
 
 	// In case the nodeNonce is not up to date, we fall back to our stored last nonce to compute the
-	// next nonce.
-	outgoingTransactions, err := account.outgoingTransactions(nil)
-	if err != nil {
-		return 0, errp.WithStack(err)
-	}
-
-	if len(outgoingTransactions) > 0 {
-		localNonce := outgoingTransactions[0].Transaction.Nonce() + 1
-		if localNonce > nextNonce {
-			nextNonce = localNonce
+	// next nonce. The accounts using the same address, e.g. the ERC20 token accounts of an Ethereum
+	// account, share the nonces.
+	for _, sameAddressAccount := range account.sameAddressAccounts() {
+		outgoingTransactions, err := sameAddressAccount.outgoingTransactions(nil)
+		if err != nil {
+			return 0, errp.WithStack(err)
+		}
+		if len(outgoingTransactions) > 0 {
+			localNonce := outgoingTransactions[0].Transaction.Nonce() + 1
+			if localNonce > nextNonce {
+				nextNonce = localNonce
+			}
 		}
 	}
 	return nextNonce, nil
@@ -482,7 +490,7 @@ func (account *Account) Transactions() (accounts.OrderedTransactions, error) {
 	if !account.Synced() {
 		return nil, accounts.ErrSyncInProgress
 	}
-	return accounts.NewOrderedTransactions(account.transactions), nil
+	return account.LabelContacts(accounts.NewOrderedTransactions(account.transactions)), nil
 }
 
 // Balance implements accounts.Interface.
@@ -512,14 +520,45 @@ type TxProposal struct {
 	// not used in the transaction or signing except for making sure the BitBox displays the address
 	// with the same case (lowercase/uppercase/mixed) as the user entered.
 	RecipientAddress string
+	// RecipientENSName is the normalized ENS name the recipient address was resolved from, or empty
+	// if the user entered an address.
+	RecipientENSName string
 	PaymentRequest   *paymentrequest.Request
 }
 
+// resolveRecipient returns the recipient address. If the recipient is an ENS name, it is resolved
+// and the resolved address is returned together with the normalized name.
+func (account *Account) resolveRecipient(recipient string) (string, string, error) {
+	if IsValidEthAddress(recipient) || !ens.IsName(recipient) {
+		return recipient, "", nil
+	}
+	ensName, err := ens.Normalize(recipient)
+	if err != nil {
+		return "", "", errp.WithStack(errors.ErrInvalidAddress)
+	}
+	resolved, err := account.coin.ensResolver.Resolve(context.TODO(), ensName)
+	if err != nil {
+		if errp.Cause(err) == ens.ErrNotFound {
+			return "", "", errp.WithStack(errors.ErrENSNameNotFound)
+		}
+		account.log.WithError(err).Error("Could not resolve ENS name")
+		return "", "", err
+	}
+	return resolved.Hex(), ensName, nil
+}
+
 func (account *Account) newTx(args *accounts.TxProposalArgs) (*TxProposal, error) {
-	if !IsValidEthAddress(args.RecipientAddress) {
+	if len(args.Data) > 0 && account.coin.erc20Token != nil {
+		return nil, errp.New("contract calls must be made from the Ethereum account")
+	}
+	recipientAddress, recipientENSName, err := account.resolveRecipient(args.RecipientAddress)
+	if err != nil {
+		return nil, err
+	}
+	if !IsValidEthAddress(recipientAddress) {
 		return nil, errp.WithStack(errors.ErrInvalidAddress)
 	}
-	address := ethcommon.HexToAddress(args.RecipientAddress)
+	address := ethcommon.HexToAddress(recipientAddress)
 
 	suggestedGasFeeCap, suggestedGasTipCap, err := account.gasFees(args)
 	if err != nil {
@@ -569,13 +608,14 @@ func (account *Account) newTx(args *accounts.TxProposalArgs) (*TxProposal, error
 			Data:     erc20ContractData,
 		}
 	} else {
-		// Standard ethereum transaction
+		// Standard ethereum transaction, or a contract call if calldata is provided.
 		message = ethereum.CallMsg{
 			From:     account.address.Address,
 			To:       &address,
 			Gas:      0,
 			GasPrice: big.NewInt(0),
 			Value:    value,
+			Data:     args.Data,
 		}
 	}
 
@@ -625,42 +665,15 @@ func (account *Account) newTx(args *accounts.TxProposalArgs) (*TxProposal, error
 		}
 	}
 
-	keystore, err := account.Config().ConnectKeystore()
+	nonce, err := account.nextNonce()
 	if err != nil {
 		return nil, err
 	}
-
-	var tx *types.Transaction
-
-	nextNonce, err := account.nextNonce()
+	tx, err := account.buildTx(message, nonce, gasLimit, suggestedGasFeeCap, suggestedGasTipCap)
 	if err != nil {
 		return nil, err
 	}
 
-	if keystore.SupportsEIP1559() {
-		txData := &types.DynamicFeeTx{
-			Nonce:     nextNonce,
-			GasTipCap: suggestedGasTipCap,
-			GasFeeCap: suggestedGasFeeCap,
-			Gas:       gasLimit,
-			To:        message.To,
-			Value:     message.Value,
-			Data:      message.Data,
-		}
-		tx = types.NewTx(txData)
-	} else {
-		tx = types.NewTransaction(
-			nextNonce,
-			*message.To,
-			message.Value,
-			gasLimit,
-			// use the maxFeePerGas (aka gasFeeCap) as gasPrice for legacy transactions
-			// the estimated maxFeePerGas is base fee + priority fee, and so is the appropriate
-			// legacy gasPrice setting for current network conditions
-			suggestedGasFeeCap,
-			message.Data)
-	}
-
 	return &TxProposal{
 		Coin:             account.coin,
 		Tx:               tx,
@@ -668,11 +681,49 @@ func (account *Account) newTx(args *accounts.TxProposalArgs) (*TxProposal, error
 		Value:            value,
 		Signer:           types.NewLondonSigner(account.coin.net.ChainID),
 		Keypath:          account.signingConfiguration.AbsoluteKeypath(),
-		RecipientAddress: args.RecipientAddress,
+		RecipientAddress: recipientAddress,
+		RecipientENSName: recipientENSName,
 		PaymentRequest:   args.PaymentRequest,
 	}, nil
 }
 
+// buildTx creates an unsigned transaction for the message with the given nonce. An EIP-1559
+// transaction is created if the keystore supports it, otherwise a legacy transaction.
+func (account *Account) buildTx(
+	message ethereum.CallMsg,
+	nonce uint64,
+	gasLimit uint64,
+	gasFeeCap *big.Int,
+	gasTipCap *big.Int,
+) (*types.Transaction, error) {
+	keystore, err := account.Config().ConnectKeystore()
+	if err != nil {
+		return nil, err
+	}
+
+	if keystore.SupportsEIP1559() {
+		return types.NewTx(&types.DynamicFeeTx{
+			Nonce:     nonce,
+			GasTipCap: gasTipCap,
+			GasFeeCap: gasFeeCap,
+			Gas:       gasLimit,
+			To:        message.To,
+			Value:     message.Value,
+			Data:      message.Data,
+		}), nil
+	}
+	return types.NewTransaction(
+		nonce,
+		*message.To,
+		message.Value,
+		gasLimit,
+		// use the maxFeePerGas (aka gasFeeCap) as gasPrice for legacy transactions
+		// the estimated maxFeePerGas is base fee + priority fee, and so is the appropriate
+		// legacy gasPrice setting for current network conditions
+		gasFeeCap,
+		message.Data), nil
+}
+
 // storePendingOutgoingTransaction puts an outgoing tx into the db with height 0 (pending).
 func (account *Account) storePendingOutgoingTransaction(transaction *types.Transaction) error {
 	dbTx, err := account.db.Begin()
@@ -722,7 +773,7 @@ func (account *Account) SendTx(txNote string) (string, error) {
 		return "", err
 	}
 
-	if err := account.SetTxNote(txProposal.Tx.Hash().Hex(), txNote); err != nil {
+	if err := account.SetTxNote(txProposal.Tx.Hash().Hex(), noteWithENSName(txNote, txProposal.RecipientENSName)); err != nil {
 		// Not critical.
 		account.log.WithError(err).Error("Failed to save transaction note when sending a tx")
 	}
@@ -730,11 +781,42 @@ func (account *Account) SendTx(txNote string) (string, error) {
 	return txProposal.Tx.Hash().String(), nil
 }
 
-// feeTargets returns three priorities with fee targets estimated by Etherscan
+// noteWithENSName adds the ENS name the recipient was resolved from to the tx note, so the name
+// stays visible in the transaction history.
+func noteWithENSName(note string, ensName string) string {
+	if ensName == "" || strings.Contains(note, ensName) {
+		return note
+	}
+	if note == "" {
+		return ensName
+	}
+	return fmt.Sprintf("%s (%s)", note, ensName)
+}
+
+// ActiveTxProposalRecipient returns the recipient address of the active tx proposal and the ENS
+// name it was resolved from. The name is empty if the recipient was entered as an address.
+func (account *Account) ActiveTxProposalRecipient() (string, string) {
+	defer account.updateLock.RLock()()
+	if account.activeTxProposal == nil {
+		return "", ""
+	}
+	return account.activeTxProposal.RecipientAddress, account.activeTxProposal.RecipientENSName
+}
+
+// feeTargets returns three priorities with fee targets. If the client is an RPC node providing
+// `eth_feeHistory`, e.g. a self-hosted node, they are estimated locally from the fee history.
+// Otherwise they are estimated by Etherscan
 // https://docs.etherscan.io/api-endpoints/gas-tracker#get-gas-oracle
 // If the service should not be reachable, we fallback to only one priority, estimated by
 // the ETH RPC eth_gasPrice endpoint.
 func (account *Account) feeTargets() []*ethtypes.FeeTarget {
+	if node, ok := account.coin.client.(ethereum.FeeHistoryReader); ok {
+		feeTargets, err := feeTargetsFromNode(context.TODO(), node)
+		if err == nil {
+			return feeTargets
+		}
+		account.log.WithError(err).Error("Could not estimate fee targets from the fee history")
+	}
 	if account.coin.code != coin.CodeSEPETH {
 		etherscanFeeTargets, err := account.coin.client.FeeTargets(context.TODO())
 		if err == nil {
@@ -935,6 +1017,26 @@ type WalletConnectArgs struct {
 	Nonce    string `json:"nonce,omitempty"`
 }
 
+// parse validates and decodes the recipient, value and calldata. The value is nil if not provided.
+func (args WalletConnectArgs) parse() (ethcommon.Address, *big.Int, []byte, error) {
+	if !IsValidEthAddress(args.To) {
+		return ethcommon.Address{}, nil, nil, errp.WithStack(errors.ErrInvalidAddress)
+	}
+	var value *big.Int
+	if args.Value != "" {
+		bigIntValue, ok := new(big.Int).SetString(strings.TrimPrefix(args.Value, "0x"), 16)
+		if !ok {
+			return ethcommon.Address{}, nil, nil, errp.New("error setting transaction value")
+		}
+		value = bigIntValue
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(args.Data, "0x"))
+	if err != nil {
+		return ethcommon.Address{}, nil, nil, err
+	}
+	return ethcommon.HexToAddress(args.To), value, data, nil
+}
+
 // EthSignWalletConnectTx signs an Ethereum Tx received from WalletConnect.
 func (account *Account) EthSignWalletConnectTx(
 	// send: whether transaction should be broadcast after signing
@@ -947,17 +1049,16 @@ func (account *Account) EthSignWalletConnectTx(
 	var nonce uint64
 	var message ethereum.CallMsg
 	var gasPrice *big.Int
-	var value *big.Int
 
 	// Error if chaindId != account.coin.ChainID() (i.e. 1) until L2 RPCs and proper support are added
 	if chainId != account.coin.ChainID() {
 		return "", "", errp.New("Unsupported EVM Network. BBApp only supports Ethereum Mainnet at the moment.")
 	}
 
-	if !IsValidEthAddress(proposedTx.To) {
-		return "", "", errp.WithStack(errors.ErrInvalidAddress)
+	address, value, data, err := proposedTx.parse()
+	if err != nil {
+		return "", "", err
 	}
-	address := ethcommon.HexToAddress(proposedTx.To)
 
 	if proposedTx.Nonce != "" {
 		parsed, err := strconv.ParseUint(strings.TrimPrefix(proposedTx.Nonce, "0x"), 16, 64)
@@ -966,25 +1067,11 @@ func (account *Account) EthSignWalletConnectTx(
 		}
 		nonce = parsed
 	} else {
-		var err error
 		if nonce, err = account.nextNonce(); err != nil {
 			return "", "", err
 		}
 	}
 
-	if proposedTx.Value != "" {
-		bigIntValue, ok := new(big.Int).SetString(strings.TrimPrefix(proposedTx.Value, "0x"), 16)
-		if !ok {
-			return "", "", errp.New("error setting transaction value")
-		}
-		value = bigIntValue
-	}
-
-	data, err := hex.DecodeString(strings.TrimPrefix(proposedTx.Data, "0x"))
-	if err != nil {
-		return "", "", err
-	}
-
 	message = ethereum.CallMsg{
 		From:     account.address.Address,
 		To:       &address,
@@ -13,6 +13,7 @@ import (
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/errors"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/ens"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/rpcclient"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/rpcclient/mocks"
 	ethtypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/types"
@@ -196,6 +197,79 @@ func TestTxProposal(t *testing.T) {
 	})
 }
 
+func TestTxProposalENSName(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+	require.NoError(t, acct.Update(big.NewInt(1e18), big.NewInt(100), nil))
+	require.Eventually(t, acct.Synced, time.Second, time.Millisecond*200)
+
+	recipient := common.HexToAddress("0xa29163852021BF4C139D03Dff59ae763AC73e84e")
+	contracts := ens.NewMockContracts()
+	contracts.Addresses["alice.eth"] = recipient
+	acct.ETHCoin().ensResolver = ens.NewResolver(contracts, contracts.Registry)
+
+	t.Run("resolved", func(t *testing.T) {
+		_, _, _, err := acct.TxProposal(&accounts.TxProposalArgs{
+			RecipientAddress: "Alice.eth",
+			Amount:           coin.NewSendAmount("0.1"),
+			FeeTargetCode:    accounts.FeeTargetCodeCustom,
+			CustomFee:        "20",
+		})
+		require.NoError(t, err)
+		address, ensName := acct.ActiveTxProposalRecipient()
+		require.Equal(t, recipient.Hex(), address)
+		require.Equal(t, "alice.eth", ensName)
+		require.Equal(t, recipient, *acct.activeTxProposal.Tx.To())
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		_, _, _, err := acct.TxProposal(&accounts.TxProposalArgs{
+			RecipientAddress: "bob.eth",
+			Amount:           coin.NewSendAmount("0.1"),
+			FeeTargetCode:    accounts.FeeTargetCodeCustom,
+			CustomFee:        "20",
+		})
+		require.Equal(t, errors.ErrENSNameNotFound, errp.Cause(err))
+	})
+}
+
+func TestTxProposalContractCall(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+	require.NoError(t, acct.Update(big.NewInt(1e18), big.NewInt(100), nil))
+	require.Eventually(t, acct.Synced, time.Second, time.Millisecond*200)
+
+	contract := common.HexToAddress("0xa29163852021BF4C139D03Dff59ae763AC73e84e")
+	data := []byte{0x86, 0xd1, 0xa6, 0x9f}
+	client := acct.coin.client.(*mocks.InterfaceMock)
+	client.EstimateGasFunc = func(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+		require.Equal(t, data, call.Data)
+		return 60000, nil
+	}
+
+	amount, fee, total, err := acct.TxProposal(&accounts.TxProposalArgs{
+		RecipientAddress: contract.Hex(),
+		Amount:           coin.NewSendAmount("0"),
+		FeeTargetCode:    accounts.FeeTargetCodeCustom,
+		CustomFee:        "20",
+		Data:             data,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "0", amount.BigInt().String())
+	require.Equal(t, big.NewInt(60000*20e9), fee.BigInt())
+	require.Equal(t, fee, total)
+	require.Equal(t, data, acct.activeTxProposal.Tx.Data())
+	require.Equal(t, contract, *acct.activeTxProposal.Tx.To())
+}
+
+func TestNoteWithENSName(t *testing.T) {
+	require.Equal(t, "", noteWithENSName("", ""))
+	require.Equal(t, "rent", noteWithENSName("rent", ""))
+	require.Equal(t, "alice.eth", noteWithENSName("", "alice.eth"))
+	require.Equal(t, "rent (alice.eth)", noteWithENSName("rent", "alice.eth"))
+	require.Equal(t, "rent to alice.eth", noteWithENSName("rent to alice.eth", "alice.eth"))
+}
+
 func newTestOutgoingTx() *gethtypes.Transaction {
 	to := common.HexToAddress("0xa29163852021BF4C139D03Dff59ae763AC73e84e")
 	return gethtypes.NewTx(&gethtypes.LegacyTx{
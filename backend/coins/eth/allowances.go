@@ -0,0 +1,210 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/erc20"
+	ethtypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// approvalEventTopic is the topic of the ERC20 `Approval(address,address,uint256)` event.
+var approvalEventTopic = crypto.Keccak256Hash([]byte("Approval(address,address,uint256)"))
+
+// erc20MetadataABI contains the optional ERC20 metadata methods.
+const erc20MetadataABI = `[{"constant":true,"inputs":[],"name":"symbol","outputs":[{"name":"","type":"string"}],"stateMutability":"view","type":"function"},{"constant":true,"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"stateMutability":"view","type":"function"}]`
+
+var (
+	erc20ABI         = ethtypes.MustParseABI(erc20.IERC20ABI)
+	erc20MetadataAbi = ethtypes.MustParseABI(erc20MetadataABI)
+)
+
+// LogsSource provides contract event logs. It is implemented by the Etherscan client.
+type LogsSource interface {
+	FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error)
+}
+
+// Allowance is an ERC20 allowance granted by the account's address to a spender.
+type Allowance struct {
+	Token ethcommon.Address
+	// Symbol and Decimals are read from the token contract. Symbol is empty and Decimals is nil if
+	// the token does not implement the optional metadata methods.
+	Symbol   string
+	Decimals *uint8
+	Spender  ethcommon.Address
+	Amount   *big.Int
+}
+
+// Unlimited returns true if the spender can practically spend all tokens.
+func (allowance *Allowance) Unlimited() bool {
+	return erc20.IsUnlimitedAllowance(allowance.Amount)
+}
+
+// FormatAmount formats the allowance in the token unit, or in the smallest unit if the token
+// decimals are unknown.
+func (allowance *Allowance) FormatAmount() string {
+	if allowance.Decimals == nil {
+		return allowance.Amount.String()
+	}
+	factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(*allowance.Decimals)), nil)
+	s := new(big.Rat).SetFrac(allowance.Amount, factor).FloatString(int(*allowance.Decimals))
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+func (account *Account) callContract(
+	contract ethcommon.Address, contractABI abi.ABI, method string, args ...interface{}) ([]interface{}, error) {
+	data, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	result, err := account.coin.client.CallContract(context.TODO(), ethereum.CallMsg{
+		From: account.address.Address,
+		To:   &contract,
+		Data: data,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	values, err := contractABI.Unpack(method, result)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if len(values) != 1 {
+		return nil, errp.Newf("unexpected number of return values for %s", method)
+	}
+	return values, nil
+}
+
+// tokenMetadata fetches the symbol and decimals of the token. Failures are ignored as the metadata
+// methods are optional.
+func (account *Account) tokenMetadata(token ethcommon.Address) (string, *uint8) {
+	var symbol string
+	var decimals *uint8
+	if values, err := account.callContract(token, erc20MetadataAbi, "symbol"); err == nil {
+		symbol, _ = values[0].(string)
+	}
+	if values, err := account.callContract(token, erc20MetadataAbi, "decimals"); err == nil {
+		if d, ok := values[0].(uint8); ok {
+			decimals = &d
+		}
+	}
+	return symbol, decimals
+}
+
+// Allowances lists the non-zero ERC20 allowances granted by the account's address. Token/spender
+// pairs are found via the `Approval` events emitted for the address, and the current allowance of
+// each pair is read from the token contract.
+func (account *Account) Allowances() ([]*Allowance, error) {
+	if !account.isInitialized() {
+		return nil, errp.New("account must be initialized")
+	}
+	if IsERC20(account) {
+		return nil, errp.New("allowances are listed for the Ethereum account")
+	}
+	logsSource, ok := account.coin.TransactionsSource().(LogsSource)
+	if !ok {
+		return nil, errp.New("the transactions source does not provide event logs")
+	}
+	owner := account.address.Address
+	logs, err := logsSource.FilterLogs(context.TODO(), ethereum.FilterQuery{
+		Topics: [][]ethcommon.Hash{
+			{approvalEventTopic},
+			{ethcommon.BytesToHash(owner.Bytes())},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type pair struct {
+		token   ethcommon.Address
+		spender ethcommon.Address
+	}
+	pairs := map[pair]struct{}{}
+	for _, log := range logs {
+		// ERC721 approvals have the same signature, but the token ID is indexed as the fourth
+		// topic.
+		if len(log.Topics) != 3 {
+			continue
+		}
+		pairs[pair{token: log.Address, spender: ethcommon.BytesToAddress(log.Topics[2].Bytes())}] = struct{}{}
+	}
+
+	metadata := map[ethcommon.Address]*Allowance{}
+	result := []*Allowance{}
+	for p := range pairs {
+		values, err := account.callContract(p.token, erc20ABI, "allowance", owner, p.spender)
+		if err != nil {
+			account.log.WithError(err).Warnf("Could not get allowance of token %s", p.token.Hex())
+			continue
+		}
+		amount, ok := values[0].(*big.Int)
+		if !ok || amount.Sign() == 0 {
+			continue
+		}
+		tokenInfo, ok := metadata[p.token]
+		if !ok {
+			symbol, decimals := account.tokenMetadata(p.token)
+			tokenInfo = &Allowance{Symbol: symbol, Decimals: decimals}
+			metadata[p.token] = tokenInfo
+		}
+		result = append(result, &Allowance{
+			Token:    p.token,
+			Symbol:   tokenInfo.Symbol,
+			Decimals: tokenInfo.Decimals,
+			Spender:  p.spender,
+			Amount:   amount,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if c := bytes.Compare(result[i].Token.Bytes(), result[j].Token.Bytes()); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(result[i].Spender.Bytes(), result[j].Spender.Bytes()) < 0
+	})
+	return result, nil
+}
+
+// RevokeAllowanceTxProposal creates a tx proposal calling `approve(spender, 0)` on the token
+// contract, which sets the allowance to zero. Like TxProposal(), it becomes the active tx proposal
+// which is signed and sent by SendTx(). Returns the fee.
+func (account *Account) RevokeAllowanceTxProposal(
+	token ethcommon.Address,
+	spender ethcommon.Address,
+	feeTargetCode accounts.FeeTargetCode,
+	customFee string,
+) (coin.Amount, error) {
+	defer account.updateLock.Lock()()
+	data, err := erc20ABI.Pack("approve", spender, big.NewInt(0))
+	if err != nil {
+		return coin.Amount{}, errp.WithStack(err)
+	}
+	txProposal, err := account.newTx(&accounts.TxProposalArgs{
+		RecipientAddress: token.Hex(),
+		Amount:           coin.NewSendAmount("0"),
+		FeeTargetCode:    feeTargetCode,
+		CustomFee:        customFee,
+		Data:             data,
+	})
+	if err != nil {
+		return coin.Amount{}, err
+	}
+	account.activeTxProposal = txProposal
+	return coin.NewAmount(txProposal.Fee), nil
+}
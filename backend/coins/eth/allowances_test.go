@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/rpcclient/mocks"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+type logsSourceMock struct {
+	TransactionsSource
+	logs []gethtypes.Log
+}
+
+func (m *logsSourceMock) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]gethtypes.Log, error) {
+	return m.logs, nil
+}
+
+func TestAllowances(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+	owner := acct.address.Address
+
+	tokenA := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	tokenB := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	nft := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	spender1 := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	spender2 := common.HexToAddress("0x0000000000000000000000000000000000000002")
+
+	approval := func(token, spender common.Address, extraTopics ...common.Hash) gethtypes.Log {
+		return gethtypes.Log{
+			Address: token,
+			Topics: append([]common.Hash{
+				approvalEventTopic,
+				common.BytesToHash(owner.Bytes()),
+				common.BytesToHash(spender.Bytes()),
+			}, extraTopics...),
+		}
+	}
+	acct.ETHCoin().TstSetTransactionsSource(&logsSourceMock{logs: []gethtypes.Log{
+		approval(tokenA, spender1),
+		approval(tokenA, spender1),
+		approval(tokenA, spender2),
+		approval(tokenB, spender1),
+		// ERC721 approval, ignored.
+		approval(nft, spender1, common.BigToHash(big.NewInt(1))),
+	}})
+
+	unlimited := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	allowances := map[common.Address]map[common.Address]*big.Int{
+		tokenA: {spender1: big.NewInt(1500000), spender2: big.NewInt(0)},
+		tokenB: {spender1: unlimited},
+	}
+	acct.ETHCoin().TstSetClient(&mocks.InterfaceMock{
+		CallContractFunc: func(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+			require.Equal(t, owner, msg.From)
+			method, err := erc20ABI.MethodById(msg.Data[:4])
+			if err != nil {
+				method, err = erc20MetadataAbi.MethodById(msg.Data[:4])
+				require.NoError(t, err)
+			}
+			switch method.Name {
+			case "allowance":
+				args, err := method.Inputs.Unpack(msg.Data[4:])
+				require.NoError(t, err)
+				require.Equal(t, owner, args[0])
+				return method.Outputs.Pack(allowances[*msg.To][args[1].(common.Address)])
+			case "symbol":
+				if *msg.To == tokenA {
+					return method.Outputs.Pack("TKA")
+				}
+			case "decimals":
+				if *msg.To == tokenA {
+					return method.Outputs.Pack(uint8(6))
+				}
+			}
+			return nil, errp.New("execution reverted")
+		},
+	})
+
+	result, err := acct.Allowances()
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+
+	require.Equal(t, tokenA, result[0].Token)
+	require.Equal(t, spender1, result[0].Spender)
+	require.Equal(t, "TKA", result[0].Symbol)
+	require.Equal(t, "1.5", result[0].FormatAmount())
+	require.False(t, result[0].Unlimited())
+
+	require.Equal(t, tokenB, result[1].Token)
+	require.Equal(t, spender1, result[1].Spender)
+	require.Equal(t, "", result[1].Symbol)
+	require.Nil(t, result[1].Decimals)
+	require.Equal(t, unlimited.String(), result[1].FormatAmount())
+	require.True(t, result[1].Unlimited())
+}
+
+func TestRevokeAllowanceTxProposal(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+	require.NoError(t, acct.Update(big.NewInt(1e18), big.NewInt(100), nil))
+	require.Eventually(t, acct.Synced, time.Second, time.Millisecond*200)
+
+	token := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	spender := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	expectedData, err := erc20ABI.Pack("approve", spender, big.NewInt(0))
+	require.NoError(t, err)
+
+	client := acct.coin.client.(*mocks.InterfaceMock)
+	client.EstimateGasFunc = func(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+		require.Equal(t, token, *call.To)
+		require.True(t, bytes.Equal(expectedData, call.Data))
+		return 46000, nil
+	}
+
+	fee, err := acct.RevokeAllowanceTxProposal(token, spender, accounts.FeeTargetCodeCustom, "20")
+	require.NoError(t, err)
+	tx := acct.activeTxProposal.Tx
+	require.Equal(t, token, *tx.To())
+	require.Equal(t, expectedData, tx.Data())
+	require.Equal(t, big.NewInt(0), tx.Value())
+	require.Equal(t, uint64(46000), tx.Gas())
+	feeInt, err := fee.Int64()
+	require.NoError(t, err)
+	require.Equal(t, int64(46000*20e9), feeInt)
+}
@@ -0,0 +1,173 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package eth
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// ParseCalldata decodes hex encoded calldata, with or without `0x` prefix.
+func ParseCalldata(data string) ([]byte, error) {
+	decoded, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(data), "0x"))
+	if err != nil {
+		return nil, errp.WithStack(errors.ErrInvalidData)
+	}
+	return decoded, nil
+}
+
+// EncodeCalldata ABI-encodes a call to `function` of the contract described by the ABI JSON.
+// `function` is either the method name, or the full signature like `transfer(address,uint256)` to
+// select between overloaded methods. Each argument is a JSON value: a string for addresses, hex
+// encoded bytes and strings, a number or a decimal/hex string for integers, a boolean for bools and
+// an array for arrays. Tuples are not supported.
+func EncodeCalldata(abiJSON string, function string, args []json.RawMessage) ([]byte, error) {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return nil, errp.WithStack(errors.ErrInvalidData)
+	}
+	method, err := findMethod(parsed, function)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != len(method.Inputs) {
+		return nil, errp.WithStack(errors.ErrInvalidData)
+	}
+	values := make([]interface{}, len(args))
+	for i, input := range method.Inputs {
+		value, err := abiValue(input.Type, args[i])
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value.Interface()
+	}
+	arguments, err := method.Inputs.Pack(values...)
+	if err != nil {
+		return nil, errp.WithStack(errors.ErrInvalidData)
+	}
+	return append(append([]byte{}, method.ID...), arguments...), nil
+}
+
+func findMethod(parsed abi.ABI, function string) (abi.Method, error) {
+	function = strings.ReplaceAll(function, " ", "")
+	var found []abi.Method
+	for _, method := range parsed.Methods {
+		if method.Sig == function || method.RawName == function {
+			found = append(found, method)
+		}
+	}
+	if len(found) != 1 {
+		// Not found, or an overloaded method referenced by name only.
+		return abi.Method{}, errp.WithStack(errors.ErrInvalidData)
+	}
+	return found[0], nil
+}
+
+// abiValue converts the JSON argument to the Go type expected by the ABI encoder.
+func abiValue(typ abi.Type, raw json.RawMessage) (reflect.Value, error) {
+	invalid := errp.WithStack(errors.ErrInvalidData)
+	switch typ.T {
+	case abi.AddressTy:
+		var address string
+		if err := json.Unmarshal(raw, &address); err != nil || !IsValidEthAddress(address) {
+			return reflect.Value{}, invalid
+		}
+		return reflect.ValueOf(ethcommon.HexToAddress(address)), nil
+	case abi.BoolTy:
+		var value bool
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return reflect.Value{}, invalid
+		}
+		return reflect.ValueOf(value), nil
+	case abi.StringTy:
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return reflect.Value{}, invalid
+		}
+		return reflect.ValueOf(value), nil
+	case abi.BytesTy, abi.FixedBytesTy:
+		var encoded string
+		if err := json.Unmarshal(raw, &encoded); err != nil {
+			return reflect.Value{}, invalid
+		}
+		value, err := ParseCalldata(encoded)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if typ.T == abi.BytesTy {
+			return reflect.ValueOf(value), nil
+		}
+		if len(value) != typ.Size {
+			return reflect.Value{}, invalid
+		}
+		array := reflect.New(typ.GetType()).Elem()
+		reflect.Copy(array, reflect.ValueOf(value))
+		return array, nil
+	case abi.IntTy, abi.UintTy:
+		value, ok := parseInteger(raw)
+		if !ok || !integerFits(typ, value) {
+			return reflect.Value{}, invalid
+		}
+		goType := typ.GetType()
+		switch goType.Kind() {
+		case reflect.Ptr:
+			return reflect.ValueOf(value), nil
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return reflect.ValueOf(value.Uint64()).Convert(goType), nil
+		default:
+			return reflect.ValueOf(value.Int64()).Convert(goType), nil
+		}
+	case abi.SliceTy, abi.ArrayTy:
+		var elements []json.RawMessage
+		if err := json.Unmarshal(raw, &elements); err != nil {
+			return reflect.Value{}, invalid
+		}
+		var result reflect.Value
+		if typ.T == abi.SliceTy {
+			result = reflect.MakeSlice(typ.GetType(), len(elements), len(elements))
+		} else {
+			if len(elements) != typ.Size {
+				return reflect.Value{}, invalid
+			}
+			result = reflect.New(typ.GetType()).Elem()
+		}
+		for i, element := range elements {
+			value, err := abiValue(*typ.Elem, element)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			result.Index(i).Set(value)
+		}
+		return result, nil
+	}
+	return reflect.Value{}, errp.Newf("unsupported argument type %s", typ.String())
+}
+
+// parseInteger parses a JSON number, or a string containing a decimal or `0x` prefixed hex number.
+func parseInteger(raw json.RawMessage) (*big.Int, bool) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		text = string(bytes.TrimSpace(raw))
+	}
+	if strings.HasPrefix(text, "0x") {
+		return new(big.Int).SetString(text[2:], 16)
+	}
+	return new(big.Int).SetString(text, 10)
+}
+
+func integerFits(typ abi.Type, value *big.Int) bool {
+	if typ.T == abi.UintTy {
+		return value.Sign() >= 0 && value.BitLen() <= typ.Size
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(typ.Size-1))
+	return value.Cmp(new(big.Int).Neg(limit)) >= 0 && value.Cmp(limit) < 0
+}
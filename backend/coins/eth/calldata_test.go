@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package eth
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/errors"
+	ethtypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+)
+
+const testContractABI = `[
+{"inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],"name":"release","outputs":[],"type":"function"},
+{"inputs":[{"name":"id","type":"uint8"},{"name":"flags","type":"bool[2]"},{"name":"salt","type":"bytes32"},{"name":"memo","type":"bytes"}],"name":"submit","outputs":[],"type":"function"},
+{"inputs":[{"name":"to","type":"address"}],"name":"release","outputs":[],"type":"function"}
+]`
+
+func rawArgs(t *testing.T, args ...interface{}) []json.RawMessage {
+	t.Helper()
+	result := make([]json.RawMessage, len(args))
+	for i, arg := range args {
+		encoded, err := json.Marshal(arg)
+		require.NoError(t, err)
+		result[i] = encoded
+	}
+	return result
+}
+
+func TestParseCalldata(t *testing.T) {
+	data, err := ParseCalldata("0xa9059cbb")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xa9, 0x05, 0x9c, 0xbb}, data)
+	data, err = ParseCalldata("a9059cbb")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xa9, 0x05, 0x9c, 0xbb}, data)
+	_, err = ParseCalldata("0xzz")
+	require.Equal(t, errors.ErrInvalidData, errp.Cause(err))
+}
+
+func TestEncodeCalldata(t *testing.T) {
+	to := common.HexToAddress("0xa29163852021BF4C139D03Dff59ae763AC73e84e")
+	parsed := ethtypes.MustParseABI(testContractABI)
+
+	data, err := EncodeCalldata(testContractABI, "release(address, uint256)",
+		rawArgs(t, to.Hex(), "1000000000000000000000"))
+	require.NoError(t, err)
+	amount, _ := new(big.Int).SetString("1000000000000000000000", 10)
+	expected, err := parsed.Pack("release", to, amount)
+	require.NoError(t, err)
+	require.Equal(t, expected, data)
+
+	// Hex and JSON number integers.
+	data, err = EncodeCalldata(testContractABI, "release(address,uint256)", rawArgs(t, to.Hex(), "0x3e8"))
+	require.NoError(t, err)
+	expected, err = parsed.Pack("release", to, big.NewInt(1000))
+	require.NoError(t, err)
+	require.Equal(t, expected, data)
+	data2, err := EncodeCalldata(testContractABI, "release(address,uint256)", rawArgs(t, to.Hex(), 1000))
+	require.NoError(t, err)
+	require.Equal(t, data, data2)
+
+	// Small integers, fixed arrays and bytes.
+	salt := [32]byte{1, 2, 3}
+	data, err = EncodeCalldata(testContractABI, "submit",
+		rawArgs(t, 7, []bool{true, false}, hexutil.Encode(salt[:]), "0xdeadbeef"))
+	require.NoError(t, err)
+	expected, err = parsed.Pack("submit", uint8(7), [2]bool{true, false}, salt, []byte{0xde, 0xad, 0xbe, 0xef})
+	require.NoError(t, err)
+	require.Equal(t, expected, data)
+
+	invalid := []struct {
+		function string
+		args     []json.RawMessage
+	}{
+		// Overloaded method referenced by name.
+		{"release", rawArgs(t, to.Hex())},
+		{"unknown", nil},
+		{"release(address,uint256)", rawArgs(t, to.Hex())},
+		{"release(address,uint256)", rawArgs(t, "0x1234", 1)},
+		{"release(address,uint256)", rawArgs(t, to.Hex(), -1)},
+		{"submit", rawArgs(t, 256, []bool{true, false}, hexutil.Encode(salt[:]), "0x")},
+		{"submit", rawArgs(t, 1, []bool{true}, hexutil.Encode(salt[:]), "0x")},
+		{"submit", rawArgs(t, 1, []bool{true, false}, "0x01", "0x")},
+	}
+	for _, test := range invalid {
+		_, err := EncodeCalldata(testContractABI, test.function, test.args)
+		require.Equal(t, errors.ErrInvalidData, errp.Cause(err), test.function)
+	}
+	_, err = EncodeCalldata("not json", "release", nil)
+	require.Equal(t, errors.ErrInvalidData, errp.Cause(err))
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
 	coinpkg "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/ens"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/erc20"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/rpcclient"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
@@ -41,6 +42,9 @@ type Coin struct {
 
 	transactionsSource TransactionsSource
 
+	// ensResolver resolves ENS names using contract calls through client.
+	ensResolver *ens.Resolver
+
 	log *logrus.Entry
 }
 
@@ -73,6 +77,8 @@ func NewCoin(
 
 		erc20Token: erc20Token,
 
+		ensResolver: ens.NewResolver(client, ens.RegistryAddress),
+
 		log: logging.Get().WithGroup("coin").WithField("code", code),
 	}
 }
@@ -80,6 +86,7 @@ func NewCoin(
 // TstSetClient must only be used in unit tests to mock the RPC client.
 func (coin *Coin) TstSetClient(client rpcclient.Interface) {
 	coin.client = client
+	coin.ensResolver = ens.NewResolver(client, ens.RegistryAddress)
 }
 
 // TstSetTransactionsSource must only be used in unit tests to mock the transactions source.
@@ -214,6 +221,11 @@ func (coin *Coin) ERC20Token() *erc20.Token {
 	return coin.erc20Token
 }
 
+// ENSResolver returns the resolver used to resolve ENS names on this network.
+func (coin *Coin) ENSResolver() *ens.Resolver {
+	return coin.ensResolver
+}
+
 // Close implements coin.Coin.
 func (coin *Coin) Close() error {
 	// TODO: shut down rpc connection.
@@ -4,33 +4,41 @@ package db
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"sort"
 
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/types"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/jsonp"
-	"go.etcd.io/bbolt"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/kvdb"
 )
 
 const (
 	bucketOutgoingTransactions = "pendingTransactions"
+	bucketSigningRequests      = "signingRequests"
+	bucketNFTs                 = "nfts"
 )
 
-// DB is a bbolt key/value database.
+// DB is a key/value database, persisted with bbolt or kept in memory.
 type DB struct {
-	db *bbolt.DB
+	db kvdb.DB
 }
 
 // NewDB creates/opens a new db.
 func NewDB(filename string) (*DB, error) {
-	db, err := bbolt.Open(filename, 0600, nil)
+	db, err := kvdb.OpenBolt(filename)
 	if err != nil {
 		return nil, err
 	}
 	return &DB{db: db}, nil
 }
 
+// NewMemoryDB creates a db which is kept only in memory.
+func NewMemoryDB() *DB {
+	return &DB{db: kvdb.NewMemory()}
+}
+
 // Begin implements transactions.Begin.
 func (db *DB) Begin() (TxInterface, error) {
 	tx, err := db.db.Begin(true)
@@ -41,9 +49,19 @@ func (db *DB) Begin() (TxInterface, error) {
 	if err != nil {
 		return nil, err
 	}
+	bucketSigningRequests, err := tx.CreateBucketIfNotExists([]byte(bucketSigningRequests))
+	if err != nil {
+		return nil, err
+	}
+	bucketNFTs, err := tx.CreateBucketIfNotExists([]byte(bucketNFTs))
+	if err != nil {
+		return nil, err
+	}
 	return &Tx{
 		tx:                         tx,
 		bucketOutgoingTransactions: bucketOutgoingTransactions,
+		bucketSigningRequests:      bucketSigningRequests,
+		bucketNFTs:                 bucketNFTs,
 	}, nil
 }
 
@@ -54,9 +72,11 @@ func (db *DB) Close() error {
 
 // Tx implements DBTxInterface.
 type Tx struct {
-	tx *bbolt.Tx
+	tx kvdb.Tx
 
-	bucketOutgoingTransactions *bbolt.Bucket
+	bucketOutgoingTransactions kvdb.Bucket
+	bucketSigningRequests      kvdb.Bucket
+	bucketNFTs                 kvdb.Bucket
 }
 
 // Rollback implements DBTxInterface.
@@ -102,3 +122,62 @@ func (tx *Tx) OutgoingTransactions() ([]*types.TransactionWithMetadata, error) {
 	sort.Sort(sort.Reverse(byNonce(transactions)))
 	return transactions, nil
 }
+
+// PutSigningRequest implements DBTxInterface.
+func (tx *Tx) PutSigningRequest(request *types.SigningRequest) error {
+	sequence, err := tx.bucketSigningRequests.NextSequence()
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	// Big endian keys keep the entries ordered by insertion.
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, sequence)
+	return tx.bucketSigningRequests.Put(key, jsonp.MustMarshal(request))
+}
+
+// SigningRequests implements DBTxInterface.
+func (tx *Tx) SigningRequests() ([]*types.SigningRequest, error) {
+	requests := []*types.SigningRequest{}
+	cursor := tx.bucketSigningRequests.Cursor()
+	for key, serialized := cursor.Last(); key != nil; key, serialized = cursor.Prev() {
+		request := new(types.SigningRequest)
+		if err := json.Unmarshal(serialized, request); err != nil {
+			return nil, errp.WithStack(err)
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+// PutNFTs implements DBTxInterface.
+func (tx *Tx) PutNFTs(nfts []*types.NFT) error {
+	if err := tx.tx.DeleteBucket([]byte(bucketNFTs)); err != nil {
+		return errp.WithStack(err)
+	}
+	bucket, err := tx.tx.CreateBucket([]byte(bucketNFTs))
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	tx.bucketNFTs = bucket
+	for _, nft := range nfts {
+		key := []byte(nft.Contract + "-" + nft.TokenID)
+		if err := bucket.Put(key, jsonp.MustMarshal(nft)); err != nil {
+			return errp.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// NFTs implements DBTxInterface.
+func (tx *Tx) NFTs() ([]*types.NFT, error) {
+	nfts := []*types.NFT{}
+	cursor := tx.bucketNFTs.Cursor()
+	for key, serialized := cursor.First(); key != nil; key, serialized = cursor.Next() {
+		nft := new(types.NFT)
+		if err := json.Unmarshal(serialized, nft); err != nil {
+			return nil, errp.WithStack(err)
+		}
+		nfts = append(nfts, nft)
+	}
+	return nfts, nil
+}
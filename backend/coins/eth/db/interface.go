@@ -18,6 +18,18 @@ type TxInterface interface {
 	// OutgoingTransactions returns the stored list of outgoing transactions, sorted descending by
 	// the transaction nonce.
 	OutgoingTransactions() ([]*types.TransactionWithMetadata, error)
+
+	// PutSigningRequest appends the entry to the audit log of dApp signing requests.
+	PutSigningRequest(*types.SigningRequest) error
+
+	// SigningRequests returns the audit log of dApp signing requests, newest first.
+	SigningRequests() ([]*types.SigningRequest, error)
+
+	// PutNFTs replaces the stored NFT inventory.
+	PutNFTs([]*types.NFT) error
+
+	// NFTs returns the stored NFT inventory.
+	NFTs() ([]*types.NFT, error)
 }
 
 // Interface can be implemented by database backends to open database transactions.
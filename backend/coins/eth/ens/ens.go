@@ -0,0 +1,238 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ens resolves Ethereum Name Service (ENS) names using read-only contract calls against
+// the ENS registry and resolver contracts. See https://docs.ens.domains/.
+package ens
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"time"
+
+	ethtypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/locker"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// RegistryAddress is the address of the ENS registry. It is the same on mainnet and Sepolia.
+var RegistryAddress = common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+
+// ErrNotFound is returned if a name has no resolver or does not resolve to an address.
+const ErrNotFound errp.ErrorCode = "ensNameNotFound"
+
+// cacheDuration is how long successful and failed lookups are cached.
+const cacheDuration = 10 * time.Minute
+
+const (
+	// RegistryABI contains the subset of the ENS registry interface needed for resolving names.
+	RegistryABI = `[{"constant":true,"inputs":[{"name":"node","type":"bytes32"}],"name":"resolver","outputs":[{"name":"","type":"address"}],"stateMutability":"view","type":"function"}]`
+	// ResolverABI contains the subset of the ENS public resolver interface needed for resolving
+	// names (`addr`).
+	ResolverABI = `[{"constant":true,"inputs":[{"name":"node","type":"bytes32"}],"name":"addr","outputs":[{"name":"","type":"address"}],"stateMutability":"view","type":"function"}]`
+)
+
+var (
+	registryABI = ethtypes.MustParseABI(RegistryABI)
+	resolverABI = ethtypes.MustParseABI(ResolverABI)
+)
+
+// ContractCaller performs read-only contract calls (`eth_call`). It is implemented by
+// rpcclient.Interface.
+type ContractCaller interface {
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+// IsName returns true if the given string looks like an ENS name, e.g. `vitalik.eth`.
+func IsName(name string) bool {
+	normalized, err := Normalize(name)
+	if err != nil {
+		return false
+	}
+	return strings.HasSuffix(normalized, ".eth")
+}
+
+// Normalize lowercases and validates the name. Only names whose labels consist of ASCII letters,
+// digits, hyphens and leading underscores are supported, for which this matches the ENSIP-15
+// normalization. Other names, e.g. containing emoji or non-Latin letters, are rejected, as their
+// normalization requires the ENSIP-15 character tables to prevent resolving a confusable name.
+func Normalize(name string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if normalized == "" {
+		return "", errp.Newf("invalid ENS name: %q", name)
+	}
+	for _, label := range strings.Split(normalized, ".") {
+		if !validLabel(label) {
+			return "", errp.Newf("invalid ENS name: %q", name)
+		}
+	}
+	return normalized, nil
+}
+
+// validLabel returns true if the lowercased label is valid according to the ENSIP-15 rules for
+// ASCII labels.
+func validLabel(label string) bool {
+	if label == "" {
+		return false
+	}
+	// Hyphens in the third and fourth position are reserved, e.g. for punycode (`xn--`).
+	if len(label) >= 4 && label[2:4] == "--" {
+		return false
+	}
+	leading := true
+	for _, char := range label {
+		switch {
+		case char == '_':
+			// Underscores are only allowed at the start of a label.
+			if !leading {
+				return false
+			}
+		case char >= 'a' && char <= 'z', char >= '0' && char <= '9', char == '-':
+			leading = false
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NameHash computes the EIP-137 namehash of an already normalized name.
+func NameHash(name string) common.Hash {
+	var node common.Hash
+	if name == "" {
+		return node
+	}
+	labels := strings.Split(name, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		labelHash := crypto.Keccak256([]byte(labels[i]))
+		node = common.BytesToHash(crypto.Keccak256(node.Bytes(), labelHash))
+	}
+	return node
+}
+
+type cacheEntry struct {
+	value   string
+	expires time.Time
+}
+
+// Resolver resolves ENS names to addresses. Results are cached in memory for a few minutes to avoid
+// repeated contract calls.
+type Resolver struct {
+	caller   ContractCaller
+	registry common.Address
+
+	// cacheLock covers forward.
+	cacheLock locker.Locker
+	// forward maps normalized names to hex addresses. Empty value means not found.
+	forward map[string]cacheEntry
+
+	now func() time.Time
+}
+
+// NewResolver creates a new resolver querying the ENS registry at `registry` using `caller`.
+func NewResolver(caller ContractCaller, registry common.Address) *Resolver {
+	return &Resolver{
+		caller:   caller,
+		registry: registry,
+		forward:  map[string]cacheEntry{},
+		now:      time.Now,
+	}
+}
+
+func (resolver *Resolver) call(
+	ctx context.Context, contract common.Address, contractABI abi.ABI, method string, node common.Hash) ([]interface{}, error) {
+	data, err := contractABI.Pack(method, node)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	result, err := resolver.caller.CallContract(ctx, ethereum.CallMsg{
+		To:   &contract,
+		Data: data,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		// Calls to addresses without code return no data.
+		return nil, errp.WithStack(ErrNotFound)
+	}
+	values, err := contractABI.Unpack(method, result)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if len(values) != 1 {
+		return nil, errp.Newf("unexpected number of return values for %s", method)
+	}
+	return values, nil
+}
+
+// resolverOf returns the resolver contract address of the node.
+func (resolver *Resolver) resolverOf(ctx context.Context, node common.Hash) (common.Address, error) {
+	values, err := resolver.call(ctx, resolver.registry, registryABI, "resolver", node)
+	if err != nil {
+		return common.Address{}, err
+	}
+	resolverAddress, ok := values[0].(common.Address)
+	if !ok || resolverAddress == (common.Address{}) {
+		return common.Address{}, errp.WithStack(ErrNotFound)
+	}
+	return resolverAddress, nil
+}
+
+func (resolver *Resolver) cached(cache map[string]cacheEntry, key string) (cacheEntry, bool) {
+	defer resolver.cacheLock.RLock()()
+	entry, ok := cache[key]
+	if !ok || resolver.now().After(entry.expires) {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// Resolve returns the address the given ENS name resolves to. Returns `ErrNotFound` if the name is
+// not registered or has no address record.
+func (resolver *Resolver) Resolve(ctx context.Context, name string) (common.Address, error) {
+	normalized, err := Normalize(name)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if entry, ok := resolver.cached(resolver.forward, normalized); ok {
+		if entry.value == "" {
+			return common.Address{}, errp.WithStack(ErrNotFound)
+		}
+		return common.HexToAddress(entry.value), nil
+	}
+	address, err := resolver.resolve(ctx, normalized)
+	if err != nil && errp.Cause(err) != ErrNotFound {
+		// Do not cache network errors.
+		return common.Address{}, err
+	}
+	value := ""
+	if err == nil {
+		value = address.Hex()
+	}
+	unlock := resolver.cacheLock.Lock()
+	resolver.forward[normalized] = cacheEntry{value: value, expires: resolver.now().Add(cacheDuration)}
+	unlock()
+	return address, err
+}
+
+func (resolver *Resolver) resolve(ctx context.Context, normalized string) (common.Address, error) {
+	node := NameHash(normalized)
+	resolverAddress, err := resolver.resolverOf(ctx, node)
+	if err != nil {
+		return common.Address{}, err
+	}
+	values, err := resolver.call(ctx, resolverAddress, resolverABI, "addr", node)
+	if err != nil {
+		return common.Address{}, err
+	}
+	address, ok := values[0].(common.Address)
+	if !ok || address == (common.Address{}) {
+		return common.Address{}, errp.WithStack(ErrNotFound)
+	}
+	return address, nil
+}
@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package ens
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNameHash(t *testing.T) {
+	// Test vectors from EIP-137.
+	require.Equal(t, common.Hash{}, NameHash(""))
+	require.Equal(t,
+		common.HexToHash("0x93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae"),
+		NameHash("eth"))
+	require.Equal(t,
+		common.HexToHash("0xde9b09fd7c5f901e23a3f19fecc54828e9c848539801e86591bd9801b019f84f"),
+		NameHash("foo.eth"))
+}
+
+func TestIsName(t *testing.T) {
+	require.True(t, IsName("vitalik.eth"))
+	require.True(t, IsName("Sub.Vitalik.ETH"))
+	require.False(t, IsName("eth"))
+	require.False(t, IsName(".eth"))
+	require.False(t, IsName("foo..eth"))
+	require.False(t, IsName("foo bar.eth"))
+	require.False(t, IsName("0xa29163852021BF4C139D03Dff59ae763AC73e84e"))
+	require.False(t, IsName(""))
+	require.True(t, IsName("_dev-1.eth"))
+	require.False(t, IsName("a_b.eth"))
+	require.False(t, IsName("ab--cd.eth"))
+	// Non-ASCII names are not supported, e.g. the Cyrillic "а" is confusable with "a".
+	require.False(t, IsName("vit\u0430lik.eth"))
+	require.False(t, IsName("🦊.eth"))
+	require.False(t, IsName("straße.eth"))
+}
+
+func TestResolve(t *testing.T) {
+	address := common.HexToAddress("0xa29163852021BF4C139D03Dff59ae763AC73e84e")
+	contracts := NewMockContracts()
+	contracts.Addresses["alice.eth"] = address
+	resolver := NewResolver(contracts, contracts.Registry)
+
+	resolved, err := resolver.Resolve(context.Background(), "Alice.eth")
+	require.NoError(t, err)
+	require.Equal(t, address, resolved)
+	require.Equal(t, 2, contracts.Calls)
+
+	// Served from cache.
+	resolved, err = resolver.Resolve(context.Background(), "alice.eth")
+	require.NoError(t, err)
+	require.Equal(t, address, resolved)
+	require.Equal(t, 2, contracts.Calls)
+
+	_, err = resolver.Resolve(context.Background(), "bob.eth")
+	require.Equal(t, ErrNotFound, errp.Cause(err))
+	require.Equal(t, 3, contracts.Calls)
+	// Negative results are cached too.
+	_, err = resolver.Resolve(context.Background(), "bob.eth")
+	require.Equal(t, ErrNotFound, errp.Cause(err))
+	require.Equal(t, 3, contracts.Calls)
+
+	// Cache entries expire.
+	resolver.now = func() time.Time { return time.Now().Add(cacheDuration + time.Second) }
+	_, err = resolver.Resolve(context.Background(), "alice.eth")
+	require.NoError(t, err)
+	require.Equal(t, 5, contracts.Calls)
+}
+
+func TestResolveNoRegistry(t *testing.T) {
+	contracts := NewMockContracts()
+	contracts.Addresses["alice.eth"] = common.HexToAddress("0xa29163852021BF4C139D03Dff59ae763AC73e84e")
+	resolver := NewResolver(contracts, common.HexToAddress("0x0000000000000000000000000000000000000001"))
+	_, err := resolver.Resolve(context.Background(), "alice.eth")
+	require.Equal(t, ErrNotFound, errp.Cause(err))
+}
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package ens
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// MockContracts is a local stand-in for the ENS registry and a public resolver contract. It
+// implements ContractCaller by decoding the calldata and answering from in-memory records, so
+// resolution can be verified without a node.
+type MockContracts struct {
+	// Registry is the address the registry stand-in is deployed at.
+	Registry common.Address
+	// Resolver is the address the resolver stand-in is deployed at.
+	Resolver common.Address
+	// Addresses maps normalized names to their address record.
+	Addresses map[string]common.Address
+	// Calls counts the contract calls made.
+	Calls int
+}
+
+// NewMockContracts returns a registry and resolver stand-in without any records.
+func NewMockContracts() *MockContracts {
+	return &MockContracts{
+		Registry:  RegistryAddress,
+		Resolver:  common.HexToAddress("0x231b0Ee14048e9dCcD1d247744d114a4EB5E8E63"),
+		Addresses: map[string]common.Address{},
+	}
+}
+
+func (contracts *MockContracts) nodes() map[common.Hash]bool {
+	nodes := map[common.Hash]bool{}
+	for name := range contracts.Addresses {
+		nodes[NameHash(name)] = true
+	}
+	return nodes
+}
+
+// CallContract implements ContractCaller.
+func (contracts *MockContracts) CallContract(
+	ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	contracts.Calls++
+	if msg.To == nil || len(msg.Data) < 4 {
+		return nil, errp.New("invalid call")
+	}
+	var node common.Hash
+	copy(node[:], msg.Data[4:])
+	switch *msg.To {
+	case contracts.Registry:
+		method := registryABI.Methods["resolver"]
+		if !bytes.Equal(msg.Data[:4], method.ID) {
+			return nil, errp.New("execution reverted")
+		}
+		resolver := common.Address{}
+		if contracts.nodes()[node] {
+			resolver = contracts.Resolver
+		}
+		return method.Outputs.Pack(resolver)
+	case contracts.Resolver:
+		method := resolverABI.Methods["addr"]
+		if !bytes.Equal(msg.Data[:4], method.ID) {
+			return nil, errp.New("execution reverted")
+		}
+		for name, address := range contracts.Addresses {
+			if NameHash(name) == node {
+				return method.Outputs.Pack(address)
+			}
+		}
+		return method.Outputs.Pack(common.Address{})
+	}
+	// No contract deployed at this address.
+	return []byte{}, nil
+}
@@ -2,7 +2,21 @@
 
 package erc20
 
-import "github.com/ethereum/go-ethereum/common"
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// unlimitedAllowanceThreshold is 2^255. dApps usually approve the maximum uint256 value, which
+// some tokens decrease as the allowance is spent, so anything above half of it is treated as
+// unlimited.
+var unlimitedAllowanceThreshold = new(big.Int).Lsh(big.NewInt(1), 255)
+
+// IsUnlimitedAllowance returns true if the allowance amount is practically unlimited.
+func IsUnlimitedAllowance(amount *big.Int) bool {
+	return amount.Cmp(unlimitedAllowanceThreshold) >= 0
+}
 
 // Token holds infos about the erc20 token needed to fetch balances, format amounts, etc.
 type Token struct {
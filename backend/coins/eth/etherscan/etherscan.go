@@ -38,6 +38,7 @@ const (
 	maxAddressesForBalances      = 20
 	maxGetRequestTargetLength    = 6000
 	maxTokenTransactionsPerQuery = 10000
+	maxLogsPerQuery              = 1000
 )
 
 // ERC20GasErr is the error message returned from etherscan when there is not enough ETH to pay the transaction fee.
@@ -501,6 +502,163 @@ func (etherScan *EtherScan) TokenTransactionsByContract(
 	return byContract, nil
 }
 
+type jsonLog struct {
+	Address         common.Address `json:"address"`
+	Topics          []common.Hash  `json:"topics"`
+	Data            string         `json:"data"`
+	BlockNumber     string         `json:"blockNumber"`
+	TransactionHash common.Hash    `json:"transactionHash"`
+	LogIndex        string         `json:"logIndex"`
+	TimeStamp       string         `json:"timeStamp"`
+}
+
+// parseHexUint64 parses a hex number. Etherscan encodes zero as "0x".
+func parseHexUint64(s string) (uint64, error) {
+	trimmed := strings.TrimPrefix(s, "0x")
+	if trimmed == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(trimmed, 16, 64)
+}
+
+func (l *jsonLog) log() (*types.Log, error) {
+	data, err := hexutil.Decode(l.Data)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	blockNumber, err := parseHexUint64(l.BlockNumber)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	logIndex, err := parseHexUint64(l.LogIndex)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	timestamp, err := parseHexUint64(l.TimeStamp)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return &types.Log{
+		Address:        l.Address,
+		Topics:         l.Topics,
+		Data:           data,
+		BlockNumber:    blockNumber,
+		TxHash:         l.TransactionHash,
+		Index:          uint(logIndex),
+		BlockTimestamp: timestamp,
+	}, nil
+}
+
+// FilterLogs returns the event logs matching the query, sorted ascending by block number.
+// Etherscan supports at most one contract address and one value per topic position.
+func (etherScan *EtherScan) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	if query.BlockHash != nil {
+		return nil, errp.New("filtering logs by block hash is not supported")
+	}
+	if len(query.Addresses) > 1 {
+		return nil, errp.New("filtering logs by multiple addresses is not supported")
+	}
+	if len(query.Topics) > 4 {
+		return nil, errp.New("too many topics")
+	}
+	params := url.Values{}
+	params.Set("module", "logs")
+	params.Set("action", "getLogs")
+	params.Set("page", "1")
+	params.Set("offset", strconv.Itoa(maxLogsPerQuery))
+	if len(query.Addresses) == 1 {
+		params.Set("address", query.Addresses[0].Hex())
+	}
+	var positions []int
+	for i, topics := range query.Topics {
+		switch len(topics) {
+		case 0:
+			continue
+		case 1:
+			params.Set(fmt.Sprintf("topic%d", i), topics[0].Hex())
+			positions = append(positions, i)
+		default:
+			return nil, errp.New("alternative topics are not supported")
+		}
+	}
+	for i := 0; i < len(positions); i++ {
+		for j := i + 1; j < len(positions); j++ {
+			params.Set(fmt.Sprintf("topic%d_%d_opr", positions[i], positions[j]), "and")
+		}
+	}
+	fromBlock := uint64(0)
+	if query.FromBlock != nil {
+		fromBlock = query.FromBlock.Uint64()
+	}
+	var toBlock *uint64
+	if query.ToBlock != nil {
+		block := query.ToBlock.Uint64()
+		toBlock = &block
+	}
+	return etherScan.filterLogsRange(ctx, params, fromBlock, toBlock)
+}
+
+// filterLogsRange returns the logs matching the query params between fromBlock and toBlock,
+// inclusive. A nil toBlock means the latest block. Etherscan returns at most maxLogsPerQuery logs per
+// request, so if a request returns a full page, the range is split in two halves which are fetched
+// separately.
+func (etherScan *EtherScan) filterLogsRange(
+	ctx context.Context, params url.Values, fromBlock uint64, toBlock *uint64) ([]types.Log, error) {
+	params.Set("fromBlock", strconv.FormatUint(fromBlock, 10))
+	if toBlock == nil {
+		params.Set("toBlock", "latest")
+	} else {
+		params.Set("toBlock", strconv.FormatUint(*toBlock, 10))
+	}
+	var response struct {
+		Status  string          `json:"status"`
+		Message string          `json:"message"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := etherScan.call(ctx, params, &response); err != nil {
+		return nil, err
+	}
+	var page []jsonLog
+	if err := json.Unmarshal(response.Result, &page); err != nil {
+		// On errors, the result is an error message string.
+		return nil, errp.Newf("unexpected response from EtherScan: %s", string(response.Result))
+	}
+	if len(page) < maxLogsPerQuery {
+		result := make([]types.Log, len(page))
+		for i, entry := range page {
+			log, err := entry.log()
+			if err != nil {
+				return nil, err
+			}
+			result[i] = *log
+		}
+		return result, nil
+	}
+
+	// The page is full, so there may be more logs in the range.
+	if toBlock == nil {
+		latest, err := etherScan.BlockNumber(ctx)
+		if err != nil {
+			return nil, err
+		}
+		latestBlock := latest.Uint64()
+		toBlock = &latestBlock
+	}
+	if fromBlock >= *toBlock {
+		return nil, errp.Newf("more than %d logs in block %d", maxLogsPerQuery, fromBlock)
+	}
+	middle := fromBlock + (*toBlock-fromBlock)/2
+	lower, err := etherScan.filterLogsRange(ctx, params, fromBlock, &middle)
+	if err != nil {
+		return nil, err
+	}
+	upper, err := etherScan.filterLogsRange(ctx, params, middle+1, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	return append(lower, upper...), nil
+}
+
 // ----- RPC node proxy methods follow
 
 func (etherScan *EtherScan) rpcCall(ctx context.Context, params url.Values, result interface{}) error {
@@ -736,6 +894,19 @@ func (etherScan *EtherScan) PendingNonceAt(ctx context.Context, account common.A
 	return uint64(result), nil
 }
 
+// NonceAt implements rpc.Interface.
+func (etherScan *EtherScan) NonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	params := url.Values{}
+	params.Set("action", "eth_getTransactionCount")
+	params.Set("address", account.Hex())
+	params.Set("tag", "latest")
+	var result hexutil.Uint64
+	if err := etherScan.rpcCall(ctx, params, &result); err != nil {
+		return 0, err
+	}
+	return uint64(result), nil
+}
+
 // SendTransaction implements rpc.Interface.
 func (etherScan *EtherScan) SendTransaction(ctx context.Context, tx *types.Transaction) error {
 	encodedTx, err := tx.MarshalBinary() // canonical RLP encoding, works for legacy and EIP-1559 txs
@@ -11,6 +11,7 @@ import (
 	"math/big"
 	"net/http"
 	"net/url"
+	"strconv"
 	"testing"
 
 	ethereum "github.com/ethereum/go-ethereum"
@@ -242,3 +243,105 @@ func TestTokenTransactionsByContractPaginationDedup(t *testing.T) {
 	}
 	require.Equal(t, 2, dupCount)
 }
+
+func TestFilterLogs(t *testing.T) {
+	contract := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	topic0 := common.HexToHash("0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925")
+	topic1 := common.HexToHash("0x000000000000000000000000a29163852021bf4c139d03dff59ae763ac73e84e")
+	makeLog := func(blockNumber uint64, logIndex uint64) map[string]interface{} {
+		logIndexHex := fmt.Sprintf("0x%x", logIndex)
+		if logIndex == 0 {
+			logIndexHex = "0x"
+		}
+		return map[string]interface{}{
+			"address":          contract.Hex(),
+			"topics":           []string{topic0.Hex(), topic1.Hex()},
+			"data":             "0x01",
+			"blockNumber":      fmt.Sprintf("0x%x", blockNumber),
+			"timeStamp":        "0x6553f100",
+			"transactionHash":  common.BigToHash(big.NewInt(int64(blockNumber))).Hex(),
+			"transactionIndex": "0x",
+			"logIndex":         logIndexHex,
+		}
+	}
+
+	// 20 logs in each of the blocks 0 to 74.
+	const logsPerBlock = 20
+	const numBlocks = 75
+	var ranges []string
+	etherScan := newTestEtherScan(func(req *http.Request) *http.Response {
+		values := formValues(t, req)
+		require.Equal(t, "logs", values.Get("module"))
+		require.Equal(t, "getLogs", values.Get("action"))
+		require.Equal(t, contract.Hex(), values.Get("address"))
+		require.Equal(t, topic0.Hex(), values.Get("topic0"))
+		require.Equal(t, topic1.Hex(), values.Get("topic1"))
+		require.Equal(t, "and", values.Get("topic0_1_opr"))
+		ranges = append(ranges, values.Get("fromBlock")+"-"+values.Get("toBlock"))
+		fromBlock, err := strconv.Atoi(values.Get("fromBlock"))
+		require.NoError(t, err)
+		toBlock, err := strconv.Atoi(values.Get("toBlock"))
+		require.NoError(t, err)
+
+		logs := []map[string]interface{}{}
+		for block := fromBlock; block <= toBlock && block < numBlocks; block++ {
+			for i := 0; i < logsPerBlock && len(logs) < maxLogsPerQuery; i++ {
+				logs = append(logs, makeLog(uint64(block), uint64(i)))
+			}
+		}
+		body, err := json.Marshal(map[string]interface{}{"status": "1", "message": "OK", "result": logs})
+		require.NoError(t, err)
+		return jsonRPCResponse(t, string(body))
+	})
+
+	logs, err := etherScan.FilterLogs(context.Background(), ethereum.FilterQuery{
+		FromBlock: big.NewInt(0),
+		ToBlock:   big.NewInt(100),
+		Addresses: []common.Address{contract},
+		Topics:    [][]common.Hash{{topic0}, {topic1}},
+	})
+	require.NoError(t, err)
+	// Full pages are split until each range fits in one page.
+	require.Equal(t, []string{"0-100", "0-50", "0-25", "26-50", "51-100"}, ranges)
+	require.Len(t, logs, numBlocks*logsPerBlock)
+	for i, log := range logs {
+		require.Equal(t, uint64(i/logsPerBlock), log.BlockNumber)
+		require.Equal(t, uint(i%logsPerBlock), log.Index)
+	}
+	require.Equal(t, []byte{0x01}, logs[0].Data)
+	require.Equal(t, uint64(0x6553f100), logs[0].BlockTimestamp)
+
+	t.Run("full block", func(t *testing.T) {
+		etherScan := newTestEtherScan(func(req *http.Request) *http.Response {
+			logs := []map[string]interface{}{}
+			for i := 0; i < maxLogsPerQuery; i++ {
+				logs = append(logs, makeLog(7, uint64(i)))
+			}
+			body, err := json.Marshal(map[string]interface{}{"status": "1", "message": "OK", "result": logs})
+			require.NoError(t, err)
+			return jsonRPCResponse(t, string(body))
+		})
+		_, err := etherScan.FilterLogs(context.Background(), ethereum.FilterQuery{
+			FromBlock: big.NewInt(7),
+			ToBlock:   big.NewInt(7),
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("no records", func(t *testing.T) {
+		etherScan := newTestEtherScan(func(req *http.Request) *http.Response {
+			return jsonRPCResponse(t, `{"status":"0","message":"No records found","result":[]}`)
+		})
+		logs, err := etherScan.FilterLogs(context.Background(), ethereum.FilterQuery{})
+		require.NoError(t, err)
+		require.Empty(t, logs)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		etherScan := newTestEtherScan(func(req *http.Request) *http.Response {
+			return jsonRPCResponse(t, `{"status":"0","message":"NOTOK","result":"Max rate limit reached"}`)
+		})
+		_, err := etherScan.FilterLogs(context.Background(), ethereum.FilterQuery{})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	ethtypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	ethereum "github.com/ethereum/go-ethereum"
+)
+
+const (
+	// BlockTimeSeconds is the time between two Ethereum blocks.
+	BlockTimeSeconds = 12
+	// feeHistoryBlocks is the number of recent blocks whose fees are taken into account.
+	feeHistoryBlocks = 20
+	// minGasTipCap is the lowest suggested priority fee, in Wei (0.01 Gwei).
+	minGasTipCap = 1e7
+	// maxGasFeeCap is the highest suggested maxFeePerGas, in Wei (1000 Gwei), unless the base fee
+	// requires more, see `gasFeeCapLimit()`. It protects against paying absurd priority fees if the
+	// node reports a broken fee history.
+	maxGasFeeCap = 1000e9
+	// minBaseFeeHeadroom is the multiple of the next block's base fee, in percent, the
+	// maxFeePerGas allows for even if this exceeds maxGasFeeCap.
+	minBaseFeeHeadroom = 200
+)
+
+// feeHistoryTarget defines how a fee target is derived from the fee history.
+type feeHistoryTarget struct {
+	code accounts.FeeTargetCode
+	// rewardPercentile is the percentile of the priority fees paid within each block.
+	rewardPercentile float64
+	// baseFeeHeadroom is the multiple of the next block's base fee the maxFeePerGas allows for,
+	// in percent. The base fee rises by at most 12.5% per block, so e.g. 200% covers the base fee
+	// of about six consecutive full blocks.
+	baseFeeHeadroom int64
+	// inclusionBlocks is the expected number of blocks until inclusion.
+	inclusionBlocks int
+}
+
+// feeHistoryTargets are ordered from the highest to the lowest target. The reward percentiles are
+// requested in the reverse order, as `eth_feeHistory` requires them to be ascending.
+var feeHistoryTargets = []feeHistoryTarget{
+	{code: accounts.FeeTargetCodeHigh, rewardPercentile: 90, baseFeeHeadroom: 200, inclusionBlocks: 1},
+	{code: accounts.FeeTargetCodeNormal, rewardPercentile: 50, baseFeeHeadroom: 150, inclusionBlocks: 3},
+	{code: accounts.FeeTargetCodeLow, rewardPercentile: 10, baseFeeHeadroom: 125, inclusionBlocks: 10},
+}
+
+// baseFeeSpikePercent is how much the next base fee needs to exceed the median base fee of the
+// recent blocks to be considered a spike.
+const baseFeeSpikePercent = 150
+
+func medianBigInt(values []*big.Int) *big.Int {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := append([]*big.Int{}, values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Cmp(sorted[j]) < 0 })
+	return sorted[len(sorted)/2]
+}
+
+func percentOf(value *big.Int, percent int64) *big.Int {
+	result := new(big.Int).Mul(value, big.NewInt(percent))
+	return result.Div(result, big.NewInt(100))
+}
+
+// gasFeeCapLimit returns the highest suggested maxFeePerGas. It is maxGasFeeCap, but at least twice
+// the next block's base fee plus the minimum priority fee, so that the transactions are still
+// included during a base fee spike above maxGasFeeCap.
+func gasFeeCapLimit(nextBaseFee *big.Int) *big.Int {
+	limit := new(big.Int).Add(percentOf(nextBaseFee, minBaseFeeHeadroom), big.NewInt(minGasTipCap))
+	if limit.Cmp(big.NewInt(maxGasFeeCap)) < 0 {
+		return big.NewInt(maxGasFeeCap)
+	}
+	return limit
+}
+
+// feeTargetsFromHistory computes the fee targets from the `eth_feeHistory` of the recent blocks.
+// The priority fee of each target is the median of the reward percentile over the recent
+// non-empty blocks, and its maxFeePerGas is the priority fee plus the next block's base fee with
+// some headroom for base fee increases, at most `gasFeeCapLimit()`.
+func feeTargetsFromHistory(history *ethereum.FeeHistory) ([]*ethtypes.FeeTarget, error) {
+	// BaseFee contains one more entry than the number of blocks: the base fee of the next block.
+	if len(history.BaseFee) < 2 || len(history.Reward) != len(history.BaseFee)-1 {
+		return nil, errp.New("unexpected fee history")
+	}
+	nextBaseFee := history.BaseFee[len(history.BaseFee)-1]
+	feeCapLimit := gasFeeCapLimit(nextBaseFee)
+	spiking := nextBaseFee.Cmp(
+		percentOf(medianBigInt(history.BaseFee[:len(history.BaseFee)-1]), baseFeeSpikePercent)) > 0
+
+	result := make([]*ethtypes.FeeTarget, len(feeHistoryTargets))
+	for i, target := range feeHistoryTargets {
+		rewards := []*big.Int{}
+		for block, blockRewards := range history.Reward {
+			// Empty blocks report a zero reward which does not reflect the fee market.
+			if len(blockRewards) != len(feeHistoryTargets) ||
+				(block < len(history.GasUsedRatio) && history.GasUsedRatio[block] == 0) {
+				continue
+			}
+			rewards = append(rewards, blockRewards[len(feeHistoryTargets)-1-i])
+		}
+		gasTipCap := big.NewInt(minGasTipCap)
+		if median := medianBigInt(rewards); median != nil && median.Cmp(gasTipCap) > 0 {
+			gasTipCap = median
+		}
+		// A lower target never tips more than a higher one.
+		if i > 0 && gasTipCap.Cmp(result[i-1].GasTipCap) > 0 {
+			gasTipCap = result[i-1].GasTipCap
+		}
+		gasFeeCap := new(big.Int).Add(percentOf(nextBaseFee, target.baseFeeHeadroom), gasTipCap)
+		if gasFeeCap.Cmp(feeCapLimit) > 0 {
+			gasFeeCap = new(big.Int).Set(feeCapLimit)
+		}
+		if gasTipCap.Cmp(gasFeeCap) > 0 {
+			gasTipCap = gasFeeCap
+		}
+		inclusionBlocks := target.inclusionBlocks
+		if spiking && target.code != accounts.FeeTargetCodeHigh {
+			// Blocks are full while the base fee rises, so lower tips wait longer.
+			inclusionBlocks *= 2
+		}
+		result[i] = &ethtypes.FeeTarget{
+			TargetCode:              target.code,
+			GasTipCap:               gasTipCap,
+			GasFeeCap:               gasFeeCap,
+			ExpectedInclusionBlocks: inclusionBlocks,
+			BaseFeeSpiking:          spiking,
+		}
+	}
+	return result, nil
+}
+
+// feeTargetsFromNode estimates the fee targets using the fee history of the node.
+func feeTargetsFromNode(ctx context.Context, node ethereum.FeeHistoryReader) ([]*ethtypes.FeeTarget, error) {
+	percentiles := make([]float64, len(feeHistoryTargets))
+	for i, target := range feeHistoryTargets {
+		percentiles[len(feeHistoryTargets)-1-i] = target.rewardPercentile
+	}
+	history, err := node.FeeHistory(ctx, feeHistoryBlocks, nil, percentiles)
+	if err != nil {
+		return nil, err
+	}
+	return feeTargetsFromHistory(history)
+}
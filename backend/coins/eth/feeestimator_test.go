@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/rpcclient/mocks"
+	ethtypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/stretchr/testify/require"
+)
+
+const gwei = 1e9
+
+func bigInts(values ...int64) []*big.Int {
+	result := make([]*big.Int, len(values))
+	for i, value := range values {
+		result[i] = big.NewInt(value)
+	}
+	return result
+}
+
+func TestFeeTargetsFromHistory(t *testing.T) {
+	history := &ethereum.FeeHistory{
+		OldestBlock: big.NewInt(100),
+		Reward: [][]*big.Int{
+			bigInts(1*gwei, 2*gwei, 5*gwei),
+			bigInts(0, 0, 0),
+			bigInts(1*gwei, 3*gwei, 4*gwei),
+			bigInts(2*gwei, 2*gwei, 6*gwei),
+		},
+		BaseFee:      bigInts(10*gwei, 10*gwei, 11*gwei, 10*gwei, 12*gwei),
+		GasUsedRatio: []float64{0.5, 0, 0.6, 0.4},
+	}
+	targets, err := feeTargetsFromHistory(history)
+	require.NoError(t, err)
+	// The empty second block is ignored.
+	require.Equal(t, []*ethtypes.FeeTarget{
+		{
+			TargetCode:              accounts.FeeTargetCodeHigh,
+			GasTipCap:               big.NewInt(5 * gwei),
+			GasFeeCap:               big.NewInt(29 * gwei),
+			ExpectedInclusionBlocks: 1,
+		},
+		{
+			TargetCode:              accounts.FeeTargetCodeNormal,
+			GasTipCap:               big.NewInt(2 * gwei),
+			GasFeeCap:               big.NewInt(20 * gwei),
+			ExpectedInclusionBlocks: 3,
+		},
+		{
+			TargetCode:              accounts.FeeTargetCodeLow,
+			GasTipCap:               big.NewInt(1 * gwei),
+			GasFeeCap:               big.NewInt(16 * gwei),
+			ExpectedInclusionBlocks: 10,
+		},
+	}, targets)
+
+	t.Run("spiking base fee", func(t *testing.T) {
+		spiking := *history
+		spiking.BaseFee = bigInts(10*gwei, 10*gwei, 11*gwei, 13*gwei, 17*gwei)
+		targets, err := feeTargetsFromHistory(&spiking)
+		require.NoError(t, err)
+		for _, target := range targets {
+			require.True(t, target.BaseFeeSpiking)
+		}
+		require.Equal(t, 1, targets[0].ExpectedInclusionBlocks)
+		require.Equal(t, 6, targets[1].ExpectedInclusionBlocks)
+		require.Equal(t, 20, targets[2].ExpectedInclusionBlocks)
+		require.Equal(t, big.NewInt(39*gwei), targets[0].GasFeeCap)
+	})
+
+	t.Run("minimum tip", func(t *testing.T) {
+		targets, err := feeTargetsFromHistory(&ethereum.FeeHistory{
+			OldestBlock:  big.NewInt(100),
+			Reward:       [][]*big.Int{bigInts(0, 0, 0)},
+			BaseFee:      bigInts(1*gwei, 1*gwei),
+			GasUsedRatio: []float64{0},
+		})
+		require.NoError(t, err)
+		for _, target := range targets {
+			require.Equal(t, big.NewInt(minGasTipCap), target.GasTipCap)
+		}
+	})
+
+	t.Run("maximum fee", func(t *testing.T) {
+		targets, err := feeTargetsFromHistory(&ethereum.FeeHistory{
+			OldestBlock:  big.NewInt(100),
+			Reward:       [][]*big.Int{bigInts(1*gwei, 2*gwei, 2000*gwei)},
+			BaseFee:      bigInts(400*gwei, 400*gwei),
+			GasUsedRatio: []float64{0.5},
+		})
+		require.NoError(t, err)
+		require.Equal(t, big.NewInt(maxGasFeeCap), targets[0].GasFeeCap)
+		require.Equal(t, big.NewInt(maxGasFeeCap), targets[0].GasTipCap)
+		require.Equal(t, big.NewInt(602*gwei), targets[1].GasFeeCap)
+		require.Equal(t, big.NewInt(501*gwei), targets[2].GasFeeCap)
+		require.Equal(t, big.NewInt(1*gwei), targets[2].GasTipCap)
+	})
+
+	t.Run("base fee above maximum fee", func(t *testing.T) {
+		targets, err := feeTargetsFromHistory(&ethereum.FeeHistory{
+			OldestBlock:  big.NewInt(100),
+			Reward:       [][]*big.Int{bigInts(1*gwei, 2*gwei, 2000*gwei)},
+			BaseFee:      bigInts(1500*gwei, 1500*gwei),
+			GasUsedRatio: []float64{0.5},
+		})
+		require.NoError(t, err)
+		limit := big.NewInt(3000*gwei + minGasTipCap)
+		require.Equal(t, limit, targets[0].GasFeeCap)
+		require.Equal(t, big.NewInt(2000*gwei), targets[0].GasTipCap)
+		require.Equal(t, big.NewInt(2252*gwei), targets[1].GasFeeCap)
+		require.Equal(t, big.NewInt(1876*gwei), targets[2].GasFeeCap)
+		// All targets can be included in the next block.
+		for _, target := range targets {
+			require.Equal(t, 1, target.GasFeeCap.Cmp(big.NewInt(1500*gwei)))
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := feeTargetsFromHistory(&ethereum.FeeHistory{BaseFee: bigInts(1)})
+		require.Error(t, err)
+	})
+}
+
+// feeHistoryClientMock is an RPC node client providing the fee history.
+type feeHistoryClientMock struct {
+	mocks.InterfaceMock
+	history *ethereum.FeeHistory
+	err     error
+}
+
+func (m *feeHistoryClientMock) FeeHistory(
+	ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64,
+) (*ethereum.FeeHistory, error) {
+	return m.history, m.err
+}
+
+func TestFeeTargetsUsesFeeHistory(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+
+	client := &feeHistoryClientMock{
+		history: &ethereum.FeeHistory{
+			OldestBlock:  big.NewInt(100),
+			Reward:       [][]*big.Int{bigInts(1*gwei, 2*gwei, 3*gwei)},
+			BaseFee:      bigInts(10*gwei, 10*gwei),
+			GasUsedRatio: []float64{0.5},
+		},
+	}
+	client.SuggestGasPriceFunc = func(ctx context.Context) (*big.Int, error) {
+		return big.NewInt(7 * gwei), nil
+	}
+	acct.ETHCoin().TstSetClient(client)
+
+	feeTargets := acct.feeTargets()
+	require.Len(t, feeTargets, 3)
+	require.Equal(t, big.NewInt(3*gwei), feeTargets[0].GasTipCap)
+
+	// Falls back to eth_gasPrice if the fee history is not available.
+	client.err = errp.New("error")
+	feeTargets = acct.feeTargets()
+	require.Len(t, feeTargets, 1)
+	require.Equal(t, big.NewInt(7*gwei), feeTargets[0].GasFeeCap)
+}
@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package multicall batches read-only contract calls into a single `eth_call` using the Multicall3
+// contract. See https://github.com/mds1/multicall.
+package multicall
+
+import (
+	"context"
+	"math/big"
+	"slices"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/erc20"
+	ethtypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Address is the address of the Multicall3 contract. It is deployed at the same address on
+// mainnet, Sepolia and most other EVM chains.
+var Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+// maxCallsPerBatch limits the number of calls aggregated into one `eth_call`, so the call stays
+// well below the gas limit nodes apply to `eth_call`.
+const maxCallsPerBatch = 300
+
+// ABI contains the `aggregate3` method of Multicall3.
+const ABI = `[{"inputs":[{"components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}],"name":"calls","type":"tuple[]"}],"name":"aggregate3","outputs":[{"components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}],"name":"returnData","type":"tuple[]"}],"stateMutability":"payable","type":"function"}]`
+
+var (
+	multicallABI = ethtypes.MustParseABI(ABI)
+	erc20ABI     = ethtypes.MustParseABI(erc20.IERC20ABI)
+)
+
+// ContractCaller performs read-only contract calls (`eth_call`). It is implemented by
+// rpcclient.Interface.
+type ContractCaller interface {
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+type call3 struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type result struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// BalanceRequest identifies the token balance of an owner.
+type BalanceRequest struct {
+	Token common.Address
+	Owner common.Address
+}
+
+// ERC20Balances fetches the token balances using one `eth_call` per batch of up to
+// maxCallsPerBatch requests. Individual calls may fail (e.g. a non-standard token) without failing
+// the batch; such requests are missing in the result and should be fetched separately. Returns the
+// balances and the number of `eth_call`s made.
+func ERC20Balances(
+	ctx context.Context, caller ContractCaller, requests []BalanceRequest,
+) (map[BalanceRequest]*big.Int, int, error) {
+	balances := make(map[BalanceRequest]*big.Int, len(requests))
+	numCalls := 0
+	for batch := range slices.Chunk(requests, maxCallsPerBatch) {
+		calls := make([]call3, len(batch))
+		for i, request := range batch {
+			callData, err := erc20ABI.Pack("balanceOf", request.Owner)
+			if err != nil {
+				return nil, numCalls, errp.WithStack(err)
+			}
+			calls[i] = call3{Target: request.Token, AllowFailure: true, CallData: callData}
+		}
+		data, err := multicallABI.Pack("aggregate3", calls)
+		if err != nil {
+			return nil, numCalls, errp.WithStack(err)
+		}
+		to := Address
+		numCalls++
+		response, err := caller.CallContract(ctx, ethereum.CallMsg{To: &to, Data: data}, nil)
+		if err != nil {
+			return nil, numCalls, err
+		}
+		if len(response) == 0 {
+			// No contract deployed at this address.
+			return nil, numCalls, errp.New("Multicall3 is not available")
+		}
+		values, err := multicallABI.Unpack("aggregate3", response)
+		if err != nil {
+			return nil, numCalls, errp.WithStack(err)
+		}
+		results := *abi.ConvertType(values[0], new([]result)).(*[]result)
+		if len(results) != len(batch) {
+			return nil, numCalls, errp.New("unexpected number of Multicall3 results")
+		}
+		for i, res := range results {
+			// Calls to addresses without code succeed with empty return data.
+			if !res.Success || len(res.ReturnData) != 32 {
+				continue
+			}
+			balances[batch[i]] = new(big.Int).SetBytes(res.ReturnData)
+		}
+	}
+	return balances, numCalls, nil
+}
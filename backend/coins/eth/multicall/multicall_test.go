@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package multicall
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMulticall answers aggregate3 calls from a balances table. Tokens missing in the table
+// revert.
+type fakeMulticall struct {
+	balances map[BalanceRequest]*big.Int
+	calls    int
+}
+
+func (f *fakeMulticall) CallContract(
+	ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	f.calls++
+	if *msg.To != Address {
+		return nil, errp.New("unexpected contract")
+	}
+	method := multicallABI.Methods["aggregate3"]
+	values, err := method.Inputs.Unpack(msg.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	calls := *abi.ConvertType(values[0], new([]call3)).(*[]call3)
+	results := make([]result, len(calls))
+	for i, call := range calls {
+		args, err := erc20ABI.Methods["balanceOf"].Inputs.Unpack(call.CallData[4:])
+		if err != nil {
+			return nil, err
+		}
+		balance, ok := f.balances[BalanceRequest{Token: call.Target, Owner: args[0].(common.Address)}]
+		if !ok {
+			results[i] = result{Success: false}
+			continue
+		}
+		results[i] = result{Success: true, ReturnData: common.LeftPadBytes(balance.Bytes(), 32)}
+	}
+	return method.Outputs.Pack(results)
+}
+
+func TestERC20Balances(t *testing.T) {
+	tokenA := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	tokenB := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	owner1 := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	owner2 := common.HexToAddress("0x0000000000000000000000000000000000000002")
+
+	fake := &fakeMulticall{balances: map[BalanceRequest]*big.Int{
+		{Token: tokenA, Owner: owner1}: big.NewInt(100),
+		{Token: tokenA, Owner: owner2}: big.NewInt(0),
+		{Token: tokenB, Owner: owner1}: new(big.Int).Lsh(big.NewInt(1), 200),
+	}}
+	requests := []BalanceRequest{
+		{Token: tokenA, Owner: owner1},
+		{Token: tokenA, Owner: owner2},
+		{Token: tokenB, Owner: owner1},
+		// Reverts.
+		{Token: tokenB, Owner: owner2},
+	}
+	balances, numCalls, err := ERC20Balances(context.Background(), fake, requests)
+	require.NoError(t, err)
+	require.Equal(t, 1, numCalls)
+	require.Equal(t, 1, fake.calls)
+	require.Len(t, balances, 3)
+	require.Equal(t, "100", balances[requests[0]].String())
+	require.Equal(t, "0", balances[requests[1]].String())
+	require.Equal(t, new(big.Int).Lsh(big.NewInt(1), 200).String(), balances[requests[2]].String())
+}
+
+func TestERC20BalancesBatches(t *testing.T) {
+	fake := &fakeMulticall{balances: map[BalanceRequest]*big.Int{}}
+	requests := make([]BalanceRequest, maxCallsPerBatch+1)
+	for i := range requests {
+		requests[i] = BalanceRequest{
+			Token: common.BigToAddress(big.NewInt(int64(i + 1))),
+			Owner: common.HexToAddress("0x01"),
+		}
+		fake.balances[requests[i]] = big.NewInt(int64(i))
+	}
+	balances, numCalls, err := ERC20Balances(context.Background(), fake, requests)
+	require.NoError(t, err)
+	require.Equal(t, 2, numCalls)
+	require.Len(t, balances, len(requests))
+}
+
+type noContract struct{}
+
+func (noContract) CallContract(context.Context, ethereum.CallMsg, *big.Int) ([]byte, error) {
+	return []byte{}, nil
+}
+
+func TestERC20BalancesNotDeployed(t *testing.T) {
+	_, numCalls, err := ERC20Balances(context.Background(), noContract{}, []BalanceRequest{{}})
+	require.Error(t, err)
+	require.Equal(t, 1, numCalls)
+}
@@ -0,0 +1,377 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sort"
+	"strings"
+
+	ethtypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	ethereum "github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// transferEventTopic is the topic of the `Transfer(address,address,uint256)` event. ERC20 and ERC721
+// share the signature, but ERC721 indexes the token ID as the fourth topic.
+var transferEventTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// nftABI contains the ERC721 and ERC1155 methods and the ERC1155 events used to build the NFT inventory.
+const nftABI = `[{"inputs":[{"name":"tokenId","type":"uint256"}],"name":"ownerOf","outputs":[{"name":"","type":"address"}],"stateMutability":"view","type":"function"},{"inputs":[{"name":"tokenId","type":"uint256"}],"name":"tokenURI","outputs":[{"name":"","type":"string"}],"stateMutability":"view","type":"function"},{"inputs":[{"name":"account","type":"address"},{"name":"id","type":"uint256"}],"name":"balanceOf","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"name":"id","type":"uint256"}],"name":"uri","outputs":[{"name":"","type":"string"}],"stateMutability":"view","type":"function"},{"anonymous":false,"inputs":[{"indexed":true,"name":"operator","type":"address"},{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":false,"name":"id","type":"uint256"},{"indexed":false,"name":"value","type":"uint256"}],"name":"TransferSingle","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"name":"operator","type":"address"},{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":false,"name":"ids","type":"uint256[]"},{"indexed":false,"name":"values","type":"uint256[]"}],"name":"TransferBatch","type":"event"}]`
+
+var nftAbi = ethtypes.MustParseABI(nftABI)
+
+// ipfsGateway is used to fetch metadata of tokens with an `ipfs://` metadata URI.
+const ipfsGateway = "https://ipfs.io/ipfs/"
+
+const (
+	// ErrNFTMetadataDisabled is returned when fetching metadata from a remote host while the user did
+	// not opt in or the connections are not proxied, see `accounts.AccountConfig.FetchNFTMetadata`.
+	ErrNFTMetadataDisabled errp.ErrorCode = "nftMetadataDisabled"
+	// ErrNFTMetadataHostBlocked is returned if the metadata URI points to the local host or a private
+	// network.
+	ErrNFTMetadataHostBlocked errp.ErrorCode = "nftMetadataHostBlocked"
+)
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which is not covered by
+// `net.IP.IsPrivate()`.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP returns false for loopback, private, link-local and other non-routable addresses.
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() &&
+		!ip.IsUnspecified() && !sharedAddressSpace.Contains(ip)
+}
+
+// validateNFTMetadataURL returns an error if metadata must not be fetched from the URL. Only https
+// is allowed, as the metadata URIs are controlled by the token contracts, and hosts in the local
+// network are blocked so that a token can't make the app send requests to them.
+func validateNFTMetadataURL(metadataURL *url.URL) error {
+	if metadataURL.Scheme != "https" {
+		return errp.New("unsupported metadata URI")
+	}
+	host := strings.ToLower(strings.TrimSuffix(metadataURL.Hostname(), "."))
+	if host == "" {
+		return errp.New("invalid metadata URI")
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".local") {
+		return errp.WithStack(ErrNFTMetadataHostBlocked)
+	}
+	if ip := net.ParseIP(host); ip != nil && !isPublicIP(ip) {
+		return errp.WithStack(ErrNFTMetadataHostBlocked)
+	}
+	return nil
+}
+
+// maxNFTMetadataSize limits the size of fetched token metadata.
+const maxNFTMetadataSize = 1 << 20
+
+type nftKey struct {
+	contract ethcommon.Address
+	tokenID  string
+}
+
+// nftCandidates finds the tokens which were transferred to the account's address, according to the
+// `Transfer`, `TransferSingle` and `TransferBatch` events.
+func (account *Account) nftCandidates(logsSource LogsSource) (map[nftKey]ethtypes.NFTStandard, error) {
+	ownerTopic := ethcommon.BytesToHash(account.address.Address.Bytes())
+	candidates := map[nftKey]ethtypes.NFTStandard{}
+
+	logs, err := logsSource.FilterLogs(context.TODO(), ethereum.FilterQuery{
+		Topics: [][]ethcommon.Hash{{transferEventTopic}, nil, {ownerTopic}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, log := range logs {
+		// ERC20 transfers have only three topics.
+		if len(log.Topics) != 4 {
+			continue
+		}
+		key := nftKey{contract: log.Address, tokenID: log.Topics[3].Big().String()}
+		candidates[key] = ethtypes.NFTStandardERC721
+	}
+
+	for _, event := range []string{"TransferSingle", "TransferBatch"} {
+		logs, err := logsSource.FilterLogs(context.TODO(), ethereum.FilterQuery{
+			Topics: [][]ethcommon.Hash{{nftAbi.Events[event].ID}, nil, nil, {ownerTopic}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, log := range logs {
+			values, err := nftAbi.Unpack(event, log.Data)
+			if err != nil || len(values) != 2 {
+				account.log.WithError(err).Warnf("Skipping invalid %s event of %s", event, log.Address.Hex())
+				continue
+			}
+			var ids []*big.Int
+			switch id := values[0].(type) {
+			case *big.Int:
+				ids = []*big.Int{id}
+			case []*big.Int:
+				ids = id
+			}
+			for _, id := range ids {
+				candidates[nftKey{contract: log.Address, tokenID: id.String()}] = ethtypes.NFTStandardERC1155
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// confirmNFT checks that the account still holds the token and fetches its metadata URI. Returns nil
+// if the token is not held anymore.
+func (account *Account) confirmNFT(key nftKey, standard ethtypes.NFTStandard) (*ethtypes.NFT, error) {
+	tokenID, ok := new(big.Int).SetString(key.tokenID, 10)
+	if !ok {
+		return nil, errp.Newf("invalid token ID %s", key.tokenID)
+	}
+	nft := &ethtypes.NFT{
+		Contract: key.contract.Hex(),
+		Standard: standard,
+		TokenID:  key.tokenID,
+	}
+	switch standard {
+	case ethtypes.NFTStandardERC721:
+		values, err := account.callContract(key.contract, nftAbi, "ownerOf", tokenID)
+		if err != nil {
+			return nil, err
+		}
+		if owner, ok := values[0].(ethcommon.Address); !ok || owner != account.address.Address {
+			return nil, nil
+		}
+		nft.Balance = "1"
+		if values, err := account.callContract(key.contract, nftAbi, "tokenURI", tokenID); err == nil {
+			nft.MetadataURI, _ = values[0].(string)
+		}
+	case ethtypes.NFTStandardERC1155:
+		values, err := account.callContract(key.contract, nftAbi, "balanceOf", account.address.Address, tokenID)
+		if err != nil {
+			return nil, err
+		}
+		balance, ok := values[0].(*big.Int)
+		if !ok || balance.Sign() == 0 {
+			return nil, nil
+		}
+		nft.Balance = balance.String()
+		if values, err := account.callContract(key.contract, nftAbi, "uri", tokenID); err == nil {
+			uri, _ := values[0].(string)
+			// See https://eips.ethereum.org/EIPS/eip-1155#metadata.
+			nft.MetadataURI = strings.ReplaceAll(uri, "{id}", fmt.Sprintf("%064x", tokenID))
+		}
+	}
+	return nft, nil
+}
+
+// sortNFTs sorts by contract and numerically by token ID.
+func sortNFTs(nfts []*ethtypes.NFT) {
+	sort.Slice(nfts, func(i, j int) bool {
+		if nfts[i].Contract != nfts[j].Contract {
+			return nfts[i].Contract < nfts[j].Contract
+		}
+		if len(nfts[i].TokenID) != len(nfts[j].TokenID) {
+			return len(nfts[i].TokenID) < len(nfts[j].TokenID)
+		}
+		return nfts[i].TokenID < nfts[j].TokenID
+	})
+}
+
+// UpdateNFTs rebuilds the NFT inventory of the account from the token transfer events of the
+// account's address. Each token is confirmed to still be held using `ownerOf` (ERC721) or
+// `balanceOf` (ERC1155), and the result is stored in the account DB.
+func (account *Account) UpdateNFTs() ([]*ethtypes.NFT, error) {
+	if !account.isInitialized() {
+		return nil, errp.New("account must be initialized")
+	}
+	if IsERC20(account) {
+		return nil, errp.New("NFTs are listed for the Ethereum account")
+	}
+	logsSource, ok := account.coin.TransactionsSource().(LogsSource)
+	if !ok {
+		return nil, errp.New("the transactions source does not provide event logs")
+	}
+	candidates, err := account.nftCandidates(logsSource)
+	if err != nil {
+		return nil, err
+	}
+	nfts := []*ethtypes.NFT{}
+	for key, standard := range candidates {
+		nft, err := account.confirmNFT(key, standard)
+		if err != nil {
+			account.log.WithError(err).Warnf("Could not confirm NFT %s/%s", key.contract.Hex(), key.tokenID)
+			continue
+		}
+		if nft != nil {
+			nfts = append(nfts, nft)
+		}
+	}
+	sortNFTs(nfts)
+
+	dbTx, err := account.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+	if err := dbTx.PutNFTs(nfts); err != nil {
+		return nil, err
+	}
+	if err := dbTx.Commit(); err != nil {
+		return nil, err
+	}
+	return nfts, nil
+}
+
+// NFTs returns the NFT inventory stored by the last call to UpdateNFTs().
+func (account *Account) NFTs() ([]*ethtypes.NFT, error) {
+	if !account.isInitialized() {
+		return nil, errp.New("account must be initialized")
+	}
+	dbTx, err := account.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+	nfts, err := dbTx.NFTs()
+	if err != nil {
+		return nil, err
+	}
+	sortNFTs(nfts)
+	return nfts, nil
+}
+
+// NFTMetadata fetches the JSON metadata of a token in the inventory from its metadata URI. Only
+// `https://`, `ipfs://` (through a fixed gateway) and inline `data:` URIs are supported. Remote
+// metadata is only fetched if `accounts.AccountConfig.FetchNFTMetadata` allows it, through the
+// configured proxy of the backend.
+func (account *Account) NFTMetadata(contract string, tokenID string) (json.RawMessage, error) {
+	nfts, err := account.NFTs()
+	if err != nil {
+		return nil, err
+	}
+	for _, nft := range nfts {
+		if strings.EqualFold(nft.Contract, contract) && nft.TokenID == tokenID {
+			if nft.MetadataURI == "" {
+				return nil, errp.New("the token has no metadata URI")
+			}
+			return account.fetchNFTMetadata(nft.MetadataURI)
+		}
+	}
+	return nil, errp.New("token not found")
+}
+
+// getNFTMetadata requests the metadata URL, including redirects, only from public hosts. The
+// resolved addresses are checked as well, so that a host name can't point to the local network.
+func (account *Account) getNFTMetadata(metadataURL string) (*http.Response, error) {
+	parsedURL, err := url.Parse(metadataURL)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if err := validateNFTMetadataURL(parsedURL); err != nil {
+		return nil, err
+	}
+	client := *account.httpClient
+	client.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errp.New("too many redirects")
+		}
+		return validateNFTMetadataURL(request.URL)
+	}
+	// The addresses are resolved locally if the connection is not proxied.
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	trace := &httptrace.ClientTrace{
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			for _, addr := range info.Addrs {
+				if !isPublicIP(addr.IP) {
+					cancel(errp.WithStack(ErrNFTMetadataHostBlocked))
+					return
+				}
+			}
+		},
+	}
+	request, err := http.NewRequestWithContext(
+		httptrace.WithClientTrace(ctx, trace), http.MethodGet, metadataURL, nil)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		if cause := context.Cause(ctx); cause != nil {
+			return nil, cause
+		}
+		// Errors of CheckRedirect are wrapped.
+		if urlErr, ok := err.(*url.Error); ok && errp.Cause(urlErr.Err) == ErrNFTMetadataHostBlocked {
+			return nil, errp.WithStack(ErrNFTMetadataHostBlocked)
+		}
+		return nil, errp.WithStack(err)
+	}
+	return response, nil
+}
+
+func (account *Account) fetchNFTMetadata(uri string) (json.RawMessage, error) {
+	var body []byte
+	switch {
+	case strings.HasPrefix(uri, "data:"):
+		header, data, found := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+		if !found {
+			return nil, errp.New("invalid data URI")
+		}
+		if strings.HasSuffix(header, ";base64") {
+			decoded, err := base64.StdEncoding.DecodeString(data)
+			if err != nil {
+				return nil, errp.WithStack(err)
+			}
+			body = decoded
+		} else {
+			decoded, err := url.PathUnescape(data)
+			if err != nil {
+				return nil, errp.WithStack(err)
+			}
+			body = []byte(decoded)
+		}
+	case strings.HasPrefix(uri, "ipfs://"), strings.HasPrefix(uri, "https://"):
+		fetchAllowed := account.Config().FetchNFTMetadata
+		if fetchAllowed == nil || !fetchAllowed() {
+			return nil, errp.WithStack(ErrNFTMetadataDisabled)
+		}
+		if strings.HasPrefix(uri, "ipfs://") {
+			uri = ipfsGateway + strings.TrimPrefix(strings.TrimPrefix(uri, "ipfs://"), "ipfs/")
+		}
+		response, err := account.getNFTMetadata(uri)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = response.Body.Close() }()
+		if response.StatusCode != http.StatusOK {
+			return nil, errp.Newf("fetching the metadata failed with status %d", response.StatusCode)
+		}
+		body, err = io.ReadAll(io.LimitReader(response.Body, maxNFTMetadataSize+1))
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		if len(body) > maxNFTMetadataSize {
+			return nil, errp.New("the metadata is too large")
+		}
+	default:
+		return nil, errp.New("unsupported metadata URI")
+	}
+	body = bytes.TrimSpace(body)
+	if !json.Valid(body) {
+		return nil, errp.New("the metadata is not valid JSON")
+	}
+	return json.RawMessage(body), nil
+}
@@ -0,0 +1,213 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/rpcclient/mocks"
+	ethtypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+// topicLogsSourceMock returns the logs matching the first topic of the query.
+type topicLogsSourceMock struct {
+	TransactionsSource
+	logs []gethtypes.Log
+}
+
+func (m *topicLogsSourceMock) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]gethtypes.Log, error) {
+	result := []gethtypes.Log{}
+	for _, log := range m.logs {
+		if log.Topics[0] == query.Topics[0][0] {
+			result = append(result, log)
+		}
+	}
+	return result, nil
+}
+
+func TestUpdateNFTs(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+	owner := acct.address.Address
+	other := common.HexToAddress("0x0000000000000000000000000000000000000001")
+
+	erc20Token := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	erc721 := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	erc1155 := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+
+	addressTopic := func(address common.Address) common.Hash {
+		return common.BytesToHash(address.Bytes())
+	}
+	mustPack := func(event string, args ...interface{}) []byte {
+		data, err := nftAbi.Events[event].Inputs.NonIndexed().Pack(args...)
+		require.NoError(t, err)
+		return data
+	}
+	acct.ETHCoin().TstSetTransactionsSource(&topicLogsSourceMock{logs: []gethtypes.Log{
+		// ERC20 transfer, ignored.
+		{Address: erc20Token, Topics: []common.Hash{transferEventTopic, addressTopic(other), addressTopic(owner)}},
+		// Token 1 is still held, token 2 was sent away.
+		{Address: erc721, Topics: []common.Hash{
+			transferEventTopic, addressTopic(other), addressTopic(owner), common.BigToHash(big.NewInt(1))}},
+		{Address: erc721, Topics: []common.Hash{
+			transferEventTopic, addressTopic(other), addressTopic(owner), common.BigToHash(big.NewInt(2))}},
+		{
+			Address: erc1155,
+			Topics: []common.Hash{
+				nftAbi.Events["TransferSingle"].ID, addressTopic(other), addressTopic(other), addressTopic(owner)},
+			Data: mustPack("TransferSingle", big.NewInt(10), big.NewInt(5)),
+		},
+		{
+			Address: erc1155,
+			Topics: []common.Hash{
+				nftAbi.Events["TransferBatch"].ID, addressTopic(other), addressTopic(other), addressTopic(owner)},
+			Data: mustPack("TransferBatch",
+				[]*big.Int{big.NewInt(11), big.NewInt(12)}, []*big.Int{big.NewInt(1), big.NewInt(1)}),
+		},
+	}})
+
+	erc1155Balances := map[int64]int64{10: 5, 11: 0, 12: 1}
+	acct.ETHCoin().TstSetClient(&mocks.InterfaceMock{
+		CallContractFunc: func(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+			method, err := nftAbi.MethodById(msg.Data[:4])
+			require.NoError(t, err)
+			args, err := method.Inputs.Unpack(msg.Data[4:])
+			require.NoError(t, err)
+			switch method.Name {
+			case "ownerOf":
+				require.Equal(t, erc721, *msg.To)
+				if args[0].(*big.Int).Int64() == 1 {
+					return method.Outputs.Pack(owner)
+				}
+				return method.Outputs.Pack(other)
+			case "tokenURI":
+				return method.Outputs.Pack("ipfs://Qm/1.json")
+			case "balanceOf":
+				require.Equal(t, erc1155, *msg.To)
+				require.Equal(t, owner, args[0])
+				return method.Outputs.Pack(big.NewInt(erc1155Balances[args[1].(*big.Int).Int64()]))
+			case "uri":
+				return method.Outputs.Pack("https://example.com/{id}.json")
+			}
+			t.Fatalf("unexpected method %s", method.Name)
+			return nil, nil
+		},
+	})
+
+	expected := []*ethtypes.NFT{
+		{
+			Contract:    erc721.Hex(),
+			Standard:    ethtypes.NFTStandardERC721,
+			TokenID:     "1",
+			Balance:     "1",
+			MetadataURI: "ipfs://Qm/1.json",
+		},
+		{
+			Contract:    erc1155.Hex(),
+			Standard:    ethtypes.NFTStandardERC1155,
+			TokenID:     "10",
+			Balance:     "5",
+			MetadataURI: "https://example.com/000000000000000000000000000000000000000000000000000000000000000a.json",
+		},
+		{
+			Contract:    erc1155.Hex(),
+			Standard:    ethtypes.NFTStandardERC1155,
+			TokenID:     "12",
+			Balance:     "1",
+			MetadataURI: "https://example.com/000000000000000000000000000000000000000000000000000000000000000c.json",
+		},
+	}
+	nfts, err := acct.UpdateNFTs()
+	require.NoError(t, err)
+	require.Equal(t, expected, nfts)
+
+	// The inventory is persisted.
+	nfts, err = acct.NFTs()
+	require.NoError(t, err)
+	require.Equal(t, expected, nfts)
+}
+
+func TestFetchNFTMetadata(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+
+	requestedHosts := []string{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedHosts = append(requestedHosts, r.Host)
+		switch r.URL.Path {
+		case "/1.json", "/ipfs/cid/1.json":
+			_, _ = w.Write([]byte(`{"name": "Token 1"}`))
+		case "/redirect.json":
+			http.Redirect(w, r, "https://127.0.0.1/1.json", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	// All hosts are served by the test server, without resolving them.
+	client := server.Client()
+	transport := client.Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+	transport.TLSClientConfig.InsecureSkipVerify = true
+	client.Transport = transport
+	acct.httpClient = client
+
+	// Remote metadata is only fetched if allowed.
+	_, err := acct.fetchNFTMetadata("https://nft.example/1.json")
+	require.Equal(t, ErrNFTMetadataDisabled, errp.Cause(err))
+	acct.Config().FetchNFTMetadata = func() bool { return true }
+
+	metadata, err := acct.fetchNFTMetadata("https://nft.example/1.json")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name": "Token 1"}`, string(metadata))
+
+	metadata, err = acct.fetchNFTMetadata("ipfs://cid/1.json")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name": "Token 1"}`, string(metadata))
+	require.Equal(t, []string{"nft.example", "ipfs.io"}, requestedHosts)
+
+	_, err = acct.fetchNFTMetadata("https://nft.example/2.json")
+	require.Error(t, err)
+
+	// Only public hosts are contacted, also after redirects.
+	for _, uri := range []string{
+		"https://127.0.0.1/1.json",
+		"https://[::1]/1.json",
+		"https://192.168.1.1/1.json",
+		"https://169.254.169.254/1.json",
+		"https://localhost/1.json",
+		"https://router.local/1.json",
+		"https://nft.example/redirect.json",
+	} {
+		_, err = acct.fetchNFTMetadata(uri)
+		require.Equal(t, ErrNFTMetadataHostBlocked, errp.Cause(err), uri)
+	}
+
+	metadata, err = acct.fetchNFTMetadata("data:application/json;base64,eyJuYW1lIjoiVG9rZW4ifQ==")
+	require.NoError(t, err)
+	require.Equal(t, json.RawMessage(`{"name":"Token"}`), metadata)
+
+	metadata, err = acct.fetchNFTMetadata(`data:application/json;utf8,{"name":"Token"}`)
+	require.NoError(t, err)
+	require.Equal(t, json.RawMessage(`{"name":"Token"}`), metadata)
+
+	_, err = acct.fetchNFTMetadata("data:text/plain,not json")
+	require.Error(t, err)
+	_, err = acct.fetchNFTMetadata("ftp://example.com/1.json")
+	require.Error(t, err)
+	_, err = acct.fetchNFTMetadata("http://nft.example/1.json")
+	require.Error(t, err)
+}
@@ -0,0 +1,316 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	ethtypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// NonceBlocker is the lowest nonce which prevents the pending outgoing transactions from being
+// mined.
+type NonceBlocker struct {
+	Nonce uint64 `json:"nonce"`
+	// TxID is the hash of the locally stored transaction with this nonce which the node does not
+	// know about, e.g. because it was dropped from the mempool. Empty if no transaction with this
+	// nonce was sent by this account, in which case the nonce can only be filled by a replacement.
+	TxID string `json:"txID"`
+	// BlockedTxIDs are the hashes of the pending outgoing transactions with a higher nonce.
+	BlockedTxIDs []string `json:"blockedTxIDs"`
+}
+
+// NonceConflict is a pending outgoing transaction whose nonce was already used by another
+// confirmed transaction. It will never be mined.
+type NonceConflict struct {
+	Nonce uint64 `json:"nonce"`
+	TxID  string `json:"txID"`
+}
+
+// NonceState compares the locally stored outgoing transactions with the nonces known to the node.
+type NonceState struct {
+	// ConfirmedNonce is the nonce of the account at the latest block.
+	ConfirmedNonce uint64 `json:"confirmedNonce"`
+	// PendingNonce is the nonce of the account including the transactions in the node's mempool.
+	PendingNonce uint64 `json:"pendingNonce"`
+	// NextNonce is the nonce used for the next transaction.
+	NextNonce uint64 `json:"nextNonce"`
+	// Blocker is nil if no pending outgoing transaction is stuck behind a nonce gap.
+	Blocker   *NonceBlocker   `json:"blocker"`
+	Conflicts []NonceConflict `json:"conflicts"`
+}
+
+// sameAddressAccounts returns the account and the other initialized accounts using its address,
+// see `accounts.AccountConfig.SameAddressAccounts`. Their transactions share the nonces of the
+// address.
+func (account *Account) sameAddressAccounts() []*Account {
+	result := []*Account{account}
+	sameAddressAccounts := account.Config().SameAddressAccounts
+	if sameAddressAccounts == nil {
+		return result
+	}
+	for _, other := range sameAddressAccounts() {
+		if otherAccount, ok := other.(*Account); ok && otherAccount != account && otherAccount.isInitialized() {
+			result = append(result, otherAccount)
+		}
+	}
+	return result
+}
+
+// storedPendingTransactions returns the outgoing transactions stored by this account which were not
+// mined yet.
+func (account *Account) storedPendingTransactions() ([]*ethtypes.TransactionWithMetadata, error) {
+	outgoingTransactions, err := account.outgoingTransactions(nil)
+	if err != nil {
+		return nil, err
+	}
+	pending := []*ethtypes.TransactionWithMetadata{}
+	for _, tx := range outgoingTransactions {
+		if tx.Height == 0 {
+			pending = append(pending, tx)
+		}
+	}
+	return pending, nil
+}
+
+// pendingOutgoingTransactions returns the stored outgoing transactions which were not mined yet,
+// sorted by nonce. This includes the transactions of the accounts using the same address, e.g. the
+// token transfers of the ERC20 accounts of an Ethereum account.
+func (account *Account) pendingOutgoingTransactions() ([]*ethtypes.TransactionWithMetadata, error) {
+	pending := []*ethtypes.TransactionWithMetadata{}
+	for _, sameAddressAccount := range account.sameAddressAccounts() {
+		accountPending, err := sameAddressAccount.storedPendingTransactions()
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, accountPending...)
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].Transaction.Nonce() < pending[j].Transaction.Nonce()
+	})
+	return pending, nil
+}
+
+// nodeKnowsTx returns true if the node has the transaction in its mempool or in a block.
+func (account *Account) nodeKnowsTx(tx *ethtypes.TransactionWithMetadata) bool {
+	remoteTx, _, err := account.coin.client.TransactionByHash(context.TODO(), tx.Transaction.Hash())
+	return err == nil && remoteTx != nil
+}
+
+// NonceState detects pending outgoing transactions which are stuck behind a nonce gap, e.g. because
+// a transaction with a lower nonce was dropped from the mempool, and pending outgoing transactions
+// which conflict with a confirmed transaction using the same nonce.
+func (account *Account) NonceState() (*NonceState, error) {
+	if !account.isInitialized() {
+		return nil, errp.New("account must be initialized")
+	}
+	address := account.address.Address
+	confirmedNonce, err := account.coin.client.NonceAt(context.TODO(), address)
+	if err != nil {
+		return nil, err
+	}
+	pendingNonce, err := account.coin.client.PendingNonceAt(context.TODO(), address)
+	if err != nil {
+		return nil, err
+	}
+	pendingTxs, err := account.pendingOutgoingTransactions()
+	if err != nil {
+		return nil, err
+	}
+	state := &NonceState{
+		ConfirmedNonce: confirmedNonce,
+		PendingNonce:   pendingNonce,
+		NextNonce:      pendingNonce,
+		Conflicts:      []NonceConflict{},
+	}
+
+	byNonce := map[uint64][]*ethtypes.TransactionWithMetadata{}
+	for _, tx := range pendingTxs {
+		nonce := tx.Transaction.Nonce()
+		if nonce < confirmedNonce {
+			// The stored height might just not be updated yet.
+			receipt, err := account.coin.client.TransactionReceiptWithBlockNumber(
+				context.TODO(), tx.Transaction.Hash())
+			if err == nil && receipt != nil {
+				continue
+			}
+			state.Conflicts = append(state.Conflicts, NonceConflict{Nonce: nonce, TxID: tx.TxID()})
+			continue
+		}
+		byNonce[nonce] = append(byNonce[nonce], tx)
+		if nonce+1 > state.NextNonce {
+			state.NextNonce = nonce + 1
+		}
+	}
+	if len(byNonce) == 0 {
+		return state, nil
+	}
+
+	for nonce := confirmedNonce; nonce < state.NextNonce; nonce++ {
+		txs := byNonce[nonce]
+		if len(txs) == 0 {
+			// A transaction with this nonce might have been sent by another wallet using the same
+			// address, in which case the node knows about it.
+			if nonce < pendingNonce {
+				continue
+			}
+			state.Blocker = &NonceBlocker{Nonce: nonce}
+			break
+		}
+		known := false
+		for _, tx := range txs {
+			if account.nodeKnowsTx(tx) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			state.Blocker = &NonceBlocker{Nonce: nonce, TxID: txs[0].TxID()}
+			break
+		}
+	}
+	if state.Blocker != nil {
+		state.Blocker.BlockedTxIDs = []string{}
+		for _, tx := range pendingTxs {
+			if tx.Transaction.Nonce() > state.Blocker.Nonce {
+				state.Blocker.BlockedTxIDs = append(state.Blocker.BlockedTxIDs, tx.TxID())
+			}
+		}
+	}
+	return state, nil
+}
+
+// RebroadcastTx broadcasts the pending outgoing transaction with the given hash again, e.g. if it
+// was dropped from the mempool and blocks later transactions. The transaction can also be one of
+// an account using the same address, e.g. a token transfer of an ERC20 account.
+func (account *Account) RebroadcastTx(txID string) error {
+	if !account.isInitialized() {
+		return errp.New("account must be initialized")
+	}
+	for _, sameAddressAccount := range account.sameAddressAccounts() {
+		pendingTxs, err := sameAddressAccount.storedPendingTransactions()
+		if err != nil {
+			return err
+		}
+		for _, tx := range pendingTxs {
+			if tx.TxID() == txID {
+				return sameAddressAccount.rebroadcastTx(tx)
+			}
+		}
+	}
+	return errp.Newf("pending transaction %s not found", txID)
+}
+
+// rebroadcastTx broadcasts the transaction stored by this account again.
+func (account *Account) rebroadcastTx(tx *ethtypes.TransactionWithMetadata) error {
+	if err := account.coin.client.SendTransaction(context.TODO(), tx.Transaction); err != nil {
+		return errp.WithStack(err)
+	}
+	dbTx, err := account.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+	tx.BroadcastAttempts++
+	if err := dbTx.PutOutgoingTransaction(tx); err != nil {
+		return err
+	}
+	return dbTx.Commit()
+}
+
+// bumpedFee returns the fee increased by the minimum of 10% nodes require to replace a transaction
+// in the mempool.
+func bumpedFee(fee *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(110))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+// ReplaceNonceTxProposal creates a tx proposal sending zero ether to the account's own address with
+// the given nonce. It fills a nonce gap, or replaces the pending transaction with this nonce, in
+// which case the fees are at least 10% higher than those of the replaced transaction. Like
+// TxProposal(), it becomes the active tx proposal which is signed and sent by SendTx(). Returns the
+// fee.
+func (account *Account) ReplaceNonceTxProposal(
+	nonce uint64,
+	feeTargetCode accounts.FeeTargetCode,
+	customFee string,
+) (coin.Amount, error) {
+	defer account.updateLock.Lock()()
+	if !account.isInitialized() {
+		return coin.Amount{}, errp.New("account must be initialized")
+	}
+	if IsERC20(account) {
+		return coin.Amount{}, errp.New("replacement transactions are sent from the Ethereum account")
+	}
+	if !account.Synced() {
+		return coin.Amount{}, errp.WithStack(errors.ErrAccountNotsynced)
+	}
+	confirmedNonce, err := account.coin.client.NonceAt(context.TODO(), account.address.Address)
+	if err != nil {
+		return coin.Amount{}, err
+	}
+	if nonce < confirmedNonce {
+		return coin.Amount{}, errp.Newf("nonce %d is already confirmed", nonce)
+	}
+	gasFeeCap, gasTipCap, err := account.gasFees(&accounts.TxProposalArgs{
+		FeeTargetCode: feeTargetCode,
+		CustomFee:     customFee,
+	})
+	if err != nil {
+		if _, ok := errp.Cause(err).(errors.TxValidationError); ok {
+			return coin.Amount{}, err
+		}
+		account.log.WithError(err).Error("error getting the gas price")
+		return coin.Amount{}, errp.WithStack(errors.ErrFeesNotAvailable)
+	}
+	pendingTxs, err := account.pendingOutgoingTransactions()
+	if err != nil {
+		return coin.Amount{}, err
+	}
+	for _, tx := range pendingTxs {
+		if tx.Transaction.Nonce() != nonce {
+			continue
+		}
+		if minFeeCap := bumpedFee(tx.Transaction.GasFeeCap()); gasFeeCap.Cmp(minFeeCap) < 0 {
+			gasFeeCap = minFeeCap
+		}
+		if minTipCap := bumpedFee(tx.Transaction.GasTipCap()); gasTipCap.Cmp(minTipCap) < 0 {
+			gasTipCap = minTipCap
+		}
+	}
+	if gasTipCap.Cmp(gasFeeCap) > 0 {
+		gasFeeCap = gasTipCap
+	}
+
+	gasLimit := params.TxGas
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(gasLimit), gasFeeCap)
+	if fee.Cmp(account.balance.BigInt()) > 0 {
+		return coin.Amount{}, errp.WithStack(errors.ErrInsufficientFunds)
+	}
+	to := account.address.Address
+	tx, err := account.buildTx(
+		ethereum.CallMsg{To: &to, Value: big.NewInt(0)}, nonce, gasLimit, gasFeeCap, gasTipCap)
+	if err != nil {
+		return coin.Amount{}, err
+	}
+	account.activeTxProposal = &TxProposal{
+		Coin:             account.coin,
+		Tx:               tx,
+		Fee:              fee,
+		Value:            big.NewInt(0),
+		Signer:           types.NewLondonSigner(account.coin.net.ChainID),
+		Keypath:          account.signingConfiguration.AbsoluteKeypath(),
+		RecipientAddress: to.Hex(),
+	}
+	return coin.NewAmount(fee), nil
+}
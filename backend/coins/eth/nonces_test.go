@@ -0,0 +1,319 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/rpcclient/mocks"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/signing"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/logging"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/test"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func newTx(nonce uint64, gasFeeCap int64) *types.Transaction {
+	to := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	return types.NewTx(&types.DynamicFeeTx{
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(gasFeeCap),
+		GasFeeCap: big.NewInt(gasFeeCap),
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(1),
+	})
+}
+
+func newPendingTx(t *testing.T, acct *Account, nonce uint64, gasFeeCap int64) *types.Transaction {
+	t.Helper()
+	tx := newTx(nonce, gasFeeCap)
+	require.NoError(t, acct.storePendingOutgoingTransaction(tx))
+	return tx
+}
+
+// newERC20Account returns an initialized ERC20 token account using the address and the node client
+// of the Ethereum account. Both accounts are configured to share their nonces.
+func newERC20Account(t *testing.T, ethAccount *Account) *Account {
+	t.Helper()
+	tokenCoin := NewCoin(ethAccount.coin.client, "sepeth-erc20-test", "Test token", "TEST", "SEPETH",
+		params.SepoliaChainConfig, "", nil,
+		erc20.NewToken("0x00000000000000000000000000000000000000aa", 6))
+	tokenAccount := NewAccount(
+		&accounts.AccountConfig{
+			Config: &config.Account{
+				Code:                  "accountcode-test",
+				Name:                  "Test token",
+				SigningConfigurations: ethAccount.Config().Config.SigningConfigurations,
+			},
+			DBFolder:        test.TstTempDir("eth-erc20-dbfolder"),
+			SkipInitialSync: true,
+			GetNotifier:     func(signing.Configurations) accounts.Notifier { return nil },
+			SameAddressAccounts: func() []accounts.Interface {
+				return []accounts.Interface{ethAccount}
+			},
+		},
+		tokenCoin,
+		&http.Client{},
+		logging.Get().WithGroup("nonces_test"),
+		make(chan *Account),
+	)
+	require.NoError(t, tokenAccount.Initialize())
+	require.Equal(t, ethAccount.address, tokenAccount.address)
+	ethAccount.Config().SameAddressAccounts = func() []accounts.Interface {
+		return []accounts.Interface{tokenAccount}
+	}
+	return tokenAccount
+}
+
+// setNodeState configures the client mock with the confirmed and pending nonces of the account,
+// and the transactions known to the node.
+func setNodeState(
+	client *mocks.InterfaceMock, confirmedNonce, pendingNonce uint64, knownTxs ...*types.Transaction) {
+	client.NonceAtFunc = func(ctx context.Context, account common.Address) (uint64, error) {
+		return confirmedNonce, nil
+	}
+	client.PendingNonceAtFunc = func(ctx context.Context, account common.Address) (uint64, error) {
+		return pendingNonce, nil
+	}
+	client.TransactionByHashFunc = func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+		for _, tx := range knownTxs {
+			if tx.Hash() == hash {
+				return tx, true, nil
+			}
+		}
+		return nil, false, errp.New("not found")
+	}
+	client.TransactionReceiptWithBlockNumberFunc = func(
+		ctx context.Context, hash common.Hash) (*rpcclient.RPCTransactionReceipt, error) {
+		return nil, nil
+	}
+}
+
+func TestNonceState(t *testing.T) {
+	t.Run("no pending txs", func(t *testing.T) {
+		acct := newAccount(t)
+		defer acct.Close()
+		setNodeState(acct.coin.client.(*mocks.InterfaceMock), 5, 5)
+
+		state, err := acct.NonceState()
+		require.NoError(t, err)
+		require.Equal(t, &NonceState{
+			ConfirmedNonce: 5,
+			PendingNonce:   5,
+			NextNonce:      5,
+			Conflicts:      []NonceConflict{},
+		}, state)
+	})
+
+	t.Run("all pending txs known", func(t *testing.T) {
+		acct := newAccount(t)
+		defer acct.Close()
+		tx5 := newPendingTx(t, acct, 5, 1e9)
+		tx6 := newPendingTx(t, acct, 6, 1e9)
+		setNodeState(acct.coin.client.(*mocks.InterfaceMock), 5, 7, tx5, tx6)
+
+		state, err := acct.NonceState()
+		require.NoError(t, err)
+		require.Nil(t, state.Blocker)
+		require.Empty(t, state.Conflicts)
+		require.Equal(t, uint64(7), state.NextNonce)
+	})
+
+	t.Run("dropped tx blocks later txs", func(t *testing.T) {
+		acct := newAccount(t)
+		defer acct.Close()
+		tx5 := newPendingTx(t, acct, 5, 1e9)
+		tx6 := newPendingTx(t, acct, 6, 1e9)
+		tx7 := newPendingTx(t, acct, 7, 1e9)
+		// The node does not know tx5, so tx6 and tx7 are queued but can't be mined.
+		setNodeState(acct.coin.client.(*mocks.InterfaceMock), 5, 5, tx6, tx7)
+
+		state, err := acct.NonceState()
+		require.NoError(t, err)
+		require.Equal(t, uint64(8), state.NextNonce)
+		require.Equal(t, &NonceBlocker{
+			Nonce:        5,
+			TxID:         tx5.Hash().Hex(),
+			BlockedTxIDs: []string{tx6.Hash().Hex(), tx7.Hash().Hex()},
+		}, state.Blocker)
+	})
+
+	t.Run("missing nonce", func(t *testing.T) {
+		acct := newAccount(t)
+		defer acct.Close()
+		tx6 := newPendingTx(t, acct, 6, 1e9)
+		setNodeState(acct.coin.client.(*mocks.InterfaceMock), 5, 5, tx6)
+
+		state, err := acct.NonceState()
+		require.NoError(t, err)
+		require.Equal(t, &NonceBlocker{
+			Nonce:        5,
+			BlockedTxIDs: []string{tx6.Hash().Hex()},
+		}, state.Blocker)
+	})
+
+	t.Run("nonce used by another wallet", func(t *testing.T) {
+		acct := newAccount(t)
+		defer acct.Close()
+		tx6 := newPendingTx(t, acct, 6, 1e9)
+		// Nonce 5 is in the node's mempool, but was not sent by this account.
+		setNodeState(acct.coin.client.(*mocks.InterfaceMock), 5, 7, tx6)
+
+		state, err := acct.NonceState()
+		require.NoError(t, err)
+		require.Nil(t, state.Blocker)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		acct := newAccount(t)
+		defer acct.Close()
+		tx4 := newPendingTx(t, acct, 4, 1e9)
+		setNodeState(acct.coin.client.(*mocks.InterfaceMock), 5, 5)
+
+		state, err := acct.NonceState()
+		require.NoError(t, err)
+		require.Equal(t, []NonceConflict{{Nonce: 4, TxID: tx4.Hash().Hex()}}, state.Conflicts)
+		require.Nil(t, state.Blocker)
+		require.Equal(t, uint64(5), state.NextNonce)
+	})
+}
+
+func TestRebroadcastTx(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+	tx := newPendingTx(t, acct, 0, 1e9)
+
+	client := acct.coin.client.(*mocks.InterfaceMock)
+	client.SendTransactionFunc = func(ctx context.Context, sent *types.Transaction) error {
+		require.Equal(t, tx.Hash(), sent.Hash())
+		return nil
+	}
+	require.NoError(t, acct.RebroadcastTx(tx.Hash().Hex()))
+	require.Len(t, client.SendTransactionCalls(), 1)
+
+	pendingTxs, err := acct.pendingOutgoingTransactions()
+	require.NoError(t, err)
+	require.Len(t, pendingTxs, 1)
+	require.Equal(t, uint16(2), pendingTxs[0].BroadcastAttempts)
+
+	require.Error(t, acct.RebroadcastTx(common.Hash{}.Hex()))
+}
+
+func TestReplaceNonceTxProposal(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+	client := acct.coin.client.(*mocks.InterfaceMock)
+	client.SuggestGasPriceFunc = func(ctx context.Context) (*big.Int, error) {
+		return big.NewInt(1e9), nil
+	}
+	// The update in the background rebroadcasts the pending tx unknown to the node.
+	client.SendTransactionFunc = func(ctx context.Context, tx *types.Transaction) error {
+		return nil
+	}
+	setNodeState(client, 3, 3)
+	acct.Update(big.NewInt(1e18), big.NewInt(100), nil)
+	require.Eventually(t, acct.Synced, time.Second, 10*time.Millisecond)
+	replaced := newPendingTx(t, acct, 3, 2e9)
+
+	t.Run("replace pending tx", func(t *testing.T) {
+		fee, err := acct.ReplaceNonceTxProposal(3, accounts.FeeTargetCodeNormal, "")
+		require.NoError(t, err)
+		tx := acct.activeTxProposal.Tx
+		require.Equal(t, uint64(3), tx.Nonce())
+		require.Equal(t, acct.address.Address, *tx.To())
+		require.Equal(t, big.NewInt(0), tx.Value())
+		require.Empty(t, tx.Data())
+		// The suggested fee is lower than the fee of the replaced tx, so the replaced tx's fee is
+		// bumped by 10%.
+		require.Equal(t, big.NewInt(2.2e9), tx.GasFeeCap())
+		require.Equal(t, big.NewInt(2.2e9), tx.GasTipCap())
+		require.NotEqual(t, replaced.Hash(), tx.Hash())
+		require.Equal(t, "46200000000000", fee.BigInt().String())
+	})
+
+	t.Run("fill gap", func(t *testing.T) {
+		_, err := acct.ReplaceNonceTxProposal(4, accounts.FeeTargetCodeNormal, "")
+		require.NoError(t, err)
+		tx := acct.activeTxProposal.Tx
+		require.Equal(t, uint64(4), tx.Nonce())
+		require.Equal(t, big.NewInt(1e9), tx.GasFeeCap())
+	})
+
+	t.Run("confirmed nonce", func(t *testing.T) {
+		_, err := acct.ReplaceNonceTxProposal(2, accounts.FeeTargetCodeNormal, "")
+		require.Error(t, err)
+	})
+}
+
+func TestNoncesERC20(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+	tokenAccount := newERC20Account(t, acct)
+	defer tokenAccount.Close()
+
+	client := acct.coin.client.(*mocks.InterfaceMock)
+	client.SuggestGasPriceFunc = func(ctx context.Context) (*big.Int, error) {
+		return big.NewInt(1e9), nil
+	}
+	client.SendTransactionFunc = func(ctx context.Context, tx *types.Transaction) error {
+		return nil
+	}
+	// The token transfer was dropped from the mempool and blocks the later Ethereum transaction.
+	tx := newTx(6, 1e9)
+	setNodeState(client, 5, 5, tx)
+	acct.Update(big.NewInt(1e18), big.NewInt(100), nil)
+	require.Eventually(t, acct.Synced, time.Second, 10*time.Millisecond)
+	tokenTx := newPendingTx(t, tokenAccount, 5, 2e9)
+	require.NoError(t, acct.storePendingOutgoingTransaction(tx))
+
+	state, err := acct.NonceState()
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), state.NextNonce)
+	require.Equal(t, &NonceBlocker{
+		Nonce:        5,
+		TxID:         tokenTx.Hash().Hex(),
+		BlockedTxIDs: []string{tx.Hash().Hex()},
+	}, state.Blocker)
+
+	for _, account := range []*Account{acct, tokenAccount} {
+		nextNonce, err := account.nextNonce()
+		require.NoError(t, err)
+		require.Equal(t, uint64(7), nextNonce)
+	}
+
+	// The token transfer is rebroadcast and updated in the database of the token account.
+	require.NoError(t, acct.RebroadcastTx(tokenTx.Hash().Hex()))
+	sentTokenTx := 0
+	for _, call := range client.SendTransactionCalls() {
+		if call.Tx.Hash() == tokenTx.Hash() {
+			sentTokenTx++
+		}
+	}
+	require.Equal(t, 1, sentTokenTx)
+	tokenPending, err := tokenAccount.storedPendingTransactions()
+	require.NoError(t, err)
+	require.Len(t, tokenPending, 1)
+	require.Equal(t, uint16(2), tokenPending[0].BroadcastAttempts)
+	ethPending, err := acct.storedPendingTransactions()
+	require.NoError(t, err)
+	require.Len(t, ethPending, 1)
+
+	// The replacement of the stuck token transfer pays at least 10% more.
+	_, err = acct.ReplaceNonceTxProposal(5, accounts.FeeTargetCodeNormal, "")
+	require.NoError(t, err)
+	replacement := acct.activeTxProposal.Tx
+	require.Equal(t, uint64(5), replacement.Nonce())
+	require.Equal(t, big.NewInt(2.2e9), replacement.GasFeeCap())
+}
@@ -26,10 +26,17 @@ type Interface interface {
 	Balance(ctx context.Context, account common.Address) (*big.Int, error)
 	// ERC20Balance returns the current confirmed token balance of the given token for the adddress.
 	ERC20Balance(account common.Address, erc20Token *erc20.Token) (*big.Int, error)
+	// CallContract executes a message call transaction, which is directly executed in the VM of
+	// the node, but never mined into the blockchain. blockNumber selects the block height at which
+	// the call runs. It can be nil, in which case the code is taken from the latest known block.
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
 	// SendTransaction injects the transaction into the pending pool for execution.
 	SendTransaction(ctx context.Context, tx *types.Transaction) error
 	// PendingNonceAt retrieves the current pending nonce associated with an account.
 	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	// NonceAt retrieves the nonce of the account at the latest block, i.e. the number of its
+	// confirmed transactions.
+	NonceAt(ctx context.Context, account common.Address) (uint64, error)
 	// EstimateGas tries to estimate the gas needed to execute a specific
 	// transaction based on the current pending state of the backend blockchain.
 	// There is no guarantee that this is the true gas limit requirement as other
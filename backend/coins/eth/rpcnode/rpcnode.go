@@ -0,0 +1,246 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package rpcnode is an Ethereum client talking to a JSON-RPC node, e.g. a self-hosted node
+// configured by the user.
+package rpcnode
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	ethtypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var _ rpcclient.Interface = &Client{}
+var _ ethereum.FeeHistoryReader = &Client{}
+
+// Client implements rpcclient.Interface and ethereum.FeeHistoryReader using the JSON-RPC API of an
+// Ethereum node.
+type Client struct {
+	rpc *rpc.Client
+}
+
+// NewClient creates a client for the node at the given HTTP(S) URL. The connection is made lazily
+// using the given http client, so requests go through the configured proxy.
+func NewClient(nodeURL string, httpClient *http.Client) (*Client, error) {
+	client, err := rpc.DialHTTPWithClient(nodeURL, httpClient)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return &Client{rpc: client}, nil
+}
+
+func blockNumberArg(blockNumber *big.Int) string {
+	if blockNumber == nil {
+		return "latest"
+	}
+	return hexutil.EncodeBig(blockNumber)
+}
+
+func callArg(msg ethereum.CallMsg) map[string]interface{} {
+	arg := map[string]interface{}{
+		"from": msg.From,
+		"to":   msg.To,
+	}
+	if len(msg.Data) > 0 {
+		arg["input"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	if msg.GasPrice != nil && msg.GasPrice.Sign() > 0 {
+		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
+	}
+	return arg
+}
+
+// TransactionReceiptWithBlockNumber implements rpcclient.Interface.
+func (client *Client) TransactionReceiptWithBlockNumber(
+	ctx context.Context, hash common.Hash) (*rpcclient.RPCTransactionReceipt, error) {
+	var receipt *types.Receipt
+	if err := client.rpc.CallContext(ctx, &receipt, "eth_getTransactionReceipt", hash); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if receipt == nil {
+		return nil, nil
+	}
+	result := &rpcclient.RPCTransactionReceipt{Receipt: *receipt}
+	if receipt.BlockNumber != nil {
+		result.BlockNumber = receipt.BlockNumber.Uint64()
+	}
+	return result, nil
+}
+
+// BlockNumber implements rpcclient.Interface.
+func (client *Client) BlockNumber(ctx context.Context) (*big.Int, error) {
+	var result hexutil.Big
+	if err := client.rpc.CallContext(ctx, &result, "eth_blockNumber"); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return result.ToInt(), nil
+}
+
+// TransactionByHash implements rpcclient.Interface.
+func (client *Client) TransactionByHash(
+	ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	var raw json.RawMessage
+	if err := client.rpc.CallContext(ctx, &raw, "eth_getTransactionByHash", hash); err != nil {
+		return nil, false, errp.WithStack(err)
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, false, errp.WithStack(ethereum.NotFound)
+	}
+	// The transaction's JSON unmarshaller ignores the block number, so it is decoded separately.
+	var tx types.Transaction
+	if err := json.Unmarshal(raw, &tx); err != nil {
+		return nil, false, errp.WithStack(err)
+	}
+	var extra struct {
+		BlockNumber *string `json:"blockNumber"`
+	}
+	if err := json.Unmarshal(raw, &extra); err != nil {
+		return nil, false, errp.WithStack(err)
+	}
+	return &tx, extra.BlockNumber == nil, nil
+}
+
+// Balance implements rpcclient.Interface.
+func (client *Client) Balance(ctx context.Context, account common.Address) (*big.Int, error) {
+	var result hexutil.Big
+	if err := client.rpc.CallContext(ctx, &result, "eth_getBalance", account, "latest"); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return result.ToInt(), nil
+}
+
+// ERC20Balance implements rpcclient.Interface.
+func (client *Client) ERC20Balance(account common.Address, erc20Token *erc20.Token) (*big.Int, error) {
+	// balanceOf(address)
+	data := append([]byte{0x70, 0xa0, 0x82, 0x31}, common.LeftPadBytes(account.Bytes(), 32)...)
+	contract := erc20Token.ContractAddress()
+	result, err := client.CallContract(
+		context.TODO(), ethereum.CallMsg{From: account, To: &contract, Data: data}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) != 32 {
+		return nil, errp.New("unexpected response from the node")
+	}
+	return new(big.Int).SetBytes(result), nil
+}
+
+// CallContract implements rpcclient.Interface.
+func (client *Client) CallContract(
+	ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	var result hexutil.Bytes
+	if err := client.rpc.CallContext(
+		ctx, &result, "eth_call", callArg(msg), blockNumberArg(blockNumber)); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return result, nil
+}
+
+// SendTransaction implements rpcclient.Interface.
+func (client *Client) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	encodedTx, err := tx.MarshalBinary()
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	if err := client.rpc.CallContext(ctx, nil, "eth_sendRawTransaction", hexutil.Bytes(encodedTx)); err != nil {
+		return errp.WithStack(err)
+	}
+	return nil
+}
+
+func (client *Client) transactionCount(ctx context.Context, account common.Address, tag string) (uint64, error) {
+	var result hexutil.Uint64
+	if err := client.rpc.CallContext(ctx, &result, "eth_getTransactionCount", account, tag); err != nil {
+		return 0, errp.WithStack(err)
+	}
+	return uint64(result), nil
+}
+
+// PendingNonceAt implements rpcclient.Interface.
+func (client *Client) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return client.transactionCount(ctx, account, "pending")
+}
+
+// NonceAt implements rpcclient.Interface.
+func (client *Client) NonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return client.transactionCount(ctx, account, "latest")
+}
+
+// EstimateGas implements rpcclient.Interface.
+func (client *Client) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	var result hexutil.Uint64
+	if err := client.rpc.CallContext(ctx, &result, "eth_estimateGas", callArg(msg)); err != nil {
+		return 0, errp.WithStack(err)
+	}
+	return uint64(result), nil
+}
+
+// SuggestGasPrice implements rpcclient.Interface.
+func (client *Client) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	var result hexutil.Big
+	if err := client.rpc.CallContext(ctx, &result, "eth_gasPrice"); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return result.ToInt(), nil
+}
+
+// FeeTargets implements rpcclient.Interface. Nodes do not provide fee targets. They are estimated
+// from FeeHistory() instead.
+func (client *Client) FeeTargets(ctx context.Context) ([]*ethtypes.FeeTarget, error) {
+	return nil, errp.New("fee targets are not provided by RPC nodes")
+}
+
+// FeeHistory implements ethereum.FeeHistoryReader.
+func (client *Client) FeeHistory(
+	ctx context.Context,
+	blockCount uint64,
+	lastBlock *big.Int,
+	rewardPercentiles []float64,
+) (*ethereum.FeeHistory, error) {
+	var result struct {
+		OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+		Reward       [][]*hexutil.Big `json:"reward"`
+		BaseFee      []*hexutil.Big   `json:"baseFeePerGas"`
+		GasUsedRatio []float64        `json:"gasUsedRatio"`
+	}
+	if err := client.rpc.CallContext(ctx, &result, "eth_feeHistory",
+		hexutil.Uint(blockCount), blockNumberArg(lastBlock), rewardPercentiles); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if result.OldestBlock == nil {
+		return nil, errp.New("unexpected response from the node")
+	}
+	history := &ethereum.FeeHistory{
+		OldestBlock:  result.OldestBlock.ToInt(),
+		Reward:       make([][]*big.Int, len(result.Reward)),
+		BaseFee:      make([]*big.Int, len(result.BaseFee)),
+		GasUsedRatio: result.GasUsedRatio,
+	}
+	for i, rewards := range result.Reward {
+		history.Reward[i] = make([]*big.Int, len(rewards))
+		for j, reward := range rewards {
+			history.Reward[i][j] = reward.ToInt()
+		}
+	}
+	for i, baseFee := range result.BaseFee {
+		history.BaseFee[i] = baseFee.ToInt()
+	}
+	return history, nil
+}
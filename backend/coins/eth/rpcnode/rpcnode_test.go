@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package rpcnode
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient returns a client for a node responding with the given results by method.
+func newTestClient(t *testing.T, results map[string]string) (*Client, *[]json.RawMessage) {
+	t.Helper()
+	var params []json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		params = append(params, request.Params)
+		result, ok := results[request.Method]
+		require.True(t, ok, request.Method)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(request.ID) + `,"result":` + result + `}`))
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClient(server.URL, server.Client())
+	require.NoError(t, err)
+	return client, &params
+}
+
+func TestFeeHistory(t *testing.T) {
+	client, params := newTestClient(t, map[string]string{
+		"eth_feeHistory": `{
+  "oldestBlock": "0x64",
+  "reward": [["0x1", "0x2"], ["0x3", "0x4"]],
+  "baseFeePerGas": ["0xa", "0xb", "0xc"],
+  "gasUsedRatio": [0.5, 0.25]
+}`,
+	})
+	history, err := client.FeeHistory(context.Background(), 2, nil, []float64{10, 90})
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(100), history.OldestBlock)
+	require.Equal(t, [][]*big.Int{
+		{big.NewInt(1), big.NewInt(2)},
+		{big.NewInt(3), big.NewInt(4)},
+	}, history.Reward)
+	require.Equal(t, []*big.Int{big.NewInt(10), big.NewInt(11), big.NewInt(12)}, history.BaseFee)
+	require.Equal(t, []float64{0.5, 0.25}, history.GasUsedRatio)
+	require.JSONEq(t, `["0x2", "latest", [10, 90]]`, string((*params)[0]))
+}
+
+func TestNonces(t *testing.T) {
+	client, params := newTestClient(t, map[string]string{
+		"eth_getTransactionCount": `"0x5"`,
+	})
+	address := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	nonce, err := client.NonceAt(context.Background(), address)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), nonce)
+	nonce, err = client.PendingNonceAt(context.Background(), address)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), nonce)
+	require.JSONEq(t, `["0x0000000000000000000000000000000000000001", "latest"]`, string((*params)[0]))
+	require.JSONEq(t, `["0x0000000000000000000000000000000000000001", "pending"]`, string((*params)[1]))
+}
+
+func TestTransactionByHash(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		client, _ := newTestClient(t, map[string]string{"eth_getTransactionByHash": `null`})
+		_, _, err := client.TransactionByHash(context.Background(), common.Hash{})
+		require.Error(t, err)
+	})
+
+	t.Run("pending", func(t *testing.T) {
+		client, _ := newTestClient(t, map[string]string{"eth_getTransactionByHash": `{
+  "blockHash": null,
+  "blockNumber": null,
+  "from": "0x0000000000000000000000000000000000000002",
+  "gas": "0x5208",
+  "gasPrice": "0x3b9aca00",
+  "hash": "0x2d2e0f6c87dc5fbb9d20f9a2c1fcb5ba1d3f1ed5c0b8db0ad0c5b0ccdd4b2d0d",
+  "input": "0x",
+  "nonce": "0x1",
+  "to": "0x0000000000000000000000000000000000000001",
+  "transactionIndex": null,
+  "value": "0x1",
+  "type": "0x0",
+  "v": "0x1b",
+  "r": "0x1",
+  "s": "0x1"
+}`})
+		tx, isPending, err := client.TransactionByHash(context.Background(), common.Hash{})
+		require.NoError(t, err)
+		require.True(t, isPending)
+		require.Equal(t, uint64(1), tx.Nonce())
+	})
+}
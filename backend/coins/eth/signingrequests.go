@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package eth
+
+import (
+	"strings"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/txpreview"
+	ethtypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+// WalletConnectTxPreview decodes a transaction received from WalletConnect and flags risks, e.g.
+// unlimited approvals or recipients this account never sent to.
+func (account *Account) WalletConnectTxPreview(
+	chainID uint64, proposedTx WalletConnectArgs) (*txpreview.Preview, error) {
+	if !account.isInitialized() {
+		return nil, errp.New("account must be initialized")
+	}
+	to, value, data, err := proposedTx.parse()
+	if err != nil {
+		return nil, err
+	}
+	preview := txpreview.DecodeTx(to, value, data)
+	account.addPreviewWarnings(preview, chainID)
+	return preview, nil
+}
+
+// TypedMsgPreview decodes an EIP-712 typed message received from WalletConnect and flags risks,
+// e.g. unlimited permits or a domain chain ID not matching the account.
+func (account *Account) TypedMsgPreview(chainID uint64, data string) (*txpreview.Preview, error) {
+	if !account.isInitialized() {
+		return nil, errp.New("account must be initialized")
+	}
+	preview, err := txpreview.DecodeTypedData([]byte(data))
+	if err != nil {
+		return nil, err
+	}
+	if preview.ChainID != 0 && preview.ChainID != account.coin.ChainID() {
+		preview.AddWarning(txpreview.WarningChainIDMismatch)
+	}
+	account.addPreviewWarnings(preview, chainID)
+	return preview, nil
+}
+
+// addPreviewWarnings adds the warnings which depend on the account.
+func (account *Account) addPreviewWarnings(preview *txpreview.Preview, chainID uint64) {
+	if chainID != account.coin.ChainID() {
+		preview.AddWarning(txpreview.WarningChainIDMismatch)
+	}
+	if preview.Counterparty != "" && !account.hasSentTo(preview.Counterparty) {
+		preview.AddWarning(txpreview.WarningUnknownRecipient)
+	}
+}
+
+// hasSentTo returns true if the transaction history, including the ERC20 token transfers of an
+// Ethereum account, contains an outgoing transaction to the address.
+func (account *Account) hasSentTo(address string) bool {
+	sentTo := func(transactions []*accounts.TransactionData) bool {
+		for _, tx := range transactions {
+			if tx.Type != accounts.TxTypeSend {
+				continue
+			}
+			for _, addressAndAmount := range tx.Addresses {
+				if strings.EqualFold(addressAndAmount.Address, address) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	unlock := account.updateLock.RLock()
+	found := sentTo(account.transactions)
+	unlock()
+	if found {
+		return true
+	}
+	if erc20Transactions := account.Config().ERC20Transactions; erc20Transactions != nil {
+		return sentTo(erc20Transactions())
+	}
+	return false
+}
+
+// LogSigningRequest appends the request to the persisted audit log of dApp signing requests.
+func (account *Account) LogSigningRequest(request *ethtypes.SigningRequest) error {
+	if !account.isInitialized() {
+		return errp.New("account must be initialized")
+	}
+	dbTx, err := account.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+	if err := dbTx.PutSigningRequest(request); err != nil {
+		return err
+	}
+	return dbTx.Commit()
+}
+
+// SigningRequests returns the audit log of dApp signing requests, newest first.
+func (account *Account) SigningRequests() ([]*ethtypes.SigningRequest, error) {
+	if !account.isInitialized() {
+		return nil, errp.New("account must be initialized")
+	}
+	dbTx, err := account.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+	return dbTx.SigningRequests()
+}
@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/txpreview"
+	ethtypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalletConnectTxPreview(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+	chainID := acct.coin.ChainID()
+	recipient := common.HexToAddress("0xa29163852021BF4C139D03Dff59ae763AC73e84e")
+	args := WalletConnectArgs{To: recipient.Hex(), Value: "0x64"}
+
+	preview, err := acct.WalletConnectTxPreview(chainID, args)
+	require.NoError(t, err)
+	require.Equal(t, txpreview.KindTransfer, preview.Kind)
+	require.Equal(t, []txpreview.Warning{txpreview.WarningUnknownRecipient}, preview.Warnings)
+
+	preview, err = acct.WalletConnectTxPreview(chainID+1, args)
+	require.NoError(t, err)
+	require.Equal(t, []txpreview.Warning{
+		txpreview.WarningChainIDMismatch, txpreview.WarningUnknownRecipient}, preview.Warnings)
+
+	unlock := acct.updateLock.Lock()
+	acct.transactions = []*accounts.TransactionData{{
+		Type:      accounts.TxTypeSend,
+		Addresses: []accounts.AddressAndAmount{{Address: recipient.Hex()}},
+	}}
+	unlock()
+	preview, err = acct.WalletConnectTxPreview(chainID, args)
+	require.NoError(t, err)
+	require.Empty(t, preview.Warnings)
+
+	_, err = acct.WalletConnectTxPreview(chainID, WalletConnectArgs{To: "invalid"})
+	require.Error(t, err)
+
+	// Token transfers of the ERC20 accounts count as known recipients too.
+	tokenRecipient := common.HexToAddress("0x0000000000000000000000000000000000000002")
+	tokenArgs := WalletConnectArgs{To: tokenRecipient.Hex(), Value: "0x64"}
+	preview, err = acct.WalletConnectTxPreview(chainID, tokenArgs)
+	require.NoError(t, err)
+	require.Equal(t, []txpreview.Warning{txpreview.WarningUnknownRecipient}, preview.Warnings)
+	acct.Config().ERC20Transactions = func() []*accounts.TransactionData {
+		return []*accounts.TransactionData{{
+			Type:      accounts.TxTypeSend,
+			Addresses: []accounts.AddressAndAmount{{Address: tokenRecipient.Hex()}},
+		}}
+	}
+	preview, err = acct.WalletConnectTxPreview(chainID, tokenArgs)
+	require.NoError(t, err)
+	require.Empty(t, preview.Warnings)
+}
+
+func TestTypedMsgPreview(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+	data := `{
+  "primaryType": "Permit",
+  "domain": {"chainId": 1, "verifyingContract": "0x00000000000000000000000000000000000000aa"},
+  "message": {"spender": "0x0000000000000000000000000000000000000001", "value": "1000"}
+}`
+	// The test account is on Sepolia.
+	preview, err := acct.TypedMsgPreview(1, data)
+	require.NoError(t, err)
+	require.Equal(t, txpreview.KindPermit, preview.Kind)
+	require.Equal(t, []txpreview.Warning{
+		txpreview.WarningChainIDMismatch, txpreview.WarningUnknownRecipient}, preview.Warnings)
+}
+
+func TestSigningRequestsLog(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+
+	requests, err := acct.SigningRequests()
+	require.NoError(t, err)
+	require.Empty(t, requests)
+
+	preview := txpreview.DecodeTx(common.HexToAddress("0x01"), big.NewInt(1), nil)
+	first := &ethtypes.SigningRequest{
+		Time:    time.Unix(1700000000, 0).UTC(),
+		Origin:  "https://app.example.com",
+		Method:  ethtypes.SigningRequestMethodSendTransaction,
+		ChainID: 11155111,
+		Preview: preview,
+		Outcome: ethtypes.SigningRequestSent,
+		TxHash:  "0xabcd",
+	}
+	second := &ethtypes.SigningRequest{
+		Time:    time.Unix(1700000100, 0).UTC(),
+		Origin:  "https://app.example.com",
+		Method:  ethtypes.SigningRequestMethodSignMessage,
+		ChainID: 11155111,
+		Outcome: ethtypes.SigningRequestAborted,
+	}
+	require.NoError(t, acct.LogSigningRequest(first))
+	require.NoError(t, acct.LogSigningRequest(second))
+
+	requests, err = acct.SigningRequests()
+	require.NoError(t, err)
+	require.Equal(t, []*ethtypes.SigningRequest{second, first}, requests)
+}
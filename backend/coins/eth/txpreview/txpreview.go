@@ -0,0 +1,351 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package txpreview decodes transactions and EIP-712 typed data received from dApps (e.g. via
+// WalletConnect) into a human readable summary, and flags risky requests before they are signed.
+package txpreview
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Kind is the kind of a decoded request.
+type Kind string
+
+const (
+	// KindTransfer is a plain ETH transfer without calldata.
+	KindTransfer Kind = "transfer"
+	// KindERC20Transfer is a call to `transfer(address,uint256)`.
+	KindERC20Transfer Kind = "erc20Transfer"
+	// KindERC20Approve is a call to `approve(address,uint256)`.
+	KindERC20Approve Kind = "erc20Approve"
+	// KindSetApprovalForAll is a call to the ERC721/ERC1155 `setApprovalForAll(address,bool)`.
+	KindSetApprovalForAll Kind = "setApprovalForAll"
+	// KindContractCall is a call to a contract method which is not decoded.
+	KindContractCall Kind = "contractCall"
+	// KindPermit is an EIP-2612 (or DAI-style) permit typed message.
+	KindPermit Kind = "permit"
+	// KindPermit2 is a Uniswap Permit2 typed message.
+	KindPermit2 Kind = "permit2"
+	// KindTypedData is any other EIP-712 typed message.
+	KindTypedData Kind = "typedData"
+)
+
+// Warning is a risk flagged for a request.
+type Warning string
+
+const (
+	// WarningUnlimitedApproval is set if the request grants a practically unlimited allowance.
+	WarningUnlimitedApproval Warning = "unlimitedApproval"
+	// WarningApprovalForAll is set if the request grants an operator access to all NFTs of a
+	// collection.
+	WarningApprovalForAll Warning = "approvalForAll"
+	// WarningUnknownRecipient is set if the recipient or spender was never sent to before.
+	WarningUnknownRecipient Warning = "unknownRecipient"
+	// WarningChainIDMismatch is set if the chain ID of the request does not match the chain of the
+	// account.
+	WarningChainIDMismatch Warning = "chainIdMismatch"
+)
+
+// ErrUnconfirmedWarnings is returned if a request is to be signed without the user confirming
+// all of its warnings.
+const ErrUnconfirmedWarnings errp.ErrorCode = "unconfirmedWarnings"
+
+// Field is a decoded, named value of a request.
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Preview is the decoded summary of a request.
+type Preview struct {
+	Kind Kind `json:"kind"`
+	// Contract is the called contract, or the verifying contract of typed data. Empty if not
+	// applicable.
+	Contract string `json:"contract,omitempty"`
+	// Method is the called contract method or the primary type of typed data.
+	Method string  `json:"method,omitempty"`
+	Fields []Field `json:"fields"`
+	// Counterparty is the address receiving funds or permissions, if any. Used to check whether
+	// the address is known.
+	Counterparty string `json:"counterparty,omitempty"`
+	// ChainID is the chain ID in the typed data domain. 0 if not present or for transactions.
+	ChainID  uint64    `json:"chainId,omitempty"`
+	Warnings []Warning `json:"warnings"`
+}
+
+// AddWarning adds a warning, skipping duplicates.
+func (preview *Preview) AddWarning(warning Warning) {
+	for _, existing := range preview.Warnings {
+		if existing == warning {
+			return
+		}
+	}
+	preview.Warnings = append(preview.Warnings, warning)
+}
+
+// CheckConfirmed returns ErrUnconfirmedWarnings if any warning of the preview is not among the
+// confirmed warnings.
+func (preview *Preview) CheckConfirmed(confirmed []Warning) error {
+	for _, warning := range preview.Warnings {
+		found := false
+		for _, confirmedWarning := range confirmed {
+			if confirmedWarning == warning {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errp.WithStack(ErrUnconfirmedWarnings)
+		}
+	}
+	return nil
+}
+
+func (preview *Preview) addField(name, value string) {
+	preview.Fields = append(preview.Fields, Field{Name: name, Value: value})
+}
+
+const callsABI = `[
+{"inputs":[{"name":"recipient","type":"address"},{"name":"amount","type":"uint256"}],"name":"transfer","outputs":[],"type":"function"},
+{"inputs":[{"name":"spender","type":"address"},{"name":"amount","type":"uint256"}],"name":"approve","outputs":[],"type":"function"},
+{"inputs":[{"name":"operator","type":"address"},{"name":"approved","type":"bool"}],"name":"setApprovalForAll","outputs":[],"type":"function"}
+]`
+
+var calls = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(callsABI))
+	if err != nil {
+		panic(errp.WithStack(err))
+	}
+	return parsed
+}()
+
+// permit2UnlimitedThreshold mirrors the ERC20 threshold for Permit2 amounts, which are uint160.
+var permit2UnlimitedThreshold = new(big.Int).Lsh(big.NewInt(1), 159)
+
+// DecodeTx decodes a transaction calling `to` with the given value and calldata. Unknown calldata
+// is reported as KindContractCall with the method selector.
+func DecodeTx(to common.Address, value *big.Int, data []byte) *Preview {
+	preview := &Preview{Fields: []Field{}, Warnings: []Warning{}}
+	if value != nil && value.Sign() != 0 {
+		preview.addField("value", value.String())
+	}
+	if len(data) == 0 {
+		preview.Kind = KindTransfer
+		preview.Counterparty = to.Hex()
+		return preview
+	}
+	// The counterparty of arbitrary contract calls is unknown, so it is only set for decoded calls.
+	preview.Contract = to.Hex()
+	preview.Kind = KindContractCall
+	if len(data) < 4 {
+		preview.addField("data", hexutil.Encode(data))
+		return preview
+	}
+	method, err := calls.MethodById(data[:4])
+	if err != nil {
+		preview.Method = hexutil.Encode(data[:4])
+		return preview
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		// Matching selector with malformed arguments.
+		preview.Method = hexutil.Encode(data[:4])
+		return preview
+	}
+	preview.Method = method.Name
+	switch method.Name {
+	case "transfer":
+		recipient, amount := args[0].(common.Address), args[1].(*big.Int)
+		preview.Kind = KindERC20Transfer
+		preview.Counterparty = recipient.Hex()
+		preview.addField("recipient", recipient.Hex())
+		preview.addField("amount", amount.String())
+	case "approve":
+		spender, amount := args[0].(common.Address), args[1].(*big.Int)
+		preview.Kind = KindERC20Approve
+		preview.Counterparty = spender.Hex()
+		preview.addField("spender", spender.Hex())
+		preview.addField("amount", amount.String())
+		if erc20.IsUnlimitedAllowance(amount) {
+			preview.AddWarning(WarningUnlimitedApproval)
+		}
+	case "setApprovalForAll":
+		operator, approved := args[0].(common.Address), args[1].(bool)
+		preview.Kind = KindSetApprovalForAll
+		preview.Counterparty = operator.Hex()
+		preview.addField("operator", operator.Hex())
+		preview.addField("approved", strconv.FormatBool(approved))
+		if approved {
+			preview.AddWarning(WarningApprovalForAll)
+		}
+	}
+	return preview
+}
+
+type typedData struct {
+	PrimaryType string                 `json:"primaryType"`
+	Domain      map[string]interface{} `json:"domain"`
+	Message     map[string]interface{} `json:"message"`
+}
+
+// parseBigInt parses numbers which are encoded in JSON as a number, a decimal string or a hex
+// string.
+func parseBigInt(value interface{}) (*big.Int, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		return new(big.Int).SetString(v.String(), 10)
+	case string:
+		if strings.HasPrefix(v, "0x") || strings.HasPrefix(v, "0X") {
+			return new(big.Int).SetString(v[2:], 16)
+		}
+		return new(big.Int).SetString(v, 10)
+	}
+	return nil, false
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+func stringField(message map[string]interface{}, name string) string {
+	value, _ := message[name].(string)
+	return value
+}
+
+// DecodeTypedData decodes an EIP-712 typed message as passed to `eth_signTypedData_v4`.
+func DecodeTypedData(data []byte) (*Preview, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var parsed typedData
+	if err := decoder.Decode(&parsed); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	preview := &Preview{
+		Kind:     KindTypedData,
+		Method:   parsed.PrimaryType,
+		Fields:   []Field{},
+		Warnings: []Warning{},
+	}
+	if contract := stringField(parsed.Domain, "verifyingContract"); contract != "" {
+		preview.Contract = contract
+	}
+	if chainID, ok := parsed.Domain["chainId"]; ok {
+		parsedChainID, ok := parseBigInt(chainID)
+		if !ok || !parsedChainID.IsUint64() {
+			return nil, errp.New("invalid domain chain ID")
+		}
+		preview.ChainID = parsedChainID.Uint64()
+	}
+	domainName := stringField(parsed.Domain, "name")
+
+	switch {
+	case domainName == "Permit2":
+		preview.Kind = KindPermit2
+		decodePermit2(preview, parsed.Message)
+	case parsed.PrimaryType == "Permit":
+		preview.Kind = KindPermit
+		decodePermit(preview, parsed.Message)
+	default:
+		if domainName != "" {
+			preview.addField("domain", domainName)
+		}
+		// Message fields come from a map; sort them for a stable output.
+		names := make([]string, 0, len(parsed.Message))
+		for name := range parsed.Message {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			preview.addField(name, formatValue(parsed.Message[name]))
+		}
+	}
+	return preview, nil
+}
+
+// decodePermit decodes EIP-2612 permits `Permit(owner,spender,value,nonce,deadline)` and DAI-style
+// permits `Permit(holder,spender,nonce,expiry,allowed)`.
+func decodePermit(preview *Preview, message map[string]interface{}) {
+	spender := stringField(message, "spender")
+	preview.Counterparty = spender
+	preview.addField("spender", spender)
+	if value, ok := parseBigInt(message["value"]); ok {
+		preview.addField("amount", value.String())
+		if erc20.IsUnlimitedAllowance(value) {
+			preview.AddWarning(WarningUnlimitedApproval)
+		}
+	}
+	if allowed, ok := message["allowed"].(bool); ok {
+		preview.addField("allowed", strconv.FormatBool(allowed))
+		if allowed {
+			preview.AddWarning(WarningUnlimitedApproval)
+		}
+	}
+	for _, name := range []string{"deadline", "expiry"} {
+		if value, ok := message[name]; ok {
+			preview.addField(name, formatValue(value))
+		}
+	}
+}
+
+// decodePermit2 decodes the Permit2 `PermitSingle`, `PermitBatch`, `PermitTransferFrom` and
+// `PermitBatchTransferFrom` messages.
+func decodePermit2(preview *Preview, message map[string]interface{}) {
+	spender := stringField(message, "spender")
+	preview.Counterparty = spender
+	preview.addField("spender", spender)
+
+	// PermitSingle/PermitBatch have `details`, the transfer permits have `permitted`.
+	var entries []interface{}
+	for _, name := range []string{"details", "permitted"} {
+		switch v := message[name].(type) {
+		case map[string]interface{}:
+			entries = append(entries, v)
+		case []interface{}:
+			entries = append(entries, v...)
+		}
+	}
+	for _, entry := range entries {
+		details, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		preview.addField("token", stringField(details, "token"))
+		if amount, ok := parseBigInt(details["amount"]); ok {
+			preview.addField("amount", amount.String())
+			if amount.Cmp(permit2UnlimitedThreshold) >= 0 {
+				preview.AddWarning(WarningUnlimitedApproval)
+			}
+		}
+		if expiration, ok := details["expiration"]; ok {
+			preview.addField("expiration", formatValue(expiration))
+		}
+	}
+	for _, name := range []string{"sigDeadline", "deadline"} {
+		if value, ok := message[name]; ok {
+			preview.addField(name, formatValue(value))
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package txpreview
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	token   = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	spender = common.HexToAddress("0x0000000000000000000000000000000000000001")
+)
+
+func TestDecodeTx(t *testing.T) {
+	t.Run("transfer", func(t *testing.T) {
+		preview := DecodeTx(spender, big.NewInt(100), nil)
+		require.Equal(t, KindTransfer, preview.Kind)
+		require.Equal(t, spender.Hex(), preview.Counterparty)
+		require.Equal(t, []Field{{Name: "value", Value: "100"}}, preview.Fields)
+		require.Empty(t, preview.Warnings)
+	})
+
+	t.Run("erc20-transfer", func(t *testing.T) {
+		data, err := calls.Pack("transfer", spender, big.NewInt(5))
+		require.NoError(t, err)
+		preview := DecodeTx(token, big.NewInt(0), data)
+		require.Equal(t, KindERC20Transfer, preview.Kind)
+		require.Equal(t, token.Hex(), preview.Contract)
+		require.Equal(t, spender.Hex(), preview.Counterparty)
+		require.Equal(t, []Field{
+			{Name: "recipient", Value: spender.Hex()},
+			{Name: "amount", Value: "5"},
+		}, preview.Fields)
+	})
+
+	t.Run("erc20-approve-unlimited", func(t *testing.T) {
+		maxUint256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+		data, err := calls.Pack("approve", spender, maxUint256)
+		require.NoError(t, err)
+		preview := DecodeTx(token, nil, data)
+		require.Equal(t, KindERC20Approve, preview.Kind)
+		require.Equal(t, []Warning{WarningUnlimitedApproval}, preview.Warnings)
+
+		data, err = calls.Pack("approve", spender, big.NewInt(1000))
+		require.NoError(t, err)
+		require.Empty(t, DecodeTx(token, nil, data).Warnings)
+	})
+
+	t.Run("set-approval-for-all", func(t *testing.T) {
+		data, err := calls.Pack("setApprovalForAll", spender, true)
+		require.NoError(t, err)
+		preview := DecodeTx(token, nil, data)
+		require.Equal(t, KindSetApprovalForAll, preview.Kind)
+		require.Equal(t, spender.Hex(), preview.Counterparty)
+		require.Equal(t, []Warning{WarningApprovalForAll}, preview.Warnings)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		preview := DecodeTx(token, nil, hexutil.MustDecode("0x12345678aabb"))
+		require.Equal(t, KindContractCall, preview.Kind)
+		require.Equal(t, "0x12345678", preview.Method)
+		require.Equal(t, token.Hex(), preview.Contract)
+		require.Empty(t, preview.Counterparty)
+	})
+}
+
+func TestDecodeTypedData(t *testing.T) {
+	t.Run("permit", func(t *testing.T) {
+		preview, err := DecodeTypedData([]byte(`{
+  "primaryType": "Permit",
+  "domain": {"name": "USD Coin", "chainId": 1, "verifyingContract": "0x00000000000000000000000000000000000000aa"},
+  "message": {
+    "owner": "0x0000000000000000000000000000000000000002",
+    "spender": "0x0000000000000000000000000000000000000001",
+    "value": "115792089237316195423570985008687907853269984665640564039457584007913129639935",
+    "nonce": 0,
+    "deadline": 1700000000
+  }
+}`))
+		require.NoError(t, err)
+		require.Equal(t, KindPermit, preview.Kind)
+		require.Equal(t, uint64(1), preview.ChainID)
+		require.Equal(t, "0x00000000000000000000000000000000000000aa", preview.Contract)
+		require.Equal(t, spender.Hex(), preview.Counterparty)
+		require.Equal(t, []Warning{WarningUnlimitedApproval}, preview.Warnings)
+		require.Contains(t, preview.Fields, Field{Name: "deadline", Value: "1700000000"})
+	})
+
+	t.Run("permit2", func(t *testing.T) {
+		preview, err := DecodeTypedData([]byte(`{
+  "primaryType": "PermitSingle",
+  "domain": {"name": "Permit2", "chainId": "0x1", "verifyingContract": "0x000000000022D473030F116dDEE9F6B43aC78BA3"},
+  "message": {
+    "details": {
+      "token": "0x00000000000000000000000000000000000000aa",
+      "amount": "1461501637330902918203684832716283019655932542975",
+      "expiration": 1700000000,
+      "nonce": 0
+    },
+    "spender": "0x0000000000000000000000000000000000000001",
+    "sigDeadline": 1700000000
+  }
+}`))
+		require.NoError(t, err)
+		require.Equal(t, KindPermit2, preview.Kind)
+		require.Equal(t, uint64(1), preview.ChainID)
+		require.Equal(t, spender.Hex(), preview.Counterparty)
+		require.Equal(t, []Warning{WarningUnlimitedApproval}, preview.Warnings)
+		require.Contains(t, preview.Fields, Field{Name: "token", Value: "0x00000000000000000000000000000000000000aa"})
+	})
+
+	t.Run("other", func(t *testing.T) {
+		preview, err := DecodeTypedData([]byte(`{
+  "primaryType": "Mail",
+  "domain": {"name": "Ether Mail"},
+  "message": {"to": "Bob", "contents": "Hello"}
+}`))
+		require.NoError(t, err)
+		require.Equal(t, KindTypedData, preview.Kind)
+		require.Equal(t, "Mail", preview.Method)
+		require.Equal(t, uint64(0), preview.ChainID)
+		require.Equal(t, []Field{
+			{Name: "domain", Value: "Ether Mail"},
+			{Name: "contents", Value: "Hello"},
+			{Name: "to", Value: "Bob"},
+		}, preview.Fields)
+		require.Empty(t, preview.Warnings)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := DecodeTypedData([]byte(`{"domain": {"chainId": "abc"}}`))
+		require.Error(t, err)
+		_, err = DecodeTypedData([]byte(`not json`))
+		require.Error(t, err)
+	})
+}
+
+func TestCheckConfirmed(t *testing.T) {
+	preview := &Preview{}
+	require.NoError(t, preview.CheckConfirmed(nil))
+
+	preview.AddWarning(WarningUnlimitedApproval)
+	preview.AddWarning(WarningUnknownRecipient)
+	err := preview.CheckConfirmed(nil)
+	require.Equal(t, ErrUnconfirmedWarnings, errp.Cause(err))
+	err = preview.CheckConfirmed([]Warning{WarningUnknownRecipient})
+	require.Equal(t, ErrUnconfirmedWarnings, errp.Cause(err))
+	require.NoError(t, preview.CheckConfirmed([]Warning{WarningUnknownRecipient, WarningUnlimitedApproval}))
+}
@@ -0,0 +1,20 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"strings"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// MustParseABI parses the JSON ABI of a contract. It panics if the ABI is invalid, so it is only
+// meant for the constant ABIs of the app.
+func MustParseABI(abiJSON string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		panic(errp.WithStack(err))
+	}
+	return parsed
+}
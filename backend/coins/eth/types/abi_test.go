@@ -0,0 +1,17 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package types_test
+
+import (
+	"testing"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/erc20"
+	ethtypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMustParseABI(t *testing.T) {
+	parsed := ethtypes.MustParseABI(erc20.IERC20ABI)
+	require.Contains(t, parsed.Methods, "transfer")
+	require.Panics(t, func() { ethtypes.MustParseABI("invalid") })
+}
@@ -0,0 +1,26 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+// NFTStandard is the token standard of an NFT contract.
+type NFTStandard string
+
+const (
+	// NFTStandardERC721 is a non-fungible token as specified by ERC-721.
+	NFTStandardERC721 NFTStandard = "erc721"
+	// NFTStandardERC1155 is a token of an ERC-1155 multi token contract.
+	NFTStandardERC1155 NFTStandard = "erc1155"
+)
+
+// NFT is a token held by an account, as stored in the account DB.
+type NFT struct {
+	Contract string      `json:"contract"`
+	Standard NFTStandard `json:"standard"`
+	// TokenID is the decimal token ID.
+	TokenID string `json:"tokenId"`
+	// Balance is the number of tokens held. Always 1 for ERC-721 tokens.
+	Balance string `json:"balance"`
+	// MetadataURI is the `tokenURI` (ERC-721) or `uri` (ERC-1155) of the token with the ERC-1155
+	// `{id}` placeholder substituted. Empty if the contract does not provide it.
+	MetadataURI string `json:"metadataUri"`
+}
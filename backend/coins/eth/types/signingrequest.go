@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/txpreview"
+)
+
+// SigningRequestOutcome is the result of a dApp signing request.
+type SigningRequestOutcome string
+
+const (
+	// SigningRequestSigned means the message or transaction was signed.
+	SigningRequestSigned SigningRequestOutcome = "signed"
+	// SigningRequestSent means the transaction was signed and broadcast.
+	SigningRequestSent SigningRequestOutcome = "sent"
+	// SigningRequestAborted means the user rejected the request on the device.
+	SigningRequestAborted SigningRequestOutcome = "aborted"
+	// SigningRequestFailed means the request failed with an error.
+	SigningRequestFailed SigningRequestOutcome = "failed"
+	// SigningRequestRefused means the request was not passed to the device, as the user did not
+	// confirm all warnings of its preview.
+	SigningRequestRefused SigningRequestOutcome = "refused"
+)
+
+// SigningRequestMethod is the kind of a dApp signing request.
+type SigningRequestMethod string
+
+const (
+	// SigningRequestMethodSignMessage is a `personal_sign` or `eth_sign` request.
+	SigningRequestMethodSignMessage SigningRequestMethod = "signMessage"
+	// SigningRequestMethodSignTypedData is an `eth_signTypedData` request.
+	SigningRequestMethodSignTypedData SigningRequestMethod = "signTypedData"
+	// SigningRequestMethodSignTransaction is an `eth_signTransaction` request.
+	SigningRequestMethodSignTransaction SigningRequestMethod = "signTransaction"
+	// SigningRequestMethodSendTransaction is an `eth_sendTransaction` request.
+	SigningRequestMethodSendTransaction SigningRequestMethod = "sendTransaction"
+)
+
+// SigningRequest is an entry of the audit log of signing requests received from dApps.
+type SigningRequest struct {
+	Time time.Time `json:"time"`
+	// Origin is the URL of the requesting dApp as reported by the dApp. Empty if unknown.
+	Origin  string               `json:"origin"`
+	Method  SigningRequestMethod `json:"method"`
+	ChainID uint64               `json:"chainId"`
+	// Preview is the decoded request. nil for messages and for requests which could not be decoded.
+	Preview *txpreview.Preview    `json:"preview,omitempty"`
+	Outcome SigningRequestOutcome `json:"outcome"`
+	// TxHash is set for signed transactions.
+	TxHash string `json:"txHash,omitempty"`
+	// Error is set if the outcome is SigningRequestFailed or SigningRequestRefused.
+	Error string `json:"error,omitempty"`
+}
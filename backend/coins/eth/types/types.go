@@ -44,6 +44,12 @@ type FeeTarget struct {
 	GasTipCap *big.Int
 	// GasFeeCap is the maxFeePerGas (base fee + priority fee) as specified by EIP-1559, in Wei.
 	GasFeeCap *big.Int
+	// ExpectedInclusionBlocks is the expected number of blocks until a transaction paying this fee
+	// is included. 0 if unknown.
+	ExpectedInclusionBlocks int
+	// BaseFeeSpiking is true if the base fee rose sharply in the last blocks, in which case the
+	// expected inclusion time is less reliable.
+	BaseFeeSpiking bool
 }
 
 // Code returns the btc fee target.
@@ -102,13 +108,12 @@ func (txh *TransactionWithMetadata) UnmarshalJSON(input []byte) error {
 	return nil
 }
 
-// TransactionData returns the tx data to be shown to the user.
+// TransactionData returns the tx data to be shown to the user. For Ethereum accounts, txs with
+// calldata are contract calls (e.g. an ERC20 approval) and are shown with the contract as the
+// recipient and the tx value as the amount.
 func (txh *TransactionWithMetadata) TransactionData(
 	tipHeight uint64, erc20Token *erc20.Token, accountAddress string) *accounts.TransactionData {
 	data := txh.Transaction.Data()
-	if erc20Token == nil && len(data) > 0 {
-		panic("invalid config")
-	}
 
 	amount := coin.NewAmount(txh.Transaction.Value())
 	address := txh.Transaction.To().Hex()
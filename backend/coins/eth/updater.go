@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"math/big"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/etherscan"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/multicall"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/logging"
 	"github.com/ethereum/go-ethereum/common"
@@ -62,6 +64,10 @@ type Updater struct {
 
 	// updateAccounts is a function that updates all ETH accounts.
 	updateAccounts func() error
+
+	// rpcCallsSaved is the number of per-token ERC20 balance calls avoided by batching them with
+	// Multicall3, minus the Multicall3 calls made.
+	rpcCallsSaved atomic.Int64
 }
 
 // NewUpdater creates a new Updater instance.
@@ -87,6 +93,12 @@ func (u *Updater) Close() {
 	close(u.quit)
 }
 
+// RPCCallsSaved returns the number of RPC calls saved so far by fetching ERC20 balances in batches
+// using Multicall3 instead of one call per token. Failed batches count negatively.
+func (u *Updater) RPCCallsSaved() int64 {
+	return u.rpcCallsSaved.Load()
+}
+
 // EnqueueUpdateForAllAccounts enqueues an update for all ETH accounts.
 func (u *Updater) EnqueueUpdateForAllAccounts() {
 	select {
@@ -184,6 +196,8 @@ func (u *Updater) UpdateBalancesAndBlockNumber(ethAccounts []*Account, etherScan
 		return
 	}
 
+	erc20Balances := u.fetchERC20Balances(ethAccounts)
+
 	prefetchedTokenTxsByAccount := map[*Account][]*accounts.TransactionData{}
 	if fetcher, ok := etherScanClient.(TokenTransactionsFetcher); ok {
 		prefetchedTokenTxsByAccount = u.prefetchTokenTransactions(ethAccounts, fetcher, blockNumber)
@@ -201,6 +215,10 @@ func (u *Updater) UpdateBalancesAndBlockNumber(ethAccounts []*Account, etherScan
 		var balance *big.Int
 		switch {
 		case IsERC20(account):
+			if batchedBalance, ok := erc20Balances[account]; ok {
+				balance = batchedBalance
+				break
+			}
 			var err error
 			balance, err = account.coin.client.ERC20Balance(account.address.Address, account.coin.erc20Token)
 			if err != nil {
@@ -243,6 +261,49 @@ func (u *Updater) UpdateBalancesAndBlockNumber(ethAccounts []*Account, etherScan
 	}
 }
 
+// fetchERC20Balances fetches the balances of all ERC20 accounts using Multicall3. Balances which
+// could not be fetched this way are missing in the result, and must be fetched one by one.
+func (u *Updater) fetchERC20Balances(ethAccounts []*Account) map[*Account]*big.Int {
+	erc20Accounts := []*Account{}
+	requests := []multicall.BalanceRequest{}
+	for _, account := range ethAccounts {
+		if account.isClosed() || !IsERC20(account) {
+			continue
+		}
+		address, err := account.Address()
+		if err != nil {
+			continue
+		}
+		erc20Accounts = append(erc20Accounts, account)
+		requests = append(requests, multicall.BalanceRequest{
+			Token: account.coin.erc20Token.ContractAddress(),
+			Owner: address.Address,
+		})
+	}
+	// Batching a single balance does not save any calls.
+	if len(requests) < 2 {
+		return nil
+	}
+	// All accounts are on the same chain, so any of their clients can make the call.
+	balances, numCalls, err := multicall.ERC20Balances(
+		context.TODO(), erc20Accounts[0].coin.client, requests)
+	if err != nil {
+		u.log.WithError(err).Warning("Could not fetch ERC20 balances with Multicall3, fetching them one by one")
+		u.rpcCallsSaved.Add(-int64(numCalls))
+		return nil
+	}
+	result := make(map[*Account]*big.Int, len(balances))
+	for i, account := range erc20Accounts {
+		if balance, ok := balances[requests[i]]; ok {
+			result[account] = balance
+		}
+	}
+	saved := u.rpcCallsSaved.Add(int64(len(result) - numCalls))
+	u.log.Debugf("Fetched %d ERC20 balances with %d Multicall3 calls, RPC calls saved in total: %d",
+		len(result), numCalls, saved)
+	return result
+}
+
 func (u *Updater) prefetchTokenTransactions(
 	ethAccounts []*Account,
 	etherScanClient TokenTransactionsFetcher,
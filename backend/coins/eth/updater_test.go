@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/erc20"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/mocks"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/multicall"
 	rpcclientmocks "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth/rpcclient/mocks"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/signing"
@@ -24,6 +26,8 @@ import (
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/test"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/btcsuite/btcd/chaincfg"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/stretchr/testify/require"
@@ -94,6 +98,10 @@ func newAccount(t *testing.T, erc20Token *erc20.Token, erc20error bool) *eth.Acc
 			}
 			return big.NewInt(1e16), nil // Mock balance for ERC20 token
 		},
+		CallContractFunc: func(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+			// Multicall3 is not available, balances are fetched one by one.
+			return nil, errp.New("execution reverted")
+		},
 	}
 
 	coin := eth.NewCoin(client, coin.CodeSEPETH, "Sepolia", "SEPETH", "SEPETH", params.SepoliaChainConfig, "", nil, erc20Token)
@@ -244,6 +252,88 @@ func TestUpdateBalancesWithError(t *testing.T) {
 
 }
 
+func TestUpdateBalancesMulticall(t *testing.T) {
+	balanceFetcher := &mocks.BalanceAndBlockNumberFetcherMock{
+		BalancesFunc: func(ctx context.Context, addresses []common.Address) (map[common.Address]*big.Int, error) {
+			return map[common.Address]*big.Int{}, nil
+		},
+		BlockNumberFunc: func(ctx context.Context) (*big.Int, error) {
+			return big.NewInt(100), nil
+		},
+	}
+	tokens := []*erc20.Token{
+		erc20.NewToken("0x0000000000000000000000000000000000000001", 12),
+		erc20.NewToken("0x0000000000000000000000000000000000000002", 12),
+		erc20.NewToken("0x0000000000000000000000000000000000000003", 12),
+	}
+	erc20Accounts := make([]*eth.Account, len(tokens))
+	for i, token := range tokens {
+		erc20Accounts[i] = newAccount(t, token, false)
+		defer erc20Accounts[i].Close()
+	}
+
+	multicallABI, err := abi.JSON(strings.NewReader(multicall.ABI))
+	require.NoError(t, err)
+	aggregate3 := multicallABI.Methods["aggregate3"]
+	type call3 struct {
+		Target       common.Address
+		AllowFailure bool
+		CallData     []byte
+	}
+	type result struct {
+		Success    bool
+		ReturnData []byte
+	}
+	multicallClient := &rpcclientmocks.InterfaceMock{
+		CallContractFunc: func(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+			require.Equal(t, multicall.Address, *msg.To)
+			values, err := aggregate3.Inputs.Unpack(msg.Data[4:])
+			require.NoError(t, err)
+			calls := *abi.ConvertType(values[0], new([]call3)).(*[]call3)
+			require.Len(t, calls, 3)
+			results := make([]result, len(calls))
+			for i, call := range calls {
+				// The third token reverts and is fetched separately.
+				if call.Target == tokens[2].ContractAddress() {
+					continue
+				}
+				balance := big.NewInt(int64(i + 1))
+				results[i] = result{Success: true, ReturnData: common.LeftPadBytes(balance.Bytes(), 32)}
+			}
+			return aggregate3.Outputs.Pack(results)
+		},
+		ERC20BalanceFunc: func(address common.Address, token *erc20.Token) (*big.Int, error) {
+			return big.NewInt(1e16), nil
+		},
+	}
+	erc20Accounts[0].ETHCoin().TstSetClient(multicallClient)
+
+	updater := eth.NewUpdater(nil, nil, nil, nil)
+	updater.UpdateBalancesAndBlockNumber(erc20Accounts, balanceFetcher)
+	assertAccountBalance(t, erc20Accounts[0], big.NewInt(1))
+	assertAccountBalance(t, erc20Accounts[1], big.NewInt(2))
+	assertAccountBalance(t, erc20Accounts[2], big.NewInt(1e16))
+	// Two balances fetched with one call instead of two.
+	require.Len(t, multicallClient.CallContractCalls(), 1)
+	require.Len(t, multicallClient.ERC20BalanceCalls(), 0)
+	require.Equal(t, int64(1), updater.RPCCallsSaved())
+
+	// Fallback to per-token calls if Multicall3 fails.
+	erc20Accounts[0].ETHCoin().TstSetClient(&rpcclientmocks.InterfaceMock{
+		CallContractFunc: func(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+			return nil, errp.New("network error")
+		},
+		ERC20BalanceFunc: func(address common.Address, token *erc20.Token) (*big.Int, error) {
+			return big.NewInt(5), nil
+		},
+	})
+	updater.UpdateBalancesAndBlockNumber(erc20Accounts, balanceFetcher)
+	assertAccountBalance(t, erc20Accounts[0], big.NewInt(5))
+	assertAccountBalance(t, erc20Accounts[1], big.NewInt(1e16))
+	// The failed Multicall3 call was made in addition to the per-token calls.
+	require.Equal(t, int64(0), updater.RPCCallsSaved())
+}
+
 func makeConfirmedTx(id string) *accounts.TransactionData {
 	amount := coin.NewAmountFromInt64(1)
 	return &accounts.TransactionData{
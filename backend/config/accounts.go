@@ -106,6 +106,59 @@ type Keystore struct {
 type AccountsConfig struct {
 	Accounts  []*Account  `json:"accounts"`
 	Keystores []*Keystore `json:"keystores"`
+
+	// The following are stored here instead of in the app config, so that they are encrypted at
+	// rest together with the accounts, see `NewConfigWithKeyring()`.
+
+	// PriceAlerts are the user-defined alerts on the latest exchange rates.
+	PriceAlerts []PriceAlert `json:"priceAlerts,omitempty"`
+	// PriceAlertHistory contains the most recently fired price alerts, oldest first.
+	PriceAlertHistory []PriceAlertTrigger `json:"priceAlertHistory,omitempty"`
+
+	// AddressBook contains the saved payment recipients of all coins.
+	AddressBook []Contact `json:"addressBook,omitempty"`
+}
+
+// PriceAlert is a stored price alert. See `rates.PriceAlert` for the meaning of the fields.
+type PriceAlert struct {
+	ID              string     `json:"id"`
+	Coin            string     `json:"coin"`
+	Fiat            string     `json:"fiat"`
+	Type            string     `json:"type"`
+	Threshold       float64    `json:"threshold"`
+	WindowHours     int        `json:"windowHours,omitempty"`
+	CooldownMinutes int        `json:"cooldownMinutes,omitempty"`
+	LastTriggered   *time.Time `json:"lastTriggered,omitempty"`
+	Active          bool       `json:"active,omitempty"`
+}
+
+// PriceAlertTrigger is a stored entry of the price alert history. See `rates.PriceAlertTrigger`
+// for the meaning of the fields.
+type PriceAlertTrigger struct {
+	AlertID        string    `json:"alertID"`
+	Coin           string    `json:"coin"`
+	Fiat           string    `json:"fiat"`
+	Type           string    `json:"type"`
+	Threshold      float64   `json:"threshold"`
+	Price          float64   `json:"price"`
+	ReferencePrice float64   `json:"referencePrice,omitempty"`
+	Time           time.Time `json:"time"`
+}
+
+// Contact is a stored address book contact. See `addressbook.Contact` for the meaning of the
+// fields.
+type Contact struct {
+	ID                 string     `json:"id"`
+	CoinCode           coin.Code  `json:"coinCode"`
+	Name               string     `json:"name"`
+	Address            string     `json:"address,omitempty"`
+	Xpub               string     `json:"xpub,omitempty"`
+	DerivedAddresses   []string   `json:"derivedAddresses,omitempty"`
+	Notes              string     `json:"notes"`
+	Verified           bool       `json:"verified"`
+	VerificationMethod string     `json:"verificationMethod,omitempty"`
+	VerifiedAt         *time.Time `json:"verifiedAt,omitempty"`
+	Created            time.Time  `json:"created"`
 }
 
 // newDefaultAccountsConfig returns the default accounts config.
@@ -9,9 +9,11 @@ import (
 
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/rates"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/atrest"
 	utilconfig "github.com/BitBoxSwiss/bitbox-wallet-app/util/config"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/locker"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/socksproxy"
 )
 
 // ServerInfo holds information about the backend server(s).
@@ -48,16 +50,35 @@ const (
 // ethCoinConfig holds configurations for ethereum coins.
 type ethCoinConfig struct {
 	DeprecatedActiveERC20Tokens []string `json:"activeERC20Tokens"`
+	// NodeURL is the URL of a self-hosted Ethereum mainnet JSON-RPC node. If set, it is used
+	// instead of Etherscan to query the chain state, estimate fees and broadcast transactions.
+	// Etherscan is still used for the transaction history.
+	NodeURL string `json:"nodeURL"`
 }
 
 type proxyConfig struct {
 	UseProxy     bool   `json:"useProxy"`
 	ProxyAddress string `json:"proxyAddress"`
+	// Routes overrides per service class whether its connections are direct, through the proxy or
+	// disabled. Services without a route use the proxy if UseProxy is true.
+	Routes map[socksproxy.Service]socksproxy.Route `json:"routes,omitempty"`
+	// IsolateStreams makes Tor use a separate circuit for each service and account.
+	IsolateStreams bool `json:"isolateStreams"`
 }
 
 // Backend holds the backend specific configuration.
 type Backend struct {
 	Proxy proxyConfig `json:"proxy"`
+	// StrictPrivacy blocks all connections except to the blockchain backends, e.g. to the exchange
+	// rates providers, the update check and the market vendors.
+	StrictPrivacy bool `json:"strictPrivacy"`
+	// ConnectionLogURLs records the redacted URLs of the requests in the connection log, see
+	// `connlog.RedactURL()`. By default, only the hosts are recorded.
+	ConnectionLogURLs bool `json:"connectionLogURLs"`
+	// NFTMetadata allows fetching the metadata of NFTs from the hosts of their metadata URIs, which
+	// are chosen by the token contracts. The metadata is only fetched if the connections of
+	// `socksproxy.ServiceOther` are proxied as well.
+	NFTMetadata bool `json:"nftMetadata"`
 
 	DeprecatedBitcoinActive  bool `json:"bitcoinActive"`
 	DeprecatedLitecoinActive bool `json:"litecoinActive"`
@@ -99,6 +120,13 @@ type Backend struct {
 	// Gap limits optionally forces gap limits for receive/change addresses used in bitcoin accounts
 	GapLimitReceive int `json:"gapLimitReceive"`
 	GapLimitChange  int `json:"gapLimitChange"`
+
+	// RatesProviders optionally sets the exchange rates providers in order of preference, see
+	// `rates.Provider*` for the supported values. Empty means CoinGecko only, so that no other
+	// provider is contacted without the user opting in.
+	RatesProviders []string `json:"ratesProviders"`
+	// RatesStaticFile is the path of the JSON file used by the "static" rates provider.
+	RatesStaticFile string `json:"ratesStaticFile"`
 }
 
 // DeprecatedCoinActive returns the Active setting for a coin by code.  This call is should not be
@@ -244,17 +272,32 @@ type Config struct {
 	accountsConfigFilename string
 	accountsConfig         AccountsConfig
 	accountsConfigLock     locker.Locker
+	// accountsConfigKeyring encrypts the accounts config at rest. nil if it is stored in plaintext.
+	accountsConfigKeyring *atrest.Keyring
 }
 
 // NewConfig creates a new Config, stored in the given location. The filename must be writable, but
 // does not have to exist.
 func NewConfig(appConfigFilename string, accountsConfigFilename string) (*Config, error) {
+	return NewConfigWithKeyring(appConfigFilename, accountsConfigFilename, nil)
+}
+
+// NewConfigWithKeyring is like `NewConfig()`, but the accounts config is read and written with the
+// given keyring, which encrypts it if encryption at rest is enabled. While the keyring is locked,
+// the accounts config is empty and can't be modified. Call `ReloadAccountsConfig()` after
+// unlocking it.
+func NewConfigWithKeyring(
+	appConfigFilename string,
+	accountsConfigFilename string,
+	accountsConfigKeyring *atrest.Keyring,
+) (*Config, error) {
 	config := &Config{
 		appConfigFilename: appConfigFilename,
 		appConfig:         NewDefaultAppConfig(),
 
 		accountsConfigFilename: accountsConfigFilename,
 		accountsConfig:         newDefaultAccountsConfig(),
+		accountsConfigKeyring:  accountsConfigKeyring,
 	}
 	config.load()
 	appconf := config.appConfig
@@ -265,12 +308,36 @@ func NewConfig(appConfigFilename string, accountsConfigFilename string) (*Config
 	if err := config.SetAppConfig(appconf); err != nil {
 		return nil, errp.WithStack(err)
 	}
+	if accountsConfigKeyring.Locked() {
+		return config, nil
+	}
 	if err := config.ModifyAccountsConfig(migrateActiveTokens); err != nil {
 		return nil, errp.WithStack(err)
 	}
 	return config, nil
 }
 
+// ReloadAccountsConfig loads the accounts config again, e.g. after unlocking its keyring.
+func (config *Config) ReloadAccountsConfig() error {
+	defer config.accountsConfigLock.Lock()()
+	accountsConfig := newDefaultAccountsConfig()
+	jsonBytes, err := config.accountsConfigKeyring.ReadFile(config.accountsConfigFilename)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(jsonBytes, &accountsConfig); err != nil {
+			return errp.WithStack(err)
+		}
+	}
+	if err := migrateActiveTokens(&accountsConfig); err != nil {
+		return err
+	}
+	config.accountsConfig = accountsConfig
+	return config.save(config.accountsConfigFilename, config.accountsConfig, config.accountsConfigKeyring)
+}
+
 // SetBTCElectrumServers sets the BTC configuration to the provided electrumIP and electrumCert.
 func (config *Config) SetBTCElectrumServers(electrumAddress, electrumCert string) {
 	config.appConfig.Backend.BTC = btcCoinConfig{
@@ -305,7 +372,7 @@ func (config *Config) load() {
 	if err := json.Unmarshal(jsonBytes, &config.appConfig); err != nil {
 		return
 	}
-	jsonBytes, err = os.ReadFile(config.accountsConfigFilename)
+	jsonBytes, err = config.accountsConfigKeyring.ReadFile(config.accountsConfigFilename)
 	if err != nil {
 		return
 	}
@@ -324,7 +391,7 @@ func (config *Config) AppConfig() AppConfig {
 func (config *Config) SetAppConfig(appConfig AppConfig) error {
 	defer config.appConfigLock.Lock()()
 	config.appConfig = appConfig
-	return config.save(config.appConfigFilename, config.appConfig)
+	return config.save(config.appConfigFilename, config.appConfig, nil)
 }
 
 // ModifyAppConfig calls f with the current config, allowing f to make any changes, and
@@ -334,7 +401,7 @@ func (config *Config) ModifyAppConfig(f func(*AppConfig) error) error {
 	if err := f(&config.appConfig); err != nil {
 		return err
 	}
-	return config.save(config.appConfigFilename, config.appConfig)
+	return config.save(config.appConfigFilename, config.appConfig, nil)
 }
 
 // AccountsConfig returns the accounts config.
@@ -347,19 +414,23 @@ func (config *Config) AccountsConfig() AccountsConfig {
 // persists the result if f returns nil error.  It propagates the f's error as is.
 func (config *Config) ModifyAccountsConfig(f func(*AccountsConfig) error) error {
 	defer config.accountsConfigLock.Lock()()
+	if config.accountsConfigKeyring.Locked() {
+		// Saving now would overwrite the encrypted accounts config with the empty one.
+		return errp.WithStack(atrest.ErrLocked)
+	}
 	if err := f(&config.accountsConfig); err != nil {
 		return err
 	}
-	return config.save(config.accountsConfigFilename, config.accountsConfig)
+	return config.save(config.accountsConfigFilename, config.accountsConfig, config.accountsConfigKeyring)
 }
 
-func (config *Config) save(filename string, conf interface{}) error {
+func (config *Config) save(filename string, conf interface{}, keyring *atrest.Keyring) error {
 	jsonBytes, err := json.MarshalIndent(conf, "", "    ")
 	if err != nil {
 		return errp.WithStack(err)
 	}
-	if err := os.WriteFile(filename, jsonBytes, 0600); err != nil {
-		return errp.WithStack(err)
+	if err := keyring.WriteFile(filename, jsonBytes, 0600); err != nil {
+		return err
 	}
 	return utilconfig.EnsurePrivateFile(filename)
 }
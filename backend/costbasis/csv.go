@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package costbasis
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+// WriteCSV writes the report as CSV. There is one "disposal" row per realized gain, followed by one
+// "holding" row per account with the unrealized gain at the time of the report, in which case the
+// proceeds column contains the market value.
+func (report *Report) WriteCSV(writer io.Writer) error {
+	csvWriter := csv.NewWriter(writer)
+	formatFiat := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', 2, 64)
+	}
+	err := csvWriter.Write([]string{
+		"Type",
+		"Time",
+		"Tax Year",
+		"Account",
+		"Coin",
+		"Transaction ID",
+		"Kind",
+		"Amount",
+		"Acquired",
+		"Fiat",
+		"Proceeds",
+		"Cost Basis",
+		"Gain",
+	})
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	for _, disposal := range report.Disposals {
+		acquired := ""
+		if disposal.AcquiredAt != nil {
+			acquired = disposal.AcquiredAt.Format(time.RFC3339)
+		}
+		err := csvWriter.Write([]string{
+			"disposal",
+			disposal.Time.Format(time.RFC3339),
+			strconv.Itoa(disposal.TaxYear),
+			disposal.AccountCode,
+			disposal.Unit,
+			disposal.TxID,
+			string(disposal.Kind),
+			disposal.Amount,
+			acquired,
+			report.Fiat,
+			formatFiat(disposal.Proceeds),
+			formatFiat(disposal.CostBasis),
+			formatFiat(disposal.Gain),
+		})
+		if err != nil {
+			return errp.WithStack(err)
+		}
+	}
+	for _, account := range report.Accounts {
+		err := csvWriter.Write([]string{
+			"holding",
+			report.At.Format(time.RFC3339),
+			"",
+			account.Code,
+			account.Unit,
+			"",
+			"",
+			account.Holdings,
+			"",
+			report.Fiat,
+			formatFiat(account.MarketValue),
+			formatFiat(account.CostBasis),
+			formatFiat(account.UnrealizedGain),
+		})
+		if err != nil {
+			return errp.WithStack(err)
+		}
+	}
+	csvWriter.Flush()
+	return errp.WithStack(csvWriter.Error())
+}
@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package costbasis computes the cost basis and the realized and unrealized gains of coins
+// from the transaction history of accounts.
+package costbasis
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+// Method is the lot selection method, which determines which acquired coins are disposed of first.
+type Method string
+
+const (
+	// MethodFIFO disposes of the earliest acquired coins first.
+	MethodFIFO Method = "fifo"
+	// MethodLIFO disposes of the latest acquired coins first.
+	MethodLIFO Method = "lifo"
+	// MethodHIFO disposes of the coins with the highest cost per unit first.
+	MethodHIFO Method = "hifo"
+	// MethodAverage pools all coins of an account, using the average cost per unit.
+	MethodAverage Method = "average"
+)
+
+// Validate returns an error if the method is not supported.
+func (method Method) Validate() error {
+	switch method {
+	case MethodFIFO, MethodLIFO, MethodHIFO, MethodAverage:
+		return nil
+	default:
+		return errp.Newf("unsupported lot method %q", method)
+	}
+}
+
+// lot is an amount of coins acquired at the same time and cost.
+type lot struct {
+	acquired time.Time
+	// amount in the smallest unit of the coin.
+	amount *big.Int
+	// costBasis is the fiat cost of the whole amount.
+	costBasis *big.Rat
+}
+
+// take removes the given amount, which must not exceed the amount of the lot, and returns the
+// removed part with the proportional cost basis.
+func (l *lot) take(amount *big.Int) *lot {
+	costBasis := new(big.Rat).Mul(l.costBasis, new(big.Rat).SetFrac(amount, l.amount))
+	l.amount = new(big.Int).Sub(l.amount, amount)
+	l.costBasis = new(big.Rat).Sub(l.costBasis, costBasis)
+	return &lot{acquired: l.acquired, amount: new(big.Int).Set(amount), costBasis: costBasis}
+}
+
+// ledger keeps the lots held by each account.
+type ledger struct {
+	method Method
+	// lots are keyed by account code, in acquisition order.
+	lots map[string][]*lot
+}
+
+func newLedger(method Method) *ledger {
+	return &ledger{method: method, lots: map[string][]*lot{}}
+}
+
+// add adds a lot to the account. With MethodAverage, all lots of an account are merged into one,
+// keeping the earliest acquisition time.
+func (l *ledger) add(account string, newLot *lot) {
+	if newLot.amount.Sign() <= 0 {
+		return
+	}
+	lots := l.lots[account]
+	if l.method == MethodAverage && len(lots) == 1 {
+		pool := lots[0]
+		pool.amount = new(big.Int).Add(pool.amount, newLot.amount)
+		pool.costBasis = new(big.Rat).Add(pool.costBasis, newLot.costBasis)
+		if newLot.acquired.Before(pool.acquired) {
+			pool.acquired = newLot.acquired
+		}
+		return
+	}
+	l.lots[account] = append(lots, newLot)
+}
+
+// remove removes the given amount from the lots of the account in the order of the lot method. It
+// returns the removed lots, and the part of the amount which was not covered by the lots of the
+// account, e.g. because transactions are missing.
+func (l *ledger) remove(account string, amount *big.Int) ([]*lot, *big.Int) {
+	lots := l.lots[account]
+	order := make([]*lot, len(lots))
+	copy(order, lots)
+	switch l.method {
+	case MethodLIFO:
+		sort.SliceStable(order, func(i, j int) bool { return order[i].acquired.After(order[j].acquired) })
+	case MethodHIFO:
+		sort.SliceStable(order, func(i, j int) bool {
+			costI := new(big.Rat).Quo(order[i].costBasis, new(big.Rat).SetInt(order[i].amount))
+			costJ := new(big.Rat).Quo(order[j].costBasis, new(big.Rat).SetInt(order[j].amount))
+			return costI.Cmp(costJ) > 0
+		})
+	default:
+		sort.SliceStable(order, func(i, j int) bool { return order[i].acquired.Before(order[j].acquired) })
+	}
+
+	remaining := new(big.Int).Set(amount)
+	removed := []*lot{}
+	for _, lot := range order {
+		if remaining.Sign() == 0 {
+			break
+		}
+		take := lot.amount
+		if take.Cmp(remaining) > 0 {
+			take = remaining
+		}
+		removed = append(removed, lot.take(take))
+		remaining.Sub(remaining, removed[len(removed)-1].amount)
+	}
+
+	kept := lots[:0]
+	for _, lot := range lots {
+		if lot.amount.Sign() > 0 {
+			kept = append(kept, lot)
+		}
+	}
+	l.lots[account] = kept
+	return removed, remaining
+}
+
+// holdings returns the amount and cost basis held by the account.
+func (l *ledger) holdings(account string) (*big.Int, *big.Rat) {
+	amount := new(big.Int)
+	costBasis := new(big.Rat)
+	for _, lot := range l.lots[account] {
+		amount.Add(amount, lot.amount)
+		costBasis.Add(costBasis, lot.costBasis)
+	}
+	return amount, costBasis
+}
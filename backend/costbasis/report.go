@@ -0,0 +1,400 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package costbasis
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+// Account contains the data of an account needed to compute the report.
+type Account struct {
+	Code     string
+	Name     string
+	CoinCode string
+	// Unit is the unit of the coin in which prices are quoted, e.g. "BTC".
+	Unit string
+	// Decimals is the number of smallest units per coin unit, e.g. 1e8 for Bitcoin.
+	Decimals     *big.Int
+	Transactions accounts.OrderedTransactions
+}
+
+// PriceFunc returns the price of one coin unit in the fiat currency of the report at the given
+// time, or 0 if it is not available.
+type PriceFunc func(coinCode, unit string, at time.Time) float64
+
+// DisposalKind describes why coins were disposed of.
+type DisposalKind string
+
+const (
+	// DisposalKindSend means coins were sent to someone else.
+	DisposalKindSend DisposalKind = "send"
+	// DisposalKindFee means coins were spent on a transaction fee.
+	DisposalKindFee DisposalKind = "fee"
+)
+
+// Disposal is a realized gain or loss.
+type Disposal struct {
+	Time        time.Time    `json:"time"`
+	TaxYear     int          `json:"taxYear"`
+	AccountCode string       `json:"accountCode"`
+	CoinCode    string       `json:"coinCode"`
+	Unit        string       `json:"unit"`
+	TxID        string       `json:"txID"`
+	Kind        DisposalKind `json:"kind"`
+	// Amount is the disposed amount in the coin unit.
+	Amount string `json:"amount"`
+	// AcquiredAt is the acquisition time of the earliest disposed coins. Nil if none of the coins
+	// are known to have been acquired, see Report.Incomplete.
+	AcquiredAt *time.Time `json:"acquiredAt"`
+	Proceeds   float64    `json:"proceeds"`
+	CostBasis  float64    `json:"costBasis"`
+	Gain       float64    `json:"gain"`
+
+	proceeds  *big.Rat
+	costBasis *big.Rat
+}
+
+// YearGains are the realized gains of one tax year.
+type YearGains struct {
+	Year      int     `json:"year"`
+	Proceeds  float64 `json:"proceeds"`
+	CostBasis float64 `json:"costBasis"`
+	Gain      float64 `json:"gain"`
+}
+
+// AccountReport contains the holdings and gains of one account.
+type AccountReport struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	CoinCode string `json:"coinCode"`
+	Unit     string `json:"unit"`
+	// Holdings is the amount held at the time of the report, in the coin unit.
+	Holdings       string  `json:"holdings"`
+	CostBasis      float64 `json:"costBasis"`
+	MarketValue    float64 `json:"marketValue"`
+	UnrealizedGain float64 `json:"unrealizedGain"`
+	// PriceMissing is true if there are holdings but no price was available at the time of the
+	// report, in which case the market value is zero.
+	PriceMissing   bool        `json:"priceMissing"`
+	RealizedByYear []YearGains `json:"realizedByYear"`
+
+	costBasis   *big.Rat
+	marketValue *big.Rat
+}
+
+// Report contains the cost basis, the realized gains per tax year and the unrealized gains at a
+// point in time of one or more accounts.
+type Report struct {
+	Method Method    `json:"method"`
+	Fiat   string    `json:"fiat"`
+	At     time.Time `json:"at"`
+
+	Accounts  []*AccountReport `json:"accounts"`
+	Disposals []*Disposal      `json:"disposals"`
+
+	// The following fields are the totals of all accounts.
+	RealizedByYear []YearGains `json:"realizedByYear"`
+	CostBasis      float64     `json:"costBasis"`
+	MarketValue    float64     `json:"marketValue"`
+	UnrealizedGain float64     `json:"unrealizedGain"`
+
+	// MissingPrices contains the IDs of the transactions for which no price was available. Their
+	// fiat value is counted as zero.
+	MissingPrices []string `json:"missingPrices"`
+	// Incomplete is true if more coins were sent than received before, e.g. because the account
+	// history is incomplete. The cost basis of the missing coins is counted as zero.
+	Incomplete bool `json:"incomplete"`
+}
+
+func ratFloat(value *big.Rat) float64 {
+	f, _ := value.Float64()
+	return f
+}
+
+func formatAmount(amount *big.Int, decimals *big.Int) string {
+	return new(big.Rat).SetFrac(amount, decimals).FloatString(len(decimals.String()) - 1)
+}
+
+// event is a transaction of an account.
+type event struct {
+	account *Account
+	tx      *accounts.TransactionData
+}
+
+// group contains the events of one transaction, which can involve multiple accounts of the same
+// coin in case of transfers between them.
+type group struct {
+	time   time.Time
+	events []event
+}
+
+// groupEvents groups the confirmed transactions up to `at` by coin and transaction ID, in
+// chronological order.
+func groupEvents(accountsList []*Account, at time.Time) []*group {
+	groups := map[[2]string]*group{}
+	for _, account := range accountsList {
+		for _, tx := range account.Transactions {
+			if tx.Height <= 0 || tx.Timestamp == nil || tx.Timestamp.After(at) {
+				continue
+			}
+			key := [2]string{account.CoinCode, tx.TxID}
+			g, ok := groups[key]
+			if !ok {
+				g = &group{time: *tx.Timestamp}
+				groups[key] = g
+			}
+			if tx.Timestamp.Before(g.time) {
+				g.time = *tx.Timestamp
+			}
+			g.events = append(g.events, event{account: account, tx: tx})
+		}
+	}
+	result := make([]*group, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, g)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].time.Equal(result[j].time) {
+			return result[i].time.Before(result[j].time)
+		}
+		return result[i].events[0].tx.TxID < result[j].events[0].tx.TxID
+	})
+	return result
+}
+
+type transferAmount struct {
+	account *Account
+	amount  *big.Int
+}
+
+// NewReport computes the report of the given accounts at the given time, using the given lot
+// method and prices in one fiat currency.
+//
+// Coins received are acquired at their market value, and coins sent to someone else are disposed
+// of at their market value. A transaction between two of the given accounts (same coin and
+// transaction ID) is a transfer: the coins keep their acquisition time and cost basis. A transaction
+// sending coins to the same account only moves coins within the account. Fees paid in the coin of
+// the account are disposed of at their market value, as the coins were spent on the fee. Fees paid
+// in another coin, e.g. ETH for ERC20 token transfers, are accounted for in the account of that
+// coin.
+func NewReport(
+	accountsList []*Account, method Method, fiat string, at time.Time, price PriceFunc,
+) (*Report, error) {
+	if err := method.Validate(); err != nil {
+		return nil, err
+	}
+	report := &Report{
+		Method:        method,
+		Fiat:          fiat,
+		At:            at,
+		Accounts:      []*AccountReport{},
+		Disposals:     []*Disposal{},
+		MissingPrices: []string{},
+	}
+	ledger := newLedger(method)
+	missingPrices := map[string]struct{}{}
+
+	for _, g := range groupEvents(accountsList, at) {
+		first := g.events[0]
+		coinCode := first.account.CoinCode
+		txID := first.tx.TxID
+		unitPrice := price(coinCode, first.account.Unit, g.time)
+		if unitPrice == 0 {
+			if _, ok := missingPrices[txID]; !ok {
+				missingPrices[txID] = struct{}{}
+				report.MissingPrices = append(report.MissingPrices, txID)
+			}
+		}
+		value := func(account *Account, amount *big.Int) *big.Rat {
+			return new(big.Rat).Mul(
+				new(big.Rat).SetFrac(amount, account.Decimals),
+				new(big.Rat).SetFloat64(unitPrice))
+		}
+		dispose := func(account *Account, amount *big.Int, kind DisposalKind) {
+			if amount.Sign() <= 0 {
+				return
+			}
+			lots, missing := ledger.remove(account.Code, amount)
+			if missing.Sign() > 0 {
+				report.Incomplete = true
+			}
+			disposal := &Disposal{
+				Time:        g.time,
+				TaxYear:     g.time.In(time.Local).Year(),
+				AccountCode: account.Code,
+				CoinCode:    account.CoinCode,
+				Unit:        account.Unit,
+				TxID:        txID,
+				Kind:        kind,
+				Amount:      formatAmount(amount, account.Decimals),
+				proceeds:    value(account, amount),
+				costBasis:   new(big.Rat),
+			}
+			for _, removed := range lots {
+				disposal.costBasis.Add(disposal.costBasis, removed.costBasis)
+				if disposal.AcquiredAt == nil || removed.acquired.Before(*disposal.AcquiredAt) {
+					acquired := removed.acquired
+					disposal.AcquiredAt = &acquired
+				}
+			}
+			report.Disposals = append(report.Disposals, disposal)
+		}
+
+		var outgoing, incoming []*transferAmount
+		for _, e := range g.events {
+			tx := e.tx
+			if tx.Status == accounts.TxStatusFailed && tx.Type != accounts.TxTypeSend &&
+				tx.Type != accounts.TxTypeSendSelf {
+				continue
+			}
+			switch tx.Type {
+			case accounts.TxTypeReceive:
+				incoming = append(incoming, &transferAmount{e.account, new(big.Int).Set(tx.Amount.BigInt())})
+			case accounts.TxTypeSend:
+				if tx.Status != accounts.TxStatusFailed {
+					outgoing = append(outgoing, &transferAmount{e.account, new(big.Int).Set(tx.Amount.BigInt())})
+				}
+			}
+			// Like in accounts.NewOrderedTransactions, the fee is paid even if the tx failed.
+			if (tx.Type == accounts.TxTypeSend || tx.Type == accounts.TxTypeSendSelf) &&
+				tx.Fee != nil && !tx.FeeIsDifferentUnit {
+				dispose(e.account, tx.Fee.BigInt(), DisposalKindFee)
+			}
+		}
+
+		// Match the coins received by one account with the coins sent by another.
+		for _, in := range incoming {
+			for _, out := range outgoing {
+				if in.amount.Sign() == 0 {
+					break
+				}
+				if out.account == in.account || out.amount.Sign() == 0 {
+					continue
+				}
+				amount := new(big.Int).Set(in.amount)
+				if amount.Cmp(out.amount) > 0 {
+					amount.Set(out.amount)
+				}
+				lots, missing := ledger.remove(out.account.Code, amount)
+				for _, transferred := range lots {
+					ledger.add(in.account.Code, transferred)
+				}
+				if missing.Sign() > 0 {
+					report.Incomplete = true
+					ledger.add(in.account.Code, &lot{
+						acquired: g.time, amount: missing, costBasis: value(in.account, missing),
+					})
+				}
+				in.amount.Sub(in.amount, amount)
+				out.amount.Sub(out.amount, amount)
+			}
+			ledger.add(in.account.Code, &lot{
+				acquired:  g.time,
+				amount:    new(big.Int).Set(in.amount),
+				costBasis: value(in.account, in.amount),
+			})
+		}
+		for _, out := range outgoing {
+			dispose(out.account, out.amount, DisposalKindSend)
+		}
+	}
+
+	for _, account := range accountsList {
+		amount, costBasis := ledger.holdings(account.Code)
+		unitPrice := price(account.CoinCode, account.Unit, at)
+		marketValue := new(big.Rat).Mul(
+			new(big.Rat).SetFrac(amount, account.Decimals), new(big.Rat).SetFloat64(unitPrice))
+		report.Accounts = append(report.Accounts, &AccountReport{
+			Code:         account.Code,
+			Name:         account.Name,
+			CoinCode:     account.CoinCode,
+			Unit:         account.Unit,
+			Holdings:     formatAmount(amount, account.Decimals),
+			PriceMissing: unitPrice == 0 && amount.Sign() > 0,
+			costBasis:    costBasis,
+			marketValue:  marketValue,
+		})
+	}
+	report.computeTotals()
+	return report, nil
+}
+
+// realizedByYear sums up the disposals by tax year, in ascending order.
+func realizedByYear(disposals []*Disposal) []YearGains {
+	proceeds := map[int]*big.Rat{}
+	costBasis := map[int]*big.Rat{}
+	years := []int{}
+	for _, disposal := range disposals {
+		if _, ok := proceeds[disposal.TaxYear]; !ok {
+			proceeds[disposal.TaxYear] = new(big.Rat)
+			costBasis[disposal.TaxYear] = new(big.Rat)
+			years = append(years, disposal.TaxYear)
+		}
+		proceeds[disposal.TaxYear].Add(proceeds[disposal.TaxYear], disposal.proceeds)
+		costBasis[disposal.TaxYear].Add(costBasis[disposal.TaxYear], disposal.costBasis)
+	}
+	sort.Ints(years)
+	result := make([]YearGains, len(years))
+	for i, year := range years {
+		result[i] = YearGains{
+			Year:      year,
+			Proceeds:  ratFloat(proceeds[year]),
+			CostBasis: ratFloat(costBasis[year]),
+			Gain:      ratFloat(new(big.Rat).Sub(proceeds[year], costBasis[year])),
+		}
+	}
+	return result
+}
+
+// computeTotals fills the exported fiat values of the disposals and accounts and the totals.
+func (report *Report) computeTotals() {
+	disposalsByAccount := map[string][]*Disposal{}
+	for _, disposal := range report.Disposals {
+		disposal.Proceeds = ratFloat(disposal.proceeds)
+		disposal.CostBasis = ratFloat(disposal.costBasis)
+		disposal.Gain = ratFloat(new(big.Rat).Sub(disposal.proceeds, disposal.costBasis))
+		disposalsByAccount[disposal.AccountCode] = append(disposalsByAccount[disposal.AccountCode], disposal)
+	}
+	costBasis := new(big.Rat)
+	marketValue := new(big.Rat)
+	for _, account := range report.Accounts {
+		account.CostBasis = ratFloat(account.costBasis)
+		account.MarketValue = ratFloat(account.marketValue)
+		account.UnrealizedGain = ratFloat(new(big.Rat).Sub(account.marketValue, account.costBasis))
+		account.RealizedByYear = realizedByYear(disposalsByAccount[account.Code])
+		costBasis.Add(costBasis, account.costBasis)
+		marketValue.Add(marketValue, account.marketValue)
+	}
+	report.RealizedByYear = realizedByYear(report.Disposals)
+	report.CostBasis = ratFloat(costBasis)
+	report.MarketValue = ratFloat(marketValue)
+	report.UnrealizedGain = ratFloat(new(big.Rat).Sub(marketValue, costBasis))
+}
+
+// ForAccount returns the report restricted to the given account. Transfers from or to other accounts
+// keep the cost basis computed for the whole portfolio.
+func (report *Report) ForAccount(accountCode string) (*Report, error) {
+	result := *report
+	result.Accounts = []*AccountReport{}
+	for _, account := range report.Accounts {
+		if account.Code == accountCode {
+			result.Accounts = append(result.Accounts, account)
+		}
+	}
+	if len(result.Accounts) == 0 {
+		return nil, errp.Newf("account %s not found", accountCode)
+	}
+	result.Disposals = []*Disposal{}
+	for _, disposal := range report.Disposals {
+		if disposal.AccountCode == accountCode {
+			result.Disposals = append(result.Disposals, disposal)
+		}
+	}
+	result.computeTotals()
+	return &result, nil
+}
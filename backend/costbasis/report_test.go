@@ -0,0 +1,273 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package costbasis
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/stretchr/testify/require"
+)
+
+const btc = 100_000_000
+
+var (
+	time1 = time.Date(2022, 6, 1, 12, 0, 0, 0, time.Local)
+	time2 = time.Date(2023, 6, 1, 12, 0, 0, 0, time.Local)
+	time3 = time.Date(2024, 6, 1, 12, 0, 0, 0, time.Local)
+	time4 = time.Date(2025, 6, 1, 12, 0, 0, 0, time.Local)
+)
+
+func newTx(
+	txID string, txType accounts.TxType, amount int64, fee int64, timestamp time.Time,
+) *accounts.TransactionData {
+	tx := &accounts.TransactionData{
+		TxID:      txID,
+		Type:      txType,
+		Status:    accounts.TxStatusComplete,
+		Height:    1,
+		Timestamp: &timestamp,
+		Amount:    coin.NewAmountFromInt64(amount),
+	}
+	if fee != 0 {
+		feeAmount := coin.NewAmountFromInt64(fee)
+		tx.Fee = &feeAmount
+	}
+	return tx
+}
+
+func newAccount(code string, txs ...*accounts.TransactionData) *Account {
+	return &Account{
+		Code:         code,
+		Name:         "Account " + code,
+		CoinCode:     "btc",
+		Unit:         "BTC",
+		Decimals:     big.NewInt(btc),
+		Transactions: txs,
+	}
+}
+
+func priceFunc(prices map[time.Time]float64) PriceFunc {
+	return func(coinCode, unit string, at time.Time) float64 {
+		if coinCode != "btc" || unit != "BTC" {
+			return 0
+		}
+		return prices[at]
+	}
+}
+
+var testPrices = priceFunc(map[time.Time]float64{
+	time1: 100,
+	time2: 300,
+	time3: 200,
+	time4: 400,
+})
+
+func TestMethodValidate(t *testing.T) {
+	require.NoError(t, MethodFIFO.Validate())
+	require.NoError(t, MethodAverage.Validate())
+	require.Error(t, Method("fofi").Validate())
+
+	_, err := NewReport(nil, Method(""), "USD", time4, testPrices)
+	require.Error(t, err)
+}
+
+func TestNewReportMethods(t *testing.T) {
+	account := newAccount("a",
+		newTx("tx3", accounts.TxTypeSend, btc, 0, time3),
+		newTx("tx2", accounts.TxTypeReceive, btc, 0, time2),
+		newTx("tx1", accounts.TxTypeReceive, btc, 0, time1),
+	)
+
+	tests := []struct {
+		method         Method
+		costBasis      float64
+		acquiredAt     time.Time
+		unrealizedGain float64
+	}{
+		{MethodFIFO, 100, time1, 100},
+		{MethodLIFO, 300, time2, 300},
+		{MethodHIFO, 300, time2, 300},
+		{MethodAverage, 200, time1, 200},
+	}
+	for _, test := range tests {
+		t.Run(string(test.method), func(t *testing.T) {
+			report, err := NewReport([]*Account{account}, test.method, "USD", time4, testPrices)
+			require.NoError(t, err)
+			require.False(t, report.Incomplete)
+			require.Empty(t, report.MissingPrices)
+
+			require.Len(t, report.Disposals, 1)
+			disposal := report.Disposals[0]
+			require.Equal(t, "tx3", disposal.TxID)
+			require.Equal(t, DisposalKindSend, disposal.Kind)
+			require.Equal(t, 2024, disposal.TaxYear)
+			require.Equal(t, "1.00000000", disposal.Amount)
+			require.Equal(t, test.acquiredAt, *disposal.AcquiredAt)
+			require.InDelta(t, 200, disposal.Proceeds, 1e-9)
+			require.InDelta(t, test.costBasis, disposal.CostBasis, 1e-9)
+			require.InDelta(t, 200-test.costBasis, disposal.Gain, 1e-9)
+			require.Equal(t,
+				[]YearGains{{Year: 2024, Proceeds: 200, CostBasis: test.costBasis, Gain: 200 - test.costBasis}},
+				report.RealizedByYear)
+
+			require.Len(t, report.Accounts, 1)
+			accountReport := report.Accounts[0]
+			require.Equal(t, "1.00000000", accountReport.Holdings)
+			require.InDelta(t, 400, accountReport.MarketValue, 1e-9)
+			require.InDelta(t, 400-test.unrealizedGain, accountReport.CostBasis, 1e-9)
+			require.InDelta(t, test.unrealizedGain, accountReport.UnrealizedGain, 1e-9)
+			require.InDelta(t, test.unrealizedGain, report.UnrealizedGain, 1e-9)
+		})
+	}
+}
+
+func TestNewReportAt(t *testing.T) {
+	account := newAccount("a",
+		newTx("tx3", accounts.TxTypeSend, btc, 0, time3),
+		newTx("tx1", accounts.TxTypeReceive, btc, 0, time1),
+	)
+	report, err := NewReport([]*Account{account}, MethodFIFO, "USD", time2, testPrices)
+	require.NoError(t, err)
+	require.Empty(t, report.Disposals)
+	require.Equal(t, "1.00000000", report.Accounts[0].Holdings)
+	require.InDelta(t, 300, report.MarketValue, 1e-9)
+	require.InDelta(t, 200, report.UnrealizedGain, 1e-9)
+}
+
+func TestNewReportFees(t *testing.T) {
+	sendSelf := newTx("tx2", accounts.TxTypeSendSelf, btc/2, btc/10, time2)
+	unconfirmed := newTx("tx4", accounts.TxTypeReceive, btc, 0, time3)
+	unconfirmed.Height = 0
+	failed := newTx("tx3", accounts.TxTypeSend, btc/2, btc/10, time3)
+	failed.Status = accounts.TxStatusFailed
+	account := newAccount("a",
+		unconfirmed,
+		failed,
+		sendSelf,
+		newTx("tx1", accounts.TxTypeReceive, btc, 0, time1),
+	)
+	report, err := NewReport([]*Account{account}, MethodFIFO, "USD", time4, testPrices)
+	require.NoError(t, err)
+
+	// Only the fees are disposed of. The amount of the failed tx was not sent.
+	require.Len(t, report.Disposals, 2)
+	for i, disposal := range report.Disposals {
+		require.Equal(t, DisposalKindFee, disposal.Kind)
+		require.Equal(t, "0.10000000", disposal.Amount)
+		require.InDelta(t, 10, disposal.CostBasis, 1e-9)
+		require.Equal(t, []string{"tx2", "tx3"}[i], disposal.TxID)
+	}
+	require.InDelta(t, 30, report.Disposals[0].Proceeds, 1e-9)
+	require.InDelta(t, 20, report.Disposals[1].Proceeds, 1e-9)
+	require.Equal(t, []YearGains{
+		{Year: 2023, Proceeds: 30, CostBasis: 10, Gain: 20},
+		{Year: 2024, Proceeds: 20, CostBasis: 10, Gain: 10},
+	}, report.RealizedByYear)
+	require.Equal(t, "0.80000000", report.Accounts[0].Holdings)
+	require.InDelta(t, 80, report.CostBasis, 1e-9)
+}
+
+func TestNewReportTransfer(t *testing.T) {
+	accountA := newAccount("a",
+		newTx("transfer", accounts.TxTypeSend, btc/2, btc/100, time2),
+		newTx("tx1", accounts.TxTypeReceive, btc, 0, time1),
+	)
+	accountB := newAccount("b",
+		newTx("tx3", accounts.TxTypeSend, btc/2, 0, time3),
+		newTx("transfer", accounts.TxTypeReceive, btc/2, 0, time2),
+	)
+	report, err := NewReport([]*Account{accountA, accountB}, MethodFIFO, "USD", time4, testPrices)
+	require.NoError(t, err)
+	require.False(t, report.Incomplete)
+
+	// The transfer itself is not a disposal, only its fee.
+	require.Len(t, report.Disposals, 2)
+	fee := report.Disposals[0]
+	require.Equal(t, DisposalKindFee, fee.Kind)
+	require.Equal(t, "a", fee.AccountCode)
+	require.InDelta(t, 3, fee.Proceeds, 1e-9)
+	require.InDelta(t, 1, fee.CostBasis, 1e-9)
+
+	// The transferred coins keep their acquisition time and cost basis.
+	send := report.Disposals[1]
+	require.Equal(t, DisposalKindSend, send.Kind)
+	require.Equal(t, "b", send.AccountCode)
+	require.Equal(t, time1, *send.AcquiredAt)
+	require.InDelta(t, 100, send.Proceeds, 1e-9)
+	require.InDelta(t, 50, send.CostBasis, 1e-9)
+
+	require.Equal(t, "0.49000000", report.Accounts[0].Holdings)
+	require.InDelta(t, 49, report.Accounts[0].CostBasis, 1e-9)
+	require.Equal(t, "0.00000000", report.Accounts[1].Holdings)
+
+	reportB, err := report.ForAccount("b")
+	require.NoError(t, err)
+	require.Len(t, reportB.Accounts, 1)
+	require.Equal(t, []*Disposal{send}, reportB.Disposals)
+	require.Equal(t, []YearGains{{Year: 2024, Proceeds: 100, CostBasis: 50, Gain: 50}}, reportB.RealizedByYear)
+	require.InDelta(t, 0, reportB.CostBasis, 1e-9)
+	// The whole report is not modified.
+	require.Len(t, report.Disposals, 2)
+
+	_, err = report.ForAccount("c")
+	require.Error(t, err)
+}
+
+func TestNewReportIncomplete(t *testing.T) {
+	account := newAccount("a",
+		newTx("tx2", accounts.TxTypeSend, btc, 0, time2),
+	)
+	report, err := NewReport([]*Account{account}, MethodFIFO, "USD", time4,
+		priceFunc(map[time.Time]float64{time4: 400}))
+	require.NoError(t, err)
+	require.True(t, report.Incomplete)
+	require.Equal(t, []string{"tx2"}, report.MissingPrices)
+	require.Len(t, report.Disposals, 1)
+	require.Nil(t, report.Disposals[0].AcquiredAt)
+	require.Zero(t, report.Disposals[0].Proceeds)
+	require.Zero(t, report.Disposals[0].CostBasis)
+
+	report, err = NewReport([]*Account{account}, MethodFIFO, "USD", time4,
+		priceFunc(map[time.Time]float64{}))
+	require.NoError(t, err)
+	// Nothing is held.
+	require.False(t, report.Accounts[0].PriceMissing)
+
+	account = newAccount("a",
+		newTx("tx1", accounts.TxTypeReceive, btc, 0, time1),
+	)
+	report, err = NewReport([]*Account{account}, MethodFIFO, "USD", time4,
+		priceFunc(map[time.Time]float64{time1: 100}))
+	require.NoError(t, err)
+	require.True(t, report.Accounts[0].PriceMissing)
+}
+
+func TestWriteCSV(t *testing.T) {
+	account := newAccount("a",
+		newTx("tx3", accounts.TxTypeSend, btc/2, 0, time3),
+		newTx("tx1", accounts.TxTypeReceive, btc, 0, time1),
+	)
+	report, err := NewReport([]*Account{account}, MethodFIFO, "USD", time4, testPrices)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteCSV(&buf))
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, "Type", records[0][0])
+	require.Equal(t, []string{
+		"disposal", time3.Format(time.RFC3339), "2024", "a", "BTC", "tx3", "send", "0.50000000",
+		time1.Format(time.RFC3339), "USD", "100.00", "50.00", "50.00",
+	}, records[1])
+	require.Equal(t, []string{
+		"holding", time4.Format(time.RFC3339), "", "a", "BTC", "", "", "0.50000000",
+		"", "USD", "200.00", "50.00", "150.00",
+	}, records[2])
+}
@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/rates"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+// ExportExchangeRates exports the cached historical exchange rates of all coin/fiat pairs to a file
+// in the given format, so they can be imported on a machine without internet access using
+// ImportExchangeRates().
+func (backend *Backend) ExportExchangeRates(format rates.HistoryFormat) error {
+	if format != rates.HistoryFormatCSV && format != rates.HistoryFormatJSON {
+		return errp.Newf("unsupported format %q", format)
+	}
+	name := fmt.Sprintf("%s-exchange-rates.%s", time.Now().Format("2006-01-02-at-15-04-05"), format)
+	return backend.exportFile(name, func(writer io.Writer) error {
+		return backend.RatesUpdater().ExportHistory(writer, format)
+	})
+}
+
+// ImportExchangeRates merges historical exchange rates from a file created by ExportExchangeRates()
+// or provided by the user into the cached history. See `rates.RateUpdater.ImportHistory()` for the
+// supported formats and validation.
+func (backend *Backend) ImportExchangeRates(fileContents []byte) (*rates.ImportHistoryResult, error) {
+	result, err := backend.RatesUpdater().ImportHistory(fileContents)
+	if err != nil {
+		return nil, err
+	}
+	backend.log.Infof("Imported %d historical exchange rates (%d skipped, %d rejected)",
+		result.Imported, result.Skipped, result.Rejected)
+	return result, nil
+}
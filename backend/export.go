@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	accountsTypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+// selectAccounts returns the given accounts, or all active accounts if accountCodes is empty.
+func (backend *Backend) selectAccounts(accountCodes []accountsTypes.Code) (AccountsList, error) {
+	accountsList := backend.Accounts()
+	var selected AccountsList
+	if len(accountCodes) == 0 {
+		for _, account := range accountsList {
+			if account.Config().Config.Inactive || account.FatalError() {
+				continue
+			}
+			selected = append(selected, account)
+		}
+	} else {
+		for _, code := range accountCodes {
+			account := accountsList.lookup(code)
+			if account == nil {
+				return nil, errp.Newf("account %s not found", code)
+			}
+			selected = append(selected, account)
+		}
+	}
+	return selected, nil
+}
+
+// exportTransactions collects the transactions of the given accounts, or of all active accounts if
+// accountCodes is empty, from newest to oldest.
+func (backend *Backend) exportTransactions(
+	accountCodes []accountsTypes.Code, options accounts.ExportOptions,
+) ([]*accounts.ExportTransaction, error) {
+	selected, err := backend.selectAccounts(accountCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*accounts.ExportTransaction{}
+	for _, account := range selected {
+		if err := account.Initialize(); err != nil {
+			return nil, err
+		}
+		transactions, err := account.Transactions()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, accounts.NewExportTransactions(
+			account, options.Filter(transactions), options.Fiat)...)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		timeI, timeJ := result[i].Transaction.Timestamp, result[j].Transaction.Timestamp
+		if timeI == nil || timeJ == nil {
+			// Transactions without a timestamp are the newest.
+			return timeI == nil && timeJ != nil
+		}
+		return timeI.After(*timeJ)
+	})
+	return result, nil
+}
+
+// ExportTransactions exports the transactions of the given accounts, or of all active accounts if
+// accountCodes is empty, to one CSV file in one of the tax tool formats.
+func (backend *Backend) ExportTransactions(
+	accountCodes []accountsTypes.Code, options accounts.ExportOptions,
+) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	if options.Format == "" || options.Format == accounts.ExportFormatDefault {
+		return errp.New("a combined export needs a tax tool format")
+	}
+	transactions, err := backend.exportTransactions(accountCodes, options)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-export-%s.csv", time.Now().Format("2006-01-02-at-15-04-05"), options.Format)
+	return backend.exportFile(name, func(writer io.Writer) error {
+		return accounts.WriteExportCSV(writer, options.Format, transactions)
+	})
+}
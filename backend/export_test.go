@@ -0,0 +1,26 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"testing"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	accountsTypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportTransactions(t *testing.T) {
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+
+	require.Error(t, b.ExportTransactions(nil, accounts.ExportOptions{}))
+	require.Error(t, b.ExportTransactions(nil, accounts.ExportOptions{Format: accounts.ExportFormatDefault}))
+	require.Error(t, b.ExportTransactions(nil, accounts.ExportOptions{Format: "unknown"}))
+	require.Error(t, b.ExportTransactions(
+		[]accountsTypes.Code{"unknown"}, accounts.ExportOptions{Format: accounts.ExportFormatKoinly}))
+
+	transactions, err := b.exportTransactions(nil, accounts.ExportOptions{Format: accounts.ExportFormatKoinly})
+	require.NoError(t, err)
+	require.Empty(t, transactions)
+}
@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	utilcfg "github.com/BitBoxSwiss/bitbox-wallet-app/util/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+// exportFile lets the user choose where to save an export, suggesting the given file name in the
+// exports directory, and writes the file with write. On mobile, the file is opened afterwards so
+// that the user can share it. Returns `errp.ErrUserAbort` if the user did not choose a file.
+func (backend *Backend) exportFile(name string, write func(writer io.Writer) error) error {
+	exportsDir, err := utilcfg.ExportsDir()
+	if err != nil {
+		return err
+	}
+	suggestedPath := filepath.Join(exportsDir, name)
+	path := backend.Environment().GetSaveFilename(suggestedPath)
+	if path == "" {
+		return errp.ErrUserAbort
+	}
+	backend.log.Infof("Export to %s.", path)
+	err = func() error {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return errp.WithStack(err)
+		}
+		defer func() { _ = file.Close() }()
+
+		writer := bufio.NewWriter(file)
+		if err := write(writer); err != nil {
+			return err
+		}
+		if err := writer.Flush(); err != nil {
+			return errp.WithStack(err)
+		}
+		return errp.WithStack(file.Close())
+	}()
+	if err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "android" || runtime.GOOS == "ios" {
+		if err := backend.environment.SystemOpen(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	accountsTypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/costbasis"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+const (
+	// ErrMissingPrices is returned if the report can't be computed because no exchange rate is
+	// available for some transactions or holdings, e.g. because the rates could not be fetched.
+	ErrMissingPrices errp.ErrorCode = "missingPrices"
+
+	// historyLoadTimeout is the maximum time to fetch the missing exchange rates history of a
+	// report or an export.
+	historyLoadTimeout = 2 * time.Minute
+)
+
+// loadRatesHistory loads the exchange rates history of the coin in the fiat currency between the
+// given times, so that the historical rates are available also if the pair is not active, e.g. if
+// the fiat currency is not the active one. Failing to fetch the history, e.g. when offline, is only
+// logged, as the cached or imported rates may already cover the transactions.
+func (backend *Backend) loadRatesHistory(coinCode coin.Code, fiat string, since, until time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), historyLoadTimeout)
+	defer cancel()
+	if err := backend.RatesUpdater().LoadHistory(ctx, string(coinCode), fiat, since, until); err != nil {
+		backend.log.WithError(err).Warningf("Could not load the %s/%s exchange rates history", coinCode, fiat)
+	}
+}
+
+// GainsReportArgs are the options of a cost basis and gains report.
+type GainsReportArgs struct {
+	Method costbasis.Method `json:"method"`
+	// Fiat defaults to the main fiat currency if empty.
+	Fiat string `json:"fiat"`
+	// At is the time of the unrealized gains, as a unix timestamp in seconds. Transactions after it
+	// are ignored. Defaults to now if zero.
+	At int64 `json:"at"`
+	// AccountCode restricts the report to one account if not empty. The cost basis of transfers
+	// between accounts is still computed for the whole portfolio.
+	AccountCode accountsTypes.Code `json:"accountCode"`
+}
+
+// GainsReport computes the cost basis and the realized and unrealized gains of all active accounts,
+// or of one account, using the historical exchange rates. The missing exchange rates history is
+// fetched first if possible. Returns ErrMissingPrices if a rate is still not available.
+func (backend *Backend) GainsReport(args GainsReportArgs) (*costbasis.Report, error) {
+	fiat := args.Fiat
+	if fiat == "" {
+		fiat = backend.config.AppConfig().Backend.MainFiat
+	}
+	at := time.Now()
+	if args.At != 0 {
+		at = time.Unix(args.At, 0)
+	}
+
+	reportAccounts := []*costbasis.Account{}
+	// since is the time of the earliest transaction of each coin, from which on the rates are
+	// needed.
+	since := map[coin.Code]time.Time{}
+	for _, account := range backend.Accounts() {
+		if account.Config().Config.Inactive {
+			continue
+		}
+		if account.FatalError() {
+			continue
+		}
+		if err := account.Initialize(); err != nil {
+			return nil, err
+		}
+		txs, err := account.Transactions()
+		if err != nil {
+			return nil, err
+		}
+		coinCode := account.Coin().Code()
+		if _, ok := since[coinCode]; !ok {
+			since[coinCode] = at
+		}
+		for _, tx := range txs {
+			if tx.Timestamp != nil && tx.Timestamp.Before(since[coinCode]) {
+				since[coinCode] = *tx.Timestamp
+			}
+		}
+		reportAccounts = append(reportAccounts, &costbasis.Account{
+			Code:         string(account.Config().Config.Code),
+			Name:         account.Config().Config.Name,
+			CoinCode:     string(account.Coin().Code()),
+			Unit:         account.Coin().Unit(false),
+			Decimals:     coin.DecimalsExp(account.Coin(), false),
+			Transactions: txs,
+		})
+	}
+
+	for coinCode, coinSince := range since {
+		backend.loadRatesHistory(coinCode, fiat, coinSince, at)
+	}
+
+	ratesUpdater := backend.RatesUpdater()
+	price := func(coinCode, unit string, at time.Time) float64 {
+		if price := ratesUpdater.HistoricalPriceAt(coinCode, fiat, at); price != 0 {
+			return price
+		}
+		// The historical rates can lag behind, see `coin.ConversionsAtTime()`.
+		if time.Since(at) < 2*time.Hour {
+			return ratesUpdater.LatestPrice()[unit][fiat]
+		}
+		return 0
+	}
+	report, err := costbasis.NewReport(reportAccounts, args.Method, fiat, at, price)
+	if err != nil {
+		return nil, err
+	}
+	// A missing price would be counted as zero, which results in wrong gains.
+	if len(report.MissingPrices) > 0 {
+		return nil, errp.WithMessage(errp.WithStack(ErrMissingPrices),
+			fmt.Sprintf("no %s exchange rate for the transactions %s", fiat,
+				strings.Join(report.MissingPrices, ", ")))
+	}
+	for _, account := range report.Accounts {
+		if account.PriceMissing {
+			return nil, errp.WithMessage(errp.WithStack(ErrMissingPrices),
+				fmt.Sprintf("no %s exchange rate for the holdings of %s", fiat, account.Name))
+		}
+	}
+	if args.AccountCode != "" {
+		return report.ForAccount(string(args.AccountCode))
+	}
+	return report, nil
+}
+
+// ExportGainsReport exports the cost basis and gains report to a CSV file, see
+// `costbasis.Report.WriteCSV()`.
+func (backend *Backend) ExportGainsReport(args GainsReportArgs) error {
+	report, err := backend.GainsReport(args)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-gains-%s.csv", time.Now().Format("2006-01-02-at-15-04-05"), report.Method)
+	return backend.exportFile(name, report.WriteCSV)
+}
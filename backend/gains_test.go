@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc/addresses"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc/blockchain"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc/types"
+	coinpkg "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/costbasis"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGainsReport(t *testing.T) {
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+
+	_, err := b.GainsReport(GainsReportArgs{Method: "fofi"})
+	require.Error(t, err)
+
+	report, err := b.GainsReport(GainsReportArgs{Method: costbasis.MethodHIFO, At: 1700000000})
+	require.NoError(t, err)
+	require.Equal(t, costbasis.MethodHIFO, report.Method)
+	require.Equal(t, b.Config().AppConfig().Backend.MainFiat, report.Fiat)
+	require.Equal(t, time.Unix(1700000000, 0), report.At)
+	require.Empty(t, report.Disposals)
+
+	_, err = b.GainsReport(GainsReportArgs{Method: costbasis.MethodFIFO, AccountCode: "unknown"})
+	require.Error(t, err)
+}
+
+func TestGainsReportImportedRatesOffline(t *testing.T) {
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+
+	day := 24 * time.Hour
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var csv strings.Builder
+	csv.WriteString("coin,fiat,time,rate\n")
+	for i := 0; i <= 30; i++ {
+		fmt.Fprintf(&csv, "btc,CHF,%s,%d\n", start.Add(time.Duration(i)*day).Format(time.RFC3339), 10000+100*i)
+	}
+	result, err := b.RatesUpdater().ImportHistory([]byte(csv.String()))
+	require.NoError(t, err)
+	require.Equal(t, 31, result.Imported)
+
+	receiveTime := start.Add(5 * day)
+	sendTime := start.Add(20 * day)
+	b.makeBtcAccount = func(config *accounts.AccountConfig, coin *btc.Coin, gapLimits *types.GapLimits, getAddress func(coinpkg.Code, blockchain.ScriptHashHex) (*addresses.AccountAddress, error), log *logrus.Entry) accounts.Interface {
+		accountMock := MockBtcAccount(t, config, coin, gapLimits, log)
+		if strings.HasSuffix(string(config.Config.Code), "-btc-0") {
+			accountMock.TransactionsFunc = func() (accounts.OrderedTransactions, error) {
+				return accounts.OrderedTransactions{
+					{
+						InternalID: "send", Type: accounts.TxTypeSend, Status: accounts.TxStatusComplete,
+						Height: 200, Timestamp: &sendTime, Amount: coinpkg.NewAmountFromInt64(100000000),
+					},
+					{
+						InternalID: "receive", Type: accounts.TxTypeReceive, Status: accounts.TxStatusComplete,
+						Height: 100, Timestamp: &receiveTime, Amount: coinpkg.NewAmountFromInt64(100000000),
+					},
+				}, nil
+			}
+		}
+		return accountMock
+	}
+	b.registerKeystore(makeBitBox02Multi())
+
+	// The rates provider is not reachable in tests, so fetching the rates after the imported ones
+	// fails. The imported rates cover all transactions.
+	report, err := b.GainsReport(GainsReportArgs{
+		Method: costbasis.MethodFIFO, Fiat: "CHF", At: start.Add(60 * day).Unix(),
+	})
+	require.NoError(t, err)
+	require.Empty(t, report.MissingPrices)
+	require.Len(t, report.Disposals, 1)
+
+	// Without rates, the report fails.
+	_, err = b.GainsReport(GainsReportArgs{
+		Method: costbasis.MethodFIFO, Fiat: "JPY", At: start.Add(60 * day).Unix(),
+	})
+	require.Equal(t, ErrMissingPrices, errp.Cause(err))
+}
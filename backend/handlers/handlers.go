@@ -15,12 +15,15 @@ import (
 	"os"
 	"runtime/debug"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
 	accountErrors "github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/errors"
 	accountsTypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/addressbook"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/banners"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/bitsurance"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc"
@@ -29,6 +32,7 @@ import (
 	coinpkg "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/costbasis"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/devices/bitbox"
 	bitboxHandlers "github.com/BitBoxSwiss/bitbox-wallet-app/backend/devices/bitbox/handlers"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/devices/bitbox02"
@@ -41,11 +45,13 @@ import (
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/keystore/software"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/market"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/market/swapkit"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/profiles"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/rates"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/signing"
 	backendutil "github.com/BitBoxSwiss/bitbox-wallet-app/backend/util"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/versioninfo"
 	utilConfig "github.com/BitBoxSwiss/bitbox-wallet-app/util/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/connlog"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/jsonp"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/locker"
@@ -99,7 +105,41 @@ type Backend interface {
 	ExportLogs() error
 	ExportNotes() error
 	ImportNotes(jsonLines []byte) (*backend.ImportNotesResult, error)
-	ChartData() (*backend.Chart, error)
+	ExportAppBackup(password string) error
+	RestoreAppBackup(contents []byte, password string, mode backend.AppBackupRestoreMode) error
+	AtRestEncryptionStatus() backend.AtRestEncryptionStatus
+	UnlockAtRestEncryption(passphrase string) error
+	EnableAtRestEncryption(passphrase string) error
+	ChangeAtRestPassphrase(passphrase string, newPassphrase string) error
+	DisableAtRestEncryption(passphrase string) error
+	StrictPrivacy() bool
+	SetStrictPrivacy(enabled bool) error
+	ConnectionLogURLs() bool
+	SetConnectionLogURLs(enabled bool) error
+	ConnectionLog(query connlog.Query) []connlog.Entry
+	ExportConnectionLog(query connlog.Query) error
+	ClearConnectionLog() error
+	ExportExchangeRates(format rates.HistoryFormat) error
+	ImportExchangeRates(fileContents []byte) (*rates.ImportHistoryResult, error)
+	PriceAlerts() []rates.PriceAlert
+	PriceAlertHistory() []rates.PriceAlertTrigger
+	AddPriceAlert(alert rates.PriceAlert) (*rates.PriceAlert, error)
+	DeletePriceAlert(id string) error
+	AddressBook(coinCode coinpkg.Code) []addressbook.Contact
+	AddContact(contact addressbook.Contact) (*addressbook.Contact, error)
+	UpdateContact(contact addressbook.Contact) (*addressbook.Contact, error)
+	DeleteContact(id string) error
+	Profiles() *profiles.Manager
+	SwitchProfile(id string, password string) error
+	VerifyContactTestSend(id string, accountCode accountsTypes.Code, txID string) error
+	VerifyContactSignedMessage(id string, message string, signature string) error
+	GainsReport(args backend.GainsReportArgs) (*costbasis.Report, error)
+	ExportGainsReport(args backend.GainsReportArgs) error
+	ExportTransactions(accountCodes []accountsTypes.Code, options accounts.ExportOptions) error
+	QueryTransactions(query backend.TransactionsQuery) (*backend.TransactionsPage, error)
+	BalanceSnapshot(args backend.BalanceSnapshotArgs) (*backend.BalanceSnapshot, error)
+	ExportBalanceSnapshot(args backend.BalanceSnapshotArgs, format backend.BalanceSnapshotFormat) error
+	ChartData(args backend.ChartArgs) (*backend.Chart, error)
 	SupportedCoins(keystore.Keystore) []coinpkg.Code
 	CanAddAccount(coinpkg.Code, keystore.Keystore) (string, bool)
 	CreateAndPersistAccountConfig(coinCode coinpkg.Code, name string, keystore keystore.Keystore) (accountsTypes.Code, error)
@@ -112,7 +152,7 @@ type Backend interface {
 	AOPPApprove()
 	AOPPChooseAccount(code accountsTypes.Code)
 	GetAccountFromCode(code accountsTypes.Code) (accounts.Interface, error)
-	HTTPClient() *http.Client
+	MarketHTTPClient() *http.Client
 	LookupInsuredAccounts(accountCode accountsTypes.Code) ([]bitsurance.AccountDetails, error)
 	Authenticate(force bool)
 	ForceAuth()
@@ -231,6 +271,30 @@ func NewHandlers(
 	getAPIRouterNoError(apiRouter)("/set-account-receive-script-type", handlers.postSetAccountReceiveScriptType).Methods("POST")
 	getAPIRouterNoError(apiRouter)("/rename-account", handlers.postRenameAccount).Methods("POST")
 	getAPIRouterNoError(apiRouter)("/rates/reconfigure-history", handlers.postReconfigureHistoryExchangeRates).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/rates/sources", handlers.getRatesSources).Methods("GET")
+	getAPIRouterNoError(apiRouter)("/rates/export", handlers.postExportExchangeRates).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/rates/import", handlers.postImportExchangeRates).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/price-alerts", handlers.getPriceAlerts).Methods("GET")
+	getAPIRouterNoError(apiRouter)("/price-alerts/add", handlers.postAddPriceAlert).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/price-alerts/delete", handlers.postDeletePriceAlert).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/address-book", handlers.getAddressBook).Methods("GET")
+	getAPIRouterNoError(apiRouter)("/address-book/add", handlers.postAddContact).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/address-book/update", handlers.postUpdateContact).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/address-book/delete", handlers.postDeleteContact).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/address-book/verify/test-send", handlers.postVerifyContactTestSend).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/address-book/verify/signed-message", handlers.postVerifyContactSignedMessage).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/profiles", handlers.getProfiles).Methods("GET")
+	getAPIRouterNoError(apiRouter)("/profiles/create", handlers.postCreateProfile).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/profiles/rename", handlers.postRenameProfile).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/profiles/set-password", handlers.postSetProfilePassword).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/profiles/delete", handlers.postDeleteProfile).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/profiles/switch", handlers.postSwitchProfile).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/gains-report", handlers.getGainsReport).Methods("GET")
+	getAPIRouterNoError(apiRouter)("/gains-report/export", handlers.postExportGainsReport).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/export", handlers.postExportTransactions).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/transactions/query", handlers.postQueryTransactions).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/balance-snapshot", handlers.getBalanceSnapshot).Methods("GET")
+	getAPIRouterNoError(apiRouter)("/balance-snapshot/export", handlers.postExportBalanceSnapshot).Methods("POST")
 	getAPIRouterNoError(apiRouter)("/chart-data", handlers.getChartData).Methods("GET")
 	getAPIRouterNoError(apiRouter)("/supported-coins", handlers.getSupportedCoins).Methods("GET")
 	getAPIRouterNoError(apiRouter)("/test/register", handlers.postRegisterTestKeystore).Methods("POST")
@@ -269,6 +333,18 @@ func NewHandlers(
 	getAPIRouterNoError(apiRouter)("/accounts/eth-account-code", handlers.lookupEthAccountCode).Methods("POST")
 	getAPIRouterNoError(apiRouter)("/notes/export", handlers.postExportNotes).Methods("POST")
 	getAPIRouterNoError(apiRouter)("/notes/import", handlers.postImportNotes).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/app-backup/export", handlers.postExportAppBackup).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/app-backup/restore", handlers.postRestoreAppBackup).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/at-rest-encryption", handlers.getAtRestEncryption).Methods("GET")
+	getAPIRouterNoError(apiRouter)("/strict-privacy", handlers.postSetStrictPrivacy).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/connection-log", handlers.getConnectionLog).Methods("GET")
+	getAPIRouterNoError(apiRouter)("/connection-log/record-urls", handlers.postSetConnectionLogURLs).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/connection-log/export", handlers.postExportConnectionLog).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/connection-log/clear", handlers.postClearConnectionLog).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/at-rest-encryption/unlock", handlers.postAtRestEncryptionUnlock).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/at-rest-encryption/enable", handlers.postAtRestEncryptionEnable).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/at-rest-encryption/change-passphrase", handlers.postAtRestEncryptionChangePassphrase).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/at-rest-encryption/disable", handlers.postAtRestEncryptionDisable).Methods("POST")
 
 	getAPIRouterNoError(apiRouter)("/bluetooth/state", handlers.getBluetoothState).Methods("GET")
 	getAPIRouterNoError(apiRouter)("/bluetooth/connect", handlers.postBluetoothConnect).Methods("POST")
@@ -1017,6 +1093,479 @@ func (handlers *Handlers) postReconfigureHistoryExchangeRates(*http.Request) int
 	return nil
 }
 
+func (handlers *Handlers) getRatesSources(*http.Request) interface{} {
+	ratesUpdater := handlers.backend.RatesUpdater()
+	latest := ratesUpdater.LatestPriceSources()
+	if latest == nil {
+		latest = map[string]map[string]string{}
+	}
+	return map[string]interface{}{
+		"latest":  latest,
+		"history": ratesUpdater.HistorySources(),
+	}
+}
+
+func (handlers *Handlers) postExportExchangeRates(r *http.Request) interface{} {
+	type result struct {
+		Success bool   `json:"success"`
+		Message string `json:"message,omitempty"`
+		Aborted bool   `json:"aborted"`
+	}
+	var format rates.HistoryFormat
+	if err := json.NewDecoder(r.Body).Decode(&format); err != nil {
+		return result{Success: false, Message: err.Error()}
+	}
+	if err := handlers.backend.ExportExchangeRates(format); err != nil {
+		if errp.Cause(err) == errp.ErrUserAbort {
+			return result{Success: false, Aborted: true}
+		}
+		handlers.log.WithError(err).Error("Error exporting exchange rates")
+		return result{Success: false, Message: err.Error()}
+	}
+	return result{Success: true}
+}
+
+func (handlers *Handlers) postImportExchangeRates(r *http.Request) interface{} {
+	type result struct {
+		Success bool                       `json:"success"`
+		Message string                     `json:"message,omitempty"`
+		Data    *rates.ImportHistoryResult `json:"data"`
+	}
+	var fileContentsHex string
+	if err := json.NewDecoder(r.Body).Decode(&fileContentsHex); err != nil {
+		return result{Success: false, Message: err.Error()}
+	}
+	fileContents, err := hex.DecodeString(fileContentsHex)
+	if err != nil {
+		return result{Success: false, Message: err.Error()}
+	}
+	data, err := handlers.backend.ImportExchangeRates(fileContents)
+	if err != nil {
+		handlers.log.WithError(err).Error("Error importing exchange rates")
+		return result{Success: false, Message: err.Error()}
+	}
+	return result{Success: true, Data: data}
+}
+
+func (handlers *Handlers) getPriceAlerts(*http.Request) interface{} {
+	return map[string]interface{}{
+		"alerts":  handlers.backend.PriceAlerts(),
+		"history": handlers.backend.PriceAlertHistory(),
+	}
+}
+
+func (handlers *Handlers) postAddPriceAlert(r *http.Request) interface{} {
+	type response struct {
+		Success      bool              `json:"success"`
+		Alert        *rates.PriceAlert `json:"alert,omitempty"`
+		ErrorMessage string            `json:"errorMessage,omitempty"`
+	}
+	var alert rates.PriceAlert
+	if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	added, err := handlers.backend.AddPriceAlert(alert)
+	if err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true, Alert: added}
+}
+
+func (handlers *Handlers) postDeletePriceAlert(r *http.Request) interface{} {
+	type response struct {
+		Success      bool   `json:"success"`
+		ErrorMessage string `json:"errorMessage,omitempty"`
+	}
+	var id string
+	if err := json.NewDecoder(r.Body).Decode(&id); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	if err := handlers.backend.DeletePriceAlert(id); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true}
+}
+
+func (handlers *Handlers) getAddressBook(r *http.Request) interface{} {
+	return handlers.backend.AddressBook(coinpkg.Code(r.URL.Query().Get("coinCode")))
+}
+
+// contactResponse is the response of the handlers adding or modifying a contact.
+type contactResponse struct {
+	Success      bool                 `json:"success"`
+	Contact      *addressbook.Contact `json:"contact,omitempty"`
+	ErrorMessage string               `json:"errorMessage,omitempty"`
+}
+
+func (handlers *Handlers) postAddContact(r *http.Request) interface{} {
+	var contact addressbook.Contact
+	if err := json.NewDecoder(r.Body).Decode(&contact); err != nil {
+		return contactResponse{Success: false, ErrorMessage: err.Error()}
+	}
+	added, err := handlers.backend.AddContact(contact)
+	if err != nil {
+		return contactResponse{Success: false, ErrorMessage: err.Error()}
+	}
+	return contactResponse{Success: true, Contact: added}
+}
+
+func (handlers *Handlers) postUpdateContact(r *http.Request) interface{} {
+	var contact addressbook.Contact
+	if err := json.NewDecoder(r.Body).Decode(&contact); err != nil {
+		return contactResponse{Success: false, ErrorMessage: err.Error()}
+	}
+	updated, err := handlers.backend.UpdateContact(contact)
+	if err != nil {
+		return contactResponse{Success: false, ErrorMessage: err.Error()}
+	}
+	return contactResponse{Success: true, Contact: updated}
+}
+
+func (handlers *Handlers) postDeleteContact(r *http.Request) interface{} {
+	type response struct {
+		Success      bool   `json:"success"`
+		ErrorMessage string `json:"errorMessage,omitempty"`
+	}
+	var id string
+	if err := json.NewDecoder(r.Body).Decode(&id); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	if err := handlers.backend.DeleteContact(id); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true}
+}
+
+// profileResponse is the response of the handlers modifying profiles.
+type profileResponse struct {
+	Success      bool              `json:"success"`
+	Profile      *profiles.Profile `json:"profile,omitempty"`
+	ErrorMessage string            `json:"errorMessage,omitempty"`
+	ErrorCode    string            `json:"errorCode,omitempty"`
+}
+
+func newProfileResponse(err error) profileResponse {
+	if err == nil {
+		return profileResponse{Success: true}
+	}
+	if errCode, ok := errp.Cause(err).(errp.ErrorCode); ok {
+		return profileResponse{Success: false, ErrorCode: string(errCode)}
+	}
+	return profileResponse{Success: false, ErrorMessage: err.Error()}
+}
+
+// profilesManager returns the profiles manager, or an error if the app does not support profiles.
+func (handlers *Handlers) profilesManager() (*profiles.Manager, error) {
+	manager := handlers.backend.Profiles()
+	if manager == nil {
+		return nil, errp.New("profiles are not supported")
+	}
+	return manager, nil
+}
+
+func (handlers *Handlers) getProfiles(*http.Request) interface{} {
+	type response struct {
+		// Supported is false if the app does not support profiles.
+		Supported bool               `json:"supported"`
+		Profiles  []profiles.Profile `json:"profiles"`
+		Active    string             `json:"active"`
+	}
+	manager := handlers.backend.Profiles()
+	if manager == nil {
+		return response{Supported: false, Profiles: []profiles.Profile{}}
+	}
+	return response{Supported: true, Profiles: manager.Profiles(), Active: manager.Active().ID}
+}
+
+func (handlers *Handlers) postCreateProfile(r *http.Request) interface{} {
+	var request struct {
+		Name     string `json:"name"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return newProfileResponse(errp.WithStack(err))
+	}
+	manager, err := handlers.profilesManager()
+	if err != nil {
+		return newProfileResponse(err)
+	}
+	profile, err := manager.Create(request.Name, request.Password)
+	if err != nil {
+		return newProfileResponse(err)
+	}
+	return profileResponse{Success: true, Profile: profile}
+}
+
+func (handlers *Handlers) postRenameProfile(r *http.Request) interface{} {
+	var request struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return newProfileResponse(errp.WithStack(err))
+	}
+	manager, err := handlers.profilesManager()
+	if err != nil {
+		return newProfileResponse(err)
+	}
+	return newProfileResponse(manager.Rename(request.ID, request.Name))
+}
+
+func (handlers *Handlers) postSetProfilePassword(r *http.Request) interface{} {
+	var request struct {
+		ID          string `json:"id"`
+		Password    string `json:"password"`
+		NewPassword string `json:"newPassword"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return newProfileResponse(errp.WithStack(err))
+	}
+	manager, err := handlers.profilesManager()
+	if err != nil {
+		return newProfileResponse(err)
+	}
+	return newProfileResponse(manager.SetPassword(request.ID, request.Password, request.NewPassword))
+}
+
+func (handlers *Handlers) postDeleteProfile(r *http.Request) interface{} {
+	var request struct {
+		ID       string `json:"id"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return newProfileResponse(errp.WithStack(err))
+	}
+	manager, err := handlers.profilesManager()
+	if err != nil {
+		return newProfileResponse(err)
+	}
+	return newProfileResponse(manager.Delete(request.ID, request.Password))
+}
+
+func (handlers *Handlers) postSwitchProfile(r *http.Request) interface{} {
+	var request struct {
+		ID       string `json:"id"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return newProfileResponse(errp.WithStack(err))
+	}
+	return newProfileResponse(handlers.backend.SwitchProfile(request.ID, request.Password))
+}
+
+func (handlers *Handlers) postVerifyContactTestSend(r *http.Request) interface{} {
+	type response struct {
+		Success      bool   `json:"success"`
+		ErrorMessage string `json:"errorMessage,omitempty"`
+	}
+	var request struct {
+		ID          string             `json:"id"`
+		AccountCode accountsTypes.Code `json:"accountCode"`
+		TxID        string             `json:"txID"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	err := handlers.backend.VerifyContactTestSend(request.ID, request.AccountCode, request.TxID)
+	if err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true}
+}
+
+func (handlers *Handlers) postVerifyContactSignedMessage(r *http.Request) interface{} {
+	type response struct {
+		Success      bool   `json:"success"`
+		ErrorMessage string `json:"errorMessage,omitempty"`
+	}
+	var request struct {
+		ID        string `json:"id"`
+		Message   string `json:"message"`
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	err := handlers.backend.VerifyContactSignedMessage(request.ID, request.Message, request.Signature)
+	if err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true}
+}
+
+func (handlers *Handlers) getGainsReport(r *http.Request) interface{} {
+	type response struct {
+		Success      bool              `json:"success"`
+		Report       *costbasis.Report `json:"report,omitempty"`
+		ErrorMessage string            `json:"errorMessage,omitempty"`
+		ErrorCode    string            `json:"errorCode,omitempty"`
+	}
+	query := r.URL.Query()
+	args := backend.GainsReportArgs{
+		Method:      costbasis.Method(query.Get("method")),
+		Fiat:        query.Get("fiat"),
+		AccountCode: accountsTypes.Code(query.Get("accountCode")),
+	}
+	if at := query.Get("at"); at != "" {
+		parsed, err := strconv.ParseInt(at, 10, 64)
+		if err != nil {
+			return response{Success: false, ErrorMessage: err.Error()}
+		}
+		args.At = parsed
+	}
+	report, err := handlers.backend.GainsReport(args)
+	if err != nil {
+		handlers.log.WithError(err).Error("Error computing the gains report")
+		if errp.Cause(err) == backend.ErrMissingPrices {
+			return response{Success: false, ErrorMessage: err.Error(), ErrorCode: string(backend.ErrMissingPrices)}
+		}
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true, Report: report}
+}
+
+func (handlers *Handlers) postExportGainsReport(r *http.Request) interface{} {
+	type result struct {
+		Success bool   `json:"success"`
+		Message string `json:"message,omitempty"`
+		Aborted bool   `json:"aborted"`
+	}
+	var args backend.GainsReportArgs
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		return result{Success: false, Message: err.Error()}
+	}
+	if err := handlers.backend.ExportGainsReport(args); err != nil {
+		if errp.Cause(err) == errp.ErrUserAbort {
+			return result{Success: false, Aborted: true}
+		}
+		handlers.log.WithError(err).Error("Error exporting the gains report")
+		return result{Success: false, Message: err.Error()}
+	}
+	return result{Success: true}
+}
+
+func (handlers *Handlers) postQueryTransactions(r *http.Request) interface{} {
+	// transaction is a transaction of the query results, with its account.
+	type transaction struct {
+		accountHandlers.Transaction
+		AccountCode accountsTypes.Code `json:"accountCode"`
+		AccountName string             `json:"accountName"`
+		CoinCode    coinpkg.Code       `json:"coinCode"`
+	}
+	type response struct {
+		Success      bool          `json:"success"`
+		List         []transaction `json:"list"`
+		NextCursor   string        `json:"nextCursor,omitempty"`
+		Total        int           `json:"total"`
+		ErrorMessage string        `json:"errorMessage,omitempty"`
+	}
+	var query backend.TransactionsQuery
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	// The transactions JSON encodes the send-to-self type as "send_to_self".
+	for i, txType := range query.Types {
+		if txType == "send_to_self" {
+			query.Types[i] = accounts.TxTypeSendSelf
+		}
+	}
+	page, err := handlers.backend.QueryTransactions(query)
+	if err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	list := []transaction{}
+	for _, result := range page.Transactions {
+		accountConfig := result.Account.Config().Config
+		list = append(list, transaction{
+			Transaction: accountHandlers.NewTransaction(result.Account, result.Transaction, false),
+			AccountCode: accountConfig.Code,
+			AccountName: accountConfig.Name,
+			CoinCode:    result.Account.Coin().Code(),
+		})
+	}
+	return response{Success: true, List: list, NextCursor: page.NextCursor, Total: page.Total}
+}
+
+func (handlers *Handlers) postExportTransactions(r *http.Request) interface{} {
+	type result struct {
+		Success bool   `json:"success"`
+		Message string `json:"message,omitempty"`
+		Aborted bool   `json:"aborted"`
+	}
+	var args struct {
+		accounts.ExportOptions
+		// AccountCodes are the accounts to export combined in one file. All active accounts if empty.
+		AccountCodes []accountsTypes.Code `json:"accountCodes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		return result{Success: false, Message: err.Error()}
+	}
+	if err := handlers.backend.ExportTransactions(args.AccountCodes, args.ExportOptions); err != nil {
+		if errp.Cause(err) == errp.ErrUserAbort {
+			return result{Success: false, Aborted: true}
+		}
+		handlers.log.WithError(err).Error("Error exporting transactions")
+		return result{Success: false, Message: err.Error()}
+	}
+	return result{Success: true}
+}
+
+func (handlers *Handlers) getBalanceSnapshot(r *http.Request) interface{} {
+	type response struct {
+		Success      bool                     `json:"success"`
+		Snapshot     *backend.BalanceSnapshot `json:"snapshot,omitempty"`
+		ErrorMessage string                   `json:"errorMessage,omitempty"`
+	}
+	query := r.URL.Query()
+	args := backend.BalanceSnapshotArgs{
+		Coin: coinpkg.Code(query.Get("coin")),
+		Fiat: query.Get("fiat"),
+	}
+	if at := query.Get("at"); at != "" {
+		parsed, err := strconv.ParseInt(at, 10, 64)
+		if err != nil {
+			return response{Success: false, ErrorMessage: err.Error()}
+		}
+		args.At = parsed
+	}
+	if height := query.Get("height"); height != "" {
+		parsed, err := strconv.Atoi(height)
+		if err != nil {
+			return response{Success: false, ErrorMessage: err.Error()}
+		}
+		args.Height = parsed
+	}
+	snapshot, err := handlers.backend.BalanceSnapshot(args)
+	if err != nil {
+		handlers.log.WithError(err).Error("Error computing the balance snapshot")
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true, Snapshot: snapshot}
+}
+
+func (handlers *Handlers) postExportBalanceSnapshot(r *http.Request) interface{} {
+	type result struct {
+		Success bool   `json:"success"`
+		Message string `json:"message,omitempty"`
+		Aborted bool   `json:"aborted"`
+	}
+	var args struct {
+		backend.BalanceSnapshotArgs
+		Format backend.BalanceSnapshotFormat `json:"format"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		return result{Success: false, Message: err.Error()}
+	}
+	if err := handlers.backend.ExportBalanceSnapshot(args.BalanceSnapshotArgs, args.Format); err != nil {
+		if errp.Cause(err) == errp.ErrUserAbort {
+			return result{Success: false, Aborted: true}
+		}
+		handlers.log.WithError(err).Error("Error exporting the balance snapshot")
+		return result{Success: false, Message: err.Error()}
+	}
+	return result{Success: true}
+}
+
 func (handlers *Handlers) getDevicesRegistered(*http.Request) interface{} {
 	jsonDevices := map[string]string{}
 	for deviceID, device := range handlers.backend.DevicesRegistered() {
@@ -1360,14 +1909,24 @@ func (handlers *Handlers) apiMiddleware(devMode bool, h func(*http.Request) (int
 	})
 }
 
-func (handlers *Handlers) getChartData(*http.Request) interface{} {
+func (handlers *Handlers) getChartData(r *http.Request) interface{} {
 	type Result struct {
 		Data    *backend.Chart `json:"data,omitempty"`
 		Success bool           `json:"success"`
 	}
 
-	data, err := handlers.backend.ChartData()
+	query := r.URL.Query()
+	args := backend.ChartArgs{
+		RootFingerprint: query.Get("rootFingerprint"),
+		CoinUnit:        query.Get("unit") == "coin",
+		Resolution:      backend.ChartResolution(query.Get("resolution")),
+	}
+	for _, code := range query["accountCode"] {
+		args.AccountCodes = append(args.AccountCodes, accountsTypes.Code(code))
+	}
+	data, err := handlers.backend.ChartData(args)
 	if err != nil {
+		handlers.log.WithError(err).Error("Error computing the chart data")
 		return Result{Success: false}
 	}
 	return Result{Success: true, Data: data}
@@ -1471,7 +2030,7 @@ func (handlers *Handlers) getMarketDeals(r *http.Request) interface{} {
 	}
 
 	regionCode := r.URL.Query().Get("region")
-	marketDealsLists, err := market.GetDeals(acct, regionCode, action, handlers.backend.HTTPClient())
+	marketDealsLists, err := market.GetDeals(acct, regionCode, action, handlers.backend.MarketHTTPClient())
 	if err != nil {
 		return errorResult{Success: false, ErrorCode: err.Error()}
 	}
@@ -1793,6 +2352,232 @@ func (handlers *Handlers) postImportNotes(r *http.Request) interface{} {
 	return result{Success: true, Data: data}
 }
 
+func (handlers *Handlers) postExportAppBackup(r *http.Request) interface{} {
+	type result struct {
+		Success   bool   `json:"success"`
+		Message   string `json:"message,omitempty"`
+		ErrorCode string `json:"errorCode,omitempty"`
+		Aborted   bool   `json:"aborted"`
+	}
+	var request struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return result{Success: false, Message: err.Error()}
+	}
+	if err := handlers.backend.ExportAppBackup(request.Password); err != nil {
+		if errp.Cause(err) == errp.ErrUserAbort {
+			return result{Success: false, Aborted: true}
+		}
+		if errCode, ok := errp.Cause(err).(errp.ErrorCode); ok {
+			return result{Success: false, ErrorCode: string(errCode)}
+		}
+		handlers.log.WithError(err).Error("Error exporting app backup")
+		return result{Success: false, Message: err.Error()}
+	}
+	return result{Success: true}
+}
+
+func (handlers *Handlers) postRestoreAppBackup(r *http.Request) interface{} {
+	type result struct {
+		Success   bool   `json:"success"`
+		Message   string `json:"message,omitempty"`
+		ErrorCode string `json:"errorCode,omitempty"`
+	}
+	var request struct {
+		// FileContents is hex encoded.
+		FileContents string                       `json:"fileContents"`
+		Password     string                       `json:"password"`
+		Mode         backend.AppBackupRestoreMode `json:"mode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return result{Success: false, Message: err.Error()}
+	}
+	fileContents, err := hex.DecodeString(request.FileContents)
+	if err != nil {
+		return result{Success: false, Message: err.Error()}
+	}
+	if err := handlers.backend.RestoreAppBackup(fileContents, request.Password, request.Mode); err != nil {
+		if errCode, ok := errp.Cause(err).(errp.ErrorCode); ok {
+			return result{Success: false, ErrorCode: string(errCode)}
+		}
+		handlers.log.WithError(err).Error("Error restoring app backup")
+		return result{Success: false, Message: err.Error()}
+	}
+	return result{Success: true}
+}
+
+func (handlers *Handlers) getAtRestEncryption(*http.Request) interface{} {
+	return handlers.backend.AtRestEncryptionStatus()
+}
+
+// atRestEncryptionResponse is the response of the endpoints changing the encryption at rest.
+func (handlers *Handlers) atRestEncryptionResponse(err error) interface{} {
+	type result struct {
+		Success   bool   `json:"success"`
+		Message   string `json:"message,omitempty"`
+		ErrorCode string `json:"errorCode,omitempty"`
+	}
+	if err != nil {
+		if errCode, ok := errp.Cause(err).(errp.ErrorCode); ok {
+			return result{Success: false, ErrorCode: string(errCode)}
+		}
+		handlers.log.WithError(err).Error("Encryption at rest failed")
+		return result{Success: false, Message: err.Error()}
+	}
+	return result{Success: true}
+}
+
+func (handlers *Handlers) postAtRestEncryptionUnlock(r *http.Request) interface{} {
+	var request struct {
+		Passphrase string `json:"passphrase"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return handlers.atRestEncryptionResponse(errp.WithStack(err))
+	}
+	return handlers.atRestEncryptionResponse(handlers.backend.UnlockAtRestEncryption(request.Passphrase))
+}
+
+func (handlers *Handlers) postAtRestEncryptionEnable(r *http.Request) interface{} {
+	var request struct {
+		Passphrase string `json:"passphrase"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return handlers.atRestEncryptionResponse(errp.WithStack(err))
+	}
+	return handlers.atRestEncryptionResponse(handlers.backend.EnableAtRestEncryption(request.Passphrase))
+}
+
+func (handlers *Handlers) postAtRestEncryptionChangePassphrase(r *http.Request) interface{} {
+	var request struct {
+		Passphrase    string `json:"passphrase"`
+		NewPassphrase string `json:"newPassphrase"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return handlers.atRestEncryptionResponse(errp.WithStack(err))
+	}
+	return handlers.atRestEncryptionResponse(
+		handlers.backend.ChangeAtRestPassphrase(request.Passphrase, request.NewPassphrase))
+}
+
+func (handlers *Handlers) postAtRestEncryptionDisable(r *http.Request) interface{} {
+	var request struct {
+		Passphrase string `json:"passphrase"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return handlers.atRestEncryptionResponse(errp.WithStack(err))
+	}
+	return handlers.atRestEncryptionResponse(handlers.backend.DisableAtRestEncryption(request.Passphrase))
+}
+
+func (handlers *Handlers) postSetStrictPrivacy(r *http.Request) interface{} {
+	var jsonBody struct {
+		Enabled bool `json:"enabled"`
+	}
+
+	type response struct {
+		Success      bool   `json:"success"`
+		ErrorMessage string `json:"errorMessage,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	if err := handlers.backend.SetStrictPrivacy(jsonBody.Enabled); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true}
+}
+
+func (handlers *Handlers) postSetConnectionLogURLs(r *http.Request) interface{} {
+	var jsonBody struct {
+		Enabled bool `json:"enabled"`
+	}
+
+	type response struct {
+		Success      bool   `json:"success"`
+		ErrorMessage string `json:"errorMessage,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	if err := handlers.backend.SetConnectionLogURLs(jsonBody.Enabled); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true}
+}
+
+// getConnectionLog returns the recorded connections, the newest first. The entries can be filtered
+// with the query params service, host, result, since (RFC3339) and limit.
+func (handlers *Handlers) getConnectionLog(r *http.Request) interface{} {
+	type response struct {
+		Success           bool            `json:"success"`
+		StrictPrivacy     bool            `json:"strictPrivacy"`
+		ConnectionLogURLs bool            `json:"connectionLogURLs"`
+		Entries           []connlog.Entry `json:"entries"`
+		ErrorMessage      string          `json:"errorMessage,omitempty"`
+	}
+	params := r.URL.Query()
+	query := connlog.Query{
+		Service: params.Get("service"),
+		Host:    params.Get("host"),
+		Result:  connlog.Result(params.Get("result")),
+	}
+	if since := params.Get("since"); since != "" {
+		parsed, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return response{Success: false, ErrorMessage: err.Error()}
+		}
+		query.Since = parsed
+	}
+	if limit := params.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil {
+			return response{Success: false, ErrorMessage: err.Error()}
+		}
+		query.Limit = parsed
+	}
+	return response{
+		Success:           true,
+		StrictPrivacy:     handlers.backend.StrictPrivacy(),
+		ConnectionLogURLs: handlers.backend.ConnectionLogURLs(),
+		Entries:           handlers.backend.ConnectionLog(query),
+	}
+}
+
+func (handlers *Handlers) postExportConnectionLog(r *http.Request) interface{} {
+	type result struct {
+		Success bool   `json:"success"`
+		Message string `json:"message,omitempty"`
+		Aborted bool   `json:"aborted"`
+	}
+	var query connlog.Query
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		return result{Success: false, Message: err.Error()}
+	}
+	if err := handlers.backend.ExportConnectionLog(query); err != nil {
+		if errp.Cause(err) == errp.ErrUserAbort {
+			return result{Success: false, Aborted: true}
+		}
+		handlers.log.WithError(err).Error("Error exporting the connection log")
+		return result{Success: false, Message: err.Error()}
+	}
+	return result{Success: true}
+}
+
+func (handlers *Handlers) postClearConnectionLog(*http.Request) interface{} {
+	type response struct {
+		Success      bool   `json:"success"`
+		ErrorMessage string `json:"errorMessage,omitempty"`
+	}
+	if err := handlers.backend.ClearConnectionLog(); err != nil {
+		handlers.log.WithError(err).Error("Error clearing the connection log")
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true}
+}
+
 func (handlers *Handlers) getBluetoothState(r *http.Request) interface{} {
 	return handlers.backend.Bluetooth().State()
 }
@@ -2039,7 +2824,7 @@ func (handlers *Handlers) postSwapkitQuote(r *http.Request) interface{} {
 	}
 	quoteResponse, quoteError := swapkit.NewQuoteFromCoinCode(
 		context.Background(),
-		handlers.backend.HTTPClient(),
+		handlers.backend.MarketHTTPClient(),
 		sellCoin,
 		buyCoin,
 		sellAmount,
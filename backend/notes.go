@@ -10,18 +10,20 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"runtime"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/notes"
 	accountsTypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc/addresses"
 	coinpkg "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/util"
-	utilcfg "github.com/BitBoxSwiss/bitbox-wallet-app/util/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/atrest"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
 )
 
@@ -42,10 +44,19 @@ type bip329BitBoxApp struct {
 type bip329Type string
 
 const (
-	bip329TypeTx   bip329Type = "tx"
-	bip329TypeXpub bip329Type = "xpub"
+	bip329TypeTx     bip329Type = "tx"
+	bip329TypeAddr   bip329Type = "addr"
+	bip329TypePubkey bip329Type = "pubkey"
+	bip329TypeInput  bip329Type = "input"
+	bip329TypeOutput bip329Type = "output"
+	bip329TypeXpub   bip329Type = "xpub"
 )
 
+// preservedNotesFilename is the name of the file in the notes directory which stores the imported
+// BIP-329 entries which could not be applied, so they are not lost when exporting again. These are
+// entries of unknown types, and labels which do not belong to any of our accounts.
+const preservedNotesFilename = "bip329-preserved.jsonl"
+
 // https://github.com/bitcoin/bips/blob/master/bip-0329.mediawiki#specification
 // Extended with a proprietary field "bitboxapp".
 type bip329Entry struct {
@@ -57,14 +68,66 @@ type bip329Entry struct {
 	// reason why.
 	// Origin string `json:"origin,omitempty"`
 
+	// Spendable is only used for outputs. Outputs are spendable if the field is missing.
+	Spendable *bool `json:"spendable,omitempty"`
+
 	BitBoxApp *bip329BitBoxApp `json:"bitboxapp,omitempty"`
 }
 
+// key identifies the labeled item of an entry, used to deduplicate entries.
+func (entry *bip329Entry) key() string {
+	return string(entry.Type) + ":" + entry.Ref
+}
+
+// readPreservedNotes reads the preserved BIP-329 entries, see `preservedNotesFilename`. If the
+// file does not exist yet, no error is returned.
+func readPreservedNotes(filename string, keyring *atrest.Keyring) ([]string, error) {
+	content, err := keyring.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	lines := []string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+func writePreservedNotes(filename string, lines []string, keyring *atrest.Keyring) error {
+	content := ""
+	for _, line := range lines {
+		content += line + "\n"
+	}
+	return keyring.WriteFile(filename, []byte(content), 0600)
+}
+
+// sortedKeys returns the keys of the given map, sorted so the export is stable.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func (backend *Backend) exportNotes(writer io.Writer) error {
 	accounts := backend.Accounts()
 
 	backend.log.Infof("Exporting notes of %d accounts", len(accounts))
 
+	// Keys of the entries exported so far, see `bip329Entry.key()`.
+	exported := map[string]struct{}{}
+	encode := func(entry bip329Entry) error {
+		exported[entry.key()] = struct{}{}
+		return json.NewEncoder(writer).Encode(entry)
+	}
+
 	for _, account := range accounts {
 		if account.FatalError() {
 			continue
@@ -108,89 +171,180 @@ func (backend *Backend) exportNotes(writer io.Writer) error {
 						AccountCode: account.Config().Config.Code,
 					},
 				}
-				if err := json.NewEncoder(writer).Encode(entry); err != nil {
+				if err := encode(entry); err != nil {
 					return err
 				}
 			}
 		}
 
+		// We use this to aid the import back into the BitBoxApp, as the same txID can be in
+		// multiple accounts with different labels (e.g. and ERC20 token in the ERC20 token account
+		// as well as in the parent ETH account), and the origin label is not a good fit (see
+		// docstring of `bip329BitBoxApp`).
+		bitboxApp := &bip329BitBoxApp{
+			CoinCode:    account.Config().Config.CoinCode,
+			AccountCode: accountCode,
+		}
+
 		notesData := account.Notes().Data()
 		for txID, note := range notesData.TransactionNotes {
 			entry := bip329Entry{
-				Type:  bip329TypeTx,
-				Ref:   txID,
-				Label: note,
-				// We use this to aid the import back into the BitBoxApp, as the same txID can be in
-				// multiple accounts with different labels (e.g. and ERC20 token in the ERC20 token
-				// account as well as in the parent ETH account), and the origin label is not a good
-				// fit (see docstring of `bip329BitBoxApp`).
-				BitBoxApp: &bip329BitBoxApp{
-					CoinCode:    account.Config().Config.CoinCode,
-					AccountCode: accountCode,
-				},
+				Type:      bip329TypeTx,
+				Ref:       txID,
+				Label:     note,
+				BitBoxApp: bitboxApp,
 			}
-			if err := json.NewEncoder(writer).Encode(entry); err != nil {
+			if err := encode(entry); err != nil {
+				return err
+			}
+		}
+		labelTypes := []struct {
+			bip329Type bip329Type
+			notes      map[string]string
+		}{
+			{bip329TypeAddr, notesData.AddressNotes},
+			{bip329TypePubkey, notesData.PubkeyNotes},
+			{bip329TypeInput, notesData.InputNotes},
+		}
+		for _, labelType := range labelTypes {
+			for _, ref := range sortedKeys(labelType.notes) {
+				entry := bip329Entry{
+					Type:      labelType.bip329Type,
+					Ref:       ref,
+					Label:     labelType.notes[ref],
+					BitBoxApp: bitboxApp,
+				}
+				if err := encode(entry); err != nil {
+					return err
+				}
+			}
+		}
+		// Outputs with a note or which are marked as not spendable.
+		outPoints := map[string]struct{}{}
+		for outPoint := range notesData.OutputNotes {
+			outPoints[outPoint] = struct{}{}
+		}
+		for outPoint := range notesData.UnspendableOutputs {
+			outPoints[outPoint] = struct{}{}
+		}
+		for _, outPoint := range sortedKeys(outPoints) {
+			entry := bip329Entry{
+				Type:      bip329TypeOutput,
+				Ref:       outPoint,
+				Label:     notesData.OutputNotes[outPoint],
+				BitBoxApp: bitboxApp,
+			}
+			if notesData.UnspendableOutputs[outPoint] {
+				spendable := false
+				entry.Spendable = &spendable
+			}
+			if err := encode(entry); err != nil {
 				return err
 			}
 		}
 	}
-	return nil
-}
 
-// ExportNotes exports the transactions and accounts labels of all accounts of all
-// connected/remembered keystores. Deactivated accounts are included in the export, except for
-// deactivated ERC-20 accounts. We export to a file using an extended version of BIP-329:
-// https://github.com/bitcoin/bips/blob/master/bip-0329.mediawiki
-func (backend *Backend) ExportNotes() error {
-	exportsDir, err := utilcfg.ExportsDir()
+	// Re-export the entries we could not apply during a previous import, unless we exported a label
+	// for the same item above.
+	preserved, err := readPreservedNotes(backend.preservedNotesPath(), backend.keyring)
 	if err != nil {
 		return err
 	}
-	name := fmt.Sprintf("%s-notes.jsonl", time.Now().Format("2006-01-02-at-15-04-05"))
-	suggestedPath := filepath.Join(exportsDir, name)
-	path := backend.Environment().GetSaveFilename(suggestedPath)
-	if path == "" {
-		return errp.ErrUserAbort
-	}
-	err = func() error {
-		file, err := os.Create(path)
-		if err != nil {
-			return err
+	for _, line := range preserved {
+		var entry bip329Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return errp.WithStack(err)
 		}
-		defer func() { _ = file.Close() }()
-
-		writer := bufio.NewWriter(file)
-		if err := backend.exportNotes(writer); err != nil {
-			return err
+		if _, ok := exported[entry.key()]; ok {
+			continue
 		}
-		return writer.Flush()
-	}()
-	if err != nil {
-		return err
-	}
-
-	if runtime.GOOS == "android" || runtime.GOOS == "ios" {
-		if err := backend.environment.SystemOpen(path); err != nil {
-			return err
+		if _, err := io.WriteString(writer, line+"\n"); err != nil {
+			return errp.WithStack(err)
 		}
 	}
 	return nil
 }
 
+func (backend *Backend) preservedNotesPath() string {
+	return filepath.Join(backend.arguments.NotesDirectoryPath(), preservedNotesFilename)
+}
+
+// ExportNotes exports the account, transaction, address, public key, input and output labels of
+// all accounts of all connected/remembered keystores, as well as the entries preserved from
+// previous imports. Deactivated accounts are included in the export, except for
+// deactivated ERC-20 accounts. We export to a file using an extended version of BIP-329:
+// https://github.com/bitcoin/bips/blob/master/bip-0329.mediawiki
+func (backend *Backend) ExportNotes() error {
+	name := fmt.Sprintf("%s-notes.jsonl", time.Now().Format("2006-01-02-at-15-04-05"))
+	return backend.exportFile(name, backend.exportNotes)
+}
+
 // ImportNotesResult contains stats from the notes import.
 type ImportNotesResult struct {
 	// AccountCount is the number of account name's updated.
 	AccountCount int `json:"accountCount"`
 	// TransactionCount is the number of transaction notes updated.
 	TransactionCount int `json:"transactionCount"`
+	// AddressCount is the number of address notes updated.
+	AddressCount int `json:"addressCount"`
+	// PubkeyCount is the number of public key notes updated.
+	PubkeyCount int `json:"pubkeyCount"`
+	// InputCount is the number of input notes updated.
+	InputCount int `json:"inputCount"`
+	// OutputCount is the number of outputs whose note or spendable flag was updated.
+	OutputCount int `json:"outputCount"`
+	// PreservedCount is the number of entries which could not be applied, e.g. because of an
+	// unknown type or because they do not belong to any account. They are kept and included in
+	// future exports.
+	PreservedCount int `json:"preservedCount"`
+}
+
+// lookupBIP329Account finds the account an addr, pubkey, input or output label belongs to. `nil,
+// nil` is returned if not found.
+func (backend *Backend) lookupBIP329Account(entry *bip329Entry, ref string) (accounts.Interface, error) {
+	if entry.BitBoxApp != nil {
+		return backend.Accounts().lookup(entry.BitBoxApp.AccountCode), nil
+	}
+	switch entry.Type {
+	case bip329TypeAddr, bip329TypePubkey:
+		for _, account := range backend.Accounts() {
+			// Only BTC-based accounts have addresses derived from public keys.
+			btcAccount, ok := account.(*btc.Account)
+			if !ok || account.FatalError() {
+				continue
+			}
+			if err := account.Initialize(); err != nil {
+				return nil, err
+			}
+			var address *addresses.AccountAddress
+			if entry.Type == bip329TypeAddr {
+				address = btcAccount.AddressByString(ref)
+			} else {
+				address = btcAccount.AddressByPublicKey(ref)
+			}
+			if address != nil {
+				return account, nil
+			}
+		}
+		return nil, nil
+	case bip329TypeInput, bip329TypeOutput:
+		// The ref is `<txid>:<index>`.
+		txID, _, ok := strings.Cut(ref, ":")
+		if !ok {
+			return nil, nil
+		}
+		return backend.Accounts().lookupByTransactionInternalID(txID)
+	default:
+		return nil, nil
+	}
 }
 
 // ImportNotes imports notes from a jsonlines document according to BIP-329:
 // https://github.com/bitcoin/bips/blob/master/bip-0329.mediawiki
 //
 // Only accounts of connected/remembered keystores are considered, also deactivated accounts (except
-// for deactivated ERC-20 accounts). If a label in the import does not belong to one of them, it is
-// ignored.
+// for deactivated ERC-20 accounts). If a label in the import does not belong to one of them, or is
+// of a type we don't know, it is preserved and included in future exports.
 func (backend *Backend) ImportNotes(jsonLines []byte) (*ImportNotesResult, error) {
 	sanityCheck := func() error {
 		scanner := bufio.NewScanner(bytes.NewReader(jsonLines))
@@ -209,26 +363,70 @@ func (backend *Backend) ImportNotes(jsonLines []byte) (*ImportNotesResult, error
 	if err := sanityCheck(); err != nil {
 		return nil, err
 	}
+
+	preserved, err := readPreservedNotes(backend.preservedNotesPath(), backend.keyring)
+	if err != nil {
+		return nil, err
+	}
+	preservedChanged := false
+	// preserve adds or replaces a preserved entry.
+	preserve := func(entry *bip329Entry, line string) error {
+		for i, preservedLine := range preserved {
+			var preservedEntry bip329Entry
+			if err := json.Unmarshal([]byte(preservedLine), &preservedEntry); err != nil {
+				return errp.WithStack(err)
+			}
+			if preservedEntry.key() == entry.key() {
+				preservedChanged = preservedChanged || preservedLine != line
+				preserved[i] = line
+				return nil
+			}
+		}
+		preservedChanged = true
+		preserved = append(preserved, line)
+		return nil
+	}
+	// unpreserve removes a preserved entry once a label for the same item could be applied.
+	unpreserve := func(entry *bip329Entry) error {
+		for i, preservedLine := range preserved {
+			var preservedEntry bip329Entry
+			if err := json.Unmarshal([]byte(preservedLine), &preservedEntry); err != nil {
+				return errp.WithStack(err)
+			}
+			if preservedEntry.key() == entry.key() {
+				preservedChanged = true
+				preserved = append(preserved[:i], preserved[i+1:]...)
+				return nil
+			}
+		}
+		return nil
+	}
+
 	scanner := bufio.NewScanner(bytes.NewReader(jsonLines))
 
 	result := &ImportNotesResult{}
 
 	for scanner.Scan() {
-		line := scanner.Bytes()
+		line := strings.TrimSpace(scanner.Text())
 		if len(line) == 0 {
 			continue
 		}
 		var entry bip329Entry
-		if err := json.Unmarshal(line, &entry); err != nil {
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
 			return nil, err
 		}
 
 		label := util.TruncateString(strings.TrimSpace(entry.Label), notes.MaxNoteLen)
 		ref := strings.TrimSpace(entry.Ref)
-		if label == "" || ref == "" {
+		if ref == "" {
+			continue
+		}
+		// Outputs can be imported only to set the spendable flag.
+		if label == "" && (entry.Type != bip329TypeOutput || entry.Spendable == nil) {
 			continue
 		}
 
+		applied := false
 		switch entry.Type {
 		case bip329TypeXpub:
 			// Import account name / label.
@@ -238,8 +436,8 @@ func (backend *Backend) ImportNotes(jsonLines []byte) (*ImportNotesResult, error
 			} else {
 				acctCode, err := backend.config.AccountsConfig().LookupByXpub(ref)
 				if err != nil {
-					// Could not find any account for this label, skipping.
-					continue
+					// Could not find any account for this label, preserving.
+					break
 				}
 				accountCode = acctCode
 			}
@@ -258,6 +456,7 @@ func (backend *Backend) ImportNotes(jsonLines []byte) (*ImportNotesResult, error
 					// added, same as we don't export the name of such accounts during notes export.
 					return nil
 				}
+				applied = true
 				if label != acct.Name {
 					acct.Name = label
 					result.AccountCount += 1
@@ -281,8 +480,8 @@ func (backend *Backend) ImportNotes(jsonLines []byte) (*ImportNotesResult, error
 				account = acct
 			}
 			if account == nil {
-				// Could not find account containing this tx. Skipping.
-				continue
+				// Could not find account containing this tx. Preserving.
+				break
 			}
 			// So `account.Notes()` is ready to use.
 			if err := account.Initialize(); err != nil {
@@ -295,16 +494,85 @@ func (backend *Backend) ImportNotes(jsonLines []byte) (*ImportNotesResult, error
 			if err != nil {
 				return nil, err
 			}
+			applied = true
 			if changed {
 				result.TransactionCount += 1
 			}
+
+		case bip329TypeAddr, bip329TypePubkey, bip329TypeInput, bip329TypeOutput:
+			account, err := backend.lookupBIP329Account(&entry, ref)
+			if err != nil {
+				return nil, err
+			}
+			if account == nil {
+				// Could not find account containing this item. Preserving.
+				break
+			}
+			if err := account.Initialize(); err != nil {
+				return nil, err
+			}
+			accountNotes := account.Notes()
+			changed := false
+			switch entry.Type {
+			case bip329TypeAddr:
+				changed, err = accountNotes.SetAddressNote(ref, label)
+				if changed {
+					result.AddressCount += 1
+				}
+			case bip329TypePubkey:
+				changed, err = accountNotes.SetPubkeyNote(ref, label)
+				if changed {
+					result.PubkeyCount += 1
+				}
+			case bip329TypeInput:
+				changed, err = accountNotes.SetInputNote(ref, label)
+				if changed {
+					result.InputCount += 1
+				}
+			case bip329TypeOutput:
+				if label != "" {
+					changed, err = accountNotes.SetOutputNote(ref, label)
+					if err != nil {
+						return nil, err
+					}
+				}
+				if entry.Spendable != nil {
+					var spendableChanged bool
+					spendableChanged, err = accountNotes.SetOutputSpendable(ref, *entry.Spendable)
+					changed = changed || spendableChanged
+				}
+				if changed {
+					result.OutputCount += 1
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+			applied = true
 		}
+
+		if applied {
+			if err := unpreserve(&entry); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := preserve(&entry, line); err != nil {
+			return nil, err
+		}
+		result.PreservedCount += 1
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, errp.WithStack(err)
 	}
 
+	if preservedChanged {
+		if err := writePreservedNotes(backend.preservedNotesPath(), preserved, backend.keyring); err != nil {
+			return nil, err
+		}
+	}
+
 	// Reflect updated account names in frontend.
 	backend.emitAccountsStatusChanged()
 	return result, nil
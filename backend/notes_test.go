@@ -177,6 +177,8 @@ func (s *notesTestSuite) TestNotesImport() {
 		&ImportNotesResult{
 			AccountCount:     2,
 			TransactionCount: 3,
+			// The note of the unknown transaction.
+			PreservedCount: 1,
 		},
 		result)
 
@@ -225,6 +227,8 @@ func (s *notesTestSuite) TestNotesImportWithoutBitBoxAppData() {
 		&ImportNotesResult{
 			AccountCount:     2,
 			TransactionCount: 3,
+			// The note of the unknown transaction.
+			PreservedCount: 1,
 		},
 		result)
 
@@ -237,6 +241,85 @@ func (s *notesTestSuite) TestNotesImportWithoutBitBoxAppData() {
 	s.Require().Equal("My ETH", ethAcct.Config().Config.Name)
 }
 
+func (s *notesTestSuite) TestNotesImportExportAllTypes() {
+	btcAcct := s.backend.Accounts().lookup("v0-55555555-btc-0")
+	s.Require().NotNil(btcAcct)
+
+	const bitboxApp = `"bitboxapp":{"coinCode":"btc","code":"v0-55555555-btc-0"}`
+	unknownType := `{"type":"future","ref":"some-ref","label":"future label","origin":"wpkh([d34db33f/84'/0'/0'])"}`
+	unknownAddress := `{"type":"addr","ref":"bc1q34aq5drpuwy3wgl9lhup9892qp6svr8ldzyy7c","label":"Not ours"}`
+	unknownOutput := `{"type":"output","ref":"unknown-tx-id:0","label":"Not ours","spendable":false}`
+	export := strings.Join([]string{
+		`{"type":"addr","ref":"bc1qaddress","label":"Donations",` + bitboxApp + `}`,
+		`{"type":"pubkey","ref":"02pubkey","label":"Key",` + bitboxApp + `}`,
+		`{"type":"input","ref":"btc-tx-id:0","label":"Spent coins"}`,
+		`{"type":"output","ref":"btc-tx-id:1","label":"Change","spendable":true}`,
+		`{"type":"output","ref":"btc-tx-id:2","spendable":false}`,
+		`{"type":"output","ref":"btc-tx-id:3","label":"Dust","spendable":false,` + bitboxApp + `}`,
+		unknownType,
+		unknownAddress,
+		unknownOutput,
+		// Duplicate, should be preserved only once.
+		unknownType,
+	}, "\n")
+	result, err := s.backend.ImportNotes([]byte(export))
+	s.Require().NoError(err)
+	s.Require().Equal(
+		&ImportNotesResult{
+			AddressCount:   1,
+			PubkeyCount:    1,
+			InputCount:     1,
+			OutputCount:    3,
+			PreservedCount: 4,
+		},
+		result)
+
+	notesData := btcAcct.Notes().Data()
+	s.Require().Equal(map[string]string{"bc1qaddress": "Donations"}, notesData.AddressNotes)
+	s.Require().Equal(map[string]string{"02pubkey": "Key"}, notesData.PubkeyNotes)
+	s.Require().Equal(map[string]string{"btc-tx-id:0": "Spent coins"}, notesData.InputNotes)
+	s.Require().Equal(
+		map[string]string{"btc-tx-id:1": "Change", "btc-tx-id:3": "Dust"},
+		notesData.OutputNotes)
+	s.Require().True(btcAcct.Notes().OutputSpendable("btc-tx-id:1"))
+	s.Require().False(btcAcct.Notes().OutputSpendable("btc-tx-id:2"))
+	s.Require().False(btcAcct.Notes().OutputSpendable("btc-tx-id:3"))
+
+	var exported bytes.Buffer
+	s.Require().NoError(s.backend.exportNotes(&exported))
+	exportedLines := strings.Split(strings.TrimSpace(exported.String()), "\n")
+	s.Require().Subset(exportedLines, []string{
+		`{"type":"addr","ref":"bc1qaddress","label":"Donations",` + bitboxApp + `}`,
+		`{"type":"pubkey","ref":"02pubkey","label":"Key",` + bitboxApp + `}`,
+		`{"type":"input","ref":"btc-tx-id:0","label":"Spent coins",` + bitboxApp + `}`,
+		`{"type":"output","ref":"btc-tx-id:1","label":"Change",` + bitboxApp + `}`,
+		`{"type":"output","ref":"btc-tx-id:2","spendable":false,` + bitboxApp + `}`,
+		`{"type":"output","ref":"btc-tx-id:3","label":"Dust","spendable":false,` + bitboxApp + `}`,
+	})
+	// Entries which could not be applied are exported unchanged, at the end.
+	s.Require().Equal(
+		[]string{unknownType, unknownAddress, unknownOutput},
+		exportedLines[len(exportedLines)-3:])
+
+	// Importing the export again changes nothing.
+	result, err = s.backend.ImportNotes(exported.Bytes())
+	s.Require().NoError(err)
+	s.Require().Equal(&ImportNotesResult{PreservedCount: 3}, result)
+	var exportedAgain bytes.Buffer
+	s.Require().NoError(s.backend.exportNotes(&exportedAgain))
+	s.Require().Equal(exported.String(), exportedAgain.String())
+
+	// A preserved entry is dropped once it is applied.
+	_, err = s.backend.ImportNotes([]byte(
+		`{"type":"output","ref":"unknown-tx-id:0","label":"Ours after all",` + bitboxApp + `}`))
+	s.Require().NoError(err)
+	s.Require().Equal("Ours after all", btcAcct.Notes().OutputNote("unknown-tx-id:0"))
+	exported.Reset()
+	s.Require().NoError(s.backend.exportNotes(&exported))
+	s.Require().NotContains(exported.String(), unknownOutput)
+	s.Require().Contains(exported.String(), unknownAddress)
+}
+
 func (s *notesTestSuite) TestNotesInvalidLine() {
 	export := `{"type":"xpub","ref":"xpub6Cxa67Bfe1Aw5VvLM1Ppua9x28CXH1zUYoAuBzFRjR6hWnA6aUcny84KYkeVcZWnWXxKSkxCEyMA8xic54ydBPWm5oziXpsXq6nX8FELMQn","label":"My BTC","bitboxapp":{"coinCode":"btc","code":"v0-55555555-btc-0"}}
 {"type":"xpub","ref":"xpub6CC9Tsi4eJvmRsGuXwKBfHDWUWN66voNeZFmXRJhYZS6yYgXKZmtz5qnxK9WL2FZP8uF3abyFZ29d7RfMks4FjCCu4LMh3edyeCoyEFuZLZ","label":"My BTC","bitboxapp":{"coinCode":"btc","code":"v0-55555555-btc-0"}}
@@ -0,0 +1,200 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/rates"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+// maxPriceAlertHistory is the maximum number of fired price alerts kept in the history.
+const maxPriceAlertHistory = 100
+
+// priceAlertsHandler implements rates.PriceAlertsHandler using the accounts config.
+type priceAlertsHandler struct {
+	backend *Backend
+}
+
+// priceAlertFromConfig converts a stored price alert.
+func priceAlertFromConfig(alert config.PriceAlert) rates.PriceAlert {
+	return rates.PriceAlert{
+		ID:              alert.ID,
+		Coin:            alert.Coin,
+		Fiat:            alert.Fiat,
+		Type:            rates.PriceAlertType(alert.Type),
+		Threshold:       alert.Threshold,
+		WindowHours:     alert.WindowHours,
+		CooldownMinutes: alert.CooldownMinutes,
+		LastTriggered:   alert.LastTriggered,
+		Active:          alert.Active,
+	}
+}
+
+// configPriceAlert converts a price alert for storing it.
+func configPriceAlert(alert rates.PriceAlert) config.PriceAlert {
+	return config.PriceAlert{
+		ID:              alert.ID,
+		Coin:            alert.Coin,
+		Fiat:            alert.Fiat,
+		Type:            string(alert.Type),
+		Threshold:       alert.Threshold,
+		WindowHours:     alert.WindowHours,
+		CooldownMinutes: alert.CooldownMinutes,
+		LastTriggered:   alert.LastTriggered,
+		Active:          alert.Active,
+	}
+}
+
+// priceAlertTriggerFromConfig converts a stored price alert history entry.
+func priceAlertTriggerFromConfig(trigger config.PriceAlertTrigger) rates.PriceAlertTrigger {
+	return rates.PriceAlertTrigger{
+		AlertID:        trigger.AlertID,
+		Coin:           trigger.Coin,
+		Fiat:           trigger.Fiat,
+		Type:           rates.PriceAlertType(trigger.Type),
+		Threshold:      trigger.Threshold,
+		Price:          trigger.Price,
+		ReferencePrice: trigger.ReferencePrice,
+		Time:           trigger.Time,
+	}
+}
+
+// configPriceAlertTrigger converts a price alert history entry for storing it.
+func configPriceAlertTrigger(trigger rates.PriceAlertTrigger) config.PriceAlertTrigger {
+	return config.PriceAlertTrigger{
+		AlertID:        trigger.AlertID,
+		Coin:           trigger.Coin,
+		Fiat:           trigger.Fiat,
+		Type:           string(trigger.Type),
+		Threshold:      trigger.Threshold,
+		Price:          trigger.Price,
+		ReferencePrice: trigger.ReferencePrice,
+		Time:           trigger.Time,
+	}
+}
+
+// PriceAlerts implements rates.PriceAlertsHandler.
+func (handler priceAlertsHandler) PriceAlerts() []rates.PriceAlert {
+	return handler.backend.PriceAlerts()
+}
+
+// SetPriceAlertActive implements rates.PriceAlertsHandler.
+func (handler priceAlertsHandler) SetPriceAlertActive(alertID string, active bool) {
+	backend := handler.backend
+	err := backend.config.ModifyAccountsConfig(func(accountsConfig *config.AccountsConfig) error {
+		for i := range accountsConfig.PriceAlerts {
+			if accountsConfig.PriceAlerts[i].ID == alertID {
+				accountsConfig.PriceAlerts[i].Active = active
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		backend.log.WithError(err).Error("Could not store the price alert state")
+	}
+}
+
+// PriceAlertTriggered implements rates.PriceAlertsHandler.
+func (handler priceAlertsHandler) PriceAlertTriggered(trigger rates.PriceAlertTrigger) {
+	backend := handler.backend
+	err := backend.config.ModifyAccountsConfig(func(accountsConfig *config.AccountsConfig) error {
+		for i := range accountsConfig.PriceAlerts {
+			if accountsConfig.PriceAlerts[i].ID == trigger.AlertID {
+				triggered := trigger.Time
+				accountsConfig.PriceAlerts[i].LastTriggered = &triggered
+				accountsConfig.PriceAlerts[i].Active = true
+			}
+		}
+		history := append(accountsConfig.PriceAlertHistory, configPriceAlertTrigger(trigger))
+		if len(history) > maxPriceAlertHistory {
+			history = history[len(history)-maxPriceAlertHistory:]
+		}
+		accountsConfig.PriceAlertHistory = history
+		return nil
+	})
+	if err != nil {
+		backend.log.WithError(err).Error("Could not store the price alert trigger")
+	}
+	backend.log.Infof("Price alert %s triggered", trigger.AlertID)
+	backend.NotifyUser(priceAlertText(trigger))
+}
+
+// priceAlertText returns the notification text of a fired price alert, e.g. "BTC/EUR is above
+// 100000: 100512.3".
+func priceAlertText(trigger rates.PriceAlertTrigger) string {
+	pair := fmt.Sprintf("%s/%s", rates.CoinUnit(trigger.Coin), trigger.Fiat)
+	formatPrice := func(price float64) string {
+		return strconv.FormatFloat(price, 'f', -1, 64)
+	}
+	switch trigger.Type {
+	case rates.PriceAlertAbove:
+		return fmt.Sprintf("%s is above %s: %s", pair, formatPrice(trigger.Threshold), formatPrice(trigger.Price))
+	case rates.PriceAlertBelow:
+		return fmt.Sprintf("%s is below %s: %s", pair, formatPrice(trigger.Threshold), formatPrice(trigger.Price))
+	default:
+		change := (trigger.Price - trigger.ReferencePrice) / trigger.ReferencePrice * 100
+		return fmt.Sprintf("%s moved %+.1f%%: %s", pair, change, formatPrice(trigger.Price))
+	}
+}
+
+// PriceAlerts returns the configured price alerts.
+func (backend *Backend) PriceAlerts() []rates.PriceAlert {
+	result := []rates.PriceAlert{}
+	for _, alert := range backend.config.AccountsConfig().PriceAlerts {
+		result = append(result, priceAlertFromConfig(alert))
+	}
+	return result
+}
+
+// PriceAlertHistory returns the most recently fired price alerts, newest first.
+func (backend *Backend) PriceAlertHistory() []rates.PriceAlertTrigger {
+	history := backend.config.AccountsConfig().PriceAlertHistory
+	result := make([]rates.PriceAlertTrigger, len(history))
+	for i, trigger := range history {
+		result[len(history)-1-i] = priceAlertTriggerFromConfig(trigger)
+	}
+	return result
+}
+
+// AddPriceAlert validates and stores a new price alert. A new ID is assigned to it. Returns the
+// stored alert.
+func (backend *Backend) AddPriceAlert(alert rates.PriceAlert) (*rates.PriceAlert, error) {
+	if err := alert.Validate(); err != nil {
+		return nil, err
+	}
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	alert.ID = hex.EncodeToString(id[:])
+	alert.LastTriggered = nil
+	alert.Active = false
+	err := backend.config.ModifyAccountsConfig(func(accountsConfig *config.AccountsConfig) error {
+		accountsConfig.PriceAlerts = append(accountsConfig.PriceAlerts, configPriceAlert(alert))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// DeletePriceAlert deletes the price alert with the given ID.
+func (backend *Backend) DeletePriceAlert(id string) error {
+	return backend.config.ModifyAccountsConfig(func(accountsConfig *config.AccountsConfig) error {
+		alerts := accountsConfig.PriceAlerts
+		for i, alert := range alerts {
+			if alert.ID == id {
+				accountsConfig.PriceAlerts = append(alerts[:i:i], alerts[i+1:]...)
+				return nil
+			}
+		}
+		return errp.Newf("price alert %s not found", id)
+	})
+}
@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/rates"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceAlerts(t *testing.T) {
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+
+	require.Equal(t, []rates.PriceAlert{}, b.PriceAlerts())
+
+	_, err := b.AddPriceAlert(rates.PriceAlert{Coin: "btc", Fiat: "EUR", Type: rates.PriceAlertAbove})
+	require.Error(t, err)
+
+	alert, err := b.AddPriceAlert(rates.PriceAlert{
+		Coin: "btc", Fiat: "EUR", Type: rates.PriceAlertAbove, Threshold: 100000,
+	})
+	require.NoError(t, err)
+	require.Len(t, alert.ID, 16)
+	alert2, err := b.AddPriceAlert(rates.PriceAlert{
+		Coin: "eth", Fiat: "USD", Type: rates.PriceAlertChange, Threshold: 10,
+	})
+	require.NoError(t, err)
+	require.NotEqual(t, alert.ID, alert2.ID)
+	require.Equal(t, []rates.PriceAlert{*alert, *alert2}, b.PriceAlerts())
+
+	handler := priceAlertsHandler{backend: b}
+	triggered := time.Unix(1700000000, 0)
+	for i := 0; i < maxPriceAlertHistory+1; i++ {
+		handler.PriceAlertTriggered(rates.PriceAlertTrigger{
+			AlertID: alert.ID, Coin: "btc", Fiat: "EUR", Type: rates.PriceAlertAbove,
+			Threshold: 100000, Price: float64(100000 + i), Time: triggered,
+		})
+	}
+	require.Equal(t, triggered.Unix(), b.PriceAlerts()[0].LastTriggered.Unix())
+	require.True(t, b.PriceAlerts()[0].Active)
+	require.Nil(t, b.PriceAlerts()[1].LastTriggered)
+	require.False(t, b.PriceAlerts()[1].Active)
+	handler.SetPriceAlertActive(alert.ID, false)
+	require.False(t, b.PriceAlerts()[0].Active)
+	history := b.PriceAlertHistory()
+	require.Len(t, history, maxPriceAlertHistory)
+	require.Equal(t, float64(100000+maxPriceAlertHistory), history[0].Price)
+
+	require.NoError(t, b.DeletePriceAlert(alert.ID))
+	require.Error(t, b.DeletePriceAlert(alert.ID))
+	require.Equal(t, []rates.PriceAlert{*alert2}, b.PriceAlerts())
+}
+
+func TestPriceAlertText(t *testing.T) {
+	require.Equal(t, "BTC/EUR is above 100000: 100512.3", priceAlertText(rates.PriceAlertTrigger{
+		Coin: "btc", Fiat: "EUR", Type: rates.PriceAlertAbove, Threshold: 100000, Price: 100512.3,
+	}))
+	require.Equal(t, "USDT/CHF is below 0.9: 0.85", priceAlertText(rates.PriceAlertTrigger{
+		Coin: "eth-erc20-usdt", Fiat: "CHF", Type: rates.PriceAlertBelow, Threshold: 0.9, Price: 0.85,
+	}))
+	require.Equal(t, "ETH/USD moved -11.0%: 1780", priceAlertText(rates.PriceAlertTrigger{
+		Coin: "eth", Fiat: "USD", Type: rates.PriceAlertChange, Threshold: 10, Price: 1780,
+		ReferencePrice: 2000,
+	}))
+}
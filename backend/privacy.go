@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/connlog"
+)
+
+const (
+	// maxConnectionLogEntries is the number of entries kept in the connection log.
+	maxConnectionLogEntries = 1000
+	// connectionLogFilename is the file in the main directory the connection log is persisted to.
+	connectionLogFilename = "connections.jsonl"
+)
+
+// StrictPrivacy returns true if the strict privacy mode is enabled, in which only the blockchain
+// backends are contacted.
+func (backend *Backend) StrictPrivacy() bool {
+	return backend.config.AppConfig().Backend.StrictPrivacy
+}
+
+// SetStrictPrivacy enables or disables the strict privacy mode. It is enforced by the http clients
+// of all services, so it applies immediately.
+func (backend *Backend) SetStrictPrivacy(enabled bool) error {
+	backend.log.Infof("Setting strict privacy mode to %v", enabled)
+	return backend.config.ModifyAppConfig(func(appConfig *config.AppConfig) error {
+		appConfig.Backend.StrictPrivacy = enabled
+		return nil
+	})
+}
+
+// ConnectionLogURLs returns true if the URLs of the requests are recorded in the connection log.
+func (backend *Backend) ConnectionLogURLs() bool {
+	return backend.config.AppConfig().Backend.ConnectionLogURLs
+}
+
+// SetConnectionLogURLs enables or disables recording the URLs of the requests in the connection
+// log. The URLs are redacted, see `connlog.RedactURL()`.
+func (backend *Backend) SetConnectionLogURLs(enabled bool) error {
+	return backend.config.ModifyAppConfig(func(appConfig *config.AppConfig) error {
+		appConfig.Backend.ConnectionLogURLs = enabled
+		return nil
+	})
+}
+
+// ConnectionLog returns the recorded connections matching the query, the newest first.
+func (backend *Backend) ConnectionLog(query connlog.Query) []connlog.Entry {
+	return backend.connectionLog.Query(query)
+}
+
+// ExportConnectionLog exports the recorded connections matching the query to a CSV file.
+func (backend *Backend) ExportConnectionLog(query connlog.Query) error {
+	name := fmt.Sprintf("%s-bitboxapp-connections.csv", time.Now().Format("2006-01-02-at-15-04-05"))
+	return backend.exportFile(name, func(writer io.Writer) error {
+		return connlog.WriteCSV(writer, backend.connectionLog.Query(query))
+	})
+}
+
+// ClearConnectionLog removes all entries of the connection log.
+func (backend *Backend) ClearConnectionLog() error {
+	return backend.connectionLog.Clear()
+}
@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/connlog"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/socksproxy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictPrivacy(t *testing.T) {
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	get := func(client *http.Client) error {
+		response, err := client.Get(server.URL)
+		if err != nil {
+			return err
+		}
+		return response.Body.Close()
+	}
+	blocked := connlog.Query{Result: connlog.ResultBlocked}
+
+	require.False(t, b.StrictPrivacy())
+	require.NoError(t, get(b.MarketHTTPClient()))
+	require.Empty(t, b.ConnectionLog(blocked))
+
+	require.NoError(t, b.SetStrictPrivacy(true))
+	require.True(t, b.StrictPrivacy())
+	require.ErrorIs(t, get(b.MarketHTTPClient()), socksproxy.ErrBlockedByPrivacyMode)
+	require.ErrorIs(t, get(b.serviceHTTPClient(socksproxy.ServiceRates)), socksproxy.ErrBlockedByPrivacyMode)
+	require.ErrorIs(t, get(b.httpClient), socksproxy.ErrBlockedByPrivacyMode)
+	require.NoError(t, get(b.serviceHTTPClient(socksproxy.ServiceEthereum)))
+
+	entries := b.ConnectionLog(blocked)
+	require.Len(t, entries, 3)
+	require.Equal(t, string(socksproxy.ServiceMarket), entries[2].Service)
+
+	require.NoError(t, b.ClearConnectionLog())
+	require.Empty(t, b.ConnectionLog(connlog.Query{}))
+	require.NoError(t, b.SetStrictPrivacy(false))
+	require.NoError(t, get(b.MarketHTTPClient()))
+}
+
+func TestConnectionLog(t *testing.T) {
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	get := func() {
+		response, err := b.serviceHTTPClient(socksproxy.ServiceRates).Get(server.URL + "/path/0123456789abcdef0123456789abcdef?apikey=secret")
+		require.NoError(t, err)
+		require.NoError(t, response.Body.Close())
+	}
+
+	// By default, only the host is recorded.
+	require.False(t, b.ConnectionLogURLs())
+	get()
+	query := connlog.Query{Service: string(socksproxy.ServiceRates), Host: serverURL.Host}
+	entries := b.ConnectionLog(query)
+	require.Len(t, entries, 1)
+	require.Empty(t, entries[0].URL)
+	require.Equal(t, connlog.ResultOK, entries[0].Result)
+
+	require.NoError(t, b.SetConnectionLogURLs(true))
+	get()
+	entries = b.ConnectionLog(query)
+	require.Len(t, entries, 2)
+	require.Equal(t, server.URL+"/path/redacted", entries[0].URL)
+
+	// The log is persisted.
+	require.NoError(t, b.Close())
+	b = newBackendWithArguments(t, b.arguments)
+	defer b.Close()
+	require.Len(t, b.ConnectionLog(query), 2)
+}
@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/profiles"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+// SetProfiles enables wallet profiles. It is called by the app owning the backend, which restarts
+// the app with a new backend for the app directory of the selected profile in switchProfile. The
+// backend is closed when switchProfile is called.
+func (backend *Backend) SetProfiles(manager *profiles.Manager, switchProfile func(mainDirectoryPath string)) {
+	backend.profiles = manager
+	backend.switchProfile = switchProfile
+}
+
+// Profiles returns the wallet profiles, or nil if the app does not support profiles.
+func (backend *Backend) Profiles() *profiles.Manager {
+	return backend.profiles
+}
+
+// SwitchProfile checks the password of a profile and restarts the app with the profile. The switch
+// happens asynchronously after this call returns, as it closes this backend.
+func (backend *Backend) SwitchProfile(id string, password string) error {
+	if backend.profiles == nil {
+		return errp.New("profiles are not supported")
+	}
+	if id == backend.profiles.Active().ID {
+		return nil
+	}
+	mainDirectoryPath, err := backend.profiles.Select(id, password)
+	if err != nil {
+		return err
+	}
+	backend.log.Infof("Switching to profile %s", id)
+	go backend.switchProfile(mainDirectoryPath)
+	return nil
+}
@@ -0,0 +1,422 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package profiles manages isolated wallet profiles. Each profile has its own app directory with
+// its own app config, accounts config, notes and caches, so that e.g. personal and company wallets
+// can be kept strictly separate on one machine.
+//
+// All profiles, including the default profile, are stored in sibling subdirectories of `profiles/`
+// in the app directory, so that no profile directory contains another one. The data of
+// installations from before profiles existed is moved to the default profile, see
+// `migrateDefaultProfile()`.
+//
+// The password of a profile only locks the profile switcher. It does not encrypt the files of the
+// profile, which is done by the encryption at rest of the profile's own backend.
+package profiles
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	utilconfig "github.com/BitBoxSwiss/bitbox-wallet-app/util/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/locker"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// DefaultProfileID is the ID of the profile the app starts with by default. It always exists and
+	// can't be password-protected, so that the app can always start without a password.
+	DefaultProfileID = "default"
+
+	defaultProfileName = "Default"
+	// maxNameLength is the maximum length of a profile name in characters.
+	maxNameLength = 64
+
+	profilesFilename = "profiles.json"
+	profilesDirname  = "profiles"
+	// migratingSuffix is appended to the directory of the default profile while the data of the app
+	// directory is moved into it, so that an interrupted migration is continued on the next start.
+	migratingSuffix = ".migrating"
+
+	// scrypt parameters of the password hashes.
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+const (
+	// ErrProfileNotFound is returned if there is no profile with the given ID.
+	ErrProfileNotFound errp.ErrorCode = "profileNotFound"
+	// ErrWrongPassword is returned if the password of a password-protected profile is wrong.
+	ErrWrongPassword errp.ErrorCode = "wrongPassword"
+	// ErrInvalidName is returned if a profile name is empty or too long.
+	ErrInvalidName errp.ErrorCode = "invalidName"
+	// ErrNameExists is returned if another profile has the same name.
+	ErrNameExists errp.ErrorCode = "nameExists"
+	// ErrProfileActive is returned when deleting the active profile.
+	ErrProfileActive errp.ErrorCode = "profileActive"
+	// ErrDefaultProfile is returned when deleting or password-protecting the default profile.
+	ErrDefaultProfile errp.ErrorCode = "defaultProfile"
+)
+
+// Profile is a wallet profile.
+type Profile struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	// SwitcherLocked is true if the password is required to switch to or delete the profile. The
+	// password only locks the profile switcher, it does not encrypt the data of the profile.
+	SwitcherLocked bool `json:"switcherLocked"`
+}
+
+// passwordHash is the scrypt hash of a profile password.
+type passwordHash struct {
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt []byte `json:"salt"`
+	Hash []byte `json:"hash"`
+}
+
+func newPasswordHash(password string) (*passwordHash, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	hash, err := scrypt.Key([]byte(password), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return &passwordHash{N: scryptN, R: scryptR, P: scryptP, Salt: salt, Hash: hash}, nil
+}
+
+func (hash *passwordHash) verify(password string) bool {
+	computed, err := scrypt.Key([]byte(password), hash.Salt, hash.N, hash.R, hash.P, len(hash.Hash))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(computed, hash.Hash) == 1
+}
+
+// storedProfile is a profile as stored in the profiles file.
+type storedProfile struct {
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Created  time.Time     `json:"created"`
+	Password *passwordHash `json:"password,omitempty"`
+}
+
+func (profile *storedProfile) profile() Profile {
+	return Profile{
+		ID:             profile.ID,
+		Name:           profile.Name,
+		Created:        profile.Created,
+		SwitcherLocked: profile.Password != nil,
+	}
+}
+
+// checkPassword returns ErrWrongPassword if the profile is password-protected and the password is
+// wrong.
+func (profile *storedProfile) checkPassword(password string) error {
+	if profile.Password != nil && !profile.Password.verify(password) {
+		return errp.WithStack(ErrWrongPassword)
+	}
+	return nil
+}
+
+// profilesFile is the contents of the profiles file.
+type profilesFile struct {
+	// Active is the ID of the active profile.
+	Active   string           `json:"active"`
+	Profiles []*storedProfile `json:"profiles"`
+}
+
+// Manager manages the profiles in an app directory. It is shared by the backends of all profiles.
+type Manager struct {
+	appDir string
+	data   profilesFile
+	lock   locker.Locker
+}
+
+// legacyEntries are the files and directories of the app data which were stored in the app
+// directory itself before profiles existed.
+var legacyEntries = []string{
+	"config.json",
+	"accounts.json",
+	"encryption.json",
+	"connections.jsonl",
+	"notifier.db",
+	"bitbox02",
+	"cache",
+	"notes",
+}
+
+// migrateDefaultProfile moves the app data stored in the app directory itself to the directory of
+// the default profile, if the directory of the default profile does not exist yet.
+func migrateDefaultProfile(appDir string) error {
+	directory := filepath.Join(appDir, profilesDirname, DefaultProfileID)
+	if _, err := os.Stat(directory); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return errp.WithStack(err)
+	}
+	migrating := directory + migratingSuffix
+	if err := utilconfig.EnsurePrivateDir(migrating); err != nil {
+		return err
+	}
+	for _, name := range legacyEntries {
+		source := filepath.Join(appDir, name)
+		if _, err := os.Lstat(source); os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return errp.WithStack(err)
+		}
+		if err := os.Rename(source, filepath.Join(migrating, name)); err != nil {
+			return errp.WithStack(err)
+		}
+	}
+	return errp.WithStack(os.Rename(migrating, directory))
+}
+
+// NewManager loads the profiles of the given app directory. App data stored in the app directory
+// itself by previous versions is moved to the default profile.
+func NewManager(appDir string) (*Manager, error) {
+	if err := migrateDefaultProfile(appDir); err != nil {
+		return nil, errp.WithMessage(err, "failed to migrate the default profile")
+	}
+	manager := &Manager{appDir: appDir}
+	jsonBytes, err := os.ReadFile(filepath.Join(appDir, profilesFilename))
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, errp.WithStack(err)
+	default:
+		if err := json.Unmarshal(jsonBytes, &manager.data); err != nil {
+			return nil, errp.WithMessage(err, "invalid profiles file")
+		}
+	}
+	if manager.lookup(DefaultProfileID) == nil {
+		manager.data.Profiles = append([]*storedProfile{{
+			ID:      DefaultProfileID,
+			Name:    defaultProfileName,
+			Created: time.Now(),
+		}}, manager.data.Profiles...)
+	}
+	if manager.lookup(manager.data.Active) == nil {
+		manager.data.Active = DefaultProfileID
+	}
+	return manager, nil
+}
+
+func (manager *Manager) lookup(id string) *storedProfile {
+	for _, profile := range manager.data.Profiles {
+		if profile.ID == id {
+			return profile
+		}
+	}
+	return nil
+}
+
+func (manager *Manager) get(id string) (*storedProfile, error) {
+	profile := manager.lookup(id)
+	if profile == nil {
+		return nil, errp.WithStack(ErrProfileNotFound)
+	}
+	return profile, nil
+}
+
+func (manager *Manager) save() error {
+	if err := utilconfig.EnsurePrivateDir(manager.appDir); err != nil {
+		return err
+	}
+	jsonBytes, err := json.MarshalIndent(manager.data, "", "    ")
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	filename := filepath.Join(manager.appDir, profilesFilename)
+	if err := os.WriteFile(filename, jsonBytes, utilconfig.PrivateFileMode); err != nil {
+		return errp.WithStack(err)
+	}
+	return utilconfig.EnsurePrivateFile(filename)
+}
+
+// validateName returns the trimmed name, or an error if it is invalid or used by another profile.
+func (manager *Manager) validateName(name string, id string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+		return "", errp.WithStack(ErrInvalidName)
+	}
+	for _, profile := range manager.data.Profiles {
+		if profile.ID != id && strings.EqualFold(profile.Name, name) {
+			return "", errp.WithStack(ErrNameExists)
+		}
+	}
+	return name, nil
+}
+
+// Profiles returns all profiles, the default profile first.
+func (manager *Manager) Profiles() []Profile {
+	defer manager.lock.RLock()()
+	result := make([]Profile, len(manager.data.Profiles))
+	for i, profile := range manager.data.Profiles {
+		result[i] = profile.profile()
+	}
+	return result
+}
+
+// Active returns the active profile, i.e. the profile of the running backend.
+func (manager *Manager) Active() Profile {
+	defer manager.lock.RLock()()
+	return manager.lookup(manager.data.Active).profile()
+}
+
+// Directory returns the app directory of the profile with the given ID.
+func (manager *Manager) Directory(id string) (string, error) {
+	defer manager.lock.RLock()()
+	if _, err := manager.get(id); err != nil {
+		return "", err
+	}
+	return manager.directory(id), nil
+}
+
+func (manager *Manager) directory(id string) string {
+	return filepath.Join(manager.appDir, profilesDirname, id)
+}
+
+// Startup returns the app directory of the profile to start the app with, and makes it the active
+// profile. This is the last active profile, or the default profile if the last active profile is
+// password-protected, as the password has to be entered again each time the app starts.
+func (manager *Manager) Startup() (string, error) {
+	defer manager.lock.Lock()()
+	if manager.lookup(manager.data.Active).Password != nil {
+		manager.data.Active = DefaultProfileID
+		if err := manager.save(); err != nil {
+			return "", err
+		}
+	}
+	return manager.directory(manager.data.Active), nil
+}
+
+// Create creates a new profile with an empty app directory. If the password is not empty, the
+// profile switcher is locked for the profile.
+func (manager *Manager) Create(name string, password string) (*Profile, error) {
+	defer manager.lock.Lock()()
+	name, err := manager.validateName(name, "")
+	if err != nil {
+		return nil, err
+	}
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	profile := &storedProfile{
+		ID:      hex.EncodeToString(id[:]),
+		Name:    name,
+		Created: time.Now(),
+	}
+	if password != "" {
+		if profile.Password, err = newPasswordHash(password); err != nil {
+			return nil, err
+		}
+	}
+	if err := utilconfig.EnsurePrivateDir(manager.directory(profile.ID)); err != nil {
+		return nil, err
+	}
+	manager.data.Profiles = append(manager.data.Profiles, profile)
+	if err := manager.save(); err != nil {
+		return nil, err
+	}
+	result := profile.profile()
+	return &result, nil
+}
+
+// Rename renames a profile.
+func (manager *Manager) Rename(id string, name string) error {
+	defer manager.lock.Lock()()
+	profile, err := manager.get(id)
+	if err != nil {
+		return err
+	}
+	if profile.Name, err = manager.validateName(name, id); err != nil {
+		return err
+	}
+	return manager.save()
+}
+
+// SetPassword changes the password of a profile. password is the current password, if the profile
+// is password-protected. An empty newPassword removes the password protection.
+func (manager *Manager) SetPassword(id string, password string, newPassword string) error {
+	defer manager.lock.Lock()()
+	profile, err := manager.get(id)
+	if err != nil {
+		return err
+	}
+	if id == DefaultProfileID {
+		return errp.WithStack(ErrDefaultProfile)
+	}
+	if err := profile.checkPassword(password); err != nil {
+		return err
+	}
+	profile.Password = nil
+	if newPassword != "" {
+		if profile.Password, err = newPasswordHash(newPassword); err != nil {
+			return err
+		}
+	}
+	return manager.save()
+}
+
+// Delete deletes a profile with its app directory, including its config, accounts and notes. The
+// default profile and the active profile can't be deleted.
+func (manager *Manager) Delete(id string, password string) error {
+	defer manager.lock.Lock()()
+	profile, err := manager.get(id)
+	if err != nil {
+		return err
+	}
+	if id == DefaultProfileID {
+		return errp.WithStack(ErrDefaultProfile)
+	}
+	if id == manager.data.Active {
+		return errp.WithStack(ErrProfileActive)
+	}
+	if err := profile.checkPassword(password); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(manager.directory(id)); err != nil {
+		return errp.WithStack(err)
+	}
+	for i, existing := range manager.data.Profiles {
+		if existing == profile {
+			manager.data.Profiles = append(manager.data.Profiles[:i], manager.data.Profiles[i+1:]...)
+			break
+		}
+	}
+	return manager.save()
+}
+
+// Select checks the password of a profile and makes it the active profile. Returns the app
+// directory of the profile, with which the backend has to be restarted.
+func (manager *Manager) Select(id string, password string) (string, error) {
+	defer manager.lock.Lock()()
+	profile, err := manager.get(id)
+	if err != nil {
+		return "", err
+	}
+	if err := profile.checkPassword(password); err != nil {
+		return "", err
+	}
+	manager.data.Active = id
+	if err := manager.save(); err != nil {
+		return "", err
+	}
+	return manager.directory(id), nil
+}
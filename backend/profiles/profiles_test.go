@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package profiles
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager(t *testing.T) {
+	appDir := test.TstTempDir("profiles")
+	defer func() { _ = os.RemoveAll(appDir) }()
+
+	manager, err := NewManager(appDir)
+	require.NoError(t, err)
+	require.Len(t, manager.Profiles(), 1)
+	require.Equal(t, DefaultProfileID, manager.Active().ID)
+	directory, err := manager.Directory(DefaultProfileID)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(appDir, "profiles", DefaultProfileID), directory)
+
+	_, err = manager.Create(" ", "")
+	require.Equal(t, ErrInvalidName, errp.Cause(err))
+	_, err = manager.Create("default", "")
+	require.Equal(t, ErrNameExists, errp.Cause(err))
+
+	personal, err := manager.Create(" Personal ", "")
+	require.NoError(t, err)
+	require.Equal(t, "Personal", personal.Name)
+	require.False(t, personal.SwitcherLocked)
+	company, err := manager.Create("Company", "secret")
+	require.NoError(t, err)
+	require.True(t, company.SwitcherLocked)
+
+	personalDir, err := manager.Directory(personal.ID)
+	require.NoError(t, err)
+	require.DirExists(t, personalDir)
+	companyDir, err := manager.Directory(company.ID)
+	require.NoError(t, err)
+	require.NotEqual(t, personalDir, companyDir)
+	_, err = manager.Directory("unknown")
+	require.Equal(t, ErrProfileNotFound, errp.Cause(err))
+
+	require.Equal(t, ErrNameExists, errp.Cause(manager.Rename(personal.ID, "company")))
+	require.NoError(t, manager.Rename(personal.ID, "Private"))
+
+	// Switching to a password-protected profile requires the password.
+	_, err = manager.Select(company.ID, "wrong")
+	require.Equal(t, ErrWrongPassword, errp.Cause(err))
+	require.Equal(t, DefaultProfileID, manager.Active().ID)
+	directory, err = manager.Select(company.ID, "secret")
+	require.NoError(t, err)
+	require.Equal(t, companyDir, directory)
+	require.Equal(t, company.ID, manager.Active().ID)
+	require.Equal(t, ErrProfileActive, errp.Cause(manager.Delete(company.ID, "secret")))
+
+	// The profiles are persisted.
+	manager, err = NewManager(appDir)
+	require.NoError(t, err)
+	require.Equal(t, company.ID, manager.Active().ID)
+	require.Equal(t,
+		[]string{"Default", "Private", "Company"},
+		func() []string {
+			names := []string{}
+			for _, profile := range manager.Profiles() {
+				names = append(names, profile.Name)
+			}
+			return names
+		}())
+
+	// Password-protected profiles are not started without the password.
+	directory, err = manager.Startup()
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(appDir, "profiles", DefaultProfileID), directory)
+	require.Equal(t, DefaultProfileID, manager.Active().ID)
+	_, err = manager.Select(personal.ID, "")
+	require.NoError(t, err)
+	directory, err = manager.Startup()
+	require.NoError(t, err)
+	require.Equal(t, personalDir, directory)
+
+	// Passwords.
+	require.Equal(t, ErrDefaultProfile, errp.Cause(manager.SetPassword(DefaultProfileID, "", "secret")))
+	require.Equal(t, ErrWrongPassword, errp.Cause(manager.SetPassword(company.ID, "wrong", "")))
+	require.NoError(t, manager.SetPassword(company.ID, "secret", ""))
+	_, err = manager.Select(company.ID, "")
+	require.NoError(t, err)
+	require.NoError(t, manager.SetPassword(personal.ID, "", "new secret"))
+	require.True(t, manager.Profiles()[1].SwitcherLocked)
+
+	// Deletion.
+	require.Equal(t, ErrDefaultProfile, errp.Cause(manager.Delete(DefaultProfileID, "")))
+	require.Equal(t, ErrWrongPassword, errp.Cause(manager.Delete(personal.ID, "")))
+	require.NoError(t, os.WriteFile(filepath.Join(personalDir, "config.json"), []byte("{}"), 0600))
+	require.NoError(t, manager.Delete(personal.ID, "new secret"))
+	require.NoDirExists(t, personalDir)
+	require.Len(t, manager.Profiles(), 2)
+	require.Equal(t, ErrProfileNotFound, errp.Cause(manager.Delete(personal.ID, "")))
+}
+
+func TestMigrateDefaultProfile(t *testing.T) {
+	appDir := test.TstTempDir("profiles-migration")
+	defer func() { _ = os.RemoveAll(appDir) }()
+
+	require.NoError(t, os.WriteFile(filepath.Join(appDir, "config.json"), []byte("{}"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(appDir, "log.txt"), nil, 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(appDir, "notes"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(appDir, "notes", "notes.json"), []byte("{}"), 0600))
+	// An interrupted migration is continued.
+	migrating := filepath.Join(appDir, "profiles", DefaultProfileID+migratingSuffix)
+	require.NoError(t, os.MkdirAll(migrating, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(migrating, "accounts.json"), []byte("{}"), 0600))
+
+	manager, err := NewManager(appDir)
+	require.NoError(t, err)
+	directory, err := manager.Directory(DefaultProfileID)
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(directory, "config.json"))
+	require.FileExists(t, filepath.Join(directory, "accounts.json"))
+	require.FileExists(t, filepath.Join(directory, "notes", "notes.json"))
+	require.NoFileExists(t, filepath.Join(appDir, "config.json"))
+	require.NoDirExists(t, filepath.Join(appDir, "notes"))
+	require.NoDirExists(t, migrating)
+	// The log is shared by all profiles.
+	require.FileExists(t, filepath.Join(appDir, "log.txt"))
+
+	// Once migrated, the app directory is not touched again.
+	require.NoError(t, os.WriteFile(filepath.Join(appDir, "config.json"), []byte("{}"), 0600))
+	_, err = NewManager(appDir)
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(appDir, "config.json"))
+}
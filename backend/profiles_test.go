@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/profiles"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwitchProfile(t *testing.T) {
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+
+	require.Nil(t, b.Profiles())
+	require.Error(t, b.SwitchProfile(profiles.DefaultProfileID, ""))
+
+	manager, err := profiles.NewManager(test.TstTempDir("profiles"))
+	require.NoError(t, err)
+	switched := make(chan string, 1)
+	b.SetProfiles(manager, func(mainDirectoryPath string) {
+		switched <- mainDirectoryPath
+	})
+	require.Equal(t, manager, b.Profiles())
+
+	company, err := manager.Create("Company", "secret")
+	require.NoError(t, err)
+	companyDir, err := manager.Directory(company.ID)
+	require.NoError(t, err)
+
+	// Switching to the active profile does nothing.
+	require.NoError(t, b.SwitchProfile(profiles.DefaultProfileID, ""))
+	require.Equal(t, profiles.ErrProfileNotFound, errp.Cause(b.SwitchProfile("unknown", "")))
+	require.Equal(t, profiles.ErrWrongPassword, errp.Cause(b.SwitchProfile(company.ID, "wrong")))
+	require.Empty(t, switched)
+
+	require.NoError(t, b.SwitchProfile(company.ID, "secret"))
+	select {
+	case mainDirectoryPath := <-switched:
+		require.Equal(t, companyDir, mainDirectoryPath)
+	case <-time.After(time.Second):
+		require.Fail(t, "profile not switched")
+	}
+	require.Equal(t, company.ID, manager.Active().ID)
+}
@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package rates
+
+import (
+	"math"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+// PriceAlertType is the condition of a price alert.
+type PriceAlertType string
+
+const (
+	// PriceAlertAbove triggers if the price is above the threshold.
+	PriceAlertAbove PriceAlertType = "above"
+	// PriceAlertBelow triggers if the price is below the threshold.
+	PriceAlertBelow PriceAlertType = "below"
+	// PriceAlertChange triggers if the price moved by more than the threshold, in percent, within the
+	// window.
+	PriceAlertChange PriceAlertType = "change"
+)
+
+const (
+	defaultPriceAlertWindow   = 24 * time.Hour
+	defaultPriceAlertCooldown = time.Hour
+)
+
+// PriceAlert is a user-defined condition on the latest price of a coin/fiat pair, e.g. "BTC/EUR
+// above 100000" or "ETH/USD moved more than 10% in 24h".
+type PriceAlert struct {
+	ID string `json:"id"`
+	// Coin is the backend coin code, e.g. "btc" or "eth-erc20-usdt", like in ReconfigureHistory.
+	Coin string         `json:"coin"`
+	Fiat string         `json:"fiat"`
+	Type PriceAlertType `json:"type"`
+	// Threshold is the price for PriceAlertAbove and PriceAlertBelow, and the change in percent for
+	// PriceAlertChange.
+	Threshold float64 `json:"threshold"`
+	// WindowHours is the period over which the change of PriceAlertChange is measured. 0 means 24
+	// hours. The historical rates of the pair must be available, i.e. the pair must be active.
+	WindowHours int `json:"windowHours,omitempty"`
+	// CooldownMinutes is the minimum time between two notifications of this alert. 0 means one hour.
+	CooldownMinutes int `json:"cooldownMinutes,omitempty"`
+	// LastTriggered is the last time a notification was fired for this alert.
+	LastTriggered *time.Time `json:"lastTriggered,omitempty"`
+	// Active is true if the condition of the alert held at the last evaluation. It is persisted
+	// so that the alert does not fire again for the same crossing after a restart.
+	Active bool `json:"active,omitempty"`
+}
+
+// PriceAlertTrigger is an entry in the history of fired price alerts.
+type PriceAlertTrigger struct {
+	AlertID   string         `json:"alertID"`
+	Coin      string         `json:"coin"`
+	Fiat      string         `json:"fiat"`
+	Type      PriceAlertType `json:"type"`
+	Threshold float64        `json:"threshold"`
+	// Price is the latest price which triggered the alert.
+	Price float64 `json:"price"`
+	// ReferencePrice is the price at the start of the window of a PriceAlertChange alert.
+	ReferencePrice float64   `json:"referencePrice,omitempty"`
+	Time           time.Time `json:"time"`
+}
+
+// PriceAlertsHandler provides the configured price alerts to the updater and is called when one of
+// them triggers.
+type PriceAlertsHandler interface {
+	PriceAlerts() []PriceAlert
+	// SetPriceAlertActive is expected to persist the Active state of the alert.
+	SetPriceAlertActive(alertID string, active bool)
+	// PriceAlertTriggered is expected to notify the user, to persist trigger.Time as the
+	// LastTriggered time of the alert and to persist the alert as active.
+	PriceAlertTriggered(trigger PriceAlertTrigger)
+}
+
+// Validate returns an error if the alert is not supported.
+func (alert *PriceAlert) Validate() error {
+	if geckoCoin[alert.Coin] == "" {
+		return errp.Newf("unsupported coin %q", alert.Coin)
+	}
+	if toGeckoFiat[alert.Fiat] == "" {
+		return errp.Newf("unsupported fiat %q", alert.Fiat)
+	}
+	switch alert.Type {
+	case PriceAlertAbove, PriceAlertBelow, PriceAlertChange:
+	default:
+		return errp.Newf("unsupported alert type %q", alert.Type)
+	}
+	if math.IsNaN(alert.Threshold) || math.IsInf(alert.Threshold, 0) || alert.Threshold <= 0 {
+		return errp.New("the threshold must be positive")
+	}
+	if alert.WindowHours < 0 || alert.CooldownMinutes < 0 {
+		return errp.New("the window and cooldown must not be negative")
+	}
+	return nil
+}
+
+func (alert *PriceAlert) window() time.Duration {
+	if alert.WindowHours == 0 {
+		return defaultPriceAlertWindow
+	}
+	return time.Duration(alert.WindowHours) * time.Hour
+}
+
+func (alert *PriceAlert) cooldown() time.Duration {
+	if alert.CooldownMinutes == 0 {
+		return defaultPriceAlertCooldown
+	}
+	return time.Duration(alert.CooldownMinutes) * time.Minute
+}
+
+// SetPriceAlertsHandler sets the handler of the price alerts, which are evaluated every time the
+// latest rates are updated.
+func (updater *RateUpdater) SetPriceAlertsHandler(handler PriceAlertsHandler) {
+	updater.alertsMu.Lock()
+	defer updater.alertsMu.Unlock()
+	updater.alertsHandler = handler
+}
+
+// evaluatePriceAlerts checks all price alerts against the latest rates. An alert fires when its
+// condition starts to hold, unless it fired within its cooldown. It fires again only after the
+// condition stopped holding in between, so that e.g. "above 100k" notifies once per crossing instead
+// of on every update.
+func (updater *RateUpdater) evaluatePriceAlerts(latest map[string]map[string]float64, now time.Time) {
+	updater.alertsMu.Lock()
+	defer updater.alertsMu.Unlock()
+	if updater.alertsHandler == nil {
+		return
+	}
+	for _, alert := range updater.alertsHandler.PriceAlerts() {
+		price := latest[CoinUnit(alert.Coin)][alert.Fiat]
+		if price == 0 {
+			continue
+		}
+		var reference float64
+		var active bool
+		switch alert.Type {
+		case PriceAlertAbove:
+			active = price > alert.Threshold
+		case PriceAlertBelow:
+			active = price < alert.Threshold
+		case PriceAlertChange:
+			reference = updater.HistoricalPriceAt(alert.Coin, alert.Fiat, now.Add(-alert.window()))
+			if reference == 0 {
+				// No historical rates available, can't evaluate.
+				continue
+			}
+			active = math.Abs(price-reference)/reference*100 >= alert.Threshold
+		}
+		if active == alert.Active {
+			continue
+		}
+		if !active || (alert.LastTriggered != nil && now.Sub(*alert.LastTriggered) < alert.cooldown()) {
+			updater.alertsHandler.SetPriceAlertActive(alert.ID, active)
+			continue
+		}
+		updater.alertsHandler.PriceAlertTriggered(PriceAlertTrigger{
+			AlertID:        alert.ID,
+			Coin:           alert.Coin,
+			Fiat:           alert.Fiat,
+			Type:           alert.Type,
+			Threshold:      alert.Threshold,
+			Price:          price,
+			ReferencePrice: reference,
+			Time:           now,
+		})
+	}
+}
@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package rates
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type priceAlertsHandlerMock struct {
+	alerts   []PriceAlert
+	triggers []PriceAlertTrigger
+}
+
+func (handler *priceAlertsHandlerMock) PriceAlerts() []PriceAlert {
+	return handler.alerts
+}
+
+func (handler *priceAlertsHandlerMock) SetPriceAlertActive(alertID string, active bool) {
+	for i := range handler.alerts {
+		if handler.alerts[i].ID == alertID {
+			handler.alerts[i].Active = active
+		}
+	}
+}
+
+func (handler *priceAlertsHandlerMock) PriceAlertTriggered(trigger PriceAlertTrigger) {
+	handler.triggers = append(handler.triggers, trigger)
+	for i := range handler.alerts {
+		if handler.alerts[i].ID == trigger.AlertID {
+			handler.alerts[i].LastTriggered = &trigger.Time
+			handler.alerts[i].Active = true
+		}
+	}
+}
+
+func TestPriceAlertValidate(t *testing.T) {
+	valid := PriceAlert{Coin: "btc", Fiat: "EUR", Type: PriceAlertAbove, Threshold: 100000}
+	require.NoError(t, valid.Validate())
+
+	for name, modify := range map[string]func(*PriceAlert){
+		"coin":      func(alert *PriceAlert) { alert.Coin = "BTC" },
+		"fiat":      func(alert *PriceAlert) { alert.Fiat = "XYZ" },
+		"type":      func(alert *PriceAlert) { alert.Type = "crossing" },
+		"threshold": func(alert *PriceAlert) { alert.Threshold = 0 },
+		"window":    func(alert *PriceAlert) { alert.WindowHours = -1 },
+		"cooldown":  func(alert *PriceAlert) { alert.CooldownMinutes = -1 },
+	} {
+		alert := valid
+		modify(&alert)
+		require.Error(t, alert.Validate(), name)
+	}
+}
+
+func TestEvaluatePriceAlerts(t *testing.T) {
+	updater := NewRateUpdater(nil, "/dev/null")
+	defer updater.Stop()
+	handler := &priceAlertsHandlerMock{
+		alerts: []PriceAlert{
+			{ID: "above", Coin: "btc", Fiat: "EUR", Type: PriceAlertAbove, Threshold: 100000},
+			{ID: "below", Coin: "eth", Fiat: "USD", Type: PriceAlertBelow, Threshold: 2000, CooldownMinutes: 1},
+		},
+	}
+	updater.SetPriceAlertsHandler(handler)
+	now := time.Unix(1700000000, 0)
+	evaluate := func(btcEUR, ethUSD float64) {
+		updater.evaluatePriceAlerts(map[string]map[string]float64{
+			"BTC": {"EUR": btcEUR},
+			"ETH": {"USD": ethUSD},
+		}, now)
+		now = now.Add(time.Minute)
+	}
+
+	evaluate(99000, 2100)
+	require.Empty(t, handler.triggers)
+
+	evaluate(100500, 2100)
+	require.Len(t, handler.triggers, 1)
+	require.Equal(t, PriceAlertTrigger{
+		AlertID:   "above",
+		Coin:      "btc",
+		Fiat:      "EUR",
+		Type:      PriceAlertAbove,
+		Threshold: 100000,
+		Price:     100500,
+		Time:      time.Unix(1700000060, 0),
+	}, handler.triggers[0])
+
+	// Fires only once while the condition holds.
+	evaluate(101000, 2100)
+	require.Len(t, handler.triggers, 1)
+
+	// Crossing the threshold again within the cooldown does not fire.
+	evaluate(99000, 2100)
+	evaluate(100500, 2100)
+	require.Len(t, handler.triggers, 1)
+
+	// After the cooldown, the next crossing fires again.
+	now = now.Add(time.Hour)
+	evaluate(99000, 2100)
+	evaluate(100500, 2100)
+	require.Len(t, handler.triggers, 2)
+
+	// The state is kept by the handler, so the alert does not fire again after a restart, even
+	// after the cooldown.
+	restarted := NewRateUpdater(nil, "/dev/null")
+	defer restarted.Stop()
+	restarted.SetPriceAlertsHandler(handler)
+	now = now.Add(2 * time.Hour)
+	restarted.evaluatePriceAlerts(map[string]map[string]float64{"BTC": {"EUR": 101000}}, now)
+	require.Len(t, handler.triggers, 2)
+
+	// Custom cooldown.
+	evaluate(100500, 1900)
+	evaluate(100500, 2100)
+	evaluate(100500, 1900)
+	require.Len(t, handler.triggers, 4)
+	require.Equal(t, "below", handler.triggers[3].AlertID)
+
+	// Missing prices are ignored.
+	evaluate(0, 0)
+	require.Len(t, handler.triggers, 4)
+}
+
+func TestEvaluatePriceAlertsChange(t *testing.T) {
+	updater := NewRateUpdater(nil, "/dev/null")
+	defer updater.Stop()
+	now := time.Unix(1700000000, 0)
+	updater.history = map[string][]exchangeRate{
+		"ethUSD": {
+			{value: 2000, timestamp: now.Add(-25 * time.Hour)},
+			{value: 2000, timestamp: now.Add(-23 * time.Hour)},
+		},
+	}
+	handler := &priceAlertsHandlerMock{
+		alerts: []PriceAlert{
+			{ID: "eth", Coin: "eth", Fiat: "USD", Type: PriceAlertChange, Threshold: 10},
+			// No historical rates of this pair.
+			{ID: "btc", Coin: "btc", Fiat: "USD", Type: PriceAlertChange, Threshold: 10},
+		},
+	}
+	updater.SetPriceAlertsHandler(handler)
+
+	updater.evaluatePriceAlerts(map[string]map[string]float64{"ETH": {"USD": 2150}, "BTC": {"USD": 1}}, now)
+	require.Empty(t, handler.triggers)
+
+	updater.evaluatePriceAlerts(map[string]map[string]float64{"ETH": {"USD": 1780}, "BTC": {"USD": 1}}, now)
+	require.Len(t, handler.triggers, 1)
+	require.Equal(t, "eth", handler.triggers[0].AlertID)
+	require.Equal(t, 1780.0, handler.triggers[0].Price)
+	require.Equal(t, 2000.0, handler.triggers[0].ReferencePrice)
+}
@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// See https://www.bitstamp.net/api/ for docs and details.
+	bitstampAPIV2 = "https://www.bitstamp.net/api/v2"
+	// bitstampMaxCandles is the maximum number of OHLC entries returned per request.
+	bitstampMaxCandles = 1000
+	// bitstampHourlyRange is the maximum range for which hourly OHLC entries are fetched, like the
+	// hourly timeseries of CoinGecko. Daily entries are fetched for larger ranges.
+	bitstampHourlyRange = 90 * 24 * time.Hour
+)
+
+// bitstampProvider fetches the latest rates using the ticker and historical rates using the OHLC
+// API of the Bitstamp exchange. Only pairs traded on Bitstamp are supported.
+type bitstampProvider struct {
+	httpClient *http.Client
+	apiURL     string
+	// Bitstamp allows 400 requests per second, we use way less.
+	limiter *rate.Limiter
+}
+
+func newBitstampProvider(client *http.Client) *bitstampProvider {
+	return &bitstampProvider{
+		httpClient: client,
+		apiURL:     bitstampAPIV2,
+		limiter:    rate.NewLimiter(rate.Limit(1), 1),
+	}
+}
+
+func (p *bitstampProvider) name() string {
+	return ProviderBitstamp
+}
+
+// get makes a GET request to the Bitstamp API and decodes the JSON response into result.
+func (p *bitstampProvider) get(ctx context.Context, endpoint string, result interface{}) error {
+	if p.httpClient == nil {
+		return errp.New("no http client")
+	}
+	if err := p.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodGet, p.apiURL+endpoint, nil)
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	res, err := p.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close() //nolint:errcheck
+	if res.StatusCode != http.StatusOK {
+		return errp.Newf("bitstamp: bad response code %d", res.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(result)
+}
+
+// bitstampFiats are the fiat currencies which can be traded against coins on Bitstamp.
+var bitstampFiats = map[string]bool{
+	USD.String(): true,
+	EUR.String(): true,
+	GBP.String(): true,
+	BTC.String(): true,
+}
+
+func (p *bitstampProvider) latestPrices(ctx context.Context) (map[string]map[string]float64, error) {
+	var tickers []struct {
+		Pair string `json:"pair"`
+		Last string `json:"last"`
+	}
+	if err := p.get(ctx, "/ticker/", &tickers); err != nil {
+		return nil, err
+	}
+	units := map[string]bool{}
+	for _, unit := range geckoCoinToUnit {
+		units[unit] = true
+	}
+	rates := map[string]map[string]float64{}
+	for _, ticker := range tickers {
+		coinUnit, fiat, found := strings.Cut(ticker.Pair, "/")
+		if !found || !units[coinUnit] || !bitstampFiats[fiat] {
+			continue
+		}
+		value, err := strconv.ParseFloat(ticker.Last, 64)
+		if err != nil {
+			continue
+		}
+		if rates[coinUnit] == nil {
+			rates[coinUnit] = map[string]float64{}
+		}
+		rates[coinUnit][fiat] = value
+	}
+	return rates, nil
+}
+
+// bitstampPair returns the Bitstamp currency pair, e.g. "btcusd", of the given backend coin code and
+// fiat.
+func bitstampPair(coin, fiat string) (string, error) {
+	coinUnit := CoinUnit(coin)
+	if coinUnit == "" {
+		return "", fmt.Errorf("bitstamp: unsupported coin %s", coin)
+	}
+	if fiat == SAT.String() {
+		fiat = BTC.String()
+	}
+	if !bitstampFiats[fiat] {
+		return "", fmt.Errorf("bitstamp: unsupported fiat %s", fiat)
+	}
+	return strings.ToLower(coinUnit + fiat), nil
+}
+
+func (p *bitstampProvider) historicalPrices(
+	ctx context.Context, coin, fiat string, timeRange fetchTimeRange) ([]exchangeRate, error) {
+	pair, err := bitstampPair(coin, fiat)
+	if err != nil {
+		return nil, err
+	}
+	start := timeRange.start
+	end := timeRange.end()
+	step := time.Hour
+	if end.Sub(start) > bitstampHourlyRange {
+		step = 24 * time.Hour
+	}
+
+	rates := []exchangeRate{}
+	for start.Before(end) {
+		chunkEnd := start.Add(bitstampMaxCandles * step)
+		if chunkEnd.After(end) {
+			chunkEnd = end
+		}
+		param := url.Values{
+			"step":  {strconv.Itoa(int(step.Seconds()))},
+			"limit": {strconv.Itoa(bitstampMaxCandles)},
+			"start": {strconv.FormatInt(start.Unix(), 10)},
+			"end":   {strconv.FormatInt(chunkEnd.Unix(), 10)},
+		}
+		var jsonBody struct {
+			Data struct {
+				OHLC []struct {
+					Timestamp string `json:"timestamp"`
+					Close     string `json:"close"`
+				} `json:"ohlc"`
+			} `json:"data"`
+		}
+		if err := p.get(ctx, fmt.Sprintf("/ohlc/%s/?%s", pair, param.Encode()), &jsonBody); err != nil {
+			return nil, err
+		}
+		for _, candle := range jsonBody.Data.OHLC {
+			timestamp, err := strconv.ParseInt(candle.Timestamp, 10, 64)
+			if err != nil {
+				return nil, errp.WithStack(err)
+			}
+			value, err := strconv.ParseFloat(candle.Close, 64)
+			if err != nil {
+				return nil, errp.WithStack(err)
+			}
+			if fiat == SAT.String() {
+				value *= unitSatoshi
+			}
+			// Consecutive chunks share their boundary.
+			if n := len(rates); n > 0 && !rates[n-1].timestamp.Before(time.Unix(timestamp, 0)) {
+				continue
+			}
+			rates = append(rates, exchangeRate{
+				value:     value,
+				timestamp: time.Unix(timestamp, 0),
+			})
+		}
+		start = chunkEnd
+	}
+	return rates, nil
+}
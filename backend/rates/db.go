@@ -59,3 +59,15 @@ func (updater *RateUpdater) dumpHistoryBucket(key string, rates []exchangeRate)
 		return nil
 	})
 }
+
+// historyBucketKeys returns the keys of all buckets in updater.historyDB, e.g. "btcUSD".
+func (updater *RateUpdater) historyBucketKeys() ([]string, error) {
+	var keys []string
+	err := updater.historyDB.View(func(tx *bbolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bbolt.Bucket) error {
+			keys = append(keys, string(name))
+			return nil
+		})
+	})
+	return keys, err
+}
@@ -131,3 +131,9 @@ var (
 		"czk": "CZK",
 	}
 )
+
+// CoinUnit returns the unit of the latest rates of a backend coin code, e.g. "BTC" for "btc" and
+// "tbtc", or an empty string if the coin is not supported.
+func CoinUnit(coin string) string {
+	return geckoCoinToUnit[geckoCoin[coin]]
+}
@@ -13,6 +13,8 @@ import (
 	"sort"
 	"strconv"
 	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
 )
 
 // ReconfigureHistory resets all currently running historical rates goroutines.
@@ -65,6 +67,73 @@ func (updater *RateUpdater) stopAllHistory() {
 	updater.ReconfigureHistory(nil, nil)
 }
 
+// LoadHistory makes the historical rates of the coin/fiat pair between `since` and `until` available
+// to HistoricalPriceAt, also if the pair is not active, see ReconfigureHistory. The rates cached in
+// the database are loaded, and the ranges missing before and after them are fetched. It returns
+// when the rates are loaded, there is no older data available or the context is done.
+func (updater *RateUpdater) LoadHistory(ctx context.Context, coin, fiat string, since, until time.Time) error {
+	if geckoCoin[coin] == "" || toGeckoFiat[fiat] == "" {
+		return errp.Newf("no exchange rates history for %s/%s", coin, fiat)
+	}
+	key := coin + fiat
+	updater.historyMu.Lock()
+	if _, active := updater.historyGo[key]; !active && len(updater.history[key]) == 0 {
+		if rates, err := updater.loadHistoryBucket(key); err != nil {
+			// Non-critical: can continue without database cache.
+			updater.log.Errorf("loadHistoryBucket(%q): %v", key, err)
+		} else {
+			updater.history[key] = rates
+		}
+	}
+	updater.historyMu.Unlock()
+
+	// Fetch backwards from the earliest rate, like backfillHistory.
+	for {
+		end := updater.HistoryEarliestTimestamp(coin, fiat)
+		if !end.IsZero() && !end.After(since) {
+			break
+		}
+		if end.IsZero() {
+			end = time.Now()
+		}
+		// One day more, so that `since` is between two rates even if they are daily.
+		start := since.Add(-24 * time.Hour)
+		if end.Sub(start) > maxGeckoRange {
+			start = end.Add(-maxGeckoRange)
+		}
+		n, err := updater.updateHistory(ctx, coin, fiat, fixedTimeRange(start, end))
+		if err != nil {
+			return err
+		}
+		// CoinGecko returns an empty list if we're too far back in history.
+		if n == 0 || !updater.HistoryEarliestTimestamp(coin, fiat).Before(end) {
+			break
+		}
+	}
+
+	// Fetch forward from the latest rate, like historyUpdateLoop. The latest rates are used for
+	// the last hours, see `coin.ConversionsAtTime()`.
+	for {
+		start := updater.HistoryLatestTimestamp(coin, fiat)
+		if start.IsZero() || !start.Before(until) || time.Since(start) < time.Hour {
+			break
+		}
+		start = start.Add(time.Minute)
+		end := time.Now()
+		if end.Sub(start) > maxGeckoRange {
+			end = start.Add(maxGeckoRange)
+		}
+		n, err := updater.updateHistory(ctx, coin, fiat, fixedTimeRange(start, end))
+		if err != nil {
+			return err
+		}
+		if n == 0 || !updater.HistoryLatestTimestamp(coin, fiat).After(start) {
+			break
+		}
+	}
+	return nil
+}
+
 // historyUpdateLoop periodically updates historical market exchange rates
 // forward in time starting with the last fetched timestamp in a loop.
 // It returns when the context is done.
@@ -168,12 +237,19 @@ func (updater *RateUpdater) backfillHistory(ctx context.Context, coin, fiat stri
 // for later use. It returns the number of the newly fetched and stored entries.
 // The data is stored in updater.history.
 func (updater *RateUpdater) updateHistory(ctx context.Context, coin, fiat string, t fetchTimeRange) (n int, err error) {
-	fetchedRates, err := updater.fetchGeckoMarketRange(ctx, coin, fiat, t)
+	fetchedRates, source, err := updater.fetchHistoryFromProviders(ctx, coin, fiat, t)
 	if err != nil {
 		return 0, err
 	}
 
 	bucketName := coin + fiat
+	updater.providersMu.Lock()
+	if updater.historySources == nil {
+		updater.historySources = make(map[string]string)
+	}
+	updater.historySources[bucketName] = source
+	updater.providersMu.Unlock()
+
 	if err := updater.dumpHistoryBucket(bucketName, fetchedRates); err != nil {
 		// Non-critical: can continue without persistent DB.
 		updater.log.Errorf("dumpHistoryBucket(%q): %v", bucketName, err)
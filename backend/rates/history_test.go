@@ -4,11 +4,13 @@ package rates
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -222,6 +224,59 @@ func TestReconfigureHistoryLoadsFromDB(t *testing.T) {
 	}
 }
 
+func TestLoadHistory(t *testing.T) {
+	now := time.Now()
+	dataStart := now.Add(-3 * 365 * 24 * time.Hour)
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		from, err := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+		require.NoError(t, err)
+		to, err := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+		require.NoError(t, err)
+		// Daily rates, starting at dataStart.
+		prices := [][2]float64{}
+		for day := dataStart.Unix(); day <= to; day += 24 * 3600 {
+			if day >= from {
+				prices = append(prices, [2]float64{float64(day * 1000), 10000})
+			}
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"prices": prices}))
+	}))
+	defer ts.Close()
+
+	dbdir := test.TstTempDir("TestLoadHistory")
+	defer os.RemoveAll(dbdir)
+	updater := NewRateUpdater(http.DefaultClient, dbdir)
+	defer updater.Stop()
+	updater.coingeckoURL = ts.URL
+	// Cached rates of an inactive pair.
+	require.NoError(t, updater.dumpHistoryBucket("btcEUR", []exchangeRate{
+		{value: 9000, timestamp: now.Add(-10*24*time.Hour + time.Hour)},
+		{value: 9000, timestamp: now.Add(-9*24*time.Hour + time.Hour)},
+	}))
+
+	require.Error(t, updater.LoadHistory(t.Context(), "unsupported", "EUR", now, now))
+
+	since := now.Add(-500 * 24 * time.Hour)
+	require.Zero(t, updater.HistoricalPriceAt("btc", "EUR", since))
+	require.NoError(t, updater.LoadHistory(t.Context(), "btc", "EUR", since, now))
+	require.Equal(t, 10000., updater.HistoricalPriceAt("btc", "EUR", since))
+	require.Equal(t, 9000., updater.HistoricalPriceAt("btc", "EUR", now.Add(-10*24*time.Hour+time.Hour)))
+	require.Less(t, time.Since(updater.HistoryLatestTimestamp("btc", "EUR")), 25*time.Hour)
+	// The pair is not activated.
+	require.Empty(t, updater.historyGo)
+
+	// Nothing is fetched if the history is loaded already.
+	requests.Store(0)
+	require.NoError(t, updater.LoadHistory(t.Context(), "btc", "EUR", since.Add(time.Hour), now))
+	require.Zero(t, requests.Load())
+
+	// Stops at the end of the available data.
+	require.NoError(t, updater.LoadHistory(t.Context(), "btc", "EUR", now.Add(-10*365*24*time.Hour), now))
+	require.Equal(t, 10000., updater.HistoricalPriceAt("btc", "EUR", dataStart))
+}
+
 func BenchmarkDumpHistoryBucket(b *testing.B) {
 	var rates []exchangeRate
 	for i := 0; i < 5000; i++ {
@@ -0,0 +1,343 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package rates
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+// HistoryFormat is the file format of exported and imported historical rates.
+type HistoryFormat string
+
+const (
+	// HistoryFormatCSV is a CSV file with the columns `coin,fiat,time,rate`, one row per rate. The
+	// time is formatted as RFC 3339, e.g. "2020-09-01T00:00:00Z".
+	HistoryFormatCSV HistoryFormat = "csv"
+	// HistoryFormatJSON is the format of the file read by the static rates provider (see
+	// staticProvider), so an exported file can directly be used as an offline rates source.
+	HistoryFormatJSON HistoryFormat = "json"
+)
+
+// maxImportDeviation is the factor by which an imported rate may deviate from the rate at the same
+// time in the existing history. Larger deviations usually mean a wrong unit or fiat, e.g. sat
+// instead of BTC.
+const maxImportDeviation = 2
+
+// maxImportGap is the largest gap allowed between imported rates extending the existing history and
+// the history. The updater only fetches rates before the earliest and after the latest rate of a
+// pair, so gaps in between would never be filled. Older rates are daily, so a gap of up to two days
+// is expected.
+const maxImportGap = 48 * time.Hour
+
+// earliestRateTime is the genesis block time. Imported rates must not be older.
+var earliestRateTime = time.Date(2009, 1, 3, 0, 0, 0, 0, time.UTC)
+
+var csvHistoryHeader = []string{"coin", "fiat", "time", "rate"}
+
+// splitHistoryKey splits a history key like "btcUSD" into the coin code and the fiat. Returns false
+// if it is not a supported pair.
+func splitHistoryKey(key string) (string, string, bool) {
+	for fiat := range toGeckoFiat {
+		coin, found := strings.CutSuffix(key, fiat)
+		if found && geckoCoin[coin] != "" {
+			return coin, fiat, true
+		}
+	}
+	return "", "", false
+}
+
+// allHistory returns the history of all pairs in the database cache and of the active pairs, keyed
+// by coin+fiat pair.
+func (updater *RateUpdater) allHistory() (map[string][]exchangeRate, error) {
+	result := map[string][]exchangeRate{}
+	keys, err := updater.historyBucketKeys()
+	if err != nil {
+		// Non-critical: the active pairs are still exported.
+		updater.log.Errorf("historyBucketKeys: %v", err)
+	}
+	for _, key := range keys {
+		if _, _, ok := splitHistoryKey(key); !ok {
+			continue
+		}
+		rates, err := updater.loadHistoryBucket(key)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = rates
+	}
+	updater.historyMu.RLock()
+	defer updater.historyMu.RUnlock()
+	for key, rates := range updater.history {
+		// The cache contains everything which was fetched, but the in-memory history might be
+		// all we have if the cache is unusable.
+		if len(rates) > len(result[key]) {
+			result[key] = append([]exchangeRate(nil), rates...)
+		}
+	}
+	return result, nil
+}
+
+// ExportHistory writes the historical rates of all cached coin/fiat pairs in the given format.
+func (updater *RateUpdater) ExportHistory(writer io.Writer, format HistoryFormat) error {
+	history, err := updater.allHistory()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(history))
+	for key := range history {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	switch format {
+	case HistoryFormatCSV:
+		csvWriter := csv.NewWriter(writer)
+		if err := csvWriter.Write(csvHistoryHeader); err != nil {
+			return errp.WithStack(err)
+		}
+		for _, key := range keys {
+			coin, fiat, _ := splitHistoryKey(key)
+			for _, rate := range history[key] {
+				err := csvWriter.Write([]string{
+					coin,
+					fiat,
+					rate.timestamp.UTC().Format(time.RFC3339),
+					strconv.FormatFloat(rate.value, 'f', -1, 64),
+				})
+				if err != nil {
+					return errp.WithStack(err)
+				}
+			}
+		}
+		csvWriter.Flush()
+		return errp.WithStack(csvWriter.Error())
+	case HistoryFormatJSON:
+		file := staticRatesFile{History: map[string][][2]float64{}}
+		for _, key := range keys {
+			entries := make([][2]float64, len(history[key]))
+			for i, rate := range history[key] {
+				entries[i] = [2]float64{float64(rate.timestamp.Unix()), rate.value}
+			}
+			file.History[key] = entries
+		}
+		return errp.WithStack(json.NewEncoder(writer).Encode(file))
+	default:
+		return errp.Newf("unsupported format %q", format)
+	}
+}
+
+// ImportHistoryResult contains stats from the historical rates import.
+type ImportHistoryResult struct {
+	// Imported is the number of rates added to the history.
+	Imported int `json:"imported"`
+	// Skipped is the number of rates whose timestamp already exists in the history. The existing
+	// rate is kept.
+	Skipped int `json:"skipped"`
+	// Rejected is the number of rates deviating too much from the existing history.
+	Rejected int `json:"rejected"`
+	// Disconnected is the number of rates not overlapping or extending the existing history, i.e.
+	// separated from it by a gap larger than two days.
+	Disconnected int `json:"disconnected"`
+	// Pairs are the coin+fiat pairs to which rates were added, e.g. "btcUSD".
+	Pairs []string `json:"pairs"`
+}
+
+// parseHistory parses and validates historical rates in one of the HistoryFormat formats. The
+// format is detected automatically. The result is keyed by coin+fiat pair and sorted in ascending
+// order.
+func parseHistory(data []byte) (map[string][]exchangeRate, error) {
+	now := time.Now()
+	result := map[string][]exchangeRate{}
+	add := func(coin, fiat string, timestamp time.Time, value float64) error {
+		if geckoCoin[coin] == "" || toGeckoFiat[fiat] == "" {
+			return errp.Newf("unsupported pair %s/%s", coin, fiat)
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) || value <= 0 {
+			return errp.Newf("invalid rate %v", value)
+		}
+		if timestamp.Before(earliestRateTime) || timestamp.After(now) {
+			return errp.Newf("invalid time %s", timestamp)
+		}
+		// Same location as the rates loaded from the database cache.
+		timestamp = time.Unix(timestamp.Unix(), 0)
+		result[coin+fiat] = append(result[coin+fiat], exchangeRate{value: value, timestamp: timestamp})
+		return nil
+	}
+
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("{")) {
+		var file staticRatesFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, errp.WithStack(err)
+		}
+		for key, entries := range file.History {
+			coin, fiat, ok := splitHistoryKey(key)
+			if !ok {
+				return nil, errp.Newf("unsupported pair %s", key)
+			}
+			for _, entry := range entries {
+				if err := add(coin, fiat, time.Unix(int64(entry[0]), 0), entry[1]); err != nil {
+					return nil, errp.WithMessage(err, key)
+				}
+			}
+		}
+	} else {
+		csvReader := csv.NewReader(bytes.NewReader(data))
+		csvReader.FieldsPerRecord = len(csvHistoryHeader)
+		csvReader.TrimLeadingSpace = true
+		records, err := csvReader.ReadAll()
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		if len(records) == 0 || strings.Join(records[0], ",") != strings.Join(csvHistoryHeader, ",") {
+			return nil, errp.Newf("the CSV header must be %q", strings.Join(csvHistoryHeader, ","))
+		}
+		for i, record := range records[1:] {
+			line := i + 2
+			timestamp, err := time.Parse(time.RFC3339, record[2])
+			if err != nil {
+				// Also accept unix timestamps in seconds.
+				unix, unixErr := strconv.ParseInt(record[2], 10, 64)
+				if unixErr != nil {
+					return nil, errp.Newf("line %d: invalid time %q", line, record[2])
+				}
+				timestamp = time.Unix(unix, 0)
+			}
+			value, err := strconv.ParseFloat(record[3], 64)
+			if err != nil {
+				return nil, errp.Newf("line %d: invalid rate %q", line, record[3])
+			}
+			if err := add(record[0], record[1], timestamp, value); err != nil {
+				return nil, errp.WithMessage(err, fmt.Sprintf("line %d", line))
+			}
+		}
+	}
+
+	for key, rates := range result {
+		sort.Slice(rates, func(i, j int) bool { return rates[i].timestamp.Before(rates[j].timestamp) })
+		for i := 1; i < len(rates); i++ {
+			if rates[i].timestamp.Equal(rates[i-1].timestamp) {
+				return nil, errp.Newf("%s: duplicate time %s", key, rates[i].timestamp.UTC().Format(time.RFC3339))
+			}
+		}
+	}
+	return result, nil
+}
+
+// ImportHistory merges user-provided historical rates into the history, e.g. to have fiat values
+// on a machine without internet access. See HistoryFormat for the supported formats. The whole
+// import is rejected if any entry is malformed, has an unsupported coin/fiat pair, a non-positive
+// rate or a time in the future.
+//
+// Imported rates at a time already covered by the history are checked against it: rates whose
+// timestamp already exists are skipped, and rates deviating by more than a factor of
+// maxImportDeviation from the existing (interpolated) rate are rejected. As the updater assumes the
+// history of a pair to be contiguous, only rates overlapping the history or extending it without a
+// gap are accepted, see contiguousRates. The remaining rates are stored in the database cache, and
+// are used right away if the pair is active.
+func (updater *RateUpdater) ImportHistory(data []byte) (*ImportHistoryResult, error) {
+	imported, err := parseHistory(data)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(imported))
+	for key := range imported {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	updater.historyMu.Lock()
+	defer updater.historyMu.Unlock()
+	result := &ImportHistoryResult{Pairs: []string{}}
+	for _, key := range keys {
+		existing := updater.history[key]
+		if len(existing) == 0 {
+			existing, err = updater.loadHistoryBucket(key)
+			if err != nil {
+				// Non-critical: can continue without database cache.
+				updater.log.Errorf("loadHistoryBucket(%q): %v", key, err)
+			}
+		}
+		existingTimes := make(map[int64]struct{}, len(existing))
+		for _, rate := range existing {
+			existingTimes[rate.timestamp.Unix()] = struct{}{}
+		}
+
+		var accepted []exchangeRate
+		for _, rate := range imported[key] {
+			if _, exists := existingTimes[rate.timestamp.Unix()]; exists {
+				result.Skipped++
+				continue
+			}
+			if reference := priceAt(existing, rate.timestamp); reference > 0 &&
+				(rate.value > reference*maxImportDeviation || rate.value < reference/maxImportDeviation) {
+				result.Rejected++
+				continue
+			}
+			accepted = append(accepted, rate)
+		}
+		contiguous := contiguousRates(existing, accepted)
+		result.Disconnected += len(accepted) - len(contiguous)
+		accepted = contiguous
+		if len(accepted) == 0 {
+			continue
+		}
+		if err := updater.dumpHistoryBucket(key, accepted); err != nil {
+			return nil, err
+		}
+		result.Imported += len(accepted)
+		result.Pairs = append(result.Pairs, key)
+
+		if _, active := updater.historyGo[key]; active {
+			updater.history[key] = append(updater.history[key], accepted...)
+			sort.Slice(updater.history[key], func(i, j int) bool {
+				return updater.history[key][i].timestamp.Before(updater.history[key][j].timestamp)
+			})
+		}
+	}
+	return result, nil
+}
+
+// contiguousRates returns the imported rates which are within the time range of the existing
+// history, or extend it backwards or forwards without a gap larger than maxImportGap. If there is no
+// existing history, the range starts with the latest imported rate. Both lists must be sorted in
+// ascending order.
+func contiguousRates(existing, imported []exchangeRate) []exchangeRate {
+	if len(imported) == 0 {
+		return nil
+	}
+	var first, last time.Time
+	if len(existing) > 0 {
+		first, last = existing[0].timestamp, existing[len(existing)-1].timestamp
+	} else {
+		first = imported[len(imported)-1].timestamp
+		last = first
+	}
+	// The index of the first rate after the range.
+	after := sort.Search(len(imported), func(i int) bool { return imported[i].timestamp.After(last) })
+	// The index of the first rate not before the range.
+	within := sort.Search(len(imported), func(i int) bool { return !imported[i].timestamp.Before(first) })
+
+	start := within
+	for start > 0 && first.Sub(imported[start-1].timestamp) <= maxImportGap {
+		start--
+		first = imported[start].timestamp
+	}
+	end := after
+	for end < len(imported) && imported[end].timestamp.Sub(last) <= maxImportGap {
+		last = imported[end].timestamp
+		end++
+	}
+	return imported[start:end]
+}
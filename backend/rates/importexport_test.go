@@ -0,0 +1,174 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package rates
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitHistoryKey(t *testing.T) {
+	for key, want := range map[string][2]string{
+		"btcUSD":            {"btc", "USD"},
+		"btcsat":            {"btc", "sat"},
+		"ethBTC":            {"eth", "BTC"},
+		"eth-erc20-usdtCHF": {"eth-erc20-usdt", "CHF"},
+	} {
+		coin, fiat, ok := splitHistoryKey(key)
+		require.True(t, ok, key)
+		require.Equal(t, want, [2]string{coin, fiat}, key)
+	}
+	for _, key := range []string{"", "btc", "USD", "dogeUSD", "btcXYZ"} {
+		_, _, ok := splitHistoryKey(key)
+		require.False(t, ok, key)
+	}
+}
+
+func TestExportImportHistory(t *testing.T) {
+	dbdir := test.TstTempDir("TestExportImportHistory")
+	defer os.RemoveAll(dbdir)
+	updater := NewRateUpdater(nil, dbdir)
+	history := map[string][]exchangeRate{
+		"btcUSD": {
+			{value: 10000.5, timestamp: time.Unix(1598918400, 0)},
+			{value: 10100, timestamp: time.Unix(1598922000, 0)},
+		},
+		"ethEUR": {
+			{value: 350, timestamp: time.Unix(1598918400, 0)},
+		},
+	}
+	for key, rates := range history {
+		require.NoError(t, updater.dumpHistoryBucket(key, rates))
+	}
+
+	var csvExport bytes.Buffer
+	require.NoError(t, updater.ExportHistory(&csvExport, HistoryFormatCSV))
+	require.Equal(t,
+		"coin,fiat,time,rate\n"+
+			"btc,USD,2020-09-01T00:00:00Z,10000.5\n"+
+			"btc,USD,2020-09-01T01:00:00Z,10100\n"+
+			"eth,EUR,2020-09-01T00:00:00Z,350\n",
+		csvExport.String())
+
+	var jsonExport bytes.Buffer
+	require.NoError(t, updater.ExportHistory(&jsonExport, HistoryFormatJSON))
+	require.JSONEq(t,
+		`{"history": {"btcUSD": [[1598918400, 10000.5], [1598922000, 10100]], "ethEUR": [[1598918400, 350]]}}`,
+		jsonExport.String())
+
+	require.Error(t, updater.ExportHistory(&bytes.Buffer{}, "xml"))
+	updater.Stop()
+
+	// The JSON export can be used by the static provider.
+	staticFile := dbdir + "/static.json"
+	require.NoError(t, os.WriteFile(staticFile, jsonExport.Bytes(), 0600))
+	staticRates, err := newStaticProvider(staticFile).historicalPrices(context.Background(), "btc", "USD",
+		fixedTimeRange(time.Unix(1598918400, 0), time.Unix(1598922000, 0)))
+	require.NoError(t, err)
+	require.Equal(t, history["btcUSD"], staticRates)
+
+	for _, export := range []*bytes.Buffer{&csvExport, &jsonExport} {
+		importDir := test.TstTempDir("TestExportImportHistory-import")
+		defer os.RemoveAll(importDir)
+		importer := NewRateUpdater(nil, importDir)
+		result, err := importer.ImportHistory(export.Bytes())
+		require.NoError(t, err)
+		require.Equal(t, &ImportHistoryResult{Imported: 3, Pairs: []string{"btcUSD", "ethEUR"}}, result)
+		for key, want := range history {
+			got, err := importer.loadHistoryBucket(key)
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+		}
+
+		// Importing again does not change anything.
+		result, err = importer.ImportHistory(export.Bytes())
+		require.NoError(t, err)
+		require.Equal(t, &ImportHistoryResult{Skipped: 3, Pairs: []string{}}, result)
+		importer.Stop()
+	}
+}
+
+func TestImportHistoryMerge(t *testing.T) {
+	dbdir := test.TstTempDir("TestImportHistoryMerge")
+	defer os.RemoveAll(dbdir)
+	updater := NewRateUpdater(nil, dbdir)
+	defer updater.Stop()
+	existing := []exchangeRate{
+		{value: 10000, timestamp: time.Unix(1598918400, 0)}, // 2020-09-01T00:00:00Z
+		{value: 10200, timestamp: time.Unix(1598925600, 0)}, // 2020-09-01T02:00:00Z
+	}
+	require.NoError(t, updater.dumpHistoryBucket("btcUSD", existing))
+	// Make the pair active without starting to fetch rates.
+	updater.history["btcUSD"] = existing
+	updater.historyGo["btcUSD"] = func() {}
+
+	result, err := updater.ImportHistory([]byte(
+		"coin,fiat,time,rate\n" +
+			"btc,USD,2020-09-01T00:00:00Z,9000\n" + // existing timestamp
+			"btc,USD,2020-09-01T01:00:00Z,10100\n" +
+			"btc,USD,1598923800,100000000\n" + // 2020-09-01T01:30:00Z, in sat instead of BTC
+			"btc,USD,2020-08-31T00:00:00Z,11000\n" +
+			"btc,USD,2020-08-01T00:00:00Z,11000\n" + // leaves a gap before the existing history
+			"btc,USD,2020-09-05T00:00:00Z,11000\n")) // leaves a gap after the existing history
+	require.NoError(t, err)
+	require.Equal(t,
+		&ImportHistoryResult{Imported: 2, Skipped: 1, Rejected: 1, Disconnected: 2, Pairs: []string{"btcUSD"}},
+		result)
+
+	want := []exchangeRate{
+		{value: 11000, timestamp: time.Unix(1598832000, 0)},
+		{value: 10000, timestamp: time.Unix(1598918400, 0)},
+		{value: 10100, timestamp: time.Unix(1598922000, 0)},
+		{value: 10200, timestamp: time.Unix(1598925600, 0)},
+	}
+	require.Equal(t, want, updater.history["btcUSD"])
+	cached, err := updater.loadHistoryBucket("btcUSD")
+	require.NoError(t, err)
+	require.Equal(t, want, cached)
+}
+
+func TestImportHistoryInvalid(t *testing.T) {
+	updater := NewRateUpdater(nil, "/dev/null")
+	defer updater.Stop()
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	for name, data := range map[string]string{
+		"header":        "a,b,c,d\nbtc,USD,2020-09-01T00:00:00Z,1\n",
+		"columns":       "coin,fiat,time,rate\nbtc,USD,2020-09-01T00:00:00Z\n",
+		"coin":          "coin,fiat,time,rate\ndoge,USD,2020-09-01T00:00:00Z,1\n",
+		"fiat":          "coin,fiat,time,rate\nbtc,XYZ,2020-09-01T00:00:00Z,1\n",
+		"rate":          "coin,fiat,time,rate\nbtc,USD,2020-09-01T00:00:00Z,abc\n",
+		"negative rate": "coin,fiat,time,rate\nbtc,USD,2020-09-01T00:00:00Z,-1\n",
+		"time":          "coin,fiat,time,rate\nbtc,USD,yesterday,1\n",
+		"future":        "coin,fiat,time,rate\nbtc,USD," + future + ",1\n",
+		"duplicate":     "coin,fiat,time,rate\nbtc,USD,2020-09-01T00:00:00Z,1\nbtc,USD,1598918400,2\n",
+		"json pair":     `{"history": {"dogeUSD": [[1598918400, 1]]}}`,
+		"json":          `{"history": `,
+	} {
+		_, err := updater.ImportHistory([]byte(data))
+		require.Error(t, err, name)
+	}
+}
+
+func TestContiguousRates(t *testing.T) {
+	day := func(d int) exchangeRate {
+		return exchangeRate{value: 1, timestamp: time.Unix(1598918400, 0).Add(time.Duration(d) * 24 * time.Hour)}
+	}
+	existing := []exchangeRate{day(10), day(11), day(12)}
+
+	require.Nil(t, contiguousRates(existing, nil))
+	require.Equal(t,
+		[]exchangeRate{day(7), day(9), day(11), day(13), day(14)},
+		contiguousRates(existing, []exchangeRate{day(1), day(7), day(9), day(11), day(13), day(14), day(20)}))
+	require.Empty(t, contiguousRates(existing, []exchangeRate{day(1), day(20)}))
+
+	// Without existing history, the range starts with the latest rate.
+	require.Equal(t,
+		[]exchangeRate{day(5), day(6), day(8)},
+		contiguousRates(nil, []exchangeRate{day(1), day(5), day(6), day(8)}))
+}
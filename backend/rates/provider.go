@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package rates
+
+import (
+	"context"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+// Names of the supported exchange rates providers, as used in the app config and in the
+// attribution of the fetched rates.
+const (
+	// ProviderCoinGecko fetches rates from the CoinGecko API (or the BitBoxApp mirror of it).
+	ProviderCoinGecko = "coingecko"
+	// ProviderBitstamp fetches rates from the public Bitstamp exchange API.
+	ProviderBitstamp = "bitstamp"
+	// ProviderStaticFile reads rates from a local JSON file, useful for offline use.
+	ProviderStaticFile = "static"
+)
+
+// provider is a source of latest and historical exchange rates.
+type provider interface {
+	// name returns one of the Provider* constants.
+	name() string
+	// latestPrices returns the latest rates, keyed by coin unit (e.g. "BTC") and fiat (e.g.
+	// "USD"). Pairs not supported by the provider are omitted.
+	latestPrices(ctx context.Context) (map[string]map[string]float64, error)
+	// historicalPrices returns the rates of the coin/fiat pair in the given time range, sorted in
+	// ascending order. `coin` is a backend coin code as used in ReconfigureHistory. An error is
+	// returned if the pair is not supported by the provider.
+	historicalPrices(ctx context.Context, coin, fiat string, timeRange fetchTimeRange) ([]exchangeRate, error)
+}
+
+// geckoProvider fetches rates from CoinGecko, using the URL and rate limiter of the updater.
+type geckoProvider struct {
+	updater *RateUpdater
+}
+
+func (p *geckoProvider) name() string {
+	return ProviderCoinGecko
+}
+
+func (p *geckoProvider) latestPrices(ctx context.Context) (map[string]map[string]float64, error) {
+	return p.updater.fetchGeckoLatest(ctx)
+}
+
+func (p *geckoProvider) historicalPrices(
+	ctx context.Context, coin, fiat string, timeRange fetchTimeRange) ([]exchangeRate, error) {
+	return p.updater.fetchGeckoMarketRange(ctx, coin, fiat, timeRange)
+}
+
+// SetProviders configures the exchange rates providers in order of preference. A provider is only
+// queried if the previous ones failed or did not return all pairs, and rates of a pair are taken from
+// the first provider which supports it. `staticFile` is the path of the JSON file used by the
+// ProviderStaticFile provider, see newStaticProvider for its format. Unknown names are ignored. If
+// no valid name is given, only CoinGecko is used. Other providers, e.g. Bitstamp, are only contacted
+// if the user configures them.
+func (updater *RateUpdater) SetProviders(names []string, staticFile string) {
+	providers := []provider{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		switch name {
+		case ProviderCoinGecko:
+			providers = append(providers, &geckoProvider{updater: updater})
+		case ProviderBitstamp:
+			providers = append(providers, newBitstampProvider(updater.httpClient))
+		case ProviderStaticFile:
+			if staticFile == "" {
+				updater.log.Error("SetProviders: static rates provider configured without a file")
+				continue
+			}
+			providers = append(providers, newStaticProvider(staticFile))
+		default:
+			updater.log.Errorf("SetProviders: unknown rates provider %q", name)
+		}
+	}
+	if len(providers) == 0 {
+		providers = updater.defaultProviderList()
+	}
+	updater.providersMu.Lock()
+	defer updater.providersMu.Unlock()
+	updater.providers = providers
+}
+
+// defaultProviderList returns the providers used if none is configured.
+func (updater *RateUpdater) defaultProviderList() []provider {
+	return []provider{&geckoProvider{updater: updater}}
+}
+
+// providerList returns the configured providers in order of preference.
+func (updater *RateUpdater) providerList() []provider {
+	updater.providersMu.RLock()
+	defer updater.providersMu.RUnlock()
+	if len(updater.providers) == 0 {
+		return updater.defaultProviderList()
+	}
+	return updater.providers
+}
+
+// missingPairs returns the number of supported coin/fiat pairs which are not in rates.
+func missingPairs(rates map[string]map[string]float64) int {
+	missing := 0
+	for _, coinUnit := range geckoCoinToUnit {
+		for _, fiat := range fromGeckoFiat {
+			if _, ok := rates[coinUnit][fiat]; !ok {
+				missing++
+			}
+		}
+	}
+	return missing
+}
+
+// fetchLatestFromProviders queries the providers in order of preference, until all supported pairs
+// are fetched. The next provider is only queried if the previous one failed or some pairs are
+// missing. For each pair, the rate of the first provider returning it is used. The second return
+// value contains the name of the provider of each pair, with the same keys as the rates.
+func (updater *RateUpdater) fetchLatestFromProviders(
+	ctx context.Context) (map[string]map[string]float64, map[string]map[string]string) {
+	rates := map[string]map[string]float64{}
+	sources := map[string]map[string]string{}
+	for _, p := range updater.providerList() {
+		fetched, err := p.latestPrices(ctx)
+		if err != nil {
+			updater.log.WithError(err).Errorf("updatelast: provider %s failed", p.name())
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		for coinUnit, fiatRates := range fetched {
+			for fiat, value := range fiatRates {
+				if _, exists := rates[coinUnit][fiat]; exists {
+					continue
+				}
+				if rates[coinUnit] == nil {
+					rates[coinUnit] = map[string]float64{}
+					sources[coinUnit] = map[string]string{}
+				}
+				rates[coinUnit][fiat] = value
+				sources[coinUnit][fiat] = p.name()
+			}
+		}
+		if missingPairs(rates) == 0 {
+			break
+		}
+	}
+	return rates, sources
+}
+
+// fetchHistoryFromProviders fetches historical rates from the first provider which supports the
+// pair and does not fail. It returns the rates and the name of the provider.
+func (updater *RateUpdater) fetchHistoryFromProviders(
+	ctx context.Context, coin, fiat string, timeRange fetchTimeRange) ([]exchangeRate, string, error) {
+	var lastErr error
+	for _, p := range updater.providerList() {
+		rates, err := p.historicalPrices(ctx, coin, fiat, timeRange)
+		if err == nil {
+			return rates, p.name(), nil
+		}
+		if err == context.Canceled {
+			return nil, "", err
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = errp.New("no exchange rates provider configured")
+	}
+	return nil, "", lastErr
+}
+
+// LatestPriceSources returns the name of the provider of each of the latest rates, keyed the same
+// way as LatestPrice.
+func (updater *RateUpdater) LatestPriceSources() map[string]map[string]string {
+	updater.providersMu.RLock()
+	defer updater.providersMu.RUnlock()
+	return updater.lastSources
+}
+
+// HistorySources returns the name of the provider the historical rates were last fetched from,
+// keyed by coin+fiat pair, e.g. "btcUSD". Pairs not fetched yet in this session are omitted.
+func (updater *RateUpdater) HistorySources() map[string]string {
+	updater.providersMu.RLock()
+	defer updater.providersMu.RUnlock()
+	result := make(map[string]string, len(updater.historySources))
+	for key, source := range updater.historySources {
+		result[key] = source
+	}
+	return result
+}
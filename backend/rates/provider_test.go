@@ -0,0 +1,212 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package rates
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestBitstampProvider(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ticker/":
+			fmt.Fprintln(w, `[
+				{"pair": "BTC/USD", "last": "60000.5"},
+				{"pair": "ETH/BTC", "last": "0.05"},
+				{"pair": "XRP/USD", "last": "0.5"},
+				{"pair": "BTC/USDT", "last": "60001"}
+			]`)
+		case "/ohlc/btcusd/":
+			assert.Equal(t, "3600", r.URL.Query().Get("step"))
+			assert.Equal(t, "1598918400", r.URL.Query().Get("start"))
+			assert.Equal(t, "1598925600", r.URL.Query().Get("end"))
+			fmt.Fprintln(w, `{"data": {"pair": "BTC/USD", "ohlc": [
+				{"timestamp": "1598918400", "open": "11600", "close": "11650.2"},
+				{"timestamp": "1598922000", "open": "11650.2", "close": "11680.5"}
+			]}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	provider := newBitstampProvider(http.DefaultClient)
+	provider.apiURL = ts.URL
+	provider.limiter = rate.NewLimiter(rate.Inf, 1)
+
+	latest, err := provider.latestPrices(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]float64{
+		"BTC": {"USD": 60000.5},
+		"ETH": {"BTC": 0.05},
+	}, latest)
+
+	history, err := provider.historicalPrices(t.Context(), "btc", "USD",
+		fixedTimeRange(time.Unix(1598918400, 0), time.Unix(1598925600, 0)))
+	require.NoError(t, err)
+	require.Equal(t, []exchangeRate{
+		{value: 11650.2, timestamp: time.Unix(1598918400, 0)},
+		{value: 11680.5, timestamp: time.Unix(1598922000, 0)},
+	}, history)
+
+	_, err = provider.historicalPrices(t.Context(), "btc", "CHF",
+		fixedTimeRange(time.Unix(1598918400, 0), time.Unix(1598925600, 0)))
+	require.Error(t, err)
+}
+
+func writeStaticRatesFile(t *testing.T) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(filename, []byte(`{
+		"latest": {"BTC": {"USD": 50000, "EUR": 45000}},
+		"history": {"btcEUR": [[1598922000, 9800], [1598918400, 9700], [1599004800, 9900]]}
+	}`), 0600))
+	return filename
+}
+
+func TestStaticProvider(t *testing.T) {
+	provider := newStaticProvider(writeStaticRatesFile(t))
+
+	latest, err := provider.latestPrices(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]float64{"BTC": {"USD": 50000, "EUR": 45000}}, latest)
+
+	history, err := provider.historicalPrices(t.Context(), "btc", "EUR",
+		fixedTimeRange(time.Unix(1598918400, 0), time.Unix(1598925600, 0)))
+	require.NoError(t, err)
+	require.Equal(t, []exchangeRate{
+		{value: 9700, timestamp: time.Unix(1598918400, 0)},
+		{value: 9800, timestamp: time.Unix(1598922000, 0)},
+	}, history)
+
+	_, err = provider.historicalPrices(t.Context(), "btc", "USD",
+		fixedTimeRange(time.Unix(1598918400, 0), time.Unix(1598925600, 0)))
+	require.Error(t, err)
+
+	_, err = newStaticProvider(filepath.Join(t.TempDir(), "missing.json")).latestPrices(t.Context())
+	require.Error(t, err)
+}
+
+func TestProvidersFallback(t *testing.T) {
+	var geckoFails atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if geckoFails.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/simple/price":
+			fmt.Fprintln(w, `{"bitcoin": {"usd": 60000}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	dbdir := test.TstTempDir("TestProvidersFallback")
+	defer os.RemoveAll(dbdir)
+	updater := NewRateUpdater(http.DefaultClient, dbdir)
+	defer updater.Stop()
+	updater.SetCoingeckoURL(ts.URL)
+	updater.geckoLimiter = rate.NewLimiter(rate.Inf, 1)
+	updater.SetProviders(
+		[]string{ProviderCoinGecko, "unknown", ProviderStaticFile},
+		writeStaticRatesFile(t))
+
+	// Pairs are taken from the first provider supporting them.
+	updater.updateLast(t.Context())
+	require.Equal(t, 60000.0, updater.LatestPrice()["BTC"]["USD"])
+	require.Equal(t, 45000.0, updater.LatestPrice()["BTC"]["EUR"])
+	require.Equal(t, 45000.0/unitSatoshi, updater.LatestPrice()["sat"]["EUR"])
+	sources := updater.LatestPriceSources()
+	require.Equal(t, ProviderCoinGecko, sources["BTC"]["USD"])
+	require.Equal(t, ProviderStaticFile, sources["BTC"]["EUR"])
+	require.Equal(t, ProviderStaticFile, sources["sat"]["EUR"])
+	require.Equal(t, ProviderStaticFile, sources["TBTC"]["EUR"])
+
+	// Failing providers are skipped.
+	geckoFails.Store(true)
+	updater.updateLast(t.Context())
+	require.Equal(t, 50000.0, updater.LatestPrice()["BTC"]["USD"])
+	require.Equal(t, ProviderStaticFile, updater.LatestPriceSources()["BTC"]["USD"])
+
+	n, err := updater.updateHistory(t.Context(), "btc", "EUR",
+		fixedTimeRange(time.Unix(1598918400, 0), time.Unix(1598925600, 0)))
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, 9700.0, updater.HistoricalPriceAt("btc", "EUR", time.Unix(1598918400, 0)))
+	require.Equal(t, map[string]string{"btcEUR": ProviderStaticFile}, updater.HistorySources())
+
+	// The fetched history is stored in the cache of the updater.
+	cached, err := updater.loadHistoryBucket("btcEUR")
+	require.NoError(t, err)
+	require.Len(t, cached, 2)
+
+	_, err = updater.updateHistory(t.Context(), "btc", "USD",
+		fixedTimeRange(time.Unix(1598918400, 0), time.Unix(1598925600, 0)))
+	require.Error(t, err)
+}
+
+func TestProvidersOrderedFallback(t *testing.T) {
+	var geckoComplete atomic.Bool
+	geckoComplete.Store(true)
+	gecko := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rates := map[string]map[string]float64{}
+		for geckoCoin := range geckoCoinToUnit {
+			rates[geckoCoin] = map[string]float64{}
+			for geckoFiat := range fromGeckoFiat {
+				if geckoComplete.Load() || geckoFiat != "gbp" {
+					rates[geckoCoin][geckoFiat] = 1
+				}
+			}
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(rates))
+	}))
+	defer gecko.Close()
+	var bitstampCalls atomic.Int32
+	bitstamp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bitstampCalls.Add(1)
+		fmt.Fprintln(w, `[{"pair": "BTC/GBP", "last": "2"}]`)
+	}))
+	defer bitstamp.Close()
+
+	dbdir := test.TstTempDir("TestProvidersOrderedFallback")
+	defer os.RemoveAll(dbdir)
+	updater := NewRateUpdater(http.DefaultClient, dbdir)
+	defer updater.Stop()
+	require.Len(t, updater.providerList(), 1)
+	require.Equal(t, ProviderCoinGecko, updater.providerList()[0].name())
+
+	updater.SetCoingeckoURL(gecko.URL)
+	updater.geckoLimiter = rate.NewLimiter(rate.Inf, 1)
+	updater.SetProviders([]string{ProviderCoinGecko, ProviderBitstamp}, "")
+	bitstampProvider := updater.providerList()[1].(*bitstampProvider)
+	bitstampProvider.apiURL = bitstamp.URL
+	bitstampProvider.limiter = rate.NewLimiter(rate.Inf, 1)
+
+	// The next provider is not contacted if the first one returns all pairs.
+	updater.updateLast(t.Context())
+	require.Equal(t, int32(0), bitstampCalls.Load())
+	require.Equal(t, 1.0, updater.LatestPrice()["BTC"]["GBP"])
+
+	// Missing pairs are taken from the next provider.
+	geckoComplete.Store(false)
+	updater.updateLast(t.Context())
+	require.Equal(t, int32(1), bitstampCalls.Load())
+	require.Equal(t, 2.0, updater.LatestPrice()["BTC"]["GBP"])
+	require.Equal(t, ProviderBitstamp, updater.LatestPriceSources()["BTC"]["GBP"])
+	require.Equal(t, ProviderCoinGecko, updater.LatestPriceSources()["BTC"]["USD"])
+}
@@ -109,6 +109,19 @@ type RateUpdater struct {
 	coingeckoURL string
 	// All requests to coingeckoURL are rate-limited using geckoLimiter.
 	geckoLimiter *rate.Limiter
+
+	providersMu sync.RWMutex // guards providers, lastSources and historySources
+	// providers are the exchange rates providers in order of preference.
+	providers []provider
+	// lastSources contains the provider name of each rate in last, keyed the same way.
+	lastSources map[string]map[string]string
+	// historySources contains the provider name of the last history update, keyed by
+	// coin+fiat pair like history.
+	historySources map[string]string
+
+	alertsMu sync.Mutex // guards alertsHandler
+	// alertsHandler provides the price alerts evaluated in updateLast.
+	alertsHandler PriceAlertsHandler
 }
 
 // NewRateUpdater returns a new rates updater.
@@ -134,16 +147,19 @@ func NewRateUpdater(client *http.Client, dbdir string) *RateUpdater {
 		db = &bbolt.DB{}
 	}
 	apiURL := shiftGeckoMirrorAPIV3
-	return &RateUpdater{
-		last:         make(map[string]map[string]float64),
-		history:      make(map[string][]exchangeRate),
-		historyGo:    make(map[string]context.CancelFunc),
-		historyDB:    db,
-		log:          log,
-		httpClient:   client,
-		coingeckoURL: apiURL,
-		geckoLimiter: rate.NewLimiter(apiRateLimit(apiURL), 1),
+	updater := &RateUpdater{
+		last:           make(map[string]map[string]float64),
+		history:        make(map[string][]exchangeRate),
+		historyGo:      make(map[string]context.CancelFunc),
+		historyDB:      db,
+		log:            log,
+		httpClient:     client,
+		coingeckoURL:   apiURL,
+		geckoLimiter:   rate.NewLimiter(apiRateLimit(apiURL), 1),
+		historySources: make(map[string]string),
 	}
+	updater.providers = updater.defaultProviderList()
+	return updater
 }
 
 // SetCoingeckoURL overrides the default URL the rates updater connects to. Useful for testing.
@@ -179,7 +195,12 @@ func (updater *RateUpdater) LatestPriceForPair(coinUnit, fiat string) (float64,
 func (updater *RateUpdater) HistoricalPriceAt(coin, fiat string, at time.Time) float64 {
 	updater.historyMu.RLock()
 	defer updater.historyMu.RUnlock()
-	data := updater.history[coin+fiat]
+	return priceAt(updater.history[coin+fiat], at)
+}
+
+// priceAt returns the rate at the given time in data, which is sorted in ascending order, like
+// HistoricalPriceAt.
+func priceAt(data []exchangeRate, at time.Time) float64 {
 	if len(data) == 0 {
 		return 0 // no data at all
 	}
@@ -248,7 +269,9 @@ func (updater *RateUpdater) lastUpdateLoop(ctx context.Context) {
 	}
 }
 
-func (updater *RateUpdater) updateLast(ctx context.Context) {
+// fetchGeckoLatest fetches the latest rates of all supported pairs using CoinGecko's
+// "simple/price" API. The result is keyed by coin unit and fiat code.
+func (updater *RateUpdater) fetchGeckoLatest(ctx context.Context) (map[string]map[string]float64, error) {
 	param := url.Values{
 		"ids":           {simplePriceAllIDs},
 		"vs_currencies": {simplePriceAllCurrencies},
@@ -256,48 +279,34 @@ func (updater *RateUpdater) updateLast(ctx context.Context) {
 	endpoint := fmt.Sprintf("%s/simple/price?%s", updater.coingeckoURL, param.Encode())
 	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
 	if err != nil {
-		updater.log.WithError(err).Error("could not create request")
-		updater.last = nil
-		return
+		return nil, errp.WithMessage(err, "could not create request")
 	}
 
 	var geckoRates map[string]map[string]float64
 	if err := updater.geckoLimiter.Wait(ctx); err != nil {
-		updater.log.WithError(err).Error("updatelast: could not wait for rate limiter")
-		updater.last = nil
-		return
+		return nil, errp.WithMessage(err, "could not wait for rate limiter")
 	}
 
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 	res, err := updater.httpClient.Do(req.WithContext(ctx))
 	if err != nil {
-		updater.log.WithError(err).Error("updatelast: could not make request")
-		updater.last = nil
-		return
+		return nil, errp.WithMessage(err, "could not make request")
 	}
 	defer res.Body.Close() //nolint:errcheck
 	if res.StatusCode != http.StatusOK {
-		updater.log.Errorf("updatelast: bad response code %d", res.StatusCode)
-		updater.last = nil
-		return
+		return nil, errp.Newf("bad response code %d", res.StatusCode)
 	}
 	const max = 10240
 	responseBody, err := io.ReadAll(io.LimitReader(res.Body, max+1))
 	if err != nil {
-		updater.log.WithError(err).Error("updatelast: could not read response")
-		updater.last = nil
-		return
+		return nil, errp.WithMessage(err, "could not read response")
 	}
 	if len(responseBody) > max {
-		updater.last = nil
-		updater.log.Errorf("updatelast: rates response too long (> %d bytes)", max)
-		return
+		return nil, errp.Newf("rates response too long (> %d bytes)", max)
 	}
 	if err := json.Unmarshal(responseBody, &geckoRates); err != nil {
-		updater.last = nil
-		updater.log.Errorf("updatelast: could not parse rates response: %s", string(responseBody))
-		return
+		return nil, errp.Newf("could not parse rates response: %s", string(responseBody))
 	}
 
 	// Convert the map with coingecko coin/fiat codes to a map of coin/fiat units.
@@ -315,13 +324,26 @@ func (updater *RateUpdater) updateLast(ctx context.Context) {
 				updater.log.Errorf("unsupported fiat: %s", geckoFiat)
 				continue
 			}
-			if fiat == BTC.String() {
-				newVal[SAT.String()] = rates * unitSatoshi
-			}
 			newVal[fiat] = rates
 		}
 		rates[coinUnit] = newVal
 	}
+	return rates, nil
+}
+
+func (updater *RateUpdater) updateLast(ctx context.Context) {
+	rates, sources := updater.fetchLatestFromProviders(ctx)
+	if len(rates) == 0 {
+		updater.last = nil
+		return
+	}
+
+	for coinUnit, fiatRates := range rates {
+		if btcRate, ok := fiatRates[BTC.String()]; ok {
+			fiatRates[SAT.String()] = btcRate * unitSatoshi
+			sources[coinUnit][SAT.String()] = sources[coinUnit][BTC.String()]
+		}
+	}
 
 	// Create sat rates from BTC
 	sat := make(map[string]float64)
@@ -329,24 +351,31 @@ func (updater *RateUpdater) updateLast(ctx context.Context) {
 		sat[currency] = rate / unitSatoshi
 	}
 	rates[SAT.String()] = sat
+	sources[SAT.String()] = sources[BTC.String()]
 
 	// Provide conversion rates for testnets as well, useful for testing.
 	for _, testnetUnit := range []string{"TBTC", "RBTC", "TLTC", "SEPETH"} {
 		switch testnetUnit {
 		case "SEPETH":
 			rates[testnetUnit] = rates[testnetUnit[3:]]
+			sources[testnetUnit] = sources[testnetUnit[3:]]
 		default:
 			rates[testnetUnit] = rates[testnetUnit[1:]]
+			sources[testnetUnit] = sources[testnetUnit[1:]]
 		}
 	}
 
-	if reflect.DeepEqual(rates, updater.last) {
-		return
+	updater.providersMu.Lock()
+	updater.lastSources = sources
+	updater.providersMu.Unlock()
+
+	if !reflect.DeepEqual(rates, updater.last) {
+		updater.last = rates
+		updater.Notify(observable.Event{
+			Subject: RatesEventSubject,
+			Action:  action.Replace,
+			Object:  rates,
+		})
 	}
-	updater.last = rates
-	updater.Notify(observable.Event{
-		Subject: RatesEventSubject,
-		Action:  action.Replace,
-		Object:  rates,
-	})
+	updater.evaluatePriceAlerts(rates, time.Now())
 }
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+// staticProvider reads rates from a local JSON file, so that rates are available without any
+// network access. The file is read on every request, so it can be replaced while the app is running.
+// The format is:
+//
+//	{
+//	  "latest": {"BTC": {"USD": 60000, "EUR": 55000}},
+//	  "history": {"btcUSD": [[1598832000, 11650.2], [1598835600, 11680.5]]}
+//	}
+//
+// "latest" is keyed by coin unit and fiat like RateUpdater.LatestPrice. "history" is keyed by
+// coin+fiat pair like the history cache and contains [unix timestamp in seconds, rate] entries.
+type staticProvider struct {
+	filename string
+}
+
+type staticRatesFile struct {
+	Latest  map[string]map[string]float64 `json:"latest,omitempty"`
+	History map[string][][2]float64       `json:"history"`
+}
+
+func newStaticProvider(filename string) *staticProvider {
+	return &staticProvider{filename: filename}
+}
+
+func (p *staticProvider) name() string {
+	return ProviderStaticFile
+}
+
+func (p *staticProvider) read() (*staticRatesFile, error) {
+	contents, err := os.ReadFile(p.filename)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	var file staticRatesFile
+	if err := json.Unmarshal(contents, &file); err != nil {
+		return nil, errp.WithMessage(err, "could not parse the static rates file")
+	}
+	return &file, nil
+}
+
+func (p *staticProvider) latestPrices(ctx context.Context) (map[string]map[string]float64, error) {
+	file, err := p.read()
+	if err != nil {
+		return nil, err
+	}
+	return file.Latest, nil
+}
+
+func (p *staticProvider) historicalPrices(
+	ctx context.Context, coin, fiat string, timeRange fetchTimeRange) ([]exchangeRate, error) {
+	file, err := p.read()
+	if err != nil {
+		return nil, err
+	}
+	entries, ok := file.History[coin+fiat]
+	if !ok {
+		return nil, fmt.Errorf("static: unsupported pair %s/%s", coin, fiat)
+	}
+	start := timeRange.start
+	end := timeRange.end()
+	rates := []exchangeRate{}
+	for _, entry := range entries {
+		timestamp := time.Unix(int64(entry[0]), 0)
+		if timestamp.Before(start) || timestamp.After(end) {
+			continue
+		}
+		rates = append(rates, exchangeRate{value: entry[1], timestamp: timestamp})
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].timestamp.Before(rates[j].timestamp)
+	})
+	return rates, nil
+}
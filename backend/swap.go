@@ -350,7 +350,7 @@ func (backend *Backend) PrepareSwap(
 
 	swapResponse, swapError := swapkit.NewSwap(
 		context.Background(),
-		backend.httpClient,
+		backend.MarketHTTPClient(),
 		string(sellAccount.Coin().Code()),
 		string(buyAccount.Coin().Code()),
 		swapSellAmount,
@@ -0,0 +1,352 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	accountsTypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+const (
+	// defaultTransactionsPageSize is the page size of a transactions query without a limit.
+	defaultTransactionsPageSize = 50
+	// maxTransactionsPageSize is the maximum page size of a transactions query.
+	maxTransactionsPageSize = 500
+)
+
+// TransactionsSort is the order of the results of a transactions query.
+type TransactionsSort string
+
+const (
+	// TransactionsSortNewest sorts from newest to oldest. Transactions without a timestamp, e.g.
+	// unconfirmed ones, are the newest.
+	TransactionsSortNewest TransactionsSort = "newest"
+	// TransactionsSortOldest sorts from oldest to newest.
+	TransactionsSortOldest TransactionsSort = "oldest"
+	// TransactionsSortAmountDesc sorts by amount, largest first. Amounts are compared in the unit
+	// of their coin, so amounts of different coins are compared by their nominal value.
+	TransactionsSortAmountDesc TransactionsSort = "amountDesc"
+	// TransactionsSortAmountAsc sorts by amount, smallest first.
+	TransactionsSortAmountAsc TransactionsSort = "amountAsc"
+)
+
+// TransactionsQuery is a query of the transactions of one or more accounts.
+type TransactionsQuery struct {
+	// AccountCodes are the accounts to query. Empty means all active accounts, for a global
+	// activity feed.
+	AccountCodes []accountsTypes.Code `json:"accountCodes"`
+	accounts.TransactionsFilter
+	// Sort defaults to TransactionsSortNewest if empty.
+	Sort TransactionsSort `json:"sort"`
+	// Cursor is the `NextCursor` of the previous page, or empty for the first page.
+	Cursor string `json:"cursor"`
+	// Limit is the page size. Defaults to 50, at most 500.
+	Limit int `json:"limit"`
+}
+
+// TransactionsQueryResult is a transaction of a transactions query, with its account.
+type TransactionsQueryResult struct {
+	Account     accounts.Interface
+	Transaction *accounts.TransactionData
+}
+
+// TransactionsPage is one page of the results of a transactions query.
+type TransactionsPage struct {
+	Transactions []*TransactionsQueryResult
+	// NextCursor is the cursor of the next page, or empty if this is the last page.
+	NextCursor string
+	// Total is the number of transactions matching the query on all pages.
+	Total int
+}
+
+// transactionsCursor is the position of a transaction in the sort order of a query. Cursors stay
+// valid if transactions are added or removed between requests, as they do not refer to an index.
+// It is also the precomputed sort key of a transaction.
+type transactionsCursor struct {
+	// Time is the unix timestamp in seconds, nil for transactions without a timestamp.
+	Time *int64 `json:"t,omitempty"`
+	// Amount is the amount in the unit of the coin, as a fraction.
+	Amount      string             `json:"a"`
+	AccountCode accountsTypes.Code `json:"c"`
+	InternalID  string             `json:"i"`
+
+	// amount is the parsed Amount, so that it is not parsed again in each comparison.
+	amount *big.Rat
+}
+
+func newTransactionsCursor(result *TransactionsQueryResult) *transactionsCursor {
+	transaction := result.Transaction
+	amount := new(big.Rat).SetFrac(
+		transaction.Amount.BigInt(),
+		coin.DecimalsExp(result.Account.Coin(), false),
+	)
+	cursor := &transactionsCursor{
+		Amount:      amount.RatString(),
+		AccountCode: result.Account.Config().Config.Code,
+		InternalID:  transaction.InternalID,
+		amount:      amount,
+	}
+	if transaction.Timestamp != nil {
+		timestamp := transaction.Timestamp.Unix()
+		cursor.Time = &timestamp
+	}
+	return cursor
+}
+
+func (cursor *transactionsCursor) encode() (string, error) {
+	jsonBytes, err := json.Marshal(cursor)
+	if err != nil {
+		return "", errp.WithStack(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(jsonBytes), nil
+}
+
+func decodeTransactionsCursor(encoded string) (*transactionsCursor, error) {
+	jsonBytes, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errp.New("invalid cursor")
+	}
+	var cursor transactionsCursor
+	if err := json.Unmarshal(jsonBytes, &cursor); err != nil {
+		return nil, errp.New("invalid cursor")
+	}
+	amount, ok := new(big.Rat).SetString(cursor.Amount)
+	if !ok {
+		return nil, errp.New("invalid cursor")
+	}
+	cursor.amount = amount
+	return &cursor, nil
+}
+
+// compareTime compares the times of two cursors, oldest first. Transactions without a timestamp
+// are the newest.
+func compareTime(a, b *transactionsCursor) int {
+	switch {
+	case a.Time == nil && b.Time == nil:
+		return 0
+	case a.Time == nil:
+		return 1
+	case b.Time == nil:
+		return -1
+	}
+	return compareInt64(*a.Time, *b.Time)
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compare returns the order of two cursors in the given sort order. The order is total, so that
+// cursors point to an unambiguous position: ties are broken by account code and transaction ID.
+func (sort TransactionsSort) compare(a, b *transactionsCursor) int {
+	var result int
+	switch sort {
+	case TransactionsSortOldest:
+		result = compareTime(a, b)
+	case TransactionsSortAmountDesc, TransactionsSortAmountAsc:
+		result = a.amount.Cmp(b.amount)
+		if sort == TransactionsSortAmountDesc {
+			result = -result
+		}
+		if result == 0 {
+			result = -compareTime(a, b)
+		}
+	default:
+		result = -compareTime(a, b)
+	}
+	if result != 0 {
+		return result
+	}
+	if result = strings.Compare(string(a.AccountCode), string(b.AccountCode)); result != 0 {
+		return result
+	}
+	return strings.Compare(a.InternalID, b.InternalID)
+}
+
+// transactionsQueryEntry is a transaction of a transactions query with its sort key.
+type transactionsQueryEntry struct {
+	result *TransactionsQueryResult
+	cursor *transactionsCursor
+}
+
+// accountTransactionsCache are the transactions of an account with their sort keys, sorted in the
+// orders queried so far. It is reset when the account synced, see
+// `resetTransactionsQueryCache()`.
+type accountTransactionsCache struct {
+	account accounts.Interface
+	entries []transactionsQueryEntry
+	sorted  map[TransactionsSort][]transactionsQueryEntry
+}
+
+// resetTransactionsQueryCache resets the cached transactions of the account, or of all accounts if
+// account is nil, e.g. because their contact labels changed.
+func (backend *Backend) resetTransactionsQueryCache(account accounts.Interface) {
+	defer backend.transactionsQueryCacheLock.Lock()()
+	backend.transactionsQueryCacheGeneration++
+	if account == nil {
+		backend.transactionsQueryCache = nil
+		return
+	}
+	delete(backend.transactionsQueryCache, account.Config().Config.Code)
+}
+
+// sortedTransactions returns the transactions of the account in the given order, from the cache if
+// possible. The lock is not held while the transactions are loaded, as labelling them with their
+// contacts locks the address book, which resets the cache when it changes.
+func (backend *Backend) sortedTransactions(
+	account accounts.Interface, sort TransactionsSort) ([]transactionsQueryEntry, error) {
+	code := account.Config().Config.Code
+	unlock := backend.transactionsQueryCacheLock.RLock()
+	cached, ok := backend.transactionsQueryCache[code]
+	generation := backend.transactionsQueryCacheGeneration
+	unlock()
+	if !ok || cached.account != account {
+		transactions, err := account.Transactions()
+		if err != nil {
+			return nil, err
+		}
+		cached = &accountTransactionsCache{
+			account: account,
+			entries: make([]transactionsQueryEntry, len(transactions)),
+			sorted:  map[TransactionsSort][]transactionsQueryEntry{},
+		}
+		for i, transaction := range transactions {
+			result := &TransactionsQueryResult{Account: account, Transaction: transaction}
+			cached.entries[i] = transactionsQueryEntry{result: result, cursor: newTransactionsCursor(result)}
+		}
+		unlock := backend.transactionsQueryCacheLock.Lock()
+		// Not cached if it was reset while loading, as the transactions might be outdated.
+		if generation == backend.transactionsQueryCacheGeneration {
+			if backend.transactionsQueryCache == nil {
+				backend.transactionsQueryCache = map[accountsTypes.Code]*accountTransactionsCache{}
+			}
+			backend.transactionsQueryCache[code] = cached
+		}
+		unlock()
+	}
+	defer backend.transactionsQueryCacheLock.Lock()()
+	sorted, ok := cached.sorted[sort]
+	if !ok {
+		sorted = slices.Clone(cached.entries)
+		slices.SortFunc(sorted, func(a, b transactionsQueryEntry) int {
+			return sort.compare(a.cursor, b.cursor)
+		})
+		cached.sorted[sort] = sorted
+	}
+	return sorted, nil
+}
+
+// mergeSortedTransactions merges two lists of transactions sorted in the given order.
+func mergeSortedTransactions(
+	a, b []transactionsQueryEntry, sort TransactionsSort) []transactionsQueryEntry {
+	result := make([]transactionsQueryEntry, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if sort.compare(a[0].cursor, b[0].cursor) <= 0 {
+			result = append(result, a[0])
+			a = a[1:]
+		} else {
+			result = append(result, b[0])
+			b = b[1:]
+		}
+	}
+	return append(append(result, a...), b...)
+}
+
+// QueryTransactions returns one page of the transactions of the queried accounts which match the
+// filter of the query, in the requested order. The sorted transactions of each account are cached
+// until the account syncs again, so that paging does not sort them again.
+func (backend *Backend) QueryTransactions(query TransactionsQuery) (*TransactionsPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	switch query.Sort {
+	case "":
+		query.Sort = TransactionsSortNewest
+	case TransactionsSortNewest, TransactionsSortOldest, TransactionsSortAmountDesc, TransactionsSortAmountAsc:
+	default:
+		return nil, errp.Newf("unsupported sort order %q", query.Sort)
+	}
+	if query.Limit < 0 {
+		return nil, errp.New("the limit must not be negative")
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultTransactionsPageSize
+	}
+	query.Limit = min(query.Limit, maxTransactionsPageSize)
+	var after *transactionsCursor
+	if query.Cursor != "" {
+		cursor, err := decodeTransactionsCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = cursor
+	}
+
+	selected, err := backend.selectAccounts(query.AccountCodes)
+	if err != nil {
+		return nil, err
+	}
+	entries := []transactionsQueryEntry{}
+	for _, account := range selected {
+		if err := account.Initialize(); err != nil {
+			return nil, err
+		}
+		sorted, err := backend.sortedTransactions(account, query.Sort)
+		if err != nil {
+			return nil, err
+		}
+		matches, err := query.Matcher(account)
+		if err != nil {
+			return nil, err
+		}
+		filtered := []transactionsQueryEntry{}
+		for _, entry := range sorted {
+			if matches(entry.result.Transaction) {
+				filtered = append(filtered, entry)
+			}
+		}
+		entries = mergeSortedTransactions(entries, filtered, query.Sort)
+	}
+
+	start := 0
+	if after != nil {
+		start, _ = slices.BinarySearchFunc(entries, after, func(e transactionsQueryEntry, cursor *transactionsCursor) int {
+			return query.Sort.compare(e.cursor, cursor)
+		})
+		// The cursor points to the last transaction of the previous page, which is skipped.
+		if start < len(entries) && query.Sort.compare(entries[start].cursor, after) == 0 {
+			start++
+		}
+	}
+	end := min(start+query.Limit, len(entries))
+
+	page := &TransactionsPage{
+		Transactions: []*TransactionsQueryResult{},
+		Total:        len(entries),
+	}
+	for _, e := range entries[start:end] {
+		page.Transactions = append(page.Transactions, e.result)
+	}
+	if end < len(entries) {
+		nextCursor, err := entries[end-1].cursor.encode()
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = nextCursor
+	}
+	return page, nil
+}
@@ -0,0 +1,186 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts"
+	accountsTypes "github.com/BitBoxSwiss/bitbox-wallet-app/backend/accounts/types"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc/addresses"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc/blockchain"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/btc/types"
+	coinpkg "github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/coin"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/backend/coins/eth"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryTransactions(t *testing.T) {
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+
+	txTime := func(seconds int64) *time.Time {
+		timestamp := time.Unix(seconds, 0)
+		return &timestamp
+	}
+	btcTransactionsCalls := 0
+	b.makeBtcAccount = func(config *accounts.AccountConfig, coin *btc.Coin, gapLimits *types.GapLimits, getAddress func(coinpkg.Code, blockchain.ScriptHashHex) (*addresses.AccountAddress, error), log *logrus.Entry) accounts.Interface {
+		accountMock := MockBtcAccount(t, config, coin, gapLimits, log)
+		var transactions accounts.OrderedTransactions
+		switch {
+		case strings.HasSuffix(string(config.Config.Code), "-btc-0"):
+			transactions = accounts.OrderedTransactions{
+				{
+					InternalID: "btc-pending", Type: accounts.TxTypeReceive, Status: accounts.TxStatusPending,
+					Amount:    coinpkg.NewAmountFromInt64(5000),
+					Addresses: []accounts.AddressAndAmount{{Address: "bc1qpending"}},
+				},
+				{
+					InternalID: "btc-send", Type: accounts.TxTypeSend, Status: accounts.TxStatusComplete,
+					Timestamp: txTime(3000), NumConfirmations: 10, Amount: coinpkg.NewAmountFromInt64(200000),
+					Addresses: []accounts.AddressAndAmount{{Address: "bc1qAliceAddress"}},
+				},
+				{
+					InternalID: "btc-receive", Type: accounts.TxTypeReceive, Status: accounts.TxStatusComplete,
+					Timestamp: txTime(1000), NumConfirmations: 100, Amount: coinpkg.NewAmountFromInt64(100000000),
+					Addresses: []accounts.AddressAndAmount{{Address: "bc1qsalary"}},
+				},
+			}
+		case strings.HasSuffix(string(config.Config.Code), "-ltc-0"):
+			transactions = accounts.OrderedTransactions{
+				{
+					InternalID: "ltc-receive", Type: accounts.TxTypeReceive, Status: accounts.TxStatusComplete,
+					Timestamp: txTime(2000), NumConfirmations: 20, Amount: coinpkg.NewAmountFromInt64(300000000),
+					Addresses: []accounts.AddressAndAmount{{Address: "ltc1qexchange"}},
+				},
+				{
+					InternalID: "ltc-self", Type: accounts.TxTypeSendSelf, Status: accounts.TxStatusComplete,
+					Timestamp: txTime(1000), NumConfirmations: 30, Amount: coinpkg.NewAmountFromInt64(100000000),
+				},
+			}
+		}
+		accountMock.TransactionsFunc = func() (accounts.OrderedTransactions, error) {
+			if strings.HasSuffix(string(config.Config.Code), "-btc-0") {
+				btcTransactionsCalls++
+			}
+			return transactions, nil
+		}
+		accountMock.TxNoteFunc = func(txID string) string {
+			if txID == "btc-receive" {
+				return "Salary, March"
+			}
+			return ""
+		}
+		return accountMock
+	}
+	b.makeEthAccount = func(config *accounts.AccountConfig, coin *eth.Coin, httpClient *http.Client, log *logrus.Entry) accounts.Interface {
+		return MockEthAccount(config, coin, httpClient, log)
+	}
+
+	ks := makeBitBox02Multi()
+	fingerprint, err := ks.RootFingerprint()
+	require.NoError(t, err)
+	b.registerKeystore(ks)
+	btcCode := accountsTypes.Code(fmt.Sprintf("v0-%x-btc-0", fingerprint))
+	ltcCode := accountsTypes.Code(fmt.Sprintf("v0-%x-ltc-0", fingerprint))
+	accountCodes := []accountsTypes.Code{btcCode, ltcCode}
+
+	internalIDs := func(page *TransactionsPage) []string {
+		result := []string{}
+		for _, transaction := range page.Transactions {
+			result = append(result, transaction.Transaction.InternalID)
+		}
+		return result
+	}
+	query := func(query TransactionsQuery) *TransactionsPage {
+		t.Helper()
+		query.AccountCodes = accountCodes
+		page, err := b.QueryTransactions(query)
+		require.NoError(t, err)
+		return page
+	}
+
+	page := query(TransactionsQuery{})
+	require.Equal(t,
+		[]string{"btc-pending", "btc-send", "ltc-receive", "btc-receive", "ltc-self"},
+		internalIDs(page))
+	require.Equal(t, 5, page.Total)
+	require.Empty(t, page.NextCursor)
+	require.Equal(t, btcCode, page.Transactions[0].Account.Config().Config.Code)
+
+	// All active accounts.
+	page, err = b.QueryTransactions(TransactionsQuery{})
+	require.NoError(t, err)
+	require.Equal(t, 5, page.Total)
+
+	// Ties are ordered by account code.
+	require.Equal(t,
+		[]string{"btc-receive", "ltc-self", "ltc-receive", "btc-send", "btc-pending"},
+		internalIDs(query(TransactionsQuery{Sort: TransactionsSortOldest})))
+	require.Equal(t,
+		[]string{"ltc-receive", "btc-receive", "ltc-self", "btc-send", "btc-pending"},
+		internalIDs(query(TransactionsQuery{Sort: TransactionsSortAmountDesc})))
+	require.Equal(t,
+		[]string{"btc-pending", "btc-send", "btc-receive", "ltc-self", "ltc-receive"},
+		internalIDs(query(TransactionsQuery{Sort: TransactionsSortAmountAsc})))
+
+	// Pagination.
+	page = query(TransactionsQuery{Limit: 2})
+	require.Equal(t, []string{"btc-pending", "btc-send"}, internalIDs(page))
+	require.Equal(t, 5, page.Total)
+	page = query(TransactionsQuery{Limit: 2, Cursor: page.NextCursor})
+	require.Equal(t, []string{"ltc-receive", "btc-receive"}, internalIDs(page))
+	page = query(TransactionsQuery{Limit: 2, Cursor: page.NextCursor})
+	require.Equal(t, []string{"ltc-self"}, internalIDs(page))
+	require.Empty(t, page.NextCursor)
+
+	// Filters.
+	filter := func(filter accounts.TransactionsFilter) []string {
+		return internalIDs(query(TransactionsQuery{TransactionsFilter: filter}))
+	}
+	require.Equal(t, []string{"btc-receive"}, filter(accounts.TransactionsFilter{Note: "salary"}))
+	require.Equal(t, []string{"btc-send"}, filter(accounts.TransactionsFilter{Address: "ALICE"}))
+	require.Equal(t,
+		[]string{"btc-send", "ltc-receive"},
+		filter(accounts.TransactionsFilter{From: 2000, To: 3000}))
+	require.Equal(t,
+		[]string{"ltc-self"},
+		filter(accounts.TransactionsFilter{Types: []accounts.TxType{accounts.TxTypeSendSelf}}))
+	require.Equal(t,
+		[]string{"btc-pending"},
+		filter(accounts.TransactionsFilter{Statuses: []accounts.TxStatus{accounts.TxStatusPending}}))
+	require.Equal(t,
+		[]string{"ltc-receive", "btc-receive", "ltc-self"},
+		filter(accounts.TransactionsFilter{MinAmount: "1"}))
+	require.Equal(t,
+		[]string{"btc-pending", "btc-send"},
+		filter(accounts.TransactionsFilter{MaxAmount: "0.002"}))
+	require.Equal(t,
+		[]string{"btc-receive", "ltc-self"},
+		filter(accounts.TransactionsFilter{MinConfirmations: 30}))
+
+	// The transactions are loaded and sorted once until the account syncs again.
+	require.Equal(t, 1, btcTransactionsCalls)
+	b.resetTransactionsQueryCache(b.Accounts().lookup(btcCode))
+	require.Equal(t, 5, query(TransactionsQuery{}).Total)
+	require.Equal(t, 2, btcTransactionsCalls)
+
+	// Invalid queries.
+	for _, invalid := range []TransactionsQuery{
+		{Sort: "unknown"},
+		{Limit: -1},
+		{Cursor: "invalid"},
+		{TransactionsFilter: accounts.TransactionsFilter{From: 2, To: 1}},
+		{TransactionsFilter: accounts.TransactionsFilter{MinAmount: "abc"}},
+		{AccountCodes: []accountsTypes.Code{"unknown"}},
+	} {
+		_, err := b.QueryTransactions(invalid)
+		require.Error(t, err)
+	}
+}
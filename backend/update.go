@@ -66,7 +66,7 @@ func newUpdateChecker(proxy *socksproxy.SocksProxy, userAgent string) *updateChe
 // checkForUpdate checks whether a newer version of this application has been released.
 // It returns the retrieved update file if a newer version has been released and nil otherwise.
 func checkForUpdate(ctx context.Context, proxy *socksproxy.SocksProxy, userAgent string) (*UpdateFile, error) {
-	client, err := proxy.GetHTTPClient()
+	client, err := proxy.ServiceHTTPClient(socksproxy.ServiceApp, "")
 	if err != nil {
 		return nil, errp.WithStack(err)
 	}
@@ -183,15 +183,101 @@ export type TChartData = {
   chartDataMissing: boolean;
   chartDataDaily: ChartData;
   chartDataHourly: ChartData;
+  // only filled if requested with the 'weekly' resolution
+  chartDataWeekly: ChartData;
   chartFiat: ConversionUnit;
+  // chartFiat, or the coin unit if requested in coin units
+  chartUnit: string;
   chartTotal: number | null;
   formattedChartTotal: string | null;
   chartIsUpToDate: boolean; // only valid if chartDataMissing is false
   lastTimestamp: number;
 };
 
-export const getChartData = (): Promise<TChartDataResponse> => {
-  return apiGet('chart-data');
+export type TChartResolution = 'hourly' | 'daily' | 'weekly';
+
+export type TChartDataArgs = {
+  // restricts the chart to these accounts
+  accountCodes?: AccountCode[];
+  // restricts the chart to the accounts of one keystore
+  rootFingerprint?: string;
+  // 'coin' if all selected accounts hold the same coin, the main fiat currency by default
+  unit?: 'fiat' | 'coin';
+  // daily and hourly points by default
+  resolution?: TChartResolution;
+};
+
+export const getChartData = (args: TChartDataArgs = {}): Promise<TChartDataResponse> => {
+  const params = new URLSearchParams();
+  args.accountCodes?.forEach(code => params.append('accountCode', code));
+  if (args.rootFingerprint) {
+    params.set('rootFingerprint', args.rootFingerprint);
+  }
+  if (args.unit) {
+    params.set('unit', args.unit);
+  }
+  if (args.resolution) {
+    params.set('resolution', args.resolution);
+  }
+  const query = params.toString();
+  return apiGet(query ? `chart-data?${query}` : 'chart-data');
+};
+
+export type TBalanceSnapshotArgs = {
+  // unix timestamp in seconds, defaults to now
+  at?: number;
+  // block height of the chain of `coin`, only accounts of this coin and its tokens are included
+  height?: number;
+  coin?: CoinCode;
+  // defaults to the main fiat currency
+  fiat?: Fiat;
+};
+
+export type TBalanceSnapshotAccount = {
+  code: AccountCode;
+  name: string;
+  coinCode: CoinCode;
+  unit: string;
+  // empty if dataMissing
+  balance: string;
+  // empty if no price was available
+  fiatValue: string;
+  dataMissing: boolean;
+};
+
+export type TBalanceSnapshot = {
+  at: string;
+  height?: number;
+  fiat: Fiat;
+  accounts: TBalanceSnapshotAccount[];
+  total: string;
+  incomplete: boolean;
+};
+
+export const getBalanceSnapshot = (
+  args: TBalanceSnapshotArgs = {},
+): Promise<{ success: true; snapshot: TBalanceSnapshot } | { success: false; errorMessage?: string }> => {
+  const params = new URLSearchParams();
+  if (args.at) {
+    params.set('at', args.at.toString());
+  }
+  if (args.height) {
+    params.set('height', args.height.toString());
+  }
+  if (args.coin) {
+    params.set('coin', args.coin);
+  }
+  if (args.fiat) {
+    params.set('fiat', args.fiat);
+  }
+  const query = params.toString();
+  return apiGet(query ? `balance-snapshot?${query}` : 'balance-snapshot');
+};
+
+export const exportBalanceSnapshot = (
+  args: TBalanceSnapshotArgs & { format: 'csv' | 'json' },
+): Promise<{ success: boolean; message?: string; aborted: boolean }> => {
+  return apiPost('balance-snapshot/export', args);
 };
 
 type Conversions = {
@@ -210,6 +296,8 @@ export type TBalance = {
   available: TAmountWithConversions;
   hasIncoming: boolean;
   incoming: TAmountWithConversions;
+  hasFrozen: boolean;
+  frozen: TAmountWithConversions;
 };
 
 type TBalanceResponse = {
@@ -226,8 +314,16 @@ export const getBalance = (code: AccountCode): Promise<TBalanceResponse> => {
 export type TTransactionStatus = 'complete' | 'pending' | 'failed';
 export type TTransactionType = 'send' | 'receive' | 'send_to_self';
 
+export type TTransactionContact = {
+  id: string;
+  name: string;
+  verified: boolean;
+};
+
 export type TTransaction = {
   addresses: string[];
+  // address book contacts paid by the transaction
+  contacts: TTransactionContact[];
   amount: TAmountWithConversions;
   amountAtTime: TAmountWithConversions;
   fee: TAmountWithConversions;
@@ -262,6 +358,14 @@ export const postNotesTx = (code: AccountCode, {
   return apiPost(`account/${code}/notes/tx`, { internalTxID, note });
 };
 
+export const postNotesAddress = (code: AccountCode, address: string, note: string): Promise<null> => {
+  return apiPost(`account/${code}/notes/address`, { address, note });
+};
+
+export const postNotesOutput = (code: AccountCode, outPoint: string, note: string): Promise<null> => {
+  return apiPost(`account/${code}/notes/output`, { outPoint, note });
+};
+
 export const getTransactionList = (code: AccountCode): Promise<TTransactions> => {
   return apiGet(`account/${code}/transactions`);
 };
@@ -276,8 +380,32 @@ type TExport = {
   errorMessage: string;
 };
 
-export const exportAccount = (code: AccountCode): Promise<TExport | null> => {
-  return apiPost(`account/${code}/export`);
+export type TExportFormat = 'default' | 'koinly' | 'cointracking' | 'universal';
+
+export type TExportOptions = {
+  format?: TExportFormat;
+  // unix timestamps in seconds (inclusive), no limit if omitted
+  from?: number;
+  to?: number;
+  // currency of the fiat values, defaults to the main currency
+  fiat?: Fiat;
+};
+
+export const exportAccount = (
+  code: AccountCode,
+  options?: TExportOptions,
+): Promise<TExport | null> => {
+  return apiPost(`account/${code}/export`, options);
+};
+
+/**
+ * Exports the transactions of several accounts combined in one file. Only the tax tool formats
+ * are supported. All active accounts are exported if `accountCodes` is empty.
+ */
+export const exportTransactions = (
+  options: TExportOptions & { format: Exclude<TExportFormat, 'default'>; accountCodes?: AccountCode[] },
+): Promise<{ success: boolean; message?: string; aborted: boolean }> => {
+  return apiPost('export', options);
 };
 
 export const verifyXPub = (
@@ -339,6 +467,14 @@ export type TTxInput = {
   sendAll: 'yes' | 'no';
   selectedUTXOs: string[];
   paymentRequest: Slip24 | null;
+  // Calldata of ETH contract calls, either hex encoded in `data`, or ABI-encoded from the ABI JSON,
+  // function name or signature, and arguments.
+  data?: string;
+  contractAbi?: string;
+  contractFunction?: string;
+  contractArgs?: unknown[];
+  // confirms sending to a recipient whose address looks like the address of a contact
+  confirmResemblingContacts?: boolean;
 } & (
   {
     useHighestFee: false;
@@ -351,20 +487,30 @@ export type TTxInput = {
 
 export type TTxProposalErrorCode =
   | 'accountNotSynced'
+  | 'ensNameNotFound'
   | 'feeTooLow'
   | 'feesNotAvailable'
   | 'insufficientFunds'
   | 'invalidAddress'
-  | 'invalidAmount';
+  | 'invalidAmount'
+  | 'invalidData'
+  | 'resemblingContact';
 
 export type TTxProposalResult = {
   amount: TAmountWithConversions;
   fee: TAmountWithConversions;
   recipientDisplayAddress: string;
+  recipientEnsName?: string;
+  data?: string;
+  recipientContact?: TTransactionContact;
+  // contacts whose address looks like the recipient address, but is not the same (address poisoning)
+  resemblingContacts?: TTransactionContact[];
   success: true;
   total: TAmountWithConversions;
 } | {
   errorCode?: TTxProposalErrorCode;
+  // set with errorCode 'resemblingContact'
+  resemblingContacts?: TTransactionContact[];
   success: false;
 };
 
@@ -405,6 +551,9 @@ export type FeeTargetCode = 'custom' | 'low' | 'economy' | 'normal' | 'high' | '
 export type TFeeTarget = {
   code: FeeTargetCode;
   feeRateInfo: string;
+  // Only set for Ethereum fee targets estimated from the fee history of a self-hosted node.
+  expectedInclusionSeconds?: number;
+  baseFeeSpiking?: boolean;
 };
 
 export type TFeeTargetList = {
@@ -427,9 +576,12 @@ export type TUTXO = {
   address: string;
   amount: TAmountWithConversions;
   note: string;
+  outputNote: string;
+  addressNote: string;
   scriptType: ScriptType;
   addressReused: boolean;
   isChange: boolean;
+  frozen: boolean;
   headerTimestamp: string | null;
 };
 
@@ -437,6 +589,10 @@ export const getUTXOs = (code: AccountCode): Promise<TUTXO[]> => {
   return apiGet(`account/${code}/utxos`);
 };
 
+export const setUTXOsFrozen = (code: AccountCode, outPoints: string[], frozen: boolean): Promise<null> => {
+  return apiPost(`account/${code}/utxos/frozen`, { outPoints, frozen });
+};
+
 type TSecureOutput = {
   hasSecureOutput: boolean;
   optional: boolean;
@@ -476,28 +632,242 @@ export const addAccount = (coinCode: string, name: string): Promise<TAddAccount>
   });
 };
 
-export type TSignMessage = { success: false; aborted?: boolean; errorMessage?: string } | { success: true; signature: string };
+export type TSignMessage = {
+  success: false;
+  aborted?: boolean;
+  errorMessage?: string;
+  errorCode?: 'unconfirmedWarnings';
+  preview?: TEthPreview;
+} | {
+  success: true;
+  signature: string;
+  preview?: TEthPreview;
+};
 
 export type TSignWalletConnectTx = {
   success: false;
   aborted?: boolean;
   errorMessage?: string;
+  errorCode?: 'unconfirmedWarnings';
+  preview?: TEthPreview;
 } | {
   success: true;
   txHash: string;
   rawTx: string;
+  preview?: TEthPreview;
+};
+
+/**
+ * The dApp signing functions take the URL of the requesting dApp as `origin`, which is recorded
+ * in the audit log of signing requests. Typed data and transactions are only signed if all
+ * warnings of their preview are passed in `confirmedWarnings`, otherwise the call fails with the
+ * `unconfirmedWarnings` error code and the preview.
+ */
+export const ethSignMessage = (code: AccountCode, message: string, origin: string): Promise<TSignMessage> => {
+  return apiPost(`account/${code}/eth-sign-msg`, { message, origin });
+};
+
+export const ethSignTypedMessage = (
+  code: AccountCode,
+  chainId: number,
+  data: any,
+  origin: string,
+  confirmedWarnings: TEthPreviewWarning[],
+): Promise<TSignMessage> => {
+  return apiPost(`account/${code}/eth-sign-typed-msg`, { chainId, data, origin, confirmedWarnings });
+};
+
+export const ethSignWalletConnectTx = (
+  code: AccountCode,
+  send: boolean,
+  chainId: number,
+  tx: any,
+  origin: string,
+  confirmedWarnings: TEthPreviewWarning[],
+): Promise<TSignWalletConnectTx> => {
+  return apiPost(`account/${code}/eth-sign-wallet-connect-tx`, { send, chainId, tx, origin, confirmedWarnings });
+};
+
+export type TEthPreviewWarning =
+  | 'approvalForAll'
+  | 'chainIdMismatch'
+  | 'unknownRecipient'
+  | 'unlimitedApproval';
+
+export type TEthPreview = {
+  kind: 'transfer' | 'erc20Transfer' | 'erc20Approve' | 'setApprovalForAll' | 'contractCall' | 'permit' | 'permit2' | 'typedData';
+  contract?: string;
+  method?: string;
+  fields: { name: string; value: string }[];
+  counterparty?: string;
+  chainId?: number;
+  warnings: TEthPreviewWarning[];
+};
+
+export type TEthPreviewResult = {
+  success: true;
+  preview: TEthPreview;
+} | {
+  success: false;
+  errorMessage?: string;
+};
+
+export const getEthWalletConnectTxPreview = (code: AccountCode, chainId: number, tx: any): Promise<TEthPreviewResult> => {
+  return apiPost(`account/${code}/eth-wallet-connect-tx-preview`, { chainId, tx });
+};
+
+export const getEthTypedMessagePreview = (code: AccountCode, chainId: number, data: any): Promise<TEthPreviewResult> => {
+  return apiPost(`account/${code}/eth-typed-msg-preview`, { chainId, data });
+};
+
+export type TEthSigningRequest = {
+  time: string;
+  origin: string;
+  method: 'signMessage' | 'signTypedData' | 'signTransaction' | 'sendTransaction';
+  chainId: number;
+  preview?: TEthPreview;
+  outcome: 'signed' | 'sent' | 'aborted' | 'failed' | 'refused';
+  txHash?: string;
+  error?: string;
+};
+
+export const getEthSigningRequests = (code: AccountCode): Promise<TEthSigningRequest[]> => {
+  return apiGet(`account/${code}/eth-signing-requests`);
 };
 
-export const ethSignMessage = (code: AccountCode, message: string): Promise<TSignMessage> => {
-  return apiPost(`account/${code}/eth-sign-msg`, message);
+export type TEthAllowance = {
+  tokenAddress: string;
+  symbol: string;
+  spenderAddress: string;
+  amount: string;
+  unlimited: boolean;
+};
+
+export type TEthAllowances = {
+  success: true;
+  allowances: TEthAllowance[];
+} | {
+  success: false;
+  errorMessage?: string;
+};
+
+export const getEthAllowances = (code: AccountCode): Promise<TEthAllowances> => {
+  return apiGet(`account/${code}/eth-allowances`);
+};
+
+/**
+ * Proposes a transaction setting the allowance of the spender to zero. The proposal is sent
+ * using `sendTx()`.
+ */
+export const proposeEthRevokeAllowance = (
+  code: AccountCode,
+  tokenAddress: string,
+  spenderAddress: string,
+  feeTarget: FeeTargetCode,
+  customFee: string,
+): Promise<TTxProposalResult> => {
+  return apiPost(`account/${code}/eth-revoke-allowance-proposal`, {
+    tokenAddress, spenderAddress, feeTarget, customFee,
+  });
+};
+
+export type TEthNFT = {
+  contract: string;
+  standard: 'erc721' | 'erc1155';
+  tokenId: string;
+  balance: string;
+  metadataUri: string;
+};
+
+export type TEthNFTs = {
+  success: true;
+  nfts?: TEthNFT[];
+} | {
+  success: false;
+  errorMessage?: string;
 };
 
-export const ethSignTypedMessage = (code: AccountCode, chainId: number, data: any): Promise<TSignMessage> => {
-  return apiPost(`account/${code}/eth-sign-typed-msg`, { chainId, data });
+export const getEthNFTs = (code: AccountCode): Promise<TEthNFTs> => {
+  return apiGet(`account/${code}/eth-nfts`);
 };
 
-export const ethSignWalletConnectTx = (code: AccountCode, send: boolean, chainId: number, tx: any): Promise<TSignWalletConnectTx> => {
-  return apiPost(`account/${code}/eth-sign-wallet-connect-tx`, { send, chainId, tx });
+/**
+ * Rebuilds the NFT inventory from the token transfers of the account.
+ */
+export const refreshEthNFTs = (code: AccountCode): Promise<TEthNFTs> => {
+  return apiPost(`account/${code}/eth-nfts-refresh`);
+};
+
+export type TEthNFTMetadata = {
+  success: true;
+  metadata: unknown;
+} | {
+  success: false;
+  errorMessage?: string;
+  errorCode?: 'nftMetadataDisabled' | 'nftMetadataHostBlocked';
+};
+
+/**
+ * Fetches the metadata of a token through the configured proxy. Remote metadata is only fetched
+ * if the user opted in (`nftMetadata` in the backend config) and the connections are proxied.
+ */
+export const getEthNFTMetadata = (
+  code: AccountCode,
+  contract: string,
+  tokenId: string,
+): Promise<TEthNFTMetadata> => {
+  return apiGet(`account/${code}/eth-nft-metadata?contract=${contract}&tokenId=${tokenId}`);
+};
+
+export type TEthNonceState = {
+  confirmedNonce: number;
+  pendingNonce: number;
+  nextNonce: number;
+  blocker: {
+    nonce: number;
+    txID: string;
+    blockedTxIDs: string[];
+  } | null;
+  conflicts: { nonce: number; txID: string }[];
+};
+
+export type TEthNonceStateResponse = {
+  success: true;
+  state: TEthNonceState;
+} | {
+  success: false;
+  errorMessage?: string;
+};
+
+/**
+ * Detects pending transactions stuck behind a nonce gap, and pending transactions conflicting
+ * with confirmed transactions using the same nonce.
+ */
+export const getEthNonceState = (code: AccountCode): Promise<TEthNonceStateResponse> => {
+  return apiGet(`account/${code}/eth-nonce-state`);
+};
+
+export const rebroadcastEthTx = (
+  code: AccountCode,
+  txID: string,
+): Promise<SuccessResponse | { success: false; errorMessage?: string }> => {
+  return apiPost(`account/${code}/eth-rebroadcast-tx`, txID);
+};
+
+/**
+ * Proposes a zero value transaction to the account's own address with the given nonce, which
+ * fills the nonce gap or replaces the pending transaction with this nonce. The proposal is sent
+ * using `sendTx()`.
+ */
+export const proposeEthReplaceNonce = (
+  code: AccountCode,
+  nonce: number,
+  feeTarget: FeeTargetCode,
+  customFee: string,
+): Promise<TTxProposalResult> => {
+  return apiPost(`account/${code}/eth-replace-nonce-proposal`, {
+    nonce, feeTarget, customFee,
+  });
 };
 
 type TAddressSignResponse = {
@@ -541,6 +911,7 @@ export type TUsedAddress = {
   addressType: 'receive' | 'change';
   canSignMsg: boolean;
   lastUsed: string | null;
+  note: string;
 };
 
 export type TUsedAddressesResponse = {
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+
+import { apiGet, apiPost } from '@/utils/request';
+import type { AccountCode, NativeCoinCode } from './account';
+
+export type TVerificationMethod = 'testSend' | 'signedMessage';
+
+export type TContact = {
+  id: string;
+  coinCode: NativeCoinCode;
+  name: string;
+  // Either address or xpub is set.
+  address?: string;
+  xpub?: string;
+  notes: string;
+  verified: boolean;
+  verificationMethod?: TVerificationMethod;
+  verifiedAt?: string;
+  created: string;
+};
+
+type TResponse = {
+  success: true;
+} | {
+  success: false;
+  errorMessage?: string;
+};
+
+export type TContactResponse = {
+  success: true;
+  contact: TContact;
+} | {
+  success: false;
+  errorMessage?: string;
+};
+
+export const getAddressBook = (coinCode?: NativeCoinCode): Promise<TContact[]> => {
+  return apiGet(coinCode ? `address-book?coinCode=${coinCode}` : 'address-book');
+};
+
+export const addContact = (
+  contact: Pick<TContact, 'coinCode' | 'name' | 'address' | 'xpub' | 'notes'>,
+): Promise<TContactResponse> => {
+  return apiPost('address-book/add', contact);
+};
+
+export const updateContact = (
+  contact: Pick<TContact, 'id' | 'name' | 'address' | 'xpub' | 'notes'>,
+): Promise<TContactResponse> => {
+  return apiPost('address-book/update', contact);
+};
+
+export const deleteContact = (id: string): Promise<TResponse> => {
+  return apiPost('address-book/delete', id);
+};
+
+export const verifyContactTestSend = (
+  id: string,
+  accountCode: AccountCode,
+  txID: string,
+): Promise<TResponse> => {
+  return apiPost('address-book/verify/test-send', { id, accountCode, txID });
+};
+
+export const verifyContactSignedMessage = (
+  id: string,
+  message: string,
+  signature: string,
+): Promise<TResponse> => {
+  return apiPost('address-book/verify/signed-message', { id, message, signature });
+};
@@ -0,0 +1,29 @@
+// SPDX-License-Identifier: Apache-2.0
+
+import { apiPost } from '@/utils/request';
+import type { FailResponse, SuccessResponse } from './response';
+
+export type TAppBackupRestoreMode = 'merge' | 'replace';
+
+export type TAppBackupErrorCode = 'wrongPassword' | 'passwordTooShort';
+
+type TAppBackupFailResponse = FailResponse & {
+  errorCode?: TAppBackupErrorCode;
+};
+
+export const exportAppBackup = (
+  password: string,
+): Promise<(TAppBackupFailResponse & { aborted: boolean }) | SuccessResponse> => {
+  return apiPost('app-backup/export', { password });
+};
+
+export const restoreAppBackup = (
+  fileContents: ArrayBuffer,
+  password: string,
+  mode: TAppBackupRestoreMode,
+): Promise<TAppBackupFailResponse | SuccessResponse> => {
+  const hexString = Array.from(new Uint8Array(fileContents))
+    .map(byte => byte.toString(16).padStart(2, '0'))
+    .join('');
+  return apiPost('app-backup/restore', { fileContents: hexString, password, mode });
+};
@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: Apache-2.0
+
+import { apiGet, apiPost } from '@/utils/request';
+import { subscribeEndpoint, TSubscriptionCallback, TUnsubscribe } from './subscribe';
+import type { FailResponse, SuccessResponse } from './response';
+
+export type TAtRestEncryptionStatus = {
+  enabled: boolean;
+  // locked is true if the passphrase has to be entered to load the accounts.
+  locked: boolean;
+};
+
+export type TAtRestEncryptionErrorCode =
+  | 'atRestLocked'
+  | 'wrongPassphrase'
+  | 'passphraseTooShort'
+  | 'authRequired';
+
+type TAtRestEncryptionResponse = (FailResponse & {
+  errorCode?: TAtRestEncryptionErrorCode;
+}) | SuccessResponse;
+
+export const getAtRestEncryption = (): Promise<TAtRestEncryptionStatus> => {
+  return apiGet('at-rest-encryption');
+};
+
+/**
+ * Unlocks the notes, labels and accounts config. If authentication is enabled, the user has to
+ * authenticate first.
+ */
+export const unlockAtRestEncryption = (passphrase: string): Promise<TAtRestEncryptionResponse> => {
+  return apiPost('at-rest-encryption/unlock', { passphrase });
+};
+
+export const enableAtRestEncryption = (passphrase: string): Promise<TAtRestEncryptionResponse> => {
+  return apiPost('at-rest-encryption/enable', { passphrase });
+};
+
+export const changeAtRestPassphrase = (
+  passphrase: string,
+  newPassphrase: string,
+): Promise<TAtRestEncryptionResponse> => {
+  return apiPost('at-rest-encryption/change-passphrase', { passphrase, newPassphrase });
+};
+
+export const disableAtRestEncryption = (passphrase: string): Promise<TAtRestEncryptionResponse> => {
+  return apiPost('at-rest-encryption/disable', { passphrase });
+};
+
+export const subscribeAtRestEncryption = (
+  cb: TSubscriptionCallback<TAtRestEncryptionStatus>
+): TUnsubscribe => {
+  return subscribeEndpoint('at-rest-encryption', cb);
+};
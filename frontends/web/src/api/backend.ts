@@ -148,6 +148,11 @@ export const exportNotes = (): Promise<(FailResponse & { aborted: boolean }) | S
 type TImportNotes = {
   accountCount: number;
   transactionCount: number;
+  addressCount: number;
+  pubkeyCount: number;
+  inputCount: number;
+  outputCount: number;
+  preservedCount: number;
 };
 
 export const importNotes = (fileContents: ArrayBuffer): Promise<FailResponse | (SuccessResponse & { data: TImportNotes })> => {
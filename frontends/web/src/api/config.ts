@@ -24,9 +24,17 @@ type TEthCoinConfig = Readonly<{
   activeERC20Tokens: string[];
 }>;
 
+export type TProxyService = 'electrum' | 'ethereum' | 'rates' | 'app' | 'market' | 'other';
+
+export type TProxyRoute = 'direct' | 'proxy' | 'disabled';
+
 export type TConfigBackendProxy = Readonly<{
   useProxy: boolean;
   proxyAddress: string;
+  // Services without a route use the proxy if useProxy is true.
+  routes?: Partial<Record<TProxyService, TProxyRoute>>;
+  // Use a separate Tor circuit for each service and account.
+  isolateStreams: boolean;
 }>;
 
 /** Keys used by NewBadge to mark UI elements as seen. */
@@ -93,6 +101,13 @@ export type TConfigBackend = Readonly<{
   litecoinActive: boolean;
   ethereumActive: boolean;
   authentication: boolean;
+  // Only the blockchain backends are contacted in the strict privacy mode.
+  strictPrivacy: boolean;
+  // Records the redacted URLs, without the query and ID-like path segments, in the connection log
+  // instead of only the hosts.
+  connectionLogURLs: boolean;
+  // Fetches NFT metadata from its hosts also if the connections are not proxied.
+  nftMetadata: boolean;
   btc: TBtcCoinConfig;
   tbtc: TBtcCoinConfig;
   rbtc: TBtcCoinConfig;
@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: Apache-2.0
+
+import { apiGet, apiPost } from '@/utils/request';
+import type { AccountCode, Fiat } from './account';
+import type { FailResponse, SuccessResponse } from './response';
+
+export type TLotMethod = 'fifo' | 'lifo' | 'hifo' | 'average';
+
+export type TGainsReportArgs = {
+  method: TLotMethod;
+  // defaults to the main fiat currency
+  fiat?: Fiat;
+  // unix timestamp in seconds, defaults to now
+  at?: number;
+  // restricts the report to one account
+  accountCode?: AccountCode;
+};
+
+export type TYearGains = {
+  year: number;
+  proceeds: number;
+  costBasis: number;
+  gain: number;
+};
+
+export type TDisposal = {
+  time: string;
+  taxYear: number;
+  accountCode: AccountCode;
+  coinCode: string;
+  unit: string;
+  txID: string;
+  kind: 'send' | 'fee';
+  amount: string;
+  acquiredAt: string | null;
+  proceeds: number;
+  costBasis: number;
+  gain: number;
+};
+
+export type TAccountGains = {
+  code: AccountCode;
+  name: string;
+  coinCode: string;
+  unit: string;
+  holdings: string;
+  costBasis: number;
+  marketValue: number;
+  unrealizedGain: number;
+  priceMissing: boolean;
+  realizedByYear: TYearGains[];
+};
+
+export type TGainsReport = {
+  method: TLotMethod;
+  fiat: Fiat;
+  at: string;
+  accounts: TAccountGains[];
+  disposals: TDisposal[];
+  realizedByYear: TYearGains[];
+  costBasis: number;
+  marketValue: number;
+  unrealizedGain: number;
+  missingPrices: string[];
+  incomplete: boolean;
+};
+
+export const getGainsReport = ({
+  method, fiat, at, accountCode,
+}: TGainsReportArgs): Promise<
+  { success: true; report: TGainsReport }
+  | { success: false; errorMessage?: string; errorCode?: 'missingPrices' }
+> => {
+  const params = new URLSearchParams({ method });
+  if (fiat) {
+    params.set('fiat', fiat);
+  }
+  if (at) {
+    params.set('at', at.toString());
+  }
+  if (accountCode) {
+    params.set('accountCode', accountCode);
+  }
+  return apiGet(`gains-report?${params.toString()}`);
+};
+
+export const exportGainsReport = (
+  args: TGainsReportArgs,
+): Promise<(FailResponse & { aborted: boolean }) | SuccessResponse> => {
+  return apiPost('gains-report/export', args);
+};
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: Apache-2.0
+
+import { apiGet, apiPost } from '@/utils/request';
+import type { TProxyService } from './config';
+
+export type TConnectionLogResult = 'blocked' | 'ok' | 'error' | 'connected' | 'closed';
+
+export type TConnectionLogEntry = {
+  time: string;
+  service: TProxyService;
+  // The host of a request, or host:port of a TCP connection.
+  host: string;
+  // Only set if recording URLs is enabled. The query is never recorded.
+  url?: string;
+  proxied: boolean;
+  bytesSent: number;
+  bytesReceived: number;
+  statusCode?: number;
+  result: TConnectionLogResult;
+  // e.g. 'blockedByPrivacyMode', 'serviceDisabled' or the error message.
+  reason?: string;
+};
+
+export type TConnectionLogQuery = {
+  service?: TProxyService;
+  host?: string;
+  result?: TConnectionLogResult;
+  // RFC3339 timestamp.
+  since?: string;
+  limit?: number;
+};
+
+export type TConnectionLog = {
+  success: true;
+  strictPrivacy: boolean;
+  connectionLogURLs: boolean;
+  // The newest first.
+  entries: TConnectionLogEntry[];
+} | {
+  success: false;
+  errorMessage?: string;
+};
+
+type TSuccessResponse = { success: true } | { success: false; errorMessage?: string };
+
+/**
+ * Enables or disables the strict privacy mode, in which only the blockchain backends are
+ * contacted. It applies immediately.
+ */
+export const setStrictPrivacy = (enabled: boolean): Promise<TSuccessResponse> => {
+  return apiPost('strict-privacy', { enabled });
+};
+
+/**
+ * Enables or disables recording the URLs of the requests in the connection log. By default, only
+ * the hosts are recorded.
+ */
+export const setConnectionLogURLs = (enabled: boolean): Promise<TSuccessResponse> => {
+  return apiPost('connection-log/record-urls', { enabled });
+};
+
+export const getConnectionLog = (query: TConnectionLogQuery = {}): Promise<TConnectionLog> => {
+  const params = new URLSearchParams();
+  Object.entries(query).forEach(([key, value]) => {
+    if (value !== undefined && value !== '') {
+      params.set(key, String(value));
+    }
+  });
+  const search = params.toString();
+  return apiGet(search ? `connection-log?${search}` : 'connection-log');
+};
+
+export const exportConnectionLog = (
+  query: TConnectionLogQuery = {},
+): Promise<{ success: true } | { success: false; aborted: boolean; message?: string }> => {
+  return apiPost('connection-log/export', query);
+};
+
+export const clearConnectionLog = (): Promise<TSuccessResponse> => {
+  return apiPost('connection-log/clear');
+};
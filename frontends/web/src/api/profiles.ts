@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: Apache-2.0
+
+import { apiGet, apiPost } from '@/utils/request';
+import { subscribeEndpoint, TSubscriptionCallback, TUnsubscribe } from './subscribe';
+
+export type TProfile = {
+  id: string;
+  name: string;
+  created: string;
+  /** True if the password is required to switch to the profile. It does not encrypt the profile data. */
+  switcherLocked: boolean;
+};
+
+export type TProfiles = {
+  supported: boolean;
+  profiles: TProfile[];
+  active: string;
+};
+
+export type TProfileErrorCode =
+  | 'profileNotFound'
+  | 'wrongPassword'
+  | 'invalidName'
+  | 'nameExists'
+  | 'profileActive'
+  | 'defaultProfile';
+
+export type TProfileResponse = {
+  success: true;
+  profile?: TProfile;
+} | {
+  success: false;
+  errorMessage?: string;
+  errorCode?: TProfileErrorCode;
+};
+
+export const getProfiles = (): Promise<TProfiles> => {
+  return apiGet('profiles');
+};
+
+export const createProfile = (name: string, password: string): Promise<TProfileResponse> => {
+  return apiPost('profiles/create', { name, password });
+};
+
+export const renameProfile = (id: string, name: string): Promise<TProfileResponse> => {
+  return apiPost('profiles/rename', { id, name });
+};
+
+/**
+ * Changes the password of a profile. An empty newPassword removes the password protection.
+ */
+export const setProfilePassword = (
+  id: string,
+  password: string,
+  newPassword: string,
+): Promise<TProfileResponse> => {
+  return apiPost('profiles/set-password', { id, password, newPassword });
+};
+
+export const deleteProfile = (id: string, password: string): Promise<TProfileResponse> => {
+  return apiPost('profiles/delete', { id, password });
+};
+
+/**
+ * Restarts the backend with the given profile. `subscribeProfileSwitched` is called when the
+ * backend of the profile is running.
+ */
+export const switchProfile = (id: string, password: string): Promise<TProfileResponse> => {
+  return apiPost('profiles/switch', { id, password });
+};
+
+export const subscribeProfileSwitched = (
+  cb: TSubscriptionCallback<TProfiles>
+): TUnsubscribe => {
+  return subscribeEndpoint('profiles', cb);
+};
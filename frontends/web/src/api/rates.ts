@@ -1,7 +1,105 @@
 // SPDX-License-Identifier: Apache-2.0
 
-import { apiPost } from '@/utils/request';
+import { apiGet, apiPost } from '@/utils/request';
+import type { FailResponse, SuccessResponse } from './response';
 
 export const reconfigureHistoryRates = (): Promise<null> => {
   return apiPost('rates/reconfigure-history');
 };
+
+export type TRatesProvider = 'coingecko' | 'bitstamp' | 'static';
+
+export type TRatesSources = {
+  // keyed by coin unit and fiat, e.g. latest['BTC']['USD']
+  latest: Record<string, Record<string, TRatesProvider>>;
+  // keyed by coin code and fiat, e.g. history['btcUSD']
+  history: Record<string, TRatesProvider>;
+};
+
+export const getRatesSources = (): Promise<TRatesSources> => {
+  return apiGet('rates/sources');
+};
+
+export type TExchangeRatesFormat = 'csv' | 'json';
+
+export const exportExchangeRates = (
+  format: TExchangeRatesFormat,
+): Promise<(FailResponse & { aborted: boolean }) | SuccessResponse> => {
+  return apiPost('rates/export', format);
+};
+
+export type TImportExchangeRates = {
+  imported: number;
+  skipped: number;
+  rejected: number;
+  // rates which would leave a gap in the history, which are not imported.
+  disconnected: number;
+  pairs: string[];
+};
+
+export const importExchangeRates = (
+  fileContents: ArrayBuffer,
+): Promise<FailResponse | (SuccessResponse & { data: TImportExchangeRates })> => {
+  const hexString = Array.from(new Uint8Array(fileContents))
+    .map(byte => byte.toString(16).padStart(2, '0'))
+    .join('');
+  return apiPost('rates/import', hexString);
+};
+
+export type TPriceAlertType = 'above' | 'below' | 'change';
+
+export type TPriceAlert = {
+  id: string;
+  // backend coin code, e.g. 'btc' or 'eth-erc20-usdt'
+  coin: string;
+  fiat: string;
+  type: TPriceAlertType;
+  // price for 'above' and 'below', change in percent for 'change'
+  threshold: number;
+  windowHours?: number;
+  cooldownMinutes?: number;
+  lastTriggered?: string;
+  // true if the condition held at the last evaluation
+  active?: boolean;
+};
+
+export type TPriceAlertTrigger = {
+  alertID: string;
+  coin: string;
+  fiat: string;
+  type: TPriceAlertType;
+  threshold: number;
+  price: number;
+  referencePrice?: number;
+  time: string;
+};
+
+export type TPriceAlerts = {
+  alerts: TPriceAlert[];
+  // newest first
+  history: TPriceAlertTrigger[];
+};
+
+export const getPriceAlerts = (): Promise<TPriceAlerts> => {
+  return apiGet('price-alerts');
+};
+
+export type TAddPriceAlertResponse = {
+  success: true;
+  alert: TPriceAlert;
+} | {
+  success: false;
+  errorMessage?: string;
+};
+
+export const addPriceAlert = (
+  alert: Omit<TPriceAlert, 'id' | 'lastTriggered' | 'active'>,
+): Promise<TAddPriceAlertResponse> => {
+  return apiPost('price-alerts/add', alert);
+};
+
+export const deletePriceAlert = (
+  id: string,
+): Promise<SuccessResponse | { success: false; errorMessage?: string }> => {
+  return apiPost('price-alerts/delete', id);
+};
@@ -1,6 +1,8 @@
 // SPDX-License-Identifier: Apache-2.0
 
+import { apiPost } from '@/utils/request';
 import type { TUnsubscribe } from '@/utils/transport-common';
+import type { AccountCode, CoinCode, TTransaction, TTransactionStatus, TTransactionType } from './account';
 import { TSubscriptionCallback, subscribeEndpoint } from './subscribe';
 
 export type TNewTxs = {
@@ -11,3 +13,47 @@ export type TNewTxs = {
 export const syncNewTxs = (cb: TSubscriptionCallback<TNewTxs>): TUnsubscribe => {
   return subscribeEndpoint('new-txs', cb);
 };
+
+export type TTransactionsSort = 'newest' | 'oldest' | 'amountDesc' | 'amountAsc';
+
+export type TTransactionsQuery = {
+  // empty or missing for all active accounts
+  accountCodes?: AccountCode[];
+  // unix timestamps in seconds, inclusive
+  from?: number;
+  to?: number;
+  types?: TTransactionType[];
+  statuses?: TTransactionStatus[];
+  // decimal amounts in the unit of the coin of each account
+  minAmount?: string;
+  maxAmount?: string;
+  address?: string;
+  note?: string;
+  minConfirmations?: number;
+  sort?: TTransactionsSort;
+  // nextCursor of the previous page
+  cursor?: string;
+  // page size, defaults to 50, at most 500
+  limit?: number;
+};
+
+export type TQueriedTransaction = TTransaction & {
+  accountCode: AccountCode;
+  accountName: string;
+  coinCode: CoinCode;
+};
+
+export type TTransactionsQueryResponse = {
+  success: true;
+  list: TQueriedTransaction[];
+  // missing on the last page
+  nextCursor?: string;
+  total: number;
+} | {
+  success: false;
+  errorMessage?: string;
+};
+
+export const queryTransactions = (query: TTransactionsQuery): Promise<TTransactionsQueryResponse> => {
+  return apiPost('transactions/query', query);
+};
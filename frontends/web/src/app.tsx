@@ -18,6 +18,7 @@ import { syncAccountsList } from './api/accountsync';
 import { getDeviceList } from './api/devices';
 import { syncDeviceList } from './api/devicessync';
 import { syncNewTxs } from './api/transactions';
+import { subscribeProfileSwitched } from './api/profiles';
 import { notifyUser } from './api/system';
 import { ConnectedApp } from './connected';
 import { Alert } from './components/alert/Alert';
@@ -133,6 +134,11 @@ export const App = () => {
     });
   }, [t]);
 
+  useEffect(() => {
+    // All state of the app belongs to the backend of the previous profile.
+    return subscribeProfileSwitched(() => window.location.reload());
+  }, []);
+
   const maybeRoute = useCallback(() => {
     const currentURL = window.location.hash.replace(/^#/, '');
     const isIndex = currentURL === '' || currentURL === '/';
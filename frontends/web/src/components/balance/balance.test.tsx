@@ -32,6 +32,12 @@ describe('components/balance/balance', () => {
     const MOCK_BALANCE: TBalance = {
       hasAvailable: true,
       hasIncoming: true,
+      hasFrozen: false,
+      frozen: {
+        amount: '0',
+        unit: 'BTC',
+        estimated: false,
+      },
       available: {
         amount: '0.005',
         unit: 'BTC',
@@ -107,6 +113,12 @@ describe('components/balance/balance', () => {
     const MOCK_BALANCE: TBalance = {
       hasAvailable: true,
       hasIncoming: true,
+      hasFrozen: false,
+      frozen: {
+        amount: '0',
+        unit: 'BTC',
+        estimated: false,
+      },
       available: {
         amount: '0.005',
         unit: 'BTC',
@@ -10,6 +10,7 @@ import { syncdone } from '@/api/accountsync';
 import { convertFromCurrency, convertToCurrency, parseExternalBtcAmount, type BtcUnit } from '@/api/coins';
 import { View, ViewContent } from '@/components/view/view';
 import { alertUser } from '@/components/alert/Alert';
+import { confirmation } from '@/components/confirm/Confirm';
 import { Balance } from '@/components/balance/balance';
 import { HideAmountsButton } from '@/components/hideamountsbutton/hideamountsbutton';
 import { Button } from '@/components/forms';
@@ -85,6 +86,10 @@ export const Send = ({
   const [note, setNote] = useState<string>('');
   const [customFee, setCustomFee] = useState<string>('');
   const [errorHandling, setErrorHandling] = useState<TProposalError>({});
+  // recipient the user confirmed sending to even though it looks like the address of a contact
+  const [confirmedResemblingRecipient, setConfirmedResemblingRecipient] = useState<string>('');
+  // recipient the user was last asked about, so that declining does not ask again on every proposal
+  const askedResemblingRecipientRef = useRef<string>('');
 
   const [proposedFee, setProposedFee] = useState<accountApi.TAmountWithConversions>();
   const [proposedTotal, setProposedTotal] = useState<accountApi.TAmountWithConversions>();
@@ -155,9 +160,10 @@ export const Send = ({
       sendAll: (sendAll ? 'yes' : 'no'),
       selectedUTXOs: Object.keys(selectedUTXOsRef.current),
       paymentRequest: null,
-      useHighestFee: false
+      useHighestFee: false,
+      confirmResemblingContacts: recipientInput === confirmedResemblingRecipient,
     };
-  }, [recipientInput, feeTarget, sendAll, amount, customFee]);
+  }, [recipientInput, feeTarget, sendAll, amount, customFee, confirmedResemblingRecipient]);
 
   const convertToFiat = useCallback(async (amount: string) => {
     if (amount) {
@@ -217,6 +223,18 @@ export const Send = ({
       const errorHandling = txProposalErrorHandling(result.errorCode);
       setErrorHandling(errorHandling);
       setIsUpdatingProposal(false);
+      if (
+        result.errorCode === 'resemblingContact'
+        && askedResemblingRecipientRef.current !== recipientInput
+      ) {
+        askedResemblingRecipientRef.current = recipientInput;
+        const names = (result.resemblingContacts || []).map(contact => contact.name).join(', ');
+        confirmation(t('send.error.resemblingContactConfirm', { names }), confirmed => {
+          if (confirmed) {
+            setConfirmedResemblingRecipient(recipientInput);
+          }
+        });
+      }
 
       if (
         errorHandling.amountError
@@ -226,7 +244,7 @@ export const Send = ({
       }
       setRecipientDisplayAddress('');
     }
-  }, [convertToFiat]);
+  }, [convertToFiat, recipientInput, t]);
 
   const validateAndDisplayFee = useCallback((
     updateFiat: boolean = true,
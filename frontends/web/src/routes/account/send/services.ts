@@ -13,7 +13,8 @@ export const txProposalErrorHandling = (errorCode?: string): TProposalError => {
   const { t } = i18n;
   switch (errorCode) {
   case 'invalidAddress':
-    return { addressError: t('send.error.invalidAddress') };
+  case 'resemblingContact':
+    return { addressError: t(`send.error.${errorCode}`) };
   case 'invalidAmount':
   case 'insufficientFunds':
     return { amountError: t(`send.error.${errorCode}`) };
@@ -3,8 +3,9 @@
 import { t } from 'i18next';
 import { SessionTypes } from '@walletconnect/types';
 import { EIP155_SIGNING_METHODS, decodeEthMessage } from './walletconnect';
-import { ethSignMessage, ethSignTypedMessage, ethSignWalletConnectTx, getEthAccountCodeAndNameByAddress } from '@/api/account';
+import { ethSignMessage, ethSignTypedMessage, ethSignWalletConnectTx, getEthAccountCodeAndNameByAddress, TEthPreviewWarning } from '@/api/account';
 import { alertUser } from '@/components/alert/Alert';
+import { confirmation } from '@/components/confirm/Confirm';
 
 type TWCParams = {
   request: {
@@ -39,6 +40,35 @@ export type TLaunchSignDialog = {
   dialogContent: TRequestDialogContent;
 };
 
+const dappOrigin = (session: SessionTypes.Struct) => session.peer.metadata.url;
+
+const confirmWarnings = (warnings: TEthPreviewWarning[]) => new Promise<boolean>(resolve => {
+  const message = [
+    t('walletConnect.signingRequest.confirmWarnings'),
+    ...warnings.map(warning => t(`walletConnect.signingRequest.warning.${warning}`)),
+  ].join('\n\n');
+  confirmation(message, resolve);
+});
+
+/**
+ * Calls `sign` without confirmed warnings first. If the backend refuses to sign because the
+ * request has warnings, the user is asked to confirm them and signing is retried with the
+ * confirmed warnings.
+ */
+const signConfirmingWarnings = async <T extends { success: boolean; errorCode?: string; preview?: { warnings: TEthPreviewWarning[] } }>(
+  sign: (confirmedWarnings: TEthPreviewWarning[]) => Promise<T>,
+): Promise<T> => {
+  const result = await sign([]);
+  if (result.success || result.errorCode !== 'unconfirmedWarnings' || !result.preview) {
+    return result;
+  }
+  const warnings = result.preview.warnings;
+  if (!await confirmWarnings(warnings)) {
+    return { ...result, aborted: true };
+  }
+  return sign(warnings);
+};
+
 const fetchAccountNameAndAddress = async (address: string) => {
   const accountDetail = await getEthAccountCodeAndNameByAddress(address);
   if (!accountDetail.success) {
@@ -94,7 +124,7 @@ const ethSignHandler = async ({
   }
   const { accountName, accountCode, displayAddress } = await fetchAccountNameAndAddress(accountAddress);
   const apiCaller = async () => {
-    const result = await ethSignMessage(accountCode, signingData);
+    const result = await ethSignMessage(accountCode, signingData, dappOrigin(currentSession));
     if (!result.success) {
       return { success: false, error: result };
     }
@@ -148,7 +178,9 @@ const ethSignTypedDataHandler = async ({
     const chainId = typedData?.domain?.chainId ?
       Number(typedData.domain.chainId) :
       Number(params.chainId.replace(/^eip155:/, ''));
-    const result = await ethSignTypedMessage(accountCode, chainId, data);
+    const result = await signConfirmingWarnings(confirmedWarnings => (
+      ethSignTypedMessage(accountCode, chainId, data, dappOrigin(currentSession), confirmedWarnings)
+    ));
     if (result.success) {
       const response = { id, jsonrpc: '2.0', result: result.signature };
       return { response, success: true };
@@ -189,7 +221,9 @@ const ethSignOrSendTransactionHandler = async (
   const apiCaller = async () => {
     // If the typed data to be signed includes its own chainId, we use that, otherwise use the id in the params
     const chainId = Number(params.chainId.replace(/^eip155:/, ''));
-    const result = await ethSignWalletConnectTx(accountCode, isSendAndSign, chainId, data);
+    const result = await signConfirmingWarnings(confirmedWarnings => (
+      ethSignWalletConnectTx(accountCode, isSendAndSign, chainId, data, dappOrigin(currentSession), confirmedWarnings)
+    ));
     if (result.success) {
       const response = { id, jsonrpc: '2.0', result: isSendAndSign ? result.txHash : result.rawTx };
       return { response, success: true };
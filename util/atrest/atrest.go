@@ -0,0 +1,449 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package atrest provides optional encryption at rest of files containing private data, e.g. the
+// notes and the accounts config.
+//
+// The files are encrypted with a random data key. The data key is stored in the keyring file,
+// encrypted with a key derived from the user's passphrase. Changing the passphrase creates a new
+// data key, so that rekeying also replaces the key of all files. Files encrypted with a previous
+// data key remain readable until they are rewritten, which makes rekeying safe to interrupt.
+package atrest
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	utilconfig "github.com/BitBoxSwiss/bitbox-wallet-app/util/config"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/locker"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// MinPassphraseLen is the minimum length of the passphrase.
+	MinPassphraseLen = 8
+
+	// magic prefixes encrypted files. It is followed by the version, the key ID, the nonce and the
+	// ciphertext.
+	magic    = "BBAPPENC"
+	version  = 1
+	keyIDLen = 8
+	// headerLen is the length of the magic, the version and the key ID.
+	headerLen = len(magic) + 1 + keyIDLen
+
+	keyringVersion = 1
+	kdfName        = "scrypt"
+	scryptN        = 1 << 15
+	scryptR        = 8
+	scryptP        = 1
+)
+
+const (
+	// ErrLocked is returned when reading or writing encrypted files before the keyring is unlocked.
+	ErrLocked errp.ErrorCode = "atRestLocked"
+	// ErrWrongPassphrase is returned if the passphrase can't decrypt the data keys.
+	ErrWrongPassphrase errp.ErrorCode = "wrongPassphrase"
+	// ErrPassphraseTooShort is returned if a new passphrase is shorter than MinPassphraseLen.
+	ErrPassphraseTooShort errp.ErrorCode = "passphraseTooShort"
+)
+
+// IsEncrypted returns true if the file contents are encrypted.
+func IsEncrypted(contents []byte) bool {
+	return bytes.HasPrefix(contents, []byte(magic))
+}
+
+type kdfParams struct {
+	Name string `json:"name"`
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt []byte `json:"salt"`
+}
+
+func (params kdfParams) deriveKey(passphrase string) (cipher.AEAD, error) {
+	if params.Name != kdfName {
+		return nil, errp.Newf("unsupported key derivation %q", params.Name)
+	}
+	key, err := scrypt.Key([]byte(passphrase), params.Salt, params.N, params.R, params.P, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return aead, nil
+}
+
+// wrappedKey is a data key encrypted with the key derived from the passphrase.
+type wrappedKey struct {
+	ID         string `json:"id"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// keyringFile is the contents of the keyring file.
+type keyringFile struct {
+	Version int       `json:"version"`
+	KDF     kdfParams `json:"kdf"`
+	// Current is the ID of the data key used to encrypt files.
+	Current string `json:"current"`
+	// Keys are the data keys. Keys other than the current one are previous keys, kept until all
+	// files are re-encrypted with the current key.
+	Keys []wrappedKey `json:"keys"`
+}
+
+// Keyring holds the data keys to read and write encrypted files. A nil keyring reads and writes
+// files in plaintext.
+type Keyring struct {
+	filename string
+	// data is nil if encryption is disabled.
+	data *keyringFile
+	// keys are the data keys by key ID. nil while locked.
+	keys map[string][]byte
+	// plaintext is true while the files are decrypted to disable the encryption.
+	plaintext bool
+	lock      locker.Locker
+}
+
+// NewKeyring loads the keyring from the given file. Encryption is disabled if the file does not
+// exist.
+func NewKeyring(filename string) (*Keyring, error) {
+	keyring := &Keyring{filename: filename}
+	jsonBytes, err := os.ReadFile(filename)
+	switch {
+	case os.IsNotExist(err):
+		return keyring, nil
+	case err != nil:
+		return nil, errp.WithStack(err)
+	}
+	var data keyringFile
+	if err := json.Unmarshal(jsonBytes, &data); err != nil {
+		return nil, errp.WithMessage(err, "invalid keyring file")
+	}
+	if data.Version != keyringVersion {
+		return nil, errp.New("unsupported keyring file")
+	}
+	current := false
+	for _, wrapped := range data.Keys {
+		current = current || wrapped.ID == data.Current
+	}
+	if !current {
+		return nil, errp.New("invalid keyring file")
+	}
+	keyring.data = &data
+	return keyring, nil
+}
+
+// Enabled returns true if files are encrypted.
+func (keyring *Keyring) Enabled() bool {
+	if keyring == nil {
+		return false
+	}
+	defer keyring.lock.RLock()()
+	return keyring.data != nil
+}
+
+// Locked returns true if files are encrypted, but the passphrase was not entered yet.
+func (keyring *Keyring) Locked() bool {
+	if keyring == nil {
+		return false
+	}
+	defer keyring.lock.RLock()()
+	return keyring.data != nil && keyring.keys == nil
+}
+
+// unwrap decrypts the data keys with the passphrase. The caller must hold the lock.
+func (keyring *Keyring) unwrap(passphrase string) (map[string][]byte, error) {
+	if keyring.data == nil {
+		return nil, errp.New("encryption at rest is not enabled")
+	}
+	kek, err := keyring.data.KDF.deriveKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	keys := map[string][]byte{}
+	for _, wrapped := range keyring.data.Keys {
+		if len(wrapped.Nonce) != kek.NonceSize() {
+			return nil, errp.New("invalid keyring file")
+		}
+		key, err := kek.Open(nil, wrapped.Nonce, wrapped.Ciphertext, []byte(wrapped.ID))
+		if err != nil {
+			return nil, errp.WithStack(ErrWrongPassphrase)
+		}
+		keys[wrapped.ID] = key
+	}
+	return keys, nil
+}
+
+// Unlock decrypts the data keys with the passphrase, so that encrypted files can be read and
+// written.
+func (keyring *Keyring) Unlock(passphrase string) error {
+	defer keyring.lock.Lock()()
+	keys, err := keyring.unwrap(passphrase)
+	if err != nil {
+		return err
+	}
+	keyring.keys = keys
+	return nil
+}
+
+// store adds a new current data key and stores the data keys wrapped with a key derived from the
+// passphrase. The caller must hold the write lock.
+func (keyring *Keyring) store(passphrase string, keys map[string][]byte) error {
+	if len(passphrase) < MinPassphraseLen {
+		return errp.WithStack(ErrPassphraseTooShort)
+	}
+	salt := make([]byte, 16)
+	id := make([]byte, keyIDLen)
+	key := make([]byte, chacha20poly1305.KeySize)
+	for _, b := range [][]byte{salt, id, key} {
+		if _, err := rand.Read(b); err != nil {
+			return errp.WithStack(err)
+		}
+	}
+	currentID := hex.EncodeToString(id)
+	keys[currentID] = key
+
+	data := &keyringFile{
+		Version: keyringVersion,
+		KDF:     kdfParams{Name: kdfName, N: scryptN, R: scryptR, P: scryptP, Salt: salt},
+		Current: currentID,
+	}
+	kek, err := data.KDF.deriveKey(passphrase)
+	if err != nil {
+		return err
+	}
+	for keyID, key := range keys {
+		nonce := make([]byte, kek.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return errp.WithStack(err)
+		}
+		data.Keys = append(data.Keys, wrappedKey{
+			ID:         keyID,
+			Nonce:      nonce,
+			Ciphertext: kek.Seal(nil, nonce, key, []byte(keyID)),
+		})
+	}
+	if err := keyring.save(data); err != nil {
+		return err
+	}
+	keyring.data = data
+	keyring.keys = keys
+	return nil
+}
+
+func (keyring *Keyring) save(data *keyringFile) error {
+	jsonBytes, err := json.MarshalIndent(data, "", "    ")
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	if err := os.WriteFile(keyring.filename, jsonBytes, utilconfig.PrivateFileMode); err != nil {
+		return errp.WithStack(err)
+	}
+	return utilconfig.EnsurePrivateFile(keyring.filename)
+}
+
+// dropPreviousKeys removes all keys except the current one, after all files were re-encrypted.
+func (keyring *Keyring) dropPreviousKeys() error {
+	defer keyring.lock.Lock()()
+	data := *keyring.data
+	data.Keys = nil
+	for _, wrapped := range keyring.data.Keys {
+		if wrapped.ID == data.Current {
+			data.Keys = append(data.Keys, wrapped)
+		} else {
+			delete(keyring.keys, wrapped.ID)
+		}
+	}
+	if err := keyring.save(&data); err != nil {
+		return err
+	}
+	keyring.data = &data
+	return nil
+}
+
+// Enable enables the encryption with the given passphrase. rewrite has to rewrite all files with
+// `WriteFile()`, which encrypts them.
+func (keyring *Keyring) Enable(passphrase string, rewrite func() error) error {
+	err := func() error {
+		defer keyring.lock.Lock()()
+		if keyring.data != nil {
+			return errp.New("encryption at rest is already enabled")
+		}
+		return keyring.store(passphrase, map[string][]byte{})
+	}()
+	if err != nil {
+		return err
+	}
+	return rewrite()
+}
+
+// ChangePassphrase changes the passphrase and rekeys the files with a new data key. rewrite has
+// to rewrite all files with `WriteFile()`, which encrypts them with the new data key. If rewrite
+// fails or is interrupted, the files not rewritten yet remain readable with the previous key.
+func (keyring *Keyring) ChangePassphrase(passphrase string, newPassphrase string, rewrite func() error) error {
+	err := func() error {
+		defer keyring.lock.Lock()()
+		keys, err := keyring.unwrap(passphrase)
+		if err != nil {
+			return err
+		}
+		return keyring.store(newPassphrase, keys)
+	}()
+	if err != nil {
+		return err
+	}
+	if err := rewrite(); err != nil {
+		return err
+	}
+	return keyring.dropPreviousKeys()
+}
+
+// Disable disables the encryption. rewrite has to rewrite all files with `WriteFile()`, which
+// writes them in plaintext. The keyring file is deleted afterwards.
+func (keyring *Keyring) Disable(passphrase string, rewrite func() error) error {
+	err := func() error {
+		defer keyring.lock.Lock()()
+		keys, err := keyring.unwrap(passphrase)
+		if err != nil {
+			return err
+		}
+		keyring.keys = keys
+		keyring.plaintext = true
+		return nil
+	}()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		defer keyring.lock.Lock()()
+		keyring.plaintext = false
+	}()
+	if err := rewrite(); err != nil {
+		return err
+	}
+	defer keyring.lock.Lock()()
+	if err := os.Remove(keyring.filename); err != nil {
+		return errp.WithStack(err)
+	}
+	keyring.data = nil
+	keyring.keys = nil
+	return nil
+}
+
+// aead returns the cipher of the data key with the given ID. The caller must hold the lock.
+func (keyring *Keyring) aead(keyID string) (cipher.AEAD, error) {
+	if keyring.keys == nil {
+		return nil, errp.WithStack(ErrLocked)
+	}
+	key, ok := keyring.keys[keyID]
+	if !ok {
+		return nil, errp.New("file encrypted with an unknown key")
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return aead, nil
+}
+
+// associatedData binds the ciphertext to the header and the name of the file, so that encrypted
+// files can't be swapped.
+func associatedData(header []byte, filename string) []byte {
+	return append(append([]byte{}, header...), filepath.Base(filename)...)
+}
+
+// Decrypt decrypts the contents of the given file. Plaintext contents are returned as they are,
+// so that files written before the encryption was enabled can still be read.
+func (keyring *Keyring) Decrypt(filename string, contents []byte) ([]byte, error) {
+	if !IsEncrypted(contents) {
+		return contents, nil
+	}
+	if keyring == nil {
+		return nil, errp.WithStack(ErrLocked)
+	}
+	defer keyring.lock.RLock()()
+	if len(contents) < headerLen+chacha20poly1305.NonceSizeX || contents[len(magic)] != version {
+		return nil, errp.Newf("unsupported encrypted file %s", filename)
+	}
+	header := contents[:headerLen]
+	aead, err := keyring.aead(hex.EncodeToString(header[len(magic)+1:]))
+	if err != nil {
+		return nil, err
+	}
+	nonce := contents[headerLen : headerLen+aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, contents[headerLen+aead.NonceSize():], associatedData(header, filename))
+	if err != nil {
+		return nil, errp.Newf("could not decrypt %s", filename)
+	}
+	return plaintext, nil
+}
+
+// Encrypt encrypts the contents of the given file with the current data key. The contents are
+// returned as they are if the encryption is disabled.
+func (keyring *Keyring) Encrypt(filename string, plaintext []byte) ([]byte, error) {
+	if keyring == nil {
+		return plaintext, nil
+	}
+	defer keyring.lock.RLock()()
+	if keyring.data == nil || keyring.plaintext {
+		return plaintext, nil
+	}
+	aead, err := keyring.aead(keyring.data.Current)
+	if err != nil {
+		return nil, err
+	}
+	keyID, err := hex.DecodeString(keyring.data.Current)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	header := append(append([]byte(magic), version), keyID...)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	contents := append(append([]byte{}, header...), nonce...)
+	return aead.Seal(contents, nonce, plaintext, associatedData(header, filename)), nil
+}
+
+// ReadFile reads and decrypts a file, see `Decrypt()`.
+func (keyring *Keyring) ReadFile(filename string) ([]byte, error) {
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return keyring.Decrypt(filename, contents)
+}
+
+// WriteFile encrypts and writes a file, see `Encrypt()`.
+func (keyring *Keyring) WriteFile(filename string, plaintext []byte, perm os.FileMode) error {
+	contents, err := keyring.Encrypt(filename, plaintext)
+	if err != nil {
+		return err
+	}
+	return errp.WithStack(os.WriteFile(filename, contents, perm))
+}
+
+// RewriteFile reads a file and writes it again, e.g. to encrypt it with the current data key.
+// Files which do not exist are skipped.
+func (keyring *Keyring) RewriteFile(filename string) error {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	plaintext, err := keyring.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return keyring.WriteFile(filename, plaintext, info.Mode().Perm())
+}
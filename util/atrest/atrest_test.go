@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package atrest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/test"
+	"github.com/stretchr/testify/require"
+)
+
+const passphrase = "correct horse battery staple"
+
+func TestNilKeyring(t *testing.T) {
+	var keyring *Keyring
+	require.False(t, keyring.Enabled())
+	require.False(t, keyring.Locked())
+	contents, err := keyring.Encrypt("file.json", []byte("plaintext"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("plaintext"), contents)
+	plaintext, err := keyring.Decrypt("file.json", contents)
+	require.NoError(t, err)
+	require.Equal(t, []byte("plaintext"), plaintext)
+	_, err = keyring.Decrypt("file.json", []byte(magic+"..."))
+	require.Equal(t, ErrLocked, errp.Cause(err))
+}
+
+func TestKeyring(t *testing.T) {
+	dir := test.TstTempDir("atrest")
+	defer func() { _ = os.RemoveAll(dir) }()
+	keyringFilename := filepath.Join(dir, "keyring.json")
+	notesFilename := filepath.Join(dir, "notes.json")
+	accountsFilename := filepath.Join(dir, "accounts.json")
+	files := map[string]string{
+		notesFilename:    `{"transactions":{"txid":"salary"}}`,
+		accountsFilename: `{"accounts":[]}`,
+	}
+	for filename, contents := range files {
+		require.NoError(t, os.WriteFile(filename, []byte(contents), 0600))
+	}
+
+	keyring, err := NewKeyring(keyringFilename)
+	require.NoError(t, err)
+	require.False(t, keyring.Enabled())
+	require.False(t, keyring.Locked())
+
+	rewrite := func() error {
+		for filename := range files {
+			if err := keyring.RewriteFile(filename); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	requireFiles := func(keyring *Keyring, encrypted bool) {
+		t.Helper()
+		for filename, expected := range files {
+			contents, err := os.ReadFile(filename)
+			require.NoError(t, err)
+			require.Equal(t, encrypted, IsEncrypted(contents))
+			plaintext, err := keyring.ReadFile(filename)
+			require.NoError(t, err)
+			require.Equal(t, expected, string(plaintext))
+		}
+	}
+
+	require.Equal(t, ErrPassphraseTooShort, errp.Cause(keyring.Enable("short", rewrite)))
+	require.False(t, keyring.Enabled())
+	require.NoError(t, keyring.Enable(passphrase, rewrite))
+	require.True(t, keyring.Enabled())
+	require.False(t, keyring.Locked())
+	requireFiles(keyring, true)
+	contents, err := os.ReadFile(notesFilename)
+	require.NoError(t, err)
+	require.NotContains(t, string(contents), "salary")
+	require.Error(t, keyring.Enable(passphrase, rewrite))
+
+	// Encrypted files can't be swapped.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.json"), contents, 0600))
+	_, err = keyring.ReadFile(filepath.Join(dir, "other.json"))
+	require.Error(t, err)
+
+	// After a restart, the keyring is locked until the passphrase is entered.
+	keyring, err = NewKeyring(keyringFilename)
+	require.NoError(t, err)
+	require.True(t, keyring.Enabled())
+	require.True(t, keyring.Locked())
+	_, err = keyring.ReadFile(notesFilename)
+	require.Equal(t, ErrLocked, errp.Cause(err))
+	require.Equal(t, ErrLocked, errp.Cause(keyring.WriteFile(notesFilename, []byte("{}"), 0600)))
+	require.Equal(t, ErrWrongPassphrase, errp.Cause(keyring.Unlock("wrong passphrase")))
+	require.True(t, keyring.Locked())
+	require.NoError(t, keyring.Unlock(passphrase))
+	require.False(t, keyring.Locked())
+	requireFiles(keyring, true)
+
+	// Rekeying. Files not rewritten yet remain readable with the previous key.
+	const newPassphrase = "new passphrase"
+	require.Equal(t, ErrWrongPassphrase, errp.Cause(keyring.ChangePassphrase("wrong passphrase", newPassphrase, rewrite)))
+	var interrupted error = errp.New("interrupted")
+	require.Equal(t, interrupted, keyring.ChangePassphrase(passphrase, newPassphrase, func() error {
+		return interrupted
+	}))
+	keyring, err = NewKeyring(keyringFilename)
+	require.NoError(t, err)
+	require.Equal(t, ErrWrongPassphrase, errp.Cause(keyring.Unlock(passphrase)))
+	require.NoError(t, keyring.Unlock(newPassphrase))
+	requireFiles(keyring, true)
+	require.NoError(t, keyring.ChangePassphrase(newPassphrase, passphrase, rewrite))
+	requireFiles(keyring, true)
+	keyring, err = NewKeyring(keyringFilename)
+	require.NoError(t, err)
+	require.Len(t, keyring.data.Keys, 1)
+	require.NoError(t, keyring.Unlock(passphrase))
+	requireFiles(keyring, true)
+
+	// Disabling decrypts the files.
+	require.Equal(t, ErrWrongPassphrase, errp.Cause(keyring.Disable("wrong passphrase", rewrite)))
+	require.NoError(t, keyring.Disable(passphrase, rewrite))
+	require.False(t, keyring.Enabled())
+	require.NoFileExists(t, keyringFilename)
+	requireFiles(keyring, false)
+	requireFiles(nil, false)
+}
@@ -0,0 +1,407 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package connlog records the outbound connections of the app, so that users can audit which hosts
+// the app contacts.
+package connlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/atrest"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/locker"
+)
+
+// Result is the outcome of a connection attempt.
+type Result string
+
+const (
+	// ResultBlocked means the connection was not made, e.g. because of the strict privacy mode.
+	ResultBlocked Result = "blocked"
+	// ResultOK means a http request succeeded. The status code can still be an error.
+	ResultOK Result = "ok"
+	// ResultError means the connection or request failed.
+	ResultError Result = "error"
+	// ResultConnected means a TCP connection was established. Another entry is recorded with
+	// `ResultClosed` when it is closed.
+	ResultConnected Result = "connected"
+	// ResultClosed means a TCP connection was closed. The entry contains the bytes transferred.
+	ResultClosed Result = "closed"
+)
+
+// Entry is an outbound connection or http request. By default, it contains only the host, not the
+// URL, so that the log does not reveal e.g. the addresses queried.
+type Entry struct {
+	Time time.Time `json:"time"`
+	// Service is the class of service which made the connection, e.g. "rates".
+	Service string `json:"service"`
+	Host    string `json:"host"`
+	// URL is the redacted URL of a http request, if recording URLs is enabled. See `RedactURL()`.
+	URL string `json:"url,omitempty"`
+	// Proxied is true if the connection was made through the SOCKS5 proxy.
+	Proxied bool `json:"proxied"`
+	// BytesSent and BytesReceived are the payload bytes, not counting the headers of http
+	// requests.
+	BytesSent     int64 `json:"bytesSent"`
+	BytesReceived int64 `json:"bytesReceived"`
+	// StatusCode is the status code of a http request.
+	StatusCode int    `json:"statusCode,omitempty"`
+	Result     Result `json:"result"`
+	// Reason explains the result, e.g. why the connection was blocked or failed.
+	Reason string `json:"reason,omitempty"`
+}
+
+const (
+	// redacted replaces the path segments of recorded URLs which can contain private data.
+	redacted = "redacted"
+	// maxPlainSegmentLen and maxPlainSegmentDigits bound the path segments of recorded URLs which
+	// are kept. Longer segments, or segments with more digits, are IDs, addresses or API keys.
+	maxPlainSegmentLen    = 16
+	maxPlainSegmentDigits = 3
+)
+
+func plainSegment(segment string) bool {
+	if len(segment) > maxPlainSegmentLen {
+		return false
+	}
+	digits := 0
+	for _, char := range segment {
+		switch {
+		case char >= '0' && char <= '9':
+			digits++
+		case char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z', char == '-', char == '_', char == '.':
+		default:
+			return false
+		}
+	}
+	return digits <= maxPlainSegmentDigits
+}
+
+// RedactURL returns the URL of a request as recorded in `Entry.URL`. The credentials, the query
+// and the fragment are dropped, as they can contain API keys or addresses. Path segments which can
+// contain them as well, e.g. `/v3/<api key>` or `/address/<address>`, are replaced by "redacted".
+func RedactURL(requestURL *url.URL) string {
+	segments := strings.Split(requestURL.EscapedPath(), "/")
+	for i, segment := range segments {
+		if segment != "" && !plainSegment(segment) {
+			segments[i] = redacted
+		}
+	}
+	recordedURL := url.URL{
+		Scheme: requestURL.Scheme,
+		Host:   requestURL.Host,
+		Path:   strings.Join(segments, "/"),
+	}
+	return recordedURL.String()
+}
+
+// Query filters the entries of the log. Empty fields match all entries.
+type Query struct {
+	Service string `json:"service"`
+	Host    string `json:"host"`
+	Result  Result `json:"result"`
+	// Since matches the entries recorded at or after this time.
+	Since time.Time `json:"since"`
+	// Limit is the maximum number of entries returned. 0 means no limit.
+	Limit int `json:"limit"`
+}
+
+func (query *Query) matches(entry *Entry) bool {
+	return (query.Service == "" || entry.Service == query.Service) &&
+		(query.Host == "" || entry.Host == query.Host) &&
+		(query.Result == "" || entry.Result == query.Result) &&
+		!entry.Time.Before(query.Since)
+}
+
+// flushInterval is how often the entries added to a persisted log are written to its file.
+const flushInterval = 5 * time.Second
+
+// Log is a bounded log of connections. The oldest entries are dropped when it is full. A nil log
+// does not record anything.
+type Log struct {
+	maxEntries int
+	entries    []Entry
+	// recordURLs returns true if the URLs of http requests are recorded, see `Entry.URL`. nil
+	// means false.
+	recordURLs func() bool
+
+	// filename is the file the log is persisted to, one JSON entry per line. Empty if the log is
+	// not persisted.
+	filename string
+	// keyring encrypts the file if the encryption at rest is enabled.
+	keyring *atrest.Keyring
+	// loaded is false if the file could not be read yet because the keyring is locked. It is not
+	// written before it was loaded, as the entries in it would be lost.
+	loaded bool
+	// dirty is true if the entries changed since the file was written.
+	dirty bool
+	// quit stops the goroutine flushing the entries. nil if the log is not persisted.
+	quit chan struct{}
+	// done is closed when the flushing goroutine stopped.
+	done      chan struct{}
+	closeOnce sync.Once
+
+	lock locker.Locker
+	// fileLock serializes writing the file, which is done without holding `lock`, so that adding
+	// entries is never blocked by disk I/O.
+	fileLock locker.Locker
+}
+
+// NewLog creates a log which keeps the last maxEntries entries in memory.
+func NewLog(maxEntries int) *Log {
+	return &Log{maxEntries: maxEntries}
+}
+
+// OpenLog loads the log persisted in the given file, which does not have to exist, and keeps
+// persisting the last maxEntries entries. The entries are buffered and written periodically, see
+// `Flush()`. The file is encrypted with the keyring if the encryption at rest is enabled. If the
+// keyring is locked, the file is loaded once it is unlocked. Call `Close()` when done.
+func OpenLog(filename string, maxEntries int, keyring *atrest.Keyring) (*Log, error) {
+	log := NewLog(maxEntries)
+	log.filename = filename
+	log.keyring = keyring
+	if err := log.load(); err != nil && errp.Cause(err) != atrest.ErrLocked {
+		return nil, err
+	}
+	log.quit = make(chan struct{})
+	log.done = make(chan struct{})
+	go log.flushLoop()
+	return log, nil
+}
+
+// load reads the file and prepends its entries to the entries in memory. Invalid lines, e.g. a
+// line which was not written completely, are skipped.
+func (log *Log) load() error {
+	contents, err := log.keyring.ReadFile(log.filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	loaded := []Entry{}
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil {
+			loaded = append(loaded, entry)
+		}
+	}
+	defer log.lock.Lock()()
+	log.entries = append(loaded, log.entries...)
+	log.truncate()
+	log.loaded = true
+	return nil
+}
+
+// truncate drops the oldest entries if there are too many. The caller must hold the lock.
+func (log *Log) truncate() {
+	if len(log.entries) > log.maxEntries {
+		log.entries = append(log.entries[:0], log.entries[len(log.entries)-log.maxEntries:]...)
+	}
+}
+
+func (log *Log) flushLoop() {
+	defer close(log.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-log.quit:
+			return
+		case <-ticker.C:
+			// Errors are retried in the next round, e.g. if the keyring is still locked.
+			_ = log.flush(false)
+		}
+	}
+}
+
+// flush writes the entries to the file if they changed, or always if force is true.
+func (log *Log) flush(force bool) error {
+	if log == nil {
+		return nil
+	}
+	defer log.fileLock.Lock()()
+	if log.filename == "" {
+		return nil
+	}
+	unlock := log.lock.RLock()
+	loaded := log.loaded
+	unlock()
+	if !loaded {
+		if err := log.load(); err != nil {
+			return err
+		}
+	}
+
+	unlock = log.lock.Lock()
+	if !log.dirty && !force {
+		unlock()
+		return nil
+	}
+	var buffer bytes.Buffer
+	for _, entry := range log.entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			unlock()
+			return errp.WithStack(err)
+		}
+		buffer.Write(append(line, '\n'))
+	}
+	log.dirty = false
+	unlock()
+
+	err := func() error {
+		contents, err := log.keyring.Encrypt(log.filename, buffer.Bytes())
+		if err != nil {
+			return err
+		}
+		tmpFilename := log.filename + ".tmp"
+		if err := os.WriteFile(tmpFilename, contents, 0600); err != nil {
+			return errp.WithStack(err)
+		}
+		return errp.WithStack(os.Rename(tmpFilename, log.filename))
+	}()
+	if err != nil {
+		defer log.lock.Lock()()
+		log.dirty = true
+	}
+	return err
+}
+
+// Flush writes all entries to the file right away, e.g. to encrypt it with the current key of the
+// keyring.
+func (log *Log) Flush() error {
+	return log.flush(true)
+}
+
+// SetRecordURLs sets whether the URLs of http requests are recorded, see `Entry.URL`. nil means
+// false, i.e. only the hosts are recorded.
+func (log *Log) SetRecordURLs(recordURLs func() bool) {
+	if log == nil {
+		return
+	}
+	defer log.lock.Lock()()
+	log.recordURLs = recordURLs
+}
+
+// RecordsURLs returns true if the URLs of http requests are recorded.
+func (log *Log) RecordsURLs() bool {
+	if log == nil {
+		return false
+	}
+	defer log.lock.RLock()()
+	return log.recordURLs != nil && log.recordURLs()
+}
+
+// Add records an entry. If its time is not set, the current time is used. It is written to the
+// file asynchronously.
+func (log *Log) Add(entry Entry) {
+	if log == nil {
+		return
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	defer log.lock.Lock()()
+	log.entries = append(log.entries, entry)
+	log.truncate()
+	log.dirty = true
+}
+
+// Entries returns the recorded entries, the oldest first.
+func (log *Log) Entries() []Entry {
+	if log == nil {
+		return []Entry{}
+	}
+	defer log.lock.RLock()()
+	return append([]Entry{}, log.entries...)
+}
+
+// Query returns the entries matching the query, the newest first.
+func (log *Log) Query(query Query) []Entry {
+	result := []Entry{}
+	if log == nil {
+		return result
+	}
+	defer log.lock.RLock()()
+	for i := len(log.entries) - 1; i >= 0; i-- {
+		if query.Limit > 0 && len(result) == query.Limit {
+			break
+		}
+		if query.matches(&log.entries[i]) {
+			result = append(result, log.entries[i])
+		}
+	}
+	return result
+}
+
+// Clear removes all entries, also from the file.
+func (log *Log) Clear() error {
+	if log == nil {
+		return nil
+	}
+	unlock := log.lock.Lock()
+	log.entries = nil
+	log.dirty = true
+	unlock()
+	return log.flush(false)
+}
+
+// Close writes the remaining entries to the file of a persisted log and stops flushing it.
+func (log *Log) Close() error {
+	if log == nil || log.quit == nil {
+		return nil
+	}
+	var err error
+	log.closeOnce.Do(func() {
+		close(log.quit)
+		<-log.done
+		err = log.flush(false)
+		defer log.fileLock.Lock()()
+		log.filename = ""
+	})
+	return err
+}
+
+// WriteCSV writes the entries as CSV, e.g. to export them.
+func WriteCSV(writer io.Writer, entries []Entry) error {
+	csvWriter := csv.NewWriter(writer)
+	err := csvWriter.Write([]string{
+		"Time", "Service", "Host", "URL", "Proxied", "Bytes sent", "Bytes received", "Status code",
+		"Result", "Reason",
+	})
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	for _, entry := range entries {
+		statusCode := ""
+		if entry.StatusCode != 0 {
+			statusCode = strconv.Itoa(entry.StatusCode)
+		}
+		err := csvWriter.Write([]string{
+			entry.Time.Format(time.RFC3339),
+			entry.Service,
+			entry.Host,
+			entry.URL,
+			strconv.FormatBool(entry.Proxied),
+			strconv.FormatInt(entry.BytesSent, 10),
+			strconv.FormatInt(entry.BytesReceived, 10),
+			statusCode,
+			string(entry.Result),
+			entry.Reason,
+		})
+		if err != nil {
+			return errp.WithStack(err)
+		}
+	}
+	csvWriter.Flush()
+	return errp.WithStack(csvWriter.Error())
+}
@@ -0,0 +1,187 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package connlog
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/atrest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog(t *testing.T) {
+	var nilLog *Log
+	nilLog.Add(Entry{Service: "rates", Host: "example.com", Result: ResultBlocked})
+	require.Empty(t, nilLog.Entries())
+
+	log := NewLog(2)
+	require.Empty(t, log.Entries())
+	for _, host := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		log.Add(Entry{Service: "rates", Host: host, Result: ResultBlocked})
+	}
+	entries := log.Entries()
+	require.Len(t, entries, 2)
+	require.Equal(t, "b.example.com", entries[0].Host)
+	require.Equal(t, "c.example.com", entries[1].Host)
+	require.False(t, entries[0].Time.IsZero())
+
+	require.NoError(t, log.Clear())
+	require.Empty(t, log.Entries())
+}
+
+func TestQuery(t *testing.T) {
+	now := time.Now()
+	log := NewLog(10)
+	log.Add(Entry{Time: now.Add(-time.Hour), Service: "rates", Host: "a.example.com", Result: ResultOK})
+	log.Add(Entry{Time: now, Service: "rates", Host: "b.example.com", Result: ResultBlocked})
+	log.Add(Entry{Time: now, Service: "electrum", Host: "a.example.com", Result: ResultOK})
+
+	hosts := func(entries []Entry) []string {
+		result := []string{}
+		for _, entry := range entries {
+			result = append(result, entry.Service+"/"+entry.Host)
+		}
+		return result
+	}
+	require.Equal(t,
+		[]string{"electrum/a.example.com", "rates/b.example.com", "rates/a.example.com"},
+		hosts(log.Query(Query{})))
+	require.Equal(t,
+		[]string{"rates/b.example.com", "rates/a.example.com"},
+		hosts(log.Query(Query{Service: "rates"})))
+	require.Equal(t,
+		[]string{"electrum/a.example.com", "rates/a.example.com"},
+		hosts(log.Query(Query{Host: "a.example.com"})))
+	require.Equal(t,
+		[]string{"rates/b.example.com"},
+		hosts(log.Query(Query{Result: ResultBlocked})))
+	require.Equal(t,
+		[]string{"electrum/a.example.com", "rates/b.example.com"},
+		hosts(log.Query(Query{Since: now})))
+	require.Equal(t,
+		[]string{"electrum/a.example.com"},
+		hosts(log.Query(Query{Limit: 1})))
+}
+
+func TestOpenLog(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "connections.jsonl")
+	log, err := OpenLog(filename, 2, nil)
+	require.NoError(t, err)
+	require.Empty(t, log.Entries())
+	for _, host := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		log.Add(Entry{Service: "rates", Host: host, Result: ResultOK})
+	}
+	// The entries are written asynchronously.
+	_, err = os.Stat(filename)
+	require.True(t, os.IsNotExist(err))
+	require.NoError(t, log.Close())
+	require.NoError(t, log.Close())
+	contents, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(contents), "\n"))
+
+	// An incomplete last line is skipped.
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"service":"rat`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	log, err = OpenLog(filename, 2, nil)
+	require.NoError(t, err)
+	entries := log.Entries()
+	require.Len(t, entries, 2)
+	require.Equal(t, "b.example.com", entries[0].Host)
+	require.Equal(t, "c.example.com", entries[1].Host)
+
+	require.NoError(t, log.Clear())
+	contents, err = os.ReadFile(filename)
+	require.NoError(t, err)
+	require.Empty(t, contents)
+	require.NoError(t, log.Close())
+}
+
+func TestOpenLogEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "connections.jsonl")
+	keyringFilename := filepath.Join(dir, "encryption.json")
+	keyring, err := atrest.NewKeyring(keyringFilename)
+	require.NoError(t, err)
+	require.NoError(t, keyring.Enable("passphrase", func() error { return nil }))
+
+	log, err := OpenLog(filename, 10, keyring)
+	require.NoError(t, err)
+	log.Add(Entry{Service: "rates", Host: "a.example.com", Result: ResultOK})
+	require.NoError(t, log.Close())
+	contents, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	require.True(t, atrest.IsEncrypted(contents))
+	require.NotContains(t, string(contents), "a.example.com")
+
+	// While the keyring is locked, the entries are kept in memory and the file is not overwritten.
+	keyring, err = atrest.NewKeyring(keyringFilename)
+	require.NoError(t, err)
+	require.True(t, keyring.Locked())
+	log, err = OpenLog(filename, 10, keyring)
+	require.NoError(t, err)
+	log.Add(Entry{Service: "rates", Host: "b.example.com", Result: ResultOK})
+	require.Error(t, log.Flush())
+	require.Len(t, log.Entries(), 1)
+
+	require.NoError(t, keyring.Unlock("passphrase"))
+	require.NoError(t, log.Flush())
+	entries := log.Entries()
+	require.Len(t, entries, 2)
+	require.Equal(t, "a.example.com", entries[0].Host)
+	require.Equal(t, "b.example.com", entries[1].Host)
+	require.NoError(t, log.Close())
+}
+
+func TestRedactURL(t *testing.T) {
+	for rawURL, expected := range map[string]string{
+		"https://api.coingecko.com/api/v3/simple/price?ids=bitcoin":                              "https://api.coingecko.com/api/v3/simple/price",
+		"https://mainnet.infura.io/v3/0123456789abcdef0123456789abcdef":                          "https://mainnet.infura.io/v3/redacted",
+		"https://user:pw@example.com/address/0xa29163852021BF4C139D03Dff59ae763AC73e84e/txs#top": "https://example.com/address/redacted/txs",
+		"https://example.com/nft/12345":                                                          "https://example.com/nft/redacted",
+		"https://example.com/a%20b/":                                                             "https://example.com/redacted/",
+	} {
+		parsed, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		require.Equal(t, expected, RedactURL(parsed), rawURL)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buffer bytes.Buffer
+	require.NoError(t, WriteCSV(&buffer, []Entry{
+		{
+			Time:          time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Service:       "rates",
+			Host:          "example.com",
+			URL:           "https://example.com/path",
+			Proxied:       true,
+			BytesSent:     1,
+			BytesReceived: 2,
+			StatusCode:    200,
+			Result:        ResultOK,
+		},
+		{
+			Time:    time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC),
+			Service: "market",
+			Host:    "example.org",
+			Result:  ResultBlocked,
+			Reason:  "blockedByPrivacyMode, really",
+		},
+	}))
+	require.Equal(t,
+		"Time,Service,Host,URL,Proxied,Bytes sent,Bytes received,Status code,Result,Reason\n"+
+			"2024-01-02T03:04:05Z,rates,example.com,https://example.com/path,true,1,2,200,ok,\n"+
+			"2024-01-02T03:04:06Z,market,example.org,,false,0,0,,blocked,\"blockedByPrivacyMode, really\"\n",
+		buffer.String())
+}
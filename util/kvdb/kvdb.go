@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package kvdb provides a key/value database with buckets, either persisted with bbolt or kept only
+// in memory. The interfaces mirror the subset of the bbolt API used by the app.
+package kvdb
+
+import (
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"go.etcd.io/bbolt"
+)
+
+// DB is a key/value database.
+type DB interface {
+	// Begin starts a database transaction. Apply `defer tx.Rollback()` in any case after. Only one
+	// writable transaction can be open at a time, concurrent read transactions do not block.
+	Begin(writable bool) (Tx, error)
+	Close() error
+}
+
+// Tx is a database transaction.
+type Tx interface {
+	// Bucket returns the bucket with the given name, or nil if it does not exist.
+	Bucket(name []byte) Bucket
+	CreateBucket(name []byte) (Bucket, error)
+	CreateBucketIfNotExists(name []byte) (Bucket, error)
+	DeleteBucket(name []byte) error
+	Commit() error
+	// Rollback closes the transaction without writing anything. It can be called safely after
+	// Commit().
+	Rollback() error
+}
+
+// Bucket is a collection of key/value pairs, sorted by key.
+type Bucket interface {
+	// Get returns the value of the key, or nil if it does not exist. The value is only valid
+	// during the transaction.
+	Get(key []byte) []byte
+	Put(key []byte, value []byte) error
+	Delete(key []byte) error
+	// NextSequence returns an autoincrementing integer for the bucket.
+	NextSequence() (uint64, error)
+	Cursor() Cursor
+}
+
+// Cursor iterates over the key/value pairs of a bucket in key order. The methods return a nil key
+// when there are no more pairs.
+type Cursor interface {
+	First() (key []byte, value []byte)
+	Last() (key []byte, value []byte)
+	Next() (key []byte, value []byte)
+	Prev() (key []byte, value []byte)
+}
+
+// OpenBolt creates or opens a database persisted in the given file.
+func OpenBolt(filename string) (DB, error) {
+	db, err := bbolt.Open(filename, 0600, nil)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return &boltDB{db: db}, nil
+}
+
+type boltDB struct {
+	db *bbolt.DB
+}
+
+func (db *boltDB) Begin(writable bool) (Tx, error) {
+	tx, err := db.db.Begin(writable)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return &boltTx{tx: tx}, nil
+}
+
+func (db *boltDB) Close() error {
+	return errp.WithStack(db.db.Close())
+}
+
+type boltTx struct {
+	tx *bbolt.Tx
+}
+
+func (tx *boltTx) Bucket(name []byte) Bucket {
+	bucket := tx.tx.Bucket(name)
+	if bucket == nil {
+		return nil
+	}
+	return boltBucket{bucket}
+}
+
+func (tx *boltTx) CreateBucket(name []byte) (Bucket, error) {
+	bucket, err := tx.tx.CreateBucket(name)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return boltBucket{bucket}, nil
+}
+
+func (tx *boltTx) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	bucket, err := tx.tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return boltBucket{bucket}, nil
+}
+
+func (tx *boltTx) DeleteBucket(name []byte) error {
+	return errp.WithStack(tx.tx.DeleteBucket(name))
+}
+
+func (tx *boltTx) Commit() error {
+	return errp.WithStack(tx.tx.Commit())
+}
+
+func (tx *boltTx) Rollback() error {
+	err := tx.tx.Rollback()
+	if err == bbolt.ErrTxClosed {
+		return nil
+	}
+	return errp.WithStack(err)
+}
+
+type boltBucket struct {
+	*bbolt.Bucket
+}
+
+func (bucket boltBucket) Cursor() Cursor {
+	return bucket.Bucket.Cursor()
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kvdb
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testDB(t *testing.T, db DB) {
+	t.Helper()
+	bucketName := []byte("bucket")
+
+	tx, err := db.Begin(false)
+	require.NoError(t, err)
+	require.Nil(t, tx.Bucket(bucketName))
+	_, err = tx.CreateBucket(bucketName)
+	require.Error(t, err)
+	require.NoError(t, tx.Rollback())
+
+	tx, err = db.Begin(true)
+	require.NoError(t, err)
+	bucket, err := tx.CreateBucketIfNotExists(bucketName)
+	require.NoError(t, err)
+	for _, key := range []string{"b", "c", "a"} {
+		require.NoError(t, bucket.Put([]byte(key), []byte("value-"+key)))
+	}
+	sequence, err := bucket.NextSequence()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), sequence)
+	_, err = tx.CreateBucket(bucketName)
+	require.Error(t, err)
+
+	// Uncommitted changes are not visible to read transactions.
+	readTx, err := db.Begin(false)
+	require.NoError(t, err)
+	require.Nil(t, readTx.Bucket(bucketName))
+	require.NoError(t, readTx.Rollback())
+
+	require.NoError(t, tx.Commit())
+	require.NoError(t, tx.Rollback())
+
+	tx, err = db.Begin(false)
+	require.NoError(t, err)
+	bucket = tx.Bucket(bucketName)
+	require.NotNil(t, bucket)
+	require.Equal(t, []byte("value-a"), bucket.Get([]byte("a")))
+	require.Nil(t, bucket.Get([]byte("d")))
+	require.Error(t, bucket.Put([]byte("d"), []byte("value-d")))
+	keys := []string{}
+	cursor := bucket.Cursor()
+	for key, _ := cursor.First(); key != nil; key, _ = cursor.Next() {
+		keys = append(keys, string(key))
+	}
+	for key, _ := cursor.Last(); key != nil; key, _ = cursor.Prev() {
+		keys = append(keys, string(key))
+	}
+	require.Equal(t, []string{"a", "b", "c", "c", "b", "a"}, keys)
+	require.NoError(t, tx.Rollback())
+
+	// Rolled back changes are discarded.
+	tx, err = db.Begin(true)
+	require.NoError(t, err)
+	bucket = tx.Bucket(bucketName)
+	require.NoError(t, bucket.Delete([]byte("a")))
+	require.Nil(t, bucket.Get([]byte("a")))
+	require.NoError(t, tx.Rollback())
+
+	tx, err = db.Begin(true)
+	require.NoError(t, err)
+	bucket = tx.Bucket(bucketName)
+	require.Equal(t, []byte("value-a"), bucket.Get([]byte("a")))
+	sequence, err = bucket.NextSequence()
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), sequence)
+	require.NoError(t, tx.DeleteBucket(bucketName))
+	require.Error(t, tx.DeleteBucket(bucketName))
+	require.Nil(t, tx.Bucket(bucketName))
+	require.NoError(t, tx.Commit())
+
+	tx, err = db.Begin(false)
+	require.NoError(t, err)
+	require.Nil(t, tx.Bucket(bucketName))
+	require.NoError(t, tx.Rollback())
+
+	require.NoError(t, db.Close())
+	_, err = db.Begin(false)
+	require.Error(t, err)
+}
+
+func TestBolt(t *testing.T) {
+	db, err := OpenBolt(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	testDB(t, db)
+}
+
+func TestMemory(t *testing.T) {
+	testDB(t, NewMemory())
+}
@@ -0,0 +1,277 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package kvdb
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+)
+
+var (
+	// ErrTxClosed is returned when using a committed or rolled back transaction.
+	ErrTxClosed = errp.New("transaction closed")
+	// ErrTxNotWritable is returned when writing in a read transaction.
+	ErrTxNotWritable = errp.New("transaction not writable")
+	// ErrDatabaseClosed is returned when beginning a transaction on a closed database.
+	ErrDatabaseClosed = errp.New("database closed")
+	// ErrBucketExists is returned by CreateBucket if the bucket already exists.
+	ErrBucketExists = errp.New("bucket already exists")
+	// ErrBucketNotFound is returned by DeleteBucket if the bucket does not exist.
+	ErrBucketNotFound = errp.New("bucket not found")
+)
+
+// memoryBucket is the immutable state of a bucket. Writable transactions modify a copy.
+type memoryBucket struct {
+	values   map[string][]byte
+	sequence uint64
+}
+
+func (bucket *memoryBucket) clone() *memoryBucket {
+	values := make(map[string][]byte, len(bucket.values))
+	for key, value := range bucket.values {
+		values[key] = value
+	}
+	return &memoryBucket{values: values, sequence: bucket.sequence}
+}
+
+// memoryDB is a database kept only in memory. Each transaction works on a snapshot of the buckets,
+// so that read transactions are not affected by concurrent writes.
+type memoryDB struct {
+	// writeLock serializes the writable transactions.
+	writeLock sync.Mutex
+	// buckets is the committed state, a map[string]*memoryBucket which is never modified.
+	buckets atomic.Pointer[map[string]*memoryBucket]
+	closed  atomic.Bool
+}
+
+// NewMemory creates a database which is kept only in memory, e.g. so that private data is not
+// written to disk.
+func NewMemory() DB {
+	db := &memoryDB{}
+	db.buckets.Store(&map[string]*memoryBucket{})
+	return db
+}
+
+func (db *memoryDB) Begin(writable bool) (Tx, error) {
+	if db.closed.Load() {
+		return nil, errp.WithStack(ErrDatabaseClosed)
+	}
+	if writable {
+		db.writeLock.Lock()
+	}
+	tx := &memoryTx{db: db, writable: writable, buckets: *db.buckets.Load()}
+	if writable {
+		buckets := make(map[string]*memoryBucket, len(tx.buckets))
+		for name, bucket := range tx.buckets {
+			buckets[name] = bucket
+		}
+		tx.buckets = buckets
+		tx.modified = map[string]bool{}
+	}
+	return tx, nil
+}
+
+func (db *memoryDB) Close() error {
+	db.closed.Store(true)
+	db.buckets.Store(&map[string]*memoryBucket{})
+	return nil
+}
+
+type memoryTx struct {
+	db       *memoryDB
+	writable bool
+	closed   bool
+	buckets  map[string]*memoryBucket
+	// modified are the buckets copied in this transaction, which can be modified in place.
+	modified map[string]bool
+}
+
+// writableBucket returns the bucket to be modified in this transaction.
+func (tx *memoryTx) writableBucket(name string) (*memoryBucket, error) {
+	if tx.closed {
+		return nil, errp.WithStack(ErrTxClosed)
+	}
+	if !tx.writable {
+		return nil, errp.WithStack(ErrTxNotWritable)
+	}
+	bucket, ok := tx.buckets[name]
+	if !ok {
+		return nil, errp.WithStack(ErrBucketNotFound)
+	}
+	if !tx.modified[name] {
+		bucket = bucket.clone()
+		tx.buckets[name] = bucket
+		tx.modified[name] = true
+	}
+	return bucket, nil
+}
+
+func (tx *memoryTx) Bucket(name []byte) Bucket {
+	if _, ok := tx.buckets[string(name)]; !ok {
+		return nil
+	}
+	return &memoryBucketView{tx: tx, name: string(name)}
+}
+
+func (tx *memoryTx) CreateBucket(name []byte) (Bucket, error) {
+	if tx.closed {
+		return nil, errp.WithStack(ErrTxClosed)
+	}
+	if !tx.writable {
+		return nil, errp.WithStack(ErrTxNotWritable)
+	}
+	if _, ok := tx.buckets[string(name)]; ok {
+		return nil, errp.WithStack(ErrBucketExists)
+	}
+	tx.buckets[string(name)] = &memoryBucket{values: map[string][]byte{}}
+	tx.modified[string(name)] = true
+	return &memoryBucketView{tx: tx, name: string(name)}, nil
+}
+
+func (tx *memoryTx) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	if bucket := tx.Bucket(name); bucket != nil {
+		return bucket, nil
+	}
+	return tx.CreateBucket(name)
+}
+
+func (tx *memoryTx) DeleteBucket(name []byte) error {
+	if tx.closed {
+		return errp.WithStack(ErrTxClosed)
+	}
+	if !tx.writable {
+		return errp.WithStack(ErrTxNotWritable)
+	}
+	if _, ok := tx.buckets[string(name)]; !ok {
+		return errp.WithStack(ErrBucketNotFound)
+	}
+	delete(tx.buckets, string(name))
+	delete(tx.modified, string(name))
+	return nil
+}
+
+func (tx *memoryTx) Commit() error {
+	if tx.closed {
+		return errp.WithStack(ErrTxClosed)
+	}
+	if !tx.writable {
+		return errp.WithStack(ErrTxNotWritable)
+	}
+	if !tx.db.closed.Load() {
+		tx.db.buckets.Store(&tx.buckets)
+	}
+	tx.close()
+	return nil
+}
+
+func (tx *memoryTx) Rollback() error {
+	if !tx.closed {
+		tx.close()
+	}
+	return nil
+}
+
+func (tx *memoryTx) close() {
+	tx.closed = true
+	if tx.writable {
+		tx.db.writeLock.Unlock()
+	}
+}
+
+// memoryBucketView accesses a bucket in a transaction by name, as writes replace the bucket by a
+// copy.
+type memoryBucketView struct {
+	tx   *memoryTx
+	name string
+}
+
+func (view *memoryBucketView) Get(key []byte) []byte {
+	bucket, ok := view.tx.buckets[view.name]
+	if !ok {
+		return nil
+	}
+	return bucket.values[string(key)]
+}
+
+func (view *memoryBucketView) Put(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errp.New("key required")
+	}
+	bucket, err := view.tx.writableBucket(view.name)
+	if err != nil {
+		return err
+	}
+	bucket.values[string(key)] = append([]byte{}, value...)
+	return nil
+}
+
+func (view *memoryBucketView) Delete(key []byte) error {
+	bucket, err := view.tx.writableBucket(view.name)
+	if err != nil {
+		return err
+	}
+	delete(bucket.values, string(key))
+	return nil
+}
+
+func (view *memoryBucketView) NextSequence() (uint64, error) {
+	bucket, err := view.tx.writableBucket(view.name)
+	if err != nil {
+		return 0, err
+	}
+	bucket.sequence++
+	return bucket.sequence, nil
+}
+
+func (view *memoryBucketView) Cursor() Cursor {
+	bucket, ok := view.tx.buckets[view.name]
+	if !ok {
+		return &memoryCursor{}
+	}
+	keys := make([]string, 0, len(bucket.values))
+	for key := range bucket.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return &memoryCursor{values: bucket.values, keys: keys}
+}
+
+// memoryCursor iterates over the keys of a bucket at the time the cursor was created.
+type memoryCursor struct {
+	values map[string][]byte
+	keys   []string
+	index  int
+}
+
+func (cursor *memoryCursor) at(index int) ([]byte, []byte) {
+	if index < 0 {
+		cursor.index = -1
+		return nil, nil
+	}
+	if index >= len(cursor.keys) {
+		cursor.index = len(cursor.keys)
+		return nil, nil
+	}
+	cursor.index = index
+	key := cursor.keys[index]
+	return []byte(key), cursor.values[key]
+}
+
+func (cursor *memoryCursor) First() ([]byte, []byte) {
+	return cursor.at(0)
+}
+
+func (cursor *memoryCursor) Last() ([]byte, []byte) {
+	return cursor.at(len(cursor.keys) - 1)
+}
+
+func (cursor *memoryCursor) Next() ([]byte, []byte) {
+	return cursor.at(cursor.index + 1)
+}
+
+func (cursor *memoryCursor) Prev() ([]byte, []byte) {
+	return cursor.at(cursor.index - 1)
+}
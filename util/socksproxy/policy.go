@@ -0,0 +1,198 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package socksproxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/connlog"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+	"golang.org/x/net/proxy"
+)
+
+// connectionPolicy decides which connections of the services are made, and records them in the
+// connection log.
+type connectionPolicy struct {
+	strictPrivacy func() bool
+	connectionLog *connlog.Log
+}
+
+// SetConnectionPolicy enforces the strict privacy mode on the dialers and http clients returned by
+// `ServiceTCPDialer()` and `ServiceHTTPClient()`: while strictPrivacy returns true, connections of
+// services which are not essential fail with `ErrBlockedByPrivacyMode`. All connections and
+// requests, including the blocked ones, are recorded in the connection log. This has to be called
+// before the proxy is copied.
+func (socksProxy *SocksProxy) SetConnectionPolicy(strictPrivacy func() bool, connectionLog *connlog.Log) {
+	socksProxy.policy = &connectionPolicy{strictPrivacy: strictPrivacy, connectionLog: connectionLog}
+}
+
+// blocked returns the reason why a connection of the service is not allowed, or an empty string if
+// it is allowed.
+func (policy *connectionPolicy) blocked(service Service, disabled bool) errp.ErrorCode {
+	switch {
+	case disabled:
+		return ErrServiceDisabled
+	case !service.Essential() && policy.strictPrivacy != nil && policy.strictPrivacy():
+		return ErrBlockedByPrivacyMode
+	}
+	return ""
+}
+
+// guardedTransport enforces the connection policy on the requests of a service and records them.
+type guardedTransport struct {
+	service Service
+	// disabled is true if the route of the service is disabled.
+	disabled bool
+	proxied  bool
+	policy   *connectionPolicy
+	base     http.RoundTripper
+}
+
+func (transport *guardedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	entry := connlog.Entry{
+		Time:    time.Now(),
+		Service: string(transport.service),
+		Host:    request.URL.Host,
+		Proxied: transport.proxied,
+	}
+	if transport.policy.connectionLog.RecordsURLs() {
+		entry.URL = connlog.RedactURL(request.URL)
+	}
+	if blocked := transport.policy.blocked(transport.service, transport.disabled); blocked != "" {
+		if request.Body != nil {
+			_ = request.Body.Close()
+		}
+		entry.Result = connlog.ResultBlocked
+		entry.Reason = string(blocked)
+		transport.policy.connectionLog.Add(entry)
+		return nil, errp.WithMessage(errp.WithStack(blocked), string(transport.service))
+	}
+	if request.ContentLength > 0 {
+		entry.BytesSent = request.ContentLength
+	}
+	response, err := transport.base.RoundTrip(request)
+	if err != nil {
+		entry.Result = connlog.ResultError
+		entry.Reason = err.Error()
+		transport.policy.connectionLog.Add(entry)
+		return nil, err
+	}
+	entry.Result = connlog.ResultOK
+	entry.StatusCode = response.StatusCode
+	response.Body = &countingBody{
+		ReadCloser: response.Body,
+		done: func(bytesReceived int64) {
+			entry.BytesReceived = bytesReceived
+			transport.policy.connectionLog.Add(entry)
+		},
+	}
+	return response, nil
+}
+
+// countingBody counts the bytes of a response body and calls done when the body was read
+// completely or closed.
+type countingBody struct {
+	io.ReadCloser
+	count int64
+	once  sync.Once
+	done  func(bytesReceived int64)
+}
+
+func (body *countingBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	body.count += int64(n)
+	if err == io.EOF {
+		body.once.Do(func() { body.done(body.count) })
+	}
+	return n, err
+}
+
+func (body *countingBody) Close() error {
+	body.once.Do(func() { body.done(body.count) })
+	return body.ReadCloser.Close()
+}
+
+// guardedDialer enforces the connection policy on the TCP connections of a service and records
+// them.
+type guardedDialer struct {
+	service Service
+	proxied bool
+	policy  *connectionPolicy
+	base    proxy.Dialer
+}
+
+func (dialer *guardedDialer) Dial(network, addr string) (net.Conn, error) {
+	return dialer.DialContext(context.Background(), network, addr)
+}
+
+func (dialer *guardedDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	entry := connlog.Entry{
+		Time:    time.Now(),
+		Service: string(dialer.service),
+		Host:    addr,
+		Proxied: dialer.proxied,
+	}
+	_, disabled := dialer.base.(disabledDialer)
+	if blocked := dialer.policy.blocked(dialer.service, disabled); blocked != "" {
+		entry.Result = connlog.ResultBlocked
+		entry.Reason = string(blocked)
+		dialer.policy.connectionLog.Add(entry)
+		return nil, errp.WithMessage(errp.WithStack(blocked), string(dialer.service))
+	}
+	var conn net.Conn
+	var err error
+	if contextDialer, ok := dialer.base.(proxy.ContextDialer); ok {
+		conn, err = contextDialer.DialContext(ctx, network, addr)
+	} else {
+		conn, err = dialer.base.Dial(network, addr)
+	}
+	if err != nil {
+		entry.Result = connlog.ResultError
+		entry.Reason = err.Error()
+		dialer.policy.connectionLog.Add(entry)
+		return nil, err
+	}
+	entry.Result = connlog.ResultConnected
+	dialer.policy.connectionLog.Add(entry)
+	return &auditedConn{Conn: conn, entry: entry, policy: dialer.policy}, nil
+}
+
+// auditedConn counts the bytes of a TCP connection and records them when it is closed.
+type auditedConn struct {
+	net.Conn
+	bytesSent     atomic.Int64
+	bytesReceived atomic.Int64
+	entry         connlog.Entry
+	policy        *connectionPolicy
+	once          sync.Once
+}
+
+func (conn *auditedConn) Read(p []byte) (int, error) {
+	n, err := conn.Conn.Read(p)
+	conn.bytesReceived.Add(int64(n))
+	return n, err
+}
+
+func (conn *auditedConn) Write(p []byte) (int, error) {
+	n, err := conn.Conn.Write(p)
+	conn.bytesSent.Add(int64(n))
+	return n, err
+}
+
+func (conn *auditedConn) Close() error {
+	conn.once.Do(func() {
+		entry := conn.entry
+		entry.Time = time.Now()
+		entry.Result = connlog.ResultClosed
+		entry.BytesSent = conn.bytesSent.Load()
+		entry.BytesReceived = conn.bytesReceived.Load()
+		conn.policy.connectionLog.Add(entry)
+	})
+	return conn.Conn.Close()
+}
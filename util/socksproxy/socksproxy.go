@@ -3,21 +3,86 @@
 package socksproxy
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"net"
 	"net/http"
 	"net/url"
 
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
 	"github.com/BitBoxSwiss/bitbox-wallet-app/util/logging"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/net/proxy"
 )
 
+// Service is a class of services the app connects to, which can be routed separately.
+type Service string
+
+const (
+	// ServiceElectrum are the Electrum servers of Bitcoin and Litecoin.
+	ServiceElectrum Service = "electrum"
+	// ServiceEthereum are the Ethereum RPC nodes and Etherscan.
+	ServiceEthereum Service = "ethereum"
+	// ServiceRates are the exchange rates providers.
+	ServiceRates Service = "rates"
+	// ServiceApp are the banners and the update check of the app.
+	ServiceApp Service = "app"
+	// ServiceMarket are the vendors to buy, sell and swap coins, and Bitsurance.
+	ServiceMarket Service = "market"
+	// ServiceOther are all other services, e.g. the fee estimation of mempool.space, NFT metadata
+	// and AOPP callbacks.
+	ServiceOther Service = "other"
+)
+
+// Services are all services which can be routed separately.
+var Services = []Service{
+	ServiceElectrum, ServiceEthereum, ServiceRates, ServiceApp, ServiceMarket, ServiceOther,
+}
+
+// Essential returns true if the service is required to use the wallet, i.e. if it is a blockchain
+// backend. Only essential services are contacted in the strict privacy mode.
+func (service Service) Essential() bool {
+	return service == ServiceElectrum || service == ServiceEthereum
+}
+
+// Route defines how the connections of a service are made.
+type Route string
+
+const (
+	// RouteDefault uses the proxy if it is enabled.
+	RouteDefault Route = ""
+	// RouteDirect connects directly, even if the proxy is enabled.
+	RouteDirect Route = "direct"
+	// RouteProxy connects through the proxy, even if the proxy is not enabled for all services.
+	RouteProxy Route = "proxy"
+	// RouteDisabled does not allow any connections.
+	RouteDisabled Route = "disabled"
+)
+
+const (
+	// ErrServiceDisabled is returned when connecting to a service whose route is disabled.
+	ErrServiceDisabled errp.ErrorCode = "serviceDisabled"
+	// ErrBlockedByPrivacyMode is returned when connecting to a non-essential service in the strict
+	// privacy mode.
+	ErrBlockedByPrivacyMode errp.ErrorCode = "blockedByPrivacyMode"
+)
+
 // SocksProxy holds the proxy address and wether to use it.
 type SocksProxy struct {
 	useProxy         bool
 	proxyAddress     string
 	fullProxyAddress string
-	log              *logrus.Entry
+	// routes overrides per service whether to use the proxy.
+	routes map[Service]Route
+	// isolationNonce is the random nonce of the SOCKS credentials used for stream isolation. Empty
+	// if streams are not isolated.
+	isolationNonce string
+	// policy is enforced on the connections of the services, which are recorded in its connection
+	// log. nil if all connections are allowed and not recorded.
+	policy *connectionPolicy
+	log    *logrus.Entry
 }
 
 const defaultProxyAddress = "127.0.0.1:9050"
@@ -37,6 +102,72 @@ func NewSocksProxy(useProxy bool, proxyAddress string) SocksProxy {
 	return proxy
 }
 
+// NewSocksProxyWithRoutes is like `NewSocksProxy()`, with a route per service. Services without
+// route use the proxy if useProxy is true.
+//
+// If isolateStreams is true, the connections of each service and account through the proxy use
+// separate SOCKS credentials. Tor uses a separate circuit for each, so that e.g. the Electrum
+// queries of different accounts can't be linked by the exit nodes.
+func NewSocksProxyWithRoutes(
+	useProxy bool, proxyAddress string, routes map[Service]Route, isolateStreams bool) SocksProxy {
+	socksProxy := NewSocksProxy(useProxy, proxyAddress)
+	socksProxy.routes = routes
+	if isolateStreams {
+		// The nonce makes the credentials unlinkable across app sessions.
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			socksProxy.log.WithError(err).Panic("Failed to create the stream isolation nonce")
+		}
+		socksProxy.isolationNonce = hex.EncodeToString(nonce)
+	}
+	return socksProxy
+}
+
+// Route returns the effective route of the service, i.e. `RouteDirect`, `RouteProxy` or
+// `RouteDisabled`.
+func (socksProxy *SocksProxy) Route(service Service) Route {
+	switch route := socksProxy.routes[service]; route {
+	case RouteDirect, RouteProxy, RouteDisabled:
+		return route
+	}
+	if socksProxy.useProxy {
+		return RouteProxy
+	}
+	return RouteDirect
+}
+
+// IsolatesStreams returns true if the connections of the given service through the proxy use
+// separate circuits per isolation key, e.g. per account.
+func (socksProxy *SocksProxy) IsolatesStreams(service Service) bool {
+	return socksProxy.isolationNonce != "" && socksProxy.Route(service) == RouteProxy
+}
+
+// auth returns the SOCKS credentials for the service and isolation key, or nil if streams are not
+// isolated. The isolation key is hashed, so that e.g. account codes are not revealed to the proxy.
+func (socksProxy *SocksProxy) auth(service Service, isolationKey string) *proxy.Auth {
+	if socksProxy.isolationNonce == "" {
+		return nil
+	}
+	hash := sha256.Sum256([]byte(socksProxy.isolationNonce + "/" + string(service) + "/" + isolationKey))
+	return &proxy.Auth{
+		User:     hex.EncodeToString(hash[:16]),
+		Password: hex.EncodeToString(hash[16:]),
+	}
+}
+
+// disabledDialer fails all connections of a disabled service.
+type disabledDialer struct {
+	service Service
+}
+
+func (dialer disabledDialer) Dial(network, addr string) (net.Conn, error) {
+	return nil, errp.WithMessage(errp.WithStack(ErrServiceDisabled), string(dialer.service))
+}
+
+func (dialer disabledDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return dialer.Dial(network, addr)
+}
+
 // Validate validates the socks5 proxy endpoint.
 // We check if we could instantiate a proxied http client.
 // Currently, no actual connectivity checks as performed.
@@ -44,6 +175,10 @@ func (socksProxy SocksProxy) Validate() error {
 	if !socksProxy.useProxy {
 		return nil
 	}
+	return socksProxy.validateAddress()
+}
+
+func (socksProxy SocksProxy) validateAddress() error {
 	tbProxyURL, err := url.Parse(socksProxy.fullProxyAddress)
 	if err != nil {
 		return err
@@ -93,3 +228,76 @@ func (socksProxy *SocksProxy) GetHTTPClient() (*http.Client, error) {
 	}
 	return &http.Client{}, nil
 }
+
+// ServiceTCPDialer returns a dialer for the given service, according to its route. Connections made
+// through the proxy use a separate circuit per isolation key if streams are isolated. The isolation
+// key can be empty to isolate only the service, or e.g. an account code. The connections are
+// recorded in the connection log, see `SetConnectionPolicy()`.
+func (socksProxy *SocksProxy) ServiceTCPDialer(service Service, isolationKey string) proxy.Dialer {
+	dialer := socksProxy.serviceTCPDialer(service, isolationKey)
+	if socksProxy.policy == nil {
+		return dialer
+	}
+	return &guardedDialer{
+		service: service,
+		proxied: socksProxy.Route(service) == RouteProxy,
+		policy:  socksProxy.policy,
+		base:    dialer,
+	}
+}
+
+func (socksProxy *SocksProxy) serviceTCPDialer(service Service, isolationKey string) proxy.Dialer {
+	switch socksProxy.Route(service) {
+	case RouteDisabled:
+		return disabledDialer{service: service}
+	case RouteProxy:
+		dialer, err := proxy.SOCKS5("tcp", socksProxy.proxyAddress, socksProxy.auth(service, isolationKey), proxy.Direct)
+		if err != nil {
+			// TODO: Remove this panic.
+			socksProxy.log.WithError(err).Panic("Failed to create SOCKS5 TCP dialer")
+		}
+		return dialer
+	default:
+		return &net.Dialer{}
+	}
+}
+
+// ServiceHTTPClient returns a http client for the given service, according to its route and the
+// connection policy. See `ServiceTCPDialer()` for the isolation key. Requests to disabled services
+// fail with `ErrServiceDisabled`.
+func (socksProxy *SocksProxy) ServiceHTTPClient(service Service, isolationKey string) (*http.Client, error) {
+	client, err := socksProxy.serviceHTTPClient(service, isolationKey)
+	if err != nil || socksProxy.policy == nil {
+		return client, err
+	}
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	client.Transport = &guardedTransport{
+		service:  service,
+		disabled: socksProxy.Route(service) == RouteDisabled,
+		proxied:  socksProxy.Route(service) == RouteProxy,
+		policy:   socksProxy.policy,
+		base:     base,
+	}
+	return client, nil
+}
+
+func (socksProxy *SocksProxy) serviceHTTPClient(service Service, isolationKey string) (*http.Client, error) {
+	switch socksProxy.Route(service) {
+	case RouteDisabled:
+		dialer := disabledDialer{service: service}
+		return &http.Client{Transport: &http.Transport{DialContext: dialer.DialContext}}, nil
+	case RouteProxy:
+		if err := socksProxy.validateAddress(); err != nil {
+			socksProxy.log.WithError(err).Error("Invalid proxy address")
+			return &http.Client{}, err
+		}
+		// The requests are recorded by the transport, not by the dialer.
+		dialer := socksProxy.serviceTCPDialer(service, isolationKey)
+		return &http.Client{Transport: &http.Transport{Dial: dialer.Dial}}, nil
+	default:
+		return &http.Client{}, nil
+	}
+}
@@ -3,8 +3,16 @@
 package socksproxy
 
 import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/connlog"
+	"github.com/BitBoxSwiss/bitbox-wallet-app/util/errp"
+
 	"github.com/stretchr/testify/require"
 )
 
@@ -16,3 +24,159 @@ func TestValidate(t *testing.T) {
 	require.Error(t, NewSocksProxy(true, "127.0.0.1:XXXX").Validate())
 	require.Error(t, NewSocksProxy(true, "127.0.0.1:9050 ").Validate())
 }
+
+func TestRoute(t *testing.T) {
+	routes := map[Service]Route{
+		ServiceElectrum: RouteProxy,
+		ServiceRates:    RouteDirect,
+		ServiceMarket:   RouteDisabled,
+	}
+
+	socksProxy := NewSocksProxyWithRoutes(false, "", routes, false)
+	require.Equal(t, RouteProxy, socksProxy.Route(ServiceElectrum))
+	require.Equal(t, RouteDirect, socksProxy.Route(ServiceRates))
+	require.Equal(t, RouteDisabled, socksProxy.Route(ServiceMarket))
+	require.Equal(t, RouteDirect, socksProxy.Route(ServiceEthereum))
+	require.False(t, socksProxy.IsolatesStreams(ServiceElectrum))
+
+	socksProxy = NewSocksProxyWithRoutes(true, "", routes, true)
+	require.Equal(t, RouteProxy, socksProxy.Route(ServiceEthereum))
+	require.Equal(t, RouteDirect, socksProxy.Route(ServiceRates))
+	require.True(t, socksProxy.IsolatesStreams(ServiceElectrum))
+	require.False(t, socksProxy.IsolatesStreams(ServiceRates))
+
+	_, err := socksProxy.ServiceTCPDialer(ServiceMarket, "").Dial("tcp", "127.0.0.1:1")
+	require.Equal(t, ErrServiceDisabled, errp.Cause(err))
+	client, err := socksProxy.ServiceHTTPClient(ServiceMarket, "")
+	require.NoError(t, err)
+	_, err = client.Get("http://127.0.0.1:1")
+	require.ErrorIs(t, err, ErrServiceDisabled)
+}
+
+func TestStreamIsolation(t *testing.T) {
+	socksProxy := NewSocksProxyWithRoutes(true, "", nil, false)
+	require.Nil(t, socksProxy.auth(ServiceElectrum, "account-1"))
+
+	socksProxy = NewSocksProxyWithRoutes(true, "", nil, true)
+	auth := socksProxy.auth(ServiceElectrum, "account-1")
+	require.NotNil(t, auth)
+	require.Equal(t, auth, socksProxy.auth(ServiceElectrum, "account-1"))
+	require.NotEqual(t, auth, socksProxy.auth(ServiceElectrum, "account-2"))
+	require.NotEqual(t, auth, socksProxy.auth(ServiceEthereum, "account-1"))
+	require.NotContains(t, auth.User+auth.Password, "account-1")
+
+	// The credentials differ in each session.
+	otherSession := NewSocksProxyWithRoutes(true, "", nil, true)
+	require.NotEqual(t, auth, otherSession.auth(ServiceElectrum, "account-1"))
+}
+
+func TestConnectionPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("response"))
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	socksProxy := NewSocksProxyWithRoutes(
+		false, "", map[Service]Route{ServiceMarket: RouteDisabled}, false)
+	strictPrivacy := false
+	recordURLs := false
+	connectionLog := connlog.NewLog(20)
+	connectionLog.SetRecordURLs(func() bool { return recordURLs })
+	socksProxy.SetConnectionPolicy(func() bool { return strictPrivacy }, connectionLog)
+
+	get := func(service Service) error {
+		client, err := socksProxy.ServiceHTTPClient(service, "")
+		require.NoError(t, err)
+		response, err := client.Get(server.URL + "/path?query")
+		if err != nil {
+			return err
+		}
+		_, err = io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return response.Body.Close()
+	}
+
+	require.NoError(t, get(ServiceRates))
+	require.ErrorIs(t, get(ServiceMarket), ErrServiceDisabled)
+	entries := connectionLog.Entries()
+	require.Len(t, entries, 2)
+	require.Equal(t, connlog.Entry{
+		Time:          entries[0].Time,
+		Service:       string(ServiceRates),
+		Host:          serverURL.Host,
+		BytesReceived: int64(len("response")),
+		StatusCode:    http.StatusOK,
+		Result:        connlog.ResultOK,
+	}, entries[0])
+	require.Equal(t, connlog.ResultBlocked, entries[1].Result)
+	require.Equal(t, string(ErrServiceDisabled), entries[1].Reason)
+
+	// The URL is recorded without the query.
+	recordURLs = true
+	require.NoError(t, get(ServiceElectrum))
+	require.Equal(t, server.URL+"/path", connectionLog.Entries()[2].URL)
+
+	connectionLog = connlog.NewLog(20)
+	socksProxy.policy.connectionLog = connectionLog
+	strictPrivacy = true
+	require.ErrorIs(t, get(ServiceRates), ErrBlockedByPrivacyMode)
+	require.ErrorIs(t, get(ServiceApp), ErrBlockedByPrivacyMode)
+	require.NoError(t, get(ServiceElectrum))
+	require.NoError(t, get(ServiceEthereum))
+
+	entries = connectionLog.Entries()
+	require.Len(t, entries, 4)
+	require.Equal(t, string(ServiceRates), entries[0].Service)
+	require.Equal(t, serverURL.Host, entries[0].Host)
+	require.Equal(t, connlog.ResultBlocked, entries[0].Result)
+	require.Equal(t, string(ErrBlockedByPrivacyMode), entries[0].Reason)
+	require.Equal(t, connlog.ResultOK, entries[3].Result)
+}
+
+func TestAuditedDialer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte("pong!"))
+	}()
+
+	socksProxy := NewSocksProxyWithRoutes(
+		false, "", map[Service]Route{ServiceEthereum: RouteDisabled}, false)
+	connectionLog := connlog.NewLog(10)
+	socksProxy.SetConnectionPolicy(nil, connectionLog)
+
+	conn, err := socksProxy.ServiceTCPDialer(ServiceElectrum, "").Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	_, err = io.ReadAll(conn)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	_ = conn.Close()
+
+	_, err = socksProxy.ServiceTCPDialer(ServiceEthereum, "").Dial("tcp", listener.Addr().String())
+	require.ErrorIs(t, err, ErrServiceDisabled)
+
+	entries := connectionLog.Entries()
+	require.Len(t, entries, 3)
+	require.Equal(t, connlog.ResultConnected, entries[0].Result)
+	require.Equal(t, listener.Addr().String(), entries[0].Host)
+	require.False(t, entries[0].Proxied)
+	require.Equal(t, connlog.ResultClosed, entries[1].Result)
+	require.Equal(t, int64(4), entries[1].BytesSent)
+	require.Equal(t, int64(5), entries[1].BytesReceived)
+	require.Equal(t, connlog.ResultBlocked, entries[2].Result)
+	require.Equal(t, string(ServiceEthereum), entries[2].Service)
+}